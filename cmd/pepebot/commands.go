@@ -0,0 +1,1119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/audit"
+	"github.com/pepebot-space/pepebot/pkg/cli"
+	"github.com/pepebot-space/pepebot/pkg/feeds"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/selfupdate"
+	"github.com/pepebot-space/pepebot/pkg/workflow"
+)
+
+// newRootCommand builds the pepebot command tree. Every command gets
+// --help, and "pepebot completion <shell>" generates completions from it.
+func newRootCommand() *cli.Command {
+	root := &cli.Command{Name: "pepebot"}
+	showVersion := root.Flags().Bool("version", "v", "Show version information")
+	root.Run = func(cmd *cli.Command, args []string) error {
+		if *showVersion {
+			fmt.Printf("%s pepebot v%s\n", logo, version)
+			return nil
+		}
+		if len(args) > 0 {
+			return cli.Usagef(cmd, "unknown command %q", args[0])
+		}
+		cmd.PrintHelp()
+		return nil
+	}
+	root.Help = func(cmd *cli.Command) {
+		fmt.Println("\n     ___")
+		fmt.Println("    (o o)")
+		fmt.Println("   (  >  )")
+		fmt.Println("   /|   |\\")
+		fmt.Println("  (_|   |_)")
+		fmt.Printf("\n  🐸 PEPEBOT v%s\n", version)
+		fmt.Println("  Personal AI Assistant")
+		fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		cmd.WriteHelp(cmd.OutOrStdout())
+	}
+
+	root.AddCommand(
+		&cli.Command{
+			Name:  "onboard",
+			Short: "Initialize pepebot configuration and workspace",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { onboard(); return nil },
+		},
+		newAgentCommand(),
+		newGatewayCommand(),
+		newStatusCommand(),
+		newDoctorCommand(),
+		newCronCommand(),
+		newSkillsCommand(),
+		newWorkflowCommand(),
+		newFeedsCommand(),
+		newSessionsCommand(),
+		newMaintenanceCommand(),
+		&cli.Command{
+			Name:  "prompts",
+			Short: "Compare prompt variants by sessions, turns and tagged outcomes",
+			Long: "Agents with directories under variants/ in their prompt directory split new\n" +
+				"sessions between the variants and the base prompt. Tag a conversation with\n" +
+				"/outcome <tag>. Results are grouped by the version of the prompt files.",
+			Args: cli.NoArgs,
+			Run:  func(*cli.Command, []string) error { promptsCmd(); return nil },
+		},
+		newAuditCommand(),
+		newAnalyticsCommand(),
+		newFeedbackCommand(),
+		newRequestsCommand(),
+		newEvalCommand(),
+		newListenCommand(),
+		newConfigCommand(),
+		&cli.Command{
+			Name:  "sync",
+			Short: "Commit workspace changes to git and push them to the sync remote",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { syncCmd(); return nil },
+		},
+		newSecretsCommand(),
+		newUpdateCommand(),
+		&cli.Command{
+			Name:  "version",
+			Short: "Show version information",
+			Args:  cli.NoArgs,
+			Run: func(*cli.Command, []string) error {
+				fmt.Printf("%s pepebot v%s\n", logo, version)
+				return nil
+			},
+		},
+		cli.NewCompletionCommand(),
+	)
+	return root
+}
+
+func newAgentCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:      "agent",
+		Short:     "Chat with an agent or manage registered agents",
+		ArgsUsage: "[message]",
+		Long: "Without a message (or -m), starts an interactive session.\n" +
+			"A positional message is sent as a one-shot message.\n" +
+			"Piped stdin is attached to the message as context, or is the message itself.\n" +
+			"--tui starts the full-screen interface instead of the line prompt.",
+		Example: `pepebot agent -m "Hello!"
+pepebot agent -a coder -s cli:project "Review main.go"
+pepebot agent --tui -s cli:project
+pepebot agent -m "Describe this" -f photo.jpg -f report.pdf
+cat app.log | pepebot agent -m "Summarize the errors" --output json
+pepebot agent register coder --model "maia/claude-3-5-sonnet" --description "Coding specialist"
+pepebot agent show coder`,
+	}
+	opts := agentChatOptions{}
+	fs := cmd.Flags()
+	agentName := fs.String("agent", "a", "", "name", "Use a specific agent (default: default agent)")
+	message := fs.String("message", "m", "", "text", "Send a single message")
+	sessionKey := fs.String("session", "s", "cli:default", "key", "Session key for context")
+	files := fs.StringSlice("file", "f", "path", "Attach a file (image, PDF, audio, video) to the message")
+	forkKey := fs.String("fork", "", "", "new-key", "Copy the session into a new key and continue there")
+	useTUI := fs.Bool("tui", "", "Start the full-screen terminal interface")
+	output := fs.Choice("output", "o", "text", []string{"text", "json"}, "Output format of one-shot messages")
+	verbose := fs.Bool("verbose", "v", "Enable verbose logging (DEBUG)")
+	chatTemperature := fs.Float("temperature", "", 0, "Override the agent's temperature (0 for the most repeatable replies)")
+	chatSeed := fs.Int("seed", "", 0, "Sampling seed for repeatable replies (OpenAI-compatible and Gemini models)")
+	cmd.Run = func(cmd *cli.Command, args []string) error {
+		opts.agentName = *agentName
+		opts.message = *message
+		if opts.message == "" {
+			opts.message = strings.Join(args, " ")
+		}
+		opts.sessionKey = *sessionKey
+		opts.forkKey = *forkKey
+		opts.files = *files
+		opts.tui = *useTUI
+		opts.jsonOutput = *output == "json"
+		if opts.tui && opts.jsonOutput {
+			return cli.Usagef(cmd, "--tui and --output json cannot be combined")
+		}
+		if opts.tui && len(opts.files) > 0 {
+			return cli.Usagef(cmd, "--file attaches to a one-shot message and cannot be used with --tui")
+		}
+		opts.verbose = *verbose
+		sampling, err := samplingFlags(cmd, *chatTemperature, *chatSeed)
+		if err != nil {
+			return err
+		}
+		opts.sampling = sampling
+		agentCmd(opts)
+		return nil
+	}
+
+	register := &cli.Command{
+		Name:      "register",
+		Short:     "Register a new agent",
+		ArgsUsage: "<name>",
+		Args:      cli.ExactArgs(1),
+	}
+	rfs := register.Flags()
+	model := rfs.String("model", "", "", "model", "Model to use (required)")
+	provider := rfs.String("provider", "", "", "provider", "Provider name")
+	description := rfs.String("description", "", "", "text", "Agent description")
+	temperature := rfs.Float("temperature", "", 0, "Temperature (0.0-1.0)")
+	maxTokens := rfs.Int("max-tokens", "", 0, "Max tokens")
+	register.Run = func(c *cli.Command, args []string) error {
+		if *model == "" {
+			return cli.Usagef(c, "--model is required")
+		}
+		agentRegisterCmd(args[0], &agent.AgentDefinition{
+			Enabled:     true,
+			Model:       *model,
+			Provider:    *provider,
+			Description: *description,
+			Temperature: *temperature,
+			MaxTokens:   *maxTokens,
+		})
+		return nil
+	}
+
+	cmd.AddCommand(
+		&cli.Command{
+			Name:  "list",
+			Short: "List all registered agents",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { agentListCmd(); return nil },
+		},
+		register,
+		nameCommand("remove", "Remove an agent", agentRemoveCmd, "unregister"),
+		nameCommand("enable", "Enable an agent", agentEnableCmd),
+		nameCommand("disable", "Disable an agent", agentDisableCmd),
+		nameCommand("show", "Show agent details", agentShowCmd),
+	)
+	return cmd
+}
+
+// nameCommand builds a subcommand that takes a single <name> argument
+func nameCommand(name, short string, run func(string), aliases ...string) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Aliases:   aliases,
+		Short:     short,
+		ArgsUsage: "<name>",
+		Args:      cli.ExactArgs(1),
+		Run: func(_ *cli.Command, args []string) error {
+			run(args[0])
+			return nil
+		},
+	}
+}
+
+func newGatewayCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "gateway",
+		Short: "Start pepebot gateway",
+		Args:  cli.NoArgs,
+	}
+	verbose := cmd.Flags().Bool("verbose", "v", "Enable verbose logging (show DEBUG logs)")
+	cmd.Run = func(*cli.Command, []string) error {
+		gatewayCmd(*verbose)
+		return nil
+	}
+	return cmd
+}
+
+func newStatusCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "status",
+		Short: "Show pepebot status and live gateway state",
+		Args:  cli.NoArgs,
+	}
+	jsonOutput := cmd.Flags().Bool("json", "", "Print machine-readable output for scripts")
+	cmd.Run = func(*cli.Command, []string) error {
+		statusCmd(*jsonOutput)
+		return nil
+	}
+	return cmd
+}
+
+func newDoctorCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "doctor",
+		Short: "Diagnose config, providers, adb, channels, MCP servers and disk",
+		Long:  "Exits with status 1 when a check fails.",
+		Args:  cli.NoArgs,
+	}
+	jsonOutput := cmd.Flags().Bool("json", "", "Print machine-readable results")
+	cmd.Run = func(*cli.Command, []string) error {
+		doctorCmd(*jsonOutput)
+		return nil
+	}
+	return cmd
+}
+
+func newCronCommand() *cli.Command {
+	storePath := func() string {
+		return filepath.Join(filepath.Dir(getConfigPath()), "cron", "jobs.json")
+	}
+	cmd := &cli.Command{
+		Name:  "cron",
+		Short: "Manage scheduled tasks",
+	}
+
+	list := &cli.Command{
+		Name:  "list",
+		Short: "List all scheduled jobs",
+		Args:  cli.NoArgs,
+	}
+	verbose := list.Flags().Bool("verbose", "v", "Show recent runs of each job")
+	list.Run = func(*cli.Command, []string) error {
+		cronListCmd(storePath(), *verbose)
+		return nil
+	}
+
+	add := &cli.Command{
+		Name:  "add",
+		Short: "Add a new scheduled job",
+		Args:  cli.NoArgs,
+		Example: `pepebot cron add -n standup -m "Summarize my calendar" -c "0 9 * * 1-5" -d --channel telegram --to 123
+pepebot cron add -n backup -m "Back up the notes folder" -e 3600 --jitter 300`,
+	}
+	fs := add.Flags()
+	name := fs.String("name", "n", "", "name", "Job name (required)")
+	message := fs.String("message", "m", "", "text", "Message for the agent (required)")
+	every := fs.Int("every", "e", 0, "Run every N seconds")
+	cronExpr := fs.String("cron", "c", "", "expr", "Cron expression (e.g. '0 9 * * *')")
+	deliver := fs.Bool("deliver", "d", "Deliver the response to a channel")
+	to := fs.String("to", "", "", "chat-id", "Recipient for delivery")
+	channel := fs.String("channel", "", "", "channel", "Channel for delivery")
+	agentName := fs.String("agent", "", "", "name", "Agent that runs the job (default agent if omitted)")
+	autonomy := fs.Bool("autonomy", "", "Run the message as the objective of an autonomous session; the summary goes to --channel/--to")
+	maxIterations := fs.Int("max-iterations", "", 0, "Tool iterations for an autonomous session (default 50)")
+	maxMinutes := fs.Int("max-minutes", "", 0, "Time budget for an autonomous session in minutes (default 60)")
+	jitter := fs.Int("jitter", "", 0, "Delay each run by a random 0-N seconds")
+	overlap := fs.Choice("overlap", "", "", []string{"skip", "queue"}, "A run due while the previous one is running is skipped (default) or queued")
+	catchUp := fs.Choice("catch-up", "", "", []string{"skip", "once", "all"}, "Runs missed while the host slept or the gateway was stopped")
+	add.Run = func(c *cli.Command, _ []string) error {
+		switch {
+		case *name == "":
+			return cli.Usagef(c, "--name is required")
+		case *message == "":
+			return cli.Usagef(c, "--message is required")
+		case *every <= 0 && *cronExpr == "":
+			return cli.Usagef(c, "either --every or --cron must be specified")
+		case *autonomy && (*channel == "" || *to == ""):
+			return cli.Usagef(c, "--autonomy needs --channel and --to for the summary")
+		}
+		cronAddCmd(storePath(), cronAddOptions{
+			name:          *name,
+			message:       *message,
+			everySec:      int64(*every),
+			cronExpr:      *cronExpr,
+			deliver:       *deliver,
+			channel:       *channel,
+			to:            *to,
+			agentName:     *agentName,
+			autonomy:      *autonomy,
+			maxIterations: *maxIterations,
+			maxMinutes:    *maxMinutes,
+			jitterSec:     int64(*jitter),
+			overlap:       *overlap,
+			catchUp:       *catchUp,
+		})
+		return nil
+	}
+
+	jobCommand := func(name, short string, run func(id string)) *cli.Command {
+		return &cli.Command{
+			Name:      name,
+			Short:     short,
+			ArgsUsage: "<job_id>",
+			Args:      cli.ExactArgs(1),
+			Run: func(_ *cli.Command, args []string) error {
+				run(args[0])
+				return nil
+			},
+		}
+	}
+
+	cmd.AddCommand(
+		list,
+		add,
+		jobCommand("remove", "Remove a job by ID", func(id string) { cronRemoveCmd(storePath(), id) }),
+		jobCommand("enable", "Enable a job", func(id string) { cronEnableCmd(storePath(), id, false) }),
+		jobCommand("disable", "Disable a job", func(id string) { cronEnableCmd(storePath(), id, true) }),
+	)
+	return cmd
+}
+
+func newSkillsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "skills",
+		Short: "Manage skills (install, list, remove)",
+	}
+
+	install := &cli.Command{
+		Name:      "install",
+		Short:     "Install a skill from GitHub (owner/repo[/path]) or a git URL",
+		ArgsUsage: "<repo>[@ref]",
+		Args:      cli.ExactArgs(1),
+		Example: `pepebot skills install pepebot/skills/weather
+pepebot skills install https://gitlab.com/me/skills.git//weather?ref=v1`,
+		Run: func(_ *cli.Command, args []string) error {
+			installer, loader := skillsTools()
+			skillsInstallCmd(installer, loader, args[0])
+			return nil
+		},
+	}
+
+	update := &cli.Command{
+		Name:      "update",
+		Short:     "Update installed skills to their latest version",
+		ArgsUsage: "[name...]",
+	}
+	all := update.Flags().Bool("all", "", "Update every skill that has a newer version")
+	update.Run = func(c *cli.Command, args []string) error {
+		if !*all && len(args) == 0 {
+			return cli.Usagef(c, "give a skill name or --all")
+		}
+		installer, _ := skillsTools()
+		skillsUpdateCmd(installer, args, *all)
+		return nil
+	}
+
+	check := &cli.Command{
+		Name:      "check",
+		Short:     "Check skill requirements (all skills when no name is given)",
+		ArgsUsage: "[name...]",
+	}
+	runInstall := check.Flags().Bool("install", "", "Run the install hooks of skills with missing requirements")
+	check.Run = func(_ *cli.Command, args []string) error {
+		_, loader := skillsTools()
+		skillsCheckCmd(loader, args, *runInstall)
+		return nil
+	}
+
+	search := &cli.Command{
+		Name:      "search",
+		Short:     "Search the skills index",
+		ArgsUsage: "[query]",
+		Example:   "pepebot skills search pdf --category documents",
+	}
+	category := search.Flags().String("category", "", "", "name", "Only show skills in this category")
+	search.Run = func(_ *cli.Command, args []string) error {
+		installer, _ := skillsTools()
+		skillsSearchCmd(installer, strings.Join(args, " "), *category)
+		return nil
+	}
+
+	test := &cli.Command{
+		Name:  "test",
+		Short: "Run the eval suites shipped in skills' tests folder",
+		Long: `Runs the YAML suites in each skill's tests/ folder through the eval
+harness (see pepebot eval run). Without names, every installed skill with
+tests is run. Exits with status 1 when a case fails.`,
+		ArgsUsage: "[name...]",
+		Example: `pepebot skills test weather
+pepebot skills test weather --model mock/ --replay weather.cassette
+pepebot skills test --json`,
+	}
+	testAgent := test.Flags().String("agent", "a", "", "name", "Agent to test with (default: each suite's agent)")
+	testModel := test.Flags().String("model", "m", "", "model", "Model to test with (default: each suite's or agent's model)")
+	testCase := test.Flags().String("case", "c", "", "text", "Only run cases whose name contains text")
+	testVerbose := test.Flags().Bool("verbose", "v", "Show the replies of passed cases too")
+	testJSON := test.Flags().Bool("json", "", "Print the reports as JSON")
+	testRecord := test.Flags().String("record", "", "", "file", "Save the model responses to a cassette file")
+	testReplay := test.Flags().String("replay", "", "", "file", "Answer from a recorded cassette instead of the model")
+	testTemperature := test.Flags().Float("temperature", "", 0, "Override the suites' temperature")
+	testSeed := test.Flags().Int("seed", "", 0, "Override the suites' sampling seed")
+	test.Run = func(c *cli.Command, args []string) error {
+		if *testRecord != "" && *testReplay != "" {
+			return cli.Usagef(c, "--record and --replay cannot be used together")
+		}
+		sampling, err := samplingFlags(c, *testTemperature, *testSeed)
+		if err != nil {
+			return err
+		}
+		_, loader := skillsTools()
+		skillsTestCmd(loader, args, evalOptions{
+			agentName:  *testAgent,
+			model:      *testModel,
+			caseFilter: *testCase,
+			record:     *testRecord,
+			replay:     *testReplay,
+			sampling:   sampling,
+			verbose:    *testVerbose,
+			asJSON:     *testJSON,
+		})
+		return nil
+	}
+
+	cmd.AddCommand(
+		&cli.Command{
+			Name:  "list",
+			Short: "List installed skills",
+			Args:  cli.NoArgs,
+			Run: func(*cli.Command, []string) error {
+				installer, loader := skillsTools()
+				skillsListCmd(loader, installer)
+				return nil
+			},
+		},
+		install,
+		&cli.Command{
+			Name:  "install-builtin",
+			Short: "Install all builtin skills from pepebot-space/skills-builtin",
+			Args:  cli.NoArgs,
+			Run: func(*cli.Command, []string) error {
+				installer, _ := skillsTools()
+				skillsInstallBuiltinCmd(installer)
+				return nil
+			},
+		},
+		update,
+		check,
+		nameCommand("remove", "Remove an installed skill", func(name string) {
+			installer, _ := skillsTools()
+			skillsRemoveCmd(installer, name)
+		}, "uninstall"),
+		search,
+		nameCommand("show", "Show skill details", func(name string) {
+			_, loader := skillsTools()
+			skillsShowCmd(loader, name)
+		}),
+		test,
+	)
+	return cmd
+}
+
+func newWorkflowCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "workflow",
+		Short: "Manage and execute workflows",
+	}
+
+	run := &cli.Command{
+		Name:      "run",
+		Short:     "Execute a workflow from the workspace or a file",
+		ArgsUsage: "[name]",
+		Args:      cli.MaxArgs(1),
+		Example: `pepebot workflow run my_workflow --var device=emulator-5554 --var query=hello
+pepebot workflow run -f /tmp/test.json`,
+	}
+	runFile := run.Flags().String("file", "f", "", "path", "Load the workflow from a file instead of the workspace")
+	runVars := run.Flags().KeyValue("var", "", "Override a workflow variable")
+	run.Run = func(c *cli.Command, args []string) error {
+		name := firstArg(args)
+		if name == "" && *runFile == "" {
+			return cli.Usagef(c, "give a workflow name or --file")
+		}
+		workspace, cfg := workflowWorkspace()
+		workflowRunCmd(workspace, cfg, name, *runFile, runVars)
+		return nil
+	}
+
+	validate := &cli.Command{
+		Name:      "validate",
+		Short:     "Validate workflow structure",
+		ArgsUsage: "[name]",
+		Args:      cli.MaxArgs(1),
+	}
+	validateFile := validate.Flags().String("file", "f", "", "path", "Validate a file instead of a workspace workflow")
+	validate.Run = func(c *cli.Command, args []string) error {
+		name := firstArg(args)
+		if name == "" && *validateFile == "" {
+			return cli.Usagef(c, "give a workflow name or --file")
+		}
+		workspace, cfg := workflowWorkspace()
+		workflowValidateCmd(workspace, cfg, name, *validateFile)
+		return nil
+	}
+
+	create := &cli.Command{
+		Name:      "new",
+		Short:     "Create a workflow from a built-in template",
+		ArgsUsage: "[name]",
+		Args:      cli.MaxArgs(1),
+		Example:   "pepebot workflow new pixel_health --template device_health_check",
+	}
+	template := create.Flags().String("template", "t", "", "template", "Template to use (see 'pepebot workflow templates')")
+	templateVars := create.Flags().KeyValue("var", "", "Set a template variable without prompting")
+	create.Run = func(c *cli.Command, args []string) error {
+		if *template == "" {
+			workflowTemplatesCmd()
+			return cli.Usagef(c, "--template is required")
+		}
+		workspace, cfg := workflowWorkspace()
+		workflowNewCmd(workspace, cfg, firstArg(args), *template, templateVars)
+		return nil
+	}
+
+	export := &cli.Command{
+		Name:      "export",
+		Short:     "Bundle a workflow with its skills and screenshots",
+		ArgsUsage: "<name>",
+		Args:      cli.ExactArgs(1),
+		Example:   "pepebot workflow export login_test -o login_test.workflow.tar.gz",
+	}
+	output := export.Flags().String("output", "o", "", "file", "Bundle path (default: <name>.workflow.tar.gz)")
+	export.Run = func(_ *cli.Command, args []string) error {
+		workspace, cfg := workflowWorkspace()
+		workflowExportCmd(workspace, cfg, args[0], *output)
+		return nil
+	}
+
+	importCmd := &cli.Command{
+		Name:      "import",
+		Short:     "Import a workflow bundle",
+		ArgsUsage: "<file>",
+		Args:      cli.ExactArgs(1),
+		Example:   "pepebot workflow import login_test.workflow.tar.gz --on-conflict rename",
+	}
+	importAs := importCmd.Flags().String("as", "", "", "name", "Import under a different name")
+	onConflict := importCmd.Flags().Choice("on-conflict", "", "fail", []string{"fail", "skip", "overwrite", "rename"}, "What to do when the workflow already exists")
+	importCmd.Run = func(_ *cli.Command, args []string) error {
+		workspace, cfg := workflowWorkspace()
+		workflowImportCmd(workspace, cfg, args[0], workflow.ImportOptions{Name: *importAs, OnConflict: *onConflict})
+		return nil
+	}
+
+	cmd.AddCommand(
+		&cli.Command{
+			Name:  "list",
+			Short: "List all workflows in the workspace",
+			Args:  cli.NoArgs,
+			Run: func(*cli.Command, []string) error {
+				workspace, cfg := workflowWorkspace()
+				workflowListCmd(workspace, cfg)
+				return nil
+			},
+		},
+		nameCommand("show", "Show workflow details (steps, variables)", func(name string) {
+			workspace, cfg := workflowWorkspace()
+			workflowShowCmd(workspace, cfg, name)
+		}),
+		run,
+		validate,
+		create,
+		&cli.Command{
+			Name:  "templates",
+			Short: "List built-in templates",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { workflowTemplatesCmd(); return nil },
+		},
+		export,
+		importCmd,
+		nameCommand("delete", "Delete a workflow from the workspace", func(name string) {
+			workspace, cfg := workflowWorkspace()
+			workflowDeleteCmd(workspace, cfg, name)
+		}, "remove"),
+	)
+	return cmd
+}
+
+func newFeedsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "feeds",
+		Short: "Manage RSS/Atom feed subscriptions",
+	}
+
+	add := &cli.Command{
+		Name:      "add",
+		Short:     "Subscribe to an RSS/Atom feed",
+		ArgsUsage: "<url>",
+		Args:      cli.ExactArgs(1),
+	}
+	fs := add.Flags()
+	channel := fs.String("channel", "", "", "channel", "Channel for delivery (required)")
+	to := fs.String("to", "", "", "chat-id", "Chat ID for delivery (required)")
+	mode := fs.Choice("mode", "", "notify", []string{"notify", "agent"}, "Post new items, or hand them to an agent")
+	agentName := fs.String("agent", "a", "", "name", "Agent that handles new items in agent mode")
+	message := fs.String("message", "m", "", "text", "Instructions for the agent in agent mode")
+	title := fs.String("name", "n", "", "name", "Display name for the feed")
+	add.Run = func(_ *cli.Command, args []string) error {
+		feedsAddCmd(feedsStore(), feeds.Subscription{
+			URL:          args[0],
+			Channel:      *channel,
+			ChatID:       *to,
+			Mode:         *mode,
+			Agent:        *agentName,
+			Instructions: *message,
+			Title:        *title,
+		})
+		return nil
+	}
+
+	remove := &cli.Command{
+		Name:      "remove",
+		Short:     "Remove a subscription",
+		ArgsUsage: "<id|url>",
+		Args:      cli.ExactArgs(1),
+		Run: func(_ *cli.Command, args []string) error {
+			feedsRemoveCmd(feedsStore(), args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		&cli.Command{
+			Name:  "list",
+			Short: "List feed subscriptions",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { feedsListCmd(feedsStore()); return nil },
+		},
+		add,
+		remove,
+	)
+	return cmd
+}
+
+func newMaintenanceCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "maintenance",
+		Short: "Put the running gateway into read-only maintenance mode",
+		Long: "In maintenance mode the gateway answers messages with a maintenance notice\n" +
+			"instead of running the agents, and tools with side effects are disabled.\n" +
+			"The mode is kept across gateway restarts.",
+		Args: cli.NoArgs,
+		Run:  func(*cli.Command, []string) error { maintenanceCmd(nil, ""); return nil },
+	}
+
+	on := &cli.Command{
+		Name:      "on",
+		Short:     "Turn maintenance mode on",
+		ArgsUsage: "[message]",
+		Run: func(_ *cli.Command, args []string) error {
+			enabled := true
+			maintenanceCmd(&enabled, strings.Join(args, " "))
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		on,
+		&cli.Command{
+			Name:  "off",
+			Short: "Turn maintenance mode off",
+			Args:  cli.NoArgs,
+			Run: func(*cli.Command, []string) error {
+				enabled := false
+				maintenanceCmd(&enabled, "")
+				return nil
+			},
+		},
+		&cli.Command{
+			Name:  "status",
+			Short: "Show whether maintenance mode is on",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { maintenanceCmd(nil, ""); return nil },
+		},
+	)
+	return cmd
+}
+
+func newSessionsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "sessions",
+		Short: "Work with stored conversations",
+	}
+
+	search := &cli.Command{
+		Name:      "search",
+		Short:     "Search messages across all sessions",
+		ArgsUsage: "<query>",
+		Args:      cli.MinArgs(1),
+	}
+	limit := search.Flags().Int("limit", "n", 20, "Maximum number of sessions to show")
+	search.Run = func(c *cli.Command, args []string) error {
+		if *limit < 1 {
+			return cli.Usagef(c, "--limit must be a positive number")
+		}
+		sessionsSearchCmd(strings.Join(args, " "), *limit)
+		return nil
+	}
+
+	report := &cli.Command{
+		Name:      "report",
+		Short:     "Compile sessions into a Markdown, HTML or PDF report",
+		ArgsUsage: "<session>...",
+		Long: "Reports are saved to workspace/reports. PDF output needs wkhtmltopdf. With --send,\n" +
+			"the running gateway delivers the file to a chat.",
+		Args: cli.MinArgs(1),
+	}
+	title := report.Flags().String("title", "t", "", "text", "Report title")
+	format := report.Flags().Choice("format", "", "markdown", []string{"markdown", "html", "pdf"}, "Output format")
+	withTools := report.Flags().Bool("tools", "", "Include tool calls and their output")
+	images := report.Flags().StringSlice("image", "i", "path", "Add a screenshot or other image")
+	send := report.Flags().String("send", "", "", "channel:chat_id", "Deliver the report to a chat through the gateway")
+	report.Run = func(c *cli.Command, args []string) error {
+		if *send != "" && !strings.Contains(*send, ":") {
+			return cli.Usagef(c, "--send must look like telegram:12345")
+		}
+		sessionsReportCmd(args, reportOptions{title: *title, format: *format, tools: *withTools, images: *images, send: *send})
+		return nil
+	}
+
+	cmd.AddCommand(search, report)
+	return cmd
+}
+
+func newConfigCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "config",
+		Short: "Read and change settings in config.json",
+		Long: "Keys are dotted paths such as providers.openai.api_base. Values are parsed as\n" +
+			"JSON (true, 4, [\"a\"]) unless the setting is text. The previous file is kept\n" +
+			"as config.json.bak.",
+	}
+
+	get := &cli.Command{
+		Name:      "get",
+		Short:     "Print a setting, with defaults and environment overrides applied",
+		ArgsUsage: "<key>",
+		Args:      cli.ExactArgs(1),
+		Run:       func(_ *cli.Command, args []string) error { configGetCmd(args[0]); return nil },
+	}
+	set := &cli.Command{
+		Name:      "set",
+		Short:     "Change a setting",
+		ArgsUsage: "<key> <value>",
+		Args:      cli.ExactArgs(2),
+		Run:       func(_ *cli.Command, args []string) error { configSetCmd(args[0], args[1]); return nil },
+	}
+	unset := &cli.Command{
+		Name:      "unset",
+		Short:     "Remove a setting so its default applies",
+		ArgsUsage: "<key>",
+		Args:      cli.ExactArgs(1),
+		Run:       func(_ *cli.Command, args []string) error { configUnsetCmd(args[0]); return nil },
+	}
+
+	cmd.AddCommand(
+		get,
+		set,
+		unset,
+		&cli.Command{
+			Name:  "path",
+			Short: "Print the location of config.json",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { fmt.Println(getConfigPath()); return nil },
+		},
+	)
+	return cmd
+}
+
+func newAuditCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "audit",
+		Short: "Show the log of file changes and commands run by tools",
+		Args:  cli.NoArgs,
+	}
+	session := cmd.Flags().String("session", "s", "", "key", "Only entries of sessions whose key contains this")
+	tool := cmd.Flags().String("tool", "t", "", "name", "Only entries of this tool (e.g. exec, write_file)")
+	status := cmd.Flags().Choice("status", "", "", []string{"ok", "error", "denied"}, "Only entries with this status")
+	since := cmd.Flags().String("since", "", "", "duration", "Only entries newer than this (e.g. 24h, 30m)")
+	limit := cmd.Flags().Int("limit", "n", 50, "Maximum number of entries to show (newest)")
+	asJSON := cmd.Flags().Bool("json", "", "Print entries as JSON lines")
+	cmd.Run = func(c *cli.Command, _ []string) error {
+		filter := audit.Filter{Session: *session, Tool: *tool, Status: *status, Limit: *limit}
+		if *since != "" {
+			d, err := time.ParseDuration(*since)
+			if err != nil || d <= 0 {
+				return cli.Usagef(c, "--since must be a duration like 24h")
+			}
+			filter.Since = time.Now().Add(-d)
+		}
+		auditCmd(filter, *asJSON)
+		return nil
+	}
+	return cmd
+}
+
+func newAnalyticsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "analytics",
+		Short: "Summarize usage: channels, busy hours, tools, latency and sessions",
+		Long: "Computed from the usage log (~/.pepebot/usage.jsonl), which records every agent\n" +
+			"turn and summarization without message content, and from the session files.",
+		Args: cli.NoArgs,
+	}
+	days := cmd.Flags().Int("days", "d", 7, "Number of days to cover, ending now")
+	top := cmd.Flags().Int("top", "n", 10, "Number of tools and sessions to list")
+	asJSON := cmd.Flags().Bool("json", "", "Print the report as JSON")
+	cmd.Run = func(c *cli.Command, _ []string) error {
+		if *days < 1 {
+			return cli.Usagef(c, "--days must be a positive number")
+		}
+		if *top < 1 {
+			return cli.Usagef(c, "--top must be a positive number")
+		}
+		analyticsCmd(*days, *top, *asJSON)
+		return nil
+	}
+	return cmd
+}
+
+func newFeedbackCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "feedback",
+		Short: "Summarize 👍/👎 reactions to agent replies",
+		Long: "Computed from the feedback log (~/.pepebot/feedback.jsonl), which records the\n" +
+			"reactions Telegram and Discord users leave on agent replies. Only the last\n" +
+			"reaction of each user on a reply counts.",
+		Example: "  pepebot feedback --days 30\n  pepebot feedback --agent coder --json",
+		Args:    cli.NoArgs,
+	}
+	days := cmd.Flags().Int("days", "d", 7, "Number of days to cover, ending now")
+	agentName := cmd.Flags().String("agent", "a", "", "name", "Only count replies of this agent")
+	recent := cmd.Flags().Int("recent", "n", 10, "Number of 👎 replies to list")
+	asJSON := cmd.Flags().Bool("json", "", "Print the report as JSON")
+	cmd.Run = func(c *cli.Command, _ []string) error {
+		if *days < 1 {
+			return cli.Usagef(c, "--days must be a positive number")
+		}
+		if *recent < 0 {
+			return cli.Usagef(c, "--recent must not be negative")
+		}
+		feedbackCmd(*days, *agentName, *recent, *asJSON)
+		return nil
+	}
+	return cmd
+}
+
+func newListenCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "listen",
+		Short: "Talk to the agent through the host microphone and speakers",
+		Long: `Captures the microphone (arecord, sox or ffmpeg), transcribes what is said
+after the wake word and speaks the agent's reply (say, espeak-ng or espeak).
+After a reply, a follow-up question needs no wake word for a few seconds.
+Point providers.groq.api_base at a local Whisper server to keep it all local.`,
+		Args: cli.NoArgs,
+		Example: `pepebot listen
+pepebot listen --wake "hey jarvis" --agent home
+pepebot listen --no-wake --tts "piper -m en_US.onnx --output-raw | aplay -q -r 22050 -f S16_LE"`,
+	}
+	agentName := cmd.Flags().String("agent", "a", "", "name", "Agent to talk to (default: the default agent)")
+	session := cmd.Flags().String("session", "s", "listen:host", "key", "Session key")
+	wake := cmd.Flags().String("wake", "w", "", "phrase", "Wake word (default: voice.wake_word or \"hey pepe\")")
+	noWake := cmd.Flags().Bool("no-wake", "", "Answer everything that is said")
+	mic := cmd.Flags().String("mic", "", "", "command", "Command writing 16 kHz mono s16le PCM to stdout")
+	tts := cmd.Flags().String("tts", "", "", "command", "Command speaking the text on its stdin")
+	mute := cmd.Flags().Bool("mute", "", "Print replies without speaking them")
+	language := cmd.Flags().String("language", "l", "", "code", "Language of the speech (ISO-639-1), detected when empty")
+	cmd.Run = func(c *cli.Command, _ []string) error {
+		if *noWake && *wake != "" {
+			return cli.Usagef(c, "--wake and --no-wake cannot be used together")
+		}
+		listenCmd(listenOptions{
+			agentName:  *agentName,
+			sessionKey: *session,
+			wakeWord:   *wake,
+			noWake:     *noWake,
+			micCommand: *mic,
+			ttsCommand: *tts,
+			mute:       *mute,
+			language:   *language,
+		})
+		return nil
+	}
+	return cmd
+}
+
+func newRequestsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "requests",
+		Short: "List and replay logged /v1/chat/completions requests",
+		Long: "Requests are logged to ~/.pepebot/requests.jsonl when gateway.request_log is\n" +
+			"enabled. Replay runs a request again, on another agent or model, to compare replies.",
+	}
+
+	list := &cli.Command{
+		Name:  "list",
+		Short: "Show the latest logged requests",
+		Args:  cli.NoArgs,
+	}
+	limit := list.Flags().Int("limit", "n", 20, "Maximum number of requests to show (newest)")
+	list.Run = func(c *cli.Command, _ []string) error {
+		if *limit < 1 {
+			return cli.Usagef(c, "--limit must be a positive number")
+		}
+		requestsListCmd(*limit)
+		return nil
+	}
+
+	replay := &cli.Command{
+		Name:      "replay",
+		Short:     "Run a logged request again and compare the replies",
+		ArgsUsage: "<id|last>",
+		Long: "Agent requests run in a temporary session seeded with the request's earlier\n" +
+			"messages. Only read-only tools run unless --live is given, so commands, file\n" +
+			"changes and messages are not repeated. Inline images and files are not logged,\n" +
+			"so they are not replayed.",
+		Args: cli.ExactArgs(1),
+	}
+	agentName := replay.Flags().String("agent", "a", "", "name", "Agent to replay on (default: the agent that answered)")
+	model := replay.Flags().String("model", "m", "", "model", "Model to replay on (default: the agent's model)")
+	live := replay.Flags().Bool("live", "", "Run tools with side effects too (exec, file writes, messages)")
+	asJSON := replay.Flags().Bool("json", "", "Print the original and replayed replies as JSON")
+	replay.Run = func(_ *cli.Command, args []string) error {
+		requestsReplayCmd(args[0], *agentName, *model, *live, *asJSON)
+		return nil
+	}
+
+	cmd.AddCommand(list, replay)
+	return cmd
+}
+
+func newEvalCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "eval",
+		Short: "Test agents and workflows with suites of prompts and assertions",
+	}
+
+	run := &cli.Command{
+		Name:      "run",
+		Short:     "Run a suite and report which cases pass",
+		ArgsUsage: "<suite.yaml>",
+		Long: "Each case sends a prompt to the agent in a new session, or runs a workflow,\n" +
+			"and checks the reply with contains, not_contains, regex, json_schema,\n" +
+			"tool_called and tool_not_called. Tools run for real. Exits with status 1\n" +
+			"when a case fails.",
+		Example: `pepebot eval run evals/family.yaml
+pepebot eval run evals/family.yaml --model gpt-4o-mini --case shopping
+pepebot eval run evals/family.yaml --record evals/family.cassette.jsonl
+pepebot eval run evals/family.yaml --replay evals/family.cassette.jsonl`,
+		Args: cli.ExactArgs(1),
+	}
+	agentName := run.Flags().String("agent", "a", "", "name", "Agent to test (default: the suite's agent)")
+	model := run.Flags().String("model", "m", "", "model", "Model to test with (default: the suite's or agent's model)")
+	caseFilter := run.Flags().String("case", "c", "", "text", "Only run cases whose name contains text")
+	verbose := run.Flags().Bool("verbose", "v", "Show the replies of passed cases too")
+	asJSON := run.Flags().Bool("json", "", "Print the report as JSON")
+	record := run.Flags().String("record", "", "", "file", "Save the model responses to a cassette file")
+	replay := run.Flags().String("replay", "", "", "file", "Answer from a recorded cassette instead of the model")
+	temperature := run.Flags().Float("temperature", "", 0, "Override the suite's temperature")
+	seed := run.Flags().Int("seed", "", 0, "Override the suite's sampling seed")
+	run.Run = func(c *cli.Command, args []string) error {
+		if *record != "" && *replay != "" {
+			return cli.Usagef(c, "--record and --replay cannot be used together")
+		}
+		sampling, err := samplingFlags(c, *temperature, *seed)
+		if err != nil {
+			return err
+		}
+		evalRunCmd(args[0], evalOptions{
+			agentName:  *agentName,
+			model:      *model,
+			caseFilter: *caseFilter,
+			record:     *record,
+			replay:     *replay,
+			sampling:   sampling,
+			verbose:    *verbose,
+			asJSON:     *asJSON,
+		})
+		return nil
+	}
+
+	cmd.AddCommand(run)
+	return cmd
+}
+
+func newSecretsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "secrets",
+		Short: "Manage encrypted secrets for workflows ({{secret:NAME}})",
+		Long: "Workflows reference secrets in tool args as {{secret:NAME}}. Names not in the\n" +
+			"store fall back to the environment variable of the same name.",
+	}
+
+	set := &cli.Command{
+		Name:      "set",
+		Short:     "Store a secret (prompts for the value when omitted)",
+		ArgsUsage: "<NAME> [value]",
+		Args:      cli.MinArgs(1),
+		Run: func(_ *cli.Command, args []string) error {
+			secretsSetCmd(secretsStore(), args[0], args[1:])
+			return nil
+		},
+	}
+
+	deleteCmd := nameCommand("delete", "Remove a secret", func(name string) {
+		secretsDeleteCmd(secretsStore(), name)
+	}, "remove")
+	deleteCmd.ArgsUsage = "<NAME>"
+
+	cmd.AddCommand(
+		&cli.Command{
+			Name:  "list",
+			Short: "List stored secret names",
+			Args:  cli.NoArgs,
+			Run:   func(*cli.Command, []string) error { secretsListCmd(secretsStore()); return nil },
+		},
+		set,
+		deleteCmd,
+	)
+	return cmd
+}
+
+func newUpdateCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "update",
+		Short: "Update pepebot binary and builtin skills",
+		Long: "Downloads the latest release of the channel, shows its release notes and\n" +
+			"verifies the archive's SHA256 checksum (and signature, when the build has a\n" +
+			"signing key) before replacing the binary. The previous binary is kept as\n" +
+			"pepebot.bak for --rollback.",
+		Example: `pepebot update
+pepebot update --channel beta --only-binary
+pepebot update --rollback`,
+		Args: cli.NoArgs,
+	}
+	fs := cmd.Flags()
+	onlyBinary := fs.Bool("only-binary", "", "Update only the binary")
+	onlySkills := fs.Bool("only-skills", "", "Update only builtin skills and workspace templates")
+	channel := fs.Choice("channel", "", selfupdate.ChannelStable, []string{selfupdate.ChannelStable, selfupdate.ChannelBeta}, "Release channel")
+	rollback := fs.Bool("rollback", "", "Restore the binary that the last update replaced")
+	yes := fs.Bool("yes", "y", "Install without asking for confirmation")
+	cmd.Run = func(cmd *cli.Command, _ []string) error {
+		if *rollback && (*onlyBinary || *onlySkills || fs.Changed("channel")) {
+			return cli.Usagef(cmd, "--rollback cannot be combined with other update options")
+		}
+		updateCmd(updateOptions{
+			onlyBinary: *onlyBinary,
+			onlySkills: *onlySkills,
+			channel:    *channel,
+			rollback:   *rollback,
+			yes:        *yes,
+		})
+		return nil
+	}
+	return cmd
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// samplingFlags returns the overrides of the --temperature and --seed flags
+// that were given
+func samplingFlags(cmd *cli.Command, temperature float64, seed int) (*providers.Sampling, error) {
+	s := &providers.Sampling{}
+	if cmd.Flags().Changed("temperature") {
+		s.Temperature = &temperature
+	}
+	if cmd.Flags().Changed("seed") {
+		s.Seed = &seed
+	}
+	if err := s.Check(); err != nil {
+		return nil, cli.Usagef(cmd, "%v", err)
+	}
+	return s, nil
+}
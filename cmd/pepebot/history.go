@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+// historyPageSize is the number of exchanges /history shows per page
+const historyPageSize = 10
+
+// chatExchange is one user message and the answer to it
+type chatExchange struct {
+	User      string
+	Assistant string
+}
+
+// sessionExchanges pairs the user messages and answers of a session,
+// skipping tool calls and tool results
+func sessionExchanges(sm *session.SessionManager, key string) []chatExchange {
+	var exchanges []chatExchange
+	for _, msg := range sm.GetHistory(key) {
+		content, _ := msg.Content.(string)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		switch msg.Role {
+		case "user":
+			exchanges = append(exchanges, chatExchange{User: content})
+		case "assistant":
+			if n := len(exchanges); n > 0 && exchanges[n-1].Assistant == "" {
+				exchanges[n-1].Assistant = content
+			} else {
+				exchanges = append(exchanges, chatExchange{Assistant: content})
+			}
+		}
+	}
+	return exchanges
+}
+
+// printHistory lists one page of exchanges, page 1 being the most recent
+func printHistory(sm *session.SessionManager, key string, page int) {
+	exchanges := sessionExchanges(sm, key)
+	if len(exchanges) == 0 {
+		fmt.Printf("\n%s No messages in session %s yet\n\n", logo, key)
+		return
+	}
+	pages := (len(exchanges) + historyPageSize - 1) / historyPageSize
+	if page > pages {
+		fmt.Printf("\n%s Session %s has only %d page(s) of history\n\n", logo, key, pages)
+		return
+	}
+
+	end := len(exchanges) - (page-1)*historyPageSize
+	start := end - historyPageSize
+	if start < 0 {
+		start = 0
+	}
+	fmt.Printf("\n%s History of %s (page %d of %d, newest last)\n", logo, key, page, pages)
+	if summary := sm.GetSummary(key); summary != "" && page == pages {
+		fmt.Printf("  Earlier messages are summarized: %s\n", truncateLine(summary, 160))
+	}
+	for i := start; i < end; i++ {
+		fmt.Printf("\n  [%d] You: %s\n", i+1, truncateLine(exchanges[i].User, 160))
+		if exchanges[i].Assistant != "" {
+			fmt.Printf("      %s %s\n", logo, truncateLine(exchanges[i].Assistant, 160))
+		}
+	}
+	if page < pages {
+		fmt.Printf("\n  /history %d for older messages\n", page+1)
+	}
+	fmt.Println()
+}
+
+// recentSessions returns all stored sessions, most recently updated first
+func recentSessions(sm *session.SessionManager) []*session.Session {
+	sessions := sm.ListSessions("")
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions
+}
+
+func printSessions(sm *session.SessionManager, current string) {
+	sessions := recentSessions(sm)
+	if len(sessions) == 0 {
+		fmt.Printf("\n%s No stored sessions\n\n", logo)
+		return
+	}
+	fmt.Printf("\n%s Sessions (most recent first):\n", logo)
+	for i, s := range sessions {
+		mark := " "
+		if s.Key == current {
+			mark = "*"
+		}
+		fmt.Printf("  %s %2d. %-30s %3d messages  %s\n", mark, i+1, s.Key, len(s.Messages), s.Updated.Format("2006-01-02 15:04"))
+	}
+	fmt.Println("\n  /sessions <n|key> to switch")
+	fmt.Println()
+}
+
+// resolveSessionArg turns the argument of /sessions into a session key: a
+// number picks from the listing, anything else is used as the key
+func resolveSessionArg(sm *session.SessionManager, arg string) string {
+	if n, err := strconv.Atoi(arg); err == nil {
+		sessions := recentSessions(sm)
+		if n >= 1 && n <= len(sessions) {
+			return sessions[n-1].Key
+		}
+	}
+	return arg
+}
+
+// transcriptExport is the JSON form of /save
+type transcriptExport struct {
+	Session  string              `json:"session"`
+	Exported time.Time           `json:"exported"`
+	Summary  string              `json:"summary,omitempty"`
+	Messages []transcriptMessage `json:"messages"`
+}
+
+type transcriptMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// saveTranscript writes the conversation to path, as JSON for .json files
+// and Markdown otherwise. It returns the number of messages written.
+func saveTranscript(sm *session.SessionManager, key, path string) (int, error) {
+	export := transcriptExport{Session: key, Exported: time.Now(), Summary: sm.GetSummary(key)}
+	for _, ex := range sessionExchanges(sm, key) {
+		if ex.User != "" {
+			export.Messages = append(export.Messages, transcriptMessage{Role: "user", Content: ex.User})
+		}
+		if ex.Assistant != "" {
+			export.Messages = append(export.Messages, transcriptMessage{Role: "assistant", Content: ex.Assistant})
+		}
+	}
+	if len(export.Messages) == 0 {
+		return 0, fmt.Errorf("session %s has no messages", key)
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, _ = json.MarshalIndent(export, "", "  ")
+	} else {
+		var b strings.Builder
+		fmt.Fprintf(&b, "# Session %s\n\nExported %s\n", key, export.Exported.Format("2006-01-02 15:04"))
+		if export.Summary != "" {
+			fmt.Fprintf(&b, "\n## Summary of earlier messages\n\n%s\n", export.Summary)
+		}
+		for _, m := range export.Messages {
+			title := "You"
+			if m.Role == "assistant" {
+				title = "Pepebot"
+			}
+			fmt.Fprintf(&b, "\n## %s\n\n%s\n", title, m.Content)
+		}
+		data = []byte(b.String())
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, err
+	}
+	return len(export.Messages), nil
+}
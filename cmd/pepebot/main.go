@@ -7,33 +7,60 @@
 package main
 
 import (
-	"archive/tar"
 	"bufio"
-	"compress/gzip"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/analytics"
+	"github.com/pepebot-space/pepebot/pkg/audit"
+	"github.com/pepebot-space/pepebot/pkg/batch"
+	"github.com/pepebot-space/pepebot/pkg/broadcast"
 	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/calendar"
 	"github.com/pepebot-space/pepebot/pkg/channels"
+	"github.com/pepebot-space/pepebot/pkg/cli"
 	"github.com/pepebot-space/pepebot/pkg/config"
 	"github.com/pepebot-space/pepebot/pkg/cron"
+	"github.com/pepebot-space/pepebot/pkg/digest"
+	"github.com/pepebot-space/pepebot/pkg/doctor"
+	"github.com/pepebot-space/pepebot/pkg/eval"
+	"github.com/pepebot-space/pepebot/pkg/feedback"
+	"github.com/pepebot-space/pepebot/pkg/feeds"
 	"github.com/pepebot-space/pepebot/pkg/gateway"
+	"github.com/pepebot-space/pepebot/pkg/gitsync"
 	"github.com/pepebot-space/pepebot/pkg/heartbeat"
+	"github.com/pepebot-space/pepebot/pkg/instance"
+	"github.com/pepebot-space/pepebot/pkg/journal"
 	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/merge"
+	"github.com/pepebot-space/pepebot/pkg/middleware"
 	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/report"
+	"github.com/pepebot-space/pepebot/pkg/rules"
+	"github.com/pepebot-space/pepebot/pkg/secrets"
+	"github.com/pepebot-space/pepebot/pkg/selfupdate"
+	"github.com/pepebot-space/pepebot/pkg/session"
 	"github.com/pepebot-space/pepebot/pkg/skills"
 	"github.com/pepebot-space/pepebot/pkg/tools"
+	"github.com/pepebot-space/pepebot/pkg/tui"
 	"github.com/pepebot-space/pepebot/pkg/voice"
 	"github.com/pepebot-space/pepebot/pkg/workflow"
 )
@@ -76,156 +103,22 @@ func copyDirectory(src, dst string) error {
 }
 
 func main() {
+	root := newRootCommand()
 	if len(os.Args) < 2 {
-		printHelp()
+		root.PrintHelp()
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
-
-	switch command {
-	case "onboard":
-		onboard()
-	case "agent":
-		// Check for subcommands
-		if len(os.Args) >= 3 {
-			subcommand := os.Args[2]
-			switch subcommand {
-			case "list":
-				agentListCmd()
-				return
-			case "register":
-				agentRegisterCmd()
-				return
-			case "remove", "unregister":
-				agentRemoveCmd()
-				return
-			case "enable":
-				agentEnableCmd()
-				return
-			case "disable":
-				agentDisableCmd()
-				return
-			case "show":
-				agentShowCmd()
-				return
-			case "help":
-				agentHelpCmd()
-				return
-			}
-		}
-		// Default to chat mode
-		agentCmd()
-	case "gateway":
-		gatewayCmd()
-	case "status":
-		statusCmd()
-	case "cron":
-		cronCmd()
-	case "skills":
-		if len(os.Args) < 3 {
-			skillsHelp()
-			return
-		}
-
-		subcommand := os.Args[2]
-
-		cfg, err := loadConfig()
-		if err != nil {
-			fmt.Printf("Error loading config: %v\n", err)
-			os.Exit(1)
+	if err := root.Execute(os.Args[1:]); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		var usageErr *cli.UsageError
+		if errors.As(err, &usageErr) {
+			fmt.Printf("Run '%s --help' for usage.\n", usageErr.Cmd.Path())
 		}
-
-		workspace := cfg.WorkspacePath()
-		installer := skills.NewSkillInstaller(workspace)
-		skillsLoader := skills.NewSkillsLoader(workspace, "")
-
-		switch subcommand {
-		case "list":
-			skillsListCmd(skillsLoader)
-		case "install":
-			skillsInstallCmd(installer)
-		case "remove", "uninstall":
-			if len(os.Args) < 4 {
-				fmt.Println("Usage: pepebot skills remove <skill-name>")
-				return
-			}
-			skillsRemoveCmd(installer, os.Args[3])
-		case "install-builtin":
-			skillsInstallBuiltinCmd(installer)
-		case "search":
-			skillsSearchCmd(installer)
-		case "show":
-			if len(os.Args) < 4 {
-				fmt.Println("Usage: pepebot skills show <skill-name>")
-				return
-			}
-			skillsShowCmd(skillsLoader, os.Args[3])
-		default:
-			fmt.Printf("Unknown skills command: %s\n", subcommand)
-			skillsHelp()
-		}
-	case "workflow":
-		workflowCmd()
-	case "update":
-		updateCmd(os.Args[2:])
-	case "version", "--version", "-v":
-		fmt.Printf("%s pepebot v%s\n", logo, version)
-	default:
-		fmt.Printf("Unknown command: %s\n", command)
-		printHelp()
 		os.Exit(1)
 	}
 }
 
-func printHelp() {
-	fmt.Println("\n     ___")
-	fmt.Println("    (o o)")
-	fmt.Println("   (  >  )")
-	fmt.Println("   /|   |\\")
-	fmt.Println("  (_|   |_)")
-	fmt.Printf("\n  🐸 PEPEBOT v%s\n", version)
-	fmt.Println("  Personal AI Assistant")
-	fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("\nUsage: pepebot <command> [options]\n")
-	fmt.Println("Commands:")
-	fmt.Println("  onboard     Initialize pepebot configuration and workspace")
-	fmt.Println("  agent       Interact with the agent directly")
-	fmt.Println("              Options:")
-	fmt.Println("                -a, --agent <name>    Use specific agent (default: default agent)")
-	fmt.Println("                -m, --message <text>  Send a single message")
-	fmt.Println("                [message]             Positional one-shot message")
-	fmt.Println("                -s, --session <key>   Session key for context")
-	fmt.Println("                -v, --verbose         Enable verbose logging (DEBUG)")
-	fmt.Println("              Subcommands:")
-	fmt.Println("                list                  List all registered agents")
-	fmt.Println("                register              Register a new agent")
-	fmt.Println("                remove                Remove an agent")
-	fmt.Println("                enable/disable        Enable or disable an agent")
-	fmt.Println("                show                  Show agent details")
-	fmt.Println("                help                  Show agent management help")
-	fmt.Println("  gateway     Start pepebot gateway")
-	fmt.Println("              Options:")
-	fmt.Println("                -v, --verbose    Enable verbose logging (show DEBUG logs)")
-	fmt.Println("  status      Show pepebot status")
-	fmt.Println("  cron        Manage scheduled tasks")
-	fmt.Println("  skills      Manage skills (install, list, remove)")
-	fmt.Println("  workflow    Manage and execute workflows")
-	fmt.Println("              Subcommands:")
-	fmt.Println("                list                        List all workflows")
-	fmt.Println("                show <name>                 Show workflow details")
-	fmt.Println("                run <name> [options]        Execute a workflow")
-	fmt.Println("                  -f, --file <path>         Run from a file instead of workspace")
-	fmt.Println("                  --var key=value           Override a workflow variable (repeatable)")
-	fmt.Println("                delete <name>               Delete a workflow")
-	fmt.Println("                validate <name> [-f <path>] Validate workflow structure")
-	fmt.Println("  update      Update pepebot binary and builtin skills")
-	fmt.Println("                --only-binary               Update only the binary")
-	fmt.Println("                --only-skills               Update only builtin skills")
-	fmt.Println("  version     Show version information")
-	fmt.Println("")
-}
-
 func onboard() {
 	configPath := getConfigPath()
 
@@ -242,7 +135,7 @@ func onboard() {
 	}
 
 	// Welcome banner with ASCII art
-	fmt.Println("\n")
+	fmt.Print("\n\n")
 	fmt.Println("     ___")
 	fmt.Println("    (o o)")
 	fmt.Println("   (  >  )")
@@ -251,7 +144,7 @@ func onboard() {
 	fmt.Println("")
 	fmt.Println("  🐸 PEPEBOT SETUP WIZARD")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("Let's get you started with your AI assistant.\n")
+	fmt.Print("Let's get you started with your AI assistant.\n\n")
 
 	reader := bufio.NewReader(os.Stdin)
 	cfg := config.DefaultConfig()
@@ -712,8 +605,10 @@ func onboard() {
 	fmt.Println("\n🎉 Happy chatting with Pepebot!")
 }
 
-func createWorkspaceTemplates(workspace string) {
-	templates := map[string]string{
+// workspaceTemplates are the instruction files onboarding puts in the
+// workspace; "pepebot update" refreshes them
+func workspaceTemplates() map[string]string {
+	return map[string]string{
 		"AGENTS.md": `# Agent Instructions
 
 You are a helpful AI assistant. Be concise, accurate, and friendly.
@@ -725,7 +620,7 @@ You are a helpful AI assistant. Be concise, accurate, and friendly.
 - Use tools to help accomplish tasks
 - Remember important information in your memory files
 - Be proactive and helpful
-- Learn from user feedback
+- Learn from user feedback, including the notes about disliked replies in memory/MEMORY.md
 `,
 		"SOUL.md": `# Soul
 
@@ -928,11 +823,20 @@ Discussions: https://github.com/pepebot-space/pepebot/discussions
 - Pepebot
 `,
 	}
+}
 
+// templateBasePath is where the upstream version of a template is kept for
+// three-way merges
+func templateBasePath(workspace, filename string) string {
+	return filepath.Join(workspace, merge.UpstreamDir, "templates", filename)
+}
+
+func createWorkspaceTemplates(workspace string) {
+	templates := workspaceTemplates()
 	for filename, content := range templates {
 		filePath := filepath.Join(workspace, filename)
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			os.WriteFile(filePath, []byte(content), 0644)
+			merge.RefreshFile(filePath, templateBasePath(workspace, filename), []byte(content), merge.Options{})
 			fmt.Printf("  Created %s\n", filename)
 		}
 	}
@@ -976,60 +880,203 @@ This file stores important information that should persist across sessions.
 	for filename, content := range templates {
 		filePath := filepath.Join(workspace, filename)
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			os.WriteFile(filePath, []byte(content), 0644)
+			merge.RefreshFile(filePath, templateBasePath(workspace, filename), []byte(content), merge.Options{})
 			fmt.Printf("  Created %s\n", filename)
 		}
 	}
 }
 
-func agentCmd() {
-	message := ""
-	sessionKey := "cli:default"
-	agentName := "" // empty = use default agent
-	verbose := false
+// refreshWorkspaceTemplates brings the workspace templates up to date with
+// this binary, merging in the user's edits
+func refreshWorkspaceTemplates(workspace string) {
+	templates := workspaceTemplates()
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	args := os.Args[2:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "-m", "--message":
-			if i+1 < len(args) {
-				message = args[i+1]
-				i++
-			}
-		case "-v", "--verbose":
-			verbose = true
-		case "-s", "--session":
-			if i+1 < len(args) {
-				sessionKey = args[i+1]
-				i++
-			}
-		case "-a", "--agent":
-			if i+1 < len(args) {
-				agentName = args[i+1]
-				i++
-			}
-		default:
-			if !strings.HasPrefix(args[i], "-") && message == "" {
-				message = args[i]
-			}
+	for _, name := range names {
+		path := filepath.Join(workspace, name)
+		outcome, err := merge.RefreshFile(path, templateBasePath(workspace, name), []byte(templates[name]), merge.Options{})
+		switch {
+		case err != nil:
+			fmt.Printf("  ✗ %s: %v\n", name, err)
+		case outcome == merge.Created:
+			fmt.Printf("  ✓ Created %s\n", name)
+		case outcome == merge.Updated:
+			fmt.Printf("  ✓ Updated %s\n", name)
+		case outcome == merge.Merged:
+			fmt.Printf("  ⇄ Updated %s, keeping your edits\n", name)
+		case outcome == merge.Conflict:
+			fmt.Printf("  ! %s: your edits conflict with the update; merged version with conflict markers saved as %s.new\n", name, name)
+		case outcome == merge.Kept:
+			fmt.Printf("  ! %s differs from the template; the new version is saved as %s.new for review\n", name, name)
 		}
 	}
+}
 
-	if verbose {
+// agentChatOptions are the flags of "pepebot agent" chat mode
+type agentChatOptions struct {
+	message    string
+	sessionKey string
+	agentName  string // empty = use default agent
+	forkKey    string
+	files      []string // attachments of a one-shot message
+	tui        bool
+	jsonOutput bool
+	verbose    bool
+	sampling   *providers.Sampling // --temperature and --seed
+}
+
+// maxStdinBytes caps piped input attached to a one-shot message
+const maxStdinBytes = 1 << 20
+
+// agentRunResult is what `pepebot agent --output json` prints
+type agentRunResult struct {
+	Response   string             `json:"response"`
+	Agent      string             `json:"agent,omitempty"`
+	Model      string             `json:"model,omitempty"`
+	Session    string             `json:"session"`
+	Usage      agentRunUsage      `json:"usage"`
+	ToolCalls  []agentRunToolCall `json:"tool_calls"`
+	DurationMs int64              `json:"duration_ms"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// agentRunUsage is the token usage of every LLM call in the run
+type agentRunUsage struct {
+	Requests         int `json:"requests"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type agentRunToolCall struct {
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"` // truncated to 200 characters
+	Failed     bool   `json:"failed,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// readPipedStdin returns stdin when it is a pipe or file rather than a
+// terminal, so `cat log.txt | pepebot agent -m "summarize"` works
+func readPipedStdin() (string, error) {
+	if readline.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, maxStdinBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxStdinBytes {
+		return "", fmt.Errorf("stdin is larger than %d KB", maxStdinBytes/1024)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// attachStdin adds piped input to the message as context. Without a message
+// the input itself is the message.
+func attachStdin(message, input string) string {
+	if strings.TrimSpace(input) == "" {
+		return message
+	}
+	if message == "" {
+		return input
+	}
+	return fmt.Sprintf("%s\n\n<stdin>\n%s\n</stdin>", message, input)
+}
+
+// resolveAttachments checks the files given with --file and returns them as
+// absolute paths for the media pipeline. URLs are passed through.
+func resolveAttachments(files []string) ([]string, error) {
+	var media []string
+	for _, file := range files {
+		if strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+			media = append(media, file)
+			continue
+		}
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", file)
+		}
+		media = append(media, path)
+	}
+	return media, nil
+}
+
+// usageSince returns the token usage recorded after before was taken
+func usageSince(before agent.UsageStats) agentRunUsage {
+	now := agent.UsageToday()
+	if now.Date != before.Date {
+		before = agent.UsageStats{}
+	}
+	return agentRunUsage{
+		Requests:         now.Requests - before.Requests,
+		PromptTokens:     now.PromptTokens - before.PromptTokens,
+		CompletionTokens: now.CompletionTokens - before.CompletionTokens,
+		TotalTokens:      now.TotalTokens - before.TotalTokens,
+	}
+}
+
+// printAgentRunError reports a failed one-shot run in the requested format
+// and exits
+func printAgentRunError(opts agentChatOptions, format string, args ...interface{}) {
+	if opts.jsonOutput {
+		data, _ := json.MarshalIndent(agentRunResult{Session: opts.sessionKey, ToolCalls: []agentRunToolCall{}, Error: fmt.Sprintf(format, args...)}, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("Error "+format+"\n", args...)
+	}
+	os.Exit(1)
+}
+
+func agentCmd(opts agentChatOptions) {
+	message := opts.message
+	sessionKey := opts.sessionKey
+	agentName := opts.agentName
+	forkKey := opts.forkKey
+
+	if opts.verbose {
 		logger.SetLevel(logger.DEBUG)
-		fmt.Println("✓ Verbose logging enabled")
+		if !opts.jsonOutput {
+			fmt.Println("✓ Verbose logging enabled")
+		}
+	}
+
+	if !opts.tui {
+		input, err := readPipedStdin()
+		if err != nil {
+			printAgentRunError(opts, "reading stdin: %v", err)
+		}
+		message = attachStdin(message, input)
+	}
+	if opts.jsonOutput && message == "" {
+		printAgentRunError(opts, "using --output json: a message is required (-m, arguments or piped stdin)")
+	}
+	if len(opts.files) > 0 && message == "" {
+		printAgentRunError(opts, "using --file: a message is required (-m, arguments or piped stdin)")
+	}
+	media, err := resolveAttachments(opts.files)
+	if err != nil {
+		printAgentRunError(opts, "attaching file: %v", err)
 	}
 
 	cfg, err := loadConfig()
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		os.Exit(1)
+		printAgentRunError(opts, "loading config: %v", err)
 	}
 
 	provider, err := providers.CreateProvider(cfg)
 	if err != nil {
-		fmt.Printf("Error creating provider: %v\n", err)
-		os.Exit(1)
+		printAgentRunError(opts, "creating provider: %v", err)
 	}
 
 	bus := bus.NewMessageBus()
@@ -1037,8 +1084,7 @@ func agentCmd() {
 	// Create agent manager for multi-agent support
 	agentManager, err := agent.NewAgentManager(cfg, bus, provider)
 	if err != nil {
-		fmt.Printf("Error creating agent manager: %v\n", err)
-		os.Exit(1)
+		printAgentRunError(opts, "creating agent manager: %v", err)
 	}
 
 	// Get the specific agent or default agent
@@ -1046,53 +1092,168 @@ func agentCmd() {
 	if agentName != "" {
 		agentLoop, err = agentManager.GetOrCreateAgent(agentName)
 		if err != nil {
-			fmt.Printf("Error getting agent '%s': %v\n", agentName, err)
-			os.Exit(1)
+			printAgentRunError(opts, "getting agent '%s': %v", agentName, err)
+		}
+		if !opts.jsonOutput {
+			fmt.Printf("Using agent: %s\n", agentName)
 		}
-		fmt.Printf("Using agent: %s\n", agentName)
 	} else {
 		agentLoop, err = agentManager.GetDefaultAgent()
 		if err != nil {
-			fmt.Printf("Error getting default agent: %v\n", err)
-			os.Exit(1)
+			printAgentRunError(opts, "getting default agent: %v", err)
+		}
+	}
+	agentLoop.SetSampling(opts.sampling)
+
+	// Continue in a copy of the session, leaving the original untouched
+	if forkKey != "" {
+		fork, err := agentLoop.Sessions().Fork(sessionKey, forkKey)
+		if err != nil {
+			printAgentRunError(opts, "forking session: %v", err)
+		}
+		if !opts.jsonOutput {
+			fmt.Printf("✓ Forked session '%s' into '%s' (%d messages)\n", sessionKey, fork.Key, len(fork.Messages))
 		}
+		sessionKey = fork.Key
 	}
 
-	if message != "" {
+	if opts.jsonOutput {
+		agentRunJSON(agentLoop, message, media, sessionKey)
+	} else if message != "" {
 		ctx := context.Background()
-		response, err := agentLoop.ProcessDirect(ctx, message, nil, sessionKey)
+		response, err := agentLoop.ProcessDirect(ctx, message, media, sessionKey)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("\n%s %s\n", logo, response)
+	} else if opts.tui {
+		// Log lines would tear the screen; send them nowhere while it is up
+		log.SetOutput(io.Discard)
+		err := tui.Run(context.Background(), &agentTUIBackend{agentLoop: agentLoop}, sessionKey)
+		log.SetOutput(os.Stderr)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	} else {
 		fmt.Printf("%s Interactive mode (Ctrl+C to exit)\n\n", logo)
 		interactiveMode(agentLoop, sessionKey)
 	}
 }
 
-func handleCLICommand(input string, agentLoop *agent.AgentLoop, sessionKey string) bool {
+// agentRunJSON runs one message and prints the result for scripts
+func agentRunJSON(agentLoop *agent.AgentLoop, message string, media []string, sessionKey string) {
+	result := agentRunResult{
+		Agent:     agentLoop.AgentName(),
+		Model:     agentLoop.Model(),
+		Session:   sessionKey,
+		ToolCalls: []agentRunToolCall{},
+	}
+	var mu sync.Mutex
+	ctx := agent.WithToolObserver(context.Background(), func(a agent.ToolActivity) {
+		if !a.Done {
+			return
+		}
+		mu.Lock()
+		result.ToolCalls = append(result.ToolCalls, agentRunToolCall{
+			Name:       a.Name,
+			Arguments:  a.Args,
+			Failed:     a.Failed,
+			DurationMs: a.Duration.Milliseconds(),
+		})
+		mu.Unlock()
+	})
+
+	before := agent.UsageToday()
+	start := time.Now()
+	response, err := agentLoop.ProcessDirect(ctx, message, media, sessionKey)
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Usage = usageSince(before)
+	result.Response = response
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	mu.Lock()
+	data, _ := json.MarshalIndent(result, "", "  ")
+	mu.Unlock()
+	fmt.Println(string(data))
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// handleCLICommand runs a slash command of interactive mode. /sessions can
+// switch *sessionKey to another session.
+func handleCLICommand(input string, agentLoop *agent.AgentLoop, sessionKey *string) bool {
 	parts := strings.Fields(input)
 	command := strings.ToLower(parts[0])
+	arg := strings.TrimSpace(strings.TrimPrefix(input, parts[0]))
 
 	switch command {
 	case "/new":
-		agentLoop.ClearSession(sessionKey)
+		agentLoop.ClearSession(*sessionKey)
 		fmt.Printf("\n%s Session cleared. Starting fresh conversation.\n\n", logo)
 		return true
 	case "/help":
 		fmt.Printf("\n%s Available commands:\n", logo)
-		fmt.Println("  /new    - Clear session, start fresh conversation")
-		fmt.Println("  /help   - Show this help message")
-		fmt.Println("  /status - Show agent & session info")
-		fmt.Println("  exit    - Exit interactive mode")
+		fmt.Println("  /new      - Clear session, start fresh conversation")
+		fmt.Println("  /help     - Show this help message")
+		fmt.Println("  /status   - Show agent & session info")
+		fmt.Println("  /history  - List recent exchanges, /history 2 for older ones")
+		fmt.Println("  /sessions - List sessions, /sessions <n|key> to switch")
+		fmt.Println("  /save     - Export the transcript to a file (.md or .json)")
+		fmt.Println("  /pin      - Pin a fact to this session")
+		fmt.Println("  /pins     - List pinned facts")
+		fmt.Println("  /unpin    - Remove a pin by number, or all")
+		fmt.Println("  /outcome  - Tag how this conversation went for prompt experiments")
+		fmt.Println("  exit      - Exit interactive mode")
 		fmt.Println()
 		return true
 	case "/status":
 		fmt.Printf("\n%s Agent: %s\n", logo, agentLoop.AgentName())
 		fmt.Printf("  Model: %s\n", agentLoop.Model())
-		fmt.Printf("  Session: %s\n\n", sessionKey)
+		fmt.Printf("  Session: %s\n\n", *sessionKey)
+		return true
+	case "/history":
+		page := 1
+		if arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				fmt.Printf("\n%s Usage: /history [page]\n\n", logo)
+				return true
+			}
+			page = n
+		}
+		printHistory(agentLoop.Sessions(), *sessionKey, page)
+		return true
+	case "/sessions":
+		if arg == "" {
+			printSessions(agentLoop.Sessions(), *sessionKey)
+			return true
+		}
+		key := resolveSessionArg(agentLoop.Sessions(), arg)
+		*sessionKey = key
+		fmt.Printf("\n%s Switched to session %s (%d messages)\n\n", logo, key, len(agentLoop.Sessions().GetHistory(key)))
+		return true
+	case "/save":
+		if arg == "" {
+			fmt.Printf("\n%s Usage: /save <file.md|file.json>\n\n", logo)
+			return true
+		}
+		n, err := saveTranscript(agentLoop.Sessions(), *sessionKey, arg)
+		if err != nil {
+			fmt.Printf("\n%s Error saving transcript: %v\n\n", logo, err)
+			return true
+		}
+		fmt.Printf("\n%s Saved %d messages to %s\n\n", logo, n, arg)
+		return true
+	case "/pin", "/pins", "/unpin":
+		fmt.Printf("\n%s %s\n\n", logo, agent.PinCommand(agentLoop.Sessions(), *sessionKey, command, arg))
+		return true
+	case "/outcome":
+		fmt.Printf("\n%s %s\n\n", logo, agentLoop.OutcomeCommand(*sessionKey, arg))
 		return true
 	}
 
@@ -1140,7 +1301,7 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 		}
 
 		if strings.HasPrefix(input, "/") {
-			if handleCLICommand(input, agentLoop, sessionKey) {
+			if handleCLICommand(input, agentLoop, &sessionKey) {
 				continue
 			}
 		}
@@ -1181,7 +1342,7 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 		}
 
 		if strings.HasPrefix(input, "/") {
-			if handleCLICommand(input, agentLoop, sessionKey) {
+			if handleCLICommand(input, agentLoop, &sessionKey) {
 				continue
 			}
 		}
@@ -1197,17 +1358,7 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	}
 }
 
-func gatewayCmd() {
-	// Parse flags
-	verbose := false
-	args := os.Args[2:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "-v", "--verbose":
-			verbose = true
-		}
-	}
-
+func gatewayCmd(verbose bool) {
 	// Enable verbose logging if requested
 	if verbose {
 		logger.SetLevel(logger.DEBUG)
@@ -1238,6 +1389,30 @@ func gatewayRun(sigChan chan os.Signal) bool {
 		os.Exit(1)
 	}
 
+	brokerCfg := cfg.Bus.Broker
+	role := brokerCfg.Role
+	if role == "" {
+		role = bus.RoleAll
+	}
+
+	// With a broker, several gateways share this configuration by design.
+	// Only the process running the channels (role all or ingest, one per
+	// channel account) runs the scheduled services, so cron jobs, digests,
+	// feeds and git sync don't run again in every worker.
+	scheduler := role != bus.RoleWorker
+	var lock *instance.Lock
+	if brokerCfg.Type == "" {
+		var ok bool
+		if lock, ok = acquireGatewayLock(cfg.Gateway.Instance, sigChan); !ok {
+			return false
+		}
+	}
+	var lockLost <-chan struct{}
+	if lock != nil {
+		defer lock.Release()
+		lockLost = lock.Lost()
+	}
+
 	provider, err := providers.CreateProvider(cfg)
 	if err != nil {
 		fmt.Printf("Error creating provider: %v\n", err)
@@ -1246,12 +1421,42 @@ func gatewayRun(sigChan chan os.Signal) bool {
 
 	msgBus := bus.NewMessageBus()
 
+	busCtx, stopBus := context.WithCancel(context.Background())
+	defer stopBus()
+	var broker bus.Broker
+	if brokerCfg.Type != "" {
+		broker, err = bus.NewBroker(brokerCfg)
+		if err == nil {
+			err = msgBus.AttachBroker(busCtx, broker, brokerCfg)
+		}
+		if err != nil {
+			fmt.Printf("Error connecting to %s broker: %v\n", brokerCfg.Type, err)
+			os.Exit(1)
+		}
+		defer broker.Close()
+		fmt.Printf("✓ Message bus on %s broker (role: %s, worker %d of %d)\n", brokerCfg.Type, role, brokerCfg.WorkerIndex+1, max(brokerCfg.Workers, 1))
+	}
+
+	var journal *bus.Journal
+	if cfg.Bus.Journal && broker == nil {
+		journal, err = bus.OpenJournal(filepath.Join(cfg.WorkspacePath(), "bus", "inbound.jsonl"))
+		if err != nil {
+			fmt.Printf("Error opening bus journal: %v\n", err)
+			os.Exit(1)
+		}
+		msgBus.EnableJournal(journal)
+	}
+
 	// Create agent manager for multi-agent support
 	agentManager, err := agent.NewAgentManager(cfg, msgBus, provider)
 	if err != nil {
 		fmt.Printf("Error creating agent manager: %v\n", err)
 		os.Exit(1)
 	}
+	agentManager.RestoreMaintenance()
+	if m := agentManager.Maintenance(); m.Enabled {
+		fmt.Printf("🛠 Maintenance mode is on (since %s); turn it off with: pepebot maintenance off\n", m.Since.Format("2006-01-02 15:04"))
+	}
 
 	// List enabled agents
 	enabledAgents := agentManager.ListEnabledAgents()
@@ -1266,10 +1471,14 @@ func gatewayRun(sigChan chan os.Signal) bool {
 	cronStorePath := filepath.Join(filepath.Dir(getConfigPath()), "cron", "jobs.json")
 	cronService := cron.NewCronService(cronStorePath, nil)
 
+	heartbeatInterval := cfg.Heartbeat.IntervalS
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = 30 * 60
+	}
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
 		nil,
-		30*60,
+		heartbeatInterval,
 		true,
 	)
 
@@ -1278,10 +1487,49 @@ func gatewayRun(sigChan chan os.Signal) bool {
 		fmt.Printf("Error creating channel manager: %v\n", err)
 		os.Exit(1)
 	}
+	channelManager.SetMiddleware(middleware.New(cfg, provider))
+
+	var outbox *bus.Outbox
+	if cfg.Outbox.Enabled {
+		outbox, err = bus.NewOutbox(
+			filepath.Join(cfg.WorkspacePath(), "outbox"),
+			cfg.Outbox.MaxAttempts,
+			time.Duration(cfg.Outbox.BaseBackoffMS)*time.Millisecond,
+			time.Duration(cfg.Outbox.MaxBackoffMS)*time.Millisecond,
+		)
+		if err != nil {
+			fmt.Printf("Error creating outbox: %v\n", err)
+			os.Exit(1)
+		}
+		channelManager.SetOutbox(outbox)
+	}
+
+	if cfg.Feedback.Enabled {
+		feedbackStore := feedback.NewStore(
+			filepath.Join(filepath.Dir(cfg.WorkspacePath()), feedback.FileName),
+			filepath.Join(cfg.WorkspacePath(), "state", "feedback_replies.json"),
+		)
+		feedbackStore.OnRating = func(e feedback.Event) {
+			logger.InfoCF("feedback", "Reply rated", map[string]interface{}{
+				"agent":   e.Agent,
+				"session": e.Session,
+				"rating":  e.Rating,
+			})
+			if e.Rating != feedback.Down || !cfg.Feedback.MemoryCorrections {
+				return
+			}
+			if _, err := agentManager.AddMemory(e.Agent, e.Correction()); err != nil {
+				logger.WarnCF("feedback", "Failed to save correction", map[string]interface{}{
+					"agent": e.Agent,
+					"error": err.Error(),
+				})
+			}
+		}
+		channelManager.SetFeedback(feedbackStore)
+	}
 
-	var transcriber *voice.GroqTranscriber
-	if cfg.Providers.Groq.APIKey != "" {
-		transcriber = voice.NewGroqTranscriber(cfg.Providers.Groq.APIKey)
+	transcriber := newTranscriber(cfg)
+	if transcriber != nil {
 		logger.InfoC("voice", "Groq voice transcription enabled")
 	}
 
@@ -1294,6 +1542,32 @@ func gatewayRun(sigChan chan os.Signal) bool {
 		}
 	}
 
+	if discordChannel, ok := channelManager.GetChannel("discord"); ok {
+		if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
+			if transcriber != nil {
+				dc.SetTranscriber(transcriber)
+				logger.InfoC("voice", "Groq transcription attached to Discord channel")
+			}
+			agentManager.RegisterTool(tools.NewDiscordVoiceJoinTool(dc))
+			agentManager.RegisterTool(tools.NewDiscordVoiceLeaveTool(dc))
+		}
+	}
+
+	if whatsappChannel, ok := channelManager.GetChannel("whatsapp"); ok {
+		if wc, ok := whatsappChannel.(*channels.WhatsAppChannel); ok {
+			if transcriber != nil {
+				wc.SetTranscriber(transcriber)
+				logger.InfoC("voice", "Groq transcription attached to WhatsApp channel")
+			}
+			agentManager.RegisterTool(tools.NewWhatsAppListGroupsTool(wc))
+			agentManager.RegisterTool(tools.NewWhatsAppResolveContactTool(wc))
+			agentManager.RegisterTool(tools.NewWhatsAppGroupMembersTool(wc))
+		}
+	}
+
+	broadcaster := broadcast.NewService(cfg.Broadcast, channelManager)
+	agentManager.RegisterTool(tools.NewBroadcastSendTool(broadcaster))
+
 	enabledChannels := channelManager.GetEnabledChannels()
 	if len(enabledChannels) > 0 {
 		fmt.Printf("✓ Channels enabled: %s\n", enabledChannels)
@@ -1304,6 +1578,12 @@ func gatewayRun(sigChan chan os.Signal) bool {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	cronService.SetJobHandler(cronJobHandler(ctx, agentManager, channelManager))
+	cronService.SetHistorySize(cfg.Cron.HistorySize)
+	if cfg.Cron.AlertAfterFailures > 0 && cfg.Cron.AlertChannel != "" && cfg.Cron.AlertChatID != "" {
+		cronService.SetFailureAlert(cfg.Cron.AlertAfterFailures, cronFailureAlert(ctx, channelManager, cfg.Cron.AlertChannel, cfg.Cron.AlertChatID))
+	}
+
 	// Restart function: sends SIGHUP to self to trigger graceful restart
 	restartFunc := func() {
 		triggerRestart()
@@ -1312,35 +1592,163 @@ func gatewayRun(sigChan chan os.Signal) bool {
 	// Start HTTP API server (with restart support)
 	gatewayServer := gateway.NewGatewayServer(cfg, agentManager, msgBus)
 	gatewayServer.SetRestartFunc(restartFunc)
+	gatewayServer.SetBroadcaster(broadcaster)
+	gatewayServer.SetOutbox(outbox)
+	gatewayServer.SetCron(cronService)
+	gatewayServer.SetChannels(channelManager)
+	gatewayServer.SetVersion(version)
+	if transcriber != nil {
+		gatewayServer.SetTranscriber(transcriber)
+	}
 	agentManager.SetRestartFunc(restartFunc)
+	agentManager.SetTypingNotifier(channelManager)
 	if err := gatewayServer.Start(ctx); err != nil {
 		fmt.Printf("Error starting HTTP API server: %v\n", err)
 		os.Exit(1)
 	}
 	fmt.Printf("✓ HTTP API server started on %s:%d\n", cfg.Gateway.Host, cfg.Gateway.Port)
 
-	if err := cronService.Start(); err != nil {
-		fmt.Printf("Error starting cron service: %v\n", err)
+	if scheduler {
+		registerWorkflowSchedules(cronService, cfg.WorkspacePath())
+		if err := cronService.Start(); err != nil {
+			fmt.Printf("Error starting cron service: %v\n", err)
+		}
+		fmt.Println("✓ Cron service started")
+	}
+
+	if len(cfg.Heartbeat.Checks) > 0 {
+		checks, errs := heartbeat.BuildChecks(cfg)
+		for _, err := range errs {
+			fmt.Printf("⚠ Heartbeat %v\n", err)
+		}
+		heartbeatService.SetChecks(checks, time.Duration(cfg.Heartbeat.CheckIntervalS)*time.Second)
+		heartbeatService.SetAlertSink(channelManager, cfg.Heartbeat.AlertChannel, cfg.Heartbeat.AlertChatID)
+		if len(checks) > 0 {
+			fmt.Printf("✓ Heartbeat checks: %d configured\n", len(checks))
+		}
+	}
+
+	if cfg.Heartbeat.Proactive && role != bus.RoleWorker {
+		channel, chatID := cfg.Heartbeat.Channel, cfg.Heartbeat.ChatID
+		if channel == "" || chatID == "" {
+			channel, chatID = cfg.Heartbeat.AlertChannel, cfg.Heartbeat.AlertChatID
+		}
+		heartbeatService.SetProactive(func(prompt string) (string, error) {
+			// One session, so the agent remembers what it already reported
+			return agentManager.ProcessDirect(ctx, prompt, nil, "heartbeat", cfg.Heartbeat.Agent)
+		}, channelManager, channel, chatID)
+		fmt.Printf("✓ Proactive heartbeat: every %ds to %s\n", heartbeatInterval, channel)
 	}
-	fmt.Println("✓ Cron service started")
 
 	if err := heartbeatService.Start(); err != nil {
 		fmt.Printf("Error starting heartbeat service: %v\n", err)
 	}
 	fmt.Println("✓ Heartbeat service started")
 
-	if err := channelManager.StartAll(ctx); err != nil {
-		fmt.Printf("Error starting channels: %v\n", err)
+	var batchQueue *batch.Queue
+	if cfg.Batch.Enabled && scheduler {
+		batchProvider, batchModel := provider, cfg.Agents.Defaults.Model
+		if cfg.Batch.Model != "" || cfg.Batch.Provider != "" || cfg.Batch.APIBase != "" {
+			p, err := providers.CreateProviderWithCredentials(cfg, cfg.Batch.Model, cfg.Batch.Provider, cfg.Batch.APIKey, cfg.Batch.APIBase)
+			if err != nil {
+				fmt.Printf("⚠ Batch model unavailable, using the default model: %v\n", err)
+			} else {
+				batchProvider = p
+				if cfg.Batch.Model != "" {
+					batchModel = cfg.Batch.Model
+				}
+			}
+		}
+		batchQueue = batch.NewQueue(cfg.Batch, cfg.WorkspacePath(), batchProvider, batchModel)
+		agentManager.SetBatchQueue(batchQueue)
+		if _, _, ok := providers.BatcherFor(batchProvider, batchModel); ok {
+			fmt.Printf("✓ Batch queue: %s, sent every %ds\n", batchModel, cfg.Batch.FlushIntervalS)
+		} else {
+			fmt.Printf("⚠ Batch queue: %s has no batch API, queued requests are sent at the regular price\n", batchModel)
+		}
+	}
+
+	var digestService *digest.Service
+	if cfg.Digest.Enabled && scheduler {
+		digestService = digest.NewService(cfg.Digest, cfg.WorkspacePath(), agentManager.GetSessions(), cronService, agentManager, channelManager)
+		if cfg.Tools.Calendar.URL != "" {
+			digestService.SetCalendar(calendar.NewClient(cfg.Tools.Calendar))
+		}
+		if batchQueue != nil {
+			digestService.SetBatchQueue(batchQueue)
+		}
+		if err := digestService.Start(); err != nil {
+			fmt.Printf("Error starting digest service: %v\n", err)
+			digestService = nil
+		} else {
+			fmt.Printf("✓ Daily digest scheduled at %s\n", cfg.Digest.Time)
+		}
+	}
+	// Started after all handlers are registered, so restored jobs find theirs
+	if batchQueue != nil {
+		batchQueue.Start()
+	}
+
+	var feedService *feeds.Service
+	if cfg.Feeds.Enabled && scheduler {
+		feedService = feeds.NewService(cfg.Feeds, feeds.NewStore(cfg.WorkspacePath()), channelManager, msgBus)
+		feedService.Start()
+		fmt.Println("✓ Feed watcher started")
+	}
+
+	var syncService *gitsync.Service
+	if cfg.Sync.Enabled && scheduler {
+		syncService = gitsync.NewService(cfg.Sync, cfg.WorkspacePath())
+		if err := syncService.Start(); err != nil {
+			fmt.Printf("Error starting workspace sync: %v\n", err)
+			syncService = nil
+		} else {
+			fmt.Println("✓ Workspace git sync started")
+		}
+	}
+
+	if role != bus.RoleWorker {
+		if err := channelManager.StartAll(ctx); err != nil {
+			fmt.Printf("Error starting channels: %v\n", err)
+		}
+	}
+
+	var logcatWatcher *tools.LogcatWatcher
+	if len(cfg.Tools.Adb.LogcatWatchers) > 0 && role != bus.RoleWorker {
+		if adbHelper, err := tools.NewAdbHelper(cfg.WorkspacePath()); err != nil {
+			fmt.Printf("⚠ Logcat watchers not started: %v\n", err)
+		} else {
+			logcatWatcher = tools.NewLogcatWatcher(adbHelper, cfg.Tools.Adb.LogcatWatchers, logcatHandler(agentManager, channelManager, msgBus))
+			fmt.Printf("✓ Logcat watchers started: %d\n", logcatWatcher.Start())
+		}
+	}
+
+	// Rules in workspace/rules.yaml route inbound events before the agents
+	// see them; the file is reloaded when it changes
+	ruleEngine := rules.NewEngine(cfg.WorkspacePath(), channelManager, msgBus, agentManager)
+	agentManager.SetInboundHook(ruleEngine.Handle)
+	if n := len(ruleEngine.Rules()); n > 0 {
+		fmt.Printf("✓ Routing rules: %d loaded\n", n)
+	}
+
+	if role != bus.RoleIngest {
+		go agentManager.Run(ctx)
 	}
 
-	go agentManager.Run(ctx)
+	if n := msgBus.Replay(time.Duration(cfg.Bus.ReplayMaxAgeMinutes) * time.Minute); n > 0 {
+		fmt.Printf("✓ Replaying %d unprocessed message(s) from bus journal\n", n)
+	}
 
 	fmt.Printf("✓ Gateway started on %s:%d\n", cfg.Gateway.Host, cfg.Gateway.Port)
 	fmt.Println("Press Ctrl+C to stop")
 
-	sig := <-sigChan
-
-	restart := isRestartSignal(sig)
+	restart := false
+	select {
+	case sig := <-sigChan:
+		restart = isRestartSignal(sig)
+	case <-lockLost:
+		fmt.Println("\n⚠ Another gateway took over the instance lock")
+	}
 
 	if restart {
 		fmt.Println("\nRestarting...")
@@ -1348,10 +1756,29 @@ func gatewayRun(sigChan chan os.Signal) bool {
 		fmt.Println("\nShutting down...")
 	}
 	cancel()
+	stopBus()
 	gatewayServer.Stop(context.Background())
 	heartbeatService.Stop()
 	cronService.Stop()
+	if digestService != nil {
+		digestService.Stop()
+	}
+	if batchQueue != nil {
+		batchQueue.Stop()
+	}
+	if syncService != nil {
+		syncService.Stop()
+	}
+	if feedService != nil {
+		feedService.Stop()
+	}
+	if logcatWatcher != nil {
+		logcatWatcher.Stop()
+	}
 	channelManager.StopAll(context.Background())
+	if journal != nil {
+		journal.Close()
+	}
 
 	if restart {
 		fmt.Println("✓ Gateway stopped (restarting)")
@@ -1362,124 +1789,1234 @@ func gatewayRun(sigChan chan os.Signal) bool {
 	return restart
 }
 
-func statusCmd() {
-	cfg, err := loadConfig()
+// acquireGatewayLock takes the instance lock of the gateway. In standby mode
+// it waits for the running gateway to go away. It returns false when the
+// gateway should not start; the lock is nil when locking is off.
+func acquireGatewayLock(cfg config.InstanceConfig, sigChan chan os.Signal) (*instance.Lock, bool) {
+	if cfg.Mode == "off" {
+		return nil, true
+	}
+	path := cfg.LockFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(getConfigPath()), "gateway.lock")
+	} else if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[2:])
+	}
+	interval := time.Duration(max(cfg.HeartbeatS, 1)) * time.Second
+	stale := time.Duration(max(cfg.StaleAfterS, 3*cfg.HeartbeatS, 3)) * time.Second
+	lock := instance.New(path, interval, stale)
+
+	if cfg.Mode != "standby" {
+		if err := lock.TryAcquire(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Stop it first, or set gateway.instance.mode to \"standby\" to run this one as its backup (lock: %s)\n", path)
+			os.Exit(1)
+		}
+		return lock, true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-sigChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	announced := false
+	err := lock.Wait(ctx, func(h instance.Holder) {
+		if !announced {
+			fmt.Printf("⏸ Standby: gateway pid %d on %s is primary; taking over if its heartbeat stops for %s\n", h.PID, h.Host, stale)
+			announced = true
+		}
+	})
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		return
+		if ctx.Err() == nil {
+			fmt.Printf("Error acquiring instance lock: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("\n✓ Standby stopped")
+		return nil, false
+	}
+	if announced {
+		fmt.Println("▶ Primary gone, taking over")
 	}
+	return lock, true
+}
 
-	configPath := getConfigPath()
+// gatewayStatus is the response of the gateway's /v1/status endpoint
+type gatewayStatus struct {
+	Version   string `json:"version"`
+	StartedAt string `json:"started_at"`
+	UptimeS   int64  `json:"uptime_s"`
+	Model     string `json:"model"`
+	Sessions  struct {
+		Active []string `json:"active"`
+	} `json:"sessions"`
+	Channels map[string]struct {
+		Enabled bool `json:"enabled"`
+		Running bool `json:"running"`
+	} `json:"channels"`
+	Queues struct {
+		Inbound    int            `json:"inbound"`
+		Outbound   int            `json:"outbound"`
+		Processing int            `json:"processing"`
+		Outbox     map[string]int `json:"outbox,omitempty"`
+	} `json:"queues"`
+	Cron        map[string]interface{} `json:"cron,omitempty"`
+	Errors      []logger.LogEntry      `json:"errors"`
+	Usage       agent.UsageStats       `json:"usage"`
+	Maintenance agent.MaintenanceState `json:"maintenance"`
+}
 
-	fmt.Printf("%s pepebot Status\n\n", logo)
+// statusReport is what `pepebot status --json` prints
+type statusReport struct {
+	Version   string          `json:"version"`
+	Config    string          `json:"config"`
+	ConfigOK  bool            `json:"config_ok"`
+	Workspace string          `json:"workspace"`
+	Model     string          `json:"model"`
+	Providers map[string]bool `json:"providers"`
+	Gateway   struct {
+		URL     string         `json:"url"`
+		Running bool           `json:"running"`
+		Error   string         `json:"error,omitempty"`
+		Live    *gatewayStatus `json:"live,omitempty"`
+	} `json:"gateway"`
+}
 
-	if _, err := os.Stat(configPath); err == nil {
+// fetchGatewayStatus asks a running gateway for its live status
+func fetchGatewayStatus(baseURL string) (*gatewayStatus, error) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(baseURL + "/v1/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway returned %s", resp.Status)
+	}
+	var status gatewayStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid status response: %w", err)
+	}
+	return &status, nil
+}
+
+// gatewayBaseURL returns the local address of the configured gateway
+func gatewayBaseURL(cfg *config.Config) string {
+	host := cfg.Gateway.Host
+	if host == "" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s:%d", host, cfg.Gateway.Port)
+}
+
+func statusCmd(jsonOutput bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	configPath := getConfigPath()
+	_, configErr := os.Stat(configPath)
+	workspace := cfg.WorkspacePath()
+	_, workspaceErr := os.Stat(workspace)
+
+	providerList := []struct {
+		key  string
+		name string
+		set  bool
+	}{
+		{"maiarouter", "MAIA Router", cfg.Providers.MAIARouter.APIKey != ""},
+		{"openrouter", "OpenRouter API", cfg.Providers.OpenRouter.APIKey != ""},
+		{"anthropic", "Anthropic API", cfg.Providers.Anthropic.APIKey != ""},
+		{"openai", "OpenAI API", cfg.Providers.OpenAI.APIKey != ""},
+		{"gemini", "Gemini API", cfg.Providers.Gemini.APIKey != ""},
+		{"zhipu", "Zhipu API", cfg.Providers.Zhipu.APIKey != ""},
+		{"groq", "Groq API", cfg.Providers.Groq.APIKey != ""},
+	}
+
+	baseURL := gatewayBaseURL(cfg)
+	live, liveErr := fetchGatewayStatus(baseURL)
+
+	if jsonOutput {
+		report := statusReport{
+			Version:   version,
+			Config:    configPath,
+			ConfigOK:  configErr == nil,
+			Workspace: workspace,
+			Model:     cfg.Agents.Defaults.Model,
+			Providers: map[string]bool{},
+		}
+		for _, p := range providerList {
+			report.Providers[p.key] = p.set
+		}
+		report.Providers["vllm"] = cfg.Providers.VLLM.APIBase != ""
+		report.Gateway.URL = baseURL
+		report.Gateway.Running = liveErr == nil
+		report.Gateway.Live = live
+		if liveErr != nil {
+			report.Gateway.Error = liveErr.Error()
+		}
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("%s pepebot Status\n\n", logo)
+
+	if configErr == nil {
 		fmt.Println("Config:", configPath, "✓")
 	} else {
 		fmt.Println("Config:", configPath, "✗")
 	}
 
-	workspace := cfg.WorkspacePath()
-	if _, err := os.Stat(workspace); err == nil {
-		fmt.Println("Workspace:", workspace, "✓")
-	} else {
-		fmt.Println("Workspace:", workspace, "✗")
-	}
+	if workspaceErr == nil {
+		fmt.Println("Workspace:", workspace, "✓")
+	} else {
+		fmt.Println("Workspace:", workspace, "✗")
+	}
+
+	if configErr == nil {
+		fmt.Printf("Model: %s\n", cfg.Agents.Defaults.Model)
+
+		fmt.Println("\nProviders:")
+		for _, p := range providerList {
+			if p.set {
+				fmt.Println(p.name+":", "✓")
+			} else {
+				fmt.Println(p.name+":", "not set")
+			}
+		}
+		if cfg.Providers.VLLM.APIBase != "" {
+			fmt.Printf("vLLM/Local: ✓ %s\n", cfg.Providers.VLLM.APIBase)
+		} else {
+			fmt.Println("vLLM/Local: not set")
+		}
+	}
+
+	fmt.Println("\nGateway:")
+	if liveErr != nil {
+		fmt.Printf("  Not running at %s\n", baseURL)
+		return
+	}
+	printGatewayStatus(live)
+}
+
+// printGatewayStatus prints the live part of `pepebot status`
+func printGatewayStatus(live *gatewayStatus) {
+	uptime := (time.Duration(live.UptimeS) * time.Second).String()
+	fmt.Printf("  Running: ✓ v%s, up %s\n", live.Version, uptime)
+	if live.Maintenance.Enabled {
+		fmt.Printf("  Maintenance: on since %s\n", live.Maintenance.Since.Format("2006-01-02 15:04"))
+	}
+
+	fmt.Printf("  Active sessions: %d\n", len(live.Sessions.Active))
+	for _, key := range live.Sessions.Active {
+		fmt.Printf("    %s\n", key)
+	}
+
+	names := make([]string, 0, len(live.Channels))
+	for name := range live.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		fmt.Println("  Channels:")
+		for _, name := range names {
+			state := "stopped"
+			if live.Channels[name].Running {
+				state = "running"
+			}
+			fmt.Printf("    %s: %s\n", name, state)
+		}
+	}
+
+	q := live.Queues
+	fmt.Printf("  Queues: %d inbound, %d outbound, %d processing\n", q.Inbound, q.Outbound, q.Processing)
+	if q.Outbox != nil {
+		fmt.Printf("  Outbox: %d pending, %d dead\n", q.Outbox["pending"], q.Outbox["dead"])
+	}
+
+	u := live.Usage
+	fmt.Printf("  Tokens today: %d (%d prompt, %d completion, %d requests)\n",
+		u.TotalTokens, u.PromptTokens, u.CompletionTokens, u.Requests)
+
+	if len(live.Errors) > 0 {
+		fmt.Println("  Recent errors:")
+		for _, e := range live.Errors {
+			line := e.Message
+			if errMsg, ok := e.Fields["error"]; ok {
+				line = fmt.Sprintf("%s: %v", line, errMsg)
+			}
+			fmt.Printf("    %s [%s] %s\n", e.Timestamp, e.Component, truncateLine(line, 120))
+		}
+	}
+}
+
+// doctorCmd runs the environment diagnostics and prints a fix for every
+// problem. It exits with status 1 when a check fails.
+func doctorCmd(jsonOutput bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if !jsonOutput {
+		fmt.Printf("%s pepebot doctor\n", logo)
+	}
+	results := doctor.New(getConfigPath()).Run(ctx)
+
+	if jsonOutput {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		icons := map[doctor.Status]string{
+			doctor.StatusOK:   "✓",
+			doctor.StatusWarn: "⚠",
+			doctor.StatusFail: "✗",
+			doctor.StatusSkip: "-",
+		}
+		titles := map[string]string{"adb": "ADB", "mcp": "MCP"}
+		group := ""
+		counts := map[doctor.Status]int{}
+		for _, r := range results {
+			if r.Group != group {
+				group = r.Group
+				title := titles[group]
+				if title == "" {
+					title = strings.ToUpper(group[:1]) + group[1:]
+				}
+				fmt.Printf("\n%s:\n", title)
+			}
+			counts[r.Status]++
+			fmt.Printf("  %s %s: %s\n", icons[r.Status], r.Name, r.Message)
+			if r.Fix != "" {
+				fmt.Printf("      → %s\n", r.Fix)
+			}
+		}
+		fmt.Printf("\n%d ok, %d warnings, %d failed\n", counts[doctor.StatusOK], counts[doctor.StatusWarn], counts[doctor.StatusFail])
+	}
+
+	if doctor.Failed(results) {
+		os.Exit(1)
+	}
+}
+
+// truncateLine cuts s to max runes for one-line output
+func truncateLine(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max]) + "…"
+	}
+	return s
+}
+
+func getConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".pepebot", "config.json")
+}
+
+func loadConfig() (*config.Config, error) {
+	return config.LoadConfig(getConfigPath())
+}
+
+// feedsStore opens the subscription store of the configured workspace
+func feedsStore() *feeds.Store {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return feeds.NewStore(cfg.WorkspacePath())
+}
+
+func feedsRemoveCmd(store *feeds.Store, id string) {
+	removed, err := store.Remove(id)
+	if err != nil {
+		fmt.Printf("Error removing subscription: %v\n", err)
+	} else if removed {
+		fmt.Printf("✓ Removed subscription %s\n", id)
+	} else {
+		fmt.Printf("✗ Subscription %s not found\n", id)
+	}
+}
+
+func syncCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result, err := gitsync.NewService(cfg.Sync, cfg.WorkspacePath()).Run(ctx)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Committed {
+		fmt.Printf("✓ Committed: %s\n", result.Message)
+	} else {
+		fmt.Println("No workspace changes to commit")
+	}
+	switch {
+	case result.Pushed:
+		fmt.Printf("✓ Pushed to %s (%s)\n", cfg.Sync.Remote, cfg.Sync.Branch)
+	case cfg.Sync.Remote == "":
+		fmt.Println("No sync.remote configured, history is kept locally")
+	}
+}
+
+// secretsStore opens the secret store the workflow runner reads from
+func secretsStore() *secrets.Store {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return secrets.NewStore(filepath.Dir(cfg.WorkspacePath()))
+}
+
+func secretsListCmd(store *secrets.Store) {
+	names, err := store.Names()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(names) == 0 {
+		fmt.Println("No secrets stored")
+		return
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
+
+func secretsSetCmd(store *secrets.Store, name string, valueArgs []string) {
+	value, err := readSecretValue(valueArgs)
+	if err != nil {
+		fmt.Printf("Error reading value: %v\n", err)
+		os.Exit(1)
+	}
+	if err := store.Set(name, value); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Secret %s saved. Use it in workflow args as {{secret:%s}}\n", name, name)
+}
+
+func secretsDeleteCmd(store *secrets.Store, name string) {
+	removed, err := store.Delete(name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if removed {
+		fmt.Printf("✓ Removed secret %s\n", name)
+	} else {
+		fmt.Printf("✗ Secret %s not found\n", name)
+	}
+}
+
+// readSecretValue takes the value from the arguments, a hidden prompt on a
+// terminal, or the first line of stdin when piped.
+func readSecretValue(args []string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+	if readline.IsTerminal(int(os.Stdin.Fd())) {
+		value, err := readline.Password("Value: ")
+		return string(value), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func sessionsSearchCmd(query string, limit int) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	sessions := session.NewSessionManager(filepath.Join(filepath.Dir(cfg.WorkspacePath()), "sessions"))
+
+	results := sessions.Search(query, limit)
+	if len(results) == 0 {
+		fmt.Printf("No sessions match %q\n", query)
+		return
+	}
+
+	fmt.Printf("\nSessions matching %q:\n", query)
+	fmt.Println("--------------------------------")
+	for _, r := range results {
+		fmt.Printf("%s  (%d matches, updated %s)\n", r.Key, r.Matches, r.Updated.Format("2006-01-02 15:04"))
+		for _, s := range r.Snippets {
+			fmt.Printf("  [%s] %s\n", s.Role, s.Snippet)
+		}
+		fmt.Println()
+	}
+}
+
+type reportOptions struct {
+	title  string
+	format string
+	tools  bool
+	images []string
+	send   string // channel:chat_id
+}
+
+func sessionsReportCmd(keys []string, opts reportOptions) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	sessions := session.NewSessionManager(filepath.Join(filepath.Dir(cfg.WorkspacePath()), "sessions"))
+
+	var selected []*session.Session
+	for _, key := range keys {
+		s := sessions.GetSession(key)
+		if s == nil {
+			fmt.Printf("Session not found: %s\n", key)
+			os.Exit(1)
+		}
+		selected = append(selected, s)
+	}
+
+	var images []string
+	for _, img := range opts.images {
+		if abs, err := filepath.Abs(img); err == nil {
+			img = abs
+		}
+		images = append(images, img)
+	}
+	path, err := report.Save(cfg.WorkspacePath(), selected, report.Options{
+		Title:  opts.title,
+		Format: opts.format,
+		Tools:  opts.tools,
+		Images: images,
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Report saved to %s\n", path)
+
+	if opts.send == "" {
+		return
+	}
+	channel, chatID, _ := strings.Cut(opts.send, ":")
+	body, _ := json.Marshal(map[string]interface{}{
+		"channel": channel,
+		"chat_id": chatID,
+		"content": opts.title,
+		"media":   []string{path},
+	})
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(gatewayBaseURL(cfg)+"/v1/send", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: could not reach the gateway to deliver the report: %v\n", err)
+		os.Exit(1)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("Error: gateway returned %s\n", resp.Status)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Sent to %s %s\n", channel, chatID)
+}
+
+// maintenanceCmd shows maintenance mode, or turns it on or off when enabled
+// is set, through the running gateway
+func maintenanceCmd(enabled *bool, message string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := gatewayBaseURL(cfg) + "/v1/maintenance"
+	var resp *http.Response
+	if enabled == nil {
+		resp, err = client.Get(url)
+	} else {
+		body, _ := json.Marshal(map[string]interface{}{"enabled": *enabled, "message": message})
+		resp, err = client.Post(url, "application/json", bytes.NewReader(body))
+	}
+	if err != nil {
+		fmt.Printf("Error: could not reach the gateway: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("Error: gateway returned %s\n", resp.Status)
+		os.Exit(1)
+	}
+
+	var state agent.MaintenanceState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		fmt.Printf("Error: invalid response: %v\n", err)
+		os.Exit(1)
+	}
+	if !state.Enabled {
+		fmt.Println("✓ Maintenance mode is off")
+		return
+	}
+	fmt.Printf("🛠 Maintenance mode is on since %s\n", state.Since.Format("2006-01-02 15:04"))
+	if state.Message != "" {
+		fmt.Printf("  Reply: %s\n", state.Message)
+	}
+}
+
+func configGetCmd(key string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	value, err := config.Lookup(cfg, key)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(config.FormatValue(value))
+}
+
+func configSetCmd(key, value string) {
+	if err := config.SetValue(getConfigPath(), key, value); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Set %s\n", key)
+}
+
+func configUnsetCmd(key string) {
+	if err := config.UnsetValue(getConfigPath(), key); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Removed %s\n", key)
+}
+
+func auditCmd(filter audit.Filter, asJSON bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	path := filepath.Join(filepath.Dir(cfg.WorkspacePath()), audit.FileName)
+
+	entries, err := audit.Query(path, filter)
+	if err != nil {
+		fmt.Printf("Error reading audit log: %v\n", err)
+		os.Exit(1)
+	}
+	if asJSON {
+		for _, e := range entries {
+			line, _ := json.Marshal(e)
+			fmt.Println(string(line))
+		}
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No audit entries in %s\n", path)
+		if !cfg.Tools.Filesystem.Audit {
+			fmt.Println("Audit logging is off (tools.filesystem.audit).")
+		}
+		return
+	}
+
+	for _, e := range entries {
+		target := e.Path
+		if e.Tool == "exec" {
+			target = e.Command
+		}
+		status := ""
+		if e.Status != audit.StatusOK {
+			status = fmt.Sprintf("  [%s: %s]", e.Status, e.Error)
+		}
+		session := e.Session
+		if session == "" {
+			session = "-"
+		}
+		fmt.Printf("%s  %-24s %-15s %s%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), session, e.Tool, target, status)
+	}
+}
+
+// analyticsCmd prints the usage report for the last days
+func analyticsCmd(days, top int, asJSON bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	home := filepath.Dir(cfg.WorkspacePath())
+
+	until := time.Now()
+	since := until.AddDate(0, 0, -days)
+	events, err := analytics.Read(filepath.Join(home, analytics.FileName), since)
+	if err != nil {
+		fmt.Printf("Error reading usage log: %v\n", err)
+		os.Exit(1)
+	}
+	sessions := session.NewSessionManager(filepath.Join(home, "sessions")).ListSessions("")
+	report := analytics.Compute(events, sessions, since, until, top)
+
+	if asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println()
+	fmt.Print(report.Format())
+	fmt.Println()
+}
+
+func feedbackCmd(days int, agentName string, recent int, asJSON bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	until := time.Now()
+	since := until.AddDate(0, 0, -days)
+	events, err := feedback.Read(filepath.Join(filepath.Dir(cfg.WorkspacePath()), feedback.FileName), since)
+	if err != nil {
+		fmt.Printf("Error reading feedback log: %v\n", err)
+		os.Exit(1)
+	}
+	report := feedback.Compute(events, since, until, agentName, recent)
+
+	if asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println()
+	fmt.Print(report.Format())
+	fmt.Println()
+}
+
+// newTranscriber returns the Whisper transcriber set up by the Groq
+// provider and voice config, or nil without a Groq API key
+func newTranscriber(cfg *config.Config) *voice.GroqTranscriber {
+	if cfg.Providers.Groq.APIKey == "" {
+		return nil
+	}
+	transcriber := voice.NewGroqTranscriber(cfg.Providers.Groq.APIKey)
+	if cfg.Providers.Groq.APIBase != "" {
+		transcriber.SetAPIBase(cfg.Providers.Groq.APIBase)
+	}
+	if cfg.Voice.Preprocess {
+		transcriber.SetPreprocessor(&voice.Preprocessor{
+			FFmpeg:   cfg.Voice.FFmpegPath,
+			MaxPause: time.Duration(cfg.Voice.MaxPauseMs) * time.Millisecond,
+		})
+	}
+	if d := cfg.Voice.Diarization; d.Enabled && d.URL != "" {
+		transcriber.SetDiarizer(voice.NewHTTPDiarizer(d.URL, d.APIKey))
+		logger.InfoC("voice", "Speaker diarization enabled")
+	}
+	return transcriber
+}
+
+// firstNonEmpty returns the first value that is set
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// listenOptions are the flags of pepebot listen
+type listenOptions struct {
+	agentName  string
+	sessionKey string
+	wakeWord   string
+	noWake     bool
+	micCommand string
+	ttsCommand string
+	mute       bool
+	language   string
+}
+
+// listenCmd runs the hands-free voice assistant on the host microphone
+func listenCmd(opts listenOptions) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	transcriber := newTranscriber(cfg)
+	if transcriber == nil {
+		fmt.Println("Error: listen needs speech-to-text; set providers.groq.api_key (and api_base for a local Whisper server)")
+		os.Exit(1)
+	}
+
+	wakeWord := firstNonEmpty(opts.wakeWord, cfg.Voice.WakeWord, voice.DefaultWakeWord)
+	if opts.noWake {
+		wakeWord = ""
+	}
+	var tts voice.TTS
+	if !opts.mute {
+		if command := firstNonEmpty(opts.ttsCommand, cfg.Voice.TTSCommand); command != "" {
+			tts = &voice.CommandTTS{Command: command}
+		} else if tts = voice.DetectTTS(); tts == nil {
+			fmt.Println("⚠ No text-to-speech found (say, espeak-ng or espeak); replies are printed only. Set voice.tts_command to speak them.")
+		}
+	}
+
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+	agentManager, err := agent.NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		fmt.Printf("Error creating agent manager: %v\n", err)
+		os.Exit(1)
+	}
+	var agentLoop *agent.AgentLoop
+	if opts.agentName != "" {
+		agentLoop, err = agentManager.GetOrCreateAgent(opts.agentName)
+	} else {
+		agentLoop, err = agentManager.GetDefaultAgent()
+	}
+	if err != nil {
+		fmt.Printf("Error getting agent: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	mic, err := voice.OpenMic(ctx, firstNonEmpty(opts.micCommand, cfg.Voice.MicCommand))
+	if err != nil {
+		fmt.Printf("Error opening microphone: %v\n", err)
+		os.Exit(1)
+	}
+	defer mic.Close()
+
+	listener := &voice.Listener{
+		Transcriber: transcriber,
+		TTS:         tts,
+		WakeWord:    wakeWord,
+		Language:    opts.language,
+		Reply: func(ctx context.Context, prompt string) (string, error) {
+			return agentLoop.ProcessDirect(ctx, prompt, nil, opts.sessionKey)
+		},
+		OnEvent: func(e voice.ListenEvent) {
+			switch e.Kind {
+			case voice.EventHeard:
+				fmt.Printf("👂 %s\n", e.Text)
+			case voice.EventAwake:
+				fmt.Println("🐸 Listening...")
+			case voice.EventReply:
+				fmt.Printf("🐸 %s\n\n", e.Text)
+			case voice.EventError:
+				fmt.Printf("✗ %s\n", e.Text)
+			}
+		},
+	}
+
+	if wakeWord != "" {
+		fmt.Printf("🎙️  Listening for \"%s\" (Ctrl+C to stop)\n\n", wakeWord)
+	} else {
+		fmt.Println("🎙️  Listening (Ctrl+C to stop)")
+		fmt.Println()
+	}
+	if err := listener.Run(ctx, mic); err != nil && ctx.Err() == nil {
+		fmt.Printf("Error reading microphone: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// evalOptions are the flags of pepebot eval run and pepebot skills test
+type evalOptions struct {
+	agentName  string
+	model      string
+	caseFilter string
+	record     string // cassette to save model responses to
+	replay     string // cassette to answer from
+	sampling   *providers.Sampling
+	verbose    bool
+	asJSON     bool
+}
+
+// apply sets the overrides of the flags on a suite
+func (o evalOptions) apply(suite *eval.Suite) {
+	if o.agentName != "" {
+		suite.Agent = o.agentName
+	}
+	if o.model != "" {
+		suite.Model = o.model
+	}
+	if o.sampling.Temperature != nil {
+		suite.Temperature = o.sampling.Temperature
+	}
+	if o.sampling.Seed != nil {
+		suite.Seed = o.sampling.Seed
+	}
+}
+
+// newEvalManager creates the agent manager eval suites run on, sending
+// model requests through a cassette when recording or replaying
+func newEvalManager(o evalOptions) *agent.AgentManager {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if o.record != "" || o.replay != "" {
+		cassette, mode := o.record, providers.RecordMode
+		if o.replay != "" {
+			cassette, mode = o.replay, providers.ReplayMode
+		}
+		recorder, err := providers.NewRecorder(cassette, mode)
+		if err != nil {
+			fmt.Printf("Error opening cassette: %v\n", err)
+			os.Exit(1)
+		}
+		providers.SetTransport(recorder)
+	}
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+	agentManager, err := agent.NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		fmt.Printf("Error creating agent manager: %v\n", err)
+		os.Exit(1)
+	}
+	return agentManager
+}
+
+// runEvalSuite runs a suite on a separate instance of its agent
+func runEvalSuite(ctx context.Context, agentManager *agent.AgentManager, suite *eval.Suite, caseFilter string) (*eval.Report, error) {
+	agentLoop, err := agentManager.ReplayAgent(suite.Agent, suite.Model)
+	if err != nil {
+		return nil, err
+	}
+	suite.Model = agentLoop.Model()
+	agentLoop.SetSampling(suite.Sampling())
+	return eval.Run(ctx, suite, &eval.AgentRunner{Agent: agentLoop}, caseFilter), nil
+}
+
+// evalRunCmd runs an eval suite and exits with status 1 when a case fails
+func evalRunCmd(file string, o evalOptions) {
+	suite, err := eval.Load(file)
+	if err != nil {
+		fmt.Printf("Error loading suite: %v\n", err)
+		os.Exit(1)
+	}
+	o.apply(suite)
+	agentManager := newEvalManager(o)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	report, err := runEvalSuite(ctx, agentManager, suite, o.caseFilter)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(report.Results) == 0 {
+		fmt.Printf("No cases match %q\n", o.caseFilter)
+		os.Exit(1)
+	}
+
+	if o.asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Println()
+		fmt.Print(report.Format(o.verbose))
+		fmt.Println()
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// skillsTestCmd runs the eval suites in the tests folder of skills, all
+// skills that have one when no name is given
+func skillsTestCmd(loader *skills.SkillsLoader, names []string, o evalOptions) {
+	if len(names) == 0 {
+		for _, s := range loader.ListSkills(false) {
+			if files, _ := loader.TestSuites(s.Name); len(files) > 0 {
+				names = append(names, s.Name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Println("No installed skill has tests.")
+			return
+		}
+	}
+
+	type skillSuites struct {
+		name   string
+		suites []*eval.Suite
+	}
+	var all []skillSuites
+	for _, name := range names {
+		files, err := loader.TestSuites(name)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			os.Exit(1)
+		}
+		if len(files) == 0 {
+			fmt.Printf("✗ Skill '%s' has no tests (add suites to its %s/ folder)\n", name, skills.TestsDir)
+			os.Exit(1)
+		}
+		entry := skillSuites{name: name}
+		for _, file := range files {
+			suite, err := eval.Load(file)
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", file, err)
+				os.Exit(1)
+			}
+			if suite.Name == "" {
+				suite.Name = name + "/" + strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			}
+			o.apply(suite)
+			entry.suites = append(entry.suites, suite)
+		}
+		all = append(all, entry)
+	}
+
+	agentManager := newEvalManager(o)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
 
-	if _, err := os.Stat(configPath); err == nil {
-		fmt.Printf("Model: %s\n", cfg.Agents.Defaults.Model)
+	type skillResult struct {
+		Skill   string         `json:"skill"`
+		Reports []*eval.Report `json:"reports"`
+		Passed  int            `json:"passed"`
+		Failed  int            `json:"failed"`
+	}
+	var results []skillResult
+	failed := false
+	for _, entry := range all {
+		result := skillResult{Skill: entry.name}
+		for _, suite := range entry.suites {
+			report, err := runEvalSuite(ctx, agentManager, suite, o.caseFilter)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			result.Reports = append(result.Reports, report)
+			result.Passed += report.Passed
+			result.Failed += report.Failed
+			if !o.asJSON && len(report.Results) > 0 {
+				fmt.Println()
+				fmt.Print(report.Format(o.verbose))
+			}
+		}
+		failed = failed || result.Failed > 0
+		results = append(results, result)
+	}
 
-		hasMAIARouter := cfg.Providers.MAIARouter.APIKey != ""
-		hasOpenRouter := cfg.Providers.OpenRouter.APIKey != ""
-		hasAnthropic := cfg.Providers.Anthropic.APIKey != ""
-		hasOpenAI := cfg.Providers.OpenAI.APIKey != ""
-		hasGemini := cfg.Providers.Gemini.APIKey != ""
-		hasZhipu := cfg.Providers.Zhipu.APIKey != ""
-		hasGroq := cfg.Providers.Groq.APIKey != ""
-		hasVLLM := cfg.Providers.VLLM.APIBase != ""
-
-		status := func(enabled bool) string {
-			if enabled {
-				return "✓"
+	if o.asJSON {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Println()
+		for _, r := range results {
+			mark := "✓"
+			if r.Failed > 0 {
+				mark = "✗"
 			}
-			return "not set"
+			fmt.Printf("%s %s: %d passed, %d failed\n", mark, r.Skill, r.Passed, r.Failed)
 		}
-		fmt.Println("\nProviders:")
-		fmt.Println("MAIA Router:", status(hasMAIARouter))
-		fmt.Println("OpenRouter API:", status(hasOpenRouter))
-		fmt.Println("Anthropic API:", status(hasAnthropic))
-		fmt.Println("OpenAI API:", status(hasOpenAI))
-		fmt.Println("Gemini API:", status(hasGemini))
-		fmt.Println("Zhipu API:", status(hasZhipu))
-		fmt.Println("Groq API:", status(hasGroq))
-		if hasVLLM {
-			fmt.Printf("vLLM/Local: ✓ %s\n", cfg.Providers.VLLM.APIBase)
-		} else {
-			fmt.Println("vLLM/Local: not set")
+		fmt.Println()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// requestsListCmd prints the newest logged gateway requests
+func requestsListCmd(limit int) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	path := filepath.Join(filepath.Dir(cfg.WorkspacePath()), gateway.RequestLogFile)
+	entries, err := gateway.ReadRequestLog(path)
+	if err != nil {
+		fmt.Printf("Error reading request log: %v\n", err)
+		os.Exit(1)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No requests in %s\n", path)
+		if !cfg.Gateway.RequestLog.Enabled {
+			fmt.Println("Request logging is off (gateway.request_log.enabled).")
+		}
+		return
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	for _, e := range entries {
+		status := ""
+		if e.Error != "" {
+			status = "  [error]"
 		}
+		fmt.Printf("%s  %s  %-10s %-20s %6dms  %s%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"),
+			e.Agent, e.Session, e.DurationMS, truncateLine(e.Prompt(), 60), status)
 	}
 }
 
-func getConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".pepebot", "config.json")
+// requestsReplayCmd runs a logged request again and prints both replies
+func requestsReplayCmd(id, agentName, model string, live, asJSON bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	entry, err := gateway.FindRequest(filepath.Join(filepath.Dir(cfg.WorkspacePath()), gateway.RequestLogFile), id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+	agentManager, err := agent.NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		fmt.Printf("Error creating agent manager: %v\n", err)
+		os.Exit(1)
+	}
+
+	result, err := gateway.Replay(context.Background(), agentManager, entry, agentName, model, live)
+	if err != nil {
+		fmt.Printf("Error replaying %s: %v\n", entry.ID, err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		data, _ := json.MarshalIndent(map[string]interface{}{
+			"request":  entry,
+			"replayed": result,
+		}, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Printf("\n── Original (%s, %dms) ──\n", entry.Agent, entry.DurationMS)
+	printReplayReply(entry.Response, entry.ToolCalls, entry.Error)
+	fmt.Printf("\n── Replay (%s on %s, %dms) ──\n", result.Agent, result.Model, result.DurationMS)
+	printReplayReply(result.Response, result.ToolCalls, "")
+	fmt.Println()
 }
 
-func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+func printReplayReply(content string, toolCalls []providers.ToolCall, errText string) {
+	if errText != "" {
+		fmt.Printf("Error: %s\n", errText)
+	}
+	if content != "" {
+		fmt.Println(content)
+	}
+	for _, tc := range toolCalls {
+		name, args := tc.Name, ""
+		if tc.Function != nil {
+			name, args = tc.Function.Name, tc.Function.Arguments
+		}
+		fmt.Printf("→ tool call %s %s\n", name, args)
+	}
 }
 
-func cronCmd() {
-	if len(os.Args) < 3 {
-		cronHelp()
+// promptsCmd prints the prompt experiment results
+func promptsCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := agent.VariantReport(agent.LoadVariantSessions(cfg.WorkspacePath()))
+	if len(report) == 0 {
+		fmt.Println("No prompt experiments yet. Add variants under workspace/variants/<variant>/ (default agent) or agents/<name>/variants/<variant>/.")
 		return
 	}
 
-	subcommand := os.Args[2]
+	fmt.Println("\nPrompt Variants:")
+	fmt.Println("----------------")
+	for _, stats := range report {
+		fmt.Printf("  %s / %s (version %s)\n", stats.Agent, stats.Variant, stats.Version)
+		fmt.Printf("    Sessions: %d, turns: %d\n", stats.Sessions, stats.Turns)
+		if len(stats.Outcomes) > 0 {
+			outcomes := make([]string, 0, len(stats.Outcomes))
+			for outcome, n := range stats.Outcomes {
+				outcomes = append(outcomes, fmt.Sprintf("%s %d", outcome, n))
+			}
+			sort.Strings(outcomes)
+			fmt.Printf("    Outcomes: %s\n", strings.Join(outcomes, ", "))
+		}
+	}
+	fmt.Println()
+}
 
-	dataDir := filepath.Join(filepath.Dir(getConfigPath()), "cron")
-	cronStorePath := filepath.Join(dataDir, "jobs.json")
+func feedsListCmd(store *feeds.Store) {
+	subs, err := store.List()
+	if err != nil {
+		fmt.Printf("Error loading subscriptions: %v\n", err)
+		return
+	}
+	if len(subs) == 0 {
+		fmt.Println("No feed subscriptions.")
+		return
+	}
 
-	switch subcommand {
-	case "list":
-		cronListCmd(cronStorePath)
-	case "add":
-		cronAddCmd(cronStorePath)
-	case "remove":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: pepebot cron remove <job_id>")
-			return
+	fmt.Println("\nFeed Subscriptions:")
+	fmt.Println("-------------------")
+	for _, sub := range subs {
+		fmt.Printf("  %s (%s)\n", sub.Title, sub.ID)
+		fmt.Printf("    URL: %s\n", sub.URL)
+		fmt.Printf("    Delivery: %s:%s (%s)\n", sub.Channel, sub.ChatID, sub.Mode)
+		if sub.LastError != "" {
+			fmt.Printf("    Last error: %s\n", sub.LastError)
+		} else if !sub.LastCheckedAt.IsZero() {
+			fmt.Printf("    Last checked: %s\n", sub.LastCheckedAt.Format("2006-01-02 15:04"))
 		}
-		cronRemoveCmd(cronStorePath, os.Args[3])
-	case "enable":
-		cronEnableCmd(cronStorePath, false)
-	case "disable":
-		cronEnableCmd(cronStorePath, true)
-	default:
-		fmt.Printf("Unknown cron command: %s\n", subcommand)
-		cronHelp()
 	}
 }
 
-func cronHelp() {
-	fmt.Println("\nCron commands:")
-	fmt.Println("  list              List all scheduled jobs")
-	fmt.Println("  add              Add a new scheduled job")
-	fmt.Println("  remove <id>       Remove a job by ID")
-	fmt.Println("  enable <id>      Enable a job")
-	fmt.Println("  disable <id>     Disable a job")
-	fmt.Println()
-	fmt.Println("Add options:")
-	fmt.Println("  -n, --name       Job name")
-	fmt.Println("  -m, --message    Message for agent")
-	fmt.Println("  -e, --every      Run every N seconds")
-	fmt.Println("  -c, --cron       Cron expression (e.g. '0 9 * * *')")
-	fmt.Println("  -d, --deliver     Deliver response to channel")
-	fmt.Println("  --to             Recipient for delivery")
-	fmt.Println("  --channel        Channel for delivery")
+func feedsAddCmd(store *feeds.Store, sub feeds.Subscription) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	feed, err := feeds.Fetch(ctx, http.DefaultClient, sub.URL)
+	if err != nil {
+		fmt.Printf("Error reading feed: %v\n", err)
+		return
+	}
+	if sub.Title == "" {
+		sub.Title = feed.Title
+	}
+
+	saved, err := store.Add(sub)
+	if err != nil {
+		fmt.Printf("Error adding subscription: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Subscribed to '%s' (%s)\n", saved.Title, saved.ID)
 }
 
-func cronListCmd(storePath string) {
+func cronListCmd(storePath string, verbose bool) {
 	cs := cron.NewCronService(storePath, nil)
 	jobs := cs.ListJobs(false)
 
@@ -1513,78 +3050,84 @@ func cronListCmd(storePath string) {
 
 		fmt.Printf("  %s (%s)\n", job.Name, job.ID)
 		fmt.Printf("    Schedule: %s\n", schedule)
+		var options []string
+		if job.Schedule.JitterMS > 0 {
+			options = append(options, fmt.Sprintf("jitter %ds", job.Schedule.JitterMS/1000))
+		}
+		if job.Schedule.Overlap != "" {
+			options = append(options, "overlap "+job.Schedule.Overlap)
+		}
+		if job.Schedule.CatchUp != "" {
+			options = append(options, "catch-up "+job.Schedule.CatchUp)
+		}
+		if len(options) > 0 {
+			fmt.Printf("    Options: %s\n", strings.Join(options, ", "))
+		}
 		fmt.Printf("    Status: %s\n", status)
 		fmt.Printf("    Next run: %s\n", nextRun)
-	}
-}
-
-func cronAddCmd(storePath string) {
-	name := ""
-	message := ""
-	var everySec *int64
-	cronExpr := ""
-	deliver := false
-	channel := ""
-	to := ""
-
-	args := os.Args[3:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "-n", "--name":
-			if i+1 < len(args) {
-				name = args[i+1]
-				i++
-			}
-		case "-m", "--message":
-			if i+1 < len(args) {
-				message = args[i+1]
-				i++
-			}
-		case "-e", "--every":
-			if i+1 < len(args) {
-				var sec int64
-				fmt.Sscanf(args[i+1], "%d", &sec)
-				everySec = &sec
-				i++
-			}
-		case "-c", "--cron":
-			if i+1 < len(args) {
-				cronExpr = args[i+1]
-				i++
-			}
-		case "-d", "--deliver":
-			deliver = true
-		case "--to":
-			if i+1 < len(args) {
-				to = args[i+1]
-				i++
-			}
-		case "--channel":
-			if i+1 < len(args) {
-				channel = args[i+1]
-				i++
-			}
+		if job.Payload.Kind == "autonomy" {
+			fmt.Printf("    Autonomy: reports to %s:%s\n", job.Payload.Channel, job.Payload.To)
+		}
+		if job.Payload.Kind == "workflow" {
+			fmt.Printf("    Workflow: %s (schedule from workflows/%s.json)\n", job.Payload.Workflow, job.Payload.Workflow)
+		}
+		if job.State.ConsecutiveFailures > 0 {
+			fmt.Printf("    Failing: %d in a row\n", job.State.ConsecutiveFailures)
+		}
+		if verbose {
+			printCronRuns(job.State.Runs)
 		}
 	}
+}
 
-	if name == "" {
-		fmt.Println("Error: --name is required")
+// printCronRuns prints a job's run history, newest first
+func printCronRuns(runs []cron.CronRun) {
+	if len(runs) == 0 {
+		fmt.Println("    Runs: none yet")
 		return
 	}
-
-	if message == "" {
-		fmt.Println("Error: --message is required")
-		return
+	fmt.Println("    Runs:")
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		started := time.UnixMilli(run.StartedAtMS).Format("2006-01-02 15:04:05")
+		duration := (time.Duration(run.DurationMS) * time.Millisecond).Round(time.Millisecond)
+		manual := ""
+		if run.Manual {
+			manual = " (manual)"
+		}
+		fmt.Printf("      %s  %-5s %s%s\n", started, run.Status, duration, manual)
+		detail := run.Output
+		if run.Error != "" {
+			detail = run.Error
+		}
+		if detail != "" {
+			fmt.Printf("        %s\n", strings.ReplaceAll(detail, "\n", " "))
+		}
 	}
+}
 
-	if everySec == nil && cronExpr == "" {
-		fmt.Println("Error: Either --every or --cron must be specified")
-		return
-	}
+// cronAddOptions are the flags of "pepebot cron add"
+type cronAddOptions struct {
+	name          string
+	message       string
+	everySec      int64 // 0 = use cronExpr
+	cronExpr      string
+	deliver       bool
+	channel       string
+	to            string
+	agentName     string
+	autonomy      bool
+	maxIterations int
+	maxMinutes    int
+	jitterSec     int64
+	overlap       string
+	catchUp       string
+}
 
+func cronAddCmd(storePath string, opts cronAddOptions) {
 	var schedule cron.CronSchedule
-	if everySec != nil {
-		everyMS := *everySec * 1000
+	if opts.everySec > 0 {
+		everyMS := opts.everySec * 1000
 		schedule = cron.CronSchedule{
 			Kind:    "every",
 			EveryMS: &everyMS,
@@ -1592,12 +3135,30 @@ func cronAddCmd(storePath string) {
 	} else {
 		schedule = cron.CronSchedule{
 			Kind: "cron",
-			Expr: cronExpr,
+			Expr: opts.cronExpr,
 		}
 	}
+	schedule.JitterMS = opts.jitterSec * 1000
+	schedule.Overlap = opts.overlap
+	schedule.CatchUp = opts.catchUp
+
+	payload := cron.CronPayload{
+		Kind:    "agent_turn",
+		Message: opts.message,
+		Deliver: opts.deliver,
+		Channel: opts.channel,
+		To:      opts.to,
+		Agent:   opts.agentName,
+	}
+	if opts.autonomy {
+		payload.Kind = "autonomy"
+		payload.Deliver = true
+		payload.MaxIterations = opts.maxIterations
+		payload.MaxMinutes = opts.maxMinutes
+	}
 
 	cs := cron.NewCronService(storePath, nil)
-	job, err := cs.AddJob(name, schedule, message, deliver, channel, to)
+	job, err := cs.AddPayloadJob(opts.name, schedule, payload)
 	if err != nil {
 		fmt.Printf("Error adding job: %v\n", err)
 		return
@@ -1615,13 +3176,7 @@ func cronRemoveCmd(storePath, jobID string) {
 	}
 }
 
-func cronEnableCmd(storePath string, disable bool) {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot cron enable/disable <job_id>")
-		return
-	}
-
-	jobID := os.Args[3]
+func cronEnableCmd(storePath, jobID string, disable bool) {
 	cs := cron.NewCronService(storePath, nil)
 	enabled := !disable
 
@@ -1637,14 +3192,156 @@ func cronEnableCmd(storePath string, disable bool) {
 	}
 }
 
-func skillsCmd() {
-	if len(os.Args) < 3 {
-		skillsHelp()
+// registerWorkflowSchedules turns the schedule blocks of workflow files into
+// cron jobs, updating or removing the jobs of earlier starts
+func registerWorkflowSchedules(cronService *cron.CronService, workspace string) {
+	jobs, errs := workflow.NewWorkflowHelper(workspace, nil).ScheduledJobs()
+	for _, err := range errs {
+		fmt.Printf("⚠ Workflow schedule skipped: %v\n", err)
+	}
+	added, updated, removed, err := cronService.SyncSourceJobs(workflow.ScheduleSourcePrefix, jobs)
+	if err != nil {
+		fmt.Printf("Error registering workflow schedules: %v\n", err)
 		return
 	}
+	if len(jobs) > 0 || removed > 0 {
+		fmt.Printf("✓ Workflow schedules: %d (%d added, %d updated, %d removed)\n", len(jobs), added, updated, removed)
+	}
+}
+
+// cronJobHandler runs due cron jobs in the gateway. Autonomy sessions can
+// take an hour, so they run in the background and report to their channel
+// themselves instead of holding up other jobs.
+func cronJobHandler(ctx context.Context, agentManager *agent.AgentManager, channelManager *channels.Manager) cron.JobHandler {
+	return func(job *cron.CronJob) (string, error) {
+		payload := job.Payload
+		switch payload.Kind {
+		case "autonomy":
+			go func() {
+				result, err := agentManager.RunAutonomous(ctx, agent.AutonomyTask{
+					Name:          job.Name,
+					Objective:     payload.Message,
+					Agent:         payload.Agent,
+					MaxIterations: payload.MaxIterations,
+					MaxMinutes:    payload.MaxMinutes,
+				})
+				content := ""
+				if err != nil {
+					logger.ErrorCF("cron", "Autonomy session failed", map[string]interface{}{
+						"job":   job.Name,
+						"error": err.Error(),
+					})
+					content = fmt.Sprintf("⚠️ Autonomous session '%s' failed: %v", job.Name, err)
+				} else {
+					content = fmt.Sprintf("🤖 %s\n\n%s", job.Name, result.Summary)
+					if result.TranscriptPath != "" {
+						content += fmt.Sprintf("\n\nTranscript: %s", result.TranscriptPath)
+					}
+				}
+				if payload.Channel != "" && payload.To != "" {
+					if err := channelManager.SendToChannel(ctx, payload.Channel, payload.To, content); err != nil {
+						logger.ErrorCF("cron", "Failed to deliver autonomy summary", map[string]interface{}{
+							"job":   job.Name,
+							"error": err.Error(),
+						})
+					}
+				}
+			}()
+			return "started", nil
+		case "workflow":
+			output, err := agentManager.RunWorkflow(ctx, payload.Workflow, payload.Vars)
+			if payload.Deliver && payload.Channel != "" && payload.To != "" {
+				content := fmt.Sprintf("📋 Workflow '%s'\n\n%s", payload.Workflow, output)
+				if err != nil {
+					content = fmt.Sprintf("⚠️ Workflow '%s' failed: %v", payload.Workflow, err)
+				}
+				if sendErr := channelManager.SendToChannel(ctx, payload.Channel, payload.To, content); sendErr != nil && err == nil {
+					err = sendErr
+				}
+			}
+			return output, err
+		default:
+			response, err := agentManager.ProcessDirect(ctx, payload.Message, nil, "cron:"+job.ID, payload.Agent)
+			if err != nil {
+				return "", err
+			}
+			if payload.Deliver && payload.Channel != "" && payload.To != "" {
+				if err := channelManager.SendToChannel(ctx, payload.Channel, payload.To, response); err != nil {
+					return response, err
+				}
+			}
+			return response, nil
+		}
+	}
+}
+
+// logcatHandler acts on matched logcat lines: watchers with a workflow run
+// it, the others send their prompt to an agent through the bus
+func logcatHandler(agentManager *agent.AgentManager, channelManager *channels.Manager, msgBus *bus.MessageBus) tools.LogcatHandler {
+	return func(ctx context.Context, event tools.LogcatEvent) {
+		w := event.Watcher
+		if w.Workflow == "" {
+			msgBus.PublishInbound(bus.InboundMessage{
+				Channel:    w.Channel,
+				SenderID:   "logcat:" + w.Name,
+				ChatID:     w.ChatID,
+				Content:    event.Prompt(),
+				SessionKey: "logcat:" + w.Name,
+				Metadata: map[string]string{
+					"agent":   w.Agent,
+					"event":   "logcat",
+					"watcher": w.Name,
+				},
+			})
+			return
+		}
+
+		vars := make(map[string]string)
+		for k, v := range w.Vars {
+			vars[k] = v
+		}
+		for k, v := range event.Vars() {
+			vars[k] = v
+		}
+		output, err := agentManager.RunWorkflow(ctx, w.Workflow, vars)
+		if err != nil {
+			logger.ErrorCF("adb", "Logcat workflow failed", map[string]interface{}{
+				"watcher":  w.Name,
+				"workflow": w.Workflow,
+				"error":    err.Error(),
+			})
+		}
+		if w.Channel != "" && w.ChatID != "" {
+			content := fmt.Sprintf("📋 Workflow '%s' (logcat: %s)\n\n%s", w.Workflow, w.Name, output)
+			if err != nil {
+				content = fmt.Sprintf("⚠️ Workflow '%s' (logcat: %s) failed: %v", w.Workflow, w.Name, err)
+			}
+			if err := channelManager.SendToChannel(ctx, w.Channel, w.ChatID, content); err != nil {
+				logger.ErrorCF("adb", "Failed to deliver logcat workflow result", map[string]interface{}{
+					"watcher": w.Name,
+					"error":   err.Error(),
+				})
+			}
+		}
+	}
+}
 
-	subcommand := os.Args[2]
+// cronFailureAlert reports a job that keeps failing to the configured chat
+func cronFailureAlert(ctx context.Context, channelManager *channels.Manager, channel, chatID string) cron.AlertHandler {
+	return func(job cron.CronJob) {
+		content := fmt.Sprintf("⚠️ Cron job '%s' (%s) has failed %d times in a row.\n\nLast error: %s",
+			job.Name, job.ID, job.State.ConsecutiveFailures, job.State.LastError)
+		if err := channelManager.SendToChannel(ctx, channel, chatID, content); err != nil {
+			logger.ErrorCF("cron", "Failed to send failure alert", map[string]interface{}{
+				"job":   job.Name,
+				"error": err.Error(),
+			})
+		}
+	}
+}
 
+// skillsTools returns the installer and loader for the configured workspace
+func skillsTools() (*skills.SkillInstaller, *skills.SkillsLoader) {
 	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
@@ -1653,50 +3350,11 @@ func skillsCmd() {
 
 	workspace := cfg.WorkspacePath()
 	installer := skills.NewSkillInstaller(workspace)
-	skillsLoader := skills.NewSkillsLoader(workspace, "")
-
-	switch subcommand {
-	case "list":
-		skillsListCmd(skillsLoader)
-	case "install":
-		skillsInstallCmd(installer)
-	case "remove", "uninstall":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: pepebot skills remove <skill-name>")
-			return
-		}
-		skillsRemoveCmd(installer, os.Args[3])
-	case "search":
-		skillsSearchCmd(installer)
-	case "show":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: pepebot skills show <skill-name>")
-			return
-		}
-		skillsShowCmd(skillsLoader, os.Args[3])
-	default:
-		fmt.Printf("Unknown skills command: %s\n", subcommand)
-		skillsHelp()
-	}
-}
-
-func skillsHelp() {
-	fmt.Println("\nSkills commands:")
-	fmt.Println("  list                    List installed skills")
-	fmt.Println("  install <repo>          Install skill from GitHub")
-	fmt.Println("  install-builtin         Install all builtin skills from pepebot-space/skills-builtin")
-	fmt.Println("  remove <name>           Remove installed skill")
-	fmt.Println("  search                  Search available skills")
-	fmt.Println("  show <name>             Show skill details")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  pepebot skills list")
-	fmt.Println("  pepebot skills install pepebot/skills/weather")
-	fmt.Println("  pepebot skills install-builtin")
-	fmt.Println("  pepebot skills remove weather")
+	installer.Configure(cfg.Skills)
+	return installer, skills.NewSkillsLoader(workspace, "")
 }
 
-func skillsListCmd(loader *skills.SkillsLoader) {
+func skillsListCmd(loader *skills.SkillsLoader, installer *skills.SkillInstaller) {
 	allSkills := loader.ListSkills(false)
 
 	if len(allSkills) == 0 {
@@ -1704,6 +3362,23 @@ func skillsListCmd(loader *skills.SkillsLoader) {
 		return
 	}
 
+	lock, err := installer.LoadLock()
+	if err != nil {
+		fmt.Printf("⚠ %v\n", err)
+		lock = &skills.Lockfile{}
+	}
+
+	// Checking for updates needs GitHub; keep it short and quiet on failure
+	updates := map[string]skills.SkillUpdate{}
+	if len(lock.Skills) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		available, _ := installer.CheckUpdates(ctx)
+		cancel()
+		for _, u := range available {
+			updates[u.Name] = u
+		}
+	}
+
 	fmt.Println("\nInstalled Skills:")
 	fmt.Println("------------------")
 	for _, skill := range allSkills {
@@ -1711,24 +3386,76 @@ func skillsListCmd(loader *skills.SkillsLoader) {
 		if !skill.Available {
 			status = "✗"
 		}
-		fmt.Printf("  %s %s (%s)\n", status, skill.Name, skill.Source)
+		version := ""
+		if entry, ok := lock.Skills[skill.Name]; ok && skill.Source == "workspace" {
+			version = " " + entry.Source
+			if entry.Version != "" {
+				version += "@" + skills.ShortVersion(entry.Version)
+			}
+		}
+		fmt.Printf("  %s %s (%s)%s\n", status, skill.Name, skill.Source, version)
 		if skill.Description != "" {
 			fmt.Printf("    %s\n", skill.Description)
 		}
 		if !skill.Available {
 			fmt.Printf("    Missing: %s\n", skill.Missing)
+			if skill.Install != "" {
+				fmt.Printf("    Install them with: pepebot skills check %s --install\n", skill.Name)
+			}
+		}
+		if u, ok := updates[skill.Name]; ok && skill.Source == "workspace" {
+			fmt.Printf("    ⬆ Update available: %s (pepebot skills update %s)\n", skills.ShortVersion(u.Latest), skill.Name)
 		}
 	}
 }
 
-func skillsInstallCmd(installer *skills.SkillInstaller) {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot skills install <github-repo>")
-		fmt.Println("Example: pepebot skills install pepebot/skills/weather")
-		return
+func skillsUpdateCmd(installer *skills.SkillInstaller, names []string, all bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if all {
+		names = nil
+		updates, err := installer.CheckUpdates(ctx)
+		if err != nil {
+			fmt.Printf("✗ Failed to check for updates: %v\n", err)
+			os.Exit(1)
+		}
+		builtinDone := false
+		for _, u := range updates {
+			// One builtin update re-installs every builtin skill
+			if u.Source == "builtin" {
+				if builtinDone {
+					continue
+				}
+				builtinDone = true
+			}
+			names = append(names, u.Name)
+		}
+		if len(names) == 0 {
+			fmt.Println("✓ All skills are up to date")
+			return
+		}
+	}
+
+	failed := false
+	for _, name := range names {
+		updated, err := installer.Update(ctx, name)
+		switch {
+		case err != nil:
+			fmt.Printf("✗ %s: %v\n", name, err)
+			failed = true
+		case updated:
+			fmt.Printf("✓ Updated %s\n", name)
+		default:
+			fmt.Printf("✓ %s is up to date\n", name)
+		}
+	}
+	if failed {
+		os.Exit(1)
 	}
+}
 
-	repo := os.Args[3]
+func skillsInstallCmd(installer *skills.SkillInstaller, loader *skills.SkillsLoader, repo string) {
 	fmt.Printf("Installing skill from %s...\n", repo)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -1738,8 +3465,81 @@ func skillsInstallCmd(installer *skills.SkillInstaller) {
 		fmt.Printf("✗ Failed to install skill: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Printf("✓ Skill '%s' installed successfully!\n", filepath.Base(repo))
+
+	name := filepath.Base(repo)
+	if src, err := skills.ParseSkillSource(repo); err == nil {
+		name = src.Name()
+	}
+	fmt.Printf("✓ Skill '%s' installed successfully!\n", name)
+
+	if report, err := loader.CheckSkill(name); err == nil && len(report.Missing) > 0 {
+		printSkillRequirements(report)
+	}
+}
+
+func printSkillRequirements(report *skills.RequirementReport) {
+	fmt.Printf("  ⚠ %s is missing requirements:\n", report.Name)
+	for _, m := range report.Missing {
+		fmt.Printf("    Missing: %s\n", m)
+	}
+	if report.Install != "" {
+		fmt.Printf("    Install them with: pepebot skills check %s --install\n", report.Name)
+	}
+}
+
+func skillsCheckCmd(loader *skills.SkillsLoader, names []string, install bool) {
+	if len(names) == 0 {
+		for _, skill := range loader.ListSkills(false) {
+			names = append(names, skill.Name)
+		}
+	}
+
+	failed := false
+	for _, name := range names {
+		report, err := loader.CheckSkill(name)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			failed = true
+			continue
+		}
+		if len(report.Missing) == 0 {
+			fmt.Printf("✓ %s\n", name)
+			continue
+		}
+
+		if install && report.Install != "" {
+			fmt.Printf("→ Running install hook for %s: %s\n", name, report.Install)
+			output, err := loader.RunInstallHook(context.Background(), name)
+			if output != "" {
+				fmt.Println(strings.TrimRight(output, "\n"))
+			}
+			if err != nil {
+				fmt.Printf("✗ %v\n", err)
+			}
+			report, err = loader.CheckSkill(name)
+			if err != nil {
+				fmt.Printf("✗ %v\n", err)
+				failed = true
+				continue
+			}
+			if len(report.Missing) == 0 {
+				fmt.Printf("✓ %s\n", name)
+				continue
+			}
+		}
+
+		fmt.Printf("✗ %s\n", name)
+		for _, m := range report.Missing {
+			fmt.Printf("    Missing: %s\n", m)
+		}
+		if report.Install != "" && !install {
+			fmt.Printf("    Install them with: pepebot skills check %s --install\n", name)
+		}
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
 }
 
 func skillsRemoveCmd(installer *skills.SkillInstaller, skillName string) {
@@ -1776,20 +3576,25 @@ func skillsInstallBuiltinCmd(installer *skills.SkillInstaller) {
 	fmt.Println("  Use 'pepebot skills list' to see installed skills")
 }
 
-func skillsSearchCmd(installer *skills.SkillInstaller) {
+func skillsSearchCmd(installer *skills.SkillInstaller, query, category string) {
+
 	fmt.Println("Searching for available skills...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	availableSkills, err := installer.ListAvailableSkills(ctx)
+	availableSkills, err := installer.SearchSkills(ctx, query, category)
 	if err != nil {
 		fmt.Printf("✗ Failed to fetch skills list: %v\n", err)
 		return
 	}
 
 	if len(availableSkills) == 0 {
-		fmt.Println("No skills available.")
+		if query != "" || category != "" {
+			fmt.Println("No skills match your search.")
+		} else {
+			fmt.Println("No skills available.")
+		}
 		return
 	}
 
@@ -1798,7 +3603,20 @@ func skillsSearchCmd(installer *skills.SkillInstaller) {
 	for _, skill := range availableSkills {
 		fmt.Printf("  📦 %s\n", skill.Name)
 		fmt.Printf("     %s\n", skill.Description)
-		fmt.Printf("     Install: pepebot skills install pepebot-space/skills/%s\n", skill.Path)
+		var details []string
+		if skill.Category != "" {
+			details = append(details, "category: "+skill.Category)
+		}
+		if skill.Downloads > 0 {
+			details = append(details, fmt.Sprintf("⬇ %d", skill.Downloads))
+		}
+		if skill.Rating > 0 {
+			details = append(details, fmt.Sprintf("★ %.1f", skill.Rating))
+		}
+		if len(details) > 0 {
+			fmt.Printf("     %s\n", strings.Join(details, "  "))
+		}
+		fmt.Printf("     Install: pepebot skills install %s\n", skill.InstallCommand())
 		fmt.Println()
 	}
 }
@@ -1840,39 +3658,6 @@ func loadAgentRegistry() (*agent.AgentRegistry, error) {
 	return registry, nil
 }
 
-func agentHelpCmd() {
-	fmt.Println("\n🐸 Pepebot Agent Management")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Println("Usage: pepebot agent <subcommand> [options]\n")
-	fmt.Println("Subcommands:")
-	fmt.Println("  list                    List all registered agents")
-	fmt.Println("  register <name>         Register a new agent")
-	fmt.Println("  remove <name>           Remove an agent")
-	fmt.Println("  enable <name>           Enable an agent")
-	fmt.Println("  disable <name>          Disable an agent")
-	fmt.Println("  show <name>             Show agent details")
-	fmt.Println("  help                    Show this help message")
-	fmt.Println("\nChat mode options:")
-	fmt.Println("  -a, --agent <name>      Use specific agent")
-	fmt.Println("  -m, --message <text>    One-shot message")
-	fmt.Println("  [message]               Positional one-shot message")
-	fmt.Println("  -s, --session <key>     Session key")
-	fmt.Println("  -v, --verbose           Enable DEBUG logs")
-	fmt.Println("\nOptions for 'register':")
-	fmt.Println("  --model <model>         Model to use (required)")
-	fmt.Println("  --provider <provider>   Provider name (optional)")
-	fmt.Println("  --description <desc>    Agent description (optional)")
-	fmt.Println("  --temperature <temp>    Temperature (0.0-1.0, optional)")
-	fmt.Println("  --max-tokens <n>        Max tokens (optional)")
-	fmt.Println("\nExamples:")
-	fmt.Println("  pepebot agent list")
-	fmt.Println("  pepebot agent register coder --model \"maia/claude-3-5-sonnet\" --description \"Coding specialist\"")
-	fmt.Println("  pepebot agent enable coder")
-	fmt.Println("  pepebot agent show coder")
-	fmt.Println("  pepebot agent remove coder")
-	fmt.Println()
-}
-
 func agentListCmd() {
 	registry, err := loadAgentRegistry()
 	if err != nil {
@@ -1887,7 +3672,7 @@ func agentListCmd() {
 	}
 
 	fmt.Println("\n🐸 Registered Agents")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 
 	// Sort agent names for consistent output
 	names := make([]string, 0, len(agents))
@@ -1917,70 +3702,13 @@ func agentListCmd() {
 	}
 }
 
-func agentRegisterCmd() {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot agent register <name> --model <model> [options]")
-		fmt.Println("Run 'pepebot agent help' for more information")
-		os.Exit(1)
-	}
-
-	name := os.Args[3]
-	var model, provider, description string
-	var temperature float64
-	var maxTokens int
-
-	// Parse flags
-	args := os.Args[4:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "--model":
-			if i+1 < len(args) {
-				model = args[i+1]
-				i++
-			}
-		case "--provider":
-			if i+1 < len(args) {
-				provider = args[i+1]
-				i++
-			}
-		case "--description":
-			if i+1 < len(args) {
-				description = args[i+1]
-				i++
-			}
-		case "--temperature":
-			if i+1 < len(args) {
-				fmt.Sscanf(args[i+1], "%f", &temperature)
-				i++
-			}
-		case "--max-tokens":
-			if i+1 < len(args) {
-				fmt.Sscanf(args[i+1], "%d", &maxTokens)
-				i++
-			}
-		}
-	}
-
-	if model == "" {
-		fmt.Println("Error: --model is required")
-		os.Exit(1)
-	}
-
+func agentRegisterCmd(name string, agentDef *agent.AgentDefinition) {
 	registry, err := loadAgentRegistry()
 	if err != nil {
 		fmt.Printf("Error loading registry: %v\n", err)
 		os.Exit(1)
 	}
 
-	agentDef := &agent.AgentDefinition{
-		Enabled:     true,
-		Model:       model,
-		Provider:    provider,
-		Description: description,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-	}
-
 	if err := registry.Register(name, agentDef); err != nil {
 		fmt.Printf("Error registering agent: %v\n", err)
 		os.Exit(1)
@@ -1997,20 +3725,14 @@ func agentRegisterCmd() {
 		fmt.Printf("Warning: could not create agent directory: %v\n", err)
 	}
 
-	fmt.Printf("✓ Registered agent '%s' with model '%s'\n", name, model)
+	fmt.Printf("✓ Registered agent '%s' with model '%s'\n", name, agentDef.Model)
 	if agentDir != "" {
 		fmt.Printf("  Agent directory: %s\n", agentDir)
 		fmt.Printf("  Tip: Add SOUL.md, USER.md, etc. in this folder to personalize the agent\n")
 	}
 }
 
-func agentRemoveCmd() {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot agent remove <name>")
-		os.Exit(1)
-	}
-
-	name := os.Args[3]
+func agentRemoveCmd(name string) {
 
 	registry, err := loadAgentRegistry()
 	if err != nil {
@@ -2031,13 +3753,7 @@ func agentRemoveCmd() {
 	fmt.Printf("✓ Removed agent '%s'\n", name)
 }
 
-func agentEnableCmd() {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot agent enable <name>")
-		os.Exit(1)
-	}
-
-	name := os.Args[3]
+func agentEnableCmd(name string) {
 
 	registry, err := loadAgentRegistry()
 	if err != nil {
@@ -2058,13 +3774,7 @@ func agentEnableCmd() {
 	fmt.Printf("✓ Enabled agent '%s'\n", name)
 }
 
-func agentDisableCmd() {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot agent disable <name>")
-		os.Exit(1)
-	}
-
-	name := os.Args[3]
+func agentDisableCmd(name string) {
 
 	registry, err := loadAgentRegistry()
 	if err != nil {
@@ -2085,13 +3795,7 @@ func agentDisableCmd() {
 	fmt.Printf("✓ Disabled agent '%s'\n", name)
 }
 
-func agentShowCmd() {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: pepebot agent show <name>")
-		os.Exit(1)
-	}
-
-	name := os.Args[3]
+func agentShowCmd(name string) {
 
 	registry, err := loadAgentRegistry()
 	if err != nil {
@@ -2111,7 +3815,7 @@ func agentShowCmd() {
 	}
 
 	fmt.Printf("\n🐸 Agent: %s\n", name)
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 	fmt.Printf("  Status:      %s\n", status)
 	fmt.Printf("  Model:       %s\n", agentDef.Model)
 	if agentDef.Provider != "" {
@@ -2173,68 +3877,14 @@ func (p *cliGoalProcessor) ProcessGoal(ctx context.Context, goal string) (string
 // Workflow Commands
 // =============================================================================
 
-func workflowCmd() {
-	if len(os.Args) < 3 {
-		workflowHelp()
-		return
-	}
-
-	subcommand := os.Args[2]
-
+// workflowWorkspace loads the config and returns it with the workspace path
+func workflowWorkspace() (string, *config.Config) {
 	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
-
-	workspace := cfg.WorkspacePath()
-
-	switch subcommand {
-	case "list":
-		workflowListCmd(workspace, cfg)
-	case "show":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: pepebot workflow show <name>")
-			return
-		}
-		workflowShowCmd(workspace, cfg, os.Args[3])
-	case "run":
-		workflowRunCmd(workspace, cfg)
-	case "delete", "remove":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: pepebot workflow delete <name>")
-			return
-		}
-		workflowDeleteCmd(workspace, cfg, os.Args[3])
-	case "validate":
-		workflowValidateCmd(workspace, cfg)
-	default:
-		fmt.Printf("Unknown workflow command: %s\n", subcommand)
-		workflowHelp()
-	}
-}
-
-func workflowHelp() {
-	fmt.Println("\nWorkflow commands:")
-	fmt.Println("  list                         List all workflows in workspace")
-	fmt.Println("  show <name>                  Show workflow details (steps, variables)")
-	fmt.Println("  run <name> [options]          Execute a workflow from workspace")
-	fmt.Println("    -f, --file <path>           Load workflow from file instead of workspace")
-	fmt.Println("    --var key=value             Override a workflow variable (repeatable)")
-	fmt.Println("  delete <name>                Delete a workflow from workspace")
-	fmt.Println("  validate <name>              Validate workflow structure")
-	fmt.Println("    -f, --file <path>           Validate a file instead of workspace workflow")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  pepebot workflow list")
-	fmt.Println("  pepebot workflow show my_workflow")
-	fmt.Println("  pepebot workflow run my_workflow")
-	fmt.Println("  pepebot workflow run my_workflow --var device=emulator-5554 --var query=hello")
-	fmt.Println("  pepebot workflow run -f /tmp/test.json")
-	fmt.Println("  pepebot workflow run -f /tmp/test.json --var key=value")
-	fmt.Println("  pepebot workflow validate my_workflow")
-	fmt.Println("  pepebot workflow validate -f /tmp/test.json")
-	fmt.Println("  pepebot workflow delete old_workflow")
+	return cfg.WorkspacePath(), cfg
 }
 
 func newWorkflowHelper(workspace string, cfg *config.Config, goalProcessor workflow.GoalProcessor) *workflow.WorkflowHelper {
@@ -2242,10 +3892,25 @@ func newWorkflowHelper(workspace string, cfg *config.Config, goalProcessor workf
 	registry.Register(tools.NewReadFileTool(workspace))
 	registry.Register(tools.NewWriteFileTool(workspace))
 	registry.Register(tools.NewListDirTool(workspace))
-	registry.Register(tools.NewExecTool(workspace))
+	execTool := tools.NewExecTool(workspace)
+	execTool.SetRestrictToWorkspace(cfg.Tools.Filesystem.RestrictToWorkspace)
+	registry.Register(execTool)
 	registry.Register(tools.NewWebSearchTool(cfg.Tools.Web.Search.APIKey, cfg.Tools.Web.Search.MaxResults))
-	registry.Register(tools.NewWebFetchTool(50000))
+	webFetch := tools.NewWebFetchTool(50000)
+	webFetch.SetBrowser(cfg.Tools.Web.Fetch.Browser)
+	registry.Register(webFetch)
+	download := tools.NewDownloadFileTool(workspace)
+	download.SetMaxBytes(int64(cfg.Tools.Download.MaxMB) << 20)
+	registry.Register(download)
+	registry.Register(tools.NewArchiveCreateTool(workspace))
+	registry.Register(tools.NewArchiveExtractTool(workspace))
+	registry.Register(tools.NewCSVReadTool(workspace))
+	registry.Register(tools.NewCSVWriteTool(workspace))
+	registry.Register(tools.NewCSVQueryTool(workspace))
 	registry.Register(tools.NewManageMCPTool(workspace))
+	dailyNotes := journal.New(workspace)
+	registry.Register(tools.NewJournalAppendTool(dailyNotes))
+	registry.Register(tools.NewJournalReadTool(dailyNotes))
 	if _, count, err := tools.RegisterMCPTools(workspace, registry); err != nil {
 		logger.WarnCF("mcp", "Failed to register MCP tools for workflow CLI", map[string]interface{}{"error": err.Error()})
 	} else if count > 0 {
@@ -2272,6 +3937,16 @@ func newWorkflowHelper(workspace string, cfg *config.Config, goalProcessor workf
 		registry.Register(tools.NewAdbSwipeTool(adbHelper))
 		registry.Register(tools.NewAdbOpenAppTool(adbHelper))
 		registry.Register(tools.NewAdbKeyEventTool(adbHelper))
+		registry.Register(tools.NewAdbLogcatTool(adbHelper))
+		registry.Register(tools.NewAdbWaitForTool(adbHelper))
+		registry.Register(tools.NewAdbGetLocationTool(adbHelper))
+		registry.Register(tools.NewAdbSetMockLocationTool(adbHelper))
+		registry.Register(tools.NewAdbSendIntentTool(adbHelper))
+		registry.Register(tools.NewAdbScrcpyStartTool(adbHelper))
+		registry.Register(tools.NewAdbScrcpyStopTool(adbHelper))
+		if gp, ok := goalProcessor.(*cliGoalProcessor); ok {
+			registry.Register(tools.NewScreenLocateToolFromConfig(cfg, adbHelper, gp.provider, gp.model))
+		}
 	}
 
 	helper := workflow.NewWorkflowHelper(workspace, registry)
@@ -2281,6 +3956,7 @@ func newWorkflowHelper(workspace string, cfg *config.Config, goalProcessor workf
 	registry.Register(tools.NewWorkflowExecuteTool(helper))
 	registry.Register(tools.NewWorkflowSaveTool(helper))
 	registry.Register(tools.NewWorkflowListTool(helper))
+	registry.SetGuard(tools.NewFileGuardFromConfig(cfg.Tools.Filesystem, workspace, filepath.Dir(cfg.WorkspacePath())))
 
 	return helper
 }
@@ -2356,42 +4032,7 @@ func workflowShowCmd(workspace string, cfg *config.Config, name string) {
 	fmt.Println()
 }
 
-func workflowRunCmd(workspace string, cfg *config.Config) {
-	args := os.Args[3:]
-	workflowName := ""
-	filePath := ""
-	overrideVars := map[string]string{}
-
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "-f", "--file":
-			if i+1 < len(args) {
-				filePath = args[i+1]
-				i++
-			}
-		case "--var":
-			if i+1 < len(args) {
-				parts := strings.SplitN(args[i+1], "=", 2)
-				if len(parts) == 2 {
-					overrideVars[parts[0]] = parts[1]
-				} else {
-					fmt.Printf("Warning: --var %q is not in key=value format, skipping\n", args[i+1])
-				}
-				i++
-			}
-		default:
-			if workflowName == "" && !strings.HasPrefix(args[i], "-") {
-				workflowName = args[i]
-			}
-		}
-	}
-
-	if workflowName == "" && filePath == "" {
-		fmt.Println("Usage: pepebot workflow run <name> [--var key=value ...]")
-		fmt.Println("       pepebot workflow run -f <path> [--var key=value ...]")
-		return
-	}
-
+func workflowRunCmd(workspace string, cfg *config.Config, workflowName, filePath string, overrideVars map[string]string) {
 	// Create LLM provider for goal step processing
 	var goalProc workflow.GoalProcessor
 	provider, err := providers.CreateProvider(cfg)
@@ -2456,31 +4097,7 @@ func workflowDeleteCmd(workspace string, cfg *config.Config, name string) {
 	fmt.Printf("✓ Deleted workflow %q\n", name)
 }
 
-func workflowValidateCmd(workspace string, cfg *config.Config) {
-	args := os.Args[3:]
-	workflowName := ""
-	filePath := ""
-
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "-f", "--file":
-			if i+1 < len(args) {
-				filePath = args[i+1]
-				i++
-			}
-		default:
-			if workflowName == "" && !strings.HasPrefix(args[i], "-") {
-				workflowName = args[i]
-			}
-		}
-	}
-
-	if workflowName == "" && filePath == "" {
-		fmt.Println("Usage: pepebot workflow validate <name>")
-		fmt.Println("       pepebot workflow validate -f <path>")
-		return
-	}
-
+func workflowValidateCmd(workspace string, cfg *config.Config, workflowName, filePath string) {
 	helper := newWorkflowHelper(workspace, cfg, nil)
 
 	var wfDef *workflow.WorkflowDefinition
@@ -2509,41 +4126,203 @@ func workflowValidateCmd(workspace string, cfg *config.Config) {
 	fmt.Printf("✓ Workflow %q is valid (%d steps)\n", source, len(wfDef.Steps))
 }
 
-// =============================================================================
-// Update Command
-// =============================================================================
+func workflowTemplatesCmd() {
+	fmt.Println("\nWorkflow templates:")
+	fmt.Println("--------------------------------")
+	for _, t := range workflow.Templates() {
+		fmt.Printf("  %-22s %s\n", t.Name, t.Description)
+	}
+	fmt.Println("\nCreate one with: pepebot workflow new [name] --template <template>")
+}
+
+func workflowNewCmd(workspace string, cfg *config.Config, name, templateName string, values map[string]string) {
+	tmpl, ok := workflow.FindTemplate(templateName)
+	if !ok {
+		fmt.Printf("✗ Unknown template %q\n", templateName)
+		workflowTemplatesCmd()
+		os.Exit(1)
+	}
+	if name == "" {
+		name = tmpl.Name
+	}
+	name = strings.TrimSuffix(name, ".json")
 
-func updateCmd(args []string) {
-	onlyBinary := false
-	onlySkills := false
+	helper := newWorkflowHelper(workspace, cfg, nil)
+	if _, err := helper.LoadWorkflow(name); err == nil {
+		fmt.Printf("Workflow %q already exists. Overwrite? (y/n): ", name)
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(strings.TrimSpace(response)) != "y" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
 
-	for _, arg := range args {
-		switch arg {
-		case "--only-binary":
-			onlyBinary = true
-		case "--only-skills":
-			onlySkills = true
+	// Ask for every variable not given with --var; Enter keeps the default
+	reader := bufio.NewReader(os.Stdin)
+	for _, p := range tmpl.Params {
+		if _, ok := values[p.Name]; ok {
+			continue
+		}
+		if p.Default != "" {
+			fmt.Printf("%s [%s]: ", p.Prompt, p.Default)
+		} else {
+			fmt.Printf("%s: ", p.Prompt)
+		}
+		line, err := reader.ReadString('\n')
+		if answer := strings.TrimSpace(line); answer != "" {
+			values[p.Name] = answer
+		}
+		if err != nil {
+			fmt.Println()
+			break
 		}
 	}
 
-	// Default: update both
-	updateBinary := !onlySkills
-	updateSkills := !onlyBinary
+	// Structure only: the template's tools (e.g. adb) may not be available on this machine
+	wfDef := tmpl.Instantiate(name, values)
+	if err := workflow.ValidateDefinition(wfDef); err != nil {
+		fmt.Printf("✗ Generated workflow is invalid: %v\n", err)
+		os.Exit(1)
+	}
+	if err := helper.SaveWorkflow(name, wfDef); err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Created workflow %q from template %q\n", name, tmpl.Name)
+	fmt.Printf("  %s\n", filepath.Join(helper.WorkflowsDir(), name+".json"))
+	fmt.Printf("  Run it with: pepebot workflow run %s\n", name)
+}
+
+func workflowExportCmd(workspace string, cfg *config.Config, name, output string) {
+	name = strings.TrimSuffix(name, ".json")
+	if output == "" {
+		output = name + ".workflow.tar.gz"
+	}
+
+	helper := newWorkflowHelper(workspace, cfg, nil)
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
+	manifest, err := helper.ExportBundle(name, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		fmt.Printf("✗ Export failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Exported workflow %q to %s\n", name, output)
+	for _, file := range manifest.Files {
+		fmt.Printf("  %s\n", file)
+	}
+}
+
+func workflowImportCmd(workspace string, cfg *config.Config, file string, opts workflow.ImportOptions) {
+	helper := newWorkflowHelper(workspace, cfg, nil)
+	importFile := func() (*workflow.ImportResult, error) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return helper.ImportBundle(f, opts)
+	}
+
+	result, err := importFile()
+	var conflict *workflow.ConflictError
+	if errors.As(err, &conflict) && opts.OnConflict == "" {
+		fmt.Println("These files already exist with different content:")
+		for _, p := range conflict.Paths {
+			fmt.Printf("  %s\n", p)
+		}
+		fmt.Print("Overwrite (o), keep existing (s), import as a new name (r) or abort (a)? ")
+		var response string
+		fmt.Scanln(&response)
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "o":
+			opts.OnConflict = workflow.ConflictOverwrite
+		case "s":
+			opts.OnConflict = workflow.ConflictSkip
+		case "r":
+			opts.OnConflict = workflow.ConflictRename
+		default:
+			fmt.Println("Aborted.")
+			return
+		}
+		result, err = importFile()
+	}
+	if err != nil {
+		fmt.Printf("✗ Import failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Imported workflow %q\n", result.Workflow)
+	for _, p := range result.Written {
+		fmt.Printf("  + %s\n", p)
+	}
+	for _, p := range result.Skipped {
+		fmt.Printf("  = %s (kept existing)\n", p)
+	}
+	fmt.Printf("Run it with: pepebot workflow run %s\n", result.Workflow)
+}
+
+// =============================================================================
+// Update Command
+// =============================================================================
+
+// updateOptions are the flags of "pepebot update"
+type updateOptions struct {
+	onlyBinary bool
+	onlySkills bool
+	channel    string // selfupdate.ChannelStable or ChannelBeta
+	rollback   bool
+	yes        bool // skip the confirmation prompt
+}
 
+func updateCmd(opts updateOptions) {
 	fmt.Printf("%s pepebot update\n\n", logo)
 	fmt.Printf("  Current version: v%s\n", version)
 
-	if updateBinary {
-		updateBinaryCmd()
+	if opts.rollback {
+		rollbackBinaryCmd()
+		return
 	}
 
-	if updateSkills {
-		updateBuiltinSkillsCmd()
+	// Default: update both
+	replaced := false
+	if !opts.onlySkills {
+		replaced = updateBinaryCmd(opts)
+	}
+	if opts.onlyBinary {
+		return
+	}
+	if !opts.onlySkills && !opts.yes && readline.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print("\nRefresh builtin skills and workspace templates (AGENTS.md, TOOLS.md, ...)? Your edits are kept. [Y/n] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a == "n" || a == "no" {
+			return
+		}
 	}
+	if replaced {
+		// The templates to merge are built into the new binary
+		cmd := exec.Command(currentExecutable(), "update", "--only-skills", "--yes")
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Printf("✗ Refreshing skills with the new binary failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	updateBuiltinSkillsCmd()
 }
 
-func updateBinaryCmd() {
-	// Detect current binary path
+// currentExecutable returns the path of the running binary, symlinks resolved
+func currentExecutable() string {
 	execPath, err := os.Executable()
 	if err != nil {
 		fmt.Printf("✗ Failed to detect binary path: %v\n", err)
@@ -2554,6 +4333,23 @@ func updateBinaryCmd() {
 		fmt.Printf("✗ Failed to resolve symlinks: %v\n", err)
 		os.Exit(1)
 	}
+	return execPath
+}
+
+func rollbackBinaryCmd() {
+	execPath := currentExecutable()
+	if err := selfupdate.Rollback(execPath); err != nil {
+		fmt.Printf("✗ Rollback failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n✓ Restored the previous binary from %s\n", selfupdate.BackupPath(execPath))
+	fmt.Println("  Run 'pepebot update --rollback' again to undo.")
+}
+
+// updateBinaryCmd installs the latest release and reports whether the binary
+// was replaced
+func updateBinaryCmd(opts updateOptions) bool {
+	execPath := currentExecutable()
 
 	// Detect OS/arch for asset naming
 	osName := runtime.GOOS
@@ -2569,93 +4365,141 @@ func updateBinaryCmd() {
 	}
 
 	fmt.Printf("  Binary:          %s\n", execPath)
-	fmt.Printf("  Platform:        %s/%s\n\n", osName, archName)
+	fmt.Printf("  Platform:        %s/%s\n", osName, archName)
+	fmt.Printf("  Channel:         %s\n\n", opts.channel)
 
-	// Fetch latest release info from GitHub
+	// Fetch release info from GitHub
 	fmt.Println("Checking for updates...")
-	latestVersion, err := fetchLatestVersion()
+	ctx := context.Background()
+	client := selfupdate.NewClient()
+	releases, err := client.Releases(ctx)
+	if err != nil {
+		fmt.Printf("✗ Failed to check for updates: %v\n", err)
+		os.Exit(1)
+	}
+	latest, err := selfupdate.Latest(releases, opts.channel)
 	if err != nil {
 		fmt.Printf("✗ Failed to check for updates: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Normalize version (strip leading 'v')
-	latestClean := strings.TrimPrefix(latestVersion, "v")
-	if latestClean == version {
+	switch selfupdate.CompareVersions(latest.TagName, version) {
+	case 0:
 		fmt.Printf("\n✓ Binary already up to date (v%s)\n", version)
-		return
+		return false
+	case -1:
+		fmt.Printf("\n✓ v%s is newer than the latest %s release (%s)\n", version, opts.channel, latest.TagName)
+		return false
 	}
 
-	fmt.Printf("  Latest version:  %s\n\n", latestVersion)
+	fmt.Printf("  Latest version:  %s\n", latest.TagName)
+	printReleaseNotes(selfupdate.Changelog(releases, version, latest.TagName))
+
+	if !opts.yes && readline.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Printf("Install %s? [y/N] ", latest.TagName)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("Update cancelled.")
+			return false
+		}
+	}
 
-	// Build download URL
 	assetName := fmt.Sprintf("pepebot-%s-%s.tar.gz", osName, archName)
-	downloadURL := fmt.Sprintf("https://github.com/pepebot-space/pepebot/releases/download/%s/%s", latestVersion, assetName)
 	binaryName := fmt.Sprintf("pepebot-%s-%s%s", osName, archName, binaryExt)
+	asset := latest.Asset(assetName)
+	if asset == nil {
+		fmt.Printf("✗ Asset not found: %s\n", assetName)
+		fmt.Printf("  Check available releases at: https://github.com/pepebot-space/pepebot/releases\n")
+		os.Exit(1)
+	}
 
-	fmt.Printf("Downloading %s...\n", assetName)
-
-	// Download the tar.gz
-	resp, err := http.Get(downloadURL)
+	fmt.Printf("\nDownloading %s...\n", assetName)
+	archive, err := client.Download(ctx, asset.URL)
 	if err != nil {
 		fmt.Printf("✗ Failed to download: %v\n", err)
 		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("✗ Download failed: HTTP %d\n", resp.StatusCode)
-		if resp.StatusCode == 404 {
-			fmt.Printf("  Asset not found: %s\n", assetName)
-			fmt.Printf("  Check available releases at: https://github.com/pepebot-space/pepebot/releases\n")
-		}
+	// Refuse archives that can't be verified
+	checksumAsset := latest.Asset(assetName + ".sha256")
+	if checksumAsset == nil {
+		fmt.Printf("✗ Release %s has no checksum for %s; not installing it\n", latest.TagName, assetName)
 		os.Exit(1)
 	}
-
-	// Extract binary from tar.gz
-	binaryData, err := extractBinaryFromTarGz(resp.Body, binaryName)
+	checksumFile, err := client.Download(ctx, checksumAsset.URL)
 	if err != nil {
-		fmt.Printf("✗ Failed to extract binary: %v\n", err)
+		fmt.Printf("✗ Failed to download checksum: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Atomic replace: write to temp file in same directory, then rename
-	dir := filepath.Dir(execPath)
-	tmpFile, err := os.CreateTemp(dir, "pepebot-update-*")
+	checksum, err := selfupdate.ParseChecksum(checksumFile, assetName)
+	if err == nil {
+		err = selfupdate.VerifySHA256(archive, checksum)
+	}
 	if err != nil {
-		fmt.Printf("✗ Failed to create temp file: %v\n", err)
+		fmt.Printf("✗ Checksum verification failed: %v\n", err)
 		os.Exit(1)
 	}
-	tmpPath := tmpFile.Name()
+	fmt.Println("  ✓ SHA256 checksum verified")
 
-	if _, err := tmpFile.Write(binaryData); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		fmt.Printf("✗ Failed to write update: %v\n", err)
-		os.Exit(1)
+	if selfupdate.PublicKey != "" {
+		sigAsset := latest.Asset(assetName + ".sig")
+		if sigAsset == nil {
+			fmt.Printf("✗ Release %s has no signature for %s; not installing it\n", latest.TagName, assetName)
+			os.Exit(1)
+		}
+		signature, err := client.Download(ctx, sigAsset.URL)
+		if err == nil {
+			err = selfupdate.VerifySignature(archive, signature, selfupdate.PublicKey)
+		}
+		if err != nil {
+			fmt.Printf("✗ Signature verification failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("  ✓ Signature verified")
+	} else {
+		fmt.Println("  - Signature not checked (this build has no release signing key)")
 	}
-	tmpFile.Close()
 
-	// Set executable permissions
-	if err := os.Chmod(tmpPath, 0755); err != nil {
-		os.Remove(tmpPath)
-		fmt.Printf("✗ Failed to set permissions: %v\n", err)
+	binaryData, err := selfupdate.ExtractBinary(archive, binaryName)
+	if err != nil {
+		fmt.Printf("✗ Failed to extract binary: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Replace the binary
-	if err := os.Rename(tmpPath, execPath); err != nil {
-		os.Remove(tmpPath)
-		fmt.Printf("✗ Failed to replace binary: %v\n", err)
+	if err := selfupdate.ReplaceBinary(execPath, binaryData); err != nil {
+		fmt.Printf("✗ %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("\n✓ Updated binary: v%s → %s\n", version, latestVersion)
+	fmt.Printf("\n✓ Updated binary: v%s → %s\n", version, latest.TagName)
+	fmt.Printf("  Previous binary kept at %s; undo with 'pepebot update --rollback'\n", selfupdate.BackupPath(execPath))
+	return true
 }
 
-func updateBuiltinSkillsCmd() {
-	fmt.Println("\nUpdating builtin skills...")
+// printReleaseNotes shows what changed between the installed and the new
+// version, newest first
+func printReleaseNotes(notes []selfupdate.Release) {
+	const maxLines = 30
+	for _, r := range notes {
+		fmt.Printf("\n── %s ──\n", r.TagName)
+		body := strings.TrimSpace(r.Body)
+		if body == "" {
+			fmt.Println("  (no release notes)")
+			continue
+		}
+		lines := strings.Split(body, "\n")
+		for i, line := range lines {
+			if i == maxLines {
+				fmt.Printf("  … %d more lines: https://github.com/pepebot-space/pepebot/releases/tag/%s\n", len(lines)-maxLines, r.TagName)
+				break
+			}
+			fmt.Println("  " + strings.TrimRight(line, "\r"))
+		}
+	}
+	fmt.Println()
+}
 
+func updateBuiltinSkillsCmd() {
 	configPath := getConfigPath()
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
@@ -2665,7 +4509,13 @@ func updateBuiltinSkillsCmd() {
 	}
 
 	workspace := cfg.WorkspacePath()
+
+	fmt.Println("\nRefreshing workspace templates...")
+	refreshWorkspaceTemplates(workspace)
+
+	fmt.Println("\nUpdating builtin skills...")
 	installer := skills.NewSkillInstaller(workspace)
+	installer.Configure(cfg.Skills)
 
 	ctx := context.Background()
 	if err := installer.InstallBuiltinSkills(ctx); err != nil {
@@ -2675,67 +4525,3 @@ func updateBuiltinSkillsCmd() {
 
 	fmt.Println("\n✓ Builtin skills updated successfully!")
 }
-
-// fetchLatestVersion queries the GitHub API for the latest release tag.
-func fetchLatestVersion() (string, error) {
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequest("GET", "https://api.github.com/repos/pepebot-space/pepebot/releases/latest", nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned HTTP %d", resp.StatusCode)
-	}
-
-	var release struct {
-		TagName string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if release.TagName == "" {
-		return "", fmt.Errorf("no tag_name in release response")
-	}
-
-	return release.TagName, nil
-}
-
-// extractBinaryFromTarGz reads a tar.gz stream and returns the contents of the
-// file matching binaryName.
-func extractBinaryFromTarGz(r io.Reader, binaryName string) ([]byte, error) {
-	gz, err := gzip.NewReader(r)
-	if err != nil {
-		return nil, fmt.Errorf("gzip error: %w", err)
-	}
-	defer gz.Close()
-
-	tr := tar.NewReader(gz)
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("tar error: %w", err)
-		}
-
-		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
-			data, err := io.ReadAll(tr)
-			if err != nil {
-				return nil, fmt.Errorf("read error: %w", err)
-			}
-			return data, nil
-		}
-	}
-
-	return nil, fmt.Errorf("binary %q not found in archive", binaryName)
-}
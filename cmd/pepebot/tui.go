@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tui"
+)
+
+// agentTUIBackend lets the TUI drive an agent loop
+type agentTUIBackend struct {
+	agentLoop *agent.AgentLoop
+}
+
+func (b *agentTUIBackend) AgentName() string { return b.agentLoop.AgentName() }
+func (b *agentTUIBackend) Model() string     { return b.agentLoop.Model() }
+
+func (b *agentTUIBackend) Send(ctx context.Context, sessionKey, content string, onText func(string), onTool func(tui.ToolEvent)) error {
+	ctx = agent.WithToolObserver(ctx, func(a agent.ToolActivity) {
+		onTool(tui.ToolEvent{ID: a.ID, Name: a.Name, Args: a.Args, Done: a.Done, Failed: a.Failed, Duration: a.Duration})
+	})
+	return b.agentLoop.ProcessDirectStream(ctx, content, nil, sessionKey, func(chunk providers.StreamChunk) {
+		if chunk.Content != "" {
+			onText(chunk.Content)
+		}
+	})
+}
+
+// History returns the visible conversation: user messages and answers,
+// without tool calls and tool results
+func (b *agentTUIBackend) History(sessionKey string) []tui.Message {
+	var history []tui.Message
+	for _, msg := range b.agentLoop.Sessions().GetHistory(sessionKey) {
+		content, _ := msg.Content.(string)
+		if (msg.Role != "user" && msg.Role != "assistant") || strings.TrimSpace(content) == "" {
+			continue
+		}
+		history = append(history, tui.Message{Role: msg.Role, Content: content})
+	}
+	return history
+}
+
+func (b *agentTUIBackend) Sessions() []string {
+	sessions := recentSessions(b.agentLoop.Sessions())
+	keys := make([]string, len(sessions))
+	for i, s := range sessions {
+		keys[i] = s.Key
+	}
+	return keys
+}
+
+func (b *agentTUIBackend) Clear(sessionKey string) {
+	b.agentLoop.ClearSession(sessionKey)
+}
+
+func (b *agentTUIBackend) Fork(sessionKey, newKey string) error {
+	_, err := b.agentLoop.Sessions().Fork(sessionKey, newKey)
+	return err
+}
+
+func (b *agentTUIBackend) Command(sessionKey, input string) (string, bool) {
+	parts := strings.Fields(input)
+	command := strings.ToLower(parts[0])
+	switch command {
+	case "/pin", "/pins", "/unpin":
+		arg := strings.TrimSpace(strings.TrimPrefix(input, parts[0]))
+		return agent.PinCommand(b.agentLoop.Sessions(), sessionKey, command, arg), true
+	case "/outcome":
+		arg := strings.TrimSpace(strings.TrimPrefix(input, parts[0]))
+		return b.agentLoop.OutcomeCommand(sessionKey, arg), true
+	}
+	return "", false
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/analytics"
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+type turnStatsKey struct{}
+
+// turnStats collects the tool calls of one turn for the usage log
+type turnStats struct {
+	mu    sync.Mutex
+	tools []string
+}
+
+func withTurnStats(ctx context.Context) (context.Context, *turnStats) {
+	stats := &turnStats{}
+	return context.WithValue(ctx, turnStatsKey{}, stats), stats
+}
+
+// addTurnTools adds tool calls to the turn's stats
+func addTurnTools(ctx context.Context, calls []providers.ToolCall) {
+	stats, ok := ctx.Value(turnStatsKey{}).(*turnStats)
+	if !ok {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	for _, tc := range calls {
+		stats.tools = append(stats.tools, tc.Name)
+	}
+}
+
+// recordTurn writes a finished turn to the usage log
+func (al *AgentLoop) recordTurn(msg bus.InboundMessage, start time.Time, stats *turnStats, err error) {
+	stats.mu.Lock()
+	tools := append([]string(nil), stats.tools...)
+	stats.mu.Unlock()
+
+	al.recordEvent(analytics.Event{
+		Kind:       analytics.KindTurn,
+		Session:    msg.SessionKey,
+		Channel:    msg.Channel,
+		DurationMS: time.Since(start).Milliseconds(),
+		Tools:      tools,
+		Error:      err != nil,
+	})
+}
+
+func (al *AgentLoop) recordEvent(e analytics.Event) {
+	if al.usageLog == nil {
+		return
+	}
+	e.Agent = al.agentName
+	if err := al.usageLog.Record(e); err != nil {
+		logger.DebugCF("agent", "Failed to write usage log", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
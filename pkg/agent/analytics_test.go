@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/analytics"
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestUsageLog(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir() + "/workspace"
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "1", Name: "list_dir", Arguments: map[string]interface{}{"path": "."}}}},
+		{Content: "Done."},
+	}}
+	am, err := NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentLoop, err := am.GetDefaultAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := agentLoop.ProcessDirect(context.Background(), "look around", nil, "cli:test"); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := analytics.Read(filepath.Join(filepath.Dir(cfg.WorkspacePath()), analytics.FileName), time.Time{})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("events = %+v, err %v", events, err)
+	}
+	e := events[0]
+	if e.Kind != analytics.KindTurn || e.Agent != "default" || e.Session != "cli:test" || e.Channel != "cli" || len(e.Tools) != 1 || e.Tools[0] != "list_dir" {
+		t.Errorf("event = %+v", e)
+	}
+}
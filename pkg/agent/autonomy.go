@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+const (
+	defaultAutonomyIterations = 50
+	defaultAutonomyMinutes    = 60
+)
+
+// AutonomyTask is an open-ended objective an agent works on alone, usually
+// started by a cron job
+type AutonomyTask struct {
+	Name          string
+	Objective     string
+	Agent         string // empty uses the default agent
+	MaxIterations int    // tool iterations, default 50
+	MaxMinutes    int    // wall-clock budget, default 60
+}
+
+// AutonomyResult is the outcome of an autonomous session
+type AutonomyResult struct {
+	Report         string // the agent's final answer
+	Summary        string // short version for a chat message
+	TranscriptPath string
+	ToolCalls      int
+	Duration       time.Duration
+}
+
+type transcriptKey struct{}
+
+// transcript captures the messages of a turn for autonomous sessions
+type transcript struct {
+	messages []providers.Message
+	final    string
+}
+
+func recordTranscript(ctx context.Context, messages []providers.Message, final string) {
+	if t, ok := ctx.Value(transcriptKey{}).(*transcript); ok {
+		t.messages = messages
+		t.final = final
+	}
+}
+
+// RunAutonomous runs a bounded autonomous session: one agent turn with the
+// task's iteration and time budget. The full transcript is saved to
+// workspace/autonomy/ and the report is condensed into a summary.
+func (am *AgentManager) RunAutonomous(ctx context.Context, task AutonomyTask) (*AutonomyResult, error) {
+	agentName := task.Agent
+	if agentName == "" {
+		agentName = am.defaultAgent
+	}
+	agentLoop, err := am.GetOrCreateAgent(agentName)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.MaxIterations <= 0 {
+		task.MaxIterations = defaultAutonomyIterations
+	}
+	if task.MaxMinutes <= 0 {
+		task.MaxMinutes = defaultAutonomyMinutes
+	}
+	limits := agentLoop.limits
+	limits.MaxTurnSeconds = task.MaxMinutes * 60
+
+	started := time.Now()
+	sessionKey := fmt.Sprintf("autonomy:%s:%s", slugify(task.Name), started.Format("20060102-1504"))
+
+	logger.InfoCF("agent", "Starting autonomous session", map[string]interface{}{
+		"agent":          agentName,
+		"task":           task.Name,
+		"max_iterations": task.MaxIterations,
+		"max_minutes":    task.MaxMinutes,
+	})
+
+	rec := &transcript{}
+	turnCtx, done := am.startTurn(ctx, sessionKey)
+	defer done()
+	turnCtx = withTurnBudget(turnCtx, turnBudget{maxIterations: task.MaxIterations, limits: limits})
+	turnCtx = context.WithValue(turnCtx, transcriptKey{}, rec)
+
+	report, err := agentLoop.processMessage(turnCtx, bus.InboundMessage{
+		Channel:    "autonomy",
+		SenderID:   "cron",
+		ChatID:     task.Name,
+		Content:    autonomyPrompt(task),
+		SessionKey: sessionKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AutonomyResult{
+		Report:    report,
+		ToolCalls: countToolCalls(rec.messages),
+		Duration:  time.Since(started),
+	}
+	result.Summary = agentLoop.summarizeReport(ctx, task, report)
+
+	path, err := am.saveTranscript(task, started, rec, result)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to save autonomy transcript", map[string]interface{}{
+			"task":  task.Name,
+			"error": err.Error(),
+		})
+	}
+	result.TranscriptPath = path
+
+	logger.InfoCF("agent", "Autonomous session finished", map[string]interface{}{
+		"task":       task.Name,
+		"tool_calls": result.ToolCalls,
+		"duration":   result.Duration.Round(time.Second).String(),
+		"transcript": path,
+	})
+
+	return result, nil
+}
+
+func autonomyPrompt(task AutonomyTask) string {
+	return fmt.Sprintf(`Autonomous session: %s
+
+Objective: %s
+
+You are working on your own; nobody will answer questions during this session. You have up to %d tool iterations and %d minutes. Plan the work, use tools to make progress, and save anything worth keeping (notes, data, drafts) to files in the workspace.
+
+When you are done or the budget runs low, finish with a report: what you did, what you found, where you saved things, and what is left open.`,
+		task.Name, task.Objective, task.MaxIterations, task.MaxMinutes)
+}
+
+// summarizeReport condenses the report into a short chat message, falling
+// back to the report itself
+func (al *AgentLoop) summarizeReport(ctx context.Context, task AutonomyTask, report string) string {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	messages := []providers.Message{
+		{Role: "system", Content: "You summarize reports of autonomous agent sessions for a chat message. Keep the key findings, results and open items. Use at most 10 short lines. Output only the summary."},
+		{Role: "user", Content: fmt.Sprintf("Objective: %s\n\nReport:\n%s", task.Objective, report)},
+	}
+	response, err := al.provider.Chat(ctx, messages, nil, al.model, al.chatOptions(ctx))
+	if err == nil {
+		recordUsage(response.Usage)
+	}
+	if err != nil || strings.TrimSpace(response.Content) == "" {
+		return truncateString(report, 3000)
+	}
+	return strings.TrimSpace(response.Content)
+}
+
+// saveTranscript writes the session as markdown to workspace/autonomy/
+func (am *AgentManager) saveTranscript(task AutonomyTask, started time.Time, rec *transcript, result *AutonomyResult) (string, error) {
+	dir := filepath.Join(am.config.WorkspacePath(), "autonomy")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", task.Name)
+	fmt.Fprintf(&sb, "- Objective: %s\n", task.Objective)
+	fmt.Fprintf(&sb, "- Started: %s\n", started.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Duration: %s\n", result.Duration.Round(time.Second))
+	fmt.Fprintf(&sb, "- Budget: %d tool iterations, %d minutes\n", task.MaxIterations, task.MaxMinutes)
+	fmt.Fprintf(&sb, "- Tool calls: %d\n\n", result.ToolCalls)
+	fmt.Fprintf(&sb, "## Summary\n\n%s\n\n## Report\n\n%s\n\n## Transcript\n\n", result.Summary, result.Report)
+
+	for _, msg := range rec.messages {
+		if msg.Role == "system" {
+			continue
+		}
+		content, _ := msg.Content.(string)
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&sb, "**tool result**\n\n```\n%s\n```\n\n", truncateString(content, 2000))
+		default:
+			fmt.Fprintf(&sb, "**%s**\n\n", msg.Role)
+			if content != "" {
+				fmt.Fprintf(&sb, "%s\n\n", content)
+			}
+			for _, tc := range msg.ToolCalls {
+				if tc.Function != nil {
+					fmt.Fprintf(&sb, "- call `%s` %s\n", tc.Function.Name, truncateString(tc.Function.Arguments, 500))
+				}
+			}
+			if len(msg.ToolCalls) > 0 {
+				sb.WriteString("\n")
+			}
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.md", started.Format("2006-01-02-1504"), slugify(task.Name)))
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func countToolCalls(messages []providers.Message) int {
+	n := 0
+	for _, msg := range messages {
+		n += len(msg.ToolCalls)
+	}
+	return n
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(name string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return "session"
+	}
+	return slug
+}
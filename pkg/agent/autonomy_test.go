@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// scriptedProvider returns its responses in order, then repeats the last one
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []*providers.LLMResponse
+	calls     int
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.calls
+	if i >= len(p.responses) {
+		i = len(p.responses) - 1
+	}
+	p.calls++
+	return p.responses[i], nil
+}
+
+func (p *scriptedProvider) ChatStream(ctx context.Context, messages []providers.Message, model string, options map[string]interface{}, callback providers.StreamCallback) error {
+	return nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string { return "test-model" }
+
+func TestRunAutonomous(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir() + "/workspace"
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "1", Name: "list_dir", Arguments: map[string]interface{}{"path": "."}}}},
+		{Content: "Report: looked at the workspace, nothing to do."},
+		{Content: "Nothing to do."},
+	}}
+	am, err := NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := am.RunAutonomous(context.Background(), AutonomyTask{Name: "Nightly check", Objective: "Look around"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Report != "Report: looked at the workspace, nothing to do." || result.Summary != "Nothing to do." || result.ToolCalls != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	data, err := os.ReadFile(result.TranscriptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "call `list_dir`") || !strings.Contains(string(data), "Objective: Look around") {
+		t.Errorf("transcript is missing the session:\n%s", data)
+	}
+	if !strings.HasSuffix(result.TranscriptPath, "-nightly-check.md") {
+		t.Errorf("transcript path = %s", result.TranscriptPath)
+	}
+}
+
+func TestTurnBudget(t *testing.T) {
+	al := &AgentLoop{maxIterations: 20}
+	if n, _ := al.turnBudget(context.Background()); n != 20 {
+		t.Errorf("default iterations = %d", n)
+	}
+	ctx := withTurnBudget(context.Background(), turnBudget{maxIterations: 50, limits: config.TurnLimitsConfig{MaxTurnSeconds: 600}})
+	if n, limits := al.turnBudget(ctx); n != 50 || limits.MaxTurnSeconds != 600 {
+		t.Errorf("budget = %d, %+v", n, limits)
+	}
+}
@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
+)
+
+// Budgeted prompt sections, in the order they appear in the system prompt
+const (
+	sectionBootstrap = "bootstrap"
+	sectionMemory    = "memory"
+	sectionSkills    = "skills"
+	sectionSummary   = "summary"
+	sectionHistory   = "history"
+)
+
+var defaultBudgetRatios = config.ContextBudgetConfig{
+	Bootstrap: 0.25,
+	Memory:    0.15,
+	Skills:    0.15,
+	Summary:   0.10,
+	History:   0.35,
+}
+
+// contextBudget trims prompt sections so the request fits the model's window
+type contextBudget struct {
+	cfg     config.ContextBudgetConfig
+	model   string // selects the tokenizer
+	reserve int    // tokens kept free for the reply (max_tokens)
+}
+
+func newContextBudget(cfg config.ContextBudgetConfig, model string, reserve int) *contextBudget {
+	if cfg.Bootstrap+cfg.Memory+cfg.Skills+cfg.Summary+cfg.History <= 0 {
+		window := cfg.Window
+		cfg = defaultBudgetRatios
+		cfg.Window = window
+	}
+	if reserve <= 0 || reserve >= cfg.Window {
+		reserve = cfg.Window / 4
+	}
+	return &contextBudget{cfg: cfg, model: model, reserve: reserve}
+}
+
+func (b *contextBudget) ratio(section string) float64 {
+	switch section {
+	case sectionBootstrap:
+		return b.cfg.Bootstrap
+	case sectionMemory:
+		return b.cfg.Memory
+	case sectionSkills:
+		return b.cfg.Skills
+	case sectionSummary:
+		return b.cfg.Summary
+	case sectionHistory:
+		return b.cfg.History
+	}
+	return 0
+}
+
+// historyLimit is the history's share of the window before other sections
+// are taken into account; longer histories should be summarized
+func (b *contextBudget) historyLimit() int {
+	return int(float64(b.cfg.Window-b.reserve) * b.cfg.History)
+}
+
+// promptParts is the content ContextBuilder assembles for one request
+type promptParts struct {
+	fixed    int // tokens that are never trimmed: base prompt, current message, tools
+	sections map[string]string
+	history  []providers.Message
+}
+
+// contextReport describes the final composition of a request
+type contextReport struct {
+	Window  int
+	Fixed   int
+	Tokens  map[string]int
+	Trimmed []string
+}
+
+func (r *contextReport) total() int {
+	total := r.Fixed
+	for _, n := range r.Tokens {
+		total += n
+	}
+	return total
+}
+
+func (r *contextReport) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"window": r.Window,
+		"fixed":  r.Fixed,
+		"total":  r.total(),
+	}
+	for name, n := range r.Tokens {
+		fields[name] = n
+	}
+	if len(r.Trimmed) > 0 {
+		fields["trimmed"] = strings.Join(r.Trimmed, ",")
+	}
+	return fields
+}
+
+// apply measures every section and trims the ones over their share
+func (b *contextBudget) apply(parts *promptParts) *contextReport {
+	report := &contextReport{Window: b.cfg.Window, Fixed: parts.fixed, Tokens: map[string]int{}}
+
+	names := []string{sectionBootstrap, sectionMemory, sectionSkills, sectionSummary, sectionHistory}
+	sizes := make([]int, len(names))
+	ratios := make([]float64, len(names))
+	for i, name := range names {
+		if name == sectionHistory {
+			sizes[i] = tokens.CountMessages(b.model, parts.history)
+		} else {
+			sizes[i] = tokens.Count(b.model, parts.sections[name])
+		}
+		ratios[i] = b.ratio(name)
+	}
+
+	available := b.cfg.Window - b.reserve - parts.fixed
+	if available < 0 {
+		available = 0
+	}
+	limits := allocate(available, sizes, ratios)
+
+	for i, name := range names {
+		if sizes[i] <= limits[i] {
+			report.Tokens[name] = sizes[i]
+			continue
+		}
+		report.Trimmed = append(report.Trimmed, name)
+		if name == sectionHistory {
+			parts.history = trimHistory(b.model, parts.history, limits[i])
+			report.Tokens[name] = tokens.CountMessages(b.model, parts.history)
+		} else {
+			parts.sections[name] = truncateToTokens(b.model, parts.sections[name], limits[i])
+			report.Tokens[name] = tokens.Count(b.model, parts.sections[name])
+		}
+	}
+	return report
+}
+
+// allocate splits available tokens between sections by ratio. Sections that
+// need less than their share hand the rest to the others.
+func allocate(available int, sizes []int, ratios []float64) []int {
+	limits := make([]int, len(sizes))
+	done := make([]bool, len(sizes))
+	remaining := available
+
+	for {
+		totalRatio := 0.0
+		for i := range sizes {
+			if !done[i] {
+				totalRatio += ratios[i]
+			}
+		}
+		if totalRatio <= 0 {
+			return limits
+		}
+
+		settled := false
+		for i := range sizes {
+			if done[i] {
+				continue
+			}
+			share := int(float64(remaining) * ratios[i] / totalRatio)
+			if sizes[i] <= share {
+				limits[i] = sizes[i]
+				remaining -= sizes[i]
+				done[i] = true
+				settled = true
+			}
+		}
+		if settled {
+			continue
+		}
+
+		for i := range sizes {
+			if !done[i] {
+				limits[i] = int(float64(remaining) * ratios[i] / totalRatio)
+			}
+		}
+		return limits
+	}
+}
+
+// truncateToTokens keeps the start of text, cut at a line break where possible
+func truncateToTokens(model, text string, limit int) string {
+	size := tokens.Count(model, text)
+	if size <= limit {
+		return text
+	}
+	if limit <= 0 {
+		return ""
+	}
+
+	marker := "\n\n[... truncated to fit the context budget]"
+	limit -= tokens.Count(model, marker)
+	keep := len(text) * limit / size
+	for keep > 0 {
+		for keep > 0 && !utf8.RuneStart(text[keep]) {
+			keep--
+		}
+		if i := strings.LastIndex(text[:keep], "\n"); i > keep/2 {
+			keep = i
+		}
+		// Token density varies along the text, so shrink until it fits
+		if tokens.Count(model, text[:keep]) <= limit {
+			return text[:keep] + marker
+		}
+		keep = keep * 9 / 10
+	}
+	return ""
+}
+
+// trimHistory drops the oldest messages until the rest fits. History is
+// stored as user/assistant pairs, so it always restarts at a user message.
+func trimHistory(model string, history []providers.Message, limit int) []providers.Message {
+	for len(history) > 0 && tokens.CountMessages(model, history) > limit {
+		history = history[1:]
+		for len(history) > 0 && history[0].Role != "user" {
+			history = history[1:]
+		}
+	}
+	return history
+}
+
+// estimateToolTokens measures tool definitions, which are sent with every call
+func estimateToolTokens(model string, defs []map[string]interface{}) int {
+	data, err := json.Marshal(defs)
+	if err != nil {
+		return 0
+	}
+	return tokens.Count(model, string(data))
+}
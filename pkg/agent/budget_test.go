@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
+)
+
+func TestAllocateRedistributesUnusedShare(t *testing.T) {
+	// The first section needs less than its half, so the second gets the rest
+	limits := allocate(1000, []int{100, 5000}, []float64{0.5, 0.5})
+	if limits[0] != 100 || limits[1] != 900 {
+		t.Errorf("limits = %v, want [100 900]", limits)
+	}
+
+	limits = allocate(1000, []int{5000, 5000}, []float64{0.25, 0.75})
+	if limits[0] != 250 || limits[1] != 750 {
+		t.Errorf("limits = %v, want [250 750]", limits)
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	t.Setenv("PEPEBOT_TOKENIZER", "estimate")
+	text := strings.Repeat("line of text\n", 100)
+	out := truncateToTokens("", text, 50)
+	if tokens.Count("", out) > 50 {
+		t.Errorf("truncated text has %d tokens, want <= 50", tokens.Count("", out))
+	}
+	if !strings.HasSuffix(out, "[... truncated to fit the context budget]") {
+		t.Errorf("missing truncation marker: %q", out[len(out)-60:])
+	}
+	if short := "short"; truncateToTokens("", short, 50) != short {
+		t.Error("text within the limit should be unchanged")
+	}
+}
+
+func TestTrimHistoryStartsWithUser(t *testing.T) {
+	t.Setenv("PEPEBOT_TOKENIZER", "estimate")
+	var history []providers.Message
+	for i := 0; i < 10; i++ {
+		history = append(history,
+			providers.Message{Role: "user", Content: strings.Repeat("q", 400)},
+			providers.Message{Role: "assistant", Content: strings.Repeat("a", 400)},
+		)
+	}
+	trimmed := trimHistory("", history, 450)
+	if len(trimmed) == 0 || trimmed[0].Role != "user" {
+		t.Fatalf("trimmed history should start with a user message: %d messages", len(trimmed))
+	}
+	if tokens.CountMessages("", trimmed) > 450 {
+		t.Errorf("history has %d tokens, want <= 450", tokens.CountMessages("", trimmed))
+	}
+}
+
+func TestBuildMessagesAppliesBudget(t *testing.T) {
+	t.Setenv("PEPEBOT_TOKENIZER", "estimate")
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "AGENTS.md"), []byte(strings.Repeat("agent rule\n", 4000)), 0644)
+
+	var history []providers.Message
+	for i := 0; i < 40; i++ {
+		history = append(history,
+			providers.Message{Role: "user", Content: strings.Repeat("q", 800)},
+			providers.Message{Role: "assistant", Content: strings.Repeat("a", 800)},
+		)
+	}
+
+	cb := NewContextBuilder(workspace)
+	unbounded := cb.BuildMessages(history, "", nil, "hello", nil, nil)
+
+	cb.SetBudget(config.ContextBudgetConfig{Window: 8000}, "", 1000, nil)
+	messages := cb.BuildMessages(history, "", nil, "hello", nil, nil)
+
+	if got := tokens.CountMessages("", messages); got > 7000 {
+		t.Errorf("budgeted request has %d tokens, want <= 7000", got)
+	}
+	if len(messages) >= len(unbounded) {
+		t.Errorf("expected history to be trimmed: %d messages vs %d", len(messages), len(unbounded))
+	}
+	if last := messages[len(messages)-1]; last.Content != "hello" {
+		t.Errorf("current message must be kept, got %v", last.Content)
+	}
+	if !strings.Contains(messages[0].Content.(string), "truncated to fit the context budget") {
+		t.Error("expected AGENTS.md to be truncated")
+	}
+	if cb.HistoryBudget() != int(float64(7000)*defaultBudgetRatios.History) {
+		t.Errorf("HistoryBudget = %d", cb.HistoryBudget())
+	}
+}
+
+func TestBuildMessagesKeepsPins(t *testing.T) {
+	t.Setenv("PEPEBOT_TOKENIZER", "estimate")
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "AGENTS.md"), []byte(strings.Repeat("agent rule\n", 4000)), 0644)
+
+	cb := NewContextBuilder(workspace)
+	cb.SetBudget(config.ContextBudgetConfig{Window: 4000}, "", 1000, nil)
+	messages := cb.BuildMessages(nil, "", []string{"the server IP is 10.0.0.5"}, "hello", nil, nil)
+
+	system := messages[0].Content.(string)
+	if !strings.Contains(system, "## Pinned Facts") || !strings.Contains(system, "- the server IP is 10.0.0.5") {
+		t.Error("pinned facts must be in the system prompt even when the budget trims")
+	}
+}
+
+func TestContextWindowFromModel(t *testing.T) {
+	al := &AgentLoop{model: "maia/gpt-4o-mini", maxTokens: 32768, contextBuilder: NewContextBuilder(t.TempDir())}
+	if got := al.window(); got != 128000 {
+		t.Errorf("window = %d, want the model's 128000", got)
+	}
+	if got := al.outputLimit(); got != 16384 {
+		t.Errorf("outputLimit = %d, want max_tokens capped at 16384", got)
+	}
+	if got := al.summarizeThreshold(); got != (128000-16384)*75/100 {
+		t.Errorf("summarizeThreshold = %d", got)
+	}
+
+	al.contextWindow = 20000
+	if got := al.window(); got != 20000 {
+		t.Errorf("configured window should win, got %d", got)
+	}
+
+	// Unknown models keep using max_tokens as the window
+	al = &AgentLoop{model: "my-local-model", maxTokens: 8192, contextBuilder: NewContextBuilder(t.TempDir())}
+	if al.window() != 8192 || al.outputLimit() != 8192 || al.summarizeThreshold() != 6144 {
+		t.Errorf("unknown model: window %d, output %d, threshold %d", al.window(), al.outputLimit(), al.summarizeThreshold())
+	}
+}
@@ -1,22 +1,45 @@
 package agent
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/journal"
 	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/middleware"
 	"github.com/pepebot-space/pepebot/pkg/providers"
 	"github.com/pepebot-space/pepebot/pkg/skills"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
 )
 
 type ContextBuilder struct {
 	workspace      string
 	agentPromptDir string
 	skillsLoader   *skills.SkillsLoader
+	budget         *contextBudget
+	toolDefs       func() []map[string]interface{}
+	journal        *journal.Journal
+	dailyNotes     config.DailyNotesConfig
+	untrusted      bool // tool output from outside sources is wrapped
+
+	filesMu sync.Mutex
+	files   map[string]promptFile // by path
+}
+
+// promptFile is a prompt file as last read. Files are checked on every
+// turn and read again when they change, so edits apply without a restart.
+type promptFile struct {
+	modTime time.Time
+	size    int64
+	data    []byte
 }
 
 func NewContextBuilder(workspace string) *ContextBuilder {
@@ -28,6 +51,7 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	return &ContextBuilder{
 		workspace:    workspace,
 		skillsLoader: loader,
+		journal:      journal.New(workspace),
 	}
 }
 
@@ -42,6 +66,7 @@ func NewContextBuilderWithAgentDir(workspace, agentPromptDir string) *ContextBui
 		workspace:      workspace,
 		agentPromptDir: agentPromptDir,
 		skillsLoader:   loader,
+		journal:        journal.New(workspace),
 	}
 }
 
@@ -50,6 +75,28 @@ func (cb *ContextBuilder) SkillsLoader() *skills.SkillsLoader {
 	return cb.skillsLoader
 }
 
+// SetBudget limits each prompt section to its share of the context window.
+// model selects the tokenizer, reserve is the reply's max_tokens and
+// toolDefs returns the tool definitions sent with every request. A zero
+// window disables budgeting.
+func (cb *ContextBuilder) SetBudget(cfg config.ContextBudgetConfig, model string, reserve int, toolDefs func() []map[string]interface{}) {
+	if cfg.Window <= 0 {
+		cb.budget = nil
+		return
+	}
+	cb.budget = newContextBudget(cfg, model, reserve)
+	cb.toolDefs = toolDefs
+}
+
+// HistoryBudget returns the token share reserved for conversation history,
+// or 0 when budgeting is disabled
+func (cb *ContextBuilder) HistoryBudget() int {
+	if cb.budget == nil {
+		return 0
+	}
+	return cb.budget.historyLimit()
+}
+
 func (cb *ContextBuilder) BuildSystemPrompt() string {
 	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
@@ -66,6 +113,7 @@ You are pepebot, a helpful AI assistant. You have access to tools that allow you
 - Spawn subagents for complex background tasks
 - Manage agent registry via manage_agent (register/list/enable/disable/remove/create_bootstrap/assign_skill/call)
 - Manage MCP server registry (stdio, remote SSE, remote HTTP) via the manage_mcp tool
+- Save a successful multi-step procedure as a reusable skill via skill_create (only after the user approves the preview)
 
 ## Current Time
 %s
@@ -73,7 +121,7 @@ You are pepebot, a helpful AI assistant. You have access to tools that allow you
 ## Workspace
 Your workspace is at: %s
 - Memory files: %s/memory/MEMORY.md
-- Daily notes: %s/memory/2006-01-02.md
+- Daily notes: %s/memory/daily/YYYY-MM-DD.md (add entries with journal_append, read past days with journal_read)
 - Custom skills: %s/skills/{skill-name}/SKILL.md
 
 ## Weather Information
@@ -112,93 +160,263 @@ When the user asks you to remember, save, or note something:
 		now, workspacePath, workspacePath, workspacePath, workspacePath, workspacePath)
 }
 
+var bootstrapFiles = []string{
+	"AGENTS.md",
+	"SOUL.md",
+	"USER.md",
+	"TOOLS.md",
+	"IDENTITY.md",
+}
+
+var memoryFiles = []string{
+	"memory/MEMORY.md",
+}
+
 func (cb *ContextBuilder) LoadBootstrapFiles() string {
-	bootstrapFiles := []string{
-		"AGENTS.md",
-		"SOUL.md",
-		"USER.md",
-		"TOOLS.md",
-		"IDENTITY.md",
-		"memory/MEMORY.md",
+	return cb.loadPromptFiles(bootstrapFiles, "") + cb.loadPromptFiles(memoryFiles, "") + cb.dailyNotesSection()
+}
+
+// SetUntrustedContent explains the untrusted content blocks in the system
+// prompt
+func (cb *ContextBuilder) SetUntrustedContent(enabled bool) {
+	cb.untrusted = enabled
+}
+
+// SetDailyNotes sets which daily notes are included in the prompt
+func (cb *ContextBuilder) SetDailyNotes(cfg config.DailyNotesConfig) {
+	cb.dailyNotes = cfg
+}
+
+// dailyNotesSection returns today's note, creating the file if needed, and
+// yesterday's when configured. Notes without entries are left out.
+func (cb *ContextBuilder) dailyNotesSection() string {
+	now := time.Now()
+	if _, err := cb.journal.Ensure(now); err != nil {
+		logger.DebugCF("agent", "Failed to create daily note", map[string]interface{}{"error": err.Error()})
+	}
+
+	days := []time.Time{now}
+	if cb.dailyNotes.IncludeYesterday {
+		days = []time.Time{now.AddDate(0, 0, -1), now}
 	}
 
 	var result string
-	for _, filename := range bootstrapFiles {
-		// Per-file fallback: check agent dir first, then workspace root
-		var data []byte
-		var err error
-
-		if cb.agentPromptDir != "" {
-			agentPath := filepath.Join(cb.agentPromptDir, filename)
-			data, err = os.ReadFile(agentPath)
+	for _, day := range days {
+		if note := cb.journal.Read(day); journal.HasEntries(note) {
+			result += fmt.Sprintf("## memory/daily/%s.md\n\n%s\n\n", day.Format("2006-01-02"), note)
 		}
+	}
+	return result
+}
 
-		if data == nil || err != nil {
-			filePath := filepath.Join(cb.workspace, filename)
-			data, err = os.ReadFile(filePath)
-		}
+// loadPromptFiles reads each file from the variant's directory, the agent's
+// directory or the workspace root, whichever has it first
+func (cb *ContextBuilder) loadPromptFiles(files []string, variant string) string {
+	var dirs []string
+	if variant != "" && variant != baseVariant && variant == filepath.Base(variant) && !strings.HasPrefix(variant, ".") {
+		dirs = append(dirs, filepath.Join(cb.promptBaseDir(), "variants", variant))
+	}
+	if cb.agentPromptDir != "" {
+		dirs = append(dirs, cb.agentPromptDir)
+	}
+	dirs = append(dirs, cb.workspace)
 
-		if err == nil {
-			result += fmt.Sprintf("## %s\n\n%s\n\n", filename, string(data))
+	var result string
+	for _, filename := range files {
+		for _, dir := range dirs {
+			if data, err := cb.readPromptFile(filepath.Join(dir, filename)); err == nil {
+				result += fmt.Sprintf("## %s\n\n%s\n\n", filename, string(data))
+				break
+			}
 		}
 	}
 
 	return result
 }
 
-func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, metadata map[string]string) []providers.Message {
+// readPromptFile returns a prompt file, reading it again only when its
+// modification time or size changed
+func (cb *ContextBuilder) readPromptFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+
+	cb.filesMu.Lock()
+	defer cb.filesMu.Unlock()
+	if cb.files == nil {
+		cb.files = make(map[string]promptFile)
+	}
+	cached, ok := cb.files[path]
+	if err != nil {
+		delete(cb.files, path)
+		return nil, err
+	}
+	if ok && info.ModTime().Equal(cached.modTime) && info.Size() == cached.size {
+		return cached.data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		delete(cb.files, path)
+		return nil, err
+	}
+	cb.files[path] = promptFile{modTime: info.ModTime(), size: info.Size(), data: data}
+	if ok && containsName(bootstrapFiles, filepath.Base(path)) {
+		logger.InfoCF("agent", "Prompt file reloaded", map[string]interface{}{
+			"file": path,
+		})
+	}
+	return data, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// promptBaseDir is the directory holding the agent's prompt files and
+// their variants
+func (cb *ContextBuilder) promptBaseDir() string {
+	if cb.agentPromptDir != "" {
+		return cb.agentPromptDir
+	}
+	return cb.workspace
+}
+
+// PromptVariants lists the prompt variants: the directories under
+// variants/ in the agent's prompt directory. A variant's files replace the
+// agent's files of the same name.
+func (cb *ContextBuilder) PromptVariants() []string {
+	entries, err := os.ReadDir(filepath.Join(cb.promptBaseDir(), "variants"))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != baseVariant && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// PromptVersion identifies the content of a variant's prompt files, so
+// outcomes can be told apart after the files are edited
+func (cb *ContextBuilder) PromptVersion(variant string) string {
+	sum := sha256.Sum256([]byte(cb.loadPromptFiles(bootstrapFiles, variant)))
+	return hex.EncodeToString(sum[:4])
+}
+
+func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, pins []string, currentMessage string, media []string, metadata map[string]string) []providers.Message {
 	messages := []providers.Message{}
 
 	systemPrompt := cb.BuildSystemPrompt()
-	bootstrapContent := cb.LoadBootstrapFiles()
-	if bootstrapContent != "" {
-		systemPrompt += "\n\n" + bootstrapContent
+	if cb.untrusted {
+		systemPrompt += "\n\n" + middleware.UntrustedContentNotice
 	}
+	conversation := pinnedSection(pins) + cb.conversationContext(metadata)
+	userMessage := cb.buildUserMessage(currentMessage, media)
 
-	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
-	if skillsSummary != "" {
-		systemPrompt += "\n\n## Available Skills\n\n" + skillsSummary
+	parts := &promptParts{
+		sections: map[string]string{
+			sectionBootstrap: cb.loadPromptFiles(bootstrapFiles, metadata["prompt_variant"]),
+			sectionMemory:    cb.loadPromptFiles(memoryFiles, "") + cb.dailyNotesSection(),
+			sectionSkills:    cb.skillsSection(),
+			sectionSummary:   summary,
+		},
+		history: history,
 	}
 
-	skillsContent := cb.loadSkills()
-	if skillsContent != "" {
-		systemPrompt += "\n\n" + skillsContent
+	if cb.budget != nil {
+		model := cb.budget.model
+		parts.fixed = tokens.Count(model, systemPrompt+conversation) + tokens.CountContent(model, userMessage.Content)
+		if cb.toolDefs != nil {
+			parts.fixed += estimateToolTokens(model, cb.toolDefs())
+		}
+		report := cb.budget.apply(parts)
+		if len(report.Trimmed) > 0 {
+			logger.InfoCF("agent", "Context trimmed to fit budget", report.fields())
+		} else {
+			logger.DebugCF("agent", "Context composition", report.fields())
+		}
 	}
 
-	if summary != "" {
-		systemPrompt += "\n\n## Summary of Previous Conversation\n\n" + summary
+	bootstrapContent := parts.sections[sectionBootstrap] + parts.sections[sectionMemory]
+	if bootstrapContent != "" {
+		systemPrompt += "\n\n" + bootstrapContent
 	}
 
-	// Add current conversation context
-	if metadata != nil && metadata["channel_id"] != "" {
-		channel := metadata["channel"]
-		if channel == "" {
-			channel = "unknown"
-		}
-		chatID := metadata["channel_id"]
+	if skillsContent := parts.sections[sectionSkills]; skillsContent != "" {
+		systemPrompt += "\n\n" + skillsContent
+	}
 
-		systemPrompt += fmt.Sprintf("\n\n## Current Conversation Context\n\n")
-		systemPrompt += fmt.Sprintf("- Channel: %s\n", channel)
-		systemPrompt += fmt.Sprintf("- Chat ID: %s\n", chatID)
-		systemPrompt += fmt.Sprintf("\nIMPORTANT: When using the send_image tool, use these values:\n")
-		systemPrompt += fmt.Sprintf("- channel: \"%s\"\n", channel)
-		systemPrompt += fmt.Sprintf("- chat_id: \"%s\"\n", chatID)
+	if summary := parts.sections[sectionSummary]; summary != "" {
+		systemPrompt += "\n\n## Summary of Previous Conversation\n\n" + summary
 	}
 
+	systemPrompt += conversation
+
 	messages = append(messages, providers.Message{
 		Role:    "system",
 		Content: systemPrompt,
 	})
 
-	messages = append(messages, history...)
-
-	// Build user message with optional media (vision support)
-	userMessage := cb.buildUserMessage(currentMessage, media)
+	messages = append(messages, parts.history...)
 	messages = append(messages, userMessage)
 
 	return messages
 }
 
+// skillsSection lists available skills and inlines the always-on ones
+func (cb *ContextBuilder) skillsSection() string {
+	var parts []string
+	if skillsSummary := cb.skillsLoader.BuildSkillsSummary(); skillsSummary != "" {
+		parts = append(parts, "## Available Skills\n\nOnly skill summaries are listed here. Call load_skill with a skill name to read its full instructions before using it.\n\n"+skillsSummary)
+	}
+	if skillsContent := cb.loadSkills(); skillsContent != "" {
+		parts = append(parts, skillsContent)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// conversationContext tells the agent which chat it is answering
+// pinnedSection lists the session's pinned facts. It is part of the fixed
+// prompt, so the context budget never trims it.
+func pinnedSection(pins []string) string {
+	if len(pins) == 0 {
+		return ""
+	}
+	text := "\n\n## Pinned Facts\n\nThe user pinned these facts for this conversation. Treat them as true unless the user changes them:\n"
+	for _, pin := range pins {
+		text += fmt.Sprintf("- %s\n", pin)
+	}
+	return text
+}
+
+func (cb *ContextBuilder) conversationContext(metadata map[string]string) string {
+	if metadata == nil || metadata["channel_id"] == "" {
+		return ""
+	}
+	channel := metadata["channel"]
+	if channel == "" {
+		channel = "unknown"
+	}
+	chatID := metadata["channel_id"]
+
+	text := "\n\n## Current Conversation Context\n\n"
+	text += fmt.Sprintf("- Channel: %s\n", channel)
+	text += fmt.Sprintf("- Chat ID: %s\n", chatID)
+	if dir := metadata["working_dir"]; dir != "" {
+		text += fmt.Sprintf("- Working directory: %s (relative paths in exec and the file tools resolve here; keep this conversation's files in it, other sessions' directories are off limits)\n", dir)
+	}
+	text += "\nIMPORTANT: When using the send_image tool, use these values:\n"
+	text += fmt.Sprintf("- channel: \"%s\"\n", channel)
+	text += fmt.Sprintf("- chat_id: \"%s\"\n", chatID)
+	return text
+}
+
 func (cb *ContextBuilder) AddToolResult(messages []providers.Message, toolCallID, toolName, result string) []providers.Message {
 	messages = append(messages, providers.Message{
 		Role:       "tool",
@@ -219,18 +437,10 @@ func (cb *ContextBuilder) AddAssistantMessage(messages []providers.Message, cont
 	return messages
 }
 
+// loadSkills inlines only the skills marked "always: true"; the rest are
+// fetched on demand through the load_skill tool
 func (cb *ContextBuilder) loadSkills() string {
-	allSkills := cb.skillsLoader.ListSkills(true)
-	if len(allSkills) == 0 {
-		return ""
-	}
-
-	var skillNames []string
-	for _, s := range allSkills {
-		skillNames = append(skillNames, s.Name)
-	}
-
-	content := cb.skillsLoader.LoadSkillsForContext(skillNames)
+	content := cb.skillsLoader.LoadSkillsForContext(cb.skillsLoader.GetAlwaysSkills())
 	if content == "" {
 		return ""
 	}
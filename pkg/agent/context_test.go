@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/journal"
+)
+
+func TestDailyNotesInContext(t *testing.T) {
+	workspace := t.TempDir()
+	cb := NewContextBuilder(workspace)
+
+	// Today's note is created but left out while it has no entries
+	messages := cb.BuildMessages(nil, "", nil, "hello", nil, nil)
+	if strings.Contains(messages[0].Content.(string), "## memory/daily/"+time.Now().Format("2006-01-02")) {
+		t.Error("empty daily note should not be in the prompt")
+	}
+
+	j := journal.New(workspace)
+	j.Append(time.Now(), "Fixed the printer")
+	j.Append(time.Now().AddDate(0, 0, -1), "Booked flights")
+
+	system := cb.BuildMessages(nil, "", nil, "hello", nil, nil)[0].Content.(string)
+	if !strings.Contains(system, "Fixed the printer") || strings.Contains(system, "Booked flights") {
+		t.Errorf("expected only today's note:\n%s", system)
+	}
+
+	cb.SetDailyNotes(config.DailyNotesConfig{IncludeYesterday: true})
+	system = cb.BuildMessages(nil, "", nil, "hello", nil, nil)[0].Content.(string)
+	if !strings.Contains(system, "Booked flights") {
+		t.Error("yesterday's note should be included")
+	}
+}
@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// baseVariant is the agent's prompt without a variant's files
+const baseVariant = "base"
+
+// VariantSession is a session's prompt variant and what came of it
+type VariantSession struct {
+	Agent    string         `json:"agent"`
+	Session  string         `json:"session"`
+	Variant  string         `json:"variant"`
+	Version  string         `json:"version"` // prompt files when the session was assigned
+	Assigned time.Time      `json:"assigned"`
+	Turns    int            `json:"turns"`
+	Outcomes map[string]int `json:"outcomes,omitempty"`
+}
+
+// PromptExperiments splits sessions between an agent's prompt variants and
+// records their turns and tagged outcomes in
+// workspace/state/prompt_experiments.json. A session keeps its variant
+// until that variant is removed.
+type PromptExperiments struct {
+	path     string
+	mu       sync.Mutex
+	sessions map[string]*VariantSession // by agent and session key
+}
+
+func NewPromptExperiments(workspace string) *PromptExperiments {
+	pe := &PromptExperiments{
+		path:     filepath.Join(workspace, "state", "prompt_experiments.json"),
+		sessions: make(map[string]*VariantSession),
+	}
+	for _, s := range LoadVariantSessions(workspace) {
+		s := s
+		pe.sessions[s.Agent+"/"+s.Session] = &s
+	}
+	return pe
+}
+
+// LoadVariantSessions reads the recorded sessions of a workspace
+func LoadVariantSessions(workspace string) []VariantSession {
+	path := filepath.Join(workspace, "state", "prompt_experiments.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var sessions []VariantSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		logger.WarnCF("agent", "Ignoring unreadable prompt experiments", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+		return nil
+	}
+	return sessions
+}
+
+// Assign returns the session's variant for this turn and counts the turn.
+// New sessions are split between the variants by weight; version is called
+// with the chosen variant to record which prompt files it had.
+func (pe *PromptExperiments) Assign(agent, sessionKey string, weights map[string]int, version func(variant string) string) string {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	key := agent + "/" + sessionKey
+	s, ok := pe.sessions[key]
+	if !ok || weights[s.Variant] <= 0 {
+		variant := pickVariant(key, weights)
+		s = &VariantSession{
+			Agent:    agent,
+			Session:  sessionKey,
+			Variant:  variant,
+			Version:  version(variant),
+			Assigned: time.Now(),
+		}
+		pe.sessions[key] = s
+		logger.DebugCF("agent", "Session assigned to prompt variant", map[string]interface{}{
+			"agent":   agent,
+			"session": sessionKey,
+			"variant": variant,
+		})
+	}
+	s.Turns++
+	pe.saveLocked()
+	return s.Variant
+}
+
+// pickVariant chooses a variant from the hash of the session key, so the
+// split is even over many sessions but the same for one session
+func pickVariant(key string, weights map[string]int) string {
+	names := make([]string, 0, len(weights))
+	total := 0
+	for name, weight := range weights {
+		if weight > 0 {
+			names = append(names, name)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return baseVariant
+	}
+	sort.Strings(names)
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	n := int(h.Sum32() % uint32(total))
+	for _, name := range names {
+		if n < weights[name] {
+			return name
+		}
+		n -= weights[name]
+	}
+	return names[len(names)-1]
+}
+
+// Tag records an outcome such as "good" or "resolved" for a session
+func (pe *PromptExperiments) Tag(agent, sessionKey, outcome string) (*VariantSession, error) {
+	outcome = strings.ToLower(strings.TrimSpace(outcome))
+	if outcome == "" || strings.ContainsAny(outcome, " \t\n") {
+		return nil, fmt.Errorf("outcome must be a single word, like good or bad")
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	s, ok := pe.sessions[agent+"/"+sessionKey]
+	if !ok {
+		return nil, fmt.Errorf("this session is not part of a prompt experiment")
+	}
+	if s.Outcomes == nil {
+		s.Outcomes = make(map[string]int)
+	}
+	s.Outcomes[outcome]++
+	pe.saveLocked()
+	copied := *s
+	return &copied, nil
+}
+
+func (pe *PromptExperiments) saveLocked() {
+	sessions := make([]*VariantSession, 0, len(pe.sessions))
+	for _, s := range pe.sessions {
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Assigned.Before(sessions[j].Assigned) })
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(pe.path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(pe.path, data, 0644)
+	}
+	if err != nil {
+		logger.WarnCF("agent", "Failed to save prompt experiments", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// VariantStats sums up the sessions of one version of a variant
+type VariantStats struct {
+	Agent    string
+	Variant  string
+	Version  string
+	Sessions int
+	Turns    int
+	Outcomes map[string]int
+}
+
+// VariantReport groups sessions by agent, variant and prompt version
+func VariantReport(sessions []VariantSession) []VariantStats {
+	byKey := make(map[string]*VariantStats)
+	for _, s := range sessions {
+		key := s.Agent + "\x00" + s.Variant + "\x00" + s.Version
+		stats, ok := byKey[key]
+		if !ok {
+			stats = &VariantStats{Agent: s.Agent, Variant: s.Variant, Version: s.Version, Outcomes: make(map[string]int)}
+			byKey[key] = stats
+		}
+		stats.Sessions++
+		stats.Turns += s.Turns
+		for outcome, n := range s.Outcomes {
+			stats.Outcomes[outcome] += n
+		}
+	}
+
+	report := make([]VariantStats, 0, len(byKey))
+	for _, stats := range byKey {
+		report = append(report, *stats)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if a.Agent != b.Agent {
+			return a.Agent < b.Agent
+		}
+		if a.Variant != b.Variant {
+			return a.Variant < b.Variant
+		}
+		return a.Version < b.Version
+	})
+	return report
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptVariants(t *testing.T) {
+	workspace := t.TempDir()
+	agentDir := filepath.Join(workspace, "agents", "default")
+	os.MkdirAll(filepath.Join(agentDir, "variants", "terse"), 0755)
+	os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("Be friendly."), 0644)
+	os.WriteFile(filepath.Join(agentDir, "IDENTITY.md"), []byte("I am Pepe."), 0644)
+	os.WriteFile(filepath.Join(agentDir, "variants", "terse", "SOUL.md"), []byte("Be brief."), 0644)
+	cb := NewContextBuilderWithAgentDir(workspace, agentDir)
+
+	if got := cb.PromptVariants(); len(got) != 1 || got[0] != "terse" {
+		t.Fatalf("variants = %v", got)
+	}
+	system := cb.BuildMessages(nil, "", nil, "hi", nil, map[string]string{"prompt_variant": "terse"})[0].Content.(string)
+	if !strings.Contains(system, "Be brief.") || strings.Contains(system, "Be friendly.") || !strings.Contains(system, "I am Pepe.") {
+		t.Errorf("variant prompt:\n%s", system)
+	}
+	system = cb.BuildMessages(nil, "", nil, "hi", nil, map[string]string{"prompt_variant": "../../etc"})[0].Content.(string)
+	if !strings.Contains(system, "Be friendly.") {
+		t.Errorf("unsafe variant name should use the base prompt:\n%s", system)
+	}
+
+	// Edits apply on the next turn and change the version
+	version := cb.PromptVersion(baseVariant)
+	os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("Be very friendly."), 0644)
+	system = cb.BuildMessages(nil, "", nil, "hi", nil, nil)[0].Content.(string)
+	if !strings.Contains(system, "Be very friendly.") {
+		t.Errorf("edit not reloaded:\n%s", system)
+	}
+	if cb.PromptVersion(baseVariant) == version {
+		t.Error("version unchanged after edit")
+	}
+}
+
+func TestPromptExperiments(t *testing.T) {
+	workspace := t.TempDir()
+	pe := NewPromptExperiments(workspace)
+	weights := map[string]int{baseVariant: 1, "terse": 1}
+	version := func(variant string) string { return "v-" + variant }
+
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		counts[pe.Assign("default", fmt.Sprintf("chat:%d", i), weights, version)]++
+	}
+	if counts[baseVariant] < 60 || counts["terse"] < 60 {
+		t.Errorf("uneven split: %v", counts)
+	}
+
+	// A session keeps its variant and its turns are counted
+	first := pe.Assign("default", "chat:1", weights, version)
+	if got := pe.Assign("default", "chat:1", weights, version); got != first {
+		t.Errorf("variant changed from %s to %s", first, got)
+	}
+	if _, err := pe.Tag("default", "chat:1", "Good"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pe.Tag("default", "unknown", "good"); err == nil {
+		t.Error("expected an error for a session outside the experiment")
+	}
+
+	// Results survive a restart
+	var stats *VariantStats
+	for _, s := range VariantReport(LoadVariantSessions(workspace)) {
+		if s.Variant == first {
+			s := s
+			stats = &s
+		}
+	}
+	if stats == nil || stats.Version != "v-"+first || stats.Sessions != counts[first] || stats.Turns != counts[first]+2 || stats.Outcomes["good"] != 1 {
+		t.Errorf("report for %s = %+v", first, stats)
+	}
+
+	// Sessions of a variant that was dropped move to the remaining ones
+	if got := pe.Assign("default", "chat:1", map[string]int{"terse": 1}, version); got != "terse" {
+		t.Errorf("variant = %s, want terse", got)
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// wrapUpTimeout bounds the final summary call after a limit was hit
+const wrapUpTimeout = 60 * time.Second
+
+// turnGuard enforces agents.defaults.limits for one turn
+type turnGuard struct {
+	limits      config.TurnLimitsConfig
+	started     time.Time
+	outputBytes int
+}
+
+func newTurnGuard(limits config.TurnLimitsConfig) *turnGuard {
+	return &turnGuard{limits: limits, started: time.Now()}
+}
+
+// withDeadline bounds ctx by the turn time limit, so LLM calls and tools
+// still running when it passes are cut off
+func (g *turnGuard) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.limits.MaxTurnSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, g.started.Add(time.Duration(g.limits.MaxTurnSeconds)*time.Second))
+}
+
+// addOutput counts the size of tool results
+func (g *turnGuard) addOutput(results []providers.Message) {
+	for _, m := range results {
+		if content, ok := m.Content.(string); ok {
+			g.outputBytes += len(content)
+		}
+	}
+}
+
+// exceeded returns why the turn has to end, or "" while within limits
+func (g *turnGuard) exceeded() string {
+	if g.limits.MaxTurnSeconds > 0 && time.Since(g.started) >= time.Duration(g.limits.MaxTurnSeconds)*time.Second {
+		return fmt.Sprintf("the turn reached its %ds time limit", g.limits.MaxTurnSeconds)
+	}
+	if g.limits.MaxToolOutputBytes > 0 && g.outputBytes >= g.limits.MaxToolOutputBytes {
+		return fmt.Sprintf("tool output reached the %d byte limit", g.limits.MaxToolOutputBytes)
+	}
+	return ""
+}
+
+// wrapUp ends a turn cut short by a limit: the model, without tools,
+// summarizes what it did and what is left. ctx is the turn's context
+// without the time limit, so /stop still applies.
+func (al *AgentLoop) wrapUp(ctx context.Context, messages []providers.Message, reason string, callback providers.StreamCallback) string {
+	logger.WarnCF("agent", "Turn limit reached, wrapping up", map[string]interface{}{
+		"agent":  al.agentName,
+		"reason": reason,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, wrapUpTimeout)
+	defer cancel()
+
+	messages = append(messages, providers.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("Stop here: %s. Do not call any tools. Briefly tell the user what you did, what you found, and what is left to do.", reason),
+	})
+	if callback == nil {
+		callback = func(providers.StreamChunk) {}
+	}
+
+	response, err := providers.StreamWithTools(ctx, al.provider, messages, nil, al.model, al.chatOptions(ctx), func(chunk providers.StreamChunk) {
+		if chunk.Content != "" {
+			callback(providers.StreamChunk{Content: chunk.Content})
+		}
+	})
+	if err == nil && response.Content != "" {
+		return response.Content
+	}
+
+	content := fmt.Sprintf("I stopped before finishing because %s.", reason)
+	callback(providers.StreamChunk{Content: content})
+	return content
+}
+
+type turnBudgetKey struct{}
+
+// turnBudget replaces the iteration count and limits for one turn
+type turnBudget struct {
+	maxIterations int
+	limits        config.TurnLimitsConfig
+}
+
+func withTurnBudget(ctx context.Context, budget turnBudget) context.Context {
+	return context.WithValue(ctx, turnBudgetKey{}, budget)
+}
+
+// turnBudget returns the iteration count and limits for the turn in ctx
+func (al *AgentLoop) turnBudget(ctx context.Context) (int, config.TurnLimitsConfig) {
+	if budget, ok := ctx.Value(turnBudgetKey{}).(turnBudget); ok {
+		return budget.maxIterations, budget.limits
+	}
+	return al.maxIterations, al.limits
+}
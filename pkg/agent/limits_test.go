@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+type blockingTool struct{}
+
+func (t *blockingTool) Name() string                       { return "hang" }
+func (t *blockingTool) Description() string                { return "" }
+func (t *blockingTool) Parameters() map[string]interface{} { return map[string]interface{}{} }
+
+func (t *blockingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	time.Sleep(5 * time.Second) // ignores cancellation
+	return "done", nil
+}
+
+func TestTurnGuardOutputLimit(t *testing.T) {
+	guard := newTurnGuard(config.TurnLimitsConfig{MaxToolOutputBytes: 10})
+	guard.addOutput([]providers.Message{{Role: "tool", Content: "12345"}})
+	if reason := guard.exceeded(); reason != "" {
+		t.Fatalf("exceeded too early: %q", reason)
+	}
+	guard.addOutput([]providers.Message{{Role: "tool", Content: "67890"}})
+	if reason := guard.exceeded(); !strings.Contains(reason, "10 byte limit") {
+		t.Errorf("reason = %q", reason)
+	}
+}
+
+func TestTurnGuardTimeLimit(t *testing.T) {
+	guard := newTurnGuard(config.TurnLimitsConfig{MaxTurnSeconds: 1})
+	if guard.exceeded() != "" {
+		t.Fatal("exceeded at start")
+	}
+	guard.started = time.Now().Add(-2 * time.Second)
+	if reason := guard.exceeded(); !strings.Contains(reason, "1s time limit") {
+		t.Errorf("reason = %q", reason)
+	}
+
+	ctx, cancel := guard.withDeadline(context.Background())
+	defer cancel()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("work context should already be past its deadline, got %v", ctx.Err())
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	registry.Register(&blockingTool{})
+	al := &AgentLoop{tools: registry, limits: config.TurnLimitsConfig{MaxToolSeconds: 1}}
+
+	start := time.Now()
+	result := al.executeToolCall(context.Background(), providers.ToolCall{ID: "x", Name: "hang"})
+	if result.Content != "Error: tool timed out after 1s" {
+		t.Errorf("unexpected result: %q", result.Content)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("timeout took %v", elapsed)
+	}
+}
+
+func TestWrapUpFallback(t *testing.T) {
+	al := &AgentLoop{provider: &failingProvider{}, model: "m"}
+	var streamed strings.Builder
+	content := al.wrapUp(context.Background(), nil, "the turn reached its 5s time limit", func(chunk providers.StreamChunk) {
+		streamed.WriteString(chunk.Content)
+	})
+	if content != "I stopped before finishing because the turn reached its 5s time limit." || streamed.String() != content {
+		t.Errorf("content = %q, streamed = %q", content, streamed.String())
+	}
+}
+
+type failingProvider struct{}
+
+func (p *failingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	return nil, context.DeadlineExceeded
+}
+
+func (p *failingProvider) ChatStream(ctx context.Context, messages []providers.Message, model string, options map[string]interface{}, callback providers.StreamCallback) error {
+	return context.DeadlineExceeded
+}
+
+func (p *failingProvider) GetDefaultModel() string { return "m" }
+
+func TestSamplingOverrides(t *testing.T) {
+	temperature, seed := 0.0, 7
+	al := &AgentLoop{temperature: 0.7, maxTokens: 1000}
+	if options := al.chatOptions(context.Background()); options["temperature"] != 0.7 || options["seed"] != nil {
+		t.Errorf("default options = %v", options)
+	}
+
+	al.SetSampling(&providers.Sampling{Temperature: &temperature})
+	ctx := providers.WithSampling(context.Background(), &providers.Sampling{Seed: &seed})
+	if options := al.chatOptions(ctx); options["temperature"] != 0.0 || options["seed"] != 7 {
+		t.Errorf("overridden options = %v", options)
+	}
+}
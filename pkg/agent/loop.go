@@ -12,15 +12,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/pepebot-space/pepebot/pkg/analytics"
+	"github.com/pepebot-space/pepebot/pkg/batch"
 	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/calendar"
 	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/feeds"
+	"github.com/pepebot-space/pepebot/pkg/journal"
 	"github.com/pepebot-space/pepebot/pkg/logger"
 	"github.com/pepebot-space/pepebot/pkg/mcp"
+	"github.com/pepebot-space/pepebot/pkg/middleware"
 	"github.com/pepebot-space/pepebot/pkg/providers"
 	"github.com/pepebot-space/pepebot/pkg/session"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
 	"github.com/pepebot-space/pepebot/pkg/tools"
 	"github.com/pepebot-space/pepebot/pkg/workflow"
 )
@@ -31,8 +39,16 @@ type AgentLoop struct {
 	workspace      string
 	model          string
 	temperature    float64
-	contextWindow  int
+	reasoning      config.ReasoningConfig
+	maxTokens      int // reply limit
+	contextWindow  int // configured window, 0 to detect it from the model
 	maxIterations  int
+	maxConcurrency int
+	maxParallel    int
+	limits         config.TurnLimitsConfig
+	verify         bool
+	extractMemory  bool // save durable facts to MEMORY.md after summarization
+	isolate        bool // give each session its own working directory
 	sessions       *session.SessionManager
 	contextBuilder *ContextBuilder
 	tools          *tools.ToolRegistry
@@ -41,6 +57,12 @@ type AgentLoop struct {
 	running        bool
 	summarizing    sync.Map
 	agentName      string
+	batch          *batch.Queue // sends memory extraction at batch prices when set
+	experiments    *PromptExperiments
+	usageLog       *analytics.Log // turns and summarizations for pepebot analytics
+	variantWeights map[string]int // prompt_variants of the agent definition
+	// sampling overrides the temperature and seed of all calls (SetSampling)
+	sampling *providers.Sampling
 }
 
 // agentGoalProcessor implements workflow.GoalProcessor using the agent's LLM provider.
@@ -58,6 +80,7 @@ func (p *agentGoalProcessor) ProcessGoal(ctx context.Context, goal string) (stri
 	if err != nil {
 		return "", fmt.Errorf("LLM call failed: %w", err)
 	}
+	recordUsage(resp.Usage)
 	return resp.Content, nil
 }
 
@@ -66,6 +89,13 @@ func (al *AgentLoop) WorkflowHelper() *workflow.WorkflowHelper {
 	return al.workflowHelper
 }
 
+// SetSampling overrides the temperature and seed of the agent's model calls,
+// e.g. for a CLI run or eval that should be repeatable. Overrides set on a
+// request context take precedence.
+func (al *AgentLoop) SetSampling(s *providers.Sampling) {
+	al.sampling = s
+}
+
 // SetManageAgentCaller wires the manage_agent tool with a caller implementation.
 func (al *AgentLoop) SetManageAgentCaller(caller tools.AgentCaller) {
 	tool, ok := al.tools.Get("manage_agent")
@@ -85,7 +115,9 @@ func NewAgentLoop(cfg *config.Config, bus *bus.MessageBus, provider providers.LL
 	toolsRegistry.Register(tools.NewReadFileTool(workspace))
 	toolsRegistry.Register(tools.NewWriteFileTool(workspace))
 	toolsRegistry.Register(tools.NewListDirTool(workspace))
-	toolsRegistry.Register(tools.NewExecTool(workspace))
+	execTool := tools.NewExecTool(workspace)
+	execTool.SetRestrictToWorkspace(cfg.Tools.Filesystem.RestrictToWorkspace)
+	toolsRegistry.Register(execTool)
 
 	// Register workflow tools (always available, no dependencies)
 	workflowHelper := workflow.NewWorkflowHelper(workspace, toolsRegistry)
@@ -94,6 +126,24 @@ func NewAgentLoop(cfg *config.Config, bus *bus.MessageBus, provider providers.LL
 	toolsRegistry.Register(tools.NewWorkflowSaveTool(workflowHelper))
 	toolsRegistry.Register(tools.NewWorkflowListTool(workflowHelper))
 
+	// Register key-value state tools (unavailable where SQLite is unsupported)
+	if kvStore, err := tools.OpenKVStore(workspace); err == nil {
+		toolsRegistry.Register(tools.NewKVGetTool(kvStore))
+		toolsRegistry.Register(tools.NewKVSetTool(kvStore))
+		toolsRegistry.Register(tools.NewKVListTool(kvStore))
+	}
+
+	// Register feed subscription tools (polled by the gateway)
+	feedStore := feeds.NewStore(workspace)
+	toolsRegistry.Register(tools.NewFeedSubscribeTool(feedStore))
+	toolsRegistry.Register(tools.NewFeedUnsubscribeTool(feedStore))
+	toolsRegistry.Register(tools.NewFeedListTool(feedStore))
+
+	// Daily notes in memory/daily/
+	dailyNotes := journal.New(workspace)
+	toolsRegistry.Register(tools.NewJournalAppendTool(dailyNotes))
+	toolsRegistry.Register(tools.NewJournalReadTool(dailyNotes))
+
 	// Register ADB tools (conditional on ADB binary availability)
 	if adbHelper, err := tools.NewAdbHelper(workspace); err == nil {
 		toolsRegistry.Register(tools.NewAdbDevicesTool(adbHelper))
@@ -105,12 +155,68 @@ func NewAgentLoop(cfg *config.Config, bus *bus.MessageBus, provider providers.LL
 		toolsRegistry.Register(tools.NewAdbSwipeTool(adbHelper))
 		toolsRegistry.Register(tools.NewAdbOpenAppTool(adbHelper))
 		toolsRegistry.Register(tools.NewAdbKeyEventTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbLogcatTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbWaitForTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbGetLocationTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbSetMockLocationTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbSendIntentTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbScrcpyStartTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbScrcpyStopTool(adbHelper))
+		toolsRegistry.Register(tools.NewScreenLocateToolFromConfig(cfg, adbHelper, provider, cfg.Agents.Defaults.Model))
 		toolsRegistry.Register(tools.NewAdbRecordWorkflowTool(adbHelper, workflowHelper))
 	}
 
 	braveAPIKey := cfg.Tools.Web.Search.APIKey
 	toolsRegistry.Register(tools.NewWebSearchTool(braveAPIKey, cfg.Tools.Web.Search.MaxResults))
-	toolsRegistry.Register(tools.NewWebFetchTool(50000))
+	webFetch := tools.NewWebFetchTool(50000)
+	webFetch.SetBrowser(cfg.Tools.Web.Fetch.Browser)
+	toolsRegistry.Register(webFetch)
+	download := tools.NewDownloadFileTool(workspace)
+	download.SetMaxBytes(int64(cfg.Tools.Download.MaxMB) << 20)
+	toolsRegistry.Register(download)
+	toolsRegistry.Register(tools.NewArchiveCreateTool(workspace))
+	toolsRegistry.Register(tools.NewArchiveExtractTool(workspace))
+	toolsRegistry.Register(tools.NewCSVReadTool(workspace))
+	toolsRegistry.Register(tools.NewCSVWriteTool(workspace))
+	toolsRegistry.Register(tools.NewCSVQueryTool(workspace))
+
+	// GitHub tools (conditional on a personal access token)
+	if cfg.Tools.GitHub.Token != "" {
+		githubClient := tools.NewGitHubClient(cfg.Tools.GitHub)
+		toolsRegistry.Register(tools.NewGitHubListIssuesTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubCreateIssueTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubCommentTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubPRDiffTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubMergePRTool(githubClient))
+	}
+
+	// Calendar tools (conditional on a CalDAV calendar URL)
+	if cfg.Tools.Calendar.URL != "" {
+		calendarClient := calendar.NewClient(cfg.Tools.Calendar)
+		toolsRegistry.Register(tools.NewCalendarListEventsTool(calendarClient))
+		toolsRegistry.Register(tools.NewCalendarCreateEventTool(calendarClient))
+	}
+
+	// Email tool (conditional on an SMTP host)
+	if cfg.Tools.Email.Host != "" {
+		toolsRegistry.Register(tools.NewEmailSendTool(cfg.Tools.Email, workspace))
+	}
+
+	// Clipboard and desktop notifications of this machine (opt-in)
+	if cfg.Tools.Host.Enabled {
+		if hostHelper, err := tools.NewHostHelper(); err == nil {
+			if hostHelper.HasClipboard() {
+				toolsRegistry.Register(tools.NewHostClipboardGetTool(hostHelper))
+				toolsRegistry.Register(tools.NewHostClipboardSetTool(hostHelper))
+			}
+			if hostHelper.HasNotify() {
+				toolsRegistry.Register(tools.NewHostNotifyTool(hostHelper))
+			}
+		} else {
+			logger.WarnCF("tools", "Host tools unavailable", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
 	toolsRegistry.Register(tools.NewSendImageTool(bus, workspace))
 	toolsRegistry.Register(tools.NewSendFileTool(bus, workspace))
 	toolsRegistry.Register(tools.NewManageAgentTool(workspace))
@@ -126,6 +232,16 @@ func NewAgentLoop(cfg *config.Config, bus *bus.MessageBus, provider providers.LL
 		}
 	}
 
+	// Script tools defined in workspace/tools/
+	tools.RegisterScriptTools(workspace, toolsRegistry)
+
+	// Path policy, write limits and the audit log of file changes and commands
+	toolsRegistry.SetGuard(tools.NewFileGuardFromConfig(cfg.Tools.Filesystem, workspace, filepath.Dir(cfg.WorkspacePath())))
+	chain := middleware.New(cfg, provider)
+	if chain != nil {
+		toolsRegistry.SetInterceptor(chain)
+	}
+
 	// Platform messaging tools (direct API — no gateway required)
 	if cfg.Channels.Telegram.Token != "" {
 		toolsRegistry.Register(tools.NewTelegramSendTool(cfg.Channels.Telegram.Token, workspace))
@@ -136,19 +252,34 @@ func NewAgentLoop(cfg *config.Config, bus *bus.MessageBus, provider providers.LL
 	toolsRegistry.Register(tools.NewWhatsAppSendTool(bus, workspace))
 
 	sessionsManager := session.NewSessionManager(filepath.Join(filepath.Dir(cfg.WorkspacePath()), "sessions"))
+	toolsRegistry.Register(tools.NewReportGenerateTool(sessionsManager, bus, workspace))
 
 	contextBuilder := NewContextBuilder(workspace)
+	contextBuilder.SetBudget(cfg.Agents.Defaults.ContextBudget, cfg.Agents.Defaults.Model, cfg.Agents.Defaults.MaxTokens, toolsRegistry.GetDefinitions)
+	contextBuilder.SetDailyNotes(cfg.Agents.Defaults.DailyNotes)
+	contextBuilder.SetUntrustedContent(cfg.Filter.Injection.Enabled)
 	workflowHelper.SetSkillProvider(contextBuilder.SkillsLoader())
+	toolsRegistry.Register(tools.NewLoadSkillTool(contextBuilder.SkillsLoader()))
+	toolsRegistry.Register(tools.NewSkillCreateTool(contextBuilder.SkillsLoader()))
 
 	return &AgentLoop{
 		bus:            bus,
-		provider:       provider,
+		provider:       middleware.WrapProvider(provider, chain),
 		workspace:      workspace,
 		model:          cfg.Agents.Defaults.Model,
 		temperature:    cfg.Agents.Defaults.Temperature,
-		contextWindow:  cfg.Agents.Defaults.MaxTokens,
+		reasoning:      cfg.Agents.Defaults.Reasoning,
+		maxTokens:      cfg.Agents.Defaults.MaxTokens,
+		contextWindow:  cfg.Agents.Defaults.ContextWindow,
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
+		maxConcurrency: cfg.Agents.Defaults.MaxConcurrency,
+		maxParallel:    cfg.Agents.Defaults.MaxParallelTools,
+		limits:         cfg.Agents.Defaults.Limits,
+		verify:         cfg.Agents.Defaults.Verify,
+		extractMemory:  cfg.Agents.Defaults.MemoryExtraction,
+		isolate:        cfg.Agents.Defaults.SessionIsolation,
 		sessions:       sessionsManager,
+		usageLog:       analytics.New(filepath.Join(filepath.Dir(workspace), analytics.FileName)),
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
 		workflowHelper: workflowHelper,
@@ -168,7 +299,9 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 	toolsRegistry.Register(tools.NewReadFileTool(workspace))
 	toolsRegistry.Register(tools.NewWriteFileTool(workspace))
 	toolsRegistry.Register(tools.NewListDirTool(workspace))
-	toolsRegistry.Register(tools.NewExecTool(workspace))
+	execTool := tools.NewExecTool(workspace)
+	execTool.SetRestrictToWorkspace(cfg.Tools.Filesystem.RestrictToWorkspace)
+	toolsRegistry.Register(execTool)
 
 	// Register workflow tools (always available, no dependencies)
 	workflowHelper := workflow.NewWorkflowHelper(workspace, toolsRegistry)
@@ -177,6 +310,24 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 	toolsRegistry.Register(tools.NewWorkflowSaveTool(workflowHelper))
 	toolsRegistry.Register(tools.NewWorkflowListTool(workflowHelper))
 
+	// Register key-value state tools (unavailable where SQLite is unsupported)
+	if kvStore, err := tools.OpenKVStore(workspace); err == nil {
+		toolsRegistry.Register(tools.NewKVGetTool(kvStore))
+		toolsRegistry.Register(tools.NewKVSetTool(kvStore))
+		toolsRegistry.Register(tools.NewKVListTool(kvStore))
+	}
+
+	// Register feed subscription tools (polled by the gateway)
+	feedStore := feeds.NewStore(workspace)
+	toolsRegistry.Register(tools.NewFeedSubscribeTool(feedStore))
+	toolsRegistry.Register(tools.NewFeedUnsubscribeTool(feedStore))
+	toolsRegistry.Register(tools.NewFeedListTool(feedStore))
+
+	// Daily notes in memory/daily/
+	dailyNotes := journal.New(workspace)
+	toolsRegistry.Register(tools.NewJournalAppendTool(dailyNotes))
+	toolsRegistry.Register(tools.NewJournalReadTool(dailyNotes))
+
 	// Register ADB tools (conditional on ADB binary availability)
 	if adbHelper, err := tools.NewAdbHelper(workspace); err == nil {
 		toolsRegistry.Register(tools.NewAdbDevicesTool(adbHelper))
@@ -188,12 +339,68 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 		toolsRegistry.Register(tools.NewAdbSwipeTool(adbHelper))
 		toolsRegistry.Register(tools.NewAdbOpenAppTool(adbHelper))
 		toolsRegistry.Register(tools.NewAdbKeyEventTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbLogcatTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbWaitForTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbGetLocationTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbSetMockLocationTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbSendIntentTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbScrcpyStartTool(adbHelper))
+		toolsRegistry.Register(tools.NewAdbScrcpyStopTool(adbHelper))
+		toolsRegistry.Register(tools.NewScreenLocateToolFromConfig(cfg, adbHelper, provider, agentDef.Model))
 		toolsRegistry.Register(tools.NewAdbRecordWorkflowTool(adbHelper, workflowHelper))
 	}
 
 	braveAPIKey := cfg.Tools.Web.Search.APIKey
 	toolsRegistry.Register(tools.NewWebSearchTool(braveAPIKey, cfg.Tools.Web.Search.MaxResults))
-	toolsRegistry.Register(tools.NewWebFetchTool(50000))
+	webFetch := tools.NewWebFetchTool(50000)
+	webFetch.SetBrowser(cfg.Tools.Web.Fetch.Browser)
+	toolsRegistry.Register(webFetch)
+	download := tools.NewDownloadFileTool(workspace)
+	download.SetMaxBytes(int64(cfg.Tools.Download.MaxMB) << 20)
+	toolsRegistry.Register(download)
+	toolsRegistry.Register(tools.NewArchiveCreateTool(workspace))
+	toolsRegistry.Register(tools.NewArchiveExtractTool(workspace))
+	toolsRegistry.Register(tools.NewCSVReadTool(workspace))
+	toolsRegistry.Register(tools.NewCSVWriteTool(workspace))
+	toolsRegistry.Register(tools.NewCSVQueryTool(workspace))
+
+	// GitHub tools (conditional on a personal access token)
+	if cfg.Tools.GitHub.Token != "" {
+		githubClient := tools.NewGitHubClient(cfg.Tools.GitHub)
+		toolsRegistry.Register(tools.NewGitHubListIssuesTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubCreateIssueTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubCommentTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubPRDiffTool(githubClient))
+		toolsRegistry.Register(tools.NewGitHubMergePRTool(githubClient))
+	}
+
+	// Calendar tools (conditional on a CalDAV calendar URL)
+	if cfg.Tools.Calendar.URL != "" {
+		calendarClient := calendar.NewClient(cfg.Tools.Calendar)
+		toolsRegistry.Register(tools.NewCalendarListEventsTool(calendarClient))
+		toolsRegistry.Register(tools.NewCalendarCreateEventTool(calendarClient))
+	}
+
+	// Email tool (conditional on an SMTP host)
+	if cfg.Tools.Email.Host != "" {
+		toolsRegistry.Register(tools.NewEmailSendTool(cfg.Tools.Email, workspace))
+	}
+
+	// Clipboard and desktop notifications of this machine (opt-in)
+	if cfg.Tools.Host.Enabled {
+		if hostHelper, err := tools.NewHostHelper(); err == nil {
+			if hostHelper.HasClipboard() {
+				toolsRegistry.Register(tools.NewHostClipboardGetTool(hostHelper))
+				toolsRegistry.Register(tools.NewHostClipboardSetTool(hostHelper))
+			}
+			if hostHelper.HasNotify() {
+				toolsRegistry.Register(tools.NewHostNotifyTool(hostHelper))
+			}
+		} else {
+			logger.WarnCF("tools", "Host tools unavailable", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
 	toolsRegistry.Register(tools.NewSendImageTool(bus, workspace))
 	toolsRegistry.Register(tools.NewSendFileTool(bus, workspace))
 	toolsRegistry.Register(tools.NewManageAgentTool(workspace))
@@ -209,6 +416,16 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 		}
 	}
 
+	// Script tools defined in workspace/tools/
+	tools.RegisterScriptTools(workspace, toolsRegistry)
+
+	// Path policy, write limits and the audit log of file changes and commands
+	toolsRegistry.SetGuard(tools.NewFileGuardFromConfig(cfg.Tools.Filesystem, workspace, filepath.Dir(cfg.WorkspacePath())))
+	chain := middleware.New(cfg, provider)
+	if chain != nil {
+		toolsRegistry.SetInterceptor(chain)
+	}
+
 	// Platform messaging tools (direct API — no gateway required)
 	if cfg.Channels.Telegram.Token != "" {
 		toolsRegistry.Register(tools.NewTelegramSendTool(cfg.Channels.Telegram.Token, workspace))
@@ -219,6 +436,7 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 	toolsRegistry.Register(tools.NewWhatsAppSendTool(bus, workspace))
 
 	sessionsManager := session.NewSessionManager(filepath.Join(filepath.Dir(cfg.WorkspacePath()), "sessions"))
+	toolsRegistry.Register(tools.NewReportGenerateTool(sessionsManager, bus, workspace))
 
 	// Use agent definition values, fallback to config defaults
 	model := agentDef.Model
@@ -230,6 +448,10 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 	if maxTokens == 0 {
 		maxTokens = cfg.Agents.Defaults.MaxTokens
 	}
+	reasoning := cfg.Agents.Defaults.Reasoning
+	if agentDef.Reasoning != nil {
+		reasoning = *agentDef.Reasoning
+	}
 
 	// Use agent-specific prompt dir if PromptFile is set
 	var contextBuilder *ContextBuilder
@@ -239,17 +461,32 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 		contextBuilder = NewContextBuilder(workspace)
 	}
 
+	contextBuilder.SkillsLoader().SetAllowedSkills(agentDef.Skills)
+	contextBuilder.SetBudget(cfg.Agents.Defaults.ContextBudget, model, maxTokens, toolsRegistry.GetDefinitions)
+	contextBuilder.SetDailyNotes(cfg.Agents.Defaults.DailyNotes)
+	contextBuilder.SetUntrustedContent(cfg.Filter.Injection.Enabled)
 	workflowHelper.SetSkillProvider(contextBuilder.SkillsLoader())
+	toolsRegistry.Register(tools.NewLoadSkillTool(contextBuilder.SkillsLoader()))
+	toolsRegistry.Register(tools.NewSkillCreateTool(contextBuilder.SkillsLoader()))
 
 	return &AgentLoop{
 		bus:            bus,
-		provider:       provider,
+		provider:       middleware.WrapProvider(provider, chain),
 		workspace:      workspace,
 		model:          model,
 		temperature:    temperature,
-		contextWindow:  maxTokens,
+		reasoning:      reasoning,
+		maxTokens:      maxTokens,
+		contextWindow:  cfg.Agents.Defaults.ContextWindow,
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
+		maxConcurrency: cfg.Agents.Defaults.MaxConcurrency,
+		maxParallel:    cfg.Agents.Defaults.MaxParallelTools,
+		limits:         cfg.Agents.Defaults.Limits,
+		verify:         cfg.Agents.Defaults.Verify,
+		extractMemory:  cfg.Agents.Defaults.MemoryExtraction,
+		isolate:        cfg.Agents.Defaults.SessionIsolation,
 		sessions:       sessionsManager,
+		usageLog:       analytics.New(filepath.Join(filepath.Dir(workspace), analytics.FileName)),
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
 		workflowHelper: workflowHelper,
@@ -257,11 +494,13 @@ func NewAgentLoopWithDefinition(cfg *config.Config, bus *bus.MessageBus, provide
 		running:        false,
 		summarizing:    sync.Map{},
 		agentName:      agentName,
+		variantWeights: agentDef.PromptVariants,
 	}
 }
 
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running = true
+	workers := newWorkerPool(al.maxConcurrency)
 
 	for al.running {
 		select {
@@ -273,18 +512,20 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
-			response, err := al.processMessage(ctx, msg)
-			if err != nil {
-				response = fmt.Sprintf("Error processing message: %v", err)
-			}
+			workers.Submit(sessionKeyFor(msg.SessionKey, msg.Channel, msg.ChatID), func() {
+				response, err := al.processMessage(ctx, msg)
+				if err != nil {
+					response = fmt.Sprintf("Error processing message: %v", err)
+				}
 
-			if response != "" {
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: msg.Channel,
-					ChatID:  msg.ChatID,
-					Content: response,
-				})
-			}
+				if response != "" {
+					al.bus.PublishOutbound(bus.OutboundMessage{
+						Channel: msg.Channel,
+						ChatID:  msg.ChatID,
+						Content: response,
+					})
+				}
+			})
 		}
 	}
 
@@ -298,6 +539,51 @@ func (al *AgentLoop) Stop() {
 	}
 }
 
+// promptVariant returns the prompt variant for a session's turn, or "" when
+// the agent has no variants
+func (al *AgentLoop) promptVariant(sessionKey string) string {
+	if al.experiments == nil {
+		return ""
+	}
+	variants := al.contextBuilder.PromptVariants()
+	if len(variants) == 0 {
+		return ""
+	}
+
+	// Without prompt_variants every variant and the base prompt get an
+	// equal share
+	weights := make(map[string]int)
+	for _, name := range append([]string{baseVariant}, variants...) {
+		weight := 1
+		if al.variantWeights != nil {
+			weight = al.variantWeights[name]
+		}
+		if weight > 0 {
+			weights[name] = weight
+		}
+	}
+	if len(weights) == 0 {
+		return ""
+	}
+	return al.experiments.Assign(al.agentName, sessionKey, weights, al.contextBuilder.PromptVersion)
+}
+
+// OutcomeCommand runs /outcome, tagging the outcome of a session's prompt
+// variant, and returns the reply
+func (al *AgentLoop) OutcomeCommand(sessionKey, outcome string) string {
+	if outcome == "" {
+		return "Usage: /outcome <tag>, for example /outcome good or /outcome bad"
+	}
+	if al.experiments == nil {
+		return "Prompt experiments are not available."
+	}
+	s, err := al.experiments.Tag(al.agentName, sessionKey, outcome)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return fmt.Sprintf("Outcome %q recorded for prompt variant %s.", strings.ToLower(outcome), s.Variant)
+}
+
 func (al *AgentLoop) ClearSession(sessionKey string) {
 	al.sessions.ClearSession(sessionKey)
 }
@@ -310,6 +596,12 @@ func (al *AgentLoop) AgentName() string {
 	return al.agentName
 }
 
+// SessionTokens counts the stored history and summary of a session
+func (al *AgentLoop) SessionTokens(sessionKey string) int {
+	return tokens.CountMessages(al.model, al.sessions.GetHistory(sessionKey)) +
+		tokens.Count(al.model, al.sessions.GetSummary(sessionKey))
+}
+
 func (al *AgentLoop) Sessions() *session.SessionManager {
 	return al.sessions
 }
@@ -324,6 +616,21 @@ func (al *AgentLoop) ExecuteTool(ctx context.Context, name string, args map[stri
 	return al.tools.Execute(ctx, name, args)
 }
 
+// Passthrough sends messages and client-defined tools straight to the
+// agent's model. There is no system prompt, session history or tool
+// execution; options override the agent's max_tokens and temperature.
+func (al *AgentLoop) Passthrough(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, options map[string]interface{}) (*providers.LLMResponse, error) {
+	chatOptions := al.chatOptions(ctx)
+	for k, v := range options {
+		chatOptions[k] = v
+	}
+	resp, err := al.provider.Chat(ctx, messages, toolDefs, al.model, chatOptions)
+	if err == nil {
+		recordUsage(resp.Usage)
+	}
+	return resp, err
+}
+
 func (al *AgentLoop) ProcessDirect(ctx context.Context, content string, media []string, sessionKey string) (string, error) {
 	msg := bus.InboundMessage{
 		Channel:    "cli",
@@ -337,9 +644,10 @@ func (al *AgentLoop) ProcessDirect(ctx context.Context, content string, media []
 	return al.processMessage(ctx, msg)
 }
 
-// ProcessDirectStream processes a message with streaming for the final response.
-// Tool iterations use non-streaming Chat(); only the final LLM call streams.
-func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content string, media []string, sessionKey string, callback providers.StreamCallback) error {
+// ProcessDirectStream processes a message and streams every LLM call. Text
+// the model writes alongside tool calls is streamed too, so the reply is the
+// concatenation of all iterations.
+func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content string, media []string, sessionKey string, callback providers.StreamCallback) (err error) {
 	msg := bus.InboundMessage{
 		Channel:    "web",
 		SenderID:   "user",
@@ -352,27 +660,62 @@ func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content string, me
 	logger.DebugCF("agent", "Processing stream message", map[string]interface{}{
 		"session_key": msg.SessionKey,
 	})
+	ctx = al.withSource(ctx, msg)
+	ctx, stats := withTurnStats(ctx)
+	defer func(start time.Time) { al.recordTurn(msg, start, stats, err) }(time.Now())
 
 	history := al.sessions.GetHistory(msg.SessionKey)
 	summary := al.sessions.GetSummary(msg.SessionKey)
 
 	metadata := map[string]string{
-		"channel":    msg.Channel,
-		"channel_id": msg.ChatID,
+		"channel":        msg.Channel,
+		"channel_id":     msg.ChatID,
+		"working_dir":    al.sessionWorkDir(msg.SessionKey),
+		"prompt_variant": al.promptVariant(msg.SessionKey),
 	}
 
 	messages := al.contextBuilder.BuildMessages(
 		history,
 		summary,
+		al.sessions.GetPins(msg.SessionKey),
 		msg.Content,
 		msg.Media,
 		metadata,
 	)
 
 	iteration := 0
+	var streamed strings.Builder
+	separate := false
+	// Forward answer text only; Done is sent once the whole turn is over
+	streamContent := func(chunk providers.StreamChunk) {
+		if chunk.Content == "" {
+			return
+		}
+		content := chunk.Content
+		if separate {
+			// Separate text from the previous iteration
+			content = "\n\n" + strings.TrimLeft(content, "\n")
+			separate = false
+		}
+		streamed.WriteString(content)
+		callback(providers.StreamChunk{Content: content})
+	}
+
+	guard := newTurnGuard(al.limits)
+	workCtx, cancelWork := guard.withDeadline(ctx)
+	defer cancelWork()
+	stopReason := fmt.Sprintf("the turn used all %d tool iterations", al.maxIterations)
+	usedTools, verifying := false, false
 
 	for iteration < al.maxIterations {
 		iteration++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if reason := guard.exceeded(); reason != "" {
+			stopReason = reason
+			break
+		}
 
 		toolDefs := al.tools.GetDefinitions()
 		providerToolDefs := make([]providers.ToolDefinition, 0, len(toolDefs))
@@ -387,48 +730,50 @@ func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content string, me
 			})
 		}
 
-		// Non-streaming call for tool iterations
-		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-			"max_tokens":  al.contextWindow,
-			"temperature": al.temperature,
-		})
+		separate = streamed.Len() > 0
+		stream := streamContent
+		if verifying {
+			// The draft is already out; only the verification note is sent
+			stream = func(providers.StreamChunk) {}
+		}
+
+		response, err := providers.StreamWithTools(workCtx, al.provider, messages, providerToolDefs, al.model, al.chatOptions(ctx), stream)
 
 		if err != nil {
+			if ctx.Err() == nil && workCtx.Err() != nil {
+				stopReason = guard.exceeded()
+				break
+			}
 			return fmt.Errorf("LLM call failed: %w", err)
 		}
+		al.logReasoning(response.Reasoning, iteration)
 
 		if len(response.ToolCalls) == 0 {
-			// No tool calls - this is the final response.
-			// If we already got content from Chat(), stream it character-by-character
-			// (provider already returned full content, so we just emit it)
-			if response.Content != "" {
-				// Use streaming for the final call instead
-				// Re-do the last call with streaming
-				err := al.provider.ChatStream(ctx, messages, al.model, map[string]interface{}{
-					"max_tokens":  al.contextWindow,
-					"temperature": al.temperature,
-				}, callback)
-				if err != nil {
-					// Fallback: emit the non-streamed content
-					callback(providers.StreamChunk{Content: response.Content})
-					callback(providers.StreamChunk{Done: true})
-				}
-			} else {
-				callback(providers.StreamChunk{Content: "I've completed processing but have no response to give."})
-				callback(providers.StreamChunk{Done: true})
+			if al.verify && usedTools && !verifying && iteration < al.maxIterations {
+				verifying = true
+				logger.DebugCF("agent", "Verifying answer", map[string]interface{}{"agent": al.agentName})
+				messages = verifyMessages(messages, response)
+				continue
+			}
+			if verifying {
+				streamContent(providers.StreamChunk{Content: verificationNote(response.Content)})
 			}
 
-			// Save to session
-			finalContent := response.Content
+			// No tool calls - this is the final response
+			finalContent := strings.TrimSpace(streamed.String())
 			if finalContent == "" {
 				finalContent = "I've completed processing but have no response to give."
+				callback(providers.StreamChunk{Content: finalContent})
 			}
+			callback(providers.StreamChunk{Done: true})
+
+			// Save to session
 			al.sessions.AddMessage(msg.SessionKey, "user", msg.Content)
 			al.sessions.AddMessage(msg.SessionKey, "assistant", finalContent)
 
 			newHistory := al.sessions.GetHistory(msg.SessionKey)
 			tokenEstimate := al.estimateTokens(newHistory)
-			threshold := al.contextWindow * 75 / 100
+			threshold := al.summarizeThreshold()
 
 			if len(newHistory) > 20 || tokenEstimate > threshold {
 				if _, loading := al.summarizing.LoadOrStore(msg.SessionKey, true); !loading {
@@ -443,10 +788,11 @@ func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content string, me
 			return nil
 		}
 
-		// Handle tool calls (non-streaming)
+		// Handle tool calls
 		assistantMsg := providers.Message{
-			Role:    "assistant",
-			Content: response.Content,
+			Role:     "assistant",
+			Content:  response.Content,
+			Thinking: response.Thinking,
 		}
 
 		for _, tc := range response.ToolCalls {
@@ -462,40 +808,37 @@ func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content string, me
 		}
 		messages = append(messages, assistantMsg)
 
-		toolExecCtx := tools.WithSessionKey(ctx, msg.SessionKey)
-		for _, tc := range response.ToolCalls {
-			logger.DebugCF("agent", "Executing tool (stream mode)", map[string]interface{}{
-				"tool_name": tc.Name,
-				"tool_id":   truncateString(tc.ID, 80),
-				"arguments": truncateString(mustJSON(tc.Arguments), 300),
-			})
-
-			result, err := al.tools.Execute(toolExecCtx, tc.Name, tc.Arguments)
-			if err != nil {
-				result = fmt.Sprintf("Error: %v", err)
-			}
-
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: tc.ID,
-			}
-			messages = append(messages, toolResultMsg)
-		}
+		toolExecCtx := tools.WithSessionKey(providers.WithResponseFormat(workCtx, nil), msg.SessionKey)
+		toolExecCtx = tools.WithWorkDir(toolExecCtx, al.sessionWorkDir(msg.SessionKey))
+		results := al.executeToolCalls(toolExecCtx, response.ToolCalls)
+		guard.addOutput(results)
+		messages = append(messages, results...)
+		usedTools = true
 	}
 
-	// Max iterations reached - stream the final content we have
-	callback(providers.StreamChunk{Content: "I've completed processing but have no response to give."})
+	// Out of iterations or past a turn limit - stream a wrap-up of the turn
+	separate = streamed.Len() > 0
+	al.wrapUp(ctx, messages, stopReason, streamContent)
 	callback(providers.StreamChunk{Done: true})
 
 	al.sessions.AddMessage(msg.SessionKey, "user", msg.Content)
-	al.sessions.AddMessage(msg.SessionKey, "assistant", "I've completed processing but have no response to give.")
+	al.sessions.AddMessage(msg.SessionKey, "assistant", strings.TrimSpace(streamed.String()))
 	al.sessions.Save(al.sessions.GetOrCreate(msg.SessionKey))
 
 	return nil
 }
 
-func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+// withSource tells middleware which conversation the turn belongs to
+func (al *AgentLoop) withSource(ctx context.Context, msg bus.InboundMessage) context.Context {
+	return middleware.WithSource(ctx, middleware.Source{
+		Agent:   al.agentName,
+		Session: msg.SessionKey,
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+	})
+}
+
+func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (reply string, err error) {
 	logger.DebugCF("agent", "Processing message", map[string]interface{}{
 		"channel":     msg.Channel,
 		"sender_id":   msg.SenderID,
@@ -503,6 +846,9 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		"session_key": msg.SessionKey,
 		"has_media":   len(msg.Media) > 0,
 	})
+	ctx = al.withSource(ctx, msg)
+	ctx, stats := withTurnStats(ctx)
+	defer func(start time.Time) { al.recordTurn(msg, start, stats, err) }(time.Now())
 
 	history := al.sessions.GetHistory(msg.SessionKey)
 	summary := al.sessions.GetSummary(msg.SessionKey)
@@ -518,10 +864,13 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	if metadata["channel_id"] == "" {
 		metadata["channel_id"] = msg.ChatID
 	}
+	metadata["working_dir"] = al.sessionWorkDir(msg.SessionKey)
+	metadata["prompt_variant"] = al.promptVariant(msg.SessionKey)
 
 	messages := al.contextBuilder.BuildMessages(
 		history,
 		summary,
+		al.sessions.GetPins(msg.SessionKey),
 		msg.Content,
 		msg.Media, // Pass media for multimodal support (images, documents, audio, video)
 		metadata,  // Pass conversation context for send tools
@@ -529,9 +878,24 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 
 	iteration := 0
 	var finalContent string
-
-	for iteration < al.maxIterations {
+	responseFormat := providers.ResponseFormatFromContext(ctx)
+	formatRetried := false
+	usedTools, verifying := false, false
+	var draft string
+	maxIterations, limits := al.turnBudget(ctx)
+	guard := newTurnGuard(limits)
+	workCtx, cancelWork := guard.withDeadline(ctx)
+	defer cancelWork()
+
+	for iteration < maxIterations {
 		iteration++
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if reason := guard.exceeded(); reason != "" {
+			finalContent = al.wrapUp(ctx, messages, reason, nil)
+			break
+		}
 
 		toolDefs := al.tools.GetDefinitions()
 		providerToolDefs := make([]providers.ToolDefinition, 0, len(toolDefs))
@@ -552,17 +916,19 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 			"tools":     len(providerToolDefs),
 		})
 
-		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-			"max_tokens":  al.contextWindow,
-			"temperature": al.temperature,
-		})
+		response, err := al.provider.Chat(workCtx, messages, providerToolDefs, al.model, al.chatOptions(ctx))
 
 		if err != nil {
+			if ctx.Err() == nil && workCtx.Err() != nil {
+				finalContent = al.wrapUp(ctx, messages, guard.exceeded(), nil)
+				break
+			}
 			logger.ErrorCF("agent", "LLM call failed", map[string]interface{}{
 				"error": err.Error(),
 			})
 			return "", fmt.Errorf("LLM call failed: %w", err)
 		}
+		recordUsage(response.Usage)
 
 		logger.DebugCF("agent", "LLM response received", map[string]interface{}{
 			"has_content":     response.Content != "",
@@ -570,15 +936,44 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 			"tool_names":      toolCallNames(response.ToolCalls),
 			"content_preview": truncateString(response.Content, 100),
 		})
+		al.logReasoning(response.Reasoning, iteration)
 
 		if len(response.ToolCalls) == 0 {
+			// Check answers built on tool results once before sending them
+			if al.verify && usedTools && !verifying && !responseFormat.WantsJSON() && iteration < maxIterations {
+				verifying = true
+				draft = response.Content
+				logger.DebugCF("agent", "Verifying answer", map[string]interface{}{"agent": al.agentName})
+				messages = verifyMessages(messages, response)
+				continue
+			}
 			finalContent = response.Content
+			if responseFormat.WantsJSON() {
+				cleaned, err := responseFormat.Validate(response.Content)
+				if err == nil {
+					finalContent = cleaned
+				} else if !formatRetried && iteration < maxIterations {
+					// Ask once for a corrected reply before giving up
+					formatRetried = true
+					logger.WarnCF("agent", "Reply does not match response_format, retrying", map[string]interface{}{
+						"error": err.Error(),
+					})
+					messages = append(messages,
+						providers.Message{Role: "assistant", Content: response.Content},
+						providers.Message{Role: "user", Content: fmt.Sprintf("Your reply does not match the required format: %v. %s", err, responseFormat.Instruction())},
+					)
+					continue
+				} else {
+					return "", fmt.Errorf("reply does not match response_format: %w", err)
+				}
+			}
 			break
 		}
 
 		assistantMsg := providers.Message{
-			Role:    "assistant",
-			Content: response.Content,
+			Role:     "assistant",
+			Content:  response.Content,
+			Thinking: response.Thinking,
 		}
 
 		for _, tc := range response.ToolCalls {
@@ -594,40 +989,27 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		}
 		messages = append(messages, assistantMsg)
 
-		toolExecCtx := tools.WithSessionKey(ctx, msg.SessionKey)
-		for _, tc := range response.ToolCalls {
-			logger.DebugCF("agent", "Executing tool", map[string]interface{}{
-				"tool_name": tc.Name,
-				"tool_id":   truncateString(tc.ID, 80),
-				"arguments": truncateString(mustJSON(tc.Arguments), 300),
-			})
+		toolExecCtx := tools.WithSessionKey(providers.WithResponseFormat(workCtx, nil), msg.SessionKey)
+		toolExecCtx = tools.WithWorkDir(toolExecCtx, al.sessionWorkDir(msg.SessionKey))
+		results := al.executeToolCalls(toolExecCtx, response.ToolCalls)
+		guard.addOutput(results)
+		messages = append(messages, results...)
+		usedTools = true
+	}
 
-			result, err := al.tools.Execute(toolExecCtx, tc.Name, tc.Arguments)
-			if err != nil {
-				logger.ErrorCF("agent", "Tool execution failed", map[string]interface{}{
-					"tool_name": tc.Name,
-					"error":     err.Error(),
-				})
-				result = fmt.Sprintf("Error: %v", err)
-			} else {
-				logger.DebugCF("agent", "Tool execution completed", map[string]interface{}{
-					"tool_name":      tc.Name,
-					"result_preview": truncateString(result, 300),
-				})
-			}
+	// Out of iterations right after running tools: report instead of going silent
+	if finalContent == "" && iteration >= maxIterations && messages[len(messages)-1].Role == "tool" {
+		finalContent = al.wrapUp(ctx, messages, fmt.Sprintf("the turn used all %d tool iterations", maxIterations), nil)
+	}
 
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: tc.ID,
-			}
-			messages = append(messages, toolResultMsg)
-		}
+	if verifying {
+		finalContent = mergeVerification(draft, finalContent)
 	}
 
 	if finalContent == "" {
 		finalContent = "I've completed processing but have no response to give."
 	}
+	recordTranscript(ctx, messages, finalContent)
 
 	al.sessions.AddMessage(msg.SessionKey, "user", msg.Content)
 	al.sessions.AddMessage(msg.SessionKey, "assistant", finalContent)
@@ -638,7 +1020,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	// Token Awareness (Dynamic)
 	// Trigger if history > 20 messages OR estimated tokens > 75% of context window
 	tokenEstimate := al.estimateTokens(newHistory)
-	threshold := al.contextWindow * 75 / 100
+	threshold := al.summarizeThreshold()
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
 		if _, loading := al.summarizing.LoadOrStore(msg.SessionKey, true); !loading {
@@ -670,7 +1052,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 
 	// Oversized Message Guard (Dynamic)
 	// Skip messages larger than 50% of context window to prevent summarizer overflow.
-	maxMessageTokens := al.contextWindow / 2
+	maxMessageTokens := al.window() / 2
 	validMessages := make([]providers.Message, 0)
 	omitted := false
 
@@ -679,7 +1061,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 			continue
 		}
 		// Estimate tokens for this message
-		msgTokens := al.getContentLength(m.Content) / 4
+		msgTokens := tokens.CountContent(al.model, m.Content)
 		if msgTokens > maxMessageTokens {
 			omitted = true
 			continue
@@ -691,47 +1073,150 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 		return
 	}
 
-	// Multi-Part Summarization
-	// Split into two parts if history is significant
-	var finalSummary string
+	// Fold the messages into the structured summary, in two passes when the
+	// history is significant so each prompt stays small
+	structured := al.sessions.GetStructuredSummary(sessionKey)
+	if structured == nil {
+		structured = &session.StructuredSummary{Overview: summary}
+	}
+	parts := [][]providers.Message{validMessages}
 	if len(validMessages) > 10 {
 		mid := len(validMessages) / 2
-		part1 := validMessages[:mid]
-		part2 := validMessages[mid:]
-
-		s1, _ := al.summarizeBatch(ctx, part1, "")
-		s2, _ := al.summarizeBatch(ctx, part2, "")
+		parts = [][]providers.Message{validMessages[:mid], validMessages[mid:]}
+	}
 
-		// Merge them
-		mergePrompt := fmt.Sprintf("Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s", s1, s2)
-		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]interface{}{
-			"max_tokens":  1024,
-			"temperature": 0.3,
-		})
-		if err == nil {
-			finalSummary = resp.Content
-		} else {
-			finalSummary = s1 + " " + s2
+	var finalSummary string
+	flat := false
+	for _, part := range parts {
+		updated, reply, err := al.summarizeStructured(ctx, part, structured)
+		if err != nil {
+			// Keep the history; the next turn over the threshold retries
+			return
 		}
-	} else {
-		finalSummary, _ = al.summarizeBatch(ctx, validMessages, summary)
+		// A reply without JSON is kept as a flat summary, like earlier
+		// releases did
+		flat = updated == nil
+		if flat {
+			updated = &session.StructuredSummary{Overview: strings.TrimSpace(reply)}
+		}
+		structured = updated
+		finalSummary = structured.Render()
+	}
+	if flat {
+		structured = nil
 	}
 
 	if omitted && finalSummary != "" {
-		finalSummary += "\n[Note: Some oversized messages were omitted from this summary for efficiency.]"
+		if structured != nil {
+			structured.Overview += "\n[Note: Some oversized messages were omitted from this summary for efficiency.]"
+		} else {
+			finalSummary += "\n[Note: Some oversized messages were omitted from this summary for efficiency.]"
+		}
 	}
 
 	if finalSummary != "" {
-		al.sessions.SetSummary(sessionKey, finalSummary)
+		if structured != nil {
+			al.sessions.SetStructuredSummary(sessionKey, structured)
+		} else {
+			al.sessions.SetSummary(sessionKey, finalSummary)
+		}
 		al.sessions.TruncateHistory(sessionKey, 4)
 		al.sessions.Save(al.sessions.GetOrCreate(sessionKey))
+		al.recordEvent(analytics.Event{Kind: analytics.KindSummary, Session: sessionKey})
+
+		if al.extractMemory {
+			al.extractMemories(validMessages)
+		}
 	}
 }
 
-func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Message, existingSummary string) (string, error) {
-	prompt := "Provide a concise summary of this conversation segment, preserving core context and key points.\n"
-	if existingSummary != "" {
-		prompt += "Existing context: " + existingSummary + "\n"
+// chatOptions are the provider options for a model call. A response format
+// and sampling overrides set on ctx (e.g. by the gateway) are passed through.
+func (al *AgentLoop) chatOptions(ctx context.Context) map[string]interface{} {
+	options := map[string]interface{}{
+		"max_tokens":  al.outputLimit(),
+		"temperature": al.temperature,
+	}
+	al.sampling.Apply(options)
+	providers.SamplingFromContext(ctx).Apply(options)
+	if rf := providers.ResponseFormatFromContext(ctx); rf != nil {
+		options["response_format"] = rf
+	}
+	if al.reasoning.Effort != "" || al.reasoning.BudgetTokens > 0 {
+		options["reasoning"] = &providers.ReasoningOptions{
+			Effort:       al.reasoning.Effort,
+			BudgetTokens: al.reasoning.BudgetTokens,
+		}
+	}
+	return options
+}
+
+// logReasoning writes the model's thinking to the log when reasoning.log
+// is enabled. Thinking is never sent to channels.
+func (al *AgentLoop) logReasoning(reasoning string, iteration int) {
+	if reasoning == "" || !al.reasoning.Log {
+		return
+	}
+	logger.InfoCF("agent", "Model reasoning", map[string]interface{}{
+		"agent":     al.agentName,
+		"iteration": iteration,
+		"reasoning": reasoning,
+	})
+}
+
+// window is the model's context window: context_window from the config,
+// or else the detected size. Unknown models fall back to max_tokens, the
+// window of earlier releases.
+func (al *AgentLoop) window() int {
+	if al.contextWindow > 0 {
+		return al.contextWindow
+	}
+	if caps, ok := providers.Capabilities(al.model); ok && caps.ContextWindow > 0 {
+		return caps.ContextWindow
+	}
+	return al.maxTokens
+}
+
+// outputLimit is max_tokens, capped at the longest reply the model allows
+func (al *AgentLoop) outputLimit() int {
+	if caps, ok := providers.Capabilities(al.model); ok && caps.MaxOutput > 0 && caps.MaxOutput < al.maxTokens {
+		return caps.MaxOutput
+	}
+	return al.maxTokens
+}
+
+// summarizeThreshold is the history size that triggers summarization: 75%
+// of the window left after the reply, or the history's context budget if
+// that is smaller
+func (al *AgentLoop) summarizeThreshold() int {
+	available := al.window() - al.outputLimit()
+	if available < al.window()/4 {
+		available = al.window()
+	}
+	threshold := available * 75 / 100
+	if budget := al.contextBuilder.HistoryBudget(); budget > 0 && budget < threshold {
+		threshold = budget
+	}
+	return threshold
+}
+
+// summaryPrompt asks for the structured summary as JSON
+const summaryPrompt = `Update the summary of this conversation with the segment below. Reply with only a JSON object:
+{"overview": "2-4 sentences on what the conversation is about so far",
+ "entities": [{"name": "...", "type": "person|place|project|device|...", "notes": "facts worth remembering about it"}],
+ "decisions": ["what was decided or agreed"],
+ "todos": ["tasks that are still open"],
+ "topics": ["short topic labels"]}
+Keep everything from the existing summary that still holds, merge new facts into existing entities, and drop tasks the segment shows were done or abandoned. Keep names, numbers, dates and paths exact.
+`
+
+// summarizeStructured folds a conversation segment into existing and
+// returns the updated summary. When the reply holds no summary object, the
+// summary is nil and the reply is returned as is.
+func (al *AgentLoop) summarizeStructured(ctx context.Context, batch []providers.Message, existing *session.StructuredSummary) (*session.StructuredSummary, string, error) {
+	prompt := summaryPrompt
+	if !existing.IsEmpty() {
+		prompt += "\nEXISTING SUMMARY:\n" + mustJSON(existing) + "\n"
 	}
 	prompt += "\nCONVERSATION:\n"
 	for _, m := range batch {
@@ -739,43 +1224,32 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 	}
 
 	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
-		"max_tokens":  1024,
+		"max_tokens":  2048,
 		"temperature": 0.3,
 	})
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
-	return response.Content, nil
+	recordUsage(response.Usage)
+	return parseStructuredSummary(response.Content), response.Content, nil
 }
 
-func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
-	total := 0
-	for _, m := range messages {
-		total += al.getContentLength(m.Content) / 4 // Simple heuristic: 4 chars per token
-	}
-	return total
-}
-
-// getContentLength returns the character length of message content
-// Handles both string and multimodal content blocks
-func (al *AgentLoop) getContentLength(content interface{}) int {
-	switch v := content.(type) {
-	case string:
-		return len(v)
-	case []providers.ContentBlock:
-		total := 0
-		for _, block := range v {
-			if block.Type == "text" {
-				total += len(block.Text)
-			} else if block.Type == "image_url" {
-				// Estimate tokens for images (varies by model, rough estimate)
-				total += 1000 // Rough estimate for image tokens
-			}
-		}
-		return total
-	default:
-		return 0
+// parseStructuredSummary reads the summary object from a model reply
+func parseStructuredSummary(reply string) *session.StructuredSummary {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var summary session.StructuredSummary
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &summary); err != nil || summary.IsEmpty() {
+		return nil
 	}
+	summary.Normalize()
+	return &summary
+}
+
+func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
+	return tokens.CountMessages(al.model, messages)
 }
 
 func truncateString(s string, maxLen int) string {
@@ -800,3 +1274,20 @@ func mustJSON(v interface{}) string {
 	}
 	return string(b)
 }
+
+// sessionWorkDir returns the private working directory of a session, creating
+// it on first use, or "" when sessions share the workspace root.
+func (al *AgentLoop) sessionWorkDir(sessionKey string) string {
+	if !al.isolate || sessionKey == "" {
+		return ""
+	}
+	dir := tools.SessionWorkDir(al.workspace, sessionKey)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.WarnCF("agent", "Failed to create session working directory", map[string]interface{}{
+			"dir":   dir,
+			"error": err.Error(),
+		})
+		return ""
+	}
+	return dir
+}
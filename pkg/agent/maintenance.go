@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+// defaultMaintenanceMessage is the reply to messages in maintenance mode
+const defaultMaintenanceMessage = "🛠 Maintenance in progress. I'll be back shortly."
+
+// MaintenanceState is the gateway's read-only mode. While enabled, messages
+// are answered with Message instead of reaching the agents, and only
+// read-only tools run.
+type MaintenanceState struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+}
+
+// maintenanceFile keeps the state across restarts, which config changes
+// during maintenance usually need
+func (am *AgentManager) maintenanceFile() string {
+	return filepath.Join(am.config.WorkspacePath(), "state", "maintenance.json")
+}
+
+// RestoreMaintenance restores the state saved by SetMaintenance. The
+// gateway calls it at startup; CLI agents are not affected.
+func (am *AgentManager) RestoreMaintenance() {
+	data, err := os.ReadFile(am.maintenanceFile())
+	if err != nil {
+		return
+	}
+	var state MaintenanceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+	am.maintenance = state
+	tools.SetMaintenance(state.Enabled)
+	if state.Enabled {
+		logger.WarnCF("agent", "Gateway is in maintenance mode", map[string]interface{}{
+			"since": state.Since.Format(time.RFC3339),
+		})
+	}
+}
+
+// SetMaintenance turns maintenance mode on or off. message replaces the
+// default reply to incoming messages.
+func (am *AgentManager) SetMaintenance(enabled bool, message string) (MaintenanceState, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	state := MaintenanceState{Enabled: enabled}
+	if enabled {
+		state.Message = message
+		state.Since = time.Now()
+		if am.maintenance.Enabled {
+			state.Since = am.maintenance.Since
+		}
+	}
+	am.maintenance = state
+	tools.SetMaintenance(enabled)
+	logger.InfoCF("agent", "Maintenance mode changed", map[string]interface{}{
+		"enabled": enabled,
+	})
+
+	if err := os.MkdirAll(filepath.Dir(am.maintenanceFile()), 0755); err != nil {
+		return state, err
+	}
+	data, _ := json.MarshalIndent(state, "", "  ")
+	return state, os.WriteFile(am.maintenanceFile(), data, 0644)
+}
+
+// Maintenance returns the maintenance mode state
+func (am *AgentManager) Maintenance() MaintenanceState {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return am.maintenance
+}
+
+// replyInMaintenance answers msg with the maintenance message and reports
+// whether maintenance mode is on
+func (am *AgentManager) replyInMaintenance(msg bus.InboundMessage) bool {
+	state := am.Maintenance()
+	if !state.Enabled {
+		return false
+	}
+	reply := state.Message
+	if reply == "" {
+		reply = defaultMaintenanceMessage
+	}
+	am.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: reply,
+	})
+	am.bus.Ack(msg)
+	return true
+}
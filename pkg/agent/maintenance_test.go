@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+func TestMaintenance(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	msgBus := bus.NewMessageBus()
+	am := &AgentManager{config: cfg, bus: msgBus}
+	defer tools.SetMaintenance(false)
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "hello"}
+	if am.replyInMaintenance(msg) {
+		t.Fatal("replied while maintenance is off")
+	}
+
+	if _, err := am.SetMaintenance(true, "Back at noon"); err != nil {
+		t.Fatal(err)
+	}
+	if !tools.InMaintenance() {
+		t.Error("tools should be in maintenance")
+	}
+	// Unknown slash text is a normal message, so it gets the maintenance
+	// reply too instead of reaching the agents
+	if am.handleCommand(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "/deploy now"}) {
+		t.Fatal("unknown command handled as a command")
+	}
+	if !am.replyInMaintenance(msg) {
+		t.Fatal("no reply in maintenance")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || out.ChatID != "42" || out.Content != "Back at noon" {
+		t.Errorf("unexpected reply: %+v", out)
+	}
+
+	// The state survives a restart
+	restarted := &AgentManager{config: cfg, bus: msgBus}
+	tools.SetMaintenance(false)
+	restarted.RestoreMaintenance()
+	if state := restarted.Maintenance(); !state.Enabled || state.Message != "Back at noon" || state.Since.IsZero() {
+		t.Errorf("state not restored: %+v", state)
+	}
+	if !tools.InMaintenance() {
+		t.Error("restore should put tools in maintenance")
+	}
+
+	if _, err := restarted.SetMaintenance(false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.replyInMaintenance(msg) || tools.InMaintenance() {
+		t.Error("maintenance still on")
+	}
+}
@@ -5,15 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/pepebot-space/pepebot/pkg/batch"
 	"github.com/pepebot-space/pepebot/pkg/bus"
 	"github.com/pepebot-space/pepebot/pkg/config"
 	"github.com/pepebot-space/pepebot/pkg/logger"
 	"github.com/pepebot-space/pepebot/pkg/providers"
 	"github.com/pepebot-space/pepebot/pkg/session"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
+	"github.com/pepebot-space/pepebot/pkg/tools"
 )
 
 // AgentManager manages multiple agent instances
@@ -21,12 +26,47 @@ type AgentManager struct {
 	config       *config.Config
 	bus          *bus.MessageBus
 	provider     providers.LLMProvider
+	providers    *providers.ProviderPool
 	registry     *AgentRegistry
 	agents       map[string]*AgentLoop
 	mu           sync.RWMutex
 	defaultAgent string
-	inFlight     sync.Map // map[sessionKey]context.CancelFunc
+	inFlight     sync.Map // map[sessionKey]*turn
 	restartFunc  func()   // called to trigger graceful restart
+	extraTools   []tools.Tool
+	workers      *workerPool // per-session ordered message processing
+	typing       TypingNotifier
+	inboundHook  InboundHook
+	maintenance  MaintenanceState
+	batch        *batch.Queue
+	experiments  *PromptExperiments
+}
+
+// InboundHook sees every inbound message before the agents do and returns
+// true when it has handled the message and agents should not process it
+type InboundHook func(ctx context.Context, msg bus.InboundMessage) bool
+
+// SetInboundHook sets the hook run for inbound messages other than commands
+func (am *AgentManager) SetInboundHook(hook InboundHook) {
+	am.inboundHook = hook
+}
+
+// SetBatchQueue sends memory extraction of every agent through the batch
+// queue and registers the handler that saves the results
+func (am *AgentManager) SetBatchQueue(q *batch.Queue) {
+	q.Handle(memoryBatchKind, handleMemoryBatch)
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.batch = q
+	for _, agentLoop := range am.agents {
+		agentLoop.batch = q
+	}
+}
+
+// SetTypingNotifier enables typing indicators while messages are processed
+func (am *AgentManager) SetTypingNotifier(typing TypingNotifier) {
+	am.typing = typing
 }
 
 // SetRestartFunc sets the function called when a restart is requested via /restart command
@@ -34,6 +74,18 @@ func (am *AgentManager) SetRestartFunc(fn func()) {
 	am.restartFunc = fn
 }
 
+// RegisterTool adds a tool to every agent, including agents created later.
+// Used for tools backed by gateway-only services such as running channels.
+func (am *AgentManager) RegisterTool(tool tools.Tool) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.extraTools = append(am.extraTools, tool)
+	for _, agentLoop := range am.agents {
+		agentLoop.tools.Register(tool)
+	}
+}
+
 // NewAgentManager creates a new agent manager
 func NewAgentManager(cfg *config.Config, bus *bus.MessageBus, provider providers.LLMProvider) (*AgentManager, error) {
 	registry := NewAgentRegistry(cfg.WorkspacePath())
@@ -59,9 +111,12 @@ func NewAgentManager(cfg *config.Config, bus *bus.MessageBus, provider providers
 		config:       cfg,
 		bus:          bus,
 		provider:     provider,
+		providers:    providers.NewProviderPool(cfg, provider),
 		registry:     registry,
 		agents:       make(map[string]*AgentLoop),
 		defaultAgent: "default",
+		workers:      newWorkerPool(cfg.Agents.Defaults.MaxConcurrency),
+		experiments:  NewPromptExperiments(cfg.WorkspacePath()),
 	}, nil
 }
 
@@ -86,10 +141,53 @@ func (am *AgentManager) GetOrCreateAgent(agentName string) (*AgentLoop, error) {
 		return nil, fmt.Errorf("agent '%s' is disabled", agentName)
 	}
 
-	// Determine if agent needs its own provider (different model or provider from defaults)
+	agentLoop := am.newAgent(agentName, agentName, agentDef)
+	am.agents[agentName] = agentLoop
+
+	logger.InfoCF("agent", "Created agent instance", map[string]interface{}{
+		"name":  agentName,
+		"model": agentDef.Model,
+	})
+
+	return agentLoop, nil
+}
+
+// ReplayAgent creates a separate instance of an agent for replaying logged
+// requests and running eval suites, optionally on another model. The instance is not kept, so the
+// agents serving chats are unaffected.
+func (am *AgentManager) ReplayAgent(agentName, model string) (*AgentLoop, error) {
+	if agentName == "" {
+		agentName = am.defaultAgent
+	}
+	agentDef, err := am.registry.Get(agentName)
+	if err != nil {
+		return nil, fmt.Errorf("agent '%s' not found in registry: %w", agentName, err)
+	}
+
+	def := *agentDef
+	if model != "" && model != def.Model {
+		// The provider follows from the new model
+		def.Model, def.Provider, def.APIKey, def.APIBase = model, "", "", ""
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return am.newAgent(agentName, "replay/"+agentName+"/"+def.Model, &def), nil
+}
+
+// newAgent creates an agent loop for a definition, with its provider kept
+// in the pool under poolKey. Callers hold am.mu.
+func (am *AgentManager) newAgent(agentName, poolKey string, agentDef *AgentDefinition) *AgentLoop {
+	// Agents with their own model, provider or credentials get a provider from the pool
 	agentProvider := am.provider
-	if agentDef.Provider != "" || agentDef.Model != am.config.Agents.Defaults.Model {
-		p, err := providers.CreateProviderWithOverrides(am.config, agentDef.Model, agentDef.Provider)
+	spec := providers.ProviderSpec{
+		Model:    agentDef.Model,
+		Provider: agentDef.Provider,
+		APIKey:   agentDef.APIKey,
+		APIBase:  agentDef.APIBase,
+	}
+	if !spec.IsDefault(am.config) {
+		p, err := am.providers.Get(poolKey, spec)
 		if err != nil {
 			logger.WarnCF("agent", "Failed to create per-agent provider, using global", map[string]interface{}{
 				"name":     agentName,
@@ -99,26 +197,34 @@ func (am *AgentManager) GetOrCreateAgent(agentName string) (*AgentLoop, error) {
 			})
 		} else {
 			agentProvider = p
-			logger.InfoCF("agent", "Created per-agent provider", map[string]interface{}{
+			logger.InfoCF("agent", "Using per-agent provider", map[string]interface{}{
 				"name":     agentName,
 				"model":    agentDef.Model,
 				"provider": agentDef.Provider,
+				"api_base": agentDef.APIBase,
 			})
 		}
 	}
 
+	// Learn the model's context window and limits from the provider
+	go providers.RefreshCapabilities(context.Background(), agentProvider)
+	if caps, ok := providers.Capabilities(agentDef.Model); ok && !caps.Tools {
+		logger.WarnCF("agent", "Model does not support tool calls; the agent can only chat", map[string]interface{}{
+			"name":  agentName,
+			"model": agentDef.Model,
+		})
+	}
+
 	// Create new agent loop
 	agentLoop := NewAgentLoopWithDefinition(am.config, am.bus, agentProvider, agentName, agentDef)
 	agentLoop.WorkflowHelper().SetAgentProcessor(am)
 	agentLoop.SetManageAgentCaller(am)
-	am.agents[agentName] = agentLoop
-
-	logger.InfoCF("agent", "Created agent instance", map[string]interface{}{
-		"name":  agentName,
-		"model": agentDef.Model,
-	})
-
-	return agentLoop, nil
+	agentLoop.batch = am.batch
+	agentLoop.experiments = am.experiments
+	for _, tool := range am.extraTools {
+		agentLoop.tools.Register(tool)
+	}
+	return agentLoop
 }
 
 // GetDefaultAgent gets the default agent
@@ -202,6 +308,8 @@ func (am *AgentManager) ProcessDirectStream(ctx context.Context, content string,
 		return err
 	}
 
+	ctx, done := am.startTurn(ctx, sessionKey)
+	defer done()
 	return agentLoop.ProcessDirectStream(ctx, content, media, sessionKey, callback)
 }
 
@@ -216,9 +324,26 @@ func (am *AgentManager) ProcessDirect(ctx context.Context, content string, media
 		return "", err
 	}
 
+	ctx, done := am.startTurn(ctx, sessionKey)
+	defer done()
 	return agentLoop.ProcessDirect(ctx, content, media, sessionKey)
 }
 
+// Passthrough forwards a client conversation to the selected agent's model
+// without running the agent loop
+func (am *AgentManager) Passthrough(ctx context.Context, agentName string, messages []providers.Message, toolDefs []providers.ToolDefinition, options map[string]interface{}) (*providers.LLMResponse, error) {
+	if agentName == "" {
+		agentName = am.defaultAgent
+	}
+
+	agentLoop, err := am.GetOrCreateAgent(agentName)
+	if err != nil {
+		return nil, err
+	}
+
+	return agentLoop.Passthrough(ctx, messages, toolDefs, options)
+}
+
 // GetToolDefinitions returns tool definitions for the selected agent.
 func (am *AgentManager) GetToolDefinitions(agentName string) ([]map[string]interface{}, error) {
 	if agentName == "" {
@@ -294,6 +419,62 @@ func (am *AgentManager) ClearSession(sessionKey, agentName string) {
 	agentLoop.ClearSession(sessionKey)
 }
 
+// ForkSession copies a session's history into a new session key on the
+// specified agent, so the conversation can continue in two directions
+func (am *AgentManager) ForkSession(sessionKey, newKey, agentName string) (*session.Session, error) {
+	if agentName == "" {
+		agentName = am.defaultAgent
+	}
+
+	agentLoop, err := am.GetOrCreateAgent(agentName)
+	if err != nil {
+		return nil, err
+	}
+
+	return agentLoop.Sessions().Fork(sessionKey, newKey)
+}
+
+// SearchSessions searches the stored messages of every running agent's
+// sessions, keeping the most recent copy of sessions that several agents
+// have loaded
+func (am *AgentManager) SearchSessions(query string, limit int) []session.SearchResult {
+	if _, err := am.GetDefaultAgent(); err != nil {
+		return nil
+	}
+
+	am.mu.RLock()
+	groups := make([][]session.SearchResult, 0, len(am.agents))
+	for _, agentLoop := range am.agents {
+		groups = append(groups, agentLoop.Sessions().Search(query, 0))
+	}
+	am.mu.RUnlock()
+
+	return session.MergeSearchResults(limit, groups...)
+}
+
+// RunWorkflow runs a workspace workflow with the default agent's tools,
+// e.g. for a scheduled workflow
+func (am *AgentManager) RunWorkflow(ctx context.Context, name string, vars map[string]string) (string, error) {
+	agentLoop, err := am.GetDefaultAgent()
+	if err != nil {
+		return "", err
+	}
+	return agentLoop.WorkflowHelper().RunWorkflow(ctx, name, vars)
+}
+
+// AgentSessions returns the session manager of an agent, creating the agent
+// if needed. An empty name selects the default agent.
+func (am *AgentManager) AgentSessions(agentName string) (*session.SessionManager, error) {
+	if agentName == "" {
+		agentName = am.defaultAgent
+	}
+	agentLoop, err := am.GetOrCreateAgent(agentName)
+	if err != nil {
+		return nil, err
+	}
+	return agentLoop.Sessions(), nil
+}
+
 // GetSessions returns the session manager from the default agent
 func (am *AgentManager) GetSessions() *session.SessionManager {
 	agentLoop, err := am.GetDefaultAgent()
@@ -303,19 +484,51 @@ func (am *AgentManager) GetSessions() *session.SessionManager {
 	return agentLoop.Sessions()
 }
 
-// StopSession stops in-flight processing for a session key (reuses cmdStop logic)
+// turn is an in-flight agent turn that /stop can cancel
+type turn struct {
+	cancel context.CancelFunc
+}
+
+// startTurn registers a cancellable context for a session. Cancelling it
+// aborts the LLM call and running tools (shell commands, ADB, workflows).
+// done must be called when the turn ends.
+func (am *AgentManager) startTurn(ctx context.Context, sessionKey string) (context.Context, func()) {
+	turnCtx, cancel := context.WithCancel(ctx)
+	t := &turn{cancel: cancel}
+	am.inFlight.Store(sessionKey, t)
+	return turnCtx, func() {
+		// A newer turn for the same session may have replaced this one
+		am.inFlight.CompareAndDelete(sessionKey, t)
+		cancel()
+	}
+}
+
+// ActiveSessions returns the session keys with a turn in progress, sorted
+func (am *AgentManager) ActiveSessions() []string {
+	var keys []string
+	am.inFlight.Range(func(key, _ interface{}) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// PendingMessages returns the number of inbound messages being processed or
+// waiting for their session
+func (am *AgentManager) PendingMessages() int {
+	return am.workers.Pending()
+}
+
+// StopSession stops in-flight processing for a session key
 func (am *AgentManager) StopSession(sessionKey string) string {
-	cancelVal, ok := am.inFlight.Load(sessionKey)
+	val, ok := am.inFlight.Load(sessionKey)
 	if !ok {
 		return "No active processing to stop."
 	}
 
-	if cancel, ok := cancelVal.(context.CancelFunc); ok {
-		cancel()
-		return "Stopping current processing..."
-	}
-
-	return "No active processing to stop."
+	val.(*turn).cancel()
+	return "Stopping current processing..."
 }
 
 // Run starts processing messages from the bus
@@ -330,26 +543,35 @@ func (am *AgentManager) Run(ctx context.Context) error {
 				continue
 			}
 
-			// Check if message is a command
-			if strings.HasPrefix(msg.Content, "/") {
-				am.handleCommand(ctx, msg)
+			// Check if message is a command; other slash text is a
+			// normal message
+			if strings.HasPrefix(msg.Content, "/") && am.handleCommand(msg) {
+				continue
+			}
+
+			if am.replyInMaintenance(msg) {
 				continue
 			}
 
-			// Process normal messages in a goroutine for concurrency
-			go am.processAndRespond(ctx, msg)
+			if am.inboundHook != nil && am.inboundHook(ctx, msg) {
+				am.bus.Ack(msg)
+				continue
+			}
+
+			// Different sessions run concurrently; messages within a
+			// session are processed in order
+			am.workers.Submit(sessionKeyFor(msg.SessionKey, msg.Channel, msg.ChatID), func() {
+				am.processAndRespond(ctx, msg)
+			})
 		}
 	}
 }
 
 // processAndRespond processes a message with cancellation support and publishes the response
 func (am *AgentManager) processAndRespond(ctx context.Context, msg bus.InboundMessage) {
-	chatCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	// Store cancel func so /stop can abort this
-	am.inFlight.Store(msg.SessionKey, cancel)
-	defer am.inFlight.Delete(msg.SessionKey)
+	// Register the turn so /stop can abort it
+	chatCtx, done := am.startTurn(ctx, msg.SessionKey)
+	defer done()
 
 	// Extract agent name from metadata if present
 	agentName := ""
@@ -357,7 +579,24 @@ func (am *AgentManager) processAndRespond(ctx context.Context, msg bus.InboundMe
 		agentName = msg.Metadata["agent"]
 	}
 
-	response, err := am.ProcessMessage(chatCtx, msg, agentName)
+	turnCtx, turnDone := context.WithCancel(chatCtx)
+	if am.typing != nil && am.config.Agents.Defaults.TypingIndicator {
+		go am.keepTyping(turnCtx, msg.Channel, msg.ChatID)
+	}
+	if am.config.Agents.Defaults.ProgressUpdates {
+		turnCtx = withProgress(turnCtx, am.progressNotifier(msg))
+		turnCtx = tools.WithProgress(turnCtx, am.toolStatusNotifier(msg))
+	}
+
+	response, err := am.ProcessMessage(turnCtx, msg, agentName)
+	turnDone()
+
+	// Leave the message unacknowledged when the gateway is shutting down so
+	// the bus journal replays it after restart. /stop still acknowledges it.
+	if ctx.Err() == nil {
+		am.bus.Ack(msg)
+	}
+
 	if err != nil {
 		if chatCtx.Err() != nil {
 			// Context was cancelled (by /stop)
@@ -368,21 +607,34 @@ func (am *AgentManager) processAndRespond(ctx context.Context, msg bus.InboundMe
 	}
 
 	if response != "" {
+		if agentName == "" {
+			agentName = am.defaultAgent
+		}
 		am.bus.PublishOutbound(bus.OutboundMessage{
-			Channel: msg.Channel,
-			ChatID:  msg.ChatID,
-			Content: response,
+			Channel:    msg.Channel,
+			ChatID:     msg.ChatID,
+			Content:    response,
+			SessionKey: sessionKeyFor(msg.SessionKey, msg.Channel, msg.ChatID),
+			Agent:      agentName,
+			InReplyTo:  msg.Content,
 		})
 	}
 }
 
-// handleCommand dispatches slash commands
-func (am *AgentManager) handleCommand(ctx context.Context, msg bus.InboundMessage) {
+// handleCommand dispatches slash commands. It returns false when msg is not
+// a known command.
+func (am *AgentManager) handleCommand(msg bus.InboundMessage) bool {
 	parts := strings.Fields(msg.Content)
 	command := strings.ToLower(parts[0])
 
 	var response string
 
+	switch command {
+	case "/new", "/stop", "/restart", "/help", "/status", "/pin", "/pins", "/unpin", "/outcome":
+		// Acknowledge commands up front so /restart is never replayed
+		am.bus.Ack(msg)
+	}
+
 	switch command {
 	case "/new":
 		response = am.cmdNew(msg)
@@ -394,10 +646,12 @@ func (am *AgentManager) handleCommand(ctx context.Context, msg bus.InboundMessag
 		response = am.cmdHelp()
 	case "/status":
 		response = am.cmdStatus(msg)
+	case "/pin", "/pins", "/unpin":
+		response = am.cmdPin(msg, command, strings.TrimSpace(strings.TrimPrefix(msg.Content, parts[0])))
+	case "/outcome":
+		response = am.cmdOutcome(msg, strings.TrimSpace(strings.TrimPrefix(msg.Content, parts[0])))
 	default:
-		// Not a known command, process as normal message
-		go am.processAndRespond(ctx, msg)
-		return
+		return false
 	}
 
 	if response != "" {
@@ -407,6 +661,7 @@ func (am *AgentManager) handleCommand(ctx context.Context, msg bus.InboundMessag
 			Content: response,
 		})
 	}
+	return true
 }
 
 // cmdNew clears the session for the current chat
@@ -425,19 +680,9 @@ func (am *AgentManager) cmdNew(msg bus.InboundMessage) string {
 	return "Session cleared. Starting fresh conversation."
 }
 
-// cmdStop cancels any in-flight LLM call for this session
+// cmdStop cancels the in-flight turn for this session, including running tools
 func (am *AgentManager) cmdStop(msg bus.InboundMessage) string {
-	cancelVal, ok := am.inFlight.Load(msg.SessionKey)
-	if !ok {
-		return "No active processing to stop."
-	}
-
-	if cancel, ok := cancelVal.(context.CancelFunc); ok {
-		cancel()
-		return "Stopping current processing..."
-	}
-
-	return "No active processing to stop."
+	return am.StopSession(msg.SessionKey)
 }
 
 // cmdRestart triggers a graceful gateway restart
@@ -465,7 +710,75 @@ func (am *AgentManager) cmdHelp() string {
 		"/stop    - Cancel current LLM processing\n" +
 		"/restart - Graceful gateway restart\n" +
 		"/help    - Show this help message\n" +
-		"/status  - Show agent & session info"
+		"/status  - Show agent & session info\n" +
+		"/pin     - Pin a fact to this session (/pin the server IP is 10.0.0.5)\n" +
+		"/pins    - List pinned facts\n" +
+		"/unpin   - Remove a pin by number, or all (/unpin 2, /unpin all)\n" +
+		"/outcome - Tag how this conversation went for prompt experiments (/outcome good)"
+}
+
+// cmdOutcome tags the outcome of the session's prompt variant
+func (am *AgentManager) cmdOutcome(msg bus.InboundMessage, outcome string) string {
+	agentName := am.defaultAgent
+	if msg.Metadata != nil && msg.Metadata["agent"] != "" {
+		agentName = msg.Metadata["agent"]
+	}
+
+	agentLoop, err := am.GetOrCreateAgent(agentName)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return agentLoop.OutcomeCommand(msg.SessionKey, outcome)
+}
+
+// cmdPin handles /pin, /pins and /unpin for the current session
+func (am *AgentManager) cmdPin(msg bus.InboundMessage, command, arg string) string {
+	sessions, err := am.AgentSessions(msg.Metadata["agent"])
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return PinCommand(sessions, msg.SessionKey, command, arg)
+}
+
+// PinCommand runs a /pin, /pins or /unpin command against a session and
+// returns the reply
+func PinCommand(sessions *session.SessionManager, sessionKey, command, arg string) string {
+	switch command {
+	case "/pin":
+		if arg == "" {
+			return "Usage: /pin <fact to remember in this conversation>"
+		}
+		pins, err := sessions.Pin(sessionKey, arg)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("Pinned (#%d). It stays in context for this session.", len(pins))
+	case "/unpin":
+		index := 0
+		if arg != "all" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return "Usage: /unpin <number> or /unpin all"
+			}
+			index = n
+		}
+		pins, err := sessions.Unpin(sessionKey, index)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("Unpinned. %d pins left.", len(pins))
+	default:
+		pins := sessions.GetPins(sessionKey)
+		if len(pins) == 0 {
+			return "No pinned facts. Add one with /pin <text>."
+		}
+		var sb strings.Builder
+		sb.WriteString("Pinned facts:")
+		for i, pin := range pins {
+			fmt.Fprintf(&sb, "\n%d. %s", i+1, pin)
+		}
+		return sb.String()
+	}
 }
 
 // cmdStatus returns info about the current agent and session
@@ -486,6 +799,11 @@ func (am *AgentManager) cmdStatus(msg bus.InboundMessage) string {
 		processingStatus = "processing"
 	}
 
-	return fmt.Sprintf("Agent: %s\nModel: %s\nSession: %s\nStatus: %s",
-		agentLoop.AgentName(), agentLoop.Model(), msg.SessionKey, processingStatus)
+	if queued := am.workers.Waiting(sessionKeyFor(msg.SessionKey, msg.Channel, msg.ChatID)); queued > 0 {
+		processingStatus = fmt.Sprintf("%s (%d queued)", processingStatus, queued)
+	}
+
+	return fmt.Sprintf("Agent: %s\nModel: %s\nSession: %s\nStatus: %s\nContext: %d tokens (%s)",
+		agentLoop.AgentName(), agentLoop.Model(), msg.SessionKey, processingStatus,
+		agentLoop.SessionTokens(msg.SessionKey), tokens.EncodingForModel(agentLoop.Model()))
 }
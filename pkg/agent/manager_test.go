@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStopSession(t *testing.T) {
+	am := &AgentManager{}
+	if got := am.StopSession("web:default"); got != "No active processing to stop." {
+		t.Errorf("idle session: %q", got)
+	}
+
+	ctx, done := am.startTurn(context.Background(), "web:default")
+	if got := am.StopSession("web:default"); got != "Stopping current processing..." {
+		t.Errorf("active session: %q", got)
+	}
+	if ctx.Err() == nil {
+		t.Error("turn context should be cancelled")
+	}
+
+	// A finished turn must not unregister a newer turn for the same session
+	newer, doneNewer := am.startTurn(context.Background(), "web:default")
+	done()
+	am.StopSession("web:default")
+	if newer.Err() == nil {
+		t.Error("newer turn should still be registered and stoppable")
+	}
+	doneNewer()
+	if _, ok := am.inFlight.Load("web:default"); ok {
+		t.Error("turn should be unregistered when done")
+	}
+}
@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/batch"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+const memoryExtractionPrompt = `Extract durable facts worth remembering across conversations from the conversation below: who the user is, their preferences, people, places, accounts, devices and standing decisions. Skip small talk, one-off tasks and anything already in the existing memory.
+
+Reply with ONLY a JSON array of short, self-contained facts in English, like ["User's name is Budi", "User prefers replies in Indonesian"]. Reply with [] when there is nothing new.`
+
+// memoryMu serializes writes to MEMORY.md across agents and sessions
+var memoryMu sync.Mutex
+
+// memoryBatchKind is the batch queue job kind of memory extraction
+const memoryBatchKind = "memory"
+
+// memoryJob is the batch payload of a memory extraction
+type memoryJob struct {
+	Agent string `json:"agent"`
+	Path  string `json:"path"`
+}
+
+// extractMemories asks the model for durable facts in the summarized
+// messages and appends the new ones to memory/MEMORY.md. With a batch queue
+// the request waits for the next batch and the facts are saved when it ends.
+func (al *AgentLoop) extractMemories(messages []providers.Message) {
+	path := filepath.Join(al.workspace, "memory", "MEMORY.md")
+	prompt := buildExtractionPrompt(path, messages)
+
+	if al.batch != nil {
+		temperature := 0.1
+		err := al.batch.Enqueue(memoryBatchKind, "", providers.BatchRequest{
+			Messages:    []providers.Message{{Role: "user", Content: prompt}},
+			MaxTokens:   1024,
+			Temperature: &temperature,
+		}, memoryJob{Agent: al.agentName, Path: path})
+		if err == nil {
+			return
+		}
+		logger.WarnCF("agent", "Failed to queue memory extraction, running it now", map[string]interface{}{
+			"agent": al.agentName,
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
+		"max_tokens":  1024,
+		"temperature": 0.1,
+	})
+	if err != nil {
+		logger.WarnCF("agent", "Memory extraction failed", map[string]interface{}{
+			"agent": al.agentName,
+			"error": err.Error(),
+		})
+		return
+	}
+	saveExtractedMemories(al.agentName, path, response)
+}
+
+// handleMemoryBatch saves the facts of a memory extraction answered in a batch
+func handleMemoryBatch(ctx context.Context, job batch.Job, resp *providers.LLMResponse, err error) {
+	var payload memoryJob
+	if json.Unmarshal(job.Payload, &payload) != nil || payload.Path == "" || err != nil {
+		return
+	}
+	saveExtractedMemories(payload.Agent, payload.Path, resp)
+}
+
+// buildExtractionPrompt asks for the facts in messages that are not in the
+// memory file at path yet
+func buildExtractionPrompt(path string, messages []providers.Message) string {
+	existing, _ := os.ReadFile(path)
+
+	var sb strings.Builder
+	sb.WriteString(memoryExtractionPrompt)
+	if len(existing) > 0 {
+		fmt.Fprintf(&sb, "\n\nEXISTING MEMORY:\n%s", truncateString(string(existing), 4000))
+	}
+	sb.WriteString("\n\nCONVERSATION:\n")
+	for _, m := range messages {
+		fmt.Fprintf(&sb, "%s: %s\n", m.Role, m.Content)
+	}
+	return sb.String()
+}
+
+// saveExtractedMemories appends the facts in a memory extraction reply
+func saveExtractedMemories(agentName, path string, response *providers.LLMResponse) {
+	recordUsage(response.Usage)
+
+	added, err := appendMemories(path, parseFacts(response.Content), time.Now())
+	if err != nil {
+		logger.WarnCF("agent", "Failed to write memories", map[string]interface{}{
+			"agent": agentName,
+			"error": err.Error(),
+		})
+		return
+	}
+	if added > 0 {
+		logger.InfoCF("agent", "Saved memories", map[string]interface{}{
+			"agent": agentName,
+			"facts": added,
+		})
+	}
+}
+
+// AddMemory appends a fact to the memory of an agent, the default agent when
+// agentName is empty, and reports whether it was new
+func (am *AgentManager) AddMemory(agentName, fact string) (bool, error) {
+	if agentName == "" {
+		agentName = am.defaultAgent
+	}
+	agentLoop, err := am.GetOrCreateAgent(agentName)
+	if err != nil {
+		return false, err
+	}
+	added, err := appendMemories(filepath.Join(agentLoop.workspace, "memory", "MEMORY.md"), []string{fact}, time.Now())
+	return added > 0, err
+}
+
+// parseFacts reads the JSON array of facts from a model reply
+func parseFacts(reply string) []string {
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var facts []string
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &facts); err != nil {
+		return nil
+	}
+	return facts
+}
+
+// appendMemories adds facts that are not in the memory file yet as bullets
+// under a heading for the day, and returns how many were added
+func appendMemories(path string, facts []string, now time.Time) (int, error) {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	content := string(data)
+
+	known := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		known[normalizeFact(strings.TrimLeft(strings.TrimSpace(line), "-* "))] = true
+	}
+
+	var bullets strings.Builder
+	added := 0
+	for _, fact := range facts {
+		fact = strings.Join(strings.Fields(fact), " ")
+		key := normalizeFact(fact)
+		if key == "" || known[key] {
+			continue
+		}
+		known[key] = true
+		fmt.Fprintf(&bullets, "- %s\n", fact)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	heading := "## " + now.Format("2006-01-02")
+	if content == "" {
+		content = "# Memory\n"
+	}
+	content = strings.TrimRight(content, "\n") + "\n"
+	if lastHeading(content) != heading {
+		content += "\n" + heading + "\n\n"
+	}
+	content += bullets.String()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	return added, os.WriteFile(path, []byte(content), 0644)
+}
+
+// lastHeading returns the last "## " heading of a markdown document
+func lastHeading(content string) string {
+	lines := strings.Split(content, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "## ") {
+			return strings.TrimSpace(lines[i])
+		}
+	}
+	return ""
+}
+
+// normalizeFact is the form facts are compared in for deduplication
+func normalizeFact(fact string) string {
+	return strings.Trim(strings.ToLower(strings.Join(strings.Fields(fact), " ")), ".!;: ")
+}
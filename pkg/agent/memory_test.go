@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestAppendMemories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory", "MEMORY.md")
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	added, err := appendMemories(path, []string{"User's name is Budi", "User prefers Indonesian"}, day1)
+	if err != nil || added != 2 {
+		t.Fatalf("added %d, err %v", added, err)
+	}
+	// Duplicates are skipped regardless of case and punctuation
+	added, _ = appendMemories(path, []string{"user's name is budi.", "User lives in Bandung"}, day1)
+	if added != 1 {
+		t.Errorf("expected 1 new fact, added %d", added)
+	}
+	added, _ = appendMemories(path, []string{"User has a Pixel 8"}, day1.AddDate(0, 0, 1))
+	if added != 1 {
+		t.Errorf("expected 1 new fact, added %d", added)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "# Memory\n\n## 2026-03-01\n\n- User's name is Budi\n- User prefers Indonesian\n- User lives in Bandung\n\n## 2026-03-02\n\n- User has a Pixel 8\n"
+	if string(data) != want {
+		t.Errorf("MEMORY.md =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestParseFacts(t *testing.T) {
+	if got := parseFacts("Here you go:\n```json\n[\"a\", \"b\"]\n```"); len(got) != 2 {
+		t.Errorf("got %v", got)
+	}
+	if got := parseFacts("nothing new"); got != nil {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestExtractMemories(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir() + "/workspace"
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		{Content: `["User's name is Budi"]`},
+	}}
+	am, err := NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentLoop, err := am.GetDefaultAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agentLoop.extractMemories([]providers.Message{{Role: "user", Content: "Hi, I'm Budi"}})
+
+	data, err := os.ReadFile(filepath.Join(cfg.WorkspacePath(), "memory", "MEMORY.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- User's name is Budi") {
+		t.Errorf("fact not saved:\n%s", data)
+	}
+}
+
+func TestAddMemory(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir() + "/workspace"
+	am, err := NewAgentManager(cfg, bus.NewMessageBus(), &scriptedProvider{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false} {
+		added, err := am.AddMemory("", "User disliked the reply \"Sydney\"")
+		if err != nil || added != want {
+			t.Fatalf("call %d: added %v, err %v", i, added, err)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(cfg.WorkspacePath(), "memory", "MEMORY.md"))
+	if strings.Count(string(data), "Sydney") != 1 {
+		t.Errorf("MEMORY.md =\n%s", data)
+	}
+}
+
+func TestSummarizeSessionStructured(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir() + "/workspace"
+	cfg.Agents.Defaults.MemoryExtraction = false
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		{Content: "```json\n" + `{"overview": "Planning a trip to Lombok.",
+ "entities": [{"name": "Budi", "type": "person", "notes": "travels with the user"}],
+ "decisions": ["Fly on 12 May"],
+ "todos": ["Book the hotel"],
+ "topics": ["travel"]}` + "\n```"},
+	}}
+	am, err := NewAgentManager(cfg, bus.NewMessageBus(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentLoop, err := am.GetDefaultAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		agentLoop.sessions.AddMessage("chat", "user", "Let's go to Lombok with Budi")
+		agentLoop.sessions.AddMessage("chat", "assistant", "Sure, when?")
+	}
+	agentLoop.summarizeSession("chat")
+
+	structured := agentLoop.sessions.GetStructuredSummary("chat")
+	if structured == nil || len(structured.Entities) != 1 || structured.Todos[0] != "Book the hotel" {
+		t.Fatalf("structured summary = %+v", structured)
+	}
+	summary := agentLoop.sessions.GetSummary("chat")
+	if !strings.Contains(summary, "- Budi (person): travels with the user") || !strings.Contains(summary, "- [ ] Book the hotel") {
+		t.Errorf("summary =\n%s", summary)
+	}
+	if n := len(agentLoop.sessions.GetHistory("chat")); n != 4 {
+		t.Errorf("history has %d messages, want 4", n)
+	}
+
+	// A reply without JSON is kept as a flat summary
+	provider.responses = []*providers.LLMResponse{{Content: "They planned a trip."}}
+	provider.calls = 0
+	for i := 0; i < 2; i++ {
+		agentLoop.sessions.AddMessage("chat", "user", "Maybe Bali instead")
+	}
+	agentLoop.summarizeSession("chat")
+	if got := agentLoop.sessions.GetSummary("chat"); got != "They planned a trip." {
+		t.Errorf("flat summary = %q", got)
+	}
+	if agentLoop.sessions.GetStructuredSummary("chat") != nil {
+		t.Error("structured summary kept after a flat one")
+	}
+}
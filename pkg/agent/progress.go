@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+// TypingNotifier shows a typing indicator in a chat. Implemented by channels.Manager.
+type TypingNotifier interface {
+	SendTyping(ctx context.Context, channel, chatID string) error
+}
+
+const (
+	// typingInterval refreshes the indicator before Telegram clears it (~5s)
+	typingInterval = 4 * time.Second
+	// progressMinInterval rate-limits progress notes within a turn
+	progressMinInterval = 3 * time.Second
+	// toolStatusInterval rate-limits status notes of long running tools
+	toolStatusInterval = 15 * time.Second
+)
+
+type progressContextKey struct{}
+
+// progressFunc is called before each tool execution in a turn
+type progressFunc func(toolName string)
+
+func withProgress(ctx context.Context, fn progressFunc) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, toolName string) {
+	if fn, ok := ctx.Value(progressContextKey{}).(progressFunc); ok {
+		fn(toolName)
+	}
+}
+
+// ToolActivity reports a tool call of a turn: once when it starts and once,
+// with Done set, when it finishes.
+type ToolActivity struct {
+	ID       string
+	Name     string
+	Args     string // JSON arguments, truncated
+	Done     bool
+	Failed   bool
+	Duration time.Duration
+}
+
+type toolObserverContextKey struct{}
+
+// WithToolObserver makes turns run with the returned context report their
+// tool calls to fn. Parallel tool calls report from their own goroutines.
+func WithToolObserver(ctx context.Context, fn func(ToolActivity)) context.Context {
+	return context.WithValue(ctx, toolObserverContextKey{}, fn)
+}
+
+func observeTool(ctx context.Context, activity ToolActivity) {
+	if fn, ok := ctx.Value(toolObserverContextKey{}).(func(ToolActivity)); ok {
+		fn(activity)
+	}
+}
+
+// keepTyping refreshes the typing indicator until ctx is done
+func (am *AgentManager) keepTyping(ctx context.Context, channel, chatID string) {
+	ticker := time.NewTicker(typingInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := am.typing.SendTyping(ctx, channel, chatID); err != nil {
+			logger.DebugCF("agent", "Typing indicator unavailable", map[string]interface{}{
+				"channel": channel,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// progressNotifier returns a progressFunc that posts "running <tool>…" notes
+// to the chat, skipping repeats and notes sent in quick succession
+func (am *AgentManager) progressNotifier(msg bus.InboundMessage) progressFunc {
+	var (
+		mu       sync.Mutex
+		lastTool string
+		lastSent time.Time
+	)
+
+	return func(toolName string) {
+		mu.Lock()
+		if toolName == lastTool || time.Since(lastSent) < progressMinInterval {
+			mu.Unlock()
+			return
+		}
+		lastTool = toolName
+		lastSent = time.Now()
+		mu.Unlock()
+
+		am.bus.PublishOutbound(bus.OutboundMessage{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  fmt.Sprintf("⏳ running %s…", toolName),
+			Progress: true,
+		})
+	}
+}
+
+// toolStatusNotifier returns a tools.ProgressFunc that posts the status of
+// long running tools (e.g. download progress) to the chat
+func (am *AgentManager) toolStatusNotifier(msg bus.InboundMessage) tools.ProgressFunc {
+	var (
+		mu       sync.Mutex
+		lastSent time.Time
+	)
+
+	return func(tool, status string) {
+		mu.Lock()
+		if time.Since(lastSent) < toolStatusInterval {
+			mu.Unlock()
+			return
+		}
+		lastSent = time.Now()
+		mu.Unlock()
+
+		am.bus.PublishOutbound(bus.OutboundMessage{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  fmt.Sprintf("⏳ %s", status),
+			Progress: true,
+		})
+	}
+}
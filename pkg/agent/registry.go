@@ -20,12 +20,25 @@ type AgentDefinition struct {
 	Temperature float64 `json:"temperature,omitempty"`
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	PromptFile  string  `json:"prompt_file,omitempty"`
+	// APIKey and APIBase replace the credentials of the provider for this
+	// agent. APIKey may be "${ENV_VAR}" to read the key from the environment.
+	APIKey  string `json:"api_key,omitempty"`
+	APIBase string `json:"api_base,omitempty"`
+	// Skills limits the agent to the named skills; empty means all skills
+	Skills []string `json:"skills,omitempty"`
+	// Reasoning overrides agents.defaults.reasoning for this agent
+	Reasoning *config.ReasoningConfig `json:"reasoning,omitempty"`
+	// PromptVariants weighs the prompt variants in the agent's variants/
+	// directory, with "base" for the unchanged prompt. Variants left out
+	// get no new sessions. Empty splits sessions evenly.
+	PromptVariants map[string]int `json:"prompt_variants,omitempty"`
 }
 
 // AgentRegistry manages multiple agent configurations
 type AgentRegistry struct {
 	Version string                      `json:"version"`
 	Agents  map[string]*AgentDefinition `json:"agents"`
+	Teams   map[string]*TeamDefinition  `json:"teams,omitempty"`
 	mu      sync.RWMutex
 	path    string
 }
@@ -56,9 +69,23 @@ func (ar *AgentRegistry) Load() error {
 		return fmt.Errorf("failed to read registry: %w", err)
 	}
 
-	if err := json.Unmarshal(data, ar); err != nil {
+	// Decode into a fresh copy so a reload drops removed agents and teams
+	var loaded struct {
+		Version string                      `json:"version"`
+		Agents  map[string]*AgentDefinition `json:"agents"`
+		Teams   map[string]*TeamDefinition  `json:"teams"`
+	}
+	if err := json.Unmarshal(data, &loaded); err != nil {
 		return fmt.Errorf("failed to parse registry: %w", err)
 	}
+	if loaded.Version != "" {
+		ar.Version = loaded.Version
+	}
+	if loaded.Agents == nil {
+		loaded.Agents = make(map[string]*AgentDefinition)
+	}
+	ar.Agents = loaded.Agents
+	ar.Teams = loaded.Teams
 
 	logger.InfoCF("agent", "Loaded agent registry", map[string]interface{}{
 		"agents": len(ar.Agents),
@@ -285,3 +312,29 @@ func (ar *AgentRegistry) GetOrDefault(name string) (*AgentDefinition, string, er
 
 	return nil, "", fmt.Errorf("no agents available")
 }
+
+// GetTeam retrieves a team definition by name
+func (ar *AgentRegistry) GetTeam(name string) (*TeamDefinition, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	team, exists := ar.Teams[name]
+	if !exists {
+		return nil, fmt.Errorf("team '%s' not found", name)
+	}
+
+	return team, nil
+}
+
+// ListTeams returns all registered teams
+func (ar *AgentRegistry) ListTeams() map[string]*TeamDefinition {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	result := make(map[string]*TeamDefinition)
+	for name, team := range ar.Teams {
+		result[name] = team
+	}
+
+	return result
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// TeamDefinition groups a coordinator agent with worker agents. The
+// coordinator splits a task into subtasks for the members and combines
+// their results.
+type TeamDefinition struct {
+	Description string       `json:"description,omitempty"`
+	Coordinator string       `json:"coordinator"`
+	Members     []TeamMember `json:"members"`
+}
+
+// TeamMember is a worker agent and the role it plays in a team
+type TeamMember struct {
+	Agent string `json:"agent"`
+	Role  string `json:"role"`
+}
+
+// teamAssignment is one subtask the coordinator gives to a member
+type teamAssignment struct {
+	Agent string `json:"agent"`
+	Task  string `json:"task"`
+}
+
+// RunTeam runs a task with a team: the coordinator plans subtasks, the
+// members work on them in parallel, and the coordinator writes the answer
+// from their results. The registry is reloaded first so teams created with
+// manage_agent can be used right away.
+func (am *AgentManager) RunTeam(ctx context.Context, teamName, task, sessionKey string) (string, error) {
+	if err := am.registry.Load(); err != nil {
+		return "", err
+	}
+	team, err := am.registry.GetTeam(teamName)
+	if err != nil {
+		return "", err
+	}
+	if sessionKey == "" {
+		sessionKey = "default"
+	}
+	prefix := fmt.Sprintf("%s:team:%s", sessionKey, teamName)
+	coordinatorSession := prefix + ":coordinator"
+
+	logger.InfoCF("agent", "Running team", map[string]interface{}{
+		"team":        teamName,
+		"coordinator": team.Coordinator,
+		"members":     len(team.Members),
+	})
+
+	plan, err := am.ProcessDirect(ctx, teamPlanPrompt(teamName, team, task), nil, coordinatorSession, team.Coordinator)
+	if err != nil {
+		return "", fmt.Errorf("coordinator '%s' failed to plan: %w", team.Coordinator, err)
+	}
+	assignments := parseAssignments(plan, team)
+	if len(assignments) == 0 {
+		logger.WarnCF("agent", "No usable team plan, giving the task to every member", map[string]interface{}{
+			"team": teamName,
+		})
+		for _, member := range team.Members {
+			assignments = append(assignments, teamAssignment{Agent: member.Agent, Task: task})
+		}
+	}
+
+	results := make([]string, len(assignments))
+	var wg sync.WaitGroup
+	for i, a := range assignments {
+		wg.Add(1)
+		go func(i int, a teamAssignment) {
+			defer wg.Done()
+			prompt := fmt.Sprintf("You are the %s in the %s team.\n\n%s", team.role(a.Agent), teamName, a.Task)
+			response, err := am.ProcessDirect(ctx, prompt, nil, fmt.Sprintf("%s:%d:%s", prefix, i+1, a.Agent), a.Agent)
+			if err != nil {
+				response = fmt.Sprintf("Error: %v", err)
+			}
+			results[i] = response
+		}(i, a)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	answer, err := am.ProcessDirect(ctx, teamSummaryPrompt(task, assignments, results), nil, coordinatorSession, team.Coordinator)
+	if err != nil {
+		return "", fmt.Errorf("coordinator '%s' failed to combine results: %w", team.Coordinator, err)
+	}
+	return answer, nil
+}
+
+func (t *TeamDefinition) role(agent string) string {
+	for _, member := range t.Members {
+		if member.Agent == agent && member.Role != "" {
+			return member.Role
+		}
+	}
+	return agent
+}
+
+func teamPlanPrompt(teamName string, team *TeamDefinition, task string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "You coordinate the %s team. Members:\n", teamName)
+	for _, member := range team.Members {
+		fmt.Fprintf(&sb, "- %s: %s\n", member.Agent, member.Role)
+	}
+	fmt.Fprintf(&sb, "\nTask: %s\n\n", task)
+	sb.WriteString("Split the task into self-contained subtasks for the members who are needed; they work in parallel and cannot see each other's work. ")
+	sb.WriteString(`Reply with ONLY a JSON array like [{"agent": "member name", "task": "full instructions"}].`)
+	return sb.String()
+}
+
+func teamSummaryPrompt(task string, assignments []teamAssignment, results []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Your team finished its subtasks for: %s\n\n", task)
+	for i, a := range assignments {
+		fmt.Fprintf(&sb, "## %s\nSubtask: %s\n\n%s\n\n", a.Agent, a.Task, results[i])
+	}
+	sb.WriteString("Combine these results into the final answer for the user. Mention any subtask that failed.")
+	return sb.String()
+}
+
+// parseAssignments reads the coordinator's plan, dropping subtasks for
+// agents that are not members of the team
+func parseAssignments(plan string, team *TeamDefinition) []teamAssignment {
+	start, end := strings.Index(plan, "["), strings.LastIndex(plan, "]")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var parsed []teamAssignment
+	if err := json.Unmarshal([]byte(plan[start:end+1]), &parsed); err != nil {
+		return nil
+	}
+
+	var assignments []teamAssignment
+	for _, a := range parsed {
+		if strings.TrimSpace(a.Task) == "" {
+			continue
+		}
+		if !team.hasMember(a.Agent) {
+			logger.WarnCF("agent", "Coordinator assigned a subtask to a non-member", map[string]interface{}{
+				"agent": a.Agent,
+			})
+			continue
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments
+}
+
+func (t *TeamDefinition) hasMember(agent string) bool {
+	for _, member := range t.Members {
+		if member.Agent == agent {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package agent
+
+import "testing"
+
+func TestParseAssignments(t *testing.T) {
+	team := &TeamDefinition{
+		Coordinator: "lead",
+		Members:     []TeamMember{{Agent: "searcher", Role: "web researcher"}, {Agent: "writer", Role: "editor"}},
+	}
+
+	plan := "Here is the plan:\n```json\n[{\"agent\": \"searcher\", \"task\": \"Find prices\"}, {\"agent\": \"intruder\", \"task\": \"x\"}, {\"agent\": \"writer\", \"task\": \"\"}]\n```"
+	got := parseAssignments(plan, team)
+	if len(got) != 1 || got[0].Agent != "searcher" || got[0].Task != "Find prices" {
+		t.Errorf("unexpected assignments: %+v", got)
+	}
+
+	if got := parseAssignments("I will do it myself.", team); got != nil {
+		t.Errorf("expected no assignments, got %+v", got)
+	}
+	if role := team.role("writer"); role != "editor" {
+		t.Errorf("role = %q", role)
+	}
+}
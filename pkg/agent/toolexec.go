@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// executeToolCalls runs the tool calls of one iteration and returns the tool
+// messages in call order. Independent calls run concurrently, at most
+// agents.defaults.max_parallel_tools at a time; a batch that includes a
+// sequential tool (device actions, file writes, commands or messages) runs
+// one call at a time.
+func (al *AgentLoop) executeToolCalls(ctx context.Context, calls []providers.ToolCall) []providers.Message {
+	results := make([]providers.Message, len(calls))
+	addTurnTools(ctx, calls)
+
+	limit := al.maxParallel
+	for _, tc := range calls {
+		if al.tools.IsSequential(tc.Name) {
+			limit = 1
+			break
+		}
+	}
+
+	if limit <= 1 || len(calls) == 1 {
+		for i, tc := range calls {
+			results[i] = al.executeToolCall(ctx, tc)
+		}
+		return results
+	}
+
+	logger.DebugCF("agent", "Executing tools in parallel", map[string]interface{}{
+		"tools": toolCallNames(calls),
+		"limit": limit,
+	})
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		wg.Add(1)
+		go func(i int, tc providers.ToolCall) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = al.executeToolCall(ctx, tc)
+		}(i, tc)
+	}
+	wg.Wait()
+	return results
+}
+
+func (al *AgentLoop) executeToolCall(ctx context.Context, tc providers.ToolCall) providers.Message {
+	// Skip calls still queued when the turn is stopped
+	if err := ctx.Err(); err != nil {
+		return providers.Message{Role: "tool", Content: fmt.Sprintf("Error: not run, %v", err), ToolCallID: tc.ID}
+	}
+
+	logger.DebugCF("agent", "Executing tool", map[string]interface{}{
+		"tool_name": tc.Name,
+		"tool_id":   truncateString(tc.ID, 80),
+		"arguments": truncateString(mustJSON(tc.Arguments), 300),
+	})
+
+	reportProgress(ctx, tc.Name)
+	activity := ToolActivity{ID: tc.ID, Name: tc.Name, Args: truncateString(mustJSON(tc.Arguments), 200)}
+	observeTool(ctx, activity)
+	start := time.Now()
+
+	result, err := al.runTool(ctx, tc)
+	activity.Done = true
+	activity.Failed = err != nil
+	activity.Duration = time.Since(start)
+	observeTool(ctx, activity)
+	if err != nil {
+		logger.ErrorCF("agent", "Tool execution failed", map[string]interface{}{
+			"tool_name": tc.Name,
+			"error":     err.Error(),
+		})
+		result = fmt.Sprintf("Error: %v", err)
+	} else {
+		logger.DebugCF("agent", "Tool execution completed", map[string]interface{}{
+			"tool_name":      tc.Name,
+			"result_preview": truncateString(result, 300),
+		})
+	}
+
+	return providers.Message{
+		Role:       "tool",
+		Content:    result,
+		ToolCallID: tc.ID,
+	}
+}
+
+// runTool executes one call, giving up after agents.defaults.limits.max_tool_seconds.
+// A tool that ignores cancellation is left to finish in the background.
+func (al *AgentLoop) runTool(ctx context.Context, tc providers.ToolCall) (string, error) {
+	if al.limits.MaxToolSeconds <= 0 {
+		return al.tools.Execute(ctx, tc.Name, tc.Arguments)
+	}
+
+	timeout := time.Duration(al.limits.MaxToolSeconds) * time.Second
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := al.tools.Execute(toolCtx, tc.Name, tc.Arguments)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-toolCtx.Done():
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("tool timed out after %ds", al.limits.MaxToolSeconds)
+	}
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+type slowTool struct {
+	name       string
+	sequential bool
+	running    *int32
+	peak       *int32
+}
+
+func (t *slowTool) Name() string                       { return t.name }
+func (t *slowTool) Description() string                { return "" }
+func (t *slowTool) Parameters() map[string]interface{} { return map[string]interface{}{} }
+func (t *slowTool) Sequential() bool                   { return t.sequential }
+
+func (t *slowTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	n := atomic.AddInt32(t.running, 1)
+	for {
+		peak := atomic.LoadInt32(t.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(t.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	atomic.AddInt32(t.running, -1)
+	return args["id"].(string), nil
+}
+
+func newToolExecLoop(maxParallel int) (*AgentLoop, *int32) {
+	var running, peak int32
+	registry := tools.NewToolRegistry()
+	registry.Register(&slowTool{name: "fetch", running: &running, peak: &peak})
+	registry.Register(&slowTool{name: "tap", sequential: true, running: &running, peak: &peak})
+	return &AgentLoop{tools: registry, maxParallel: maxParallel}, &peak
+}
+
+func toolCalls(name string, ids ...string) []providers.ToolCall {
+	calls := make([]providers.ToolCall, 0, len(ids))
+	for _, id := range ids {
+		calls = append(calls, providers.ToolCall{ID: id, Name: name, Arguments: map[string]interface{}{"id": id}})
+	}
+	return calls
+}
+
+func TestExecuteToolCallsParallel(t *testing.T) {
+	al, peak := newToolExecLoop(2)
+	results := al.executeToolCalls(context.Background(), toolCalls("fetch", "a", "b", "c", "d"))
+
+	for i, id := range []string{"a", "b", "c", "d"} {
+		if results[i].ToolCallID != id || results[i].Content != id || results[i].Role != "tool" {
+			t.Errorf("result %d = %+v, want call %s in order", i, results[i], id)
+		}
+	}
+	if *peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", *peak)
+	}
+}
+
+func TestExecuteToolCallsSequential(t *testing.T) {
+	al, peak := newToolExecLoop(4)
+	calls := append(toolCalls("fetch", "a"), toolCalls("tap", "b", "c")...)
+	results := al.executeToolCalls(context.Background(), calls)
+
+	if len(results) != 3 || results[2].Content != "c" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if *peak != 1 {
+		t.Errorf("batches with a sequential tool must not overlap, peak = %d", *peak)
+	}
+}
+
+func TestExecuteToolCallsUnknownTool(t *testing.T) {
+	al, _ := newToolExecLoop(4)
+	results := al.executeToolCalls(context.Background(), []providers.ToolCall{{ID: "x", Name: "missing"}})
+	if results[0].Content != "Error: tool 'missing' not found" {
+		t.Errorf("unexpected result: %q", results[0].Content)
+	}
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// UsageStats is the LLM token usage of all agents for one day
+type UsageStats struct {
+	Date             string `json:"date"` // YYYY-MM-DD, local time
+	Requests         int    `json:"requests"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+}
+
+// usageToday counts tokens reported by providers since midnight. It lives in
+// memory only, so it starts from zero when the gateway restarts.
+var usageToday struct {
+	mu    sync.Mutex
+	stats UsageStats
+}
+
+// recordUsage adds the usage of one LLM response to today's totals.
+// Providers that don't report usage still count as a request.
+func recordUsage(usage *providers.UsageInfo) {
+	usageToday.mu.Lock()
+	defer usageToday.mu.Unlock()
+
+	today := time.Now().Format("2006-01-02")
+	if usageToday.stats.Date != today {
+		usageToday.stats = UsageStats{Date: today}
+	}
+	usageToday.stats.Requests++
+	if usage == nil {
+		return
+	}
+	usageToday.stats.PromptTokens += usage.PromptTokens
+	usageToday.stats.CompletionTokens += usage.CompletionTokens
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	usageToday.stats.TotalTokens += total
+}
+
+// UsageToday returns the token usage recorded since midnight
+func UsageToday() UsageStats {
+	usageToday.mu.Lock()
+	defer usageToday.mu.Unlock()
+
+	today := time.Now().Format("2006-01-02")
+	if usageToday.stats.Date != today {
+		return UsageStats{Date: today}
+	}
+	return usageToday.stats
+}
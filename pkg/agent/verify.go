@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// verifiedMarker is the whole reply of a verification pass that found nothing to add
+const verifiedMarker = "VERIFIED"
+
+const verifyPrompt = `Before this answer goes to the user, check it against the original request and the tool results above.
+- Did every step the request needed actually succeed? Look for errors, empty results, wrong devices, apps or screens.
+- Does the answer claim anything the tool results do not show?
+If something went wrong and can be fixed, fix it with tools now. Then reply with ONLY the corrections or caveats to add to the answer, written for the user.
+If the answer is correct and complete, reply with exactly ` + verifiedMarker + `.`
+
+// verifyMessages appends a draft answer and the verification request
+func verifyMessages(messages []providers.Message, draft *providers.LLMResponse) []providers.Message {
+	return append(messages,
+		providers.Message{Role: "assistant", Content: draft.Content, Thinking: draft.Thinking},
+		providers.Message{Role: "user", Content: verifyPrompt},
+	)
+}
+
+// verificationNote returns what the verification pass adds to the draft,
+// or "" when it confirmed the answer
+func verificationNote(review string) string {
+	review = strings.TrimSpace(review)
+	if strings.HasPrefix(strings.ToUpper(strings.Trim(review, "*_`. ")), verifiedMarker) {
+		return ""
+	}
+	return review
+}
+
+// mergeVerification appends the verification note to the draft answer
+func mergeVerification(draft, review string) string {
+	note := verificationNote(review)
+	if note == "" {
+		return draft
+	}
+	if strings.TrimSpace(draft) == "" {
+		return note
+	}
+	return strings.TrimRight(draft, "\n") + "\n\n" + note
+}
@@ -0,0 +1,19 @@
+package agent
+
+import "testing"
+
+func TestMergeVerification(t *testing.T) {
+	tests := []struct {
+		draft, review, want string
+	}{
+		{"Opened the app.", "VERIFIED", "Opened the app."},
+		{"Opened the app.", "**Verified.**", "Opened the app."},
+		{"Opened the app.\n", "Note: the login screen did not load, so the search was not run.", "Opened the app.\n\nNote: the login screen did not load, so the search was not run."},
+		{"", "The screenshot failed.", "The screenshot failed."},
+	}
+	for _, tt := range tests {
+		if got := mergeVerification(tt.draft, tt.review); got != tt.want {
+			t.Errorf("mergeVerification(%q, %q) = %q, want %q", tt.draft, tt.review, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import "sync"
+
+// defaultMaxConcurrency is used when agents.defaults.max_concurrency is unset
+const defaultMaxConcurrency = 4
+
+// workerPool runs jobs for different session keys concurrently, up to a
+// fixed limit, while jobs sharing a session key run one at a time in the
+// order they were submitted.
+type workerPool struct {
+	slots  chan struct{}
+	queues map[string][]func()
+	mu     sync.Mutex
+}
+
+func newWorkerPool(limit int) *workerPool {
+	if limit <= 0 {
+		limit = defaultMaxConcurrency
+	}
+	return &workerPool{
+		slots:  make(chan struct{}, limit),
+		queues: make(map[string][]func()),
+	}
+}
+
+// Submit queues job behind any pending jobs for the same key
+func (p *workerPool) Submit(key string, job func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if queue, active := p.queues[key]; active {
+		p.queues[key] = append(queue, job)
+		return
+	}
+
+	p.queues[key] = []func(){job}
+	go p.drain(key)
+}
+
+// drain runs the queued jobs for key until the queue is empty
+func (p *workerPool) drain(key string) {
+	for {
+		p.mu.Lock()
+		queue := p.queues[key]
+		if len(queue) == 0 {
+			delete(p.queues, key)
+			p.mu.Unlock()
+			return
+		}
+		job := queue[0]
+		p.mu.Unlock()
+
+		p.slots <- struct{}{}
+		job()
+		<-p.slots
+
+		// Only dequeue after the job ran so Submit keeps appending to this
+		// queue instead of starting a second drain for the same key
+		p.mu.Lock()
+		p.queues[key] = p.queues[key][1:]
+		p.mu.Unlock()
+	}
+}
+
+// Waiting returns the number of jobs for key queued behind the running one
+func (p *workerPool) Waiting(key string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n := len(p.queues[key]); n > 1 {
+		return n - 1
+	}
+	return 0
+}
+
+// Pending returns the number of jobs running or queued across all keys
+func (p *workerPool) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, queue := range p.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// sessionKeyFor returns the ordering key for an inbound message
+func sessionKeyFor(sessionKey, channel, chatID string) string {
+	if sessionKey != "" {
+		return sessionKey
+	}
+	return channel + ":" + chatID
+}
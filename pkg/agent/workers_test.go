@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolOrdersWithinSession(t *testing.T) {
+	pool := newWorkerPool(4)
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		for _, key := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			key, i := key, i
+			pool.Submit(key, func() {
+				defer wg.Done()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	for key, seq := range got {
+		for i, v := range seq {
+			if v != i {
+				t.Fatalf("session %s processed out of order: %v", key, seq)
+			}
+		}
+	}
+}
+
+func TestWorkerPoolLimitsConcurrency(t *testing.T) {
+	pool := newWorkerPool(2)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		pool.Submit(string(rune('a'+i)), func() {
+			defer wg.Done()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Fatalf("expected at most 2 concurrent jobs, got %d", peak)
+	}
+}
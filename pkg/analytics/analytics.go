@@ -0,0 +1,291 @@
+// Package analytics keeps a log of agent turns and summarizations, one JSON
+// object per line, and sums it up over a period. Only metadata is logged,
+// never message content.
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+// FileName is the usage log in the pepebot home directory
+const FileName = "usage.jsonl"
+
+// Kinds of events
+const (
+	KindTurn    = "turn"
+	KindSummary = "summary"
+)
+
+// Event is one agent turn or summarization
+type Event struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Agent      string    `json:"agent,omitempty"`
+	Session    string    `json:"session,omitempty"`
+	Channel    string    `json:"channel,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Tools      []string  `json:"tools,omitempty"` // one entry per call
+	Error      bool      `json:"error,omitempty"`
+}
+
+// Log appends events to a file
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Record appends an event, stamping it with the current time when unset
+func (l *Log) Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the events at path since a time, oldest first. A missing
+// log has no events; unreadable lines are skipped.
+func Read(path string, since time.Time) ([]Event, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Event
+		if json.Unmarshal([]byte(line), &e) != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Count is a name and how often it occurred
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// SessionActivity is what a session did in the period
+type SessionActivity struct {
+	Key      string `json:"key"`
+	Turns    int    `json:"turns"`
+	Messages int    `json:"messages"` // stored in the session file now
+}
+
+// Report sums up the usage in a period
+type Report struct {
+	Since          time.Time         `json:"since"`
+	Until          time.Time         `json:"until"`
+	Turns          int               `json:"turns"`
+	Errors         int               `json:"errors"`
+	Channels       []Count           `json:"channels"`
+	Hours          [24]int           `json:"hours"` // turns by hour of day, local time
+	Tools          []Count           `json:"tools"`
+	AvgLatencyMS   int64             `json:"avg_latency_ms"`
+	P95LatencyMS   int64             `json:"p95_latency_ms"`
+	Summaries      int               `json:"summaries"`
+	ActiveSessions int               `json:"active_sessions"`
+	TopSessions    []SessionActivity `json:"top_sessions"`
+}
+
+// Compute builds the report for the events since a time. sessions are the
+// stored sessions; those updated in the period count as active and give
+// the message counts.
+func Compute(events []Event, sessions []*session.Session, since, until time.Time, top int) *Report {
+	r := &Report{Since: since, Until: until}
+
+	channels := make(map[string]int)
+	tools := make(map[string]int)
+	turns := make(map[string]int)
+	var latencies []int64
+	var total int64
+	for _, e := range events {
+		if e.Time.Before(since) || e.Time.After(until) {
+			continue
+		}
+		switch e.Kind {
+		case KindSummary:
+			r.Summaries++
+		case KindTurn:
+			r.Turns++
+			if e.Error {
+				r.Errors++
+			}
+			channel := e.Channel
+			if channel == "" {
+				channel = "unknown"
+			}
+			channels[channel]++
+			r.Hours[e.Time.Local().Hour()]++
+			for _, name := range e.Tools {
+				tools[name]++
+			}
+			if e.Session != "" {
+				turns[e.Session]++
+			}
+			latencies = append(latencies, e.DurationMS)
+			total += e.DurationMS
+		}
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		r.AvgLatencyMS = total / int64(len(latencies))
+		r.P95LatencyMS = latencies[(len(latencies)*95-1)/100]
+	}
+	r.Channels = sortedCounts(channels)
+	r.Tools = sortedCounts(tools)
+
+	messages := make(map[string]int)
+	for _, s := range sessions {
+		if !s.Updated.Before(since) && !s.Updated.After(until) {
+			r.ActiveSessions++
+			messages[s.Key] = len(s.Messages)
+			if _, ok := turns[s.Key]; !ok {
+				turns[s.Key] = 0
+			}
+		}
+	}
+	for key, n := range turns {
+		r.TopSessions = append(r.TopSessions, SessionActivity{Key: key, Turns: n, Messages: messages[key]})
+	}
+	sort.Slice(r.TopSessions, func(i, j int) bool {
+		a, b := r.TopSessions[i], r.TopSessions[j]
+		if a.Turns != b.Turns {
+			return a.Turns > b.Turns
+		}
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		return a.Key < b.Key
+	})
+	if top > 0 && len(r.TopSessions) > top {
+		r.TopSessions = r.TopSessions[:top]
+	}
+	if len(r.Tools) > top && top > 0 {
+		r.Tools = r.Tools[:top]
+	}
+	return r
+}
+
+func sortedCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+// BusiestHours returns the hours of day with the most turns, busiest first
+func (r *Report) BusiestHours(n int) []int {
+	hours := make([]int, 0, 24)
+	for h, turns := range r.Hours {
+		if turns > 0 {
+			hours = append(hours, h)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool { return r.Hours[hours[i]] > r.Hours[hours[j]] })
+	if len(hours) > n {
+		hours = hours[:n]
+	}
+	return hours
+}
+
+// Format writes the report as text for the terminal
+func (r *Report) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage from %s to %s\n\n", r.Since.Format("2006-01-02 15:04"), r.Until.Format("2006-01-02 15:04"))
+	if r.Turns == 0 && r.Summaries == 0 && r.ActiveSessions == 0 {
+		b.WriteString("No activity in this period.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Turns: %d", r.Turns)
+	if r.Errors > 0 {
+		fmt.Fprintf(&b, " (%d failed)", r.Errors)
+	}
+	fmt.Fprintf(&b, "\nAverage latency: %s (p95 %s)\n", formatMS(r.AvgLatencyMS), formatMS(r.P95LatencyMS))
+	fmt.Fprintf(&b, "Summarizations: %d\n", r.Summaries)
+	fmt.Fprintf(&b, "Active sessions: %d\n", r.ActiveSessions)
+
+	if len(r.Channels) > 0 {
+		b.WriteString("\nMessages per channel:\n")
+		for _, c := range r.Channels {
+			fmt.Fprintf(&b, "  %-12s %d\n", c.Name, c.Count)
+		}
+	}
+	if hours := r.BusiestHours(3); len(hours) > 0 {
+		b.WriteString("\nBusiest hours:\n")
+		for _, h := range hours {
+			fmt.Fprintf(&b, "  %02d:00-%02d:00  %d turns\n", h, (h+1)%24, r.Hours[h])
+		}
+	}
+	if len(r.Tools) > 0 {
+		b.WriteString("\nTop tools:\n")
+		for _, c := range r.Tools {
+			fmt.Fprintf(&b, "  %-20s %d\n", c.Name, c.Count)
+		}
+	}
+	if len(r.TopSessions) > 0 {
+		b.WriteString("\nMost active sessions:\n")
+		for _, s := range r.TopSessions {
+			fmt.Fprintf(&b, "  %-30s %d turns, %d messages stored\n", s.Key, s.Turns, s.Messages)
+		}
+	}
+	return b.String()
+}
+
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+func TestReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	log := New(path)
+	now := time.Now()
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+
+	log.Record(Event{Time: at(200), Kind: KindTurn, Session: "telegram:1", Channel: "telegram", DurationMS: 9000})
+	log.Record(Event{Time: at(3), Kind: KindTurn, Session: "telegram:1", Channel: "telegram", DurationMS: 1000, Tools: []string{"exec", "read_file"}})
+	log.Record(Event{Time: at(3), Kind: KindTurn, Session: "telegram:1", Channel: "telegram", DurationMS: 3000, Tools: []string{"exec"}})
+	log.Record(Event{Time: at(2), Kind: KindTurn, Session: "cli:direct", Channel: "cli", DurationMS: 2000, Error: true})
+	log.Record(Event{Time: at(1), Kind: KindSummary, Session: "telegram:1"})
+
+	since := now.AddDate(0, 0, -7)
+	events, err := Read(path, since)
+	if err != nil || len(events) != 4 {
+		t.Fatalf("read %d events, err %v", len(events), err)
+	}
+
+	sessions := []*session.Session{
+		{Key: "telegram:1", Messages: make([]providers.Message, 6), Updated: at(1)},
+		{Key: "discord:9", Messages: make([]providers.Message, 2), Updated: at(5)},
+		{Key: "old", Updated: at(500)},
+	}
+	r := Compute(events, sessions, since, now, 10)
+
+	if r.Turns != 3 || r.Errors != 1 || r.Summaries != 1 || r.ActiveSessions != 2 {
+		t.Errorf("totals: %+v", r)
+	}
+	if r.AvgLatencyMS != 2000 || r.P95LatencyMS != 3000 {
+		t.Errorf("latency avg %d p95 %d", r.AvgLatencyMS, r.P95LatencyMS)
+	}
+	if len(r.Channels) != 2 || r.Channels[0] != (Count{"telegram", 2}) {
+		t.Errorf("channels = %v", r.Channels)
+	}
+	if len(r.Tools) != 2 || r.Tools[0] != (Count{"exec", 2}) {
+		t.Errorf("tools = %v", r.Tools)
+	}
+	if r.Hours[at(3).Hour()] < 2 || r.BusiestHours(1)[0] != at(3).Hour() {
+		t.Errorf("hours = %v", r.Hours)
+	}
+	want := []SessionActivity{{"telegram:1", 2, 6}, {"cli:direct", 1, 0}, {"discord:9", 0, 2}}
+	if len(r.TopSessions) != 3 || r.TopSessions[0] != want[0] || r.TopSessions[1] != want[1] || r.TopSessions[2] != want[2] {
+		t.Errorf("sessions = %v", r.TopSessions)
+	}
+
+	text := r.Format()
+	for _, s := range []string{"Turns: 3 (1 failed)", "telegram     2", "exec", "telegram:1"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("report is missing %q:\n%s", s, text)
+		}
+	}
+}
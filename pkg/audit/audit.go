@@ -0,0 +1,140 @@
+// Package audit keeps an append-only log of file changes and commands run
+// by the agent's tools, one JSON object per line.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the audit log in the pepebot home directory
+const FileName = "audit.jsonl"
+
+// Statuses of an entry
+const (
+	StatusOK     = "ok"
+	StatusError  = "error"
+	StatusDenied = "denied"
+)
+
+// Entry is one tool call
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+	Tool    string    `json:"tool"`
+	Path    string    `json:"path,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Dir     string    `json:"dir,omitempty"`
+	Bytes   int       `json:"bytes,omitempty"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Log appends entries to a file. The file is only ever opened for appending.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry, stamping it with the current time when unset
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Filter selects entries in Query. Zero fields match everything.
+type Filter struct {
+	Session string // substring of the session key
+	Tool    string
+	Status  string
+	Since   time.Time
+	Limit   int // newest entries kept, all when zero
+}
+
+func (f Filter) match(e Entry) bool {
+	if f.Session != "" && !strings.Contains(e.Session, f.Session) {
+		return false
+	}
+	if f.Tool != "" && e.Tool != f.Tool {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
+
+// Query reads the log at path and returns the matching entries, oldest
+// first. A missing log has no entries.
+func Query(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, lineNo, err)
+		}
+		if filter.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
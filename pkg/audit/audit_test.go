@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", FileName)
+	log := New(path)
+
+	old := time.Now().Add(-2 * time.Hour)
+	entries := []Entry{
+		{Time: old, Session: "telegram:1", Tool: "exec", Command: "ls", Status: StatusOK},
+		{Session: "telegram:1", Tool: "write_file", Path: "/w/a.txt", Bytes: 3, Status: StatusOK},
+		{Session: "discord:2", Tool: "write_file", Path: "/etc/passwd", Status: StatusDenied, Error: "access denied"},
+	}
+	for _, e := range entries {
+		if err := log.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := Query(path, Filter{})
+	if err != nil || len(all) != 3 || all[0].Command != "ls" || all[1].Time.IsZero() {
+		t.Fatalf("Query = %+v, %v", all, err)
+	}
+	if got, _ := Query(path, Filter{Session: "telegram"}); len(got) != 2 {
+		t.Errorf("session filter = %d entries", len(got))
+	}
+	if got, _ := Query(path, Filter{Tool: "write_file", Status: StatusDenied}); len(got) != 1 || got[0].Path != "/etc/passwd" {
+		t.Errorf("tool/status filter = %+v", got)
+	}
+	if got, _ := Query(path, Filter{Since: time.Now().Add(-time.Hour)}); len(got) != 2 {
+		t.Errorf("since filter = %d entries", len(got))
+	}
+	if got, _ := Query(path, Filter{Limit: 1}); len(got) != 1 || got[0].Session != "discord:2" {
+		t.Errorf("limit keeps the newest, got %+v", got)
+	}
+
+	if got, err := Query(filepath.Join(t.TempDir(), "missing.jsonl"), Filter{}); err != nil || got != nil {
+		t.Errorf("missing log = %v, %v", got, err)
+	}
+}
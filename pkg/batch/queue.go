@@ -0,0 +1,386 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// Job is a queued request and the data its handler needs once the response
+// arrives
+type Job struct {
+	Kind      string                 `json:"kind"`
+	Key       string                 `json:"key,omitempty"`
+	Request   providers.BatchRequest `json:"request"`
+	Payload   json.RawMessage        `json:"payload,omitempty"`
+	Queued    time.Time              `json:"queued"`
+	Batch     string                 `json:"batch,omitempty"` // provider batch ID once submitted
+	Submitted time.Time              `json:"submitted,omitempty"`
+}
+
+// Handler receives the response to a job of its kind, or the error that
+// kept it from being answered
+type Handler func(ctx context.Context, job Job, resp *providers.LLMResponse, err error)
+
+// Queue collects non-urgent requests and sends them as one provider batch
+// every FlushIntervalS, or sooner once MaxRequests are waiting. Finished
+// batches are picked up every PollIntervalS and each response is handed to
+// the handler registered for its job's kind. Jobs are kept in
+// workspace/state/batch.json, so batches that end after a restart are still
+// delivered. When the provider has no batch API, or a submission fails, the
+// jobs are sent as regular requests instead.
+type Queue struct {
+	cfg      config.BatchConfig
+	path     string
+	provider providers.LLMProvider
+	model    string
+	handlers map[string]Handler
+	mu       sync.Mutex
+	jobs     []Job
+	runMu    sync.Mutex // one flush or poll at a time
+	lastPoll time.Time
+	stopChan chan struct{}
+}
+
+func NewQueue(cfg config.BatchConfig, workspace string, provider providers.LLMProvider, model string) *Queue {
+	q := &Queue{
+		cfg:      cfg,
+		path:     filepath.Join(workspace, "state", "batch.json"),
+		provider: provider,
+		model:    model,
+		handlers: make(map[string]Handler),
+	}
+	if data, err := os.ReadFile(q.path); err == nil {
+		if err := json.Unmarshal(data, &q.jobs); err != nil {
+			logger.WarnCF("batch", "Ignoring unreadable batch queue", map[string]interface{}{
+				"path":  q.path,
+				"error": err.Error(),
+			})
+		}
+	}
+	return q
+}
+
+// Handle registers the handler for jobs of kind. Register handlers before
+// Start so restored jobs find theirs.
+func (q *Queue) Handle(kind string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+var unsafeIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Enqueue adds a request for the queue's model. key, when set, identifies
+// the job for Pending and replaces a queued job with the same key. payload
+// is stored as JSON and passed back to the handler in Job.Payload.
+func (q *Queue) Enqueue(kind, key string, req providers.BatchRequest, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	now := time.Now()
+	req.ID = fmt.Sprintf("%s-%d", unsafeIDChars.ReplaceAllString(kind, "_"), now.UnixNano())
+	req.Model = q.model
+
+	q.mu.Lock()
+	kept := q.jobs[:0]
+	for _, job := range q.jobs {
+		if key == "" || job.Key != key || job.Batch != "" {
+			kept = append(kept, job)
+		}
+	}
+	q.jobs = append(kept, Job{Kind: kind, Key: key, Request: req, Payload: data, Queued: now})
+	full := q.cfg.MaxRequests > 0 && q.queuedLocked() >= q.cfg.MaxRequests
+	err = q.saveLocked()
+	q.mu.Unlock()
+
+	if full {
+		go q.Flush(context.Background())
+	}
+	return err
+}
+
+// Pending reports whether a job with key is waiting to be sent or answered
+func (q *Queue) Pending(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, job := range q.jobs {
+		if job.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Jobs returns the queued and submitted jobs
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Job(nil), q.jobs...)
+}
+
+func (q *Queue) Start() {
+	q.stopChan = make(chan struct{})
+	go q.runLoop()
+}
+
+func (q *Queue) Stop() {
+	if q.stopChan != nil {
+		close(q.stopChan)
+		q.stopChan = nil
+	}
+}
+
+func (q *Queue) runLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	stop := q.stopChan
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			q.tick(context.Background(), now)
+		}
+	}
+}
+
+// tick sends the queued jobs once the oldest has waited FlushIntervalS, and
+// checks submitted batches every PollIntervalS
+func (q *Queue) tick(ctx context.Context, now time.Time) {
+	q.mu.Lock()
+	var oldest time.Time
+	for _, job := range q.jobs {
+		if job.Batch == "" && (oldest.IsZero() || job.Queued.Before(oldest)) {
+			oldest = job.Queued
+		}
+	}
+	q.mu.Unlock()
+
+	if !oldest.IsZero() && now.Sub(oldest) >= time.Duration(q.cfg.FlushIntervalS)*time.Second {
+		q.Flush(ctx)
+	}
+	if now.Sub(q.lastPoll) >= time.Duration(q.cfg.PollIntervalS)*time.Second {
+		q.lastPoll = now
+		q.Poll(ctx)
+	}
+}
+
+// Flush sends all queued jobs now
+func (q *Queue) Flush(ctx context.Context) {
+	q.runMu.Lock()
+	defer q.runMu.Unlock()
+
+	q.mu.Lock()
+	var requests []providers.BatchRequest
+	for _, job := range q.jobs {
+		if job.Batch == "" {
+			requests = append(requests, job.Request)
+		}
+	}
+	q.mu.Unlock()
+	if len(requests) == 0 {
+		return
+	}
+
+	batcher, model, ok := providers.BatcherFor(q.provider, q.model)
+	if ok {
+		for i := range requests {
+			requests[i].Model = model
+		}
+		id, err := batcher.SubmitBatch(ctx, requests)
+		if err == nil {
+			q.markSubmitted(requests, id)
+			logger.InfoCF("batch", "Batch submitted", map[string]interface{}{
+				"batch":    id,
+				"requests": len(requests),
+			})
+			return
+		}
+		logger.WarnCF("batch", "Batch submission failed, sending requests directly", map[string]interface{}{
+			"requests": len(requests),
+			"error":    err.Error(),
+		})
+	}
+	q.runDirect(ctx, requests)
+}
+
+func (q *Queue) markSubmitted(requests []providers.BatchRequest, batchID string) {
+	sent := make(map[string]bool, len(requests))
+	for _, r := range requests {
+		sent[r.ID] = true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.jobs {
+		if sent[q.jobs[i].Request.ID] {
+			q.jobs[i].Batch = batchID
+			q.jobs[i].Submitted = time.Now()
+		}
+	}
+	q.saveLocked()
+}
+
+// runDirect sends requests one by one as regular, full-price requests
+func (q *Queue) runDirect(ctx context.Context, requests []providers.BatchRequest) {
+	for _, r := range requests {
+		options := map[string]interface{}{}
+		if r.MaxTokens > 0 {
+			options["max_tokens"] = r.MaxTokens
+		}
+		if r.Temperature != nil {
+			options["temperature"] = *r.Temperature
+		}
+		resp, err := q.provider.Chat(ctx, r.Messages, nil, q.model, options)
+		if job, ok := q.take(r.ID); ok {
+			q.deliver(ctx, job, resp, err)
+		}
+	}
+}
+
+// Poll checks the submitted batches and delivers the results of those that
+// have ended
+func (q *Queue) Poll(ctx context.Context) {
+	q.runMu.Lock()
+	defer q.runMu.Unlock()
+
+	q.mu.Lock()
+	batches := make(map[string]bool)
+	for _, job := range q.jobs {
+		if job.Batch != "" {
+			batches[job.Batch] = true
+		}
+	}
+	q.mu.Unlock()
+	if len(batches) == 0 {
+		return
+	}
+
+	batcher, _, ok := providers.BatcherFor(q.provider, q.model)
+	if !ok {
+		logger.WarnCF("batch", "Provider has no batch API, submitted batches cannot be checked", map[string]interface{}{
+			"batches": len(batches),
+		})
+		return
+	}
+
+	for id := range batches {
+		results, done, err := batcher.BatchResults(ctx, id)
+		if !done {
+			if err != nil {
+				logger.WarnCF("batch", "Failed to check batch", map[string]interface{}{
+					"batch": id,
+					"error": err.Error(),
+				})
+			}
+			continue
+		}
+
+		byID := make(map[string]providers.BatchResult, len(results))
+		for _, r := range results {
+			byID[r.ID] = r
+		}
+		for _, job := range q.takeBatch(id) {
+			r, found := byID[job.Request.ID]
+			switch {
+			case err != nil:
+				q.deliver(ctx, job, nil, fmt.Errorf("batch %s failed: %w", id, err))
+			case !found:
+				q.deliver(ctx, job, nil, fmt.Errorf("batch %s ended without answering this request", id))
+			case r.Error != "":
+				q.deliver(ctx, job, nil, fmt.Errorf("%s", r.Error))
+			default:
+				q.deliver(ctx, job, r.Response, nil)
+			}
+		}
+		logger.InfoCF("batch", "Batch finished", map[string]interface{}{
+			"batch":   id,
+			"results": len(results),
+		})
+	}
+}
+
+// take removes the job for a request from the queue
+func (q *Queue) take(requestID string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, job := range q.jobs {
+		if job.Request.ID == requestID {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			q.saveLocked()
+			return job, true
+		}
+	}
+	return Job{}, false
+}
+
+// takeBatch removes the jobs of a batch from the queue
+func (q *Queue) takeBatch(batchID string) []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var taken []Job
+	kept := q.jobs[:0]
+	for _, job := range q.jobs {
+		if job.Batch == batchID {
+			taken = append(taken, job)
+		} else {
+			kept = append(kept, job)
+		}
+	}
+	q.jobs = kept
+	q.saveLocked()
+	return taken
+}
+
+func (q *Queue) deliver(ctx context.Context, job Job, resp *providers.LLMResponse, err error) {
+	q.mu.Lock()
+	handler := q.handlers[job.Kind]
+	q.mu.Unlock()
+	if handler == nil {
+		logger.WarnCF("batch", "No handler for batch job", map[string]interface{}{
+			"kind": job.Kind,
+		})
+		return
+	}
+	if err != nil {
+		logger.WarnCF("batch", "Batch request failed", map[string]interface{}{
+			"kind":  job.Kind,
+			"key":   job.Key,
+			"error": err.Error(),
+		})
+	}
+	handler(ctx, job, resp, err)
+}
+
+func (q *Queue) queuedLocked() int {
+	n := 0
+	for _, job := range q.jobs {
+		if job.Batch == "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (q *Queue) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(q.jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.path, data, 0644)
+}
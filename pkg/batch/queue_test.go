@@ -0,0 +1,135 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// chatProvider answers every request directly with its last message
+type chatProvider struct {
+	calls int
+}
+
+func (p *chatProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	return &providers.LLMResponse{Content: "direct: " + fmt.Sprint(messages[len(messages)-1].Content)}, nil
+}
+
+func (p *chatProvider) ChatStream(ctx context.Context, messages []providers.Message, model string, options map[string]interface{}, callback providers.StreamCallback) error {
+	return nil
+}
+
+func (p *chatProvider) GetDefaultModel() string { return "" }
+
+// batchProvider also has a batch API, which ends a batch on the second check
+type batchProvider struct {
+	chatProvider
+	submitted map[string][]providers.BatchRequest
+	checks    int
+}
+
+func (p *batchProvider) SubmitBatch(ctx context.Context, requests []providers.BatchRequest) (string, error) {
+	id := fmt.Sprintf("batch_%d", len(p.submitted)+1)
+	p.submitted[id] = requests
+	return id, nil
+}
+
+func (p *batchProvider) BatchResults(ctx context.Context, id string) ([]providers.BatchResult, bool, error) {
+	p.checks++
+	if p.checks < 2 {
+		return nil, false, nil
+	}
+	var results []providers.BatchResult
+	for i, r := range p.submitted[id] {
+		if i == 1 {
+			continue // not answered
+		}
+		results = append(results, providers.BatchResult{ID: r.ID, Response: &providers.LLMResponse{Content: "batched: " + fmt.Sprint(r.Messages[0].Content)}})
+	}
+	return results, true, nil
+}
+
+type delivery struct {
+	text string
+	err  error
+}
+
+func collect(q *Queue) map[string]delivery {
+	got := make(map[string]delivery)
+	q.Handle("note", func(ctx context.Context, job Job, resp *providers.LLMResponse, err error) {
+		var payload string
+		json.Unmarshal(job.Payload, &payload)
+		d := delivery{err: err}
+		if resp != nil {
+			d.text = resp.Content
+		}
+		got[payload] = d
+	})
+	return got
+}
+
+func enqueue(t *testing.T, q *Queue, key, text string) {
+	t.Helper()
+	err := q.Enqueue("note", key, providers.BatchRequest{Messages: []providers.Message{{Role: "user", Content: text}}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueBatches(t *testing.T) {
+	workspace := t.TempDir()
+	provider := &batchProvider{submitted: make(map[string][]providers.BatchRequest)}
+	q := NewQueue(config.BatchConfig{}, workspace, provider, "gpt-4o-mini")
+	got := collect(q)
+
+	enqueue(t, q, "a", "first")
+	enqueue(t, q, "b", "second")
+	enqueue(t, q, "a", "first again") // replaces the queued "a"
+	if !q.Pending("a") || q.Pending("c") {
+		t.Error("unexpected pending state")
+	}
+
+	ctx := context.Background()
+	q.Flush(ctx)
+	if len(provider.submitted) != 1 || len(provider.submitted["batch_1"]) != 2 {
+		t.Fatalf("expected one batch of 2 requests, got %v", provider.submitted)
+	}
+	if r := provider.submitted["batch_1"][0]; r.Model != "gpt-4o-mini" || r.ID == "" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+
+	// Submitted jobs survive a restart
+	q = NewQueue(config.BatchConfig{}, workspace, provider, "gpt-4o-mini")
+	got = collect(q)
+	q.Poll(ctx)
+	if len(got) != 0 || !q.Pending("b") {
+		t.Fatal("batch should still be running")
+	}
+	q.Poll(ctx)
+	if got["b"].text != "batched: second" {
+		t.Errorf("b: %+v", got["b"])
+	}
+	if got["a"].err == nil {
+		t.Errorf("unanswered request should fail: %+v", got["a"])
+	}
+	if len(q.Jobs()) != 0 || provider.calls != 0 {
+		t.Errorf("jobs left: %d, direct calls: %d", len(q.Jobs()), provider.calls)
+	}
+}
+
+func TestQueueWithoutBatchAPI(t *testing.T) {
+	provider := &chatProvider{}
+	q := NewQueue(config.BatchConfig{}, t.TempDir(), provider, "local-model")
+	got := collect(q)
+
+	enqueue(t, q, "a", "hello")
+	q.Flush(context.Background())
+	if got["a"].text != "direct: hello" || provider.calls != 1 || q.Pending("a") {
+		t.Errorf("expected a direct request, got %+v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Sender delivers a message to a single chat. Implemented by channels.Manager.
+type Sender interface {
+	SendToChannel(ctx context.Context, channelName, chatID, content string) error
+}
+
+// Request describes a broadcast. Recipients come from a configured list,
+// an inline recipient list, or both. The message is either an inline
+// template or the name of a configured template.
+type Request struct {
+	List       string                      `json:"list,omitempty"`
+	Recipients []config.BroadcastRecipient `json:"recipients,omitempty"`
+	Template   string                      `json:"template,omitempty"`
+	Message    string                      `json:"message,omitempty"`
+	Vars       map[string]string           `json:"vars,omitempty"`
+	ThrottleMS int                         `json:"throttle_ms,omitempty"`
+}
+
+// Delivery is the per-recipient outcome of a broadcast
+type Delivery struct {
+	Channel string `json:"channel"`
+	ChatID  string `json:"chat_id"`
+	Name    string `json:"name,omitempty"`
+	Status  string `json:"status"` // sent, failed, skipped
+	Error   string `json:"error,omitempty"`
+	SentAt  string `json:"sent_at,omitempty"`
+}
+
+// Result summarizes a broadcast
+type Result struct {
+	Total      int        `json:"total"`
+	Sent       int        `json:"sent"`
+	Failed     int        `json:"failed"`
+	Skipped    int        `json:"skipped"`
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Service sends templated messages to lists of recipients across channels
+type Service struct {
+	cfg    config.BroadcastConfig
+	sender Sender
+}
+
+func NewService(cfg config.BroadcastConfig, sender Sender) *Service {
+	return &Service{cfg: cfg, sender: sender}
+}
+
+// Lists returns the configured recipient lists
+func (s *Service) Lists() map[string][]config.BroadcastRecipient {
+	return s.cfg.Lists
+}
+
+// Templates returns the configured message templates
+func (s *Service) Templates() map[string]string {
+	return s.cfg.Templates
+}
+
+// Send renders the template for each recipient and delivers it, waiting
+// ThrottleMS between sends. Recipients not reached before ctx is cancelled
+// are reported as skipped.
+func (s *Service) Send(ctx context.Context, req Request) (*Result, error) {
+	tmpl := req.Message
+	if req.Template != "" {
+		named, ok := s.cfg.Templates[req.Template]
+		if !ok {
+			return nil, fmt.Errorf("template '%s' not found", req.Template)
+		}
+		tmpl = named
+	}
+	if tmpl == "" {
+		return nil, fmt.Errorf("message or template is required")
+	}
+
+	recipients := []config.BroadcastRecipient{}
+	if req.List != "" {
+		list, ok := s.cfg.Lists[req.List]
+		if !ok {
+			return nil, fmt.Errorf("recipient list '%s' not found", req.List)
+		}
+		recipients = append(recipients, list...)
+	}
+	recipients = append(recipients, req.Recipients...)
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients: set list or recipients")
+	}
+
+	throttle := time.Duration(s.cfg.ThrottleMS) * time.Millisecond
+	if req.ThrottleMS > 0 {
+		throttle = time.Duration(req.ThrottleMS) * time.Millisecond
+	}
+
+	result := &Result{Total: len(recipients), Deliveries: make([]Delivery, 0, len(recipients))}
+
+	for i, rcpt := range recipients {
+		delivery := Delivery{Channel: rcpt.Channel, ChatID: rcpt.ChatID, Name: rcpt.Name}
+
+		if ctx.Err() != nil {
+			delivery.Status = "skipped"
+			delivery.Error = ctx.Err().Error()
+			result.Skipped++
+			result.Deliveries = append(result.Deliveries, delivery)
+			continue
+		}
+
+		if rcpt.Channel == "" || rcpt.ChatID == "" {
+			delivery.Status = "failed"
+			delivery.Error = "channel and chat_id are required"
+			result.Failed++
+			result.Deliveries = append(result.Deliveries, delivery)
+			continue
+		}
+
+		content := Render(tmpl, recipientVars(rcpt, req.Vars))
+		if err := s.sender.SendToChannel(ctx, rcpt.Channel, rcpt.ChatID, content); err != nil {
+			delivery.Status = "failed"
+			delivery.Error = err.Error()
+			result.Failed++
+		} else {
+			delivery.Status = "sent"
+			delivery.SentAt = time.Now().Format(time.RFC3339)
+			result.Sent++
+		}
+		result.Deliveries = append(result.Deliveries, delivery)
+
+		if throttle > 0 && i < len(recipients)-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(throttle):
+			}
+		}
+	}
+
+	logger.InfoCF("broadcast", "Broadcast finished", map[string]interface{}{
+		"total":   result.Total,
+		"sent":    result.Sent,
+		"failed":  result.Failed,
+		"skipped": result.Skipped,
+	})
+
+	return result, nil
+}
+
+// recipientVars merges built-in, broadcast-wide and per-recipient variables
+// (later sources win).
+func recipientVars(rcpt config.BroadcastRecipient, vars map[string]string) map[string]string {
+	now := time.Now()
+	merged := map[string]string{
+		"name":    rcpt.Name,
+		"channel": rcpt.Channel,
+		"chat_id": rcpt.ChatID,
+		"date":    now.Format("2006-01-02"),
+		"time":    now.Format("15:04"),
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for k, v := range rcpt.Vars {
+		merged[k] = v
+	}
+	return merged
+}
+
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.-]+)\s*\}\}`)
+
+// Render substitutes {{var}} placeholders. Unknown variables are left as-is.
+func Render(tmpl string, vars map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		name := templateVarPattern.FindStringSubmatch(match)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return match
+	})
+}
@@ -0,0 +1,62 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+type fakeSender struct {
+	sent []string
+}
+
+func (f *fakeSender) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
+	if channelName == "missing" {
+		return fmt.Errorf("channel %s not found", channelName)
+	}
+	f.sent = append(f.sent, chatID+": "+content)
+	return nil
+}
+
+func TestRender(t *testing.T) {
+	got := Render("Hi {{name}}, {{ slot }} {{unknown}}", map[string]string{"name": "Ani", "slot": "10:30"})
+	want := "Hi Ani, 10:30 {{unknown}}"
+	if got != want {
+		t.Fatalf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestSend(t *testing.T) {
+	sender := &fakeSender{}
+	svc := NewService(config.BroadcastConfig{
+		Lists: map[string][]config.BroadcastRecipient{
+			"team": {
+				{Channel: "telegram", ChatID: "1", Name: "Ani"},
+				{Channel: "missing", ChatID: "2", Name: "Budi"},
+			},
+		},
+		Templates: map[string]string{"greet": "Hi {{name}} {{emoji}}"},
+	}, sender)
+
+	result, err := svc.Send(context.Background(), Request{
+		List:       "team",
+		Template:   "greet",
+		Vars:       map[string]string{"emoji": "🐸"},
+		Recipients: []config.BroadcastRecipient{{Channel: "discord", ChatID: "3", Name: "Cici", Vars: map[string]string{"emoji": "👋"}}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	if result.Total != 3 || result.Sent != 2 || result.Failed != 1 {
+		t.Fatalf("unexpected counts: %+v", result)
+	}
+	if result.Deliveries[1].Status != "failed" || result.Deliveries[1].Error == "" {
+		t.Fatalf("expected failed delivery for missing channel, got %+v", result.Deliveries[1])
+	}
+	if sender.sent[0] != "1: Hi Ani 🐸" || sender.sent[1] != "3: Hi Cici 👋" {
+		t.Fatalf("unexpected messages: %v", sender.sent)
+	}
+}
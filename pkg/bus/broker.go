@@ -0,0 +1,166 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Broker roles. An ingest process runs the channels and sends replies, a
+// worker runs the agents, and "all" does both.
+const (
+	RoleAll    = "all"
+	RoleIngest = "ingest"
+	RoleWorker = "worker"
+)
+
+// Broker carries bus messages between gateway processes. Each message
+// published to a subject is delivered to one of its subscribers.
+type Broker interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Subscribe delivers the messages of subject until ctx ends
+	Subscribe(ctx context.Context, subject string) (<-chan []byte, error)
+	Close() error
+}
+
+// NewBroker connects to the broker in cfg: a redis:// or nats:// URL
+func NewBroker(cfg config.BrokerConfig) (Broker, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker url: %w", err)
+	}
+	switch cfg.Type {
+	case "redis":
+		return newRedisBroker(u)
+	case "nats":
+		return newNATSBroker(u)
+	}
+	return nil, fmt.Errorf("unknown broker type %q (use redis or nats)", cfg.Type)
+}
+
+// brokerLink routes the bus through a broker
+type brokerLink struct {
+	broker  Broker
+	prefix  string
+	role    string
+	workers int
+	index   int
+}
+
+// AttachBroker moves the bus onto broker. Inbound messages are sharded by
+// session key over cfg.Workers subjects, so one worker sees all of a
+// session's messages in order; this process consumes shard cfg.WorkerIndex
+// unless it only ingests. Outbound messages go to the ingest processes.
+func (mb *MessageBus) AttachBroker(ctx context.Context, broker Broker, cfg config.BrokerConfig) error {
+	link := &brokerLink{
+		broker:  broker,
+		prefix:  cfg.Prefix,
+		role:    cfg.Role,
+		workers: max(cfg.Workers, 1),
+		index:   cfg.WorkerIndex,
+	}
+	if link.prefix == "" {
+		link.prefix = "pepebot"
+	}
+	if link.role == "" {
+		link.role = RoleAll
+	}
+	switch link.role {
+	case RoleAll, RoleIngest, RoleWorker:
+	default:
+		return fmt.Errorf("unknown broker role %q (use all, ingest or worker)", link.role)
+	}
+	if link.index < 0 || link.index >= link.workers {
+		return fmt.Errorf("worker_index %d is out of range for %d workers", link.index, link.workers)
+	}
+
+	if link.role != RoleIngest {
+		subject := link.inboundSubject(link.index)
+		if err := forward(ctx, broker, subject, mb.inbound, "inbound"); err != nil {
+			return err
+		}
+	}
+	if link.role != RoleWorker {
+		if err := forward(ctx, broker, link.prefix+".outbound", mb.outbound, "outbound"); err != nil {
+			return err
+		}
+	}
+
+	mb.mu.Lock()
+	mb.broker = link
+	mb.mu.Unlock()
+	return nil
+}
+
+func (l *brokerLink) inboundSubject(shard int) string {
+	return fmt.Sprintf("%s.inbound.%d", l.prefix, shard)
+}
+
+// shard picks the worker of a session
+func (l *brokerLink) shard(sessionKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(sessionKey))
+	return int(h.Sum32() % uint32(l.workers))
+}
+
+// affinityKey is the session a message belongs to; channels that don't set
+// a session key use their chat
+func affinityKey(msg InboundMessage) string {
+	if msg.SessionKey != "" {
+		return msg.SessionKey
+	}
+	return msg.Channel + ":" + msg.ChatID
+}
+
+func (l *brokerLink) publish(subject string, msg interface{}) bool {
+	data, err := json.Marshal(msg)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = l.broker.Publish(ctx, subject, data)
+		cancel()
+	}
+	if err != nil {
+		logger.ErrorCF("bus", "Failed to publish to broker", map[string]interface{}{
+			"subject": subject,
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// forward decodes the messages of subject into the local queue
+func forward[T any](ctx context.Context, broker Broker, subject string, queue chan T, kind string) error {
+	messages, err := broker.Subscribe(ctx, subject)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	logger.InfoCF("bus", "Consuming from broker", map[string]interface{}{
+		"subject": subject,
+		"kind":    kind,
+	})
+	go func() {
+		for data := range messages {
+			var msg T
+			if err := json.Unmarshal(data, &msg); err != nil {
+				logger.WarnCF("bus", "Dropping undecodable broker message", map[string]interface{}{
+					"subject": subject,
+					"error":   err.Error(),
+				})
+				continue
+			}
+			select {
+			case queue <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
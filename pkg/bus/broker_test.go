@@ -0,0 +1,312 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// memoryBroker is a Broker whose subjects are shared channels
+type memoryBroker struct {
+	mu       sync.Mutex
+	subjects map[string]chan []byte
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{subjects: map[string]chan []byte{}}
+}
+
+func (b *memoryBroker) queue(subject string) chan []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.subjects[subject]
+	if !ok {
+		q = make(chan []byte, 100)
+		b.subjects[subject] = q
+	}
+	return q
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	b.queue(subject) <- data
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(ctx context.Context, subject string) (<-chan []byte, error) {
+	return b.queue(subject), nil
+}
+
+func (b *memoryBroker) Close() error {
+	return nil
+}
+
+func attach(t *testing.T, ctx context.Context, broker Broker, role string, workers, index int) *MessageBus {
+	t.Helper()
+	mb := NewMessageBus()
+	err := mb.AttachBroker(ctx, broker, config.BrokerConfig{Role: role, Workers: workers, WorkerIndex: index})
+	if err != nil {
+		t.Fatalf("AttachBroker: %v", err)
+	}
+	return mb
+}
+
+func consume(t *testing.T, mb *MessageBus) (InboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	return mb.ConsumeInbound(ctx)
+}
+
+func TestBrokerRoutesSessionsToOneWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := newMemoryBroker()
+
+	ingest := attach(t, ctx, broker, RoleIngest, 2, 0)
+	workers := []*MessageBus{
+		attach(t, ctx, broker, RoleWorker, 2, 0),
+		attach(t, ctx, broker, RoleWorker, 2, 1),
+	}
+
+	link := ingest.brokerLink()
+	sessions := []string{"telegram:1", "telegram:2", "discord:3", "discord:4", "whatsapp:5"}
+	for _, key := range sessions {
+		for i := 0; i < 2; i++ {
+			ingest.PublishInbound(InboundMessage{Channel: "test", SessionKey: key, Content: fmt.Sprint(i)})
+		}
+	}
+
+	// Each session's messages arrive in order at the worker of its shard
+	got := map[string][]string{}
+	for i, w := range workers {
+		for {
+			msg, ok := consume(t, w)
+			if !ok {
+				break
+			}
+			if shard := link.shard(msg.SessionKey); shard != i {
+				t.Fatalf("%s belongs to worker %d, got it at %d", msg.SessionKey, shard, i)
+			}
+			got[msg.SessionKey] = append(got[msg.SessionKey], msg.Content)
+		}
+	}
+	for _, key := range sessions {
+		if strings.Join(got[key], ",") != "0,1" {
+			t.Fatalf("expected both messages of %s in order, got %v", key, got[key])
+		}
+	}
+	if _, ok := consume(t, ingest); ok {
+		t.Fatal("ingest process should not consume inbound messages")
+	}
+}
+
+func TestBrokerShardIsStable(t *testing.T) {
+	link := &brokerLink{workers: 4}
+	seen := map[int]bool{}
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("telegram:%d", i)
+		shard := link.shard(key)
+		if shard != link.shard(key) {
+			t.Fatalf("shard of %s changed", key)
+		}
+		seen[shard] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("expected sessions spread over 4 workers, got %v", seen)
+	}
+	if affinityKey(InboundMessage{Channel: "cli", ChatID: "7"}) != "cli:7" {
+		t.Fatal("messages without a session key should use their chat")
+	}
+}
+
+func TestBrokerDeliversOutboundToIngest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := newMemoryBroker()
+
+	ingest := attach(t, ctx, broker, RoleIngest, 1, 0)
+	worker := attach(t, ctx, broker, RoleWorker, 1, 0)
+
+	worker.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "42", Content: "hi"})
+
+	subCtx, subCancel := context.WithTimeout(ctx, time.Second)
+	defer subCancel()
+	msg, ok := ingest.SubscribeOutbound(subCtx)
+	if !ok || msg.Content != "hi" || msg.ChatID != "42" {
+		t.Fatalf("expected the reply at the ingest process, got %+v (%v)", msg, ok)
+	}
+}
+
+func TestAttachBrokerValidates(t *testing.T) {
+	mb := NewMessageBus()
+	if err := mb.AttachBroker(context.Background(), newMemoryBroker(), config.BrokerConfig{Workers: 2, WorkerIndex: 2}); err == nil {
+		t.Fatal("expected an out of range worker index to fail")
+	}
+	if err := mb.AttachBroker(context.Background(), newMemoryBroker(), config.BrokerConfig{Role: "both"}); err == nil {
+		t.Fatal("expected an unknown role to fail")
+	}
+}
+
+// fakeServer hands each connection it accepts to handle
+func fakeServer(t *testing.T, handle func(r *bufio.Reader, conn net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(bufio.NewReader(conn), conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRedisBrokerPushesAndPops(t *testing.T) {
+	var mu sync.Mutex
+	var lists = map[string][]string{}
+	addr := fakeServer(t, func(r *bufio.Reader, conn net.Conn) {
+		for {
+			args, err := readCommand(r)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			switch strings.ToUpper(args[0]) {
+			case "AUTH":
+				fmt.Fprint(conn, "+OK\r\n")
+			case "RPUSH":
+				lists[args[1]] = append(lists[args[1]], args[2])
+				fmt.Fprintf(conn, ":%d\r\n", len(lists[args[1]]))
+			case "BLPOP":
+				if items := lists[args[1]]; len(items) > 0 {
+					lists[args[1]] = items[1:]
+					fmt.Fprintf(conn, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(items[0]), items[0])
+				} else {
+					fmt.Fprint(conn, "*-1\r\n")
+				}
+			default:
+				fmt.Fprint(conn, "-ERR unknown command\r\n")
+			}
+			mu.Unlock()
+		}
+	})
+
+	u, _ := url.Parse("redis://secret@" + addr)
+	b, err := newRedisBroker(u)
+	if err != nil {
+		t.Fatalf("newRedisBroker: %v", err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.Publish(ctx, "pepebot.outbound", []byte(`{"content":"hi"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	messages, err := b.Subscribe(ctx, "pepebot.outbound")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	select {
+	case data := <-messages:
+		if string(data) != `{"content":"hi"}` {
+			t.Fatalf("unexpected message %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+// readCommand reads one RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	c := &redisConn{r: r}
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]interface{})
+	args := make([]string, len(items))
+	for i, item := range items {
+		b, _ := item.([]byte)
+		args[i] = string(b)
+	}
+	return args, nil
+}
+
+func TestNATSBrokerPublishesToQueueGroup(t *testing.T) {
+	subscribed := make(chan string, 1)
+	addr := fakeServer(t, func(r *bufio.Reader, conn net.Conn) {
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		sid := ""
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case "SUB":
+				// SUB <subject> <queue> <sid>
+				sid = fields[3]
+				subscribed <- fields[1] + " " + fields[2]
+				fmt.Fprint(conn, "PING\r\n")
+			case "PUB":
+				var size int
+				fmt.Sscan(fields[2], &size)
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", fields[1], sid, size, payload[:size])
+			}
+		}
+	})
+
+	u, _ := url.Parse("nats://" + addr)
+	b, err := newNATSBroker(u)
+	if err != nil {
+		t.Fatalf("newNATSBroker: %v", err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := b.Subscribe(ctx, "pepebot.inbound.0")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if got := <-subscribed; got != "pepebot.inbound.0 pepebot.inbound.0" {
+		t.Fatalf("expected a queue group subscription, got %q", got)
+	}
+	if err := b.Publish(ctx, "pepebot.inbound.0", []byte("hello")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	select {
+	case data := <-messages:
+		if string(data) != "hello" {
+			t.Fatalf("unexpected message %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
@@ -2,7 +2,9 @@ package bus
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/pepebot-space/pepebot/pkg/logger"
 )
@@ -11,6 +13,8 @@ type MessageBus struct {
 	inbound  chan InboundMessage
 	outbound chan OutboundMessage
 	handlers map[string]MessageHandler
+	journal  *Journal
+	broker   *brokerLink
 	mu       sync.RWMutex
 }
 
@@ -22,7 +26,69 @@ func NewMessageBus() *MessageBus {
 	}
 }
 
+// EnableJournal makes inbound messages durable: each published message is
+// journaled until acknowledged with Ack.
+func (mb *MessageBus) EnableJournal(journal *Journal) {
+	mb.journal = journal
+}
+
+// Replay re-publishes journaled messages that were never acknowledged,
+// skipping (and dropping) messages older than maxAge.
+func (mb *MessageBus) Replay(maxAge time.Duration) int {
+	if mb.journal == nil {
+		return 0
+	}
+
+	since := time.Time{}
+	if maxAge > 0 {
+		since = time.Now().Add(-maxAge)
+	}
+
+	pending := mb.journal.Pending(since)
+	if len(pending) == 0 {
+		return 0
+	}
+
+	logger.InfoCF("bus", "Replaying journaled inbound messages", map[string]interface{}{
+		"count": len(pending),
+	})
+
+	// Publish from a goroutine: the inbound buffer may be smaller than the backlog
+	go func() {
+		for _, msg := range pending {
+			mb.inbound <- msg
+		}
+	}()
+
+	return len(pending)
+}
+
+// Ack marks an inbound message as handled so it is not replayed
+func (mb *MessageBus) Ack(msg InboundMessage) {
+	if mb.journal == nil || msg.ID == "" {
+		return
+	}
+	if err := mb.journal.Ack(msg.ID); err != nil {
+		logger.WarnCF("bus", "Failed to acknowledge journaled message", map[string]interface{}{
+			"id":    msg.ID,
+			"error": err.Error(),
+		})
+	}
+}
+
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
+	link := mb.brokerLink()
+	if mb.journal != nil && link == nil {
+		if msg.ID == "" {
+			msg.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+		}
+		if err := mb.journal.Append(msg); err != nil {
+			logger.WarnCF("bus", "Failed to journal inbound message", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	logger.DebugCF("bus", "Publishing inbound message", map[string]interface{}{
 		"channel":   msg.Channel,
 		"sender_id": msg.SenderID,
@@ -30,9 +96,18 @@ func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 		"has_media": len(msg.Media) > 0,
 		"preview":   truncateString(msg.Content, 100),
 	})
+	if link != nil && (link.publish(link.inboundSubject(link.shard(affinityKey(msg))), msg) || link.role == RoleIngest) {
+		return
+	}
 	mb.inbound <- msg
 }
 
+func (mb *MessageBus) brokerLink() *brokerLink {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	return mb.broker
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -56,6 +131,9 @@ func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
 		"has_media": len(msg.Media) > 0,
 		"preview":   truncateString(msg.Content, 100),
 	})
+	if link := mb.brokerLink(); link != nil && (link.publish(link.prefix+".outbound", msg) || link.role == RoleWorker) {
+		return
+	}
 	mb.outbound <- msg
 }
 
@@ -68,6 +146,12 @@ func (mb *MessageBus) SubscribeOutbound(ctx context.Context) (OutboundMessage, b
 	}
 }
 
+// QueueDepths returns the number of messages waiting in the inbound and
+// outbound queues
+func (mb *MessageBus) QueueDepths() (inbound, outbound int) {
+	return len(mb.inbound), len(mb.outbound)
+}
+
 func (mb *MessageBus) RegisterHandler(channel string, handler MessageHandler) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
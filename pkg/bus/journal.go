@@ -0,0 +1,223 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// compactThreshold is the number of acknowledged records after which the
+// journal file is rewritten to contain only unacknowledged messages.
+const compactThreshold = 200
+
+type journalRecord struct {
+	Op      string          `json:"op"` // "in" or "ack"
+	ID      string          `json:"id"`
+	Time    time.Time       `json:"time"`
+	Message *InboundMessage `json:"message,omitempty"`
+}
+
+// Journal is an append-only write-ahead log of inbound messages. A message is
+// recorded when published and acknowledged once the agent has handled it, so
+// anything still unacknowledged after a crash or restart can be replayed.
+type Journal struct {
+	path    string
+	file    *os.File
+	pending map[string]journalRecord
+	order   []string
+	acked   int
+	mu      sync.Mutex
+}
+
+// OpenJournal opens (or creates) the journal file and loads unacknowledged messages
+func OpenJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &Journal{
+		path:    path,
+		pending: make(map[string]journalRecord),
+	}
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	// Start every run from a compact file
+	if err := j.rewrite(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A torn final line from a crash is expected; skip it
+			continue
+		}
+		switch rec.Op {
+		case "in":
+			if rec.Message != nil {
+				if _, exists := j.pending[rec.ID]; !exists {
+					j.order = append(j.order, rec.ID)
+				}
+				j.pending[rec.ID] = rec
+			}
+		case "ack":
+			delete(j.pending, rec.ID)
+		}
+	}
+	return scanner.Err()
+}
+
+// Append records a newly published inbound message
+func (j *Journal) Append(msg InboundMessage) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rec := journalRecord{Op: "in", ID: msg.ID, Time: time.Now(), Message: &msg}
+	if err := j.write(rec); err != nil {
+		return err
+	}
+	j.pending[msg.ID] = rec
+	j.order = append(j.order, msg.ID)
+	return nil
+}
+
+// Ack marks a message as handled
+func (j *Journal) Ack(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	delete(j.pending, id)
+
+	if err := j.write(journalRecord{Op: "ack", ID: id, Time: time.Now()}); err != nil {
+		return err
+	}
+
+	j.acked++
+	if j.acked >= compactThreshold {
+		return j.rewrite()
+	}
+	return nil
+}
+
+// Pending returns unacknowledged messages received after since, oldest first.
+// Older messages are acknowledged and dropped.
+func (j *Journal) Pending(since time.Time) []InboundMessage {
+	j.mu.Lock()
+	msgs := make([]InboundMessage, 0, len(j.pending))
+	stale := []string{}
+	for _, id := range j.order {
+		rec, ok := j.pending[id]
+		if !ok {
+			continue
+		}
+		if rec.Time.Before(since) {
+			stale = append(stale, id)
+			continue
+		}
+		msgs = append(msgs, *rec.Message)
+	}
+	j.mu.Unlock()
+
+	for _, id := range stale {
+		j.Ack(id)
+	}
+	if len(stale) > 0 {
+		logger.InfoCF("bus", "Dropped stale journaled messages", map[string]interface{}{
+			"count": len(stale),
+		})
+	}
+
+	return msgs
+}
+
+// Close flushes and closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+func (j *Journal) write(rec journalRecord) error {
+	if j.file == nil {
+		return fmt.Errorf("journal is closed")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// rewrite replaces the journal file with only the unacknowledged messages
+func (j *Journal) rewrite() error {
+	tmp := j.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+
+	order := make([]string, 0, len(j.pending))
+	for _, id := range j.order {
+		rec, ok := j.pending[id]
+		if !ok {
+			continue
+		}
+		data, _ := json.Marshal(rec)
+		f.Write(append(data, '\n'))
+		order = append(order, id)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen journal: %w", err)
+	}
+
+	j.order = order
+	j.acked = 0
+	return nil
+}
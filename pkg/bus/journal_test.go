@@ -0,0 +1,48 @@
+package bus
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalReplaysUnacknowledged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbound.jsonl")
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := j.Append(InboundMessage{ID: id, Channel: "telegram", ChatID: "42", Content: "msg " + id}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := j.Ack("2"); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	j.Close()
+
+	// Simulate a restart
+	j, err = OpenJournal(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer j.Close()
+
+	pending := j.Pending(time.Time{})
+	if len(pending) != 2 || pending[0].ID != "1" || pending[1].ID != "3" {
+		t.Fatalf("expected messages 1 and 3 pending, got %+v", pending)
+	}
+	if pending[0].Content != "msg 1" {
+		t.Fatalf("message content not preserved: %q", pending[0].Content)
+	}
+
+	// Messages older than the cutoff are dropped
+	if stale := j.Pending(time.Now().Add(time.Minute)); len(stale) != 0 {
+		t.Fatalf("expected stale messages to be dropped, got %d", len(stale))
+	}
+	if again := j.Pending(time.Time{}); len(again) != 0 {
+		t.Fatalf("expected dropped messages to stay acknowledged, got %d", len(again))
+	}
+}
@@ -0,0 +1,292 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// natsBroker speaks the NATS text protocol. Subscriptions join a queue
+// group named after the subject, so each message reaches one subscriber.
+// NATS core doesn't keep messages while nobody is subscribed.
+type natsBroker struct {
+	addr   string
+	useTLS bool
+	host   string
+	user   string
+	pass   string
+	token  string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	w      *bufio.Writer
+	subs   map[int]*natsSub
+	nextID int
+	closed bool
+}
+
+type natsSub struct {
+	subject string
+	ctx     context.Context
+	out     chan []byte
+
+	mu   sync.Mutex // held while delivering, so out isn't closed mid-send
+	done bool
+}
+
+func newNATSBroker(u *url.URL) (*natsBroker, error) {
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("nats broker url must start with nats:// or tls://")
+	}
+	b := &natsBroker{
+		addr:   u.Host,
+		useTLS: u.Scheme == "tls",
+		host:   u.Hostname(),
+		subs:   map[int]*natsSub{},
+	}
+	if u.Port() == "" {
+		b.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			b.user, b.pass = u.User.Username(), pass
+		} else {
+			b.token = u.User.Username()
+		}
+	}
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// connect dials the server and starts reading from it. Called with b.mu
+// free; existing subscriptions are sent again.
+func (b *natsBroker) connect() error {
+	nc, err := net.DialTimeout("tcp", b.addr, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats at %s: %w", b.addr, err)
+	}
+	r := bufio.NewReader(nc)
+
+	nc.SetDeadline(time.Now().Add(10 * time.Second))
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		nc.Close()
+		return fmt.Errorf("unexpected nats greeting %q: %v", strings.TrimSpace(line), err)
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+	if b.useTLS || info.TLSRequired {
+		tlsConn := tls.Client(nc, &tls.Config{ServerName: b.host})
+		if err := tlsConn.Handshake(); err != nil {
+			nc.Close()
+			return fmt.Errorf("nats tls handshake failed: %w", err)
+		}
+		nc = tlsConn
+		r = bufio.NewReader(nc)
+	}
+
+	opts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "pepebot",
+		"lang":     "go",
+		"version":  "1",
+	}
+	if b.user != "" {
+		opts["user"], opts["pass"] = b.user, b.pass
+	}
+	if b.token != "" {
+		opts["auth_token"] = b.token
+	}
+	connectOpts, _ := json.Marshal(opts)
+	w := bufio.NewWriter(nc)
+	fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connectOpts)
+	if err := w.Flush(); err != nil {
+		nc.Close()
+		return err
+	}
+	// The PING is answered after CONNECT is accepted
+	line, err = r.ReadString('\n')
+	if err != nil || strings.HasPrefix(line, "-ERR") {
+		nc.Close()
+		return fmt.Errorf("nats connect failed: %s", strings.TrimSpace(line))
+	}
+	nc.SetDeadline(time.Time{})
+
+	b.mu.Lock()
+	b.conn, b.w = nc, w
+	for sid, sub := range b.subs {
+		fmt.Fprintf(w, "SUB %s %s %d\r\n", sub.subject, sub.subject, sid)
+	}
+	err = w.Flush()
+	b.mu.Unlock()
+	if err != nil {
+		nc.Close()
+		return err
+	}
+
+	go b.readLoop(nc, r)
+	return nil
+}
+
+func (b *natsBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return fmt.Errorf("not connected to nats")
+	}
+	fmt.Fprintf(b.w, "PUB %s %d\r\n", subject, len(data))
+	b.w.Write(data)
+	b.w.WriteString("\r\n")
+	return b.w.Flush()
+}
+
+func (b *natsBroker) Subscribe(ctx context.Context, subject string) (<-chan []byte, error) {
+	b.mu.Lock()
+	b.nextID++
+	sid := b.nextID
+	sub := &natsSub{subject: subject, ctx: ctx, out: make(chan []byte, 64)}
+	b.subs[sid] = sub
+	var err error
+	if b.conn != nil {
+		fmt.Fprintf(b.w, "SUB %s %s %d\r\n", subject, subject, sid)
+		err = b.w.Flush()
+	}
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, sid)
+		if b.conn != nil {
+			fmt.Fprintf(b.w, "UNSUB %d\r\n", sid)
+			b.w.Flush()
+		}
+		b.mu.Unlock()
+		sub.mu.Lock()
+		sub.done = true
+		close(sub.out)
+		sub.mu.Unlock()
+	}()
+	return sub.out, nil
+}
+
+func (b *natsBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+	return nil
+}
+
+// readLoop handles server messages until the connection fails, then
+// reconnects
+func (b *natsBroker) readLoop(nc net.Conn, r *bufio.Reader) {
+	err := b.read(r)
+
+	b.mu.Lock()
+	if b.conn == nc {
+		b.conn = nil
+	}
+	closed := b.closed
+	b.mu.Unlock()
+	nc.Close()
+	if closed {
+		return
+	}
+
+	logger.WarnCF("bus", "NATS connection lost", map[string]interface{}{"error": err.Error()})
+	backoff := time.Second
+	for {
+		time.Sleep(backoff)
+		b.mu.Lock()
+		closed := b.closed
+		b.mu.Unlock()
+		if closed {
+			return
+		}
+		if err := b.connect(); err != nil {
+			logger.WarnCF("bus", "NATS reconnect failed", map[string]interface{}{"error": err.Error()})
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		logger.InfoC("bus", "NATS reconnected")
+		return
+	}
+}
+
+func (b *natsBroker) read(r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "PING":
+			b.mu.Lock()
+			if b.w != nil {
+				b.w.WriteString("PONG\r\n")
+				b.w.Flush()
+			}
+			b.mu.Unlock()
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				return fmt.Errorf("malformed nats message %q", line)
+			}
+			sid, _ := strconv.Atoi(fields[2])
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("malformed nats message %q", line)
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return err
+			}
+			b.deliver(sid, payload[:size])
+		case strings.HasPrefix(line, "-ERR"):
+			logger.WarnCF("bus", "NATS error", map[string]interface{}{"error": strings.TrimPrefix(line, "-ERR ")})
+		}
+	}
+}
+
+func (b *natsBroker) deliver(sid int, data []byte) {
+	b.mu.Lock()
+	sub := b.subs[sid]
+	b.mu.Unlock()
+	if sub == nil {
+		return
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.done {
+		return
+	}
+	select {
+	case sub.out <- data:
+	case <-sub.ctx.Done():
+	}
+}
@@ -0,0 +1,358 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Outbox entry statuses
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// maxDeliveryReceipts bounds delivered.jsonl; it is trimmed to half when exceeded.
+const maxDeliveryReceipts = 1000
+
+// OutboxEntry is a persisted outbound message together with its delivery state
+type OutboxEntry struct {
+	ID          string          `json:"id"`
+	Message     OutboundMessage `json:"message"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	PartsSent   int             `json:"parts_sent,omitempty"` // parts of a split message already delivered
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	NextAttempt time.Time       `json:"next_attempt,omitempty"`
+	DeliveredAt *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Outbox is a persistent outbound queue stored under workspace/outbox:
+//
+//	pending/<id>.json  messages waiting for (re)delivery
+//	dead/<id>.json     messages that exhausted their retries
+//	delivered.jsonl    delivery receipts (most recent last)
+type Outbox struct {
+	dir         string
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	receipts    int             // lines in delivered.jsonl
+	inflight    map[string]bool // entries currently being sent
+	mu          sync.Mutex
+}
+
+func NewOutbox(dir string, maxAttempts int, baseBackoff, maxBackoff time.Duration) (*Outbox, error) {
+	for _, sub := range []string{OutboxPending, OutboxDead} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	o := &Outbox{
+		dir:         dir,
+		maxAttempts: maxAttempts,
+		baseBackoff: baseBackoff,
+		maxBackoff:  maxBackoff,
+		inflight:    make(map[string]bool),
+	}
+	if receipts, err := o.readReceipts(); err == nil {
+		o.receipts = len(receipts)
+	}
+	return o, nil
+}
+
+// Enqueue persists a message as pending. The returned entry is claimed by the
+// caller, which must report the outcome with MarkDelivered or MarkFailed.
+func (o *Outbox) Enqueue(msg OutboundMessage) (*OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	entry := &OutboxEntry{
+		ID:          fmt.Sprintf("%d", now.UnixNano()),
+		Message:     msg,
+		Status:      OutboxPending,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}
+
+	if err := o.writeEntry(OutboxPending, entry); err != nil {
+		return nil, err
+	}
+	o.inflight[entry.ID] = true
+	return entry, nil
+}
+
+// MarkDelivered removes a pending entry and records a delivery receipt
+func (o *Outbox) MarkDelivered(entry *OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	entry.Attempts++
+	entry.Status = OutboxDelivered
+	entry.DeliveredAt = &now
+	entry.LastError = ""
+	entry.NextAttempt = time.Time{}
+	delete(o.inflight, entry.ID)
+
+	os.Remove(o.entryPath(OutboxPending, entry.ID))
+	return o.appendReceipt(entry)
+}
+
+// MarkFailed records a failed attempt. The entry is rescheduled with
+// exponential backoff, or moved to the dead-letter queue once it has used
+// all attempts (or immediately when permanent is set).
+func (o *Outbox) MarkFailed(entry *OutboxEntry, sendErr error, permanent bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry.Attempts++
+	entry.LastError = sendErr.Error()
+	delete(o.inflight, entry.ID)
+
+	if permanent || entry.Attempts >= o.maxAttempts {
+		entry.Status = OutboxDead
+		entry.NextAttempt = time.Time{}
+		os.Remove(o.entryPath(OutboxPending, entry.ID))
+
+		logger.WarnCF("outbox", "Message moved to dead-letter queue", map[string]interface{}{
+			"id":       entry.ID,
+			"channel":  entry.Message.Channel,
+			"chat_id":  entry.Message.ChatID,
+			"attempts": entry.Attempts,
+			"error":    entry.LastError,
+		})
+		return o.writeEntry(OutboxDead, entry)
+	}
+
+	entry.NextAttempt = time.Now().Add(o.backoff(entry.Attempts))
+	return o.writeEntry(OutboxPending, entry)
+}
+
+// backoff returns baseBackoff * 2^(attempts-1), capped at maxBackoff
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.baseBackoff
+	for i := 1; i < attempts && d < o.maxBackoff; i++ {
+		d *= 2
+	}
+	if o.maxBackoff > 0 && d > o.maxBackoff {
+		d = o.maxBackoff
+	}
+	return d
+}
+
+// Due claims and returns pending entries whose next attempt time has passed.
+// Entries already being sent are skipped.
+func (o *Outbox) Due(now time.Time) ([]*OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, err := o.readDir(OutboxPending)
+	if err != nil {
+		return nil, err
+	}
+
+	due := []*OutboxEntry{}
+	for _, entry := range entries {
+		if o.inflight[entry.ID] || entry.NextAttempt.After(now) {
+			continue
+		}
+		o.inflight[entry.ID] = true
+		due = append(due, entry)
+	}
+	return due, nil
+}
+
+// List returns entries with the given status, oldest first
+func (o *Outbox) List(status string) ([]*OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch status {
+	case OutboxPending, OutboxDead:
+		return o.readDir(status)
+	case OutboxDelivered:
+		return o.readReceipts()
+	default:
+		return nil, fmt.Errorf("unknown outbox status '%s'", status)
+	}
+}
+
+// Retry moves a dead-lettered entry back to pending with a fresh attempt budget
+func (o *Outbox) Retry(id string) (*OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry, err := o.readEntry(o.entryPath(OutboxDead, id))
+	if err != nil {
+		return nil, fmt.Errorf("dead-lettered message '%s' not found", id)
+	}
+
+	entry.Status = OutboxPending
+	entry.Attempts = 0
+	entry.NextAttempt = time.Now()
+	if err := o.writeEntry(OutboxPending, entry); err != nil {
+		return nil, err
+	}
+	os.Remove(o.entryPath(OutboxDead, id))
+	return entry, nil
+}
+
+// Delete removes a pending or dead-lettered entry
+func (o *Outbox) Delete(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, status := range []string{OutboxPending, OutboxDead} {
+		if err := os.Remove(o.entryPath(status, id)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("outbox message '%s' not found", id)
+}
+
+// Stats returns the number of entries per status
+func (o *Outbox) Stats() map[string]int {
+	stats := map[string]int{}
+	for _, status := range []string{OutboxPending, OutboxDead, OutboxDelivered} {
+		entries, _ := o.List(status)
+		stats[status] = len(entries)
+	}
+	return stats
+}
+
+func (o *Outbox) entryPath(status, id string) string {
+	return filepath.Join(o.dir, status, filepath.Base(id)+".json")
+}
+
+func (o *Outbox) writeEntry(status string, entry *OutboxEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated entry
+	path := o.entryPath(status, entry.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (o *Outbox) readEntry(path string) (*OutboxEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry OutboxEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (o *Outbox) readDir(status string) ([]*OutboxEntry, error) {
+	files, err := os.ReadDir(filepath.Join(o.dir, status))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*OutboxEntry{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		entry, err := o.readEntry(filepath.Join(o.dir, status, f.Name()))
+		if err != nil {
+			logger.WarnCF("outbox", "Skipping unreadable outbox entry", map[string]interface{}{
+				"file":  f.Name(),
+				"error": err.Error(),
+			})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+func (o *Outbox) receiptsPath() string {
+	return filepath.Join(o.dir, "delivered.jsonl")
+}
+
+func (o *Outbox) readReceipts() ([]*OutboxEntry, error) {
+	f, err := os.Open(o.receiptsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*OutboxEntry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []*OutboxEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry OutboxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, &entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func (o *Outbox) appendReceipt(entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(o.receiptsPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write delivery receipt: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	o.receipts++
+	if o.receipts <= maxDeliveryReceipts {
+		return nil
+	}
+
+	receipts, err := o.readReceipts()
+	if err != nil {
+		return err
+	}
+	o.receipts = maxDeliveryReceipts / 2
+
+	var buf strings.Builder
+	for _, r := range receipts[len(receipts)-maxDeliveryReceipts/2:] {
+		line, _ := json.Marshal(r)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(o.receiptsPath(), []byte(buf.String()), 0644)
+}
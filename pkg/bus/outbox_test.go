@@ -0,0 +1,61 @@
+package bus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOutboxRetryAndDeadLetter(t *testing.T) {
+	ob, err := NewOutbox(t.TempDir(), 2, time.Second, time.Minute)
+	if err != nil {
+		t.Fatalf("NewOutbox: %v", err)
+	}
+
+	entry, err := ob.Enqueue(OutboundMessage{Channel: "telegram", ChatID: "1", Content: "hi"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// Claimed by Enqueue, so not handed out again until the outcome is known
+	if due, _ := ob.Due(time.Now()); len(due) != 0 {
+		t.Fatalf("expected in-flight entry to be skipped, got %d due", len(due))
+	}
+
+	if err := ob.MarkFailed(entry, fmt.Errorf("timeout"), false); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if due, _ := ob.Due(time.Now()); len(due) != 0 {
+		t.Fatalf("expected entry to wait for backoff")
+	}
+
+	due, _ := ob.Due(time.Now().Add(2 * time.Second))
+	if len(due) != 1 || due[0].Attempts != 1 {
+		t.Fatalf("expected one due entry after backoff, got %+v", due)
+	}
+
+	if err := ob.MarkFailed(due[0], fmt.Errorf("timeout"), false); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	stats := ob.Stats()
+	if stats[OutboxPending] != 0 || stats[OutboxDead] != 1 {
+		t.Fatalf("expected entry in dead-letter queue, got %v", stats)
+	}
+
+	retried, err := ob.Retry(entry.ID)
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	due, _ = ob.Due(time.Now())
+	if len(due) != 1 || due[0].ID != retried.ID || due[0].Attempts != 0 {
+		t.Fatalf("expected retried entry to be due, got %+v", due)
+	}
+
+	if err := ob.MarkDelivered(due[0]); err != nil {
+		t.Fatalf("MarkDelivered: %v", err)
+	}
+	delivered, _ := ob.List(OutboxDelivered)
+	if len(delivered) != 1 || delivered[0].DeliveredAt == nil {
+		t.Fatalf("expected delivery receipt, got %+v", delivered)
+	}
+}
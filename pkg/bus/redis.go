@@ -0,0 +1,272 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// redisBroker keeps each subject in a Redis list: RPUSH to publish, BLPOP to
+// consume. Messages wait in the list while no consumer is running.
+type redisBroker struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn *redisConn // publishing connection
+}
+
+func newRedisBroker(u *url.URL) (*redisBroker, error) {
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis broker url must start with redis:// or rediss://")
+	}
+	b := &redisBroker{addr: u.Host, useTLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		b.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		b.username = u.User.Username()
+		b.password, _ = u.User.Password()
+		if _, hasPassword := u.User.Password(); !hasPassword {
+			// redis://secret@host uses the name as the password
+			b.username, b.password = "", u.User.Username()
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+		b.db = n
+	}
+
+	conn, err := b.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	b.conn = conn
+	return b, nil
+}
+
+func (b *redisBroker) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var nc net.Conn
+	var err error
+	if b.useTLS {
+		nc, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", b.addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", b.addr, err)
+	}
+	c := &redisConn{conn: nc, r: bufio.NewReader(nc)}
+
+	if b.password != "" {
+		args := []string{"AUTH", b.password}
+		if b.username != "" {
+			args = []string{"AUTH", b.username, b.password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis auth failed: %w", err)
+		}
+	}
+	if b.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(b.db)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (b *redisBroker) Publish(ctx context.Context, subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// One reconnect when the connection has gone stale
+	for attempt := 0; ; attempt++ {
+		if b.conn == nil {
+			conn, err := b.dial(ctx)
+			if err != nil {
+				return err
+			}
+			b.conn = conn
+		}
+		_, err := b.conn.do("RPUSH", subject, string(data))
+		if err == nil {
+			return nil
+		}
+		var redisErr redisError
+		if errors.As(err, &redisErr) || attempt > 0 {
+			return err
+		}
+		b.conn.Close()
+		b.conn = nil
+	}
+}
+
+func (b *redisBroker) Subscribe(ctx context.Context, subject string) (<-chan []byte, error) {
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		defer func() {
+			if conn != nil {
+				conn.Close()
+			}
+		}()
+		backoff := time.Second
+		for ctx.Err() == nil {
+			if conn == nil {
+				if conn, err = b.dial(ctx); err != nil {
+					logger.WarnCF("bus", "Redis reconnect failed", map[string]interface{}{"error": err.Error()})
+					select {
+					case <-ctx.Done():
+					case <-time.After(backoff):
+					}
+					backoff = min(backoff*2, 30*time.Second)
+					continue
+				}
+				backoff = time.Second
+			}
+
+			// A short block timeout lets the loop notice ctx ending
+			reply, err := conn.do("BLPOP", subject, "1")
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.WarnCF("bus", "Redis subscription error", map[string]interface{}{
+						"subject": subject,
+						"error":   err.Error(),
+					})
+				}
+				conn.Close()
+				conn = nil
+				continue
+			}
+			items, ok := reply.([]interface{})
+			if !ok || len(items) != 2 {
+				continue // timed out
+			}
+			data, _ := items[1].([]byte)
+			select {
+			case out <- data:
+			case <-ctx.Done():
+				// Put it back for another consumer
+				b.Publish(context.Background(), subject, data)
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *redisBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+	return nil
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisConn speaks RESP over one connection
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends a command and reads its reply: string, int64, []byte (nil for
+// a null reply), []interface{} or a redisError
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(a), a)
+	}
+	// BLPOP blocks for up to its timeout; leave room for it
+	c.conn.SetDeadline(time.Now().Add(15 * time.Second))
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(redisError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return []byte(nil), err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected redis reply %q", line)
+}
@@ -1,6 +1,7 @@
 package bus
 
 type InboundMessage struct {
+	ID         string            `json:"id,omitempty"` // journal ID, set when the bus journal is enabled
 	Channel    string            `json:"channel"`
 	SenderID   string            `json:"sender_id"`
 	ChatID     string            `json:"chat_id"`
@@ -15,6 +16,14 @@ type OutboundMessage struct {
 	ChatID  string   `json:"chat_id"`
 	Content string   `json:"content"`
 	Media   []string `json:"media,omitempty"` // URLs or file paths to send as attachments
+	// Progress marks an intermediate status note sent while the agent is
+	// still working; it is not retried and does not end the typing indicator.
+	Progress bool `json:"progress,omitempty"`
+	// SessionKey, Agent and InReplyTo describe the turn an agent reply
+	// answers, so reactions to it can be recorded as feedback
+	SessionKey string `json:"session_key,omitempty"`
+	Agent      string `json:"agent,omitempty"`
+	InReplyTo  string `json:"in_reply_to,omitempty"`
 }
 
 type MessageHandler func(InboundMessage) error
@@ -0,0 +1,157 @@
+package calendar
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// Client talks to a single CalDAV calendar collection
+type Client struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+func NewClient(cfg config.CalendarConfig) *Client {
+	url := cfg.URL
+	if url != "" && !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	return &Client{
+		url:      url,
+		username: cfg.Username,
+		password: cfg.Password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data>
+      <C:expand start="%[1]s" end="%[2]s"/>
+    </C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="%[1]s" end="%[2]s"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+// ListEvents returns events overlapping [from, to), sorted by start time.
+// Recurring events are expanded into instances by the server.
+func (c *Client) ListEvents(ctx context.Context, from, to time.Time) ([]Event, error) {
+	const stamp = "20060102T150405Z"
+	body := fmt.Sprintf(calendarQuery, from.UTC().Format(stamp), to.UTC().Format(stamp))
+
+	req, err := http.NewRequestWithContext(ctx, "REPORT", c.url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	data, err := c.do(req, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("invalid CalDAV response: %w", err)
+	}
+
+	events := []Event{}
+	for _, resp := range ms.Responses {
+		for _, ps := range resp.Propstat {
+			if ps.Prop.Data == "" || (ps.Status != "" && !strings.Contains(ps.Status, " 200 ")) {
+				continue
+			}
+			for _, e := range ParseICS(ps.Prop.Data) {
+				if e.Start.Before(to) && (e.End.After(from) || e.Start.Equal(from)) {
+					events = append(events, e)
+				}
+			}
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+// CreateEvent stores a new event and returns it with its UID
+func (c *Client) CreateEvent(ctx context.Context, e Event) (Event, error) {
+	if e.UID == "" {
+		e.UID = fmt.Sprintf("%d@pepebot", time.Now().UnixNano())
+	}
+	if !e.End.After(e.Start) {
+		return e, fmt.Errorf("event end must be after start")
+	}
+
+	href := c.url + strings.NewReplacer("@", "-", "/", "-").Replace(e.UID) + ".ics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, href, strings.NewReader(EncodeICS(e)))
+	if err != nil {
+		return e, err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("If-None-Match", "*")
+
+	if _, err := c.do(req, http.StatusCreated, http.StatusNoContent, http.StatusOK); err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+func (c *Client) do(req *http.Request, expected ...int) ([]byte, error) {
+	if c.url == "" {
+		return nil, fmt.Errorf("calendar url is not configured")
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("User-Agent", "pepebot")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CalDAV request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("CalDAV %s returned %s", req.Method, resp.Status)
+}
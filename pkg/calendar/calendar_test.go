@@ -0,0 +1,137 @@
+package calendar
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:abc-123\r\n" +
+	"SUMMARY:Team sync\\, weekly\r\n" +
+	"LOCATION:Room 4\r\n" +
+	"DESCRIPTION:Agenda:\\n- roadmap\r\n" +
+	"DTSTART;TZID=UTC:20260310T090000\r\n" +
+	"DURATION:PT30M\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"SUMMARY:not the event title\r\n" +
+	"TRIGGER:-PT10M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"SUMMARY:Public holiday with a very long title that has to be folded across\r\n" +
+	"  lines\r\n" +
+	"DTSTART;VALUE=DATE:20260311\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	events := ParseICS(sampleICS)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	sync := events[0]
+	if sync.Summary != "Team sync, weekly" || sync.Location != "Room 4" || sync.Description != "Agenda:\n- roadmap" {
+		t.Errorf("unexpected text fields: %+v", sync)
+	}
+	if !sync.Start.Equal(time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)) || sync.End.Sub(sync.Start) != 30*time.Minute {
+		t.Errorf("unexpected times: %v - %v", sync.Start, sync.End)
+	}
+
+	holiday := events[1]
+	if !holiday.AllDay || holiday.Summary != "Public holiday with a very long title that has to be folded across lines" {
+		t.Errorf("unexpected all-day event: %+v", holiday)
+	}
+	if holiday.End.Sub(holiday.Start) != 24*time.Hour {
+		t.Errorf("all-day event should default to one day, got %v", holiday.End.Sub(holiday.Start))
+	}
+}
+
+func TestEncodeICSRoundTrip(t *testing.T) {
+	start := time.Date(2026, 4, 1, 14, 0, 0, 0, time.UTC)
+	in := Event{
+		UID:             "round-trip",
+		Summary:         "Call; with, " + strings.Repeat("x", 100),
+		Start:           start,
+		End:             start.Add(time.Hour),
+		ReminderMinutes: 15,
+	}
+	data := EncodeICS(in)
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+	if !strings.Contains(data, "TRIGGER:-PT15M") {
+		t.Errorf("missing alarm:\n%s", data)
+	}
+
+	out := ParseICS(data)
+	if len(out) != 1 || out[0].Summary != in.Summary || !out[0].Start.Equal(in.Start) || !out[0].End.Equal(in.End) {
+		t.Fatalf("round trip mismatch: %+v", out)
+	}
+}
+
+func TestClientListAndCreate(t *testing.T) {
+	var created string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "REPORT":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `start="20260310T000000Z"`) {
+				t.Errorf("unexpected query: %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, `<?xml version="1.0"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/cal/abc-123.ics</D:href>
+    <D:propstat>
+      <D:prop><C:calendar-data>`+strings.ReplaceAll(sampleICS, "&", "&amp;")+`</C:calendar-data></D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`)
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "*" {
+				t.Errorf("expected If-None-Match: *")
+			}
+			created = r.URL.Path
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(config.CalendarConfig{URL: server.URL + "/cal", Username: "me", Password: "secret"})
+
+	from := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	events, err := client.ListEvents(context.Background(), from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].UID != "abc-123" {
+		t.Fatalf("expected only the event inside the range, got %+v", events)
+	}
+
+	event, err := client.CreateEvent(context.Background(), Event{Summary: "Dentist", Start: from, End: from.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if event.UID == "" || !strings.HasPrefix(created, "/cal/") || !strings.HasSuffix(created, ".ics") {
+		t.Errorf("unexpected create: uid=%q path=%q", event.UID, created)
+	}
+}
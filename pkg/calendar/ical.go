@@ -0,0 +1,227 @@
+// Package calendar reads and writes events on a CalDAV calendar.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is a calendar event. All-day events have midnight Start/End in the
+// local timezone and End is exclusive.
+type Event struct {
+	UID             string    `json:"uid"`
+	Summary         string    `json:"summary"`
+	Description     string    `json:"description,omitempty"`
+	Location        string    `json:"location,omitempty"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	AllDay          bool      `json:"all_day,omitempty"`
+	ReminderMinutes int       `json:"reminder_minutes,omitempty"`
+}
+
+// ParseICS extracts the VEVENTs from an iCalendar document
+func ParseICS(data string) []Event {
+	var events []Event
+	var cur *Event
+	depth := 0 // nesting inside the current VEVENT (VALARM etc.)
+
+	for _, line := range unfold(data) {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			cur = &Event{}
+			depth = 0
+			continue
+		case name == "BEGIN" && cur != nil:
+			depth++
+			continue
+		case name == "END" && value == "VEVENT" && cur != nil:
+			if cur.End.IsZero() {
+				if cur.AllDay {
+					cur.End = cur.Start.AddDate(0, 0, 1)
+				} else {
+					cur.End = cur.Start
+				}
+			}
+			events = append(events, *cur)
+			cur = nil
+			continue
+		case name == "END" && cur != nil:
+			depth--
+			continue
+		}
+		if cur == nil || depth > 0 {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			cur.UID = value
+		case "SUMMARY":
+			cur.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			cur.Description = unescapeText(value)
+		case "LOCATION":
+			cur.Location = unescapeText(value)
+		case "DTSTART":
+			cur.Start, cur.AllDay = parseDateTime(value, params)
+		case "DTEND":
+			cur.End, _ = parseDateTime(value, params)
+		case "DURATION":
+			if d, err := parseDuration(value); err == nil && !cur.Start.IsZero() {
+				cur.End = cur.Start.Add(d)
+			}
+		}
+	}
+	return events
+}
+
+// unfold joins continuation lines (RFC 5545 §3.1)
+func unfold(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitProperty splits "NAME;PARAM=x:value"
+func splitProperty(line string) (string, map[string]string, string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+func parseDateTime(value string, params map[string]string) (time.Time, bool) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse("20060102T150405Z", value)
+		return t.Local(), false
+	}
+
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, _ := time.ParseInLocation("20060102T150405", value, loc)
+	return t.Local(), false
+}
+
+// parseDuration handles the common iCalendar durations (e.g. PT1H30M, P1D)
+func parseDuration(value string) (time.Duration, error) {
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	}
+	value = strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	num := 0
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			num = num*10 + int(r-'0')
+		case r == 'T':
+		case r == 'W':
+			total += time.Duration(num) * 7 * 24 * time.Hour
+			num = 0
+		case r == 'D':
+			total += time.Duration(num) * 24 * time.Hour
+			num = 0
+		case r == 'H':
+			total += time.Duration(num) * time.Hour
+			num = 0
+		case r == 'M':
+			total += time.Duration(num) * time.Minute
+			num = 0
+		case r == 'S':
+			total += time.Duration(num) * time.Second
+			num = 0
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	return sign * total, nil
+}
+
+var textUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+var textEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`)
+
+func unescapeText(s string) string { return textUnescaper.Replace(s) }
+
+// EncodeICS renders a single event as an iCalendar document
+func EncodeICS(e Event) string {
+	var b strings.Builder
+	write := func(line string) {
+		// Fold at 75 octets
+		for len(line) > 75 {
+			cut := 75
+			for cut > 0 && !isRuneStart(line[cut]) {
+				cut--
+			}
+			b.WriteString(line[:cut] + "\r\n")
+			line = " " + line[cut:]
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//pepebot//calendar//EN")
+	write("BEGIN:VEVENT")
+	write("UID:" + e.UID)
+	write("DTSTAMP:" + time.Now().UTC().Format("20060102T150405Z"))
+	if e.AllDay {
+		write("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+		write("DTEND;VALUE=DATE:" + e.End.Format("20060102"))
+	} else {
+		write("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+		write("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+	}
+	write("SUMMARY:" + textEscaper.Replace(e.Summary))
+	if e.Description != "" {
+		write("DESCRIPTION:" + textEscaper.Replace(e.Description))
+	}
+	if e.Location != "" {
+		write("LOCATION:" + textEscaper.Replace(e.Location))
+	}
+	if e.ReminderMinutes > 0 {
+		write("BEGIN:VALARM")
+		write("ACTION:DISPLAY")
+		write("DESCRIPTION:" + textEscaper.Replace(e.Summary))
+		write(fmt.Sprintf("TRIGGER:-PT%dM", e.ReminderMinutes))
+		write("END:VALARM")
+	}
+	write("END:VEVENT")
+	write("END:VCALENDAR")
+	return b.String()
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
@@ -3,10 +3,23 @@ package channels
 import (
 	"context"
 	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/pepebot-space/pepebot/pkg/bus"
 )
 
+// Group modes control when the bot answers in group chats:
+//   - mention: only when mentioned, replied to, or addressed with the trigger prefix
+//   - always:  every message in the group
+//   - command: only when the message starts with the trigger prefix
+const (
+	GroupModeMention = "mention"
+	GroupModeAlways  = "always"
+	GroupModeCommand = "command"
+)
+
 type Channel interface {
 	Name() string
 	Start(ctx context.Context) error
@@ -16,12 +29,25 @@ type Channel interface {
 	IsAllowed(senderID string) bool
 }
 
+// TypingIndicator is implemented by channels that can show a "typing…" status
+type TypingIndicator interface {
+	SendTyping(ctx context.Context, chatID string) error
+}
+
+// FeedbackRecorder is told about the replies a channel sends and the
+// reactions users leave on them
+type FeedbackRecorder interface {
+	Sent(msg bus.OutboundMessage, messageID string)
+	Reacted(channel, chatID, messageID, userID, emoji string, added bool)
+}
+
 type BaseChannel struct {
 	config    interface{}
 	bus       *bus.MessageBus
 	running   bool
 	name      string
 	allowList []string
+	feedback  FeedbackRecorder
 }
 
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -79,6 +105,53 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	c.bus.PublishInbound(msg)
 }
 
+// SetFeedback records sent replies and reactions to them with recorder
+func (c *BaseChannel) SetFeedback(recorder FeedbackRecorder) {
+	c.feedback = recorder
+}
+
+// recordSent tells the feedback recorder about a sent message
+func (c *BaseChannel) recordSent(msg bus.OutboundMessage, messageID string) {
+	if c.feedback != nil {
+		c.feedback.Sent(msg, messageID)
+	}
+}
+
+// recordReaction tells the feedback recorder about a reaction from an
+// allowed user
+func (c *BaseChannel) recordReaction(chatID, messageID, userID, emoji string, added bool) {
+	if c.feedback != nil && c.IsAllowed(userID) {
+		c.feedback.Reacted(c.name, chatID, messageID, userID, emoji, added)
+	}
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
+
+// shouldRespondInGroup decides whether a group message should be handled.
+// addressed reports whether the bot was mentioned or replied to. The returned
+// content has the trigger prefix stripped when it was used.
+func shouldRespondInGroup(mode, trigger, content string, addressed bool) (string, bool) {
+	triggered := false
+	if trigger != "" {
+		trimmed := strings.TrimSpace(content)
+		if len(trimmed) >= len(trigger) && strings.EqualFold(trimmed[:len(trigger)], trigger) {
+			rest := trimmed[len(trigger):]
+			// Require a word boundary so "!pepe" does not match "!pepernoot"
+			if r, _ := utf8.DecodeRuneInString(rest); rest == "" || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				content = strings.TrimLeft(rest, " \t\n:,")
+				triggered = true
+			}
+		}
+	}
+
+	switch strings.ToLower(mode) {
+	case GroupModeAlways:
+		return content, true
+	case GroupModeCommand:
+		return content, triggered
+	default:
+		return content, triggered || addressed
+	}
+}
@@ -0,0 +1,40 @@
+package channels
+
+import "testing"
+
+func TestShouldRespondInGroup(t *testing.T) {
+	cases := []struct {
+		name      string
+		mode      string
+		trigger   string
+		content   string
+		addressed bool
+		want      string
+		respond   bool
+	}{
+		{"mention addressed", GroupModeMention, "", "what's the time?", true, "what's the time?", true},
+		{"mention not addressed", GroupModeMention, "", "what's the time?", false, "what's the time?", false},
+		{"empty mode is mention", "", "", "hello", false, "hello", false},
+		{"mention with trigger", GroupModeMention, "!pepe", "!pepe what's the time?", false, "what's the time?", true},
+		{"always not addressed", GroupModeAlways, "", "anyone around?", false, "anyone around?", true},
+		{"always strips trigger", GroupModeAlways, "!pepe", "!pepe: status", false, "status", true},
+		{"command with trigger", GroupModeCommand, "!pepe", "  !pepe, summarize this", false, "summarize this", true},
+		{"command trigger is case-insensitive", GroupModeCommand, "!pepe", "!PEPE help", false, "help", true},
+		{"command trigger alone", GroupModeCommand, "!pepe", "!pepe", false, "", true},
+		{"command ignores mentions", GroupModeCommand, "!pepe", "hey bot", true, "hey bot", false},
+		{"command without trigger", GroupModeCommand, "!pepe", "just chatting", false, "just chatting", false},
+		{"trigger needs a word boundary", GroupModeCommand, "!pepe", "!pepernoot is tasty", false, "!pepernoot is tasty", false},
+		{"trigger followed by a digit", GroupModeCommand, "!pepe", "!pepe2 go", false, "!pepe2 go", false},
+		{"trigger in the middle", GroupModeCommand, "!pepe", "ask !pepe later", false, "ask !pepe later", false},
+		{"boundary miss still answers mentions", GroupModeMention, "!pepe", "!pepernoot", true, "!pepernoot", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, respond := shouldRespondInGroup(c.mode, c.trigger, c.content, c.addressed)
+			if got != c.want || respond != c.respond {
+				t.Errorf("shouldRespondInGroup(%q, %q, %q, %v) = %q, %v; want %q, %v",
+					c.mode, c.trigger, c.content, c.addressed, got, respond, c.want, c.respond)
+			}
+		})
+	}
+}
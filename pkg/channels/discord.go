@@ -15,6 +15,7 @@ import (
 	"github.com/pepebot-space/pepebot/pkg/bus"
 	"github.com/pepebot-space/pepebot/pkg/config"
 	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/voice"
 )
 
 type DiscordChannel struct {
@@ -23,6 +24,9 @@ type DiscordChannel struct {
 	config         config.DiscordConfig
 	typingChannels map[string]chan bool
 	typingMutex    sync.RWMutex
+	transcriber    *voice.GroqTranscriber
+	voiceSessions  map[string]*discordVoiceSession // guildID -> session
+	voiceMu        sync.Mutex
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -38,6 +42,7 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 		session:        session,
 		config:         cfg,
 		typingChannels: make(map[string]chan bool),
+		voiceSessions:  make(map[string]*discordVoiceSession),
 	}, nil
 }
 
@@ -45,6 +50,12 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	logger.InfoC("discord", "Starting Discord bot")
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+		c.handleReaction(s, r.MessageReaction, true)
+	})
+	c.session.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+		c.handleReaction(s, r.MessageReaction, false)
+	})
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -66,6 +77,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 
 func (c *DiscordChannel) Stop(ctx context.Context) error {
 	logger.InfoC("discord", "Stopping Discord bot")
+	c.LeaveVoice("")
 	c.setRunning(false)
 
 	if err := c.session.Close(); err != nil {
@@ -85,6 +97,11 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("channel ID is empty")
 	}
 
+	if msg.Progress {
+		_, err := c.session.ChannelMessageSend(channelID, msg.Content)
+		return err
+	}
+
 	// Stop typing indicator since we're about to send the response
 	c.stopTyping(channelID)
 
@@ -96,42 +113,40 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	}
 
 	// Discord has a 2000 character limit per message
-	const maxLength = 2000
+	formatted := FormatMessage(message, outputFormatFor(c.Name()))
+	defer formatted.Cleanup()
 
-	// If message is short enough, send it directly
-	if len(message) <= maxLength {
-		if _, err := c.session.ChannelMessageSend(channelID, message); err != nil {
-			return fmt.Errorf("failed to send discord message: %w", err)
-		}
-		return nil
+	if len(formatted.Parts) > 1 {
+		logger.DebugCF("discord", "Splitting long message", map[string]interface{}{
+			"original_length": len(message),
+			"parts":           len(formatted.Parts),
+		})
 	}
 
-	// Split long message into multiple parts
-	parts := splitMessage(message, maxLength)
-
-	logger.DebugCF("discord", "Splitting long message", map[string]interface{}{
-		"original_length": len(message),
-		"parts":           len(parts),
-	})
-
-	// Send each part
-	for i, part := range parts {
-		// Add part indicator if multiple parts
-		// if len(parts) > 1 {
-		// 	partHeader := fmt.Sprintf("*[Part %d/%d]*\n", i+1, len(parts))
-		// 	part = partHeader + part
-		// }
-
-		if _, err := c.session.ChannelMessageSend(channelID, part); err != nil {
-			return fmt.Errorf("failed to send discord message part %d: %w", i+1, err)
+	skip := sentParts(ctx)
+	for i, part := range formatted.Parts {
+		if i < skip {
+			continue
 		}
+		sent, err := c.session.ChannelMessageSend(channelID, part.Text)
+		if err != nil {
+			return partialSend(i, fmt.Errorf("failed to send discord message part %d: %w", i+1, err))
+		}
+		c.recordSent(msg, sent.ID)
 
 		// Small delay between messages to avoid rate limiting
-		if i < len(parts)-1 {
+		if i < len(formatted.Parts)-1 {
 			time.Sleep(500 * time.Millisecond)
 		}
 	}
 
+	// Long code blocks are attached as files
+	if len(formatted.Files) > 0 {
+		if err := c.sendWithMedia(channelID, "", formatted.Files); err != nil {
+			return partialSend(len(formatted.Parts), err)
+		}
+	}
+
 	return nil
 }
 
@@ -148,17 +163,18 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	// Check if this is a DM (direct message)
 	isDM := m.GuildID == ""
 
-	// In groups, only respond if:
-	// 1. Bot is mentioned
-	// 2. Message is a reply to bot's message
+	// In groups, respond according to group_mode:
+	// mention (default) - bot is mentioned, replied to, or trigger prefix is used
+	// always            - every message
+	// command           - only messages starting with the trigger prefix
+	text := m.Content
 	if !isDM {
-		isMentioned := false
-		isReplyToBot := false
+		addressed := false
 
 		// Check if bot is mentioned
 		for _, mention := range m.Mentions {
 			if mention.ID == s.State.User.ID {
-				isMentioned = true
+				addressed = true
 				break
 			}
 		}
@@ -166,16 +182,18 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		// Check if message is a reply to bot
 		if m.ReferencedMessage != nil && m.ReferencedMessage.Author != nil {
 			if m.ReferencedMessage.Author.ID == s.State.User.ID {
-				isReplyToBot = true
+				addressed = true
 			}
 		}
 
-		// Ignore message if bot is not mentioned or replied to
-		if !isMentioned && !isReplyToBot {
-			logger.DebugCF("discord", "Ignoring message (not mentioned or replied)", map[string]interface{}{
+		var ok bool
+		text, ok = shouldRespondInGroup(c.config.GroupMode, c.config.GroupTrigger, removeMention(text, s.State.User.ID), addressed)
+		if !ok {
+			logger.DebugCF("discord", "Ignoring group message", map[string]interface{}{
 				"sender":     m.Author.Username,
 				"channel_id": m.ChannelID,
 				"guild_id":   m.GuildID,
+				"group_mode": c.config.GroupMode,
 			})
 			return
 		}
@@ -203,7 +221,7 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		senderName += "#" + m.Author.Discriminator
 	}
 
-	content := m.Content
+	content := text
 	mediaPaths := []string{}
 
 	// Remove bot mention from content if present
@@ -288,44 +306,20 @@ func removeMention(content string, botID string) string {
 	return content
 }
 
-// splitMessage splits a long message into chunks that fit Discord's character limit
-func splitMessage(message string, maxLength int) []string {
-	// Reserve space for part indicator like "[Part 1/3]\n"
-	effectiveMaxLength := maxLength - 20
-
-	if len(message) <= effectiveMaxLength {
-		return []string{message}
+// handleReaction records a reaction added to or removed from a message
+func (c *DiscordChannel) handleReaction(s *discordgo.Session, r *discordgo.MessageReaction, added bool) {
+	if r == nil || r.UserID == s.State.User.ID {
+		return
 	}
+	c.recordReaction(r.ChannelID, r.MessageID, r.UserID, r.Emoji.Name, added)
+}
 
-	var parts []string
-	remaining := message
-
-	for len(remaining) > 0 {
-		if len(remaining) <= effectiveMaxLength {
-			parts = append(parts, remaining)
-			break
-		}
-
-		// Find a good split point
-		splitPoint := effectiveMaxLength
-		chunk := remaining[:splitPoint]
-
-		// Try to split at a newline
-		if lastNewline := strings.LastIndex(chunk, "\n"); lastNewline > effectiveMaxLength/2 {
-			splitPoint = lastNewline + 1
-		} else if lastSpace := strings.LastIndex(chunk, " "); lastSpace > effectiveMaxLength/2 {
-			// Try to split at a space
-			splitPoint = lastSpace + 1
-		} else if lastPeriod := strings.LastIndex(chunk, ". "); lastPeriod > effectiveMaxLength/2 {
-			// Try to split at a sentence
-			splitPoint = lastPeriod + 2
-		}
-
-		parts = append(parts, strings.TrimSpace(remaining[:splitPoint]))
-		remaining = strings.TrimSpace(remaining[splitPoint:])
+// SendTyping shows the typing indicator in a channel (lasts ~10 seconds)
+func (c *DiscordChannel) SendTyping(ctx context.Context, chatID string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("discord bot not running")
 	}
-
-	return parts
+	return c.session.ChannelTyping(chatID)
 }
 
 // keepTyping continuously sends typing indicator to Discord channel
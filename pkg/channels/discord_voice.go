@@ -0,0 +1,288 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/voice"
+)
+
+// Voice modes for Discord voice sessions
+const (
+	DiscordVoiceTranscribe = "transcribe" // post live transcripts to the text channel
+	DiscordVoiceRespond    = "respond"    // treat each utterance as a prompt to the agent
+)
+
+const (
+	voiceSilenceGap    = 800 * time.Millisecond
+	voiceMinPackets    = 15   // ~300ms, shorter utterances are ignored
+	voiceMaxPackets    = 1500 // ~30s, longer utterances are flushed in chunks
+	voiceTranscribeTTL = 30 * time.Second
+)
+
+type discordVoiceSession struct {
+	conn          *discordgo.VoiceConnection
+	guildID       string
+	channelID     string
+	textChannelID string
+	mode          string
+	users         map[uint32]string // SSRC -> user ID
+	mu            sync.Mutex
+	stop          chan struct{}
+}
+
+type voiceUtterance struct {
+	packets  [][]byte
+	lastSeen time.Time
+}
+
+func (s *discordVoiceSession) setUser(ssrc uint32, userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[ssrc] = userID
+}
+
+func (s *discordVoiceSession) user(ssrc uint32) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[ssrc]
+}
+
+// SetTranscriber attaches the transcriber used for voice channel audio
+func (c *DiscordChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+	c.transcriber = transcriber
+}
+
+// JoinVoice connects to a voice channel and transcribes what is said there.
+// Transcripts (or agent replies in respond mode) are posted to textChannelID.
+// Returns the guild ID of the joined channel.
+func (c *DiscordChannel) JoinVoice(channelID, textChannelID, mode string) (string, error) {
+	if !c.IsRunning() {
+		return "", fmt.Errorf("discord bot not running")
+	}
+	if c.transcriber == nil || !c.transcriber.IsAvailable() {
+		return "", fmt.Errorf("voice transcription requires providers.groq.api_key")
+	}
+	if mode == "" {
+		mode = DiscordVoiceRespond
+	}
+	if mode != DiscordVoiceRespond && mode != DiscordVoiceTranscribe {
+		return "", fmt.Errorf("invalid voice mode %q (use %s or %s)", mode, DiscordVoiceTranscribe, DiscordVoiceRespond)
+	}
+
+	ch, err := c.session.State.Channel(channelID)
+	if err != nil {
+		ch, err = c.session.Channel(channelID)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve voice channel: %w", err)
+		}
+	}
+	if ch.Type != discordgo.ChannelTypeGuildVoice && ch.Type != discordgo.ChannelTypeGuildStageVoice {
+		return "", fmt.Errorf("channel %s is not a voice channel", channelID)
+	}
+
+	// Only one voice connection per guild is allowed by Discord
+	c.leaveVoiceSession(ch.GuildID)
+
+	conn, err := c.session.ChannelVoiceJoin(ch.GuildID, channelID, true, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to join voice channel: %w", err)
+	}
+
+	sess := &discordVoiceSession{
+		conn:          conn,
+		guildID:       ch.GuildID,
+		channelID:     channelID,
+		textChannelID: textChannelID,
+		mode:          mode,
+		users:         make(map[uint32]string),
+		stop:          make(chan struct{}),
+	}
+
+	conn.AddHandler(func(_ *discordgo.VoiceConnection, vs *discordgo.VoiceSpeakingUpdate) {
+		sess.setUser(uint32(vs.SSRC), vs.UserID)
+	})
+
+	c.voiceMu.Lock()
+	c.voiceSessions[ch.GuildID] = sess
+	c.voiceMu.Unlock()
+
+	go c.receiveVoice(sess)
+
+	logger.InfoCF("discord", "Joined voice channel", map[string]interface{}{
+		"guild_id":        ch.GuildID,
+		"channel_id":      channelID,
+		"text_channel_id": textChannelID,
+		"mode":            mode,
+	})
+
+	return ch.GuildID, nil
+}
+
+// LeaveVoice disconnects from the voice channel in a guild, or from all
+// voice channels when guildID is empty. Returns the number of sessions closed.
+func (c *DiscordChannel) LeaveVoice(guildID string) (int, error) {
+	if guildID != "" {
+		if !c.leaveVoiceSession(guildID) {
+			return 0, fmt.Errorf("not connected to a voice channel in guild %s", guildID)
+		}
+		return 1, nil
+	}
+
+	c.voiceMu.Lock()
+	guildIDs := make([]string, 0, len(c.voiceSessions))
+	for id := range c.voiceSessions {
+		guildIDs = append(guildIDs, id)
+	}
+	c.voiceMu.Unlock()
+
+	for _, id := range guildIDs {
+		c.leaveVoiceSession(id)
+	}
+	return len(guildIDs), nil
+}
+
+func (c *DiscordChannel) leaveVoiceSession(guildID string) bool {
+	c.voiceMu.Lock()
+	sess, ok := c.voiceSessions[guildID]
+	delete(c.voiceSessions, guildID)
+	c.voiceMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	close(sess.stop)
+	if err := sess.conn.Disconnect(); err != nil {
+		logger.DebugCF("discord", "Voice disconnect failed", map[string]interface{}{
+			"guild_id": guildID,
+			"error":    err.Error(),
+		})
+	}
+
+	logger.InfoCF("discord", "Left voice channel", map[string]interface{}{
+		"guild_id":   guildID,
+		"channel_id": sess.channelID,
+	})
+	return true
+}
+
+// receiveVoice collects Opus packets per speaker and flushes an utterance
+// once the speaker has been silent for voiceSilenceGap.
+func (c *DiscordChannel) receiveVoice(sess *discordVoiceSession) {
+	utterances := make(map[uint32]*voiceUtterance)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sess.stop:
+			return
+		case pkt, ok := <-sess.conn.OpusRecv:
+			if !ok || pkt == nil {
+				return
+			}
+			u, exists := utterances[pkt.SSRC]
+			if !exists {
+				u = &voiceUtterance{}
+				utterances[pkt.SSRC] = u
+			}
+			u.packets = append(u.packets, pkt.Opus)
+			u.lastSeen = time.Now()
+
+			if len(u.packets) >= voiceMaxPackets {
+				go c.handleUtterance(sess, pkt.SSRC, u.packets)
+				delete(utterances, pkt.SSRC)
+			}
+		case <-ticker.C:
+			for ssrc, u := range utterances {
+				if time.Since(u.lastSeen) >= voiceSilenceGap {
+					go c.handleUtterance(sess, ssrc, u.packets)
+					delete(utterances, ssrc)
+				}
+			}
+		}
+	}
+}
+
+func (c *DiscordChannel) handleUtterance(sess *discordVoiceSession, ssrc uint32, packets [][]byte) {
+	if len(packets) < voiceMinPackets {
+		return
+	}
+
+	mediaDir := filepath.Join(os.TempDir(), "pepebot_media")
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		logger.WarnCF("discord", "Failed to create media dir", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	audioPath := filepath.Join(mediaDir, fmt.Sprintf("discord_voice_%s_%d_%d.ogg", sess.guildID, ssrc, time.Now().UnixNano()))
+	if err := voice.WriteOggOpus(audioPath, packets, 2); err != nil {
+		logger.WarnCF("discord", "Failed to write voice audio", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer os.Remove(audioPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), voiceTranscribeTTL)
+	defer cancel()
+
+	result, err := c.transcriber.Transcribe(ctx, audioPath)
+	if err != nil {
+		logger.WarnCF("discord", "Voice transcription failed", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	text := strings.TrimSpace(result.Text)
+	if text == "" {
+		return
+	}
+
+	userID := sess.user(ssrc)
+
+	logger.DebugCF("discord", "Voice transcribed", map[string]interface{}{
+		"guild_id": sess.guildID,
+		"user_id":  userID,
+		"preview":  truncateString(text, 50),
+	})
+
+	if sess.textChannelID == "" {
+		return
+	}
+
+	if sess.mode == DiscordVoiceTranscribe {
+		speaker := "someone"
+		if userID != "" {
+			speaker = "<@" + userID + ">"
+		}
+		if _, err := c.session.ChannelMessageSend(sess.textChannelID, fmt.Sprintf("🎙️ %s: %s", speaker, text)); err != nil {
+			logger.WarnCF("discord", "Failed to post transcript", map[string]interface{}{"error": err.Error()})
+		}
+		return
+	}
+
+	if userID == "" {
+		return
+	}
+
+	stopTyping := make(chan bool, 1)
+	go c.keepTyping(c.session, sess.textChannelID, stopTyping)
+	c.storeTypingChannel(sess.textChannelID, stopTyping)
+
+	metadata := map[string]string{
+		"user_id":          userID,
+		"guild_id":         sess.guildID,
+		"channel_id":       sess.textChannelID,
+		"voice_channel_id": sess.channelID,
+		"is_dm":            "false",
+		"source":           "voice",
+	}
+
+	c.HandleMessage(userID, sess.textChannelID, fmt.Sprintf("[voice transcription: %s]", text), nil, metadata)
+}
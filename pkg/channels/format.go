@@ -0,0 +1,361 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Markup dialects understood by channels
+const (
+	MarkupMarkdown     = "markdown"      // sent as-is (Discord)
+	MarkupTelegramHTML = "telegram_html" // Telegram HTML parse mode
+	MarkupWhatsApp     = "whatsapp"      // *bold* _italic_ ~strike~ ```mono```
+	MarkupPlain        = "plain"
+)
+
+// OutputFormat describes how a channel renders and limits outgoing text
+type OutputFormat struct {
+	MaxLength    int    // maximum characters per message after rendering
+	Markup       string // one of the Markup* constants
+	MaxCodeLines int    // longer code blocks are sent as file attachments (0 = never)
+}
+
+var outputFormats = map[string]OutputFormat{
+	"telegram": {MaxLength: 4096, Markup: MarkupTelegramHTML, MaxCodeLines: 40},
+	"discord":  {MaxLength: 2000, Markup: MarkupMarkdown, MaxCodeLines: 40},
+	"whatsapp": {MaxLength: 4000, Markup: MarkupWhatsApp, MaxCodeLines: 40},
+}
+
+// outputFormatFor returns the output format for a channel, falling back to
+// unlimited plain markdown
+func outputFormatFor(channel string) OutputFormat {
+	if f, ok := outputFormats[channel]; ok {
+		return f
+	}
+	return OutputFormat{Markup: MarkupMarkdown}
+}
+
+// MessagePart is one rendered message together with the markdown it came
+// from, so channels can fall back to plain text if rendering is rejected.
+type MessagePart struct {
+	Text   string
+	Source string
+}
+
+// FormattedMessage is an agent response adapted to a channel: split into
+// parts that fit the length limit, with long code blocks moved to files.
+type FormattedMessage struct {
+	Parts   []MessagePart
+	Files   []string
+	tempDir string
+}
+
+// Cleanup removes the temporary code block files
+func (m *FormattedMessage) Cleanup() {
+	if m.tempDir != "" {
+		os.RemoveAll(m.tempDir)
+	}
+}
+
+// PartialSendError reports a split message of which only the first Sent
+// parts were delivered. The code block files count as one more part.
+type PartialSendError struct {
+	Sent int
+	Err  error
+}
+
+func (e *PartialSendError) Error() string {
+	return fmt.Sprintf("sent %d part(s), then: %v", e.Sent, e.Err)
+}
+
+func (e *PartialSendError) Unwrap() error {
+	return e.Err
+}
+
+// partialSend wraps the error of part i, once earlier parts went out
+func partialSend(i int, err error) error {
+	if i == 0 {
+		return err
+	}
+	return &PartialSendError{Sent: i, Err: err}
+}
+
+type sentPartsKey struct{}
+
+// withSentParts makes Send skip the first n parts of a split message,
+// which an earlier attempt already delivered
+func withSentParts(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, sentPartsKey{}, n)
+}
+
+// sentParts returns how many parts of the message to skip
+func sentParts(ctx context.Context) int {
+	n, _ := ctx.Value(sentPartsKey{}).(int)
+	return n
+}
+
+// FormatMessage converts markdown content for a channel
+func FormatMessage(content string, format OutputFormat) *FormattedMessage {
+	msg := &FormattedMessage{}
+
+	if format.MaxCodeLines > 0 {
+		content = msg.extractLongCode(content, format.MaxCodeLines)
+	}
+
+	if format.MaxLength <= 0 {
+		msg.Parts = []MessagePart{{Text: renderMarkup(content, format.Markup), Source: content}}
+		return msg
+	}
+
+	// Rendering can grow the text (HTML tags and escapes), so shrink the
+	// split budget until every rendered part fits
+	for budget := format.MaxLength; ; budget = budget * 3 / 4 {
+		msg.Parts = msg.Parts[:0]
+		fits := true
+		for _, chunk := range splitMarkdown(content, budget) {
+			text := renderMarkup(chunk, format.Markup)
+			if utf8.RuneCountInString(text) > format.MaxLength {
+				fits = false
+			}
+			msg.Parts = append(msg.Parts, MessagePart{Text: text, Source: chunk})
+		}
+		if fits || budget < 256 {
+			return msg
+		}
+	}
+}
+
+var fencedCodePattern = regexp.MustCompile("(?s)```([\\w+#.-]*)[ \\t]*\\n(.*?)```")
+
+// extractLongCode writes code blocks longer than maxLines to temp files and
+// replaces them with a short reference
+func (m *FormattedMessage) extractLongCode(content string, maxLines int) string {
+	return fencedCodePattern.ReplaceAllStringFunc(content, func(block string) string {
+		match := fencedCodePattern.FindStringSubmatch(block)
+		lang, code := match[1], match[2]
+		lines := strings.Count(strings.TrimRight(code, "\n"), "\n") + 1
+		if lines <= maxLines {
+			return block
+		}
+
+		if m.tempDir == "" {
+			dir, err := os.MkdirTemp("", "pepebot-snippets-")
+			if err != nil {
+				return block
+			}
+			m.tempDir = dir
+		}
+
+		name := fmt.Sprintf("snippet-%d%s", len(m.Files)+1, codeFileExt(lang))
+		path := filepath.Join(m.tempDir, name)
+		if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+			logger.WarnCF("channels", "Failed to attach code block", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return block
+		}
+		m.Files = append(m.Files, path)
+
+		return fmt.Sprintf("📎 %s (%d lines, attached)", name, lines)
+	})
+}
+
+func codeFileExt(lang string) string {
+	switch strings.ToLower(lang) {
+	case "go", "golang":
+		return ".go"
+	case "py", "python":
+		return ".py"
+	case "js", "javascript":
+		return ".js"
+	case "ts", "typescript":
+		return ".ts"
+	case "json":
+		return ".json"
+	case "sh", "bash", "shell", "zsh":
+		return ".sh"
+	case "yaml", "yml":
+		return ".yaml"
+	case "html":
+		return ".html"
+	case "css":
+		return ".css"
+	case "sql":
+		return ".sql"
+	case "java":
+		return ".java"
+	case "kotlin", "kt":
+		return ".kt"
+	case "rust", "rs":
+		return ".rs"
+	case "c", "cpp", "c++", "h":
+		return ".c"
+	case "xml":
+		return ".xml"
+	case "md", "markdown":
+		return ".md"
+	default:
+		return ".txt"
+	}
+}
+
+// splitMarkdown splits text into chunks of at most limit bytes, preferring
+// paragraph breaks, then line breaks, then spaces. A chunk that ends inside
+// a fenced code block closes the fence and the next chunk reopens it.
+func splitMarkdown(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if len(text) <= limit {
+		return []string{text}
+	}
+
+	const fenceReserve = 8 // room for a closing "\n```"
+	lineLimit := limit - fenceReserve - 64
+
+	var (
+		parts     []string
+		cur       strings.Builder
+		fence     string // opening line of the currently open code block
+		lastBreak int    // offset in cur just after the last paragraph break
+	)
+
+	flush := func() {
+		if lastBreak > limit/2 && fence == "" {
+			// Split at the last paragraph break and carry the rest over
+			s := cur.String()
+			parts = append(parts, strings.TrimSpace(s[:lastBreak]))
+			cur.Reset()
+			cur.WriteString(strings.TrimLeft(s[lastBreak:], "\n"))
+			lastBreak = 0
+			return
+		}
+
+		part := strings.TrimRight(cur.String(), "\n")
+		if fence != "" {
+			part += "\n```"
+		}
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, strings.TrimSpace(part))
+		}
+		cur.Reset()
+		lastBreak = 0
+		if fence != "" {
+			cur.WriteString(fence + "\n")
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > lineLimit {
+			cut := safeCut(line, lineLimit)
+			if cur.Len() > 0 {
+				lastBreak = 0
+				flush()
+			}
+			cur.WriteString(line[:cut])
+			flush()
+			line = line[cut:]
+		}
+
+		if cur.Len()+len(line)+fenceReserve > limit {
+			flush()
+			// The carried-over paragraph may still leave too little room
+			if cur.Len()+len(line)+fenceReserve > limit {
+				flush()
+			}
+		}
+		cur.WriteString(line)
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			if fence == "" {
+				fence = trimmed
+			} else {
+				fence = ""
+			}
+		case trimmed == "" && fence == "":
+			lastBreak = cur.Len()
+		}
+	}
+
+	if last := strings.TrimSpace(cur.String()); last != "" && last != fence {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// safeCut returns a cut offset <= max that falls on a space if possible and
+// never splits a UTF-8 sequence
+func safeCut(s string, max int) int {
+	if i := strings.LastIndex(s[:max], " "); i > max/2 {
+		return i + 1
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return max
+}
+
+// renderMarkup converts markdown into the channel's markup dialect
+func renderMarkup(text, markup string) string {
+	switch markup {
+	case MarkupTelegramHTML:
+		return markdownToTelegramHTML(text)
+	case MarkupWhatsApp:
+		return markdownToWhatsApp(text)
+	default:
+		return text
+	}
+}
+
+var (
+	mdHeadingPattern    = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+	mdListPattern       = regexp.MustCompile(`(?m)^([ \t]*)[-*]\s+`)
+	mdLinkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	mdBoldStarPattern   = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdBoldUnderPattern  = regexp.MustCompile(`__(.+?)__`)
+	mdItalicStarPattern = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mdStrikePattern     = regexp.MustCompile(`~~(.+?)~~`)
+)
+
+// markdownToWhatsApp converts markdown to WhatsApp formatting. Code blocks
+// and inline code are kept verbatim.
+func markdownToWhatsApp(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	codeBlocks := extractCodeBlocks(text)
+	text = codeBlocks.text
+
+	inlineCodes := extractInlineCodes(text)
+	text = inlineCodes.text
+
+	text = mdListPattern.ReplaceAllString(text, "$1• ")
+	text = mdHeadingPattern.ReplaceAllString(text, "\x00B$1\x00B")
+	text = mdBoldStarPattern.ReplaceAllString(text, "\x00B$1\x00B")
+	text = mdBoldUnderPattern.ReplaceAllString(text, "\x00B$1\x00B")
+	text = mdItalicStarPattern.ReplaceAllString(text, "_${1}_")
+	text = strings.ReplaceAll(text, "\x00B", "*")
+	text = mdStrikePattern.ReplaceAllString(text, "~$1~")
+	text = mdLinkPattern.ReplaceAllString(text, "$1 ($2)")
+
+	for i, code := range inlineCodes.codes {
+		text = strings.ReplaceAll(text, fmt.Sprintf("\x00IC%d\x00", i), "`"+code+"`")
+	}
+
+	for i, code := range codeBlocks.codes {
+		text = strings.ReplaceAll(text, fmt.Sprintf("\x00CB%d\x00", i), "```"+strings.TrimRight(code, "\n")+"```")
+	}
+
+	return text
+}
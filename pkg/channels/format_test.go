@@ -0,0 +1,75 @@
+package channels
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitMarkdownKeepsCodeFencesBalanced(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("Intro paragraph.\n\n```go\n")
+	for i := 0; i < 60; i++ {
+		b.WriteString("fmt.Println(\"line of code number\", i)\n")
+	}
+	b.WriteString("```\n\nOutro paragraph.")
+
+	parts := splitMarkdown(b.String(), 500)
+	if len(parts) < 2 {
+		t.Fatalf("expected message to be split, got %d part(s)", len(parts))
+	}
+	for i, part := range parts {
+		if len(part) > 500 {
+			t.Fatalf("part %d exceeds limit: %d bytes", i, len(part))
+		}
+		if strings.Count(part, "```")%2 != 0 {
+			t.Fatalf("part %d has an unbalanced code fence:\n%s", i, part)
+		}
+	}
+	if !strings.HasPrefix(parts[1], "```go") {
+		t.Fatalf("expected continuation to reopen the fence, got %q", parts[1][:20])
+	}
+}
+
+func TestSplitMarkdownPrefersParagraphs(t *testing.T) {
+	para := strings.Repeat("word ", 70) // 350 bytes
+	parts := splitMarkdown(para+"\n\n"+para+"\n\n"+para, 800)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	if !strings.HasSuffix(parts[0], strings.TrimSpace(para)) {
+		t.Fatalf("expected split at a paragraph break")
+	}
+}
+
+func TestMarkdownToWhatsApp(t *testing.T) {
+	in := "# Title\n**bold** and *italic* and ~~gone~~\n- item\nSee [docs](https://example.com) `x**y**`"
+	want := "*Title*\n*bold* and _italic_ and ~gone~\n• item\nSee docs (https://example.com) `x**y**`"
+	if got := markdownToWhatsApp(in); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownToTelegramHTMLMultipleCodeBlocks(t *testing.T) {
+	got := markdownToTelegramHTML("```\nfirst\n```\n\n```\nsecond\n```")
+	if !strings.Contains(got, "first") || !strings.Contains(got, "second") {
+		t.Fatalf("code blocks were not preserved: %q", got)
+	}
+}
+
+func TestFormatMessageAttachesLongCode(t *testing.T) {
+	code := strings.Repeat("print('hi')\n", 50)
+	msg := FormatMessage("Here you go:\n```python\n"+code+"```", outputFormatFor("telegram"))
+	defer msg.Cleanup()
+
+	if len(msg.Files) != 1 || !strings.HasSuffix(msg.Files[0], ".py") {
+		t.Fatalf("expected one .py attachment, got %v", msg.Files)
+	}
+	data, err := os.ReadFile(msg.Files[0])
+	if err != nil || string(data) != code {
+		t.Fatalf("attachment content mismatch: %v", err)
+	}
+	if len(msg.Parts) != 1 || !strings.Contains(msg.Parts[0].Text, "snippet-1.py") {
+		t.Fatalf("expected reference to attachment, got %+v", msg.Parts)
+	}
+}
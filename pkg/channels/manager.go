@@ -8,12 +8,15 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/pepebot-space/pepebot/pkg/bus"
 	"github.com/pepebot-space/pepebot/pkg/config"
 	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/middleware"
 )
 
 type Manager struct {
@@ -21,6 +24,8 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	outbox       *bus.Outbox
+	middleware   *middleware.Chain // pre_send middleware
 	mu           sync.RWMutex
 }
 
@@ -42,6 +47,30 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 	return m, nil
 }
 
+// SetOutbox routes outbound messages through a persistent queue with retries.
+// Must be called before StartAll.
+func (m *Manager) SetOutbox(outbox *bus.Outbox) {
+	m.outbox = outbox
+}
+
+// SetFeedback records the replies of channels that support reactions and
+// the reactions users leave on them
+func (m *Manager) SetFeedback(recorder FeedbackRecorder) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, channel := range m.channels {
+		if c, ok := channel.(interface{ SetFeedback(FeedbackRecorder) }); ok {
+			c.SetFeedback(recorder)
+		}
+	}
+}
+
+// SetMiddleware runs chain's pre_send middleware on outbound messages.
+// Must be called before StartAll.
+func (m *Manager) SetMiddleware(chain *middleware.Chain) {
+	m.middleware = chain
+}
+
 func (m *Manager) initChannels() error {
 	logger.InfoC("channels", "Initializing channel manager")
 
@@ -119,6 +148,9 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	m.dispatchTask = &asyncTask{cancel: cancel}
 
 	go m.dispatchOutbound(dispatchCtx)
+	if m.outbox != nil {
+		go m.retryOutbox(dispatchCtx)
+	}
 
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Starting channel", map[string]interface{}{
@@ -177,6 +209,31 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			if !msg.Progress {
+				var err error
+				if msg, err = m.middleware.BeforeSend(ctx, msg); err != nil {
+					logger.WarnCF("channels", "Outbound message dropped", map[string]interface{}{
+						"channel": msg.Channel,
+						"chat_id": msg.ChatID,
+						"error":   err.Error(),
+					})
+					continue
+				}
+			}
+
+			// Progress notes are ephemeral and never go through the outbox
+			if m.outbox != nil && !msg.Progress {
+				entry, err := m.outbox.Enqueue(msg)
+				if err == nil {
+					m.deliver(ctx, entry)
+					continue
+				}
+				logger.ErrorCF("channels", "Failed to persist outbound message, sending directly", map[string]interface{}{
+					"channel": msg.Channel,
+					"error":   err.Error(),
+				})
+			}
+
 			m.mu.RLock()
 			channel, exists := m.channels[msg.Channel]
 			m.mu.RUnlock()
@@ -198,6 +255,61 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 	}
 }
 
+// deliver sends a claimed outbox entry and records the outcome
+func (m *Manager) deliver(ctx context.Context, entry *bus.OutboxEntry) {
+	m.mu.RLock()
+	channel, exists := m.channels[entry.Message.Channel]
+	m.mu.RUnlock()
+
+	if !exists {
+		m.outbox.MarkFailed(entry, fmt.Errorf("channel %s not found", entry.Message.Channel), true)
+		return
+	}
+
+	// A retry resumes a split message after the parts already delivered
+	if err := channel.Send(withSentParts(ctx, entry.PartsSent), entry.Message); err != nil {
+		logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
+			"channel":  entry.Message.Channel,
+			"id":       entry.ID,
+			"attempts": entry.Attempts + 1,
+			"error":    err.Error(),
+		})
+		var partial *PartialSendError
+		if errors.As(err, &partial) {
+			entry.PartsSent = partial.Sent
+		}
+		m.outbox.MarkFailed(entry, err, false)
+		return
+	}
+
+	m.outbox.MarkDelivered(entry)
+}
+
+// retryOutbox periodically re-sends pending outbox entries that are due,
+// including entries left over from a previous run.
+func (m *Manager) retryOutbox(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			due, err := m.outbox.Due(time.Now())
+			if err != nil {
+				logger.WarnCF("channels", "Failed to read outbox", map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+			for _, entry := range due {
+				m.deliver(ctx, entry)
+			}
+		}
+	}
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -256,6 +368,27 @@ func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, conten
 		ChatID:  chatID,
 		Content: content,
 	}
+	msg, err := m.middleware.BeforeSend(ctx, msg)
+	if err != nil {
+		return err
+	}
 
 	return channel.Send(ctx, msg)
 }
+
+// SendTyping shows a typing indicator in a chat. Channels without typing
+// support are ignored.
+func (m *Manager) SendTyping(ctx context.Context, channelName, chatID string) error {
+	m.mu.RLock()
+	channel, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("channel %s not found", channelName)
+	}
+
+	if typing, ok := channel.(TypingIndicator); ok {
+		return typing.SendTyping(ctx, chatID)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+)
+
+// flakyChannel sends the parts of a split message and fails once at failAt
+type flakyChannel struct {
+	sent   []string
+	failAt int
+}
+
+func (c *flakyChannel) Name() string                    { return "flaky" }
+func (c *flakyChannel) Start(ctx context.Context) error { return nil }
+func (c *flakyChannel) Stop(ctx context.Context) error  { return nil }
+func (c *flakyChannel) IsRunning() bool                 { return true }
+func (c *flakyChannel) IsAllowed(senderID string) bool  { return true }
+
+func (c *flakyChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	formatted := FormatMessage(msg.Content, OutputFormat{MaxLength: 300, Markup: MarkupPlain})
+	for i, part := range formatted.Parts {
+		if i < sentParts(ctx) {
+			continue
+		}
+		if i == c.failAt {
+			c.failAt = -1
+			return partialSend(i, errors.New("rate limited"))
+		}
+		c.sent = append(c.sent, part.Text)
+	}
+	return nil
+}
+
+func TestOutboxRetryResumesSplitMessage(t *testing.T) {
+	outbox, err := bus.NewOutbox(t.TempDir(), 3, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := &flakyChannel{failAt: 1}
+	m := &Manager{channels: map[string]Channel{"flaky": channel}, outbox: outbox}
+
+	para := strings.Repeat("word ", 40)
+	entry, err := outbox.Enqueue(bus.OutboundMessage{Channel: "flaky", ChatID: "1", Content: "one " + para + "\n\ntwo " + para + "\n\nthree " + para})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	m.deliver(ctx, entry)
+
+	due, err := outbox.Due(time.Now().Add(time.Minute))
+	if err != nil || len(due) != 1 {
+		t.Fatalf("due = %v, %v", due, err)
+	}
+	if due[0].PartsSent != 1 {
+		t.Errorf("parts sent = %d, want 1", due[0].PartsSent)
+	}
+	m.deliver(ctx, due[0])
+
+	if len(channel.sent) != 3 || !strings.HasPrefix(channel.sent[0], "one") || !strings.HasPrefix(channel.sent[1], "two") {
+		t.Errorf("sent = %q, want every part once", channel.sent)
+	}
+}
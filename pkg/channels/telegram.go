@@ -2,10 +2,12 @@ package channels
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,7 +24,7 @@ type TelegramChannel struct {
 	bot          *tgbotapi.BotAPI
 	config       config.TelegramConfig
 	chatIDs      map[string]int64
-	updates      tgbotapi.UpdatesChannel
+	stopPolling  chan struct{}
 	transcriber  *voice.GroqTranscriber
 	placeholders sync.Map // chatID -> messageID
 	stopThinking sync.Map // chatID -> chan struct{}
@@ -54,11 +56,9 @@ func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
 func (c *TelegramChannel) Start(ctx context.Context) error {
 	log.Printf("Starting Telegram bot (polling mode)...")
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 30
-
-	updates := c.bot.GetUpdatesChan(u)
-	c.updates = updates
+	stop := make(chan struct{})
+	c.stopPolling = stop
+	updates := c.pollUpdates(stop)
 
 	c.setRunning(true)
 
@@ -79,7 +79,10 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 					return
 				}
 				if update.Message != nil {
-					c.handleMessage(update)
+					c.handleMessage(update.Update)
+				}
+				if update.MessageReaction != nil {
+					c.handleReaction(update.MessageReaction)
 				}
 			}
 		}
@@ -92,9 +95,9 @@ func (c *TelegramChannel) Stop(ctx context.Context) error {
 	log.Println("Stopping Telegram bot...")
 	c.setRunning(false)
 
-	if c.updates != nil {
-		c.bot.StopReceivingUpdates()
-		c.updates = nil
+	if c.stopPolling != nil {
+		close(c.stopPolling)
+		c.stopPolling = nil
 	}
 
 	return nil
@@ -116,39 +119,199 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		c.stopThinking.Delete(msg.ChatID)
 	}
 
-	htmlContent := markdownToTelegramHTML(msg.Content)
+	if msg.Progress {
+		return c.sendProgress(chatID, msg.ChatID, msg.Content)
+	}
 
 	// If there are media attachments, send with media
 	if len(msg.Media) > 0 {
-		return c.sendWithMedia(chatID, htmlContent, msg.Content, msg.Media)
+		return c.sendWithMedia(chatID, markdownToTelegramHTML(msg.Content), msg.Content, msg.Media)
 	}
 
-	// Try to edit placeholder
-	if pID, ok := c.placeholders.Load(msg.ChatID); ok {
-		c.placeholders.Delete(msg.ChatID)
-		editMsg := tgbotapi.NewEditMessageText(chatID, pID.(int), htmlContent)
-		editMsg.ParseMode = tgbotapi.ModeHTML
+	formatted := FormatMessage(msg.Content, outputFormatFor(c.Name()))
+	defer formatted.Cleanup()
 
-		if _, err := c.bot.Send(editMsg); err == nil {
-			return nil
+	skip := sentParts(ctx)
+	for i, part := range formatted.Parts {
+		if i < skip {
+			continue
+		}
+		// The first part replaces the placeholder
+		if pID, ok := c.placeholders.Load(msg.ChatID); ok && i == 0 {
+			c.placeholders.Delete(msg.ChatID)
+			editMsg := tgbotapi.NewEditMessageText(chatID, pID.(int), part.Text)
+			editMsg.ParseMode = tgbotapi.ModeHTML
+
+			if _, err := c.bot.Send(editMsg); err == nil {
+				c.recordSent(msg, strconv.Itoa(pID.(int)))
+				continue
+			}
+			// Fallback to new message if edit fails
+		}
+
+		if err := c.sendHTML(chatID, part, msg); err != nil {
+			return partialSend(i, err)
+		}
+	}
+
+	// Long code blocks are attached as files
+	if len(formatted.Files) > 0 {
+		if err := c.sendWithMedia(chatID, "", "", formatted.Files); err != nil {
+			return partialSend(len(formatted.Parts), err)
 		}
-		// Fallback to new message if edit fails
 	}
 
-	tgMsg := tgbotapi.NewMessage(chatID, htmlContent)
+	return nil
+}
+
+// sendHTML sends one formatted part of msg, falling back to plain text if
+// Telegram rejects the HTML
+func (c *TelegramChannel) sendHTML(chatID int64, part MessagePart, msg bus.OutboundMessage) error {
+	tgMsg := tgbotapi.NewMessage(chatID, part.Text)
 	tgMsg.ParseMode = tgbotapi.ModeHTML
 
-	if _, err := c.bot.Send(tgMsg); err != nil {
+	sent, err := c.bot.Send(tgMsg)
+	if err != nil {
 		log.Printf("HTML parse failed, falling back to plain text: %v", err)
-		tgMsg = tgbotapi.NewMessage(chatID, msg.Content)
+		tgMsg = tgbotapi.NewMessage(chatID, part.Source)
 		tgMsg.ParseMode = ""
-		_, err = c.bot.Send(tgMsg)
-		return err
+		if sent, err = c.bot.Send(tgMsg); err != nil {
+			return err
+		}
+	}
+	c.recordSent(msg, strconv.Itoa(sent.MessageID))
+	return nil
+}
+
+// telegramUpdate is an update including reactions, which the bot API
+// library does not know about yet
+type telegramUpdate struct {
+	tgbotapi.Update
+	MessageReaction *telegramReaction `json:"message_reaction"`
+}
+
+// telegramReaction is a change of the reactions a user left on a message
+type telegramReaction struct {
+	Chat        tgbotapi.Chat   `json:"chat"`
+	MessageID   int             `json:"message_id"`
+	User        *tgbotapi.User  `json:"user"`
+	OldReaction []reactionEmoji `json:"old_reaction"`
+	NewReaction []reactionEmoji `json:"new_reaction"`
+}
+
+type reactionEmoji struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+// pollUpdates long-polls for messages and reactions until stop is closed.
+// Telegram only sends reactions when they are asked for explicitly.
+func (c *TelegramChannel) pollUpdates(stop chan struct{}) <-chan telegramUpdate {
+	updates := make(chan telegramUpdate, 100)
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 30
+	u.AllowedUpdates = []string{"message", "message_reaction"}
+
+	go func() {
+		defer close(updates)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			resp, err := c.bot.Request(u)
+			var batch []telegramUpdate
+			if err == nil {
+				err = json.Unmarshal(resp.Result, &batch)
+			}
+			if err != nil {
+				log.Printf("Failed to get Telegram updates: %v, retrying in 3 seconds...", err)
+				select {
+				case <-stop:
+					return
+				case <-time.After(3 * time.Second):
+				}
+				continue
+			}
+
+			for _, update := range batch {
+				if update.UpdateID >= u.Offset {
+					u.Offset = update.UpdateID + 1
+				}
+				select {
+				case updates <- update:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return updates
+}
+
+// handleReaction records the ratings added and removed in a reaction change
+func (c *TelegramChannel) handleReaction(r *telegramReaction) {
+	if r.User == nil || r.User.IsBot {
+		// Anonymous group admins react as the chat
+		return
+	}
+	senderID := fmt.Sprintf("%d", r.User.ID)
+	if r.User.UserName != "" {
+		senderID = fmt.Sprintf("%d|%s", r.User.ID, r.User.UserName)
+	}
+	chatID := fmt.Sprintf("%d", r.Chat.ID)
+	messageID := strconv.Itoa(r.MessageID)
+
+	has := func(list []reactionEmoji, emoji string) bool {
+		for _, e := range list {
+			if e.Type == "emoji" && e.Emoji == emoji {
+				return true
+			}
+		}
+		return false
+	}
+	// Removals first, so switching from 👍 to 👎 ends with 👎
+	for _, e := range r.OldReaction {
+		if e.Type == "emoji" && !has(r.NewReaction, e.Emoji) {
+			c.recordReaction(chatID, messageID, senderID, e.Emoji, false)
+		}
+	}
+	for _, e := range r.NewReaction {
+		if e.Type == "emoji" && !has(r.OldReaction, e.Emoji) {
+			c.recordReaction(chatID, messageID, senderID, e.Emoji, true)
+		}
+	}
+}
+
+// sendProgress shows a progress note in the placeholder message, creating one
+// if needed, so the final response replaces it
+func (c *TelegramChannel) sendProgress(chatID int64, key, note string) error {
+	if pID, ok := c.placeholders.Load(key); ok {
+		if _, err := c.bot.Send(tgbotapi.NewEditMessageText(chatID, pID.(int), note)); err == nil {
+			return nil
+		}
 	}
 
+	pMsg, err := c.bot.Send(tgbotapi.NewMessage(chatID, note))
+	if err != nil {
+		return err
+	}
+	c.placeholders.Store(key, pMsg.MessageID)
 	return nil
 }
 
+// SendTyping shows the "typing…" chat action (lasts ~5 seconds)
+func (c *TelegramChannel) SendTyping(ctx context.Context, chatID string) error {
+	id, err := parseChatID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+	_, err = c.bot.Request(tgbotapi.NewChatAction(id, tgbotapi.ChatTyping))
+	return err
+}
+
 // sendWithMedia sends a message with media attachments (images, documents, audio, video, files)
 func (c *TelegramChannel) sendWithMedia(chatID int64, htmlContent, plainContent string, mediaURLs []string) error {
 	// Delete placeholder if exists (can't edit with media)
@@ -280,6 +443,24 @@ func (c *TelegramChannel) handleMessage(update tgbotapi.Update) {
 	}
 
 	chatID := message.Chat.ID
+	isGroup := message.Chat.Type != "private"
+
+	text := message.Text
+	caption := message.Caption
+
+	// In groups, respond according to group_mode (mention, always, command)
+	if isGroup {
+		body := &text
+		if text == "" {
+			body = &caption
+		}
+		stripped, ok := shouldRespondInGroup(c.config.GroupMode, c.config.GroupTrigger, c.removeBotMention(*body), c.isAddressedToBot(message))
+		if !ok {
+			return
+		}
+		*body = stripped
+	}
+
 	c.chatIDs[senderID] = chatID
 
 	content := ""
@@ -304,18 +485,18 @@ func (c *TelegramChannel) handleMessage(update tgbotapi.Update) {
 		}
 	}
 
-	if message.Text != "" {
+	if text != "" {
 		if content != "" {
 			content += "\n"
 		}
-		content += message.Text
+		content += text
 	}
 
-	if message.Caption != "" {
+	if caption != "" {
 		if content != "" {
 			content += "\n"
 		}
-		content += message.Caption
+		content += caption
 	}
 
 	if message.Photo != nil && len(message.Photo) > 0 {
@@ -423,12 +604,42 @@ func (c *TelegramChannel) handleMessage(update tgbotapi.Update) {
 		"user_id":    fmt.Sprintf("%d", user.ID),
 		"username":   user.UserName,
 		"first_name": user.FirstName,
-		"is_group":   fmt.Sprintf("%t", message.Chat.Type != "private"),
+		"is_group":   fmt.Sprintf("%t", isGroup),
 	}
 
 	c.HandleMessage(senderID, fmt.Sprintf("%d", chatID), content, mediaPaths, metadata)
 }
 
+// isAddressedToBot reports whether a message mentions the bot or replies to one of its messages
+func (c *TelegramChannel) isAddressedToBot(message *tgbotapi.Message) bool {
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && message.ReplyToMessage.From.ID == c.bot.Self.ID {
+		return true
+	}
+
+	entities := message.Entities
+	if len(entities) == 0 {
+		entities = message.CaptionEntities
+	}
+	for _, entity := range entities {
+		if entity.Type == "text_mention" && entity.User != nil && entity.User.ID == c.bot.Self.ID {
+			return true
+		}
+	}
+
+	mention := "@" + strings.ToLower(c.bot.Self.UserName)
+	return c.bot.Self.UserName != "" &&
+		(strings.Contains(strings.ToLower(message.Text), mention) || strings.Contains(strings.ToLower(message.Caption), mention))
+}
+
+// removeBotMention strips "@botusername" from the message text
+func (c *TelegramChannel) removeBotMention(text string) string {
+	if c.bot.Self.UserName == "" {
+		return text
+	}
+	re := regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(c.bot.Self.UserName) + `\b`)
+	return strings.TrimSpace(re.ReplaceAllString(text, ""))
+}
+
 func (c *TelegramChannel) downloadPhoto(fileID string) string {
 	file, err := c.bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
 	if err != nil {
@@ -502,9 +713,9 @@ func markdownToTelegramHTML(text string) string {
 	inlineCodes := extractInlineCodes(text)
 	text = inlineCodes.text
 
-	text = regexp.MustCompile(`^#{1,6}\s+(.+)$`).ReplaceAllString(text, "$1")
+	text = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`).ReplaceAllString(text, "**$1**")
 
-	text = regexp.MustCompile(`^>\s*(.*)$`).ReplaceAllString(text, "$1")
+	text = regexp.MustCompile(`(?m)^>\s*(.*)$`).ReplaceAllString(text, "$1")
 
 	text = escapeHTML(text)
 
@@ -525,7 +736,7 @@ func markdownToTelegramHTML(text string) string {
 
 	text = regexp.MustCompile(`~~(.+?)~~`).ReplaceAllString(text, "<s>$1</s>")
 
-	text = regexp.MustCompile(`^[-*]\s+`).ReplaceAllString(text, "• ")
+	text = regexp.MustCompile(`(?m)^([ \t]*)[-*]\s+`).ReplaceAllString(text, "$1• ")
 
 	for i, code := range inlineCodes.codes {
 		escaped := escapeHTML(code)
@@ -554,8 +765,11 @@ func extractCodeBlocks(text string) codeBlockMatch {
 		codes = append(codes, match[1])
 	}
 
+	i := 0
 	text = re.ReplaceAllStringFunc(text, func(m string) string {
-		return fmt.Sprintf("\x00CB%d\x00", len(codes)-1)
+		placeholder := fmt.Sprintf("\x00CB%d\x00", i)
+		i++
+		return placeholder
 	})
 
 	return codeBlockMatch{text: text, codes: codes}
@@ -575,8 +789,11 @@ func extractInlineCodes(text string) inlineCodeMatch {
 		codes = append(codes, match[1])
 	}
 
+	i := 0
 	text = re.ReplaceAllStringFunc(text, func(m string) string {
-		return fmt.Sprintf("\x00IC%d\x00", len(codes)-1)
+		placeholder := fmt.Sprintf("\x00IC%d\x00", i)
+		i++
+		return placeholder
 	})
 
 	return inlineCodeMatch{text: text, codes: codes}
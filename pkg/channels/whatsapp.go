@@ -25,6 +25,7 @@ import (
 	"github.com/pepebot-space/pepebot/pkg/bus"
 	"github.com/pepebot-space/pepebot/pkg/config"
 	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/voice"
 )
 
 type WhatsAppChannel struct {
@@ -35,6 +36,7 @@ type WhatsAppChannel struct {
 	mu             sync.Mutex
 	typingChannels map[string]chan bool
 	typingMutex    sync.RWMutex
+	transcriber    *voice.GroqTranscriber
 }
 
 func NewWhatsAppChannel(cfg config.WhatsAppConfig, messageBus *bus.MessageBus) (*WhatsAppChannel, error) {
@@ -72,6 +74,27 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, messageBus *bus.MessageBus) (
 	return ch, nil
 }
 
+// SetTranscriber enables transcription of voice notes
+func (c *WhatsAppChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+	c.transcriber = transcriber
+}
+
+// transcribeVoiceNote returns the text of a voice note for the message
+// content, or a placeholder when transcription fails
+func (c *WhatsAppChannel) transcribeVoiceNote(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	result, err := c.transcriber.Transcribe(ctx, path)
+	if err != nil {
+		logger.WarnCF("whatsapp", "Voice transcription failed", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+		return fmt.Sprintf("[voice: %s (transcription failed)]", path)
+	}
+	return fmt.Sprintf("[voice transcription: %s]", result.Text)
+}
+
 func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	logger.InfoC("whatsapp", "Starting WhatsApp channel...")
 
@@ -144,6 +167,13 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("failed to parse JID %q: %w", msg.ChatID, err)
 	}
 
+	if msg.Progress {
+		_, err = c.client.SendMessage(ctx, jid, &waE2E.Message{
+			Conversation: proto.String(msg.Content),
+		})
+		return err
+	}
+
 	// Stop typing indicator since we're about to send the response
 	c.stopTyping(msg.ChatID)
 
@@ -155,12 +185,28 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return c.sendWithMedia(ctx, jid, msg.Content, msg.Media)
 	}
 
-	// Send text-only message
-	_, err = c.client.SendMessage(ctx, jid, &waE2E.Message{
-		Conversation: proto.String(msg.Content),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+	formatted := FormatMessage(msg.Content, outputFormatFor(c.Name()))
+	defer formatted.Cleanup()
+
+	// Send text-only message, split into parts when it is too long
+	skip := sentParts(ctx)
+	for i, part := range formatted.Parts {
+		if i < skip {
+			continue
+		}
+		_, err = c.client.SendMessage(ctx, jid, &waE2E.Message{
+			Conversation: proto.String(part.Text),
+		})
+		if err != nil {
+			return partialSend(i, fmt.Errorf("failed to send message: %w", err))
+		}
+	}
+
+	// Long code blocks are attached as documents
+	if len(formatted.Files) > 0 {
+		if err := c.sendWithMedia(ctx, jid, "", formatted.Files); err != nil {
+			return partialSend(len(formatted.Parts), err)
+		}
 	}
 
 	return nil
@@ -314,6 +360,18 @@ func (c *WhatsAppChannel) sendWithMedia(ctx context.Context, jid types.JID, capt
 	return nil
 }
 
+// SendTyping shows the composing presence in a chat
+func (c *WhatsAppChannel) SendTyping(ctx context.Context, chatID string) error {
+	if !c.client.IsConnected() {
+		return fmt.Errorf("whatsapp client not connected")
+	}
+	jid, err := types.ParseJID(chatID)
+	if err != nil {
+		return fmt.Errorf("failed to parse JID %q: %w", chatID, err)
+	}
+	return c.client.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+}
+
 // keepTyping continuously sends composing presence to WhatsApp chat
 // WhatsApp typing indicator is short-lived, so we refresh every 5 seconds
 func (c *WhatsAppChannel) keepTyping(chatJID types.JID, stop chan bool) {
@@ -405,8 +463,29 @@ func (c *WhatsAppChannel) handleIncomingMessage(evt *events.Message) {
 		content = fmt.Sprintf("[replying to: %s]", quotedText)
 	}
 
-	// Extract the main message text
-	if text := extractTextContent(evt.Message); text != "" {
+	// Extract the main message text and media caption
+	text := extractTextContent(evt.Message)
+	caption := extractCaption(evt.Message)
+
+	// In groups, respond according to group_mode (mention, always, command)
+	if evt.Info.IsGroup {
+		body := &text
+		if text == "" {
+			body = &caption
+		}
+		stripped, ok := shouldRespondInGroup(c.config.GroupMode, c.config.GroupTrigger, c.removeOwnMention(*body), c.isAddressedToMe(evt.Message))
+		if !ok {
+			logger.DebugCF("whatsapp", "Ignoring group message", map[string]interface{}{
+				"sender":     senderID,
+				"chat":       chatID,
+				"group_mode": c.config.GroupMode,
+			})
+			return
+		}
+		*body = stripped
+	}
+
+	if text != "" {
 		if content != "" {
 			content += "\n"
 		}
@@ -422,8 +501,8 @@ func (c *WhatsAppChannel) handleIncomingMessage(evt *events.Message) {
 		mediaPath := c.downloadWhatsAppMedia(evt)
 		if mediaPath != "" {
 			mediaPaths = append(mediaPaths, mediaPath)
-			if imgMsg.GetCaption() != "" {
-				content = imgMsg.GetCaption()
+			if caption != "" {
+				content = caption
 			}
 			if content == "" {
 				content = "[image received]"
@@ -440,8 +519,8 @@ func (c *WhatsAppChannel) handleIncomingMessage(evt *events.Message) {
 		mediaPath := c.downloadWhatsAppMedia(evt)
 		if mediaPath != "" {
 			mediaPaths = append(mediaPaths, mediaPath)
-			if vidMsg.GetCaption() != "" {
-				content = vidMsg.GetCaption()
+			if caption != "" {
+				content = caption
 			}
 			if content == "" {
 				content = "[video received]"
@@ -458,7 +537,12 @@ func (c *WhatsAppChannel) handleIncomingMessage(evt *events.Message) {
 		mediaPath := c.downloadWhatsAppMedia(evt)
 		if mediaPath != "" {
 			mediaPaths = append(mediaPaths, mediaPath)
-			if content == "" {
+			if c.transcriber != nil && c.transcriber.IsAvailable() && audMsg.GetPTT() {
+				if content != "" {
+					content += "\n"
+				}
+				content += c.transcribeVoiceNote(mediaPath)
+			} else if content == "" {
 				content = "[audio received]"
 			}
 		}
@@ -474,8 +558,8 @@ func (c *WhatsAppChannel) handleIncomingMessage(evt *events.Message) {
 		mediaPath := c.downloadWhatsAppMedia(evt)
 		if mediaPath != "" {
 			mediaPaths = append(mediaPaths, mediaPath)
-			if docMsg.GetCaption() != "" {
-				content = docMsg.GetCaption()
+			if caption != "" {
+				content = caption
 			}
 			if content == "" {
 				content = fmt.Sprintf("[document received: %s]", docMsg.GetFileName())
@@ -491,6 +575,7 @@ func (c *WhatsAppChannel) handleIncomingMessage(evt *events.Message) {
 	metadata := map[string]string{
 		"message_id": string(evt.Info.ID),
 		"push_name":  evt.Info.PushName,
+		"is_group":   fmt.Sprintf("%t", evt.Info.IsGroup),
 	}
 
 	logger.DebugCF("whatsapp", "Message received", map[string]interface{}{
@@ -608,26 +693,47 @@ func extractTextContent(msg *waE2E.Message) string {
 	return ""
 }
 
-// extractQuotedText extracts the quoted/replied-to message text from a WhatsApp message
-func extractQuotedText(msg *waE2E.Message) string {
+// extractContextInfo returns the ContextInfo (quote, mentions) attached to a WhatsApp message
+func extractContextInfo(msg *waE2E.Message) *waE2E.ContextInfo {
 	if msg == nil {
-		return ""
+		return nil
 	}
 
-	var ctxInfo *waE2E.ContextInfo
-
 	if ext := msg.GetExtendedTextMessage(); ext != nil {
-		ctxInfo = ext.GetContextInfo()
+		return ext.GetContextInfo()
 	} else if img := msg.GetImageMessage(); img != nil {
-		ctxInfo = img.GetContextInfo()
+		return img.GetContextInfo()
 	} else if vid := msg.GetVideoMessage(); vid != nil {
-		ctxInfo = vid.GetContextInfo()
+		return vid.GetContextInfo()
 	} else if aud := msg.GetAudioMessage(); aud != nil {
-		ctxInfo = aud.GetContextInfo()
+		return aud.GetContextInfo()
+	} else if doc := msg.GetDocumentMessage(); doc != nil {
+		return doc.GetContextInfo()
+	}
+
+	return nil
+}
+
+// extractCaption returns the caption of an image, video or document message
+func extractCaption(msg *waE2E.Message) string {
+	if msg == nil {
+		return ""
+	}
+
+	if img := msg.GetImageMessage(); img != nil {
+		return img.GetCaption()
+	} else if vid := msg.GetVideoMessage(); vid != nil {
+		return vid.GetCaption()
 	} else if doc := msg.GetDocumentMessage(); doc != nil {
-		ctxInfo = doc.GetContextInfo()
+		return doc.GetCaption()
 	}
 
+	return ""
+}
+
+// extractQuotedText extracts the quoted/replied-to message text from a WhatsApp message
+func extractQuotedText(msg *waE2E.Message) string {
+	ctxInfo := extractContextInfo(msg)
 	if ctxInfo == nil {
 		return ""
 	}
@@ -640,6 +746,55 @@ func extractQuotedText(msg *waE2E.Message) string {
 	return extractTextContent(quotedMsg)
 }
 
+// ownUsers returns the user parts of the bot's own phone and LID JIDs
+func (c *WhatsAppChannel) ownUsers() []string {
+	users := []string{}
+	if c.client.Store.ID != nil {
+		users = append(users, c.client.Store.ID.User)
+	}
+	if !c.client.Store.LID.IsEmpty() {
+		users = append(users, c.client.Store.LID.User)
+	}
+	return users
+}
+
+// isAddressedToMe reports whether a group message mentions the bot or quotes one of its messages
+func (c *WhatsAppChannel) isAddressedToMe(msg *waE2E.Message) bool {
+	ctxInfo := extractContextInfo(msg)
+	if ctxInfo == nil {
+		return false
+	}
+
+	isOwn := func(jidStr string) bool {
+		jid, err := types.ParseJID(jidStr)
+		if err != nil {
+			return false
+		}
+		for _, user := range c.ownUsers() {
+			if jid.User == user {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, mentioned := range ctxInfo.GetMentionedJID() {
+		if isOwn(mentioned) {
+			return true
+		}
+	}
+
+	return ctxInfo.GetQuotedMessage() != nil && isOwn(ctxInfo.GetParticipant())
+}
+
+// removeOwnMention strips "@<number>" mention tokens of the bot from the text
+func (c *WhatsAppChannel) removeOwnMention(text string) string {
+	for _, user := range c.ownUsers() {
+		text = strings.ReplaceAll(text, "@"+user, "")
+	}
+	return strings.TrimSpace(text)
+}
+
 func expandDBPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, _ := os.UserHomeDir()
@@ -0,0 +1,26 @@
+package channels
+
+// WhatsAppGroup describes a group the WhatsApp account has joined
+type WhatsAppGroup struct {
+	JID          string `json:"jid"`
+	Name         string `json:"name"`
+	Topic        string `json:"topic,omitempty"`
+	Participants int    `json:"participants"`
+}
+
+// WhatsAppContact is a contact matched by name or phone number
+type WhatsAppContact struct {
+	JID      string `json:"jid"`
+	Name     string `json:"name,omitempty"`
+	PushName string `json:"push_name,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+}
+
+// WhatsAppGroupMember is a participant of a WhatsApp group
+type WhatsAppGroupMember struct {
+	JID          string `json:"jid"`
+	Phone        string `json:"phone,omitempty"`
+	Name         string `json:"name,omitempty"`
+	IsAdmin      bool   `json:"is_admin,omitempty"`
+	IsSuperAdmin bool   `json:"is_super_admin,omitempty"`
+}
@@ -0,0 +1,230 @@
+//go:build !mips && !mipsle && !mips64 && !mips64le
+// +build !mips,!mipsle,!mips64,!mips64le
+
+package channels
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ListGroups returns the groups the account has joined
+func (c *WhatsAppChannel) ListGroups(ctx context.Context) ([]WhatsAppGroup, error) {
+	if !c.client.IsConnected() {
+		return nil, fmt.Errorf("whatsapp not connected")
+	}
+
+	groups, err := c.client.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	result := make([]WhatsAppGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, WhatsAppGroup{
+			JID:          g.JID.String(),
+			Name:         g.Name,
+			Topic:        g.Topic,
+			Participants: len(g.Participants),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+
+	return result, nil
+}
+
+// ResolveContact finds contacts whose name, push name or phone number matches
+// the query. Exact name matches are returned first.
+func (c *WhatsAppChannel) ResolveContact(ctx context.Context, query string) ([]WhatsAppContact, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	contacts, err := c.client.Store.Contacts.GetAllContacts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contacts: %w", err)
+	}
+
+	q := strings.ToLower(query)
+	digits := phoneDigits(query)
+
+	type match struct {
+		contact WhatsAppContact
+		exact   bool
+	}
+	matches := []match{}
+
+	for jid, info := range contacts {
+		name := contactName(info)
+		fields := []string{info.FullName, info.FirstName, info.PushName, info.BusinessName}
+
+		exact := false
+		found := false
+		for _, f := range fields {
+			lf := strings.ToLower(f)
+			if lf == "" {
+				continue
+			}
+			if lf == q {
+				exact = true
+			}
+			if strings.Contains(lf, q) {
+				found = true
+			}
+		}
+		if !found && digits != "" && strings.Contains(jid.User, digits) {
+			found = true
+			exact = jid.User == digits
+		}
+		if !found {
+			continue
+		}
+
+		contact := WhatsAppContact{
+			JID:      jid.String(),
+			Name:     name,
+			PushName: info.PushName,
+		}
+		if jid.Server == types.DefaultUserServer {
+			contact.Phone = "+" + jid.User
+		}
+		matches = append(matches, match{contact: contact, exact: exact})
+	}
+
+	// A bare phone number that isn't in the contact store still resolves to a JID
+	if len(matches) == 0 && digits != "" && !strings.ContainsFunc(query, unicode.IsLetter) {
+		jid := types.NewJID(digits, types.DefaultUserServer)
+		matches = append(matches, match{
+			contact: WhatsAppContact{JID: jid.String(), Phone: "+" + digits},
+			exact:   true,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].exact != matches[j].exact {
+			return matches[i].exact
+		}
+		return strings.ToLower(matches[i].contact.Name) < strings.ToLower(matches[j].contact.Name)
+	})
+
+	result := make([]WhatsAppContact, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.contact)
+	}
+	return result, nil
+}
+
+// GroupMembers lists the participants of a group, identified by JID or name
+func (c *WhatsAppChannel) GroupMembers(ctx context.Context, group string) ([]WhatsAppGroupMember, error) {
+	if !c.client.IsConnected() {
+		return nil, fmt.Errorf("whatsapp not connected")
+	}
+
+	groupJID, err := c.resolveGroupJID(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.client.GetGroupInfo(ctx, groupJID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group info: %w", err)
+	}
+
+	members := make([]WhatsAppGroupMember, 0, len(info.Participants))
+	for _, p := range info.Participants {
+		member := WhatsAppGroupMember{
+			JID:          p.JID.String(),
+			Name:         p.DisplayName,
+			IsAdmin:      p.IsAdmin,
+			IsSuperAdmin: p.IsSuperAdmin,
+		}
+		if !p.PhoneNumber.IsEmpty() {
+			member.Phone = "+" + p.PhoneNumber.User
+		}
+		for _, jid := range []types.JID{p.PhoneNumber, p.LID, p.JID} {
+			if jid.IsEmpty() {
+				continue
+			}
+			if contact, err := c.client.Store.Contacts.GetContact(ctx, jid); err == nil && contact.Found {
+				if name := contactName(contact); name != "" {
+					member.Name = name
+					break
+				}
+			}
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// resolveGroupJID accepts a group JID or a (case-insensitive) group name
+func (c *WhatsAppChannel) resolveGroupJID(ctx context.Context, group string) (types.JID, error) {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return types.EmptyJID, fmt.Errorf("group is required")
+	}
+
+	if strings.HasSuffix(group, "@"+types.GroupServer) {
+		return types.ParseJID(group)
+	}
+
+	groups, err := c.ListGroups(ctx)
+	if err != nil {
+		return types.EmptyJID, err
+	}
+
+	var candidates []WhatsAppGroup
+	for _, g := range groups {
+		if strings.EqualFold(g.Name, group) {
+			return types.ParseJID(g.JID)
+		}
+		if strings.Contains(strings.ToLower(g.Name), strings.ToLower(group)) {
+			candidates = append(candidates, g)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return types.EmptyJID, fmt.Errorf("no group matching %q", group)
+	case 1:
+		return types.ParseJID(candidates[0].JID)
+	default:
+		names := make([]string, 0, len(candidates))
+		for _, g := range candidates {
+			names = append(names, g.Name)
+		}
+		return types.EmptyJID, fmt.Errorf("group %q is ambiguous, matches: %s", group, strings.Join(names, ", "))
+	}
+}
+
+func contactName(info types.ContactInfo) string {
+	for _, name := range []string{info.FullName, info.FirstName, info.BusinessName, info.PushName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func phoneDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() < 5 {
+		return ""
+	}
+	return b.String()
+}
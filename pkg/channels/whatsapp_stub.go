@@ -9,6 +9,7 @@ import (
 
 	"github.com/pepebot-space/pepebot/pkg/bus"
 	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/voice"
 )
 
 // WhatsAppChannel stub for MIPS architectures (SQLite not supported)
@@ -20,6 +21,8 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, messageBus *bus.MessageBus) (
 	return nil, fmt.Errorf("WhatsApp channel is not supported on MIPS architecture (SQLite dependency unavailable)")
 }
 
+func (c *WhatsAppChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {}
+
 func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	return fmt.Errorf("WhatsApp channel is not supported on MIPS architecture")
 }
@@ -31,3 +34,15 @@ func (c *WhatsAppChannel) Stop(ctx context.Context) error {
 func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	return fmt.Errorf("WhatsApp channel is not supported on MIPS architecture")
 }
+
+func (c *WhatsAppChannel) ListGroups(ctx context.Context) ([]WhatsAppGroup, error) {
+	return nil, fmt.Errorf("WhatsApp channel is not supported on MIPS architecture")
+}
+
+func (c *WhatsAppChannel) ResolveContact(ctx context.Context, query string) ([]WhatsAppContact, error) {
+	return nil, fmt.Errorf("WhatsApp channel is not supported on MIPS architecture")
+}
+
+func (c *WhatsAppChannel) GroupMembers(ctx context.Context, group string) ([]WhatsAppGroupMember, error) {
+	return nil, fmt.Errorf("WhatsApp channel is not supported on MIPS architecture")
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFlagParse(t *testing.T) {
+	fs := &FlagSet{}
+	name := fs.String("name", "n", "", "name", "Job name")
+	verbose := fs.Bool("verbose", "v", "Verbose")
+	every := fs.Int("every", "e", 0, "Interval")
+	mode := fs.Choice("mode", "", "notify", []string{"notify", "agent"}, "Mode")
+	vars := fs.KeyValue("var", "", "Variable")
+
+	err := fs.Parse([]string{"first", "-n", "daily", "--every=60", "-v", "--var", "a=1", "--var", "b=x=y", "second", "--", "--literal"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if *name != "daily" || *every != 60 || !*verbose || *mode != "notify" {
+		t.Errorf("unexpected values: name=%q every=%d verbose=%v mode=%q", *name, *every, *verbose, *mode)
+	}
+	if vars["a"] != "1" || vars["b"] != "x=y" {
+		t.Errorf("vars = %v", vars)
+	}
+	if got := strings.Join(fs.Args(), ","); got != "first,second,--literal" {
+		t.Errorf("args = %q", got)
+	}
+	if !fs.Changed("every") || fs.Changed("mode") {
+		t.Error("Changed does not track given flags")
+	}
+
+	cases := map[string][]string{
+		"unknown flag --nope":            {"--nope"},
+		"needs a value":                  {"--name"},
+		"must be a whole number":         {"--every", "soon"},
+		"must be one of notify, agent":   {"--mode", "loud"},
+		"given more than once":           {"-n", "a", "-n", "b"},
+		"unknown flag -name":             {"-name", "x"},
+		"not in key=value format":        {"--var", "novalue"},
+		"invalid value \"maybe\" for -v": {"-v=maybe"},
+	}
+	for want, args := range cases {
+		fs := &FlagSet{}
+		fs.String("name", "n", "", "name", "")
+		fs.Int("every", "", 0, "")
+		fs.Bool("verbose", "v", "")
+		fs.Choice("mode", "", "", []string{"notify", "agent"}, "")
+		fs.KeyValue("var", "", "")
+		if err := fs.Parse(args); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%v) = %v, want error containing %q", args, err, want)
+		}
+	}
+
+	if err := (&FlagSet{}).Parse([]string{"x", "--help"}); !errors.Is(err, ErrHelp) {
+		t.Errorf("--help should return ErrHelp, got %v", err)
+	}
+	if err := (&FlagSet{}).Parse([]string{"-5"}); err != nil {
+		t.Errorf("negative numbers are positional: %v", err)
+	}
+}
+
+func testTree(ran *string) *Command {
+	root := &Command{Name: "app"}
+	cron := &Command{Name: "cron", Short: "Manage jobs"}
+	add := &Command{Name: "add", Short: "Add a job", Args: NoArgs}
+	name := add.Flags().String("name", "n", "", "name", "Job name")
+	add.Flags().Choice("overlap", "", "", []string{"skip", "queue"}, "Overlap policy")
+	add.Run = func(cmd *Command, args []string) error {
+		if *name == "" {
+			return Usagef(cmd, "--name is required")
+		}
+		*ran = "add " + *name
+		return nil
+	}
+	remove := &Command{
+		Name:    "remove",
+		Aliases: []string{"rm"},
+		Short:   "Remove a job",
+		Args:    ExactArgs(1),
+		Run: func(_ *Command, args []string) error {
+			*ran = "remove " + args[0]
+			return nil
+		},
+	}
+	cron.AddCommand(add, remove)
+	root.AddCommand(cron, NewCompletionCommand())
+	return root
+}
+
+func TestExecute(t *testing.T) {
+	var ran string
+	var out bytes.Buffer
+	// A tree parses one command line, like a process does
+	execute := func(args ...string) error {
+		out.Reset()
+		root := testTree(&ran)
+		root.Out = &out
+		return root.Execute(args)
+	}
+
+	if err := execute("cron", "add", "-n", "daily"); err != nil || ran != "add daily" {
+		t.Fatalf("add: err=%v ran=%q", err, ran)
+	}
+	if err := execute("cron", "rm", "42"); err != nil || ran != "remove 42" {
+		t.Fatalf("alias: err=%v ran=%q", err, ran)
+	}
+
+	var usageErr *UsageError
+	err := execute("cron", "remove")
+	if !errors.As(err, &usageErr) || usageErr.Cmd.Path() != "app cron remove" {
+		t.Errorf("missing argument should be a usage error of the subcommand, got %v", err)
+	}
+	if err := execute("cron", "bogus"); !errors.As(err, &usageErr) {
+		t.Errorf("unknown subcommand should be a usage error, got %v", err)
+	}
+	if err := execute("cron", "add"); err == nil || !strings.Contains(err.Error(), "--name is required") {
+		t.Errorf("Run error not returned: %v", err)
+	}
+
+	for _, args := range [][]string{{"cron", "add", "--help"}, {"help", "cron", "add"}, {"cron", "help", "add"}} {
+		if err := execute(args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		help := out.String()
+		for _, want := range []string{"Usage: app cron add [options]", "-n, --name <name>", "--overlap <skip|queue>", "-h, --help"} {
+			if !strings.Contains(help, want) {
+				t.Errorf("%v: help missing %q:\n%s", args, want, help)
+			}
+		}
+	}
+
+	execute("cron")
+	if !strings.Contains(out.String(), "add     Add a job") || !strings.Contains(out.String(), "remove  Remove a job") {
+		t.Errorf("group help should list subcommands:\n%s", out.String())
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	var ran string
+	var out bytes.Buffer
+	root := testTree(&ran)
+	root.Out = &out
+
+	checks := map[string][]string{
+		"bash": {
+			"complete -o default -F _app app",
+			"'app cron rm') cmdpath='app cron remove'",
+			"'app cron add --overlap') COMPREPLY=($(compgen -W 'skip queue'",
+			"commands='add remove'",
+			"commands='bash zsh fish'",
+		},
+		"zsh": {
+			"#compdef app",
+			"'add:Add a job'",
+			"'--name:Job name'",
+			"'app cron add --overlap') compadd -- skip queue",
+		},
+		"fish": {
+			"function __app_path",
+			"complete -c app -n 'test (__app_path) = \\'app cron\\'' -f -a 'add' -d 'Add a job'",
+			"-l overlap -r -f -a 'skip queue'",
+			"-l name -s n -r -d 'Job name'",
+		},
+	}
+	for shell, wants := range checks {
+		out.Reset()
+		if err := root.Execute([]string{"completion", shell}); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s script missing %q", shell, want)
+			}
+		}
+	}
+
+	if err := root.Execute([]string{"completion", "tcsh"}); err == nil {
+		t.Error("unsupported shell should fail")
+	}
+}
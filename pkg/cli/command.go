@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Command is one node of the command tree. A command either runs something,
+// groups subcommands, or both (e.g. "pepebot agent" chats unless the first
+// argument names a subcommand).
+type Command struct {
+	Name      string
+	Aliases   []string
+	Short     string // one line shown in command lists
+	Long      string // printed below the usage line in help
+	ArgsUsage string // positional arguments for the usage line, e.g. "<job_id>"
+	Example   string
+	Hidden    bool
+	// ValidArgs are offered by shell completion for the first argument
+	ValidArgs []string
+
+	// Args validates the positional arguments; nil accepts anything
+	Args func(args []string) error
+	Run  func(cmd *Command, args []string) error
+	// Help replaces the generated help text
+	Help func(cmd *Command)
+
+	// Out receives help and generated output; set it on the root command.
+	// Defaults to stdout.
+	Out io.Writer
+
+	parent *Command
+	subs   []*Command
+	flags  *FlagSet
+}
+
+// UsageError is a mistake in the command line rather than a failure of the
+// command itself. Callers print it with a pointer to the command's help.
+type UsageError struct {
+	Cmd *Command
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// Usagef builds a UsageError for cmd
+func Usagef(cmd *Command, format string, a ...interface{}) error {
+	return &UsageError{Cmd: cmd, Err: fmt.Errorf(format, a...)}
+}
+
+// NoArgs rejects positional arguments
+func NoArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected argument %q", args[0])
+	}
+	return nil
+}
+
+// ExactArgs requires exactly n positional arguments
+func ExactArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("expected %d argument(s), got %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// MinArgs requires at least n positional arguments
+func MinArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) < n {
+			return fmt.Errorf("expected at least %d argument(s), got %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// MaxArgs allows at most n positional arguments
+func MaxArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) > n {
+			return fmt.Errorf("expected at most %d argument(s), got %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// Flags returns the command's flag set, creating it on first use
+func (c *Command) Flags() *FlagSet {
+	if c.flags == nil {
+		c.flags = &FlagSet{}
+	}
+	return c.flags
+}
+
+// AddCommand attaches subcommands
+func (c *Command) AddCommand(cmds ...*Command) {
+	for _, sub := range cmds {
+		sub.parent = c
+		c.subs = append(c.subs, sub)
+	}
+}
+
+// Commands returns the subcommands in the order they were added
+func (c *Command) Commands() []*Command {
+	return c.subs
+}
+
+// Parent returns the enclosing command, nil for the root
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// Root returns the top of the tree
+func (c *Command) Root() *Command {
+	for c.parent != nil {
+		c = c.parent
+	}
+	return c
+}
+
+// Path returns the full command name, e.g. "pepebot cron add"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// OutOrStdout returns the writer for help and generated output
+func (c *Command) OutOrStdout() io.Writer {
+	if out := c.Root().Out; out != nil {
+		return out
+	}
+	return os.Stdout
+}
+
+// Find returns the subcommand called name or one of its aliases
+func (c *Command) Find(name string) *Command {
+	for _, sub := range c.subs {
+		if sub.Name == name {
+			return sub
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// Execute resolves the subcommand named by args, parses its flags and runs
+// it. "help [command...]", -h and --help print help at any level.
+func (c *Command) Execute(args []string) error {
+	cmd := c
+	for len(args) > 0 {
+		if args[0] == "help" && len(cmd.subs) > 0 {
+			target := cmd
+			for _, name := range args[1:] {
+				sub := target.Find(name)
+				if sub == nil {
+					return Usagef(target, "unknown command %q for %s", name, target.Path())
+				}
+				target = sub
+			}
+			target.PrintHelp()
+			return nil
+		}
+		sub := cmd.Find(args[0])
+		if sub == nil {
+			break
+		}
+		cmd, args = sub, args[1:]
+	}
+
+	if cmd.Run == nil {
+		// Pure group: show what is available
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+			cmd.PrintHelp()
+			return nil
+		}
+		return Usagef(cmd, "unknown command %q for %s", args[0], cmd.Path())
+	}
+
+	fs := cmd.Flags()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, ErrHelp) {
+			cmd.PrintHelp()
+			return nil
+		}
+		return &UsageError{Cmd: cmd, Err: err}
+	}
+	if cmd.Args != nil {
+		if err := cmd.Args(fs.Args()); err != nil {
+			return &UsageError{Cmd: cmd, Err: err}
+		}
+	}
+	return cmd.Run(cmd, fs.Args())
+}
+
+// PrintHelp writes the command's help to its output
+func (c *Command) PrintHelp() {
+	if c.Help != nil {
+		c.Help(c)
+		return
+	}
+	c.WriteHelp(c.OutOrStdout())
+}
+
+// UsageLine returns the one-line synopsis, e.g.
+// "pepebot cron add [options]"
+func (c *Command) UsageLine() string {
+	line := c.Path()
+	if len(c.subs) > 0 {
+		if c.Run != nil {
+			line += " [command]"
+		} else {
+			line += " <command>"
+		}
+	}
+	if c.flags != nil && len(c.flags.flags) > 0 {
+		line += " [options]"
+	}
+	if c.ArgsUsage != "" {
+		line += " " + c.ArgsUsage
+	}
+	return line
+}
+
+// WriteHelp writes the generated help text
+func (c *Command) WriteHelp(w io.Writer) {
+	fmt.Fprintf(w, "\nUsage: %s\n", c.UsageLine())
+	if c.Short != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Short)
+	}
+	if c.Long != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(c.Long, "\n"))
+	}
+
+	var visible []*Command
+	width := 0
+	for _, sub := range c.subs {
+		if sub.Hidden {
+			continue
+		}
+		visible = append(visible, sub)
+		if len(sub.Name) > width {
+			width = len(sub.Name)
+		}
+	}
+	if len(visible) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range visible {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, sub.Name, sub.Short)
+		}
+	}
+
+	if c.Run != nil {
+		fmt.Fprintln(w, "\nOptions:")
+		printFlags(w, c.allFlags())
+	}
+
+	if c.Example != "" {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, line := range strings.Split(strings.TrimRight(c.Example, "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	if len(visible) > 0 {
+		fmt.Fprintf(w, "\nRun '%s <command> --help' for details on a command.\n", c.Path())
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// NewCompletionCommand returns the "completion <shell>" command. The scripts
+// are generated from the command tree it is attached to.
+func NewCompletionCommand() *Command {
+	cmd := &Command{
+		Name:      "completion",
+		Short:     "Generate shell completion scripts (bash, zsh, fish)",
+		ArgsUsage: "<bash|zsh|fish>",
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args:      ExactArgs(1),
+	}
+	cmd.Help = func(c *Command) {
+		name := c.Root().Name
+		c.Long = fmt.Sprintf(`To load completions in the current shell:
+  bash:  source <(%[1]s completion bash)
+  zsh:   source <(%[1]s completion zsh)
+  fish:  %[1]s completion fish | source
+
+To load them for every session:
+  bash:  %[1]s completion bash > ~/.local/share/bash-completion/completions/%[1]s
+  zsh:   %[1]s completion zsh > "${fpath[1]}/_%[1]s"
+  fish:  %[1]s completion fish > ~/.config/fish/completions/%[1]s.fish`, name)
+		c.WriteHelp(c.OutOrStdout())
+	}
+	cmd.Run = func(c *Command, args []string) error {
+		root, w := c.Root(), c.OutOrStdout()
+		switch args[0] {
+		case "bash":
+			return root.GenBashCompletion(w)
+		case "zsh":
+			return root.GenZshCompletion(w)
+		case "fish":
+			return root.GenFishCompletion(w)
+		default:
+			return Usagef(c, "unsupported shell %q (use bash, zsh or fish)", args[0])
+		}
+	}
+	return cmd
+}
+
+// walk visits every visible command, parents before children
+func (c *Command) walk(fn func(*Command)) {
+	fn(c)
+	for _, sub := range c.subs {
+		if !sub.Hidden {
+			sub.walk(fn)
+		}
+	}
+}
+
+// pathCases maps "<parent path> <word>" to the resolved command path for
+// every subcommand name and alias, sorted for stable output
+func (c *Command) pathCases() [][2]string {
+	var cases [][2]string
+	c.walk(func(cmd *Command) {
+		if cmd.parent == nil {
+			return
+		}
+		parent := cmd.parent.Path()
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			cases = append(cases, [2]string{parent + " " + name, cmd.Path()})
+		}
+	})
+	sort.Slice(cases, func(i, j int) bool { return cases[i][0] < cases[j][0] })
+	return cases
+}
+
+// allFlags returns the flags of cmd plus the implicit --help
+func (c *Command) allFlags() []*Flag {
+	var flags []*Flag
+	if c.flags != nil {
+		flags = append(flags, c.flags.flags...)
+	}
+	return append(flags, &Flag{Name: "help", Short: "h", Usage: "Show this help", isBool: true})
+}
+
+func (c *Command) visibleCommands() []*Command {
+	var subs []*Command
+	for _, sub := range c.subs {
+		if !sub.Hidden {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// GenBashCompletion writes a bash completion script for the tree
+func (c *Command) GenBashCompletion(w io.Writer) error {
+	name := c.Name
+	fn := "_" + strings.ReplaceAll(name, "-", "_")
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# bash completion for %s\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur prev cmdpath word i commands flags\n")
+	b.WriteString("    COMPREPLY=()\n")
+	b.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(&b, "    cmdpath=%s\n", shellQuote(name))
+	b.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("        word=\"${COMP_WORDS[i]}\"\n")
+	b.WriteString("        case \"$cmdpath $word\" in\n")
+	for _, pc := range c.pathCases() {
+		fmt.Fprintf(&b, "            %s) cmdpath=%s ;;\n", shellQuote(pc[0]), shellQuote(pc[1]))
+	}
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n\n")
+
+	// Values of the flag just typed
+	b.WriteString("    case \"$cmdpath $prev\" in\n")
+	c.walk(func(cmd *Command) {
+		for _, f := range cmd.allFlags() {
+			if f.isBool {
+				continue
+			}
+			action := "return"
+			if len(f.Choices) > 0 {
+				action = fmt.Sprintf("COMPREPLY=($(compgen -W %s -- \"$cur\")); return", shellQuote(strings.Join(f.Choices, " ")))
+			}
+			for _, label := range flagLabels(f) {
+				fmt.Fprintf(&b, "        %s) %s ;;\n", shellQuote(cmd.Path()+" "+label), action)
+			}
+		}
+	})
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    case \"$cmdpath\" in\n")
+	c.walk(func(cmd *Command) {
+		var commands, flags []string
+		for _, sub := range cmd.visibleCommands() {
+			commands = append(commands, sub.Name)
+		}
+		commands = append(commands, cmd.ValidArgs...)
+		for _, f := range cmd.allFlags() {
+			flags = append(flags, flagLabels(f)...)
+		}
+		fmt.Fprintf(&b, "        %s)\n", shellQuote(cmd.Path()))
+		fmt.Fprintf(&b, "            commands=%s\n", shellQuote(strings.Join(commands, " ")))
+		fmt.Fprintf(&b, "            flags=%s\n", shellQuote(strings.Join(flags, " ")))
+		b.WriteString("            ;;\n")
+	})
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	b.WriteString("    elif [[ -n \"$commands\" ]]; then\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$commands\" -- \"$cur\"))\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, name)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// GenZshCompletion writes a zsh completion script for the tree
+func (c *Command) GenZshCompletion(w io.Writer) error {
+	name := c.Name
+	fn := "_" + strings.ReplaceAll(name, "-", "_")
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "#compdef %s\n\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	// "path" is special in zsh (tied to $PATH), hence cmdpath
+	fmt.Fprintf(&b, "    local cmdpath=%s word i\n", shellQuote(name))
+	b.WriteString("    local -a commands flags\n")
+	b.WriteString("    for ((i = 2; i < CURRENT; i++)); do\n")
+	b.WriteString("        word=\"${words[i]}\"\n")
+	b.WriteString("        case \"$cmdpath $word\" in\n")
+	for _, pc := range c.pathCases() {
+		fmt.Fprintf(&b, "            %s) cmdpath=%s ;;\n", shellQuote(pc[0]), shellQuote(pc[1]))
+	}
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n\n")
+
+	b.WriteString("    case \"$cmdpath ${words[CURRENT-1]}\" in\n")
+	c.walk(func(cmd *Command) {
+		for _, f := range cmd.allFlags() {
+			if f.isBool {
+				continue
+			}
+			action := "_files; return"
+			if len(f.Choices) > 0 {
+				action = "compadd -- " + strings.Join(f.Choices, " ") + "; return"
+			}
+			for _, label := range flagLabels(f) {
+				fmt.Fprintf(&b, "        %s) %s ;;\n", shellQuote(cmd.Path()+" "+label), action)
+			}
+		}
+	})
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    case \"$cmdpath\" in\n")
+	c.walk(func(cmd *Command) {
+		fmt.Fprintf(&b, "        %s)\n", shellQuote(cmd.Path()))
+		b.WriteString("            commands=(")
+		for _, sub := range cmd.visibleCommands() {
+			b.WriteString(" " + shellQuote(zshEscape(sub.Name)+":"+zshEscape(sub.Short)))
+		}
+		for _, arg := range cmd.ValidArgs {
+			b.WriteString(" " + shellQuote(zshEscape(arg)))
+		}
+		b.WriteString(" )\n")
+		b.WriteString("            flags=(")
+		for _, f := range cmd.allFlags() {
+			for _, label := range flagLabels(f) {
+				b.WriteString(" " + shellQuote(zshEscape(label)+":"+zshEscape(f.Usage)))
+			}
+		}
+		b.WriteString(" )\n")
+		b.WriteString("            ;;\n")
+	})
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    if [[ \"$PREFIX\" == -* ]]; then\n")
+	b.WriteString("        _describe -t options 'option' flags\n")
+	b.WriteString("    elif (( ${#commands} )); then\n")
+	b.WriteString("        _describe -t commands 'command' commands\n")
+	b.WriteString("    else\n")
+	b.WriteString("        _files\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, name)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// GenFishCompletion writes a fish completion script for the tree
+func (c *Command) GenFishCompletion(w io.Writer) error {
+	name := c.Name
+	fn := "__" + strings.ReplaceAll(name, "-", "_") + "_path"
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# fish completion for %s\n", name)
+	fmt.Fprintf(&b, "function %s\n", fn)
+	fmt.Fprintf(&b, "    set -l cmdpath %s\n", shellQuote(name))
+	b.WriteString("    for word in (commandline -opc)[2..-1]\n")
+	b.WriteString("        switch \"$cmdpath $word\"\n")
+	for _, pc := range c.pathCases() {
+		fmt.Fprintf(&b, "            case %s\n", fishQuote(pc[0]))
+		fmt.Fprintf(&b, "                set cmdpath %s\n", fishQuote(pc[1]))
+	}
+	b.WriteString("        end\n")
+	b.WriteString("    end\n")
+	b.WriteString("    echo $cmdpath\n")
+	b.WriteString("end\n\n")
+
+	c.walk(func(cmd *Command) {
+		cond := fishQuote(fmt.Sprintf("test (%s) = %s", fn, shellQuote(cmd.Path())))
+		for _, sub := range cmd.visibleCommands() {
+			fmt.Fprintf(&b, "complete -c %s -n %s -f -a %s -d %s\n", name, cond, fishQuote(sub.Name), fishQuote(sub.Short))
+		}
+		if len(cmd.ValidArgs) > 0 {
+			fmt.Fprintf(&b, "complete -c %s -n %s -f -a %s\n", name, cond, fishQuote(strings.Join(cmd.ValidArgs, " ")))
+		}
+		for _, f := range cmd.allFlags() {
+			line := fmt.Sprintf("complete -c %s -n %s -l %s", name, cond, f.Name)
+			if f.Short != "" {
+				line += " -s " + f.Short
+			}
+			if !f.isBool {
+				line += " -r"
+			}
+			if len(f.Choices) > 0 {
+				line += " -f -a " + fishQuote(strings.Join(f.Choices, " "))
+			}
+			fmt.Fprintf(&b, "%s -d %s\n", line, fishQuote(f.Usage))
+		}
+	})
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func flagLabels(f *Flag) []string {
+	labels := []string{"--" + f.Name}
+	if f.Short != "" {
+		labels = append(labels, "-"+f.Short)
+	}
+	return labels
+}
+
+// shellQuote single-quotes s for bash and zsh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s for fish, where only \ and ' are special
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// zshEscape protects the ':' separator used by _describe
+func zshEscape(s string) string {
+	return strings.ReplaceAll(s, ":", `\:`)
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrHelp is returned by Parse when -h or --help is given
+var ErrHelp = errors.New("help requested")
+
+// Flag describes one option of a command. The typed helpers on FlagSet
+// create flags and return a pointer to the parsed value.
+type Flag struct {
+	Name    string   // long name, used as --name
+	Short   string   // optional one-letter alias, used as -s
+	Usage   string   // one-line description for help output
+	ArgName string   // placeholder shown in help, e.g. "path"; empty for bool flags
+	Default string   // default shown in help when not empty
+	Choices []string // allowed values, also offered by shell completion
+	Repeat  bool     // flag may be given more than once
+
+	set     func(string) error
+	isBool  bool
+	changed bool
+}
+
+// FlagSet parses GNU style options: "--name value", "--name=value",
+// "-n value" and bare "--flag" for booleans. Options and positional
+// arguments may be mixed; everything after "--" is positional.
+type FlagSet struct {
+	flags []*Flag
+	byKey map[string]*Flag
+	args  []string
+}
+
+func (fs *FlagSet) add(f *Flag) *Flag {
+	if fs.byKey == nil {
+		fs.byKey = make(map[string]*Flag)
+	}
+	if _, dup := fs.byKey[f.Name]; dup {
+		panic("cli: duplicate flag --" + f.Name)
+	}
+	fs.byKey[f.Name] = f
+	if f.Short != "" {
+		if _, dup := fs.byKey[f.Short]; dup {
+			panic("cli: duplicate flag -" + f.Short)
+		}
+		fs.byKey[f.Short] = f
+	}
+	fs.flags = append(fs.flags, f)
+	return f
+}
+
+// String defines a string option
+func (fs *FlagSet) String(name, short, def, argName, usage string) *string {
+	v := def
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, ArgName: argName, Default: def})
+	f.set = func(s string) error {
+		v = s
+		return nil
+	}
+	return &v
+}
+
+// Choice defines a string option limited to the given values
+func (fs *FlagSet) Choice(name, short, def string, choices []string, usage string) *string {
+	v := def
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, ArgName: strings.Join(choices, "|"), Default: def, Choices: choices})
+	f.set = func(s string) error {
+		for _, c := range choices {
+			if s == c {
+				v = s
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(choices, ", "))
+	}
+	return &v
+}
+
+// Bool defines a switch. "--name=false" turns it off again.
+func (fs *FlagSet) Bool(name, short, usage string) *bool {
+	v := false
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, isBool: true})
+	f.set = func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		v = b
+		return nil
+	}
+	return &v
+}
+
+// Int defines an integer option
+func (fs *FlagSet) Int(name, short string, def int, usage string) *int {
+	v := def
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, ArgName: "n"})
+	if def != 0 {
+		f.Default = strconv.Itoa(def)
+	}
+	f.set = func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		v = n
+		return nil
+	}
+	return &v
+}
+
+// Float defines a floating point option
+func (fs *FlagSet) Float(name, short string, def float64, usage string) *float64 {
+	v := def
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, ArgName: "n"})
+	if def != 0 {
+		f.Default = strconv.FormatFloat(def, 'g', -1, 64)
+	}
+	f.set = func(s string) error {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		v = n
+		return nil
+	}
+	return &v
+}
+
+// StringSlice defines a repeatable option collecting every value given
+func (fs *FlagSet) StringSlice(name, short, argName, usage string) *[]string {
+	var v []string
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, ArgName: argName, Repeat: true})
+	f.set = func(s string) error {
+		v = append(v, s)
+		return nil
+	}
+	return &v
+}
+
+// KeyValue defines a repeatable key=value option, e.g. --var device=pixel
+func (fs *FlagSet) KeyValue(name, short, usage string) map[string]string {
+	v := map[string]string{}
+	f := fs.add(&Flag{Name: name, Short: short, Usage: usage, ArgName: "key=value", Repeat: true})
+	f.set = func(s string) error {
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("%q is not in key=value format", s)
+		}
+		v[parts[0]] = parts[1]
+		return nil
+	}
+	return v
+}
+
+// Changed reports whether the flag was given on the command line
+func (fs *FlagSet) Changed(name string) bool {
+	f := fs.byKey[name]
+	return f != nil && f.changed
+}
+
+// Args returns the positional arguments left after Parse
+func (fs *FlagSet) Args() []string {
+	return fs.args
+}
+
+// Flags returns the defined flags in definition order
+func (fs *FlagSet) Flags() []*Flag {
+	return fs.flags
+}
+
+// Parse reads args, setting flag values and collecting positionals
+func (fs *FlagSet) Parse(args []string) error {
+	fs.args = nil
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			fs.args = append(fs.args, args[i+1:]...)
+			return nil
+		}
+		if len(arg) < 2 || arg[0] != '-' || isNumber(arg) {
+			fs.args = append(fs.args, arg)
+			continue
+		}
+		if arg == "-h" || arg == "--help" {
+			return ErrHelp
+		}
+
+		key, value, hasValue := strings.TrimLeft(arg, "-"), "", false
+		if eq := strings.IndexByte(key, '='); eq >= 0 {
+			key, value, hasValue = key[:eq], key[eq+1:], true
+		}
+		long := strings.HasPrefix(arg, "--")
+		f := fs.byKey[key]
+		if f == nil || (long && key != f.Name) || (!long && key != f.Short) {
+			return fmt.Errorf("unknown flag %s", strings.SplitN(arg, "=", 2)[0])
+		}
+		if f.changed && !f.Repeat {
+			return fmt.Errorf("flag %s given more than once", flagLabel(f, long))
+		}
+
+		if !hasValue {
+			if f.isBool {
+				value = "true"
+			} else {
+				if i+1 >= len(args) {
+					return fmt.Errorf("flag %s needs a value", flagLabel(f, long))
+				}
+				i++
+				value = args[i]
+			}
+		}
+		if err := f.set(value); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", value, flagLabel(f, long), err)
+		}
+		f.changed = true
+	}
+	return nil
+}
+
+// PrintDefaults writes the flag table used in help output
+func (fs *FlagSet) PrintDefaults(w io.Writer) {
+	printFlags(w, fs.flags)
+}
+
+func printFlags(w io.Writer, flags []*Flag) {
+	labels := make([]string, len(flags))
+	width := 0
+	for i, f := range flags {
+		label := "    --" + f.Name
+		if f.Short != "" {
+			label = "-" + f.Short + ", --" + f.Name
+		}
+		if f.ArgName != "" {
+			label += " <" + f.ArgName + ">"
+		}
+		labels[i] = label
+		if len(label) > width {
+			width = len(label)
+		}
+	}
+	for i, f := range flags {
+		usage := f.Usage
+		if f.Default != "" {
+			usage += fmt.Sprintf(" (default: %s)", f.Default)
+		}
+		if f.Repeat {
+			usage += " (repeatable)"
+		}
+		fmt.Fprintf(w, "  %-*s  %s\n", width, labels[i], usage)
+	}
+}
+
+func flagLabel(f *Flag, long bool) string {
+	if long || f.Short == "" {
+		return "--" + f.Name
+	}
+	return "-" + f.Short
+}
+
+// isNumber lets negative numbers through as positional arguments
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -11,13 +11,30 @@ import (
 )
 
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers"`
-	Gateway   GatewayConfig   `json:"gateway"`
-	Live      LiveConfig      `json:"live"`
-	Tools     ToolsConfig     `json:"tools"`
-	mu        sync.RWMutex
+	Agents     AgentsConfig          `json:"agents"`
+	Channels   ChannelsConfig        `json:"channels"`
+	Providers  ProvidersConfig       `json:"providers"`
+	Gateway    GatewayConfig         `json:"gateway"`
+	Embeddings EmbeddingsConfig      `json:"embeddings"`
+	Models     ModelsConfig          `json:"models"`
+	Live       LiveConfig            `json:"live"`
+	Tools      ToolsConfig           `json:"tools"`
+	Broadcast  BroadcastConfig       `json:"broadcast"`
+	Outbox     OutboxConfig          `json:"outbox"`
+	Cron       CronConfig            `json:"cron"`
+	Bus        BusConfig             `json:"bus"`
+	Digest     DigestConfig          `json:"digest"`
+	Batch      BatchConfig           `json:"batch"`
+	Heartbeat  HeartbeatConfig       `json:"heartbeat"`
+	Feeds      FeedsConfig           `json:"feeds"`
+	Sync       SyncConfig            `json:"sync"`
+	Skills     SkillsConfig          `json:"skills"`
+	Hooks      map[string]HookConfig `json:"hooks,omitempty"`
+	Middleware []MiddlewareConfig    `json:"middleware,omitempty"`
+	Filter     FilterConfig          `json:"filter"`
+	Voice      VoiceConfig           `json:"voice"`
+	Feedback   FeedbackConfig        `json:"feedback"`
+	mu         sync.RWMutex
 }
 
 type AgentsConfig struct {
@@ -25,12 +42,62 @@ type AgentsConfig struct {
 }
 
 type AgentDefaults struct {
-	Workspace         string  `json:"workspace" env:"PEPEBOT_AGENTS_DEFAULTS_WORKSPACE"`
-	Model             string  `json:"model" env:"PEPEBOT_AGENTS_DEFAULTS_MODEL"`
-	Provider          string  `json:"provider,omitempty" env:"PEPEBOT_AGENTS_DEFAULTS_PROVIDER"`
-	MaxTokens         int     `json:"max_tokens" env:"PEPEBOT_AGENTS_DEFAULTS_MAX_TOKENS"`
-	Temperature       float64 `json:"temperature" env:"PEPEBOT_AGENTS_DEFAULTS_TEMPERATURE"`
-	MaxToolIterations int     `json:"max_tool_iterations" env:"PEPEBOT_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	Workspace         string              `json:"workspace" env:"PEPEBOT_AGENTS_DEFAULTS_WORKSPACE"`
+	Model             string              `json:"model" env:"PEPEBOT_AGENTS_DEFAULTS_MODEL"`
+	Provider          string              `json:"provider,omitempty" env:"PEPEBOT_AGENTS_DEFAULTS_PROVIDER"`
+	MaxTokens         int                 `json:"max_tokens" env:"PEPEBOT_AGENTS_DEFAULTS_MAX_TOKENS"`
+	ContextWindow     int                 `json:"context_window,omitempty" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_WINDOW"` // 0 detects it from the model
+	Temperature       float64             `json:"temperature" env:"PEPEBOT_AGENTS_DEFAULTS_TEMPERATURE"`
+	MaxToolIterations int                 `json:"max_tool_iterations" env:"PEPEBOT_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	MaxConcurrency    int                 `json:"max_concurrency" env:"PEPEBOT_AGENTS_DEFAULTS_MAX_CONCURRENCY"`
+	MaxParallelTools  int                 `json:"max_parallel_tools" env:"PEPEBOT_AGENTS_DEFAULTS_MAX_PARALLEL_TOOLS"`
+	TypingIndicator   bool                `json:"typing_indicator" env:"PEPEBOT_AGENTS_DEFAULTS_TYPING_INDICATOR"`
+	ProgressUpdates   bool                `json:"progress_updates" env:"PEPEBOT_AGENTS_DEFAULTS_PROGRESS_UPDATES"`
+	Verify            bool                `json:"verify" env:"PEPEBOT_AGENTS_DEFAULTS_VERIFY"`
+	MemoryExtraction  bool                `json:"memory_extraction" env:"PEPEBOT_AGENTS_DEFAULTS_MEMORY_EXTRACTION"`
+	SessionIsolation  bool                `json:"session_isolation" env:"PEPEBOT_AGENTS_DEFAULTS_SESSION_ISOLATION"`
+	ContextBudget     ContextBudgetConfig `json:"context_budget"`
+	DailyNotes        DailyNotesConfig    `json:"daily_notes"`
+	Reasoning         ReasoningConfig     `json:"reasoning"`
+	Limits            TurnLimitsConfig    `json:"limits"`
+}
+
+// DailyNotesConfig controls which daily notes (memory/daily/YYYY-MM-DD.md)
+// are included in the prompt. Today's note is always included.
+type DailyNotesConfig struct {
+	IncludeYesterday bool `json:"include_yesterday" env:"PEPEBOT_AGENTS_DEFAULTS_DAILY_NOTES_INCLUDE_YESTERDAY"`
+}
+
+// TurnLimitsConfig bounds a single agent turn. When the turn or its tool
+// output hits a limit, the agent summarizes its progress and replies
+// instead of continuing. Zero disables a limit.
+type TurnLimitsConfig struct {
+	MaxTurnSeconds     int `json:"max_turn_seconds" env:"PEPEBOT_AGENTS_DEFAULTS_LIMITS_MAX_TURN_SECONDS"`
+	MaxToolSeconds     int `json:"max_tool_seconds" env:"PEPEBOT_AGENTS_DEFAULTS_LIMITS_MAX_TOOL_SECONDS"`
+	MaxToolOutputBytes int `json:"max_tool_output_bytes" env:"PEPEBOT_AGENTS_DEFAULTS_LIMITS_MAX_TOOL_OUTPUT_BYTES"`
+}
+
+// ReasoningConfig enables thinking on reasoning models. Effort is sent as
+// reasoning_effort (OpenAI o-series) and converted to a thinking budget for
+// Claude and Gemini unless BudgetTokens is set. Thinking never reaches
+// channel output; Log writes it to the log.
+type ReasoningConfig struct {
+	Effort       string `json:"effort,omitempty" env:"PEPEBOT_AGENTS_DEFAULTS_REASONING_EFFORT"` // "", "low", "medium", "high"
+	BudgetTokens int    `json:"budget_tokens,omitempty" env:"PEPEBOT_AGENTS_DEFAULTS_REASONING_BUDGET_TOKENS"`
+	Log          bool   `json:"log,omitempty" env:"PEPEBOT_AGENTS_DEFAULTS_REASONING_LOG"`
+}
+
+// ContextBudgetConfig caps how much of the model's context window each part
+// of the prompt may use. Ratios are shares of what is left after the base
+// system prompt, the current message, tool definitions and max_tokens for
+// the reply. Window 0 disables budgeting.
+type ContextBudgetConfig struct {
+	Window    int     `json:"window" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_BUDGET_WINDOW"`
+	Bootstrap float64 `json:"bootstrap" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_BUDGET_BOOTSTRAP"` // AGENTS.md, SOUL.md, USER.md, TOOLS.md, IDENTITY.md
+	Memory    float64 `json:"memory" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_BUDGET_MEMORY"`
+	Skills    float64 `json:"skills" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_BUDGET_SKILLS"`
+	Summary   float64 `json:"summary" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_BUDGET_SUMMARY"`
+	History   float64 `json:"history" env:"PEPEBOT_AGENTS_DEFAULTS_CONTEXT_BUDGET_HISTORY"`
 }
 
 type ChannelsConfig struct {
@@ -42,15 +109,19 @@ type ChannelsConfig struct {
 }
 
 type WhatsAppConfig struct {
-	Enabled   bool     `json:"enabled" env:"PEPEBOT_CHANNELS_WHATSAPP_ENABLED"`
-	DBPath    string   `json:"db_path" env:"PEPEBOT_CHANNELS_WHATSAPP_DB_PATH"`
-	AllowFrom []string `json:"allow_from" env:"PEPEBOT_CHANNELS_WHATSAPP_ALLOW_FROM"`
+	Enabled      bool     `json:"enabled" env:"PEPEBOT_CHANNELS_WHATSAPP_ENABLED"`
+	DBPath       string   `json:"db_path" env:"PEPEBOT_CHANNELS_WHATSAPP_DB_PATH"`
+	AllowFrom    []string `json:"allow_from" env:"PEPEBOT_CHANNELS_WHATSAPP_ALLOW_FROM"`
+	GroupMode    string   `json:"group_mode" env:"PEPEBOT_CHANNELS_WHATSAPP_GROUP_MODE"`
+	GroupTrigger string   `json:"group_trigger" env:"PEPEBOT_CHANNELS_WHATSAPP_GROUP_TRIGGER"`
 }
 
 type TelegramConfig struct {
-	Enabled   bool     `json:"enabled" env:"PEPEBOT_CHANNELS_TELEGRAM_ENABLED"`
-	Token     string   `json:"token" env:"PEPEBOT_CHANNELS_TELEGRAM_TOKEN"`
-	AllowFrom []string `json:"allow_from" env:"PEPEBOT_CHANNELS_TELEGRAM_ALLOW_FROM"`
+	Enabled      bool     `json:"enabled" env:"PEPEBOT_CHANNELS_TELEGRAM_ENABLED"`
+	Token        string   `json:"token" env:"PEPEBOT_CHANNELS_TELEGRAM_TOKEN"`
+	AllowFrom    []string `json:"allow_from" env:"PEPEBOT_CHANNELS_TELEGRAM_ALLOW_FROM"`
+	GroupMode    string   `json:"group_mode" env:"PEPEBOT_CHANNELS_TELEGRAM_GROUP_MODE"`
+	GroupTrigger string   `json:"group_trigger" env:"PEPEBOT_CHANNELS_TELEGRAM_GROUP_TRIGGER"`
 }
 
 type FeishuConfig struct {
@@ -63,9 +134,11 @@ type FeishuConfig struct {
 }
 
 type DiscordConfig struct {
-	Enabled   bool     `json:"enabled" env:"PEPEBOT_CHANNELS_DISCORD_ENABLED"`
-	Token     string   `json:"token" env:"PEPEBOT_CHANNELS_DISCORD_TOKEN"`
-	AllowFrom []string `json:"allow_from" env:"PEPEBOT_CHANNELS_DISCORD_ALLOW_FROM"`
+	Enabled      bool     `json:"enabled" env:"PEPEBOT_CHANNELS_DISCORD_ENABLED"`
+	Token        string   `json:"token" env:"PEPEBOT_CHANNELS_DISCORD_TOKEN"`
+	AllowFrom    []string `json:"allow_from" env:"PEPEBOT_CHANNELS_DISCORD_ALLOW_FROM"`
+	GroupMode    string   `json:"group_mode" env:"PEPEBOT_CHANNELS_DISCORD_GROUP_MODE"`
+	GroupTrigger string   `json:"group_trigger" env:"PEPEBOT_CHANNELS_DISCORD_GROUP_TRIGGER"`
 }
 
 type MaixCamConfig struct {
@@ -86,6 +159,7 @@ type ProvidersConfig struct {
 	Gemini     GeminiConfig     `json:"gemini"`
 	Vertex     VertexConfig     `json:"vertex"`
 	OpenCodeGo OpenCodeGoConfig `json:"opencodego"`
+	Mock       MockConfig       `json:"mock"`
 }
 
 type MAIARouterConfig struct {
@@ -139,9 +213,83 @@ type OpenCodeGoConfig struct {
 	APIBase string `json:"api_base" env:"PEPEBOT_PROVIDERS_OPENCODEGO_API_BASE"`
 }
 
+// MockConfig points the "mock" provider at a script of replies, for
+// offline tests and evals
+type MockConfig struct {
+	File string `json:"file,omitempty" env:"PEPEBOT_PROVIDERS_MOCK_FILE"`
+}
+
 type GatewayConfig struct {
 	Host string `json:"host" env:"PEPEBOT_GATEWAY_HOST"`
 	Port int    `json:"port" env:"PEPEBOT_GATEWAY_PORT"`
+	// ToolMode is "agent" (default: pepebot runs its own tools) or
+	// "passthrough" (client tools are forwarded and tool_calls returned).
+	// The X-Tool-Mode header overrides it per request.
+	ToolMode   string           `json:"tool_mode,omitempty" env:"PEPEBOT_GATEWAY_TOOL_MODE"`
+	Instance   InstanceConfig   `json:"instance"`
+	RequestLog RequestLogConfig `json:"request_log"`
+}
+
+// RequestLogConfig records /v1/chat/completions requests and the agent's
+// replies in ~/.pepebot/requests.jsonl for pepebot requests replay. Redact
+// lists the personal data masked in logged text (phone, email,
+// credit_card; default all, "none" to log text as is).
+type RequestLogConfig struct {
+	Enabled bool     `json:"enabled" env:"PEPEBOT_GATEWAY_REQUEST_LOG_ENABLED"`
+	Redact  []string `json:"redact,omitempty"`
+}
+
+// InstanceConfig keeps two gateways on one config from both answering.
+// Mode is "exclusive" (default: a second gateway refuses to start),
+// "standby" (a second gateway waits and takes over when the first one's
+// heartbeat is older than StaleAfterS) or "off". LockFile defaults to
+// ~/.pepebot/gateway.lock; put it on shared storage for a standby on
+// another machine.
+type InstanceConfig struct {
+	Mode        string `json:"mode,omitempty" env:"PEPEBOT_GATEWAY_INSTANCE_MODE"`
+	LockFile    string `json:"lock_file,omitempty" env:"PEPEBOT_GATEWAY_INSTANCE_LOCK_FILE"`
+	HeartbeatS  int    `json:"heartbeat_s,omitempty" env:"PEPEBOT_GATEWAY_INSTANCE_HEARTBEAT_S"`
+	StaleAfterS int    `json:"stale_after_s,omitempty" env:"PEPEBOT_GATEWAY_INSTANCE_STALE_AFTER_S"`
+}
+
+// EmbeddingsConfig selects the model behind /v1/embeddings. With APIBase set,
+// requests go to that OpenAI-compatible server (e.g. a local Ollama);
+// otherwise the provider is picked like a chat provider.
+type EmbeddingsConfig struct {
+	Model    string `json:"model,omitempty" env:"PEPEBOT_EMBEDDINGS_MODEL"`
+	Provider string `json:"provider,omitempty" env:"PEPEBOT_EMBEDDINGS_PROVIDER"`
+	APIBase  string `json:"api_base,omitempty" env:"PEPEBOT_EMBEDDINGS_API_BASE"`
+	APIKey   string `json:"api_key,omitempty" env:"PEPEBOT_EMBEDDINGS_API_KEY"`
+}
+
+// ModelsConfig defines model aliases and routing rules. An alias such as
+// "fast" or "smart" can be used anywhere a model name is accepted.
+type ModelsConfig struct {
+	Aliases map[string]ModelAlias `json:"aliases,omitempty"`
+	Rules   []ModelRule           `json:"rules,omitempty"`
+}
+
+// ModelAlias is the provider/model pair behind an alias. APIKey and APIBase
+// replace the provider's configured credentials, as for agents.
+type ModelAlias struct {
+	Model    string `json:"model"`
+	Provider string `json:"provider,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	APIBase  string `json:"api_base,omitempty"`
+}
+
+// ModelRule switches a request to another model. Rules are checked in
+// order and the first one whose conditions all hold wins.
+type ModelRule struct {
+	Match     string `json:"match,omitempty"`      // requested model or alias; empty matches any
+	Tools     bool   `json:"tools,omitempty"`      // the request offers tools
+	MinTokens int    `json:"min_tokens,omitempty"` // the prompt has at least this many tokens
+	Model     string `json:"model"`                // model or alias to use instead
+}
+
+// Routing reports whether any aliases or rules are configured
+func (m ModelsConfig) Routing() bool {
+	return len(m.Aliases) > 0 || len(m.Rules) > 0
 }
 
 type LiveConfig struct {
@@ -167,12 +315,332 @@ type WebSearchConfig struct {
 	MaxResults int    `json:"max_results" env:"PEPEBOT_TOOLS_WEB_SEARCH_MAX_RESULTS"`
 }
 
+// WebFetchConfig controls web_fetch
+type WebFetchConfig struct {
+	Browser string `json:"browser,omitempty" env:"PEPEBOT_TOOLS_WEB_FETCH_BROWSER"` // Chrome/Chromium/Edge used for render=true; found automatically when empty
+}
+
 type WebToolsConfig struct {
 	Search WebSearchConfig `json:"search"`
+	Fetch  WebFetchConfig  `json:"fetch"`
+}
+
+// GitHubToolsConfig enables the github_* tools with a personal access token
+type GitHubToolsConfig struct {
+	Token       string `json:"token" env:"PEPEBOT_TOOLS_GITHUB_TOKEN"`
+	APIBase     string `json:"api_base,omitempty" env:"PEPEBOT_TOOLS_GITHUB_API_BASE"`         // GitHub Enterprise: https://host/api/v3
+	DefaultRepo string `json:"default_repo,omitempty" env:"PEPEBOT_TOOLS_GITHUB_DEFAULT_REPO"` // owner/name used when a tool call omits repo
+}
+
+// CalendarConfig points the calendar_* tools at a CalDAV calendar collection
+type CalendarConfig struct {
+	URL      string `json:"url" env:"PEPEBOT_TOOLS_CALENDAR_URL"` // e.g. https://dav.example.com/calendars/me/personal/
+	Username string `json:"username" env:"PEPEBOT_TOOLS_CALENDAR_USERNAME"`
+	Password string `json:"password" env:"PEPEBOT_TOOLS_CALENDAR_PASSWORD"`
+}
+
+// SkillsConfig controls where `pepebot skills search` and install look for skills
+type SkillsConfig struct {
+	IndexURL string `json:"index_url" env:"PEPEBOT_SKILLS_INDEX_URL"` // marketplace index JSON
+	Token    string `json:"token" env:"PEPEBOT_SKILLS_TOKEN"`         // GitHub/Git token for private skill repositories
+}
+
+// EmailConfig is the SMTP account used by the email_send tool
+type EmailConfig struct {
+	Host     string `json:"host" env:"PEPEBOT_TOOLS_EMAIL_HOST"`
+	Port     int    `json:"port" env:"PEPEBOT_TOOLS_EMAIL_PORT"` // 587 (STARTTLS) or 465 (implicit TLS)
+	Username string `json:"username" env:"PEPEBOT_TOOLS_EMAIL_USERNAME"`
+	Password string `json:"password" env:"PEPEBOT_TOOLS_EMAIL_PASSWORD"`
+	From     string `json:"from" env:"PEPEBOT_TOOLS_EMAIL_FROM"` // defaults to username
+}
+
+// DownloadConfig limits download_file
+type DownloadConfig struct {
+	MaxMB int `json:"max_mb,omitempty" env:"PEPEBOT_TOOLS_DOWNLOAD_MAX_MB"` // largest file, 1024 when zero
+}
+
+// FilesystemConfig is the policy of the file and exec tools. With
+// RestrictToWorkspace, paths outside the workspace and AllowPaths are
+// denied. Audit records file changes and commands in ~/.pepebot/audit.jsonl.
+type FilesystemConfig struct {
+	RestrictToWorkspace bool     `json:"restrict_to_workspace" env:"PEPEBOT_TOOLS_FILESYSTEM_RESTRICT_TO_WORKSPACE"`
+	AllowPaths          []string `json:"allow_paths" env:"PEPEBOT_TOOLS_FILESYSTEM_ALLOW_PATHS"`
+	MaxWriteMB          int      `json:"max_write_mb,omitempty" env:"PEPEBOT_TOOLS_FILESYSTEM_MAX_WRITE_MB"` // largest single write, 10 when zero
+	Audit               bool     `json:"audit" env:"PEPEBOT_TOOLS_FILESYSTEM_AUDIT"`
+}
+
+// HostToolsConfig enables the host_* tools, which use the clipboard and
+// notifications of the machine pepebot runs on
+type HostToolsConfig struct {
+	Enabled bool `json:"enabled" env:"PEPEBOT_TOOLS_HOST_ENABLED"`
+}
+
+// VisionToolsConfig selects the model screen_locate uses to find UI
+// elements in screenshots. Without Model or APIBase it uses the agent's own
+// model. APIBase without Provider is an OpenAI-compatible server, such as
+// Ollama or llama.cpp running a local grounding model.
+type VisionToolsConfig struct {
+	Model       string `json:"model,omitempty" env:"PEPEBOT_TOOLS_VISION_MODEL"`
+	Provider    string `json:"provider,omitempty" env:"PEPEBOT_TOOLS_VISION_PROVIDER"`
+	APIKey      string `json:"api_key,omitempty" env:"PEPEBOT_TOOLS_VISION_API_KEY"`
+	APIBase     string `json:"api_base,omitempty" env:"PEPEBOT_TOOLS_VISION_API_BASE"`
+	Coordinates string `json:"coordinates,omitempty" env:"PEPEBOT_TOOLS_VISION_COORDINATES"` // pixels, relative (0-1) or relative_1000; guessed when empty
+}
+
+// AdbToolsConfig configures the adb_* tools
+type AdbToolsConfig struct {
+	// LogcatWatchers run in the gateway while it is up
+	LogcatWatchers []LogcatWatcherConfig `json:"logcat_watchers,omitempty"`
+}
+
+// LogcatWatcherConfig follows a device's logcat and acts on lines matching
+// Pattern: it runs Workflow, or else sends Prompt to an agent, whose reply
+// goes to Channel/ChatID. {{line}}, {{match}} and {{device}} are replaced
+// in Prompt and passed to the workflow as variables.
+type LogcatWatcherConfig struct {
+	Name      string            `json:"name"`
+	Device    string            `json:"device,omitempty"`
+	Filter    string            `json:"filter,omitempty"` // logcat filter spec, e.g. "ActivityManager:I *:S"
+	Buffer    string            `json:"buffer,omitempty"`
+	Pattern   string            `json:"pattern"`
+	Agent     string            `json:"agent,omitempty"`
+	Prompt    string            `json:"prompt,omitempty"`
+	Workflow  string            `json:"workflow,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+	Channel   string            `json:"channel,omitempty"`
+	ChatID    string            `json:"chat_id,omitempty"`
+	CooldownS int               `json:"cooldown_s,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
 }
 
 type ToolsConfig struct {
-	Web WebToolsConfig `json:"web"`
+	Web        WebToolsConfig    `json:"web"`
+	GitHub     GitHubToolsConfig `json:"github"`
+	Calendar   CalendarConfig    `json:"calendar"`
+	Email      EmailConfig       `json:"email"`
+	Download   DownloadConfig    `json:"download"`
+	Host       HostToolsConfig   `json:"host"`
+	Adb        AdbToolsConfig    `json:"adb"`
+	Filesystem FilesystemConfig  `json:"filesystem"`
+	Vision     VisionToolsConfig `json:"vision"`
+}
+
+// BroadcastRecipient is a single target of a broadcast. Vars are substituted
+// into the message template for this recipient only.
+type BroadcastRecipient struct {
+	Channel string            `json:"channel"`
+	ChatID  string            `json:"chat_id"`
+	Name    string            `json:"name,omitempty"`
+	Vars    map[string]string `json:"vars,omitempty"`
+}
+
+type BroadcastConfig struct {
+	ThrottleMS int                             `json:"throttle_ms" env:"PEPEBOT_BROADCAST_THROTTLE_MS"`
+	Lists      map[string][]BroadcastRecipient `json:"lists,omitempty"`
+	Templates  map[string]string               `json:"templates,omitempty"`
+}
+
+// OutboxConfig controls the persistent outbound queue (workspace/outbox)
+type OutboxConfig struct {
+	Enabled       bool `json:"enabled" env:"PEPEBOT_OUTBOX_ENABLED"`
+	MaxAttempts   int  `json:"max_attempts" env:"PEPEBOT_OUTBOX_MAX_ATTEMPTS"`
+	BaseBackoffMS int  `json:"base_backoff_ms" env:"PEPEBOT_OUTBOX_BASE_BACKOFF_MS"`
+	MaxBackoffMS  int  `json:"max_backoff_ms" env:"PEPEBOT_OUTBOX_MAX_BACKOFF_MS"`
+}
+
+// CronConfig controls cron run history and failure alerts. Alerts go to
+// AlertChannel/AlertChatID once a job has failed AlertAfterFailures times in
+// a row; zero disables them.
+type CronConfig struct {
+	HistorySize        int    `json:"history_size" env:"PEPEBOT_CRON_HISTORY_SIZE"`
+	AlertAfterFailures int    `json:"alert_after_failures" env:"PEPEBOT_CRON_ALERT_AFTER_FAILURES"`
+	AlertChannel       string `json:"alert_channel" env:"PEPEBOT_CRON_ALERT_CHANNEL"`
+	AlertChatID        string `json:"alert_chat_id" env:"PEPEBOT_CRON_ALERT_CHAT_ID"`
+}
+
+// BusConfig controls durability of the inbound message bus
+type BusConfig struct {
+	Journal             bool         `json:"journal" env:"PEPEBOT_BUS_JOURNAL"`
+	ReplayMaxAgeMinutes int          `json:"replay_max_age_minutes" env:"PEPEBOT_BUS_REPLAY_MAX_AGE_MINUTES"`
+	Broker              BrokerConfig `json:"broker"`
+}
+
+// BrokerConfig moves the message bus onto Redis or NATS so several gateway
+// processes can share channel ingestion and agent work
+type BrokerConfig struct {
+	Type        string `json:"type" env:"PEPEBOT_BUS_BROKER_TYPE"` // "", "redis" or "nats"
+	URL         string `json:"url" env:"PEPEBOT_BUS_BROKER_URL"`
+	Prefix      string `json:"prefix,omitempty" env:"PEPEBOT_BUS_BROKER_PREFIX"`
+	Role        string `json:"role,omitempty" env:"PEPEBOT_BUS_BROKER_ROLE"` // all, ingest or worker
+	Workers     int    `json:"workers,omitempty" env:"PEPEBOT_BUS_BROKER_WORKERS"`
+	WorkerIndex int    `json:"worker_index" env:"PEPEBOT_BUS_BROKER_WORKER_INDEX"`
+}
+
+// DigestConfig schedules the daily activity digest
+type DigestConfig struct {
+	Enabled bool   `json:"enabled" env:"PEPEBOT_DIGEST_ENABLED"`
+	Time    string `json:"time" env:"PEPEBOT_DIGEST_TIME"` // HH:MM, local time
+	Channel string `json:"channel" env:"PEPEBOT_DIGEST_CHANNEL"`
+	ChatID  string `json:"chat_id" env:"PEPEBOT_DIGEST_CHAT_ID"`
+	Agent   string `json:"agent,omitempty" env:"PEPEBOT_DIGEST_AGENT"`
+}
+
+// BatchConfig sends non-urgent requests (memory extraction, the daily
+// digest) through the provider's batch API, which answers within 24 hours at
+// about half the price. Model and the credentials select a cheaper model
+// for them; empty uses the default model.
+type BatchConfig struct {
+	Enabled        bool   `json:"enabled" env:"PEPEBOT_BATCH_ENABLED"`
+	Model          string `json:"model,omitempty" env:"PEPEBOT_BATCH_MODEL"`
+	Provider       string `json:"provider,omitempty" env:"PEPEBOT_BATCH_PROVIDER"`
+	APIKey         string `json:"api_key,omitempty" env:"PEPEBOT_BATCH_API_KEY"`
+	APIBase        string `json:"api_base,omitempty" env:"PEPEBOT_BATCH_API_BASE"`
+	FlushIntervalS int    `json:"flush_interval_s" env:"PEPEBOT_BATCH_FLUSH_INTERVAL_S"` // longest a request waits to be sent
+	MaxRequests    int    `json:"max_requests" env:"PEPEBOT_BATCH_MAX_REQUESTS"`         // sends a batch early at this many requests
+	PollIntervalS  int    `json:"poll_interval_s" env:"PEPEBOT_BATCH_POLL_INTERVAL_S"`
+}
+
+// HeartbeatConfig configures health checks run alongside the heartbeat.
+// Failing checks are reported to AlertChannel/AlertChatID.
+type HeartbeatConfig struct {
+	CheckIntervalS int                    `json:"check_interval_s" env:"PEPEBOT_HEARTBEAT_CHECK_INTERVAL_S"`
+	AlertChannel   string                 `json:"alert_channel" env:"PEPEBOT_HEARTBEAT_ALERT_CHANNEL"`
+	AlertChatID    string                 `json:"alert_chat_id" env:"PEPEBOT_HEARTBEAT_ALERT_CHAT_ID"`
+	Checks         []HeartbeatCheckConfig `json:"checks,omitempty"`
+
+	// Proactive runs the prompts in workspace/memory/HEARTBEAT.md every
+	// IntervalS through Agent and sends replies to Channel/ChatID (the alert
+	// chat when empty)
+	Proactive bool   `json:"proactive" env:"PEPEBOT_HEARTBEAT_PROACTIVE"`
+	IntervalS int    `json:"interval_s" env:"PEPEBOT_HEARTBEAT_INTERVAL_S"`
+	Agent     string `json:"agent,omitempty" env:"PEPEBOT_HEARTBEAT_AGENT"`
+	Channel   string `json:"channel,omitempty" env:"PEPEBOT_HEARTBEAT_CHANNEL"`
+	ChatID    string `json:"chat_id,omitempty" env:"PEPEBOT_HEARTBEAT_CHAT_ID"`
+}
+
+// HeartbeatCheckConfig is one configured check. Type selects the check
+// implementation (disk, adb_battery, gateway, provider); Params are type specific.
+type HeartbeatCheckConfig struct {
+	Type   string                 `json:"type"`
+	Name   string                 `json:"name,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// FeedsConfig controls polling of RSS/Atom subscriptions (workspace/feeds)
+type FeedsConfig struct {
+	Enabled         bool `json:"enabled" env:"PEPEBOT_FEEDS_ENABLED"`
+	PollIntervalS   int  `json:"poll_interval_s" env:"PEPEBOT_FEEDS_POLL_INTERVAL_S"`
+	MaxItemsPerPoll int  `json:"max_items_per_poll" env:"PEPEBOT_FEEDS_MAX_ITEMS_PER_POLL"`
+}
+
+// SyncConfig commits workspace changes (memory, skills, workflows) to a git
+// repository in the workspace and pushes them to Remote when it is set
+type SyncConfig struct {
+	Enabled     bool     `json:"enabled" env:"PEPEBOT_SYNC_ENABLED"`
+	Remote      string   `json:"remote" env:"PEPEBOT_SYNC_REMOTE"` // git URL; empty keeps history local
+	Branch      string   `json:"branch" env:"PEPEBOT_SYNC_BRANCH"`
+	Token       string   `json:"token,omitempty" env:"PEPEBOT_SYNC_TOKEN"` // for HTTPS remotes
+	IntervalS   int      `json:"interval_s" env:"PEPEBOT_SYNC_INTERVAL_S"`
+	Paths       []string `json:"paths"` // relative to the workspace
+	AuthorName  string   `json:"author_name" env:"PEPEBOT_SYNC_AUTHOR_NAME"`
+	AuthorEmail string   `json:"author_email" env:"PEPEBOT_SYNC_AUTHOR_EMAIL"`
+}
+
+// MiddlewareConfig registers a script or webhook that sees agent traffic at
+// the given stages (pre_llm, post_llm, pre_tool, post_tool, pre_send) and
+// may change or block it. The event is sent as JSON on the command's stdin
+// or POSTed to URL.
+type MiddlewareConfig struct {
+	Name     string            `json:"name"`
+	Stages   []string          `json:"stages"`
+	Command  string            `json:"command,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Tools    []string          `json:"tools,omitempty"`     // limit tool stages to these tools
+	Agents   []string          `json:"agents,omitempty"`    // limit to these agents
+	TimeoutS int               `json:"timeout_s,omitempty"` // default 10
+	OnError  string            `json:"on_error,omitempty"`  // "allow" (default) or "block"
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// FilterConfig sets up the built-in PII redaction and outbound moderation
+type FilterConfig struct {
+	Redact     RedactConfig     `json:"redact"`
+	Moderation ModerationConfig `json:"moderation"`
+	Injection  InjectionConfig  `json:"injection"`
+}
+
+// RedactConfig masks personal data in messages
+type RedactConfig struct {
+	Inbound  bool     `json:"inbound" env:"PEPEBOT_FILTER_REDACT_INBOUND"`   // before user messages reach the model
+	Outbound bool     `json:"outbound" env:"PEPEBOT_FILTER_REDACT_OUTBOUND"` // before messages go to a channel
+	Types    []string `json:"types,omitempty"`                               // phone, email, credit_card; default all
+}
+
+// ModerationConfig has a model check messages for group chats before they
+// are sent
+type ModerationConfig struct {
+	Enabled     bool     `json:"enabled" env:"PEPEBOT_FILTER_MODERATION_ENABLED"`
+	Model       string   `json:"model,omitempty" env:"PEPEBOT_FILTER_MODERATION_MODEL"`
+	Channels    []string `json:"channels,omitempty"` // check every chat of these channels too
+	ChatIDs     []string `json:"chat_ids,omitempty"` // and these chats
+	Replacement string   `json:"replacement,omitempty"`
+}
+
+// InjectionConfig guards the model against instructions planted in content
+// that tools fetch from the web or read from files
+type InjectionConfig struct {
+	Enabled    bool     `json:"enabled" env:"PEPEBOT_FILTER_INJECTION_ENABLED"`
+	Tools      []string `json:"tools,omitempty"` // tools whose output is untrusted
+	Strip      bool     `json:"strip" env:"PEPEBOT_FILTER_INJECTION_STRIP"` // never applied to read_file
+	Classifier bool     `json:"classifier" env:"PEPEBOT_FILTER_INJECTION_CLASSIFIER"`
+	Model      string   `json:"model,omitempty" env:"PEPEBOT_FILTER_INJECTION_MODEL"`
+}
+
+// VoiceConfig sets up how voice notes are transcribed
+type VoiceConfig struct {
+	// Preprocess trims silence, converts to 16 kHz mono and normalizes audio
+	// before transcription. Needs ffmpeg for anything but WAV.
+	Preprocess bool   `json:"preprocess" env:"PEPEBOT_VOICE_PREPROCESS"`
+	FFmpegPath string `json:"ffmpeg_path,omitempty" env:"PEPEBOT_VOICE_FFMPEG_PATH"`   // default: ffmpeg in PATH
+	MaxPauseMs int    `json:"max_pause_ms,omitempty" env:"PEPEBOT_VOICE_MAX_PAUSE_MS"` // longer pauses are cut; default 1000
+
+	Diarization DiarizationConfig `json:"diarization"`
+
+	// pepebot listen
+	WakeWord   string `json:"wake_word,omitempty" env:"PEPEBOT_VOICE_WAKE_WORD"`     // default "hey pepe"
+	MicCommand string `json:"mic_command,omitempty" env:"PEPEBOT_VOICE_MIC_COMMAND"` // writes 16 kHz mono s16le PCM to stdout
+	TTSCommand string `json:"tts_command,omitempty" env:"PEPEBOT_VOICE_TTS_COMMAND"` // reads the text on stdin
+}
+
+// DiarizationConfig labels transcripts by speaker using a diarization
+// service that answers with speaker turns
+type DiarizationConfig struct {
+	Enabled bool   `json:"enabled" env:"PEPEBOT_VOICE_DIARIZATION_ENABLED"`
+	URL     string `json:"url" env:"PEPEBOT_VOICE_DIARIZATION_URL"`
+	APIKey  string `json:"api_key,omitempty" env:"PEPEBOT_VOICE_DIARIZATION_API_KEY"`
+}
+
+// FeedbackConfig records 👍/👎 reactions to agent replies on Telegram and
+// Discord as ratings
+type FeedbackConfig struct {
+	Enabled bool `json:"enabled" env:"PEPEBOT_FEEDBACK_ENABLED"`
+	// MemoryCorrections appends a note about every 👎 reply to the agent's
+	// memory/MEMORY.md
+	MemoryCorrections bool `json:"memory_corrections" env:"PEPEBOT_FEEDBACK_MEMORY_CORRECTIONS"`
+}
+
+// HookConfig defines an inbound webhook served at /v1/hooks/{name}. Verified
+// payloads are rendered with Template and sent to Agent; the reply goes to
+// Channel/ChatID.
+type HookConfig struct {
+	Secret          string `json:"secret"`
+	Signature       string `json:"signature,omitempty"`        // hmac-sha256 (default), github, stripe, token, none
+	SignatureHeader string `json:"signature_header,omitempty"` // header carrying the signature or token
+	Agent           string `json:"agent,omitempty"`
+	Channel         string `json:"channel"`
+	ChatID          string `json:"chat_id"`
+	Template        string `json:"template,omitempty"` // Go text/template over the payload
 }
 
 func DefaultConfig() *Config {
@@ -184,18 +652,36 @@ func DefaultConfig() *Config {
 				MaxTokens:         8192,
 				Temperature:       0.7,
 				MaxToolIterations: 20,
+				MaxConcurrency:    4,
+				MaxParallelTools:  4,
+				TypingIndicator:   true,
+				ProgressUpdates:   false,
+				Verify:            false,
+				MemoryExtraction:  true,
+				ContextBudget: ContextBudgetConfig{
+					Window:    0,
+					Bootstrap: 0.25,
+					Memory:    0.15,
+					Skills:    0.15,
+					Summary:   0.10,
+					History:   0.35,
+				},
 			},
 		},
 		Channels: ChannelsConfig{
 			WhatsApp: WhatsAppConfig{
-				Enabled:   false,
-				DBPath:    "~/.pepebot/whatsapp.db",
-				AllowFrom: []string{},
+				Enabled:      false,
+				DBPath:       "~/.pepebot/whatsapp.db",
+				AllowFrom:    []string{},
+				GroupMode:    "mention",
+				GroupTrigger: "!pepe",
 			},
 			Telegram: TelegramConfig{
-				Enabled:   false,
-				Token:     "",
-				AllowFrom: []string{},
+				Enabled:      false,
+				Token:        "",
+				AllowFrom:    []string{},
+				GroupMode:    "mention",
+				GroupTrigger: "!pepe",
 			},
 			Feishu: FeishuConfig{
 				Enabled:           false,
@@ -206,9 +692,11 @@ func DefaultConfig() *Config {
 				AllowFrom:         []string{},
 			},
 			Discord: DiscordConfig{
-				Enabled:   false,
-				Token:     "",
-				AllowFrom: []string{},
+				Enabled:      false,
+				Token:        "",
+				AllowFrom:    []string{},
+				GroupMode:    "mention",
+				GroupTrigger: "!pepe",
 			},
 			MaixCam: MaixCamConfig{
 				Enabled:   false,
@@ -234,6 +722,11 @@ func DefaultConfig() *Config {
 		Gateway: GatewayConfig{
 			Host: "127.0.0.1",
 			Port: 18790,
+			Instance: InstanceConfig{
+				Mode:        "exclusive",
+				HeartbeatS:  10,
+				StaleAfterS: 30,
+			},
 		},
 		Live: LiveConfig{
 			Enabled:  false,
@@ -272,6 +765,77 @@ func DefaultConfig() *Config {
 					MaxResults: 5,
 				},
 			},
+			Email: EmailConfig{
+				Port: 587,
+			},
+			Filesystem: FilesystemConfig{
+				AllowPaths: []string{},
+				Audit:      true,
+			},
+		},
+		Broadcast: BroadcastConfig{
+			ThrottleMS: 1000,
+		},
+		Skills: SkillsConfig{
+			IndexURL: "https://raw.githubusercontent.com/pepebot-space/skills/refs/heads/main/skills.json",
+		},
+		Outbox: OutboxConfig{
+			Enabled:       true,
+			MaxAttempts:   6,
+			BaseBackoffMS: 2000,
+			MaxBackoffMS:  300000,
+		},
+		Cron: CronConfig{
+			HistorySize:        10,
+			AlertAfterFailures: 3,
+		},
+		Filter: FilterConfig{
+			Injection: InjectionConfig{
+				Enabled: true,
+				Tools:   []string{"web_fetch", "web_search", "read_file", "github_list_issues", "github_pr_diff"},
+			},
+		},
+		Bus: BusConfig{
+			Journal:             false,
+			ReplayMaxAgeMinutes: 60,
+			Broker: BrokerConfig{
+				Prefix:  "pepebot",
+				Role:    "all",
+				Workers: 1,
+			},
+		},
+		Digest: DigestConfig{
+			Enabled: false,
+			Time:    "21:00",
+		},
+		Batch: BatchConfig{
+			Enabled:        false,
+			FlushIntervalS: 600,
+			MaxRequests:    50,
+			PollIntervalS:  300,
+		},
+		Heartbeat: HeartbeatConfig{
+			CheckIntervalS: 300,
+			IntervalS:      1800,
+		},
+		Feeds: FeedsConfig{
+			Enabled:         true,
+			PollIntervalS:   900,
+			MaxItemsPerPoll: 5,
+		},
+		Sync: SyncConfig{
+			Enabled:     false,
+			Branch:      "main",
+			IntervalS:   900,
+			Paths:       []string{"memory", "skills", "workflows"},
+			AuthorName:  "Pepebot",
+			AuthorEmail: "pepebot@localhost",
+		},
+		Voice: VoiceConfig{
+			Preprocess: true,
+		},
+		Feedback: FeedbackConfig{
+			Enabled: true,
 		},
 	}
 }
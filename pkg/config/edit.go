@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Editing config.json from the command line. Keys are dotted paths like
+// providers.openai.api_base; a key that itself contains dots (a model name,
+// say) is matched as a whole. Edits keep the file's key order, and the
+// previous file is kept as config.json.bak.
+
+// Lookup returns the value at key in the effective configuration
+func Lookup(cfg *Config, key string) (interface{}, error) {
+	cfg.mu.RLock()
+	data, err := json.Marshal(cfg)
+	cfg.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := lookupPath(doc, splitKey(key))
+	if !ok {
+		return nil, fmt.Errorf("%s is not set (or is not a config key)", key)
+	}
+	return value, nil
+}
+
+// FormatValue renders a looked up value: strings as they are, everything
+// else as JSON
+func FormatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return string(data)
+}
+
+// SetValue sets key in the config file at path. raw is parsed as JSON
+// unless the setting is a string, so `true`, `4` and `["a"]` get their
+// types. The result must still load as a configuration.
+func SetValue(path, key, raw string) error {
+	doc, err := readOrdered(path)
+	if err != nil {
+		return err
+	}
+
+	value, err := parseValue(key, raw)
+	if err != nil {
+		return err
+	}
+	if err := setPath(doc, splitKey(key), value); err != nil {
+		return err
+	}
+	return writeOrdered(path, doc)
+}
+
+// UnsetValue removes key from the config file at path, so its default
+// applies again
+func UnsetValue(path, key string) error {
+	doc, err := readOrdered(path)
+	if err != nil {
+		return err
+	}
+	parts := splitKey(key)
+	parent := doc
+	if len(parts) > 1 {
+		v, ok := lookupPath(doc, parts[:len(parts)-1])
+		parent, _ = v.(*orderedObject)
+		if !ok || parent == nil {
+			return fmt.Errorf("%s is not set", key)
+		}
+	}
+	if !parent.delete(parts[len(parts)-1]) {
+		return fmt.Errorf("%s is not set", key)
+	}
+	return writeOrdered(path, doc)
+}
+
+// parseValue turns the command line value into JSON, keeping it a string
+// when the setting is one
+func parseValue(key, raw string) (interface{}, error) {
+	if current, err := Lookup(DefaultConfig(), key); err == nil {
+		if _, isString := current.(string); isString {
+			return raw, nil
+		}
+	}
+	v, err := decodeOrdered([]byte(raw))
+	if err != nil {
+		// Not JSON: a bare word for a setting without a default
+		return raw, nil
+	}
+	return v, nil
+}
+
+func splitKey(key string) []string {
+	return strings.Split(strings.Trim(key, "."), ".")
+}
+
+// lookupPath walks parts, joining parts when a key contains dots
+func lookupPath(v interface{}, parts []string) (interface{}, bool) {
+	if len(parts) == 0 {
+		return v, true
+	}
+	switch node := v.(type) {
+	case *orderedObject:
+		for n := 1; n <= len(parts); n++ {
+			if child, ok := node.get(strings.Join(parts[:n], ".")); ok {
+				if found, ok := lookupPath(child, parts[n:]); ok {
+					return found, true
+				}
+			}
+		}
+	case []interface{}:
+		var i int
+		if _, err := fmt.Sscanf(parts[0], "%d", &i); err == nil && i >= 0 && i < len(node) {
+			return lookupPath(node[i], parts[1:])
+		}
+	}
+	return nil, false
+}
+
+// setPath stores value at parts, creating objects on the way
+func setPath(doc *orderedObject, parts []string, value interface{}) error {
+	node := doc
+	for i := 0; i < len(parts)-1; i++ {
+		// Prefer an existing key that contains dots
+		matched := false
+		for n := len(parts) - 1; n > i+1; n-- {
+			if child, ok := node.get(strings.Join(parts[i:n], ".")); ok {
+				if obj, ok := child.(*orderedObject); ok {
+					node, i, matched = obj, n-1, true
+					break
+				}
+			}
+		}
+		if matched {
+			continue
+		}
+		child, ok := node.get(parts[i])
+		if !ok || child == nil {
+			obj := newOrderedObject()
+			node.set(parts[i], obj)
+			node = obj
+			continue
+		}
+		obj, ok := child.(*orderedObject)
+		if !ok {
+			return fmt.Errorf("%s is not an object", strings.Join(parts[:i+1], "."))
+		}
+		node = obj
+	}
+	node.set(parts[len(parts)-1], value)
+	return nil
+}
+
+func readOrdered(path string) (*orderedObject, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newOrderedObject(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return newOrderedObject(), nil
+	}
+	v, err := decodeOrdered(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	doc, ok := v.(*orderedObject)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a JSON object", path)
+	}
+	return doc, nil
+}
+
+// writeOrdered validates doc, backs up the old file and replaces it
+func writeOrdered(path string, doc *orderedObject) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := validateConfig(path, data); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path+".bak", old, mode); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// validateConfig checks that data loads as a configuration. Unknown keys
+// are only reported when the current file has none, so an old setting
+// elsewhere in the file doesn't block edits.
+func validateConfig(path string, data []byte) error {
+	if err := json.Unmarshal(data, DefaultConfig()); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	if err := decodeStrict(data); err != nil {
+		if old, readErr := os.ReadFile(path); readErr == nil && decodeStrict(old) != nil {
+			return nil
+		}
+		return fmt.Errorf("invalid key: %w", err)
+	}
+	return nil
+}
+
+func decodeStrict(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(DefaultConfig())
+}
+
+// orderedObject is a JSON object that remembers the order of its keys
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedObject() *orderedObject {
+	return &orderedObject{values: map[string]interface{}{}}
+}
+
+func (o *orderedObject) get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+func (o *orderedObject) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *orderedObject) delete(key string) bool {
+	if _, ok := o.values[key]; !ok {
+		return false
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrdered parses one JSON value, keeping object key order
+func decodeOrdered(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := newOrderedObject()
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, _ := keyTok.(string)
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.set(key, v)
+			}
+			_, err := dec.Token()
+			return obj, err
+		case '[':
+			arr := []interface{}{}
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+			_, err := dec.Token()
+			return arr, err
+		}
+		return nil, fmt.Errorf("unexpected %v", t)
+	}
+	return tok, nil
+}
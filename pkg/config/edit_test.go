@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetAndUnsetValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := `{
+  "providers": {"openai": {"api_key": "sk-1"}},
+  "agents": {"defaults": {"model": "gpt-4o", "max_tool_iterations": 20}},
+  "models": {"aliases": {"gemini-2.5-flash": {"model": "gemini-2.5-flash", "provider": "gemini"}}}
+}`
+	os.WriteFile(path, []byte(original), 0600)
+
+	if err := SetValue(path, "providers.openai.api_base", "https://proxy.example/v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(path, "agents.defaults.max_tool_iterations", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(path, "channels.telegram.allow_from", `["123"]`); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	text := string(data)
+	if strings.Index(text, `"providers"`) > strings.Index(text, `"agents"`) {
+		t.Errorf("key order changed:\n%s", text)
+	}
+	// The backup is the file before the last change
+	if backup, _ := os.ReadFile(path + ".bak"); !strings.Contains(string(backup), `"max_tool_iterations": 30`) || strings.Contains(string(backup), "allow_from") {
+		t.Errorf("backup = %s", backup)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Providers.OpenAI.APIBase != "https://proxy.example/v1" || cfg.Agents.Defaults.MaxToolIterations != 30 || len(cfg.Channels.Telegram.AllowFrom) != 1 {
+		t.Errorf("config = %+v", cfg.Agents.Defaults)
+	}
+	if v, err := Lookup(cfg, "models.aliases.gemini-2.5-flash.provider"); err != nil || FormatValue(v) != "gemini" {
+		t.Errorf("dotted key lookup = %v, %v", v, err)
+	}
+
+	if err := SetValue(path, "models.aliases.gemini-2.5-flash.provider", "openrouter"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, _ := LoadConfig(path); cfg.Models.Aliases["gemini-2.5-flash"].Provider != "openrouter" {
+		t.Errorf("dotted key set = %+v", cfg.Models.Aliases)
+	}
+
+	if err := SetValue(path, "agents.defaults.max_tool_iterations", "many"); err == nil {
+		t.Error("a string was accepted for a number")
+	}
+	if err := SetValue(path, "agents.defaults.no_such_setting", "1"); err == nil {
+		t.Error("an unknown key was accepted")
+	}
+
+	if err := UnsetValue(path, "agents.defaults.max_tool_iterations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnsetValue(path, "agents.defaults.max_tool_iterations"); err == nil {
+		t.Error("unsetting a missing key succeeded")
+	}
+	cfg, _ = LoadConfig(path)
+	if cfg.Agents.Defaults.MaxToolIterations != DefaultConfig().Agents.Defaults.MaxToolIterations {
+		t.Errorf("unset did not restore the default: %d", cfg.Agents.Defaults.MaxToolIterations)
+	}
+}
@@ -0,0 +1,89 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExprNext(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC) // a Monday
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{"0 9 * * *", "2026-03-02 09:00"},
+		{"*/15 * * * *", "2026-03-02 08:45"},
+		{"0 9 * * 1-5", "2026-03-02 09:00"},
+		{"0 9 * * sat,sun", "2026-03-07 09:00"},
+		{"30 8 * * *", "2026-03-03 08:30"},
+		{"0 0 1 * *", "2026-04-01 00:00"},
+		{"0 12 29 2 *", "2028-02-29 12:00"},
+		{"0 9 15 * 7", "2026-03-08 09:00"}, // day of month OR Sunday
+		{"@hourly", "2026-03-02 09:00"},
+		{"@weekly", "2026-03-08 00:00"},
+		{"0 6 * jun-aug *", "2026-06-01 06:00"},
+	}
+	for _, c := range cases {
+		expr, err := ParseExpr(c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if got := expr.Next(base).Format("2006-01-02 15:04"); got != c.want {
+			t.Errorf("%s: next = %s, want %s", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "0 9 * * funday", "5-1 * * * *"} {
+		if _, err := ParseExpr(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestSyncSourceJobs(t *testing.T) {
+	cs := NewCronService(t.TempDir()+"/jobs.json", nil)
+	if _, err := cs.AddJob("manual", CronSchedule{Kind: "cron", Expr: "0 8 * * *"}, "hi", false, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	job := func(name, expr string) CronJob {
+		return CronJob{
+			Name:     name,
+			Enabled:  true,
+			Source:   "workflow:" + name,
+			Schedule: CronSchedule{Kind: "cron", Expr: expr},
+			Payload:  CronPayload{Kind: "workflow", Workflow: name},
+		}
+	}
+
+	added, updated, removed, err := cs.SyncSourceJobs("workflow:", []CronJob{job("backup", "0 2 * * *"), job("report", "0 9 * * 1")})
+	if err != nil || added != 2 || updated != 0 || removed != 0 {
+		t.Fatalf("first sync: %d %d %d %v", added, updated, removed, err)
+	}
+	var backupID string
+	for _, j := range cs.ListJobs(true) {
+		if j.Source == "workflow:backup" {
+			backupID = j.ID
+			if j.State.NextRunAtMS == nil {
+				t.Error("scheduled job has no next run")
+			}
+		}
+	}
+
+	// backup changes schedule, report is gone, the manual job stays
+	added, updated, removed, err = cs.SyncSourceJobs("workflow:", []CronJob{job("backup", "0 3 * * *")})
+	if err != nil || added != 0 || updated != 1 || removed != 1 {
+		t.Fatalf("second sync: %d %d %d %v", added, updated, removed, err)
+	}
+	jobs := cs.ListJobs(true)
+	if len(jobs) != 2 || jobs[1].ID != backupID || jobs[1].Schedule.Expr != "0 3 * * *" {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+
+	if _, _, _, err := cs.SyncSourceJobs("workflow:", []CronJob{job("bad", "99 * * * *")}); err == nil {
+		t.Error("invalid expression should be rejected")
+	}
+}
@@ -3,10 +3,15 @@ package cron
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
 )
 
 type CronSchedule struct {
@@ -15,23 +20,70 @@ type CronSchedule struct {
 	EveryMS *int64 `json:"everyMs,omitempty"`
 	Expr    string `json:"expr,omitempty"`
 	TZ      string `json:"tz,omitempty"`
+	// JitterMS delays each run by a random amount up to this long, so jobs
+	// on many hosts don't all fire at the same moment
+	JitterMS int64 `json:"jitterMs,omitempty"`
+	// Overlap decides what happens when a run is due while the previous one
+	// is still going: "skip" (default) drops it, "queue" runs it afterwards
+	Overlap string `json:"overlap,omitempty"`
+	// CatchUp decides what happens to runs missed while the host was asleep
+	// or the gateway was stopped: "skip" drops them, "once" runs the job
+	// once, "all" runs every missed occurrence (up to maxCatchUpRuns). The
+	// default runs once after a sleep and skips runs missed while stopped.
+	CatchUp string `json:"catchUp,omitempty"`
 }
 
+// CronPayload is what a job does. Kind "agent_turn" sends Message to the
+// agent; kind "autonomy" gives the agent Message as an objective for a
+// bounded autonomous session and always reports to Channel/To; kind
+// "workflow" runs Workflow with Vars.
 type CronPayload struct {
 	Kind    string `json:"kind"`
 	Message string `json:"message"`
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+	Agent   string `json:"agent,omitempty"`
+	// Budget of autonomy jobs; zero uses the defaults
+	MaxIterations int `json:"maxIterations,omitempty"`
+	MaxMinutes    int `json:"maxMinutes,omitempty"`
+	// Workflow jobs
+	Workflow string            `json:"workflow,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
 }
 
 type CronJobState struct {
-	NextRunAtMS *int64 `json:"nextRunAtMs,omitempty"`
-	LastRunAtMS *int64 `json:"lastRunAtMs,omitempty"`
-	LastStatus  string `json:"lastStatus,omitempty"`
-	LastError   string `json:"lastError,omitempty"`
+	NextRunAtMS         *int64    `json:"nextRunAtMs,omitempty"`
+	LastRunAtMS         *int64    `json:"lastRunAtMs,omitempty"`
+	LastStatus          string    `json:"lastStatus,omitempty"`
+	LastError           string    `json:"lastError,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures,omitempty"`
+	Runs                []CronRun `json:"runs,omitempty"`
+}
+
+// CronRun is one entry of a job's run history, newest last
+type CronRun struct {
+	StartedAtMS int64  `json:"startedAtMs"`
+	DurationMS  int64  `json:"durationMs"`
+	Status      string `json:"status"`
+	Output      string `json:"output,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Manual      bool   `json:"manual,omitempty"`
 }
 
+// DefaultHistorySize is the number of runs kept per job
+const DefaultHistorySize = 10
+
+// maxRunSnippet caps the output and error kept for each run
+const maxRunSnippet = 300
+
+const (
+	// missedAfterMS is how late a run has to be to count as missed
+	missedAfterMS = 60 * 1000
+	// maxCatchUpRuns caps the runs made up with CatchUp "all"
+	maxCatchUpRuns = 10
+)
+
 type CronJob struct {
 	ID             string       `json:"id"`
 	Name           string       `json:"name"`
@@ -42,6 +94,9 @@ type CronJob struct {
 	CreatedAtMS    int64        `json:"createdAtMs"`
 	UpdatedAtMS    int64        `json:"updatedAtMs"`
 	DeleteAfterRun bool         `json:"deleteAfterRun"`
+	// Source is set on jobs defined elsewhere, e.g. "workflow:backup" for
+	// the schedule block of a workflow file; see SyncSourceJobs
+	Source string `json:"source,omitempty"`
 }
 
 type CronStore struct {
@@ -51,25 +106,60 @@ type CronStore struct {
 
 type JobHandler func(job *CronJob) (string, error)
 
+// AlertHandler is called with a copy of a job that has just failed the
+// configured number of times in a row
+type AlertHandler func(job CronJob)
+
 type CronService struct {
-	storePath string
-	store     *CronStore
-	onJob     JobHandler
-	mu        sync.RWMutex
-	running   bool
-	stopChan  chan struct{}
+	storePath   string
+	store       *CronStore
+	onJob       JobHandler
+	onAlert     AlertHandler
+	alertAfter  int
+	historySize int
+	// active holds the IDs of jobs with a scheduled run in progress and
+	// the number of runs queued behind it
+	active   map[string]int
+	mu       sync.RWMutex
+	running  bool
+	stopChan chan struct{}
 }
 
 func NewCronService(storePath string, onJob JobHandler) *CronService {
 	cs := &CronService{
-		storePath: storePath,
-		onJob:     onJob,
-		stopChan:  make(chan struct{}),
+		storePath:   storePath,
+		onJob:       onJob,
+		historySize: DefaultHistorySize,
+		active:      make(map[string]int),
+		stopChan:    make(chan struct{}),
 	}
 	cs.loadStore()
 	return cs
 }
 
+// SetJobHandler sets the function that runs due jobs
+func (cs *CronService) SetJobHandler(onJob JobHandler) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.onJob = onJob
+}
+
+// SetHistorySize sets how many runs are kept per job; zero keeps none
+func (cs *CronService) SetHistorySize(n int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.historySize = n
+}
+
+// SetFailureAlert calls onAlert once a job fails after times in a row. The
+// streak resets on the next successful run, so each streak alerts once.
+func (cs *CronService) SetFailureAlert(after int, onAlert AlertHandler) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.alertAfter = after
+	cs.onAlert = onAlert
+}
+
 func (cs *CronService) Start() error {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
@@ -119,69 +209,162 @@ func (cs *CronService) runLoop() {
 	}
 }
 
+// checkJobs starts the jobs that are due. Each job runs in its own
+// goroutine so a slow job doesn't hold up the others; the next run is
+// scheduled when a run starts.
 func (cs *CronService) checkJobs() {
-	cs.mu.RLock()
+	cs.mu.Lock()
 	if !cs.running {
-		cs.mu.RUnlock()
+		cs.mu.Unlock()
 		return
 	}
 
 	now := time.Now().UnixMilli()
-	var dueJobs []*CronJob
-
+	start := make(map[string]int)
 	for i := range cs.store.Jobs {
 		job := &cs.store.Jobs[i]
-		if job.Enabled && job.State.NextRunAtMS != nil && *job.State.NextRunAtMS <= now {
-			dueJobs = append(dueJobs, job)
+		if !job.Enabled || job.State.NextRunAtMS == nil || *job.State.NextRunAtMS > now {
+			continue
+		}
+
+		runs := cs.dueRuns(job, now)
+		if job.Schedule.Kind == "at" {
+			job.State.NextRunAtMS = nil
+		} else {
+			job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
 		}
+		if runs == 0 {
+			logger.InfoCF("cron", "Skipped missed run", map[string]interface{}{"job": job.Name})
+			continue
+		}
+
+		if queued, busy := cs.active[job.ID]; busy {
+			if job.Schedule.Overlap == "queue" {
+				cs.active[job.ID] = queued + runs
+				continue
+			}
+			logger.WarnCF("cron", "Skipped run, previous run still in progress", map[string]interface{}{"job": job.Name})
+			continue
+		}
+		cs.active[job.ID] = 0
+		start[job.ID] = runs
 	}
-	cs.mu.RUnlock()
+	cs.saveStore()
+	cs.mu.Unlock()
 
-	for _, job := range dueJobs {
-		cs.executeJob(job)
+	for id, runs := range start {
+		go cs.runScheduled(id, runs)
 	}
+}
 
-	cs.mu.Lock()
-	defer cs.mu.Unlock()
-	cs.saveStore()
+// dueRuns returns how many times a due job should run now, applying its
+// catch-up policy when the run is late. Callers hold cs.mu.
+func (cs *CronService) dueRuns(job *CronJob, nowMS int64) int {
+	scheduled := *job.State.NextRunAtMS
+	if nowMS-scheduled < missedAfterMS {
+		return 1
+	}
+	switch job.Schedule.CatchUp {
+	case "skip":
+		return 0
+	case "all":
+		runs := 1
+		for next := scheduled; runs < maxCatchUpRuns; runs++ {
+			n := cs.nextOccurrence(&job.Schedule, next)
+			if n == nil || *n > nowMS {
+				break
+			}
+			next = *n
+		}
+		return runs
+	default:
+		return 1
+	}
 }
 
-func (cs *CronService) executeJob(job *CronJob) {
-	startTime := time.Now().UnixMilli()
+// runScheduled runs a job, then any runs queued behind it
+func (cs *CronService) runScheduled(jobID string, runs int) {
+	for {
+		for i := 0; i < runs; i++ {
+			if _, err := cs.executeJob(jobID, false); err == errJobGone {
+				break
+			}
+		}
 
-	var err error
-	if cs.onJob != nil {
-		_, err = cs.onJob(job)
+		cs.mu.Lock()
+		runs = cs.active[jobID]
+		if runs == 0 {
+			delete(cs.active, jobID)
+			cs.mu.Unlock()
+			return
+		}
+		cs.active[jobID] = 0
+		cs.mu.Unlock()
 	}
+}
 
-	cs.mu.Lock()
-	defer cs.mu.Unlock()
+var errJobGone = fmt.Errorf("job not found")
 
-	job.State.LastRunAtMS = &startTime
-	job.UpdatedAtMS = time.Now().UnixMilli()
+// executeJob runs a job once and records the result. Manual runs leave
+// one-shot ("at") jobs as they are.
+func (cs *CronService) executeJob(jobID string, manual bool) (string, error) {
+	job := cs.GetJob(jobID)
+	if job == nil {
+		return "", errJobGone
+	}
 
-	if err != nil {
-		job.State.LastStatus = "error"
-		job.State.LastError = err.Error()
-	} else {
-		job.State.LastStatus = "ok"
-		job.State.LastError = ""
+	cs.mu.RLock()
+	onJob := cs.onJob
+	cs.mu.RUnlock()
+	if onJob == nil {
+		return "", fmt.Errorf("no job handler configured")
 	}
 
-	if job.Schedule.Kind == "at" {
-		if job.DeleteAfterRun {
-			cs.removeJobUnsafe(job.ID)
-		} else {
-			job.Enabled = false
-			job.State.NextRunAtMS = nil
+	startTime := time.Now().UnixMilli()
+	output, err := onJob(job)
+
+	cs.mu.Lock()
+	alert := false
+	var alerted CronJob
+	for i := range cs.store.Jobs {
+		stored := &cs.store.Jobs[i]
+		if stored.ID != jobID {
+			continue
 		}
-	} else {
-		nextRun := cs.computeNextRun(&job.Schedule, time.Now().UnixMilli())
-		job.State.NextRunAtMS = nextRun
+		alert = cs.recordRun(stored, startTime, output, err, manual)
+		alerted = *stored
+		if !manual && stored.Schedule.Kind == "at" {
+			if stored.DeleteAfterRun {
+				cs.removeJobUnsafe(stored.ID)
+			} else {
+				stored.Enabled = false
+				stored.State.NextRunAtMS = nil
+			}
+		}
+		cs.saveStore()
+		break
+	}
+	onAlert := cs.onAlert
+	cs.mu.Unlock()
+
+	if alert && onAlert != nil {
+		onAlert(alerted)
 	}
+	return output, err
 }
 
+// computeNextRun returns when the job should run next, including jitter
 func (cs *CronService) computeNextRun(schedule *CronSchedule, nowMS int64) *int64 {
+	next := cs.nextOccurrence(schedule, nowMS)
+	if next == nil || schedule.Kind == "at" || schedule.JitterMS <= 0 {
+		return next
+	}
+	jittered := *next + rand.Int63n(schedule.JitterMS+1)
+	return &jittered
+}
+
+// nextOccurrence returns the next time the schedule fires after nowMS
+func (cs *CronService) nextOccurrence(schedule *CronSchedule, nowMS int64) *int64 {
 	if schedule.Kind == "at" {
 		if schedule.AtMS != nil && *schedule.AtMS > nowMS {
 			return schedule.AtMS
@@ -197,16 +380,73 @@ func (cs *CronService) computeNextRun(schedule *CronSchedule, nowMS int64) *int6
 		return &next
 	}
 
+	if schedule.Kind == "cron" {
+		expr, err := ParseExpr(schedule.Expr)
+		if err != nil {
+			return nil
+		}
+		loc := time.Local
+		if schedule.TZ != "" {
+			if l, err := time.LoadLocation(schedule.TZ); err == nil {
+				loc = l
+			}
+		}
+		next := expr.Next(time.UnixMilli(nowMS).In(loc))
+		if next.IsZero() {
+			return nil
+		}
+		nextMS := next.UnixMilli()
+		return &nextMS
+	}
+
 	return nil
 }
 
+// ValidateSchedule checks a cron expression, time zone and run options
+// before a job is added
+func ValidateSchedule(schedule CronSchedule) error {
+	if schedule.JitterMS < 0 {
+		return fmt.Errorf("jitter must not be negative")
+	}
+	switch schedule.Overlap {
+	case "", "skip", "queue":
+	default:
+		return fmt.Errorf("invalid overlap %q (use skip or queue)", schedule.Overlap)
+	}
+	switch schedule.CatchUp {
+	case "", "skip", "once", "all":
+	default:
+		return fmt.Errorf("invalid catch-up %q (use skip, once or all)", schedule.CatchUp)
+	}
+	if schedule.Kind != "cron" {
+		return nil
+	}
+	if _, err := ParseExpr(schedule.Expr); err != nil {
+		return err
+	}
+	if schedule.TZ != "" {
+		if _, err := time.LoadLocation(schedule.TZ); err != nil {
+			return fmt.Errorf("invalid time zone %q", schedule.TZ)
+		}
+	}
+	return nil
+}
+
+// recomputeNextRuns schedules every enabled job from now. A run missed
+// while the gateway was stopped is kept for jobs that catch up, so
+// checkJobs makes it up.
 func (cs *CronService) recomputeNextRuns() {
 	now := time.Now().UnixMilli()
 	for i := range cs.store.Jobs {
 		job := &cs.store.Jobs[i]
-		if job.Enabled {
-			job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
+		if !job.Enabled {
+			continue
+		}
+		missed := job.State.NextRunAtMS != nil && *job.State.NextRunAtMS <= now
+		if missed && (job.Schedule.CatchUp == "once" || job.Schedule.CatchUp == "all") {
+			continue
 		}
+		job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
 	}
 }
 
@@ -258,6 +498,21 @@ func (cs *CronService) saveStore() error {
 }
 
 func (cs *CronService) AddJob(name string, schedule CronSchedule, message string, deliver bool, channel, to string) (*CronJob, error) {
+	return cs.AddPayloadJob(name, schedule, CronPayload{
+		Kind:    "agent_turn",
+		Message: message,
+		Deliver: deliver,
+		Channel: channel,
+		To:      to,
+	})
+}
+
+// AddPayloadJob adds a job with a complete payload, e.g. an autonomy job
+func (cs *CronService) AddPayloadJob(name string, schedule CronSchedule, payload CronPayload) (*CronJob, error) {
+	if err := ValidateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
@@ -268,13 +523,7 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 		Name:     name,
 		Enabled:  true,
 		Schedule: schedule,
-		Payload: CronPayload{
-			Kind:    "agent_turn",
-			Message: message,
-			Deliver: deliver,
-			Channel: channel,
-			To:      to,
-		},
+		Payload:  payload,
 		State: CronJobState{
 			NextRunAtMS: cs.computeNextRun(&schedule, now),
 		},
@@ -291,6 +540,85 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 	return &job, nil
 }
 
+// SyncSourceJobs makes the jobs whose Source starts with prefix match
+// wanted: new sources are added, changed jobs are updated and jobs whose
+// source is no longer wanted are removed. Unchanged jobs keep their state.
+func (cs *CronService) SyncSourceJobs(prefix string, wanted []CronJob) (added, updated, removed int, err error) {
+	for _, w := range wanted {
+		if !strings.HasPrefix(w.Source, prefix) {
+			return 0, 0, 0, fmt.Errorf("job %q: source %q does not start with %q", w.Name, w.Source, prefix)
+		}
+		if err := ValidateSchedule(w.Schedule); err != nil {
+			return 0, 0, 0, fmt.Errorf("job %q: %w", w.Name, err)
+		}
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	bySource := make(map[string]CronJob, len(wanted))
+	for _, w := range wanted {
+		bySource[w.Source] = w
+	}
+
+	jobs := cs.store.Jobs[:0]
+	for _, job := range cs.store.Jobs {
+		if job.Source == "" || !strings.HasPrefix(job.Source, prefix) {
+			jobs = append(jobs, job)
+			continue
+		}
+		w, ok := bySource[job.Source]
+		if !ok {
+			removed++
+			continue
+		}
+		delete(bySource, job.Source)
+		if job.Name != w.Name || job.Enabled != w.Enabled || !reflect.DeepEqual(job.Schedule, w.Schedule) || !reflect.DeepEqual(job.Payload, w.Payload) {
+			job.Name, job.Enabled, job.Schedule, job.Payload = w.Name, w.Enabled, w.Schedule, w.Payload
+			job.State.NextRunAtMS = nil
+			if job.Enabled {
+				job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
+			}
+			job.UpdatedAtMS = now
+			updated++
+		}
+		jobs = append(jobs, job)
+	}
+
+	ids := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		ids[job.ID] = true
+	}
+
+	// Add the remaining sources in the order they were given
+	for _, w := range wanted {
+		if _, ok := bySource[w.Source]; !ok {
+			continue
+		}
+		job := w
+		job.ID = generateID()
+		for ids[job.ID] {
+			job.ID = generateID()
+		}
+		ids[job.ID] = true
+		job.CreatedAtMS = now
+		job.UpdatedAtMS = now
+		job.State = CronJobState{}
+		if job.Enabled {
+			job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
+		}
+		jobs = append(jobs, job)
+		added++
+	}
+	cs.store.Jobs = jobs
+
+	if added+updated+removed > 0 {
+		err = cs.saveStore()
+	}
+	return added, updated, removed, err
+}
+
 func (cs *CronService) RemoveJob(jobID string) bool {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
@@ -340,6 +668,78 @@ func (cs *CronService) EnableJob(jobID string, enabled bool) *CronJob {
 	return nil
 }
 
+// GetJob returns a copy of the job, or nil if there is no job with that ID
+func (cs *CronService) GetJob(jobID string) *CronJob {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, job := range cs.store.Jobs {
+		if job.ID == jobID {
+			return &job
+		}
+	}
+	return nil
+}
+
+// RunJob runs a job now, outside its schedule, and returns the handler's
+// output. The result is recorded in the job state; the next scheduled run
+// and one-shot ("at") jobs are left as they are.
+func (cs *CronService) RunJob(jobID string) (string, error) {
+	if cs.GetJob(jobID) == nil {
+		return "", fmt.Errorf("job %s not found", jobID)
+	}
+	return cs.executeJob(jobID, true)
+}
+
+// recordRun updates the job state and run history after a run and reports
+// whether the failure streak just reached the alert threshold. Callers hold
+// cs.mu.
+func (cs *CronService) recordRun(job *CronJob, startMS int64, output string, err error, manual bool) bool {
+	now := time.Now().UnixMilli()
+	job.State.LastRunAtMS = &startMS
+	job.UpdatedAtMS = now
+
+	run := CronRun{
+		StartedAtMS: startMS,
+		DurationMS:  now - startMS,
+		Status:      "ok",
+		Output:      snippet(output),
+		Manual:      manual,
+	}
+	if err != nil {
+		job.State.LastStatus = "error"
+		job.State.LastError = err.Error()
+		job.State.ConsecutiveFailures++
+		run.Status = "error"
+		run.Error = snippet(err.Error())
+	} else {
+		job.State.LastStatus = "ok"
+		job.State.LastError = ""
+		job.State.ConsecutiveFailures = 0
+	}
+
+	if cs.historySize > 0 {
+		runs := append(append([]CronRun{}, job.State.Runs...), run)
+		if len(runs) > cs.historySize {
+			runs = runs[len(runs)-cs.historySize:]
+		}
+		job.State.Runs = runs
+	} else {
+		job.State.Runs = nil
+	}
+
+	return err != nil && cs.alertAfter > 0 && job.State.ConsecutiveFailures == cs.alertAfter
+}
+
+func snippet(s string) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= maxRunSnippet {
+		return s
+	}
+	return string(runes[:maxRunSnippet]) + "…"
+}
+
 func (cs *CronService) ListJobs(includeDisabled bool) []CronJob {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
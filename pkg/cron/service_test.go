@@ -0,0 +1,143 @@
+package cron
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunHistoryAndAlerts(t *testing.T) {
+	fail := true
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		if fail {
+			return "", errors.New("device offline")
+		}
+		return "all good", nil
+	})
+	cs.SetHistorySize(3)
+	var alerts []CronJob
+	cs.SetFailureAlert(2, func(job CronJob) { alerts = append(alerts, job) })
+
+	every := int64(60000)
+	job, err := cs.AddPayloadJob("check", CronSchedule{Kind: "every", EveryMS: &every}, CronPayload{Kind: "agent_turn", Message: "check"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		cs.RunJob(job.ID)
+	}
+	if len(alerts) != 1 || alerts[0].State.ConsecutiveFailures != 2 {
+		t.Fatalf("expected one alert at the second failure, got %+v", alerts)
+	}
+
+	fail = false
+	cs.RunJob(job.ID)
+	state := cs.GetJob(job.ID).State
+	if state.ConsecutiveFailures != 0 || len(state.Runs) != 3 {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	last := state.Runs[2]
+	if last.Status != "ok" || last.Output != "all good" || !last.Manual || state.Runs[0].Error != "device offline" {
+		t.Errorf("unexpected runs: %+v", state.Runs)
+	}
+
+	// History survives a reload of the store
+	reloaded := NewCronService(filepath.Join(filepath.Dir(cs.storePath), "jobs.json"), nil)
+	if got := reloaded.GetJob(job.ID); got == nil || len(got.State.Runs) != 3 {
+		t.Errorf("history not persisted: %+v", got)
+	}
+}
+
+func TestDueRunsCatchUp(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	every := int64(60 * 60 * 1000)
+	now := time.Now().UnixMilli()
+	missed := now - 5*every - 1000 // five hours and a bit late: six occurrences
+
+	cases := map[string]int{"": 1, "skip": 0, "once": 1, "all": 6}
+	for catchUp, want := range cases {
+		job := &CronJob{Schedule: CronSchedule{Kind: "every", EveryMS: &every, CatchUp: catchUp}}
+		job.State.NextRunAtMS = &missed
+		if got := cs.dueRuns(job, now); got != want {
+			t.Errorf("catch-up %q: %d runs, want %d", catchUp, got, want)
+		}
+	}
+
+	// A run that is only a little late is not a missed run
+	onTime := now - 1000
+	job := &CronJob{Schedule: CronSchedule{Kind: "every", EveryMS: &every, CatchUp: "skip"}}
+	job.State.NextRunAtMS = &onTime
+	if got := cs.dueRuns(job, now); got != 1 {
+		t.Errorf("on-time run skipped")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	every := int64(1000)
+	schedule := CronSchedule{Kind: "every", EveryMS: &every, JitterMS: 500}
+	for i := 0; i < 50; i++ {
+		next := *cs.computeNextRun(&schedule, 0)
+		if next < 1000 || next > 1500 {
+			t.Fatalf("next run %d outside jitter window", next)
+		}
+	}
+	if err := ValidateSchedule(CronSchedule{Kind: "every", Overlap: "parallel"}); err == nil {
+		t.Error("expected invalid overlap to be rejected")
+	}
+}
+
+func TestOverlapPolicy(t *testing.T) {
+	for overlap, want := range map[string]int{"skip": 1, "queue": 2} {
+		release := make(chan struct{})
+		var mu sync.Mutex
+		runs := 0
+		cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+			<-release
+			mu.Lock()
+			runs++
+			mu.Unlock()
+			return "", nil
+		})
+		every := int64(60000)
+		job, err := cs.AddPayloadJob("slow", CronSchedule{Kind: "every", EveryMS: &every, Overlap: overlap}, CronPayload{Kind: "agent_turn", Message: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs.running = true
+
+		due := func() {
+			cs.mu.Lock()
+			past := time.Now().UnixMilli() - 10
+			cs.store.Jobs[0].State.NextRunAtMS = &past
+			cs.mu.Unlock()
+			cs.checkJobs()
+		}
+		due()
+		due() // previous run still blocked
+
+		release <- struct{}{}
+		if overlap == "queue" {
+			release <- struct{}{}
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			cs.mu.RLock()
+			_, busy := cs.active[job.ID]
+			cs.mu.RUnlock()
+			if !busy || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		mu.Lock()
+		if runs != want {
+			t.Errorf("overlap %s: %d runs, want %d", overlap, runs, want)
+		}
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,465 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/batch"
+	"github.com/pepebot-space/pepebot/pkg/calendar"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/cron"
+	"github.com/pepebot-space/pepebot/pkg/journal"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/session"
+	"github.com/pepebot-space/pepebot/pkg/workflow"
+)
+
+// Composer turns the collected activity into a digest. Implemented by agent.AgentManager.
+type Composer interface {
+	ProcessDirect(ctx context.Context, content string, media []string, sessionKey, agentName string) (string, error)
+}
+
+// Sender delivers the digest. Implemented by channels.Manager.
+type Sender interface {
+	SendToChannel(ctx context.Context, channelName, chatID, content string) error
+}
+
+// EventSource lists upcoming calendar events. Implemented by calendar.Client.
+type EventSource interface {
+	ListEvents(ctx context.Context, from, to time.Time) ([]calendar.Event, error)
+}
+
+// Service composes a daily digest of the last 24 hours (sessions, memory
+// changes, cron and workflow runs) at a configured time and delivers it to a
+// channel. Digests are also saved to workspace/digests/YYYY-MM-DD.md, which
+// doubles as the marker that today's digest was already sent.
+type Service struct {
+	cfg       config.DigestConfig
+	workspace string
+	sessions  *session.SessionManager
+	cron      *cron.CronService
+	composer  Composer
+	sender    Sender
+	calendar  EventSource
+	batch     *batch.Queue
+	mu        sync.Mutex
+	stopChan  chan struct{}
+}
+
+func NewService(cfg config.DigestConfig, workspace string, sessions *session.SessionManager, cronService *cron.CronService, composer Composer, sender Sender) *Service {
+	return &Service{
+		cfg:       cfg,
+		workspace: workspace,
+		sessions:  sessions,
+		cron:      cronService,
+		composer:  composer,
+		sender:    sender,
+	}
+}
+
+// SetCalendar adds the next 24 hours of calendar events to each digest
+func (s *Service) SetCalendar(source EventSource) {
+	s.calendar = source
+}
+
+// batchKind is the batch queue job kind of digests
+const batchKind = "digest"
+
+// SetBatchQueue composes digests in the next provider batch instead of right
+// away. They arrive later, but at batch prices.
+func (s *Service) SetBatchQueue(q *batch.Queue) {
+	s.batch = q
+	q.Handle(batchKind, s.handleBatch)
+}
+
+func (s *Service) Start() error {
+	if _, err := parseClock(s.cfg.Time); err != nil {
+		return err
+	}
+	if s.cfg.Channel == "" || s.cfg.ChatID == "" {
+		return fmt.Errorf("digest channel and chat_id are required")
+	}
+
+	s.stopChan = make(chan struct{})
+	go s.runLoop()
+	return nil
+}
+
+func (s *Service) Stop() {
+	if s.stopChan != nil {
+		close(s.stopChan)
+		s.stopChan = nil
+	}
+}
+
+func (s *Service) runLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	// A failed digest is retried with exponential backoff instead of on
+	// every tick, so a failing agent or channel isn't hit once a minute.
+	stop := s.stopChan
+	backoff := time.Minute
+	var retryAt time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if now.Before(retryAt) || !s.isDue(now) {
+				continue
+			}
+			if _, err := s.Run(context.Background(), now); err != nil {
+				logger.ErrorCF("digest", "Digest failed", map[string]interface{}{
+					"error":       err.Error(),
+					"retry_after": backoff.String(),
+				})
+				retryAt = now.Add(backoff)
+				backoff = min(backoff*2, time.Hour)
+				continue
+			}
+			backoff = time.Minute
+			retryAt = time.Time{}
+		}
+	}
+}
+
+// isDue reports whether the configured time has passed today and no digest was saved yet
+func (s *Service) isDue(now time.Time) bool {
+	at, err := parseClock(s.cfg.Time)
+	if err != nil {
+		return false
+	}
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if now.Before(scheduled) {
+		return false
+	}
+	if s.batch != nil && s.batch.Pending(batchKey(now)) {
+		return false
+	}
+	_, err = os.Stat(s.digestPath(now))
+	return os.IsNotExist(err)
+}
+
+func batchKey(day time.Time) string {
+	return "digest:" + day.Format("2006-01-02")
+}
+
+// Run composes, saves and delivers the digest for the 24 hours before now
+func (s *Service) Run(ctx context.Context, now time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := now.Add(-24 * time.Hour)
+	activity := s.collect(ctx, since, now)
+
+	digest := "Nothing happened in the last 24 hours."
+	if activity != "" {
+		prompt := digestPrompt(now, activity)
+		if s.batch != nil {
+			err := s.batch.Enqueue(batchKind, batchKey(now), providers.BatchRequest{
+				Messages: []providers.Message{
+					{Role: "system", Content: "You write short, well organized daily digests of a personal assistant's activity for its user."},
+					{Role: "user", Content: prompt},
+				},
+				MaxTokens: 2048,
+			}, digestJob{Day: now.Format("2006-01-02"), Prompt: prompt})
+			if err == nil {
+				logger.InfoCF("digest", "Daily digest queued for the next batch", map[string]interface{}{
+					"day": now.Format("2006-01-02"),
+				})
+				return "", nil
+			}
+			logger.WarnCF("digest", "Failed to queue digest, composing it now", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+
+		response, err := s.compose(ctx, now, prompt)
+		if err != nil {
+			return "", err
+		}
+		digest = response
+	}
+	return digest, s.deliver(ctx, now, digest)
+}
+
+// digestJob is the batch payload of a digest
+type digestJob struct {
+	Day    string `json:"day"`
+	Prompt string `json:"prompt"`
+}
+
+// handleBatch delivers a digest composed in a batch. A failed batch request
+// falls back to composing the digest directly.
+func (s *Service) handleBatch(ctx context.Context, job batch.Job, resp *providers.LLMResponse, err error) {
+	var payload digestJob
+	if json.Unmarshal(job.Payload, &payload) != nil {
+		return
+	}
+	day, perr := time.ParseInLocation("2006-01-02", payload.Day, time.Local)
+	if perr != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	digest := ""
+	if err == nil && resp != nil {
+		digest = strings.TrimSpace(resp.Content)
+	}
+	if digest == "" {
+		if digest, err = s.compose(ctx, day, payload.Prompt); err != nil {
+			logger.ErrorCF("digest", "Digest failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	if err := s.deliver(ctx, day, digest); err != nil {
+		logger.ErrorCF("digest", "Digest failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+func digestPrompt(now time.Time, activity string) string {
+	return fmt.Sprintf(`# Daily Digest
+
+Write a concise daily digest for %s covering the activity below.
+Group it into short sections (conversations, memory, scheduled jobs, workflows,
+upcoming events),
+highlight decisions, open tasks and failures, and skip empty sections.
+
+%s`, now.Format("Monday, 2006-01-02"), activity)
+}
+
+// compose has the digest agent write the digest
+func (s *Service) compose(ctx context.Context, now time.Time, prompt string) (string, error) {
+	sessionKey := "digest:" + now.Format("2006-01-02")
+	response, err := s.composer.ProcessDirect(ctx, prompt, nil, sessionKey, s.cfg.Agent)
+	if err != nil {
+		return "", fmt.Errorf("failed to compose digest: %w", err)
+	}
+	return response, nil
+}
+
+// deliver sends and saves the digest for the day of now. The file is only
+// written once the digest was sent, so a failed send is retried.
+func (s *Service) deliver(ctx context.Context, now time.Time, digest string) error {
+	if err := s.sender.SendToChannel(ctx, s.cfg.Channel, s.cfg.ChatID, digest); err != nil {
+		return fmt.Errorf("failed to deliver digest: %w", err)
+	}
+
+	path := s.digestPath(now)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(digest), 0644); err != nil {
+		return fmt.Errorf("failed to save digest: %w", err)
+	}
+
+	logger.InfoCF("digest", "Daily digest delivered", map[string]interface{}{
+		"channel": s.cfg.Channel,
+		"chat_id": s.cfg.ChatID,
+		"path":    path,
+	})
+	return nil
+}
+
+func (s *Service) digestPath(day time.Time) string {
+	return filepath.Join(s.workspace, "digests", day.Format("2006-01-02")+".md")
+}
+
+// collect gathers the raw activity since the given time, plus the calendar
+// events of the next 24 hours, as markdown. Returns an empty string when
+// there is nothing to report.
+func (s *Service) collect(ctx context.Context, since, now time.Time) string {
+	var sections []string
+
+	if text := s.collectSessions(since); text != "" {
+		sections = append(sections, "## Conversations\n\n"+text)
+	}
+	if text := s.collectMemory(since); text != "" {
+		sections = append(sections, "## Memory changes\n\n"+text)
+	}
+	if text := s.collectCron(since); text != "" {
+		sections = append(sections, "## Scheduled jobs\n\n"+text)
+	}
+	if text := s.collectWorkflows(since); text != "" {
+		sections = append(sections, "## Workflow runs\n\n"+text)
+	}
+	if text := s.collectEvents(ctx, now); text != "" {
+		sections = append(sections, "## Upcoming events\n\n"+text)
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+func (s *Service) collectSessions(since time.Time) string {
+	if s.sessions == nil {
+		return ""
+	}
+
+	active := []*session.Session{}
+	for _, sess := range s.sessions.ListSessions("") {
+		if sess.Updated.Before(since) || strings.HasPrefix(sess.Key, "digest:") {
+			continue
+		}
+		active = append(active, sess)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Updated.Before(active[j].Updated)
+	})
+
+	var b strings.Builder
+	for _, sess := range active {
+		fmt.Fprintf(&b, "### %s (last active %s)\n", sess.Key, sess.Updated.Format("15:04"))
+		if sess.Summary != "" {
+			fmt.Fprintf(&b, "Summary: %s\n", truncate(sess.Summary, 500))
+		}
+
+		// Messages carry no timestamps; the tail is the most recent exchange
+		msgs := sess.Messages
+		if len(msgs) > 6 {
+			msgs = msgs[len(msgs)-6:]
+		}
+		for _, m := range msgs {
+			if m.Role != "user" && m.Role != "assistant" {
+				continue
+			}
+			text, ok := m.Content.(string)
+			if !ok {
+				text = "[multimodal content]"
+			}
+			fmt.Fprintf(&b, "- %s: %s\n", m.Role, truncate(text, 200))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *Service) collectMemory(since time.Time) string {
+	var b strings.Builder
+	// memory/ holds MEMORY.md, memory/daily/ the daily notes
+	for _, sub := range []string{"", "daily"} {
+		dir := filepath.Join(s.workspace, "memory", sub)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.ModTime().Before(since) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			// Keep the end of the file, where new entries are appended
+			content := string(data)
+			if sub == "daily" && !journal.HasEntries(content) {
+				continue
+			}
+			if len(content) > 1500 {
+				content = "..." + content[len(content)-1500:]
+			}
+			fmt.Fprintf(&b, "### %s\n%s\n\n", filepath.ToSlash(filepath.Join(sub, e.Name())), strings.TrimSpace(content))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *Service) collectCron(since time.Time) string {
+	if s.cron == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, job := range s.cron.ListJobs(true) {
+		if job.State.LastRunAtMS == nil || time.UnixMilli(*job.State.LastRunAtMS).Before(since) {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s at %s: %s", job.Name, time.UnixMilli(*job.State.LastRunAtMS).Format("15:04"), job.State.LastStatus)
+		if job.State.LastError != "" {
+			fmt.Fprintf(&b, " (%s)", truncate(job.State.LastError, 200))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *Service) collectWorkflows(since time.Time) string {
+	runs, err := workflow.LoadRuns(s.workspace, since)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, run := range runs {
+		fmt.Fprintf(&b, "- %s at %s: %s (%.1fs)", run.Workflow, run.StartedAt.Format("15:04"), run.Status, float64(run.DurationMS)/1000)
+		if run.Error != "" {
+			fmt.Fprintf(&b, " (%s)", truncate(run.Error, 200))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func parseClock(value string) (time.Time, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid digest time %q (expected HH:MM)", value)
+	}
+	return t, nil
+}
+
+func truncate(s string, maxLen int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen]) + "..."
+}
+
+func (s *Service) collectEvents(ctx context.Context, now time.Time) string {
+	if s.calendar == nil {
+		return ""
+	}
+	events, err := s.calendar.ListEvents(ctx, now, now.Add(24*time.Hour))
+	if err != nil {
+		logger.WarnCF("digest", "Failed to list calendar events", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ""
+	}
+
+	var b strings.Builder
+	for _, e := range events {
+		when := "all day " + e.Start.Format("Mon 2006-01-02")
+		if !e.AllDay {
+			when = e.Start.Format("Mon 15:04") + "-" + e.End.Format("15:04")
+		}
+		fmt.Fprintf(&b, "- %s: %s", when, e.Summary)
+		if e.Location != "" {
+			fmt.Fprintf(&b, " (%s)", e.Location)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,131 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/calendar"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+type fakeComposer struct{ prompt string }
+
+func (f *fakeComposer) ProcessDirect(ctx context.Context, content string, media []string, sessionKey, agentName string) (string, error) {
+	f.prompt = content
+	return "digest body", nil
+}
+
+type fakeSender struct {
+	sent []string
+	err  error
+}
+
+func (f *fakeSender) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, channelName+"/"+chatID+": "+content)
+	return nil
+}
+
+type fakeCalendar struct{ events []calendar.Event }
+
+func (f *fakeCalendar) ListEvents(ctx context.Context, from, to time.Time) ([]calendar.Event, error) {
+	return f.events, nil
+}
+
+func TestDigestRun(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "memory"), 0755)
+	os.WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte("- User prefers morning reports"), 0644)
+
+	sessions := session.NewSessionManager("")
+	sessions.AddMessage("telegram:42", "user", "remind me to renew the domain")
+	sessions.AddMessage("telegram:42", "assistant", "Reminder set for Friday")
+
+	composer := &fakeComposer{}
+	sender := &fakeSender{}
+	cfg := config.DigestConfig{Enabled: true, Time: "21:00", Channel: "telegram", ChatID: "42"}
+	svc := NewService(cfg, workspace, sessions, nil, composer, sender)
+
+	now := time.Date(2026, 3, 5, 21, 0, 30, 0, time.Local)
+	if !svc.isDue(now) {
+		t.Fatalf("expected digest to be due")
+	}
+	if svc.isDue(now.Add(-time.Hour)) {
+		t.Fatalf("digest should not be due before the configured time")
+	}
+
+	if _, err := svc.Run(context.Background(), now); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, want := range []string{"telegram:42", "renew the domain", "MEMORY.md", "morning reports"} {
+		if !strings.Contains(composer.prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if len(sender.sent) != 1 || sender.sent[0] != "telegram/42: digest body" {
+		t.Fatalf("unexpected deliveries: %v", sender.sent)
+	}
+	if svc.isDue(now.Add(time.Minute)) {
+		t.Fatalf("digest should not be due again on the same day")
+	}
+}
+
+func TestDigestFailedSendStaysDue(t *testing.T) {
+	sender := &fakeSender{err: errors.New("channel down")}
+	cfg := config.DigestConfig{Enabled: true, Time: "21:00", Channel: "telegram", ChatID: "42"}
+	svc := NewService(cfg, t.TempDir(), session.NewSessionManager(""), nil, &fakeComposer{}, sender)
+
+	now := time.Date(2026, 3, 5, 21, 0, 30, 0, time.Local)
+	if _, err := svc.Run(context.Background(), now); err == nil {
+		t.Fatal("expected the failed send to be reported")
+	}
+	if !svc.isDue(now.Add(time.Minute)) {
+		t.Fatal("digest should still be due after a failed send")
+	}
+
+	sender.err = nil
+	if _, err := svc.Run(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if svc.isDue(now.Add(2 * time.Minute)) {
+		t.Fatal("digest should not be due once it was sent")
+	}
+}
+
+func TestTruncateKeepsRunes(t *testing.T) {
+	if got := truncate("héllo wörld", 2); got != "hé..." {
+		t.Fatalf("truncate = %q", got)
+	}
+}
+
+func TestDigestIncludesUpcomingEvents(t *testing.T) {
+	composer := &fakeComposer{}
+	cfg := config.DigestConfig{Enabled: true, Time: "21:00", Channel: "telegram", ChatID: "42"}
+	svc := NewService(cfg, t.TempDir(), session.NewSessionManager(""), nil, composer, &fakeSender{})
+
+	now := time.Date(2026, 3, 5, 21, 0, 0, 0, time.Local)
+	svc.SetCalendar(&fakeCalendar{events: []calendar.Event{{
+		Summary:  "Dentist",
+		Location: "Main St",
+		Start:    now.Add(12 * time.Hour),
+		End:      now.Add(13 * time.Hour),
+	}}})
+
+	if _, err := svc.Run(context.Background(), now); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, want := range []string{"## Upcoming events", "Fri 09:00-10:00: Dentist (Main St)"} {
+		if !strings.Contains(composer.prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, composer.prompt)
+		}
+	}
+}
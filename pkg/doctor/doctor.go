@@ -0,0 +1,385 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/heartbeat"
+	"github.com/pepebot-space/pepebot/pkg/mcp"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+// Status is the outcome of a single diagnostic
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Result is one diagnostic. Fix tells the user what to do about a warning
+// or failure.
+type Result struct {
+	Group   string `json:"group"`
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// Doctor checks the environment pepebot runs in: config, providers, adb,
+// channels, MCP servers and disk space.
+type Doctor struct {
+	configPath string
+	cfg        *config.Config
+	loadErr    error
+	client     *http.Client
+
+	// API endpoints, replaced in tests
+	telegramAPI string
+	discordAPI  string
+}
+
+// New loads the config at configPath. A config that fails to load is
+// reported by Run; the other checks then use the defaults.
+func New(configPath string) *Doctor {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	return &Doctor{
+		configPath:  configPath,
+		cfg:         cfg,
+		loadErr:     err,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		telegramAPI: "https://api.telegram.org",
+		discordAPI:  "https://discord.com/api/v10",
+	}
+}
+
+// Run runs all diagnostics in order
+func (d *Doctor) Run(ctx context.Context) []Result {
+	var results []Result
+	results = append(results, d.checkConfig()...)
+	results = append(results, d.checkProviders(ctx)...)
+	results = append(results, d.checkADB(ctx)...)
+	results = append(results, d.checkChannels(ctx)...)
+	results = append(results, d.checkMCP(ctx)...)
+	results = append(results, d.checkDisk(ctx)...)
+	return results
+}
+
+// Failed reports whether any result failed
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// ==================== Config ====================
+
+func (d *Doctor) checkConfig() []Result {
+	const group = "config"
+	var results []Result
+
+	if _, err := os.Stat(d.configPath); os.IsNotExist(err) {
+		results = append(results, Result{group, "config file", StatusFail, d.configPath + " does not exist", "Run 'pepebot onboard' to create it"})
+	} else if d.loadErr != nil {
+		results = append(results, Result{group, "config file", StatusFail, "invalid config: " + d.loadErr.Error(), "Fix the JSON in " + d.configPath + " (compare with config.example.json)"})
+	} else {
+		results = append(results, Result{group, "config file", StatusOK, d.configPath, ""})
+	}
+
+	workspace := d.cfg.WorkspacePath()
+	if info, err := os.Stat(workspace); err != nil || !info.IsDir() {
+		results = append(results, Result{group, "workspace", StatusFail, workspace + " does not exist", "Run 'pepebot onboard' or fix agents.defaults.workspace"})
+	} else {
+		results = append(results, Result{group, "workspace", StatusOK, workspace, ""})
+	}
+
+	if d.cfg.Agents.Defaults.Model == "" {
+		results = append(results, Result{group, "model", StatusFail, "no default model set", "Set agents.defaults.model"})
+	} else {
+		results = append(results, Result{group, "model", StatusOK, d.cfg.Agents.Defaults.Model, ""})
+	}
+
+	if port := d.cfg.Gateway.Port; port <= 0 || port > 65535 {
+		results = append(results, Result{group, "gateway port", StatusFail, fmt.Sprintf("invalid port %d", port), "Set gateway.port to a port between 1 and 65535"})
+	}
+
+	if len(d.cfg.Heartbeat.Checks) > 0 {
+		if _, errs := heartbeat.BuildChecks(d.cfg); len(errs) > 0 {
+			for _, err := range errs {
+				results = append(results, Result{group, "heartbeat", StatusWarn, err.Error(), "Fix the entry in heartbeat.checks"})
+			}
+		}
+	}
+	return results
+}
+
+// ==================== Providers ====================
+
+// providerEndpoint is a configured LLM provider and its OpenAI-compatible base
+type providerEndpoint struct {
+	name    string
+	apiKey  string
+	apiBase string
+	field   string // config path, for fixes
+}
+
+func (d *Doctor) providerEndpoints() []providerEndpoint {
+	p := d.cfg.Providers
+	base := func(configured, fallback string) string {
+		if configured != "" {
+			return configured
+		}
+		return fallback
+	}
+	all := []providerEndpoint{
+		{"maiarouter", p.MAIARouter.APIKey, base(p.MAIARouter.APIBase, "https://api.maiarouter.ai/v1"), "providers.maiarouter"},
+		{"openrouter", p.OpenRouter.APIKey, base(p.OpenRouter.APIBase, "https://openrouter.ai/api/v1"), "providers.openrouter"},
+		{"anthropic", p.Anthropic.APIKey, base(p.Anthropic.APIBase, "https://api.anthropic.com/v1"), "providers.anthropic"},
+		{"openai", p.OpenAI.APIKey, base(p.OpenAI.APIBase, "https://api.openai.com/v1"), "providers.openai"},
+		{"gemini", p.Gemini.APIKey, base(p.Gemini.APIBase, "https://generativelanguage.googleapis.com/v1beta/openai"), "providers.gemini"},
+		{"zhipu", p.Zhipu.APIKey, base(p.Zhipu.APIBase, "https://open.bigmodel.cn/api/paas/v4"), "providers.zhipu"},
+		{"groq", p.Groq.APIKey, base(p.Groq.APIBase, "https://api.groq.com/openai/v1"), "providers.groq"},
+	}
+	var configured []providerEndpoint
+	for _, e := range all {
+		if e.apiKey != "" {
+			configured = append(configured, e)
+		}
+	}
+	if p.VLLM.APIBase != "" {
+		configured = append(configured, providerEndpoint{"vllm", p.VLLM.APIKey, p.VLLM.APIBase, "providers.vllm"})
+	}
+	return configured
+}
+
+func (d *Doctor) checkProviders(ctx context.Context) []Result {
+	const group = "providers"
+	endpoints := d.providerEndpoints()
+	if len(endpoints) == 0 && d.cfg.Providers.Vertex.CredentialsFile == "" {
+		return []Result{{group, "providers", StatusFail, "no provider configured", "Set an api_key under providers (e.g. providers.maiarouter.api_key) or run 'pepebot onboard'"}}
+	}
+
+	var results []Result
+	for _, e := range endpoints {
+		results = append(results, d.pingProvider(ctx, e))
+	}
+	if file := d.cfg.Providers.Vertex.CredentialsFile; file != "" {
+		if _, err := os.Stat(file); err != nil {
+			results = append(results, Result{group, "vertex", StatusFail, "credentials file not found: " + file, "Fix providers.vertex.credentials_file"})
+		} else {
+			results = append(results, Result{group, "vertex", StatusOK, "credentials file present", ""})
+		}
+	}
+	return results
+}
+
+// pingProvider lists the provider's models, which costs no tokens and
+// checks both reachability and the API key
+func (d *Doctor) pingProvider(ctx context.Context, e providerEndpoint) Result {
+	const group = "providers"
+	url := strings.TrimRight(e.apiBase, "/") + "/models"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Result{group, e.name, StatusFail, "invalid api_base: " + err.Error(), "Fix " + e.field + ".api_base"}
+	}
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return Result{group, e.name, StatusFail, e.apiBase + " unreachable: " + err.Error(), "Check the network connection and " + e.field + ".api_base"}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	latency := time.Since(start).Milliseconds()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return Result{group, e.name, StatusFail, fmt.Sprintf("API key rejected (HTTP %d)", resp.StatusCode), "Check " + e.field + ".api_key"}
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Result{group, e.name, StatusOK, fmt.Sprintf("reachable, key accepted (%dms)", latency), ""}
+	default:
+		return Result{group, e.name, StatusWarn, fmt.Sprintf("reachable, but %s returned HTTP %d", url, resp.StatusCode), "The key could not be verified; send a test message with 'pepebot agent -m hello'"}
+	}
+}
+
+// ==================== ADB ====================
+
+func (d *Doctor) checkADB(ctx context.Context) []Result {
+	const group = "adb"
+	helper, err := tools.NewAdbHelper(d.cfg.WorkspacePath())
+	if err != nil {
+		return []Result{{group, "adb", StatusWarn, "adb not found; Android tools are unavailable", "Install Android platform-tools and add them to PATH, or set ANDROID_HOME"}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	output, err := tools.NewAdbDevicesTool(helper).Execute(ctx, nil)
+	if err != nil {
+		return []Result{{group, "adb", StatusFail, "adb failed: " + err.Error(), "Restart the adb server with 'adb kill-server && adb start-server'"}}
+	}
+
+	var devices []map[string]string
+	json.Unmarshal([]byte(output), &devices)
+	if len(devices) == 0 {
+		return []Result{{group, "devices", StatusWarn, "adb works, but no device is connected", "Connect a device with USB debugging enabled, or 'adb connect <host:port>'"}}
+	}
+
+	var results []Result
+	for _, dev := range devices {
+		name := dev["serial"]
+		if model := dev["model"]; model != "" {
+			name += " (" + model + ")"
+		}
+		switch dev["status"] {
+		case "device":
+			results = append(results, Result{group, name, StatusOK, "connected", ""})
+		case "unauthorized":
+			results = append(results, Result{group, name, StatusFail, "unauthorized", "Accept the USB debugging prompt on the device"})
+		default:
+			results = append(results, Result{group, name, StatusWarn, dev["status"], "Reconnect the device"})
+		}
+	}
+	return results
+}
+
+// ==================== Channels ====================
+
+func (d *Doctor) checkChannels(ctx context.Context) []Result {
+	const group = "channels"
+	ch := d.cfg.Channels
+	var results []Result
+
+	if ch.Telegram.Enabled {
+		if ch.Telegram.Token == "" {
+			results = append(results, Result{group, "telegram", StatusFail, "enabled without a token", "Set channels.telegram.token (from @BotFather)"})
+		} else {
+			results = append(results, d.verifyToken(ctx, "telegram", d.telegramAPI+"/bot"+ch.Telegram.Token+"/getMe", nil, "channels.telegram.token"))
+		}
+	}
+	if ch.Discord.Enabled {
+		if ch.Discord.Token == "" {
+			results = append(results, Result{group, "discord", StatusFail, "enabled without a token", "Set channels.discord.token (from the Discord developer portal)"})
+		} else {
+			results = append(results, d.verifyToken(ctx, "discord", d.discordAPI+"/users/@me", map[string]string{"Authorization": "Bot " + ch.Discord.Token}, "channels.discord.token"))
+		}
+	}
+	if ch.Feishu.Enabled {
+		if ch.Feishu.AppID == "" || ch.Feishu.AppSecret == "" {
+			results = append(results, Result{group, "feishu", StatusFail, "enabled without app_id/app_secret", "Set channels.feishu.app_id and app_secret"})
+		} else {
+			results = append(results, Result{group, "feishu", StatusOK, "credentials set (not verified)", ""})
+		}
+	}
+	if ch.MaixCam.Enabled {
+		if ch.MaixCam.Port <= 0 {
+			results = append(results, Result{group, "maixcam", StatusFail, "enabled without a port", "Set channels.maixcam.port"})
+		} else {
+			results = append(results, Result{group, "maixcam", StatusOK, fmt.Sprintf("listening on %s:%d", ch.MaixCam.Host, ch.MaixCam.Port), ""})
+		}
+	}
+	if ch.WhatsApp.Enabled {
+		results = append(results, Result{group, "whatsapp", StatusOK, "enabled (on first start, pair by scanning the QR code 'pepebot gateway' prints)", ""})
+	}
+
+	if len(results) == 0 {
+		results = append(results, Result{group, "channels", StatusSkip, "no channels enabled", ""})
+	}
+	return results
+}
+
+// verifyToken calls an identity endpoint that only answers 2xx for a valid
+// bot token
+func (d *Doctor) verifyToken(ctx context.Context, name, url string, headers map[string]string, field string) Result {
+	const group = "channels"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Result{group, name, StatusFail, err.Error(), ""}
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// The token is part of the Telegram URL; keep it out of the message
+		return Result{group, name, StatusFail, "API unreachable", "Check the network connection"}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Result{group, name, StatusOK, "token valid", ""}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNotFound:
+		return Result{group, name, StatusFail, fmt.Sprintf("token rejected (HTTP %d)", resp.StatusCode), "Check " + field}
+	default:
+		return Result{group, name, StatusWarn, fmt.Sprintf("unexpected HTTP %d", resp.StatusCode), "Try again later"}
+	}
+}
+
+// ==================== MCP ====================
+
+func (d *Doctor) checkMCP(ctx context.Context) []Result {
+	const group = "mcp"
+	servers, err := mcp.NewRegistryStore(d.cfg.WorkspacePath()).List()
+	if err != nil {
+		return []Result{{group, "registry", StatusFail, err.Error(), "Fix workspace/mcp/registry.json"}}
+	}
+
+	var results []Result
+	for _, name := range mcp.SortedServerNames(servers) {
+		def := servers[name]
+		if def == nil || !def.Enabled {
+			continue
+		}
+		probeCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+		count, err := mcp.Probe(probeCtx, def)
+		cancel()
+		if err != nil {
+			fix := "Check the server's url and headers"
+			if strings.EqualFold(def.Transport, "stdio") {
+				fix = fmt.Sprintf("Run '%s %s' by hand to see why it fails", def.Command, strings.Join(def.Args, " "))
+			}
+			results = append(results, Result{group, name, StatusFail, err.Error(), fix})
+			continue
+		}
+		results = append(results, Result{group, name, StatusOK, fmt.Sprintf("started, %d tools", count), ""})
+	}
+	if len(results) == 0 {
+		results = append(results, Result{group, "mcp", StatusSkip, "no MCP servers enabled", ""})
+	}
+	return results
+}
+
+// ==================== Disk ====================
+
+func (d *Doctor) checkDisk(ctx context.Context) []Result {
+	const group = "disk"
+	check, err := heartbeat.NewCheck(config.HeartbeatCheckConfig{Type: "disk"}, d.cfg)
+	if err != nil {
+		return []Result{{group, "workspace", StatusSkip, err.Error(), ""}}
+	}
+	res := check.Run(ctx)
+	if !res.OK {
+		return []Result{{group, "workspace", StatusWarn, res.Message, "Free up space on the disk that holds the workspace"}}
+	}
+	return []Result{{group, "workspace", StatusOK, res.Message, ""}}
+}
@@ -0,0 +1,88 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func find(results []Result, group, name string) *Result {
+	for i := range results {
+		if results[i].Group == group && results[i].Name == name {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+func TestCheckConfig(t *testing.T) {
+	d := New(writeConfig(t, `{"agents": {`))
+	results := d.checkConfig()
+	if r := find(results, "config", "config file"); r == nil || r.Status != StatusFail || r.Fix == "" {
+		t.Errorf("invalid JSON not reported: %+v", results)
+	}
+
+	d = New(filepath.Join(t.TempDir(), "missing.json"))
+	if r := find(d.checkConfig(), "config", "config file"); r == nil || r.Status != StatusFail {
+		t.Errorf("missing config not reported")
+	}
+}
+
+func TestProviderAndChannelChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/models" && r.Header.Get("Authorization") == "Bearer good":
+			w.Write([]byte(`{"data": []}`))
+		case strings.HasPrefix(r.URL.Path, "/botvalid/"):
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	workspace := t.TempDir()
+	d := New(writeConfig(t, `{
+		"agents": {"defaults": {"workspace": "`+workspace+`", "model": "gpt-4o"}},
+		"providers": {
+			"openai": {"api_key": "good", "api_base": "`+server.URL+`/v1"},
+			"groq": {"api_key": "bad", "api_base": "`+server.URL+`/v1"}
+		},
+		"channels": {"telegram": {"enabled": true, "token": "valid"}, "discord": {"enabled": true, "token": "nope"}}
+	}`))
+	d.telegramAPI = server.URL
+	d.discordAPI = server.URL
+
+	ctx := context.Background()
+	providers := d.checkProviders(ctx)
+	if r := find(providers, "providers", "openai"); r == nil || r.Status != StatusOK {
+		t.Errorf("openai: %+v", r)
+	}
+	if r := find(providers, "providers", "groq"); r == nil || r.Status != StatusFail || !strings.Contains(r.Fix, "providers.groq.api_key") {
+		t.Errorf("groq: %+v", r)
+	}
+
+	channels := d.checkChannels(ctx)
+	if r := find(channels, "channels", "telegram"); r == nil || r.Status != StatusOK {
+		t.Errorf("telegram: %+v", r)
+	}
+	if r := find(channels, "channels", "discord"); r == nil || r.Status != StatusFail {
+		t.Errorf("discord: %+v", r)
+	}
+	if !Failed(append(providers, channels...)) {
+		t.Error("Failed should report the rejected keys")
+	}
+}
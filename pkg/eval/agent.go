@@ -0,0 +1,24 @@
+package eval
+
+import (
+	"context"
+
+	"github.com/pepebot-space/pepebot/pkg/agent"
+)
+
+// AgentRunner runs cases on an agent. Each prompt gets a new session,
+// deleted afterwards, so cases do not see each other's messages.
+type AgentRunner struct {
+	Agent *agent.AgentLoop
+}
+
+func (r *AgentRunner) Prompt(ctx context.Context, sessionKey, prompt string) (string, error) {
+	sessions := r.Agent.Sessions()
+	sessions.DeleteSession(sessionKey)
+	defer sessions.DeleteSession(sessionKey)
+	return r.Agent.ProcessDirect(ctx, prompt, nil, sessionKey)
+}
+
+func (r *AgentRunner) Workflow(ctx context.Context, name string, vars map[string]string) (string, error) {
+	return r.Agent.WorkflowHelper().RunWorkflow(ctx, name, vars)
+}
@@ -0,0 +1,45 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestAgentRunner(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir() + "/workspace"
+	os.MkdirAll(cfg.WorkspacePath(), 0755)
+	os.WriteFile(filepath.Join(cfg.WorkspacePath(), "notes.txt"), []byte("buy milk"), 0644)
+
+	mock := providers.NewMock().
+		On("my notes", providers.MockReply{ToolCalls: []providers.MockToolCall{{Name: "read_file", Arguments: map[string]interface{}{"path": "notes.txt"}}}}).
+		On("buy milk", providers.MockReply{Content: "Your note says: buy milk."})
+	am, err := agent.NewAgentManager(cfg, bus.NewMessageBus(), mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentLoop, err := am.GetDefaultAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suite := &Suite{Name: "notes", Cases: []Case{{
+		Name:   "reads notes",
+		Prompt: "What is in my notes?",
+		Expect: Expect{Contains: []string{"milk"}, ToolCalled: []string{"read_file"}, ToolNotCalled: []string{"exec"}},
+	}}}
+	report := Run(context.Background(), suite, &AgentRunner{Agent: agentLoop}, "")
+	if report.Passed != 1 {
+		t.Fatalf("report:\n%s", report.Format(true))
+	}
+	if sessions := agentLoop.Sessions().ListSessions(""); len(sessions) != 0 {
+		t.Errorf("eval session kept: %d sessions", len(sessions))
+	}
+}
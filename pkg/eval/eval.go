@@ -0,0 +1,291 @@
+// Package eval runs suites of prompts and workflows against an agent and
+// checks the replies with assertions, so prompt and skill changes can be
+// tested before they reach a chat. Suites are YAML files:
+//
+//	name: family bot
+//	agent: default
+//	cases:
+//	  - name: knows the shopping list
+//	    prompt: What is on the shopping list?
+//	    expect:
+//	      tool_called: [kv_get]
+//	      contains: [milk]
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+// Suite is the layout of a suite file
+type Suite struct {
+	Name  string `json:"name" yaml:"name"`
+	Agent string `json:"agent,omitempty" yaml:"agent,omitempty"`
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Temperature and Seed override the agent's sampling, usually to 0 and a
+	// fixed number so runs are repeatable
+	Temperature *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	Seed        *int     `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Cases       []Case   `json:"cases" yaml:"cases"`
+}
+
+// Case is a prompt sent to the agent in a new session, or a workflow run
+// with variables, and what its reply must satisfy
+type Case struct {
+	Name     string            `json:"name" yaml:"name"`
+	Prompt   string            `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	Workflow string            `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Expect   Expect            `json:"expect" yaml:"expect"`
+}
+
+// Expect lists the assertions of a case. Contains and NotContains ignore
+// case; Regex uses Go syntax ((?i) for case-insensitive). JSONSchema needs a
+// JSON reply, optionally in a code fence, matching the schema.
+type Expect struct {
+	Contains      []string               `json:"contains,omitempty" yaml:"contains,omitempty"`
+	NotContains   []string               `json:"not_contains,omitempty" yaml:"not_contains,omitempty"`
+	Regex         []string               `json:"regex,omitempty" yaml:"regex,omitempty"`
+	JSONSchema    map[string]interface{} `json:"json_schema,omitempty" yaml:"json_schema,omitempty"`
+	ToolCalled    []string               `json:"tool_called,omitempty" yaml:"tool_called,omitempty"`
+	ToolNotCalled []string               `json:"tool_not_called,omitempty" yaml:"tool_not_called,omitempty"`
+
+	regex []*regexp.Regexp
+}
+
+// Load reads and validates a suite file
+func Load(file string) (*Suite, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid suite file: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Suite) compile() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("suite has no cases")
+	}
+	if err := s.Sampling().Check(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("case %s: duplicate name", c.Name)
+		}
+		seen[c.Name] = true
+		if (c.Prompt == "") == (c.Workflow == "") {
+			return fmt.Errorf("case %s: needs exactly one of prompt or workflow", c.Name)
+		}
+		for _, pattern := range c.Expect.Regex {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("case %s: invalid regex: %w", c.Name, err)
+			}
+			c.Expect.regex = append(c.Expect.regex, re)
+		}
+		if c.Expect.JSONSchema != nil {
+			// YAML numbers decode as ints; the validator expects JSON's float64
+			data, err := json.Marshal(c.Expect.JSONSchema)
+			if err == nil {
+				err = json.Unmarshal(data, &c.Expect.JSONSchema)
+			}
+			if err != nil {
+				return fmt.Errorf("case %s: invalid json_schema: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Sampling returns the suite's temperature and seed overrides
+func (s *Suite) Sampling() *providers.Sampling {
+	return &providers.Sampling{Temperature: s.Temperature, Seed: s.Seed}
+}
+
+// Runner answers the cases of a suite. Implemented by AgentRunner.
+type Runner interface {
+	Prompt(ctx context.Context, sessionKey, prompt string) (string, error)
+	Workflow(ctx context.Context, name string, vars map[string]string) (string, error)
+}
+
+// Result is the outcome of one case
+type Result struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Failures []string      `json:"failures,omitempty"`
+	Reply    string        `json:"reply"`
+	Tools    []string      `json:"tools,omitempty"` // in call order
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the outcome of a suite run
+type Report struct {
+	Suite    string        `json:"suite"`
+	Agent    string        `json:"agent,omitempty"`
+	Model    string        `json:"model,omitempty"`
+	Results  []Result      `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Run runs the cases of a suite one after another. Cases whose name does
+// not contain filter are skipped.
+func Run(ctx context.Context, s *Suite, runner Runner, filter string) *Report {
+	report := &Report{Suite: s.Name, Agent: s.Agent, Model: s.Model}
+	start := time.Now()
+	for i, c := range s.Cases {
+		if filter != "" && !strings.Contains(strings.ToLower(c.Name), strings.ToLower(filter)) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		result := runCase(ctx, s, i, c, runner)
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func runCase(ctx context.Context, s *Suite, index int, c Case, runner Runner) Result {
+	caseCtx, trace := tools.WithCallTrace(ctx)
+	start := time.Now()
+	var reply string
+	var err error
+	if c.Workflow != "" {
+		reply, err = runner.Workflow(caseCtx, c.Workflow, c.Vars)
+	} else {
+		reply, err = runner.Prompt(caseCtx, fmt.Sprintf("eval:%s:%d", s.Name, index+1), c.Prompt)
+	}
+
+	result := Result{Name: c.Name, Reply: reply, Duration: time.Since(start)}
+	for _, call := range trace.Calls() {
+		result.Tools = append(result.Tools, call.Name)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Failures = append(result.Failures, "error: "+err.Error())
+	} else {
+		result.Failures = c.Expect.check(reply, result.Tools)
+	}
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// check returns a description of each assertion the reply fails
+func (e *Expect) check(reply string, called []string) []string {
+	var failures []string
+	lower := strings.ToLower(reply)
+	for _, s := range e.Contains {
+		if !strings.Contains(lower, strings.ToLower(s)) {
+			failures = append(failures, fmt.Sprintf("reply does not contain %q", s))
+		}
+	}
+	for _, s := range e.NotContains {
+		if strings.Contains(lower, strings.ToLower(s)) {
+			failures = append(failures, fmt.Sprintf("reply contains %q", s))
+		}
+	}
+	for _, re := range e.regex {
+		if !re.MatchString(reply) {
+			failures = append(failures, fmt.Sprintf("reply does not match /%s/", re))
+		}
+	}
+	if e.JSONSchema != nil {
+		rf := &providers.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &providers.JSONSchemaFormat{Name: "expect", Schema: e.JSONSchema},
+		}
+		if _, err := rf.Validate(reply); err != nil {
+			failures = append(failures, "json_schema: "+err.Error())
+		}
+	}
+
+	calledSet := make(map[string]bool, len(called))
+	for _, name := range called {
+		calledSet[name] = true
+	}
+	for _, name := range e.ToolCalled {
+		if !calledSet[name] {
+			failures = append(failures, fmt.Sprintf("tool %s was not called", name))
+		}
+	}
+	for _, name := range e.ToolNotCalled {
+		if calledSet[name] {
+			failures = append(failures, fmt.Sprintf("tool %s was called", name))
+		}
+	}
+	return failures
+}
+
+// Format writes the report as text for the terminal. verbose shows the
+// replies of passed cases too.
+func (r *Report) Format(verbose bool) string {
+	var b strings.Builder
+	target := r.Agent
+	if target == "" {
+		target = "default agent"
+	}
+	if r.Model != "" {
+		target += " on " + r.Model
+	}
+	fmt.Fprintf(&b, "Suite %s (%s)\n\n", r.Suite, target)
+	for _, res := range r.Results {
+		mark := "✓"
+		if !res.Passed {
+			mark = "✗"
+		}
+		fmt.Fprintf(&b, "%s %s (%s)\n", mark, res.Name, res.Duration.Round(100*time.Millisecond))
+		for _, f := range res.Failures {
+			fmt.Fprintf(&b, "    - %s\n", f)
+		}
+		if !res.Passed || verbose {
+			if len(res.Tools) > 0 {
+				fmt.Fprintf(&b, "    tools: %s\n", strings.Join(res.Tools, ", "))
+			}
+			if res.Reply != "" {
+				fmt.Fprintf(&b, "    reply: %s\n", indent(res.Reply, "           "))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n%d passed, %d failed in %s\n", r.Passed, r.Failed, r.Duration.Round(100*time.Millisecond))
+	return b.String()
+}
+
+func indent(s, prefix string) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > 600 {
+		s = string(runes[:600]) + "…"
+	}
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,125 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+type listTool struct{}
+
+func (listTool) Name() string                       { return "shopping_list" }
+func (listTool) Description() string                { return "Read the shopping list" }
+func (listTool) Parameters() map[string]interface{} { return map[string]interface{}{"type": "object"} }
+func (listTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return "milk, eggs", nil
+}
+
+// fakeRunner answers prompts from a map, running the shopping list tool
+// for prompts that mention shopping
+type fakeRunner struct {
+	replies  map[string]string
+	registry *tools.ToolRegistry
+}
+
+func (r *fakeRunner) Prompt(ctx context.Context, sessionKey, prompt string) (string, error) {
+	if strings.Contains(prompt, "shopping") {
+		r.registry.Execute(ctx, "shopping_list", nil)
+	}
+	reply, ok := r.replies[prompt]
+	if !ok {
+		return "", errors.New("provider down")
+	}
+	return reply, nil
+}
+
+func (r *fakeRunner) Workflow(ctx context.Context, name string, vars map[string]string) (string, error) {
+	return "Weather in " + vars["city"] + ": sunny", nil
+}
+
+func TestRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "suite.yaml")
+	os.WriteFile(file, []byte(`
+name: family
+cases:
+  - name: shopping
+    prompt: What is on the shopping list?
+    expect:
+      tool_called: [shopping_list]
+      contains: [MILK]
+      not_contains: [bread]
+  - name: json
+    prompt: Give me JSON
+    expect:
+      json_schema:
+        type: object
+        required: [count]
+        properties:
+          count: {type: integer, minimum: 3}
+      tool_not_called: [shopping_list]
+  - name: weather
+    workflow: weather
+    vars: {city: Jakarta}
+    expect:
+      regex: ["(?i)weather in jakarta"]
+      tool_called: [web_search]
+  - prompt: Unknown
+`), 0644)
+	suite, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := tools.NewToolRegistry()
+	registry.Register(listTool{})
+	runner := &fakeRunner{registry: registry, replies: map[string]string{
+		"What is on the shopping list?": "You need milk and eggs.",
+		"Give me JSON":                  "```json\n{\"count\": 2}\n```",
+	}}
+	report := Run(context.Background(), suite, runner, "")
+	if report.Passed != 1 || report.Failed != 3 {
+		t.Fatalf("passed %d, failed %d:\n%s", report.Passed, report.Failed, report.Format(true))
+	}
+
+	results := map[string]Result{}
+	for _, r := range report.Results {
+		results[r.Name] = r
+	}
+	if r := results["shopping"]; !r.Passed || len(r.Tools) != 1 {
+		t.Errorf("shopping: %+v", r)
+	}
+	if r := results["json"]; len(r.Failures) != 1 || !strings.Contains(r.Failures[0], "json_schema") {
+		t.Errorf("json: %+v", r)
+	}
+	if r := results["weather"]; len(r.Failures) != 1 || r.Failures[0] != "tool web_search was not called" {
+		t.Errorf("weather: %+v", r)
+	}
+	if r := results["case 4"]; r.Error != "provider down" {
+		t.Errorf("case 4: %+v", r)
+	}
+
+	if report := Run(context.Background(), suite, runner, "SHOP"); len(report.Results) != 1 {
+		t.Errorf("filter ran %d cases", len(report.Results))
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for name, suite := range map[string]string{
+		"no cases":  "name: x\n",
+		"both":      "cases:\n  - {prompt: hi, workflow: w}\n",
+		"neither":   "cases:\n  - {name: a}\n",
+		"duplicate": "cases:\n  - {name: a, prompt: hi}\n  - {name: a, prompt: ho}\n",
+		"regex":     "cases:\n  - {prompt: hi, expect: {regex: ['(']}}\n",
+	} {
+		file := filepath.Join(t.TempDir(), "suite.yaml")
+		os.WriteFile(file, []byte(suite), 0644)
+		if _, err := Load(file); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
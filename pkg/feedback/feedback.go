@@ -0,0 +1,428 @@
+// Package feedback records the reactions users leave on agent replies in
+// chat channels as ratings: a thumbs up approves a reply, a thumbs down
+// rejects it. Ratings are logged one JSON object per line and summed up in a
+// report.
+package feedback
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+)
+
+// FileName is the feedback log in the pepebot home directory
+const FileName = "feedback.jsonl"
+
+// MaxReplies is how many recent replies are remembered for reactions
+const MaxReplies = 500
+
+// Ratings
+const (
+	Down = -1
+	None = 0 // a rating taken back
+	Up   = 1
+)
+
+var (
+	upEmoji   = []string{"👍", "❤", "💯", "🔥", "🎉", "👏", "🙏", "😍"}
+	downEmoji = []string{"👎", "😡", "💩"}
+)
+
+// Rate returns the rating a reaction emoji stands for, ignoring skin tones
+// and variation selectors, or None for other emoji
+func Rate(emoji string) int {
+	base := strings.Map(func(r rune) rune {
+		if r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF) {
+			return -1
+		}
+		return r
+	}, emoji)
+	for _, e := range upEmoji {
+		if base == e {
+			return Up
+		}
+	}
+	for _, e := range downEmoji {
+		if base == e {
+			return Down
+		}
+	}
+	return None
+}
+
+// Reply is an agent reply sent to a chat
+type Reply struct {
+	Channel   string         `json:"channel"`
+	ChatID    string         `json:"chat_id"`
+	MessageID string         `json:"message_id"`
+	Session   string         `json:"session,omitempty"`
+	Agent     string         `json:"agent,omitempty"`
+	Prompt    string         `json:"prompt,omitempty"`
+	Content   string         `json:"content"`
+	Time      time.Time      `json:"time"`
+	Ratings   map[string]int `json:"ratings,omitempty"` // user -> rating
+}
+
+// Event is a user rating a reply
+type Event struct {
+	Time      time.Time `json:"time"`
+	Channel   string    `json:"channel"`
+	ChatID    string    `json:"chat_id"`
+	MessageID string    `json:"message_id"`
+	User      string    `json:"user"`
+	Emoji     string    `json:"emoji,omitempty"`
+	Rating    int       `json:"rating"`
+	Session   string    `json:"session,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	Prompt    string    `json:"prompt,omitempty"`
+	Reply     string    `json:"reply,omitempty"`
+}
+
+// Correction is the memory note for a rejected reply
+func (e Event) Correction() string {
+	if e.Prompt == "" {
+		return fmt.Sprintf("User disliked the reply %q", excerpt(e.Reply, 200))
+	}
+	return fmt.Sprintf("User disliked the reply %q to %q", excerpt(e.Reply, 200), excerpt(e.Prompt, 150))
+}
+
+// Store remembers the replies sent recently and turns reactions to them into
+// rating events. The replies are kept in a state file so reactions still
+// count after a restart.
+type Store struct {
+	logPath   string
+	statePath string
+	mu        sync.Mutex
+	replies   []*Reply
+
+	// OnRating is called after a rating is logged
+	OnRating func(Event)
+}
+
+// NewStore returns a store logging to logPath and keeping replies in
+// statePath
+func NewStore(logPath, statePath string) *Store {
+	s := &Store{logPath: logPath, statePath: statePath}
+	if data, err := os.ReadFile(statePath); err == nil {
+		json.Unmarshal(data, &s.replies)
+	}
+	return s
+}
+
+// Sent remembers an agent reply delivered as messageID. Messages that do not
+// answer a turn, such as progress notes and command output, are ignored.
+func (s *Store) Sent(msg bus.OutboundMessage, messageID string) {
+	if msg.Progress || msg.Agent == "" || messageID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, &Reply{
+		Channel:   msg.Channel,
+		ChatID:    msg.ChatID,
+		MessageID: messageID,
+		Session:   msg.SessionKey,
+		Agent:     msg.Agent,
+		Prompt:    excerpt(msg.InReplyTo, 500),
+		Content:   excerpt(msg.Content, 1000),
+		Time:      time.Now(),
+	})
+	if len(s.replies) > MaxReplies {
+		s.replies = append([]*Reply(nil), s.replies[len(s.replies)-MaxReplies:]...)
+	}
+	s.saveLocked()
+}
+
+// Reacted records a reaction added to or removed from a message. Reactions
+// to messages other than known replies, and emoji that are not ratings, are
+// ignored. Each user has one rating per reply; removing the reaction takes
+// it back.
+func (s *Store) Reacted(channel, chatID, messageID, userID, emoji string, added bool) {
+	rating := Rate(emoji)
+	if rating == None {
+		return
+	}
+
+	s.mu.Lock()
+	reply := s.findLocked(channel, chatID, messageID)
+	if reply == nil {
+		s.mu.Unlock()
+		return
+	}
+	current := reply.Ratings[userID]
+	if !added {
+		if current != rating {
+			// Another reaction replaced this one already
+			s.mu.Unlock()
+			return
+		}
+		rating = None
+	}
+	if rating == current {
+		s.mu.Unlock()
+		return
+	}
+	if reply.Ratings == nil {
+		reply.Ratings = make(map[string]int)
+	}
+	if rating == None {
+		delete(reply.Ratings, userID)
+	} else {
+		reply.Ratings[userID] = rating
+	}
+	s.saveLocked()
+
+	e := Event{
+		Time:      time.Now(),
+		Channel:   channel,
+		ChatID:    chatID,
+		MessageID: messageID,
+		User:      userID,
+		Rating:    rating,
+		Session:   reply.Session,
+		Agent:     reply.Agent,
+		Prompt:    reply.Prompt,
+		Reply:     reply.Content,
+	}
+	if added {
+		e.Emoji = emoji
+	}
+	err := s.appendLocked(e)
+	s.mu.Unlock()
+
+	if err != nil {
+		return
+	}
+	if s.OnRating != nil {
+		s.OnRating(e)
+	}
+}
+
+func (s *Store) findLocked(channel, chatID, messageID string) *Reply {
+	for i := len(s.replies) - 1; i >= 0; i-- {
+		r := s.replies[i]
+		if r.Channel == channel && r.ChatID == chatID && r.MessageID == messageID {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *Store) saveLocked() {
+	data, err := json.Marshal(s.replies)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0755); err != nil {
+		return
+	}
+	os.WriteFile(s.statePath, data, 0600)
+}
+
+func (s *Store) appendLocked(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.logPath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the events at path since a time, oldest first. A missing
+// log has no events; unreadable lines are skipped.
+func Read(path string, since time.Time) ([]Event, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Event
+		if json.Unmarshal([]byte(line), &e) != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Tally is the ratings of an agent or channel
+type Tally struct {
+	Name string `json:"name"`
+	Up   int    `json:"up"`
+	Down int    `json:"down"`
+}
+
+// Approval is the share of ratings that approve, from 0 to 1
+func (t Tally) Approval() float64 {
+	if t.Up+t.Down == 0 {
+		return 0
+	}
+	return float64(t.Up) / float64(t.Up+t.Down)
+}
+
+// Report sums up the ratings in a period
+type Report struct {
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+	Up       int       `json:"up"`
+	Down     int       `json:"down"`
+	Agents   []Tally   `json:"agents"`
+	Channels []Tally   `json:"channels"`
+	Rejected []Event   `json:"rejected"` // recent thumbs down, newest first
+}
+
+// Compute builds the report for the events in a period. Only the last rating
+// of each user on a reply counts. agent limits the report to one agent when
+// set; recent is how many rejected replies to list.
+func Compute(events []Event, since, until time.Time, agent string, recent int) *Report {
+	r := &Report{Since: since, Until: until}
+
+	type key struct{ channel, chat, message, user string }
+	latest := make(map[key]Event)
+	var order []key
+	for _, e := range events {
+		if e.Time.Before(since) || e.Time.After(until) || (agent != "" && e.Agent != agent) {
+			continue
+		}
+		k := key{e.Channel, e.ChatID, e.MessageID, e.User}
+		if _, ok := latest[k]; !ok {
+			order = append(order, k)
+		}
+		latest[k] = e
+	}
+
+	agents := make(map[string]*Tally)
+	channels := make(map[string]*Tally)
+	count := func(m map[string]*Tally, name string, rating int) {
+		if name == "" {
+			name = "unknown"
+		}
+		t, ok := m[name]
+		if !ok {
+			t = &Tally{Name: name}
+			m[name] = t
+		}
+		if rating == Up {
+			t.Up++
+		} else {
+			t.Down++
+		}
+	}
+	for _, k := range order {
+		e := latest[k]
+		switch e.Rating {
+		case Up:
+			r.Up++
+		case Down:
+			r.Down++
+			r.Rejected = append(r.Rejected, e)
+		default:
+			continue
+		}
+		count(agents, e.Agent, e.Rating)
+		count(channels, e.Channel, e.Rating)
+	}
+	r.Agents = sortedTallies(agents)
+	r.Channels = sortedTallies(channels)
+
+	sort.SliceStable(r.Rejected, func(i, j int) bool { return r.Rejected[i].Time.After(r.Rejected[j].Time) })
+	if recent >= 0 && len(r.Rejected) > recent {
+		r.Rejected = r.Rejected[:recent]
+	}
+	return r
+}
+
+func sortedTallies(m map[string]*Tally) []Tally {
+	tallies := make([]Tally, 0, len(m))
+	for _, t := range m {
+		tallies = append(tallies, *t)
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		a, b := tallies[i], tallies[j]
+		if a.Up+a.Down != b.Up+b.Down {
+			return a.Up+a.Down > b.Up+b.Down
+		}
+		return a.Name < b.Name
+	})
+	return tallies
+}
+
+// Format writes the report as text for the terminal
+func (r *Report) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Feedback from %s to %s\n\n", r.Since.Format("2006-01-02 15:04"), r.Until.Format("2006-01-02 15:04"))
+	if r.Up+r.Down == 0 {
+		b.WriteString("No rated replies in this period.\n")
+		return b.String()
+	}
+
+	total := Tally{Up: r.Up, Down: r.Down}
+	fmt.Fprintf(&b, "Ratings: %d 👍  %d 👎  (%.0f%% approval)\n", r.Up, r.Down, total.Approval()*100)
+
+	for _, section := range []struct {
+		title   string
+		tallies []Tally
+	}{{"Per agent", r.Agents}, {"Per channel", r.Channels}} {
+		if len(section.tallies) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, t := range section.tallies {
+			fmt.Fprintf(&b, "  %-16s %3d 👍  %3d 👎  %3.0f%%\n", t.Name, t.Up, t.Down, t.Approval()*100)
+		}
+	}
+
+	if len(r.Rejected) > 0 {
+		b.WriteString("\nRecent 👎:\n")
+		for _, e := range r.Rejected {
+			fmt.Fprintf(&b, "  %s  %s/%s  %s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Channel, e.Agent, e.Session)
+			if e.Prompt != "" {
+				fmt.Fprintf(&b, "    > %s\n", excerpt(e.Prompt, 100))
+			}
+			fmt.Fprintf(&b, "    < %s\n", excerpt(e.Reply, 100))
+		}
+	}
+	return b.String()
+}
+
+// excerpt returns s on one line, cut to at most n runes
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,118 @@
+package feedback
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+)
+
+func TestRate(t *testing.T) {
+	cases := map[string]int{
+		"👍":  Up,
+		"👍🏽": Up,
+		"❤️": Up,
+		"👎":  Down,
+		"👎🏿": Down,
+		"😂":  None,
+		"":   None,
+	}
+	for emoji, want := range cases {
+		if got := Rate(emoji); got != want {
+			t.Errorf("Rate(%q) = %d, want %d", emoji, got, want)
+		}
+	}
+}
+
+func TestStoreReactions(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, FileName)
+	statePath := filepath.Join(dir, "state", "feedback_replies.json")
+	store := NewStore(logPath, statePath)
+
+	var rated []Event
+	store.OnRating = func(e Event) { rated = append(rated, e) }
+
+	reply := bus.OutboundMessage{
+		Channel:    "telegram",
+		ChatID:     "42",
+		Content:    "The capital of Australia is Sydney.",
+		SessionKey: "telegram:42",
+		Agent:      "default",
+		InReplyTo:  "What is the capital of Australia?",
+	}
+	store.Sent(reply, "100")
+	store.Sent(bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "Working…", Progress: true, Agent: "default"}, "101")
+	store.Sent(bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "Session cleared."}, "102")
+
+	store.Reacted("telegram", "42", "101", "7", "👍", true) // progress note
+	store.Reacted("telegram", "42", "102", "7", "👍", true) // command output
+	store.Reacted("telegram", "42", "100", "7", "😂", true) // not a rating
+	if len(rated) != 0 {
+		t.Fatalf("unexpected ratings: %+v", rated)
+	}
+
+	// A restarted gateway still knows the reply
+	store = NewStore(logPath, statePath)
+	store.OnRating = func(e Event) { rated = append(rated, e) }
+
+	store.Reacted("telegram", "42", "100", "7", "👍", true)
+	store.Reacted("telegram", "42", "100", "7", "👎", true)  // switches to 👎
+	store.Reacted("telegram", "42", "100", "7", "👍", false) // stale removal is ignored
+	store.Reacted("telegram", "42", "100", "8", "👍", true)
+	store.Reacted("telegram", "42", "100", "8", "👍", false) // taken back
+
+	if len(rated) != 4 {
+		t.Fatalf("ratings = %+v", rated)
+	}
+	e := rated[1]
+	if e.Rating != Down || e.User != "7" || e.Agent != "default" || e.Session != "telegram:42" || e.Prompt != reply.InReplyTo || e.Reply != reply.Content {
+		t.Errorf("event = %+v", e)
+	}
+	if rated[3].Rating != None {
+		t.Errorf("removal = %+v", rated[3])
+	}
+	if c := e.Correction(); !strings.Contains(c, "Sydney") || !strings.Contains(c, "capital of Australia?") {
+		t.Errorf("correction = %q", c)
+	}
+
+	events, err := Read(logPath, time.Time{})
+	if err != nil || len(events) != 4 {
+		t.Fatalf("read %d events, err %v", len(events), err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	now := time.Now()
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+	events := []Event{
+		{Time: at(300), Channel: "telegram", MessageID: "1", User: "a", Agent: "default", Rating: Down},
+		{Time: at(5), Channel: "telegram", MessageID: "2", User: "a", Agent: "default", Rating: Up},
+		{Time: at(4), Channel: "telegram", MessageID: "3", User: "a", Agent: "default", Rating: Down, Reply: "older"},
+		{Time: at(4), Channel: "discord", MessageID: "4", User: "b", Agent: "coder", Rating: Up},
+		{Time: at(3), Channel: "discord", MessageID: "4", User: "b", Agent: "coder", Rating: Down, Reply: "newer"},
+		{Time: at(2), Channel: "discord", MessageID: "5", User: "b", Agent: "coder", Rating: Up},
+		{Time: at(1), Channel: "discord", MessageID: "5", User: "b", Agent: "coder", Rating: None},
+	}
+
+	r := Compute(events, now.AddDate(0, 0, -7), now, "", 10)
+	if r.Up != 1 || r.Down != 2 {
+		t.Fatalf("totals: %+v", r)
+	}
+	if len(r.Agents) != 2 || r.Agents[0].Name != "default" || r.Agents[0].Up != 1 || r.Agents[0].Down != 1 {
+		t.Errorf("agents: %+v", r.Agents)
+	}
+	if len(r.Rejected) != 2 || r.Rejected[0].Reply != "newer" {
+		t.Errorf("rejected: %+v", r.Rejected)
+	}
+	if out := r.Format(); !strings.Contains(out, "33% approval") || !strings.Contains(out, "newer") {
+		t.Errorf("format:\n%s", out)
+	}
+
+	r = Compute(events, now.AddDate(0, 0, -7), now, "coder", 1)
+	if r.Up != 0 || r.Down != 1 || len(r.Channels) != 1 || r.Channels[0].Name != "discord" {
+		t.Errorf("coder report: %+v", r)
+	}
+}
@@ -0,0 +1,132 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+const rssSample = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel>
+<title>Example Blog</title>
+<item><title>Second post</title><link>https://example.com/2</link><guid>post-2</guid>
+<pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate><description>&lt;p&gt;Hello &amp;amp; bye&lt;/p&gt;</description></item>
+<item><title>First post</title><link>https://example.com/1</link></item>
+</channel></rss>`
+
+const atomSample = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>Atom Log</title>
+<entry><id>urn:1</id><title>Entry one</title>
+<link rel="alternate" href="https://example.org/one"/>
+<updated>2024-01-03T12:00:00Z</updated><summary>Short</summary></entry>
+</feed>`
+
+func TestParseRSS(t *testing.T) {
+	feed, err := Parse([]byte(rssSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Example Blog" || len(feed.Items) != 2 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	first := feed.Items[0]
+	if first.ID != "post-2" || first.Summary != "Hello & bye" || first.Published.IsZero() {
+		t.Errorf("unexpected item: %+v", first)
+	}
+	if feed.Items[1].ID != "https://example.com/1" {
+		t.Errorf("item without guid should use link as ID, got %q", feed.Items[1].ID)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	feed, err := Parse([]byte(atomSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Atom Log" || len(feed.Items) != 1 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	if item := feed.Items[0]; item.ID != "urn:1" || item.Link != "https://example.org/one" || item.Published.IsZero() {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	if _, err := Parse([]byte("<html><body>nope</body></html>")); err == nil {
+		t.Error("expected error for HTML document")
+	}
+}
+
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (s *recordingSender) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, content)
+	return nil
+}
+
+type recordingPublisher struct {
+	messages []bus.InboundMessage
+}
+
+func (p *recordingPublisher) PublishInbound(msg bus.InboundMessage) {
+	p.messages = append(p.messages, msg)
+}
+
+func TestPollDeliversOnlyNewItems(t *testing.T) {
+	body := rssSample
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	store := NewStore(t.TempDir())
+	if _, err := store.Add(Subscription{URL: srv.URL, Channel: "telegram", ChatID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Add(Subscription{URL: srv.URL, Channel: "telegram", ChatID: "1"}); err == nil {
+		t.Fatal("duplicate subscription accepted")
+	}
+	if _, err := store.Add(Subscription{URL: srv.URL, Channel: "discord", ChatID: "2", Mode: ModeAgent, Agent: "reader"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &recordingSender{}
+	publisher := &recordingPublisher{}
+	svc := NewService(config.FeedsConfig{MaxItemsPerPoll: 5}, store, sender, publisher)
+
+	// First poll primes the seen list without delivering
+	svc.Poll(context.Background())
+	if len(sender.messages) != 0 || len(publisher.messages) != 0 {
+		t.Fatalf("first poll delivered items: %v %v", sender.messages, publisher.messages)
+	}
+
+	body = strings.Replace(rssSample, "<item>", `<item><title>Third post</title><link>https://example.com/3</link><guid>post-3</guid></item><item>`, 1)
+	svc.Poll(context.Background())
+	svc.Poll(context.Background())
+
+	if len(sender.messages) != 1 || !strings.Contains(sender.messages[0], "Third post") || strings.Contains(sender.messages[0], "Second post") {
+		t.Fatalf("unexpected notifications: %v", sender.messages)
+	}
+	if len(publisher.messages) != 1 {
+		t.Fatalf("expected one agent message, got %d", len(publisher.messages))
+	}
+	msg := publisher.messages[0]
+	if msg.Metadata["agent"] != "reader" || msg.Channel != "discord" || !strings.Contains(msg.Content, "Third post") {
+		t.Errorf("unexpected agent message: %+v", msg)
+	}
+
+	subs, _ := store.List()
+	if subs[0].Title != "Example Blog" || !subs[0].Primed || len(subs[0].Seen) != 3 {
+		t.Errorf("subscription state not saved: %+v", subs[0])
+	}
+}
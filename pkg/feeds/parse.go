@@ -0,0 +1,185 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Feed is a parsed RSS or Atom document
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// Item is a single feed entry
+type Item struct {
+	ID        string
+	Title     string
+	Link      string
+	Published time.Time
+	Summary   string
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 (RDF) puts items next to the channel element
+	Items []rssItem `xml:"item"`
+}
+
+type atomDocument struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+	} `xml:"entry"`
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom 1.0 document
+func Parse(data []byte) (*Feed, error) {
+	decoder := newDecoder(data)
+	var root xml.StartElement
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("not a valid feed: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+
+	switch root.Name.Local {
+	case "rss", "RDF":
+		var doc rssDocument
+		if err := decoder.DecodeElement(&doc, &root); err != nil {
+			return nil, fmt.Errorf("invalid RSS feed: %w", err)
+		}
+		feed := &Feed{Title: strings.TrimSpace(doc.Channel.Title)}
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			published := it.PubDate
+			if published == "" {
+				published = it.Date
+			}
+			feed.Items = append(feed.Items, newItem(it.GUID, it.Title, strings.TrimSpace(it.Link), published, it.Description))
+		}
+		return feed, nil
+
+	case "feed":
+		var doc atomDocument
+		if err := decoder.DecodeElement(&doc, &root); err != nil {
+			return nil, fmt.Errorf("invalid Atom feed: %w", err)
+		}
+		feed := &Feed{Title: strings.TrimSpace(doc.Title)}
+		for _, e := range doc.Entries {
+			link := ""
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			if link == "" && len(e.Links) > 0 {
+				link = e.Links[0].Href
+			}
+			published := e.Published
+			if published == "" {
+				published = e.Updated
+			}
+			summary := e.Summary
+			if summary == "" {
+				summary = e.Content
+			}
+			feed.Items = append(feed.Items, newItem(e.ID, e.Title, link, published, summary))
+		}
+		return feed, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported feed format <%s>", root.Name.Local)
+	}
+}
+
+func newDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	// Feeds declaring legacy charsets are almost always ASCII-compatible
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+func newItem(id, title, link, published, summary string) Item {
+	item := Item{
+		ID:        strings.TrimSpace(id),
+		Title:     cleanText(title),
+		Link:      link,
+		Published: parseTime(published),
+		Summary:   cleanText(summary),
+	}
+	// Not every feed has GUIDs; the link is the next most stable identity
+	if item.ID == "" {
+		item.ID = item.Link
+	}
+	if item.ID == "" {
+		item.ID = item.Title
+	}
+	return item
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// cleanText strips HTML tags and collapses whitespace
+func cleanText(s string) string {
+	s = tagPattern.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02",
+}
+
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,260 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// maxFeedSize limits how much of a feed response is read
+const maxFeedSize = 5 * 1024 * 1024
+
+// Sender delivers notifications. Implemented by channels.Manager.
+type Sender interface {
+	SendToChannel(ctx context.Context, channelName, chatID, content string) error
+}
+
+// Publisher injects messages for agents. Implemented by bus.MessageBus.
+type Publisher interface {
+	PublishInbound(msg bus.InboundMessage)
+}
+
+// Service polls subscribed feeds and delivers new items
+type Service struct {
+	store     *Store
+	cfg       config.FeedsConfig
+	sender    Sender
+	publisher Publisher
+	client    *http.Client
+	stopChan  chan struct{}
+}
+
+func NewService(cfg config.FeedsConfig, store *Store, sender Sender, publisher Publisher) *Service {
+	return &Service{
+		store:     store,
+		cfg:       cfg,
+		sender:    sender,
+		publisher: publisher,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *Service) Start() {
+	s.stopChan = make(chan struct{})
+	go s.runLoop()
+}
+
+func (s *Service) Stop() {
+	if s.stopChan != nil {
+		close(s.stopChan)
+		s.stopChan = nil
+	}
+}
+
+func (s *Service) runLoop() {
+	interval := time.Duration(s.cfg.PollIntervalS) * time.Second
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stop := s.stopChan
+	s.Poll(context.Background())
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.Poll(context.Background())
+		}
+	}
+}
+
+// Poll checks every subscription once and delivers new items
+func (s *Service) Poll(ctx context.Context) {
+	subs, err := s.store.List()
+	if err != nil {
+		logger.ErrorCF("feeds", "Failed to load subscriptions", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for _, sub := range subs {
+		if err := s.pollOne(ctx, sub); err != nil {
+			logger.WarnCF("feeds", "Feed poll failed", map[string]interface{}{
+				"url":   sub.URL,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+func (s *Service) pollOne(ctx context.Context, sub Subscription) error {
+	feed, err := Fetch(ctx, s.client, sub.URL)
+	if err != nil {
+		s.store.update(sub.ID, func(stored *Subscription) {
+			stored.LastCheckedAt = time.Now()
+			stored.LastError = err.Error()
+		})
+		return err
+	}
+
+	seen := make(map[string]bool, len(sub.Seen))
+	for _, id := range sub.Seen {
+		seen[id] = true
+	}
+
+	// Feeds list newest first; deliver oldest first
+	fresh := []Item{}
+	for i := len(feed.Items) - 1; i >= 0; i-- {
+		if item := feed.Items[i]; !seen[item.ID] {
+			fresh = append(fresh, item)
+		}
+	}
+
+	// The first poll only records what is already there
+	deliver := fresh
+	if !sub.Primed {
+		deliver = nil
+	}
+	if max := s.cfg.MaxItemsPerPoll; max > 0 && len(deliver) > max {
+		deliver = deliver[len(deliver)-max:]
+	}
+
+	if len(deliver) > 0 {
+		title := sub.Title
+		if title == "" {
+			title = feed.Title
+		}
+		if err := s.deliver(ctx, sub, title, deliver); err != nil {
+			// Leave the items unseen so the next poll retries them
+			s.store.update(sub.ID, func(stored *Subscription) {
+				stored.LastCheckedAt = time.Now()
+				stored.LastError = err.Error()
+			})
+			return err
+		}
+		logger.InfoCF("feeds", "Delivered new feed items", map[string]interface{}{
+			"url":   sub.URL,
+			"count": len(deliver),
+			"mode":  sub.Mode,
+		})
+	}
+
+	return s.store.update(sub.ID, func(stored *Subscription) {
+		for _, item := range fresh {
+			stored.Seen = append(stored.Seen, item.ID)
+		}
+		if len(stored.Seen) > maxSeen {
+			stored.Seen = stored.Seen[len(stored.Seen)-maxSeen:]
+		}
+		if stored.Title == "" {
+			stored.Title = feed.Title
+		}
+		stored.Primed = true
+		stored.LastCheckedAt = time.Now()
+		stored.LastError = ""
+	})
+}
+
+func (s *Service) deliver(ctx context.Context, sub Subscription, title string, items []Item) error {
+	if sub.Mode == ModeAgent {
+		if s.publisher == nil {
+			return fmt.Errorf("no agent bus available")
+		}
+		s.publisher.PublishInbound(bus.InboundMessage{
+			Channel:    sub.Channel,
+			SenderID:   "feeds",
+			ChatID:     sub.ChatID,
+			Content:    agentPrompt(sub, title, items),
+			SessionKey: "feed:" + sub.ID,
+			Metadata: map[string]string{
+				"agent":   sub.Agent,
+				"feed_id": sub.ID,
+			},
+		})
+		return nil
+	}
+
+	if s.sender == nil {
+		return fmt.Errorf("no channel sender available")
+	}
+	return s.sender.SendToChannel(ctx, sub.Channel, sub.ChatID, notification(title, items))
+}
+
+func notification(title string, items []Item) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📰 **%s**\n", title)
+	for _, item := range items {
+		b.WriteString("\n• ")
+		if item.Link != "" {
+			fmt.Fprintf(&b, "[%s](%s)", item.Title, item.Link)
+		} else {
+			b.WriteString(item.Title)
+		}
+	}
+	return b.String()
+}
+
+func agentPrompt(sub Subscription, title string, items []Item) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "New items in the feed \"%s\" (%s):\n", title, sub.URL)
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n## %s\n", item.Title)
+		if item.Link != "" {
+			fmt.Fprintf(&b, "Link: %s\n", item.Link)
+		}
+		if !item.Published.IsZero() {
+			fmt.Fprintf(&b, "Published: %s\n", item.Published.Format("2006-01-02 15:04"))
+		}
+		if item.Summary != "" {
+			summary := item.Summary
+			if runes := []rune(summary); len(runes) > 1000 {
+				summary = string(runes[:1000]) + "..."
+			}
+			fmt.Fprintf(&b, "\n%s\n", summary)
+		}
+	}
+
+	instructions := sub.Instructions
+	if instructions == "" {
+		instructions = "Briefly tell the user what was posted and why it might matter to them."
+	}
+	fmt.Fprintf(&b, "\n---\n%s", instructions)
+	return b.String()
+}
+
+// Fetch downloads and parses a feed
+func Fetch(ctx context.Context, client *http.Client, url string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "pepebot-feeds/1.0")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml;q=0.9, */*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
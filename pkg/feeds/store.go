@@ -0,0 +1,163 @@
+package feeds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Delivery modes for new feed items
+const (
+	ModeNotify = "notify" // send the items to the chat as a message
+	ModeAgent  = "agent"  // hand the items to an agent as an inbound message
+)
+
+// maxSeen caps how many item IDs are remembered per subscription
+const maxSeen = 500
+
+// Subscription is a watched feed and where its new items go
+type Subscription struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title,omitempty"`
+	Channel       string    `json:"channel"`
+	ChatID        string    `json:"chat_id"`
+	Mode          string    `json:"mode"`
+	Agent         string    `json:"agent,omitempty"`
+	Instructions  string    `json:"instructions,omitempty"` // what the agent should do with new items
+	Seen          []string  `json:"seen,omitempty"`
+	Primed        bool      `json:"primed"` // existing items were recorded on the first poll
+	LastCheckedAt time.Time `json:"last_checked_at,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Store persists subscriptions in workspace/feeds/subscriptions.json. The
+// file is re-read on every operation because the CLI, agent tools and the
+// gateway poller may all modify it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(workspace string) *Store {
+	return &Store{path: filepath.Join(workspace, "feeds", "subscriptions.json")}
+}
+
+// List returns all subscriptions
+func (s *Store) List() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Add stores a new subscription. Subscribing the same chat to the same URL
+// twice is an error.
+func (s *Store) Add(sub Subscription) (*Subscription, error) {
+	if sub.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if sub.Channel == "" || sub.ChatID == "" {
+		return nil, fmt.Errorf("channel and chat_id are required")
+	}
+	if sub.Mode == "" {
+		sub.Mode = ModeNotify
+	}
+	if sub.Mode != ModeNotify && sub.Mode != ModeAgent {
+		return nil, fmt.Errorf("invalid mode '%s' (use notify or agent)", sub.Mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range subs {
+		if existing.URL == sub.URL && existing.Channel == sub.Channel && existing.ChatID == sub.ChatID {
+			return nil, fmt.Errorf("already subscribed to %s (%s)", sub.URL, existing.ID)
+		}
+	}
+
+	sub.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+	sub.CreatedAt = time.Now()
+	subs = append(subs, sub)
+	if err := s.save(subs); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// Remove deletes a subscription by ID or URL and reports whether one was found
+func (s *Store) Remove(idOrURL string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	kept := subs[:0]
+	for _, sub := range subs {
+		if sub.ID != idOrURL && sub.URL != idOrURL {
+			kept = append(kept, sub)
+		}
+	}
+	if len(kept) == len(subs) {
+		return false, nil
+	}
+	return true, s.save(kept)
+}
+
+// update applies fn to the subscription with the given ID and saves it
+func (s *Store) update(id string, fn func(sub *Subscription)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range subs {
+		if subs[i].ID == id {
+			fn(&subs[i])
+			return s.save(subs)
+		}
+	}
+	// Removed while it was being polled
+	return nil
+}
+
+func (s *Store) load() ([]Subscription, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Subscription{}, nil
+		}
+		return nil, err
+	}
+	var subs []Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return subs, nil
+}
+
+func (s *Store) save(subs []Subscription) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/voice"
+)
+
+// maxAudioUpload matches OpenAI's 25 MB limit for transcription uploads
+const maxAudioUpload = 25 << 20
+
+// handleTranscriptions handles the OpenAI-compatible transcription endpoint
+// (multipart/form-data with a "file" field)
+func (gs *GatewayServer) handleTranscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+	if gs.transcriber == nil || !gs.transcriber.IsAvailable() {
+		writeError(w, http.StatusServiceUnavailable, "audio transcription not available (set providers.groq.api_key)", "server_error")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAudioUpload+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid multipart body: "+err.Error(), "invalid_request_error")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file is required", "invalid_request_error")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAudioUpload {
+		writeError(w, http.StatusRequestEntityTooLarge, "file exceeds the 25 MB limit", "invalid_request_error")
+		return
+	}
+
+	// The backend detects the audio format from the file extension
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if ext == "" {
+		writeError(w, http.StatusBadRequest, "file name must have an audio extension (e.g. .mp3, .wav, .ogg)", "invalid_request_error")
+		return
+	}
+
+	responseFormat := r.FormValue("response_format")
+	switch responseFormat {
+	case "":
+		responseFormat = "json"
+	case "json", "text", "verbose_json", "srt", "vtt":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported response_format '%s'", responseFormat), "invalid_request_error")
+		return
+	}
+
+	opts := voice.TranscribeOptions{
+		Model:    r.FormValue("model"),
+		Language: r.FormValue("language"),
+		Prompt:   r.FormValue("prompt"),
+		Segments: responseFormat != "json" && responseFormat != "text",
+	}
+	// OpenAI clients send whisper-1; use the backend's default Whisper model
+	if opts.Model == "whisper-1" {
+		opts.Model = ""
+	}
+	if value := r.FormValue("temperature"); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "temperature must be a number", "invalid_request_error")
+			return
+		}
+		opts.Temperature = &temperature
+	}
+
+	tmp, err := os.CreateTemp("", "pepebot-audio-*"+ext)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store upload: "+err.Error(), "server_error")
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, file)
+	tmp.Close()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store upload: "+err.Error(), "server_error")
+		return
+	}
+
+	logger.DebugCF("gateway", "Transcription request", map[string]interface{}{
+		"file":            header.Filename,
+		"size":            header.Size,
+		"model":           opts.Model,
+		"response_format": responseFormat,
+	})
+
+	result, err := gs.transcriber.TranscribeWithOptions(r.Context(), tmp.Name(), opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "transcription error: "+err.Error(), "server_error")
+		return
+	}
+
+	switch responseFormat {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, result.Text)
+	case "srt":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, voice.FormatSRT(result.Segments))
+	case "vtt":
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		io.WriteString(w, voice.FormatVTT(result.Segments))
+	case "verbose_json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"task":     "transcribe",
+			"language": result.Language,
+			"duration": result.Duration,
+			"text":     result.Text,
+			"segments": result.Segments,
+		})
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"text": result.Text})
+	}
+}
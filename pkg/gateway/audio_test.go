@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/voice"
+)
+
+type fakeTranscriber struct {
+	opts voice.TranscribeOptions
+	path string
+}
+
+func (f *fakeTranscriber) TranscribeWithOptions(ctx context.Context, path string, opts voice.TranscribeOptions) (*voice.TranscriptionResponse, error) {
+	f.path, f.opts = path, opts
+	return &voice.TranscriptionResponse{
+		Text:     "hello world",
+		Segments: []voice.TranscriptionSegment{{Start: 0, End: 1.5, Text: "hello world"}},
+	}, nil
+}
+
+func (f *fakeTranscriber) IsAvailable() bool { return true }
+
+func transcriptionRequest(t *testing.T, filename string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("fake audio"))
+	for k, v := range fields {
+		writer.WriteField(k, v)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/v1/audio/transcriptions", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandleTranscriptions(t *testing.T) {
+	fake := &fakeTranscriber{}
+	gs := &GatewayServer{transcriber: fake}
+
+	rec := httptest.NewRecorder()
+	gs.handleTranscriptions(rec, transcriptionRequest(t, "note.ogg", map[string]string{"model": "whisper-1", "language": "id"}))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"text":"hello world"`) {
+		t.Fatalf("json response: %d %s", rec.Code, rec.Body.String())
+	}
+	if fake.opts.Model != "" || fake.opts.Language != "id" || fake.opts.Segments {
+		t.Errorf("unexpected options: %+v", fake.opts)
+	}
+	if !strings.HasSuffix(fake.path, ".ogg") {
+		t.Errorf("temp file should keep the extension, got %s", fake.path)
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleTranscriptions(rec, transcriptionRequest(t, "note.ogg", map[string]string{"response_format": "srt"}))
+	if !fake.opts.Segments || !strings.Contains(rec.Body.String(), "00:00:00,000 --> 00:00:01,500") {
+		t.Errorf("srt response: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleTranscriptions(rec, transcriptionRequest(t, "note", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing extension: got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	(&GatewayServer{}).handleTranscriptions(rec, transcriptionRequest(t, "note.ogg", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("no transcriber: got %d", rec.Code)
+	}
+}
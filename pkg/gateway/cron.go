@@ -0,0 +1,181 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/cron"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// cronAddRequest is the body of POST /v1/cron. Schedule and payload use the
+// same shape as jobs.json.
+type cronAddRequest struct {
+	Name     string            `json:"name"`
+	Schedule cron.CronSchedule `json:"schedule"`
+	Payload  cron.CronPayload  `json:"payload"`
+}
+
+// handleCron lists jobs (GET) or adds one (POST)
+func (gs *GatewayServer) handleCron(w http.ResponseWriter, r *http.Request) {
+	if gs.cron == nil {
+		writeError(w, http.StatusServiceUnavailable, "cron service not available", "server_error")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		jobs := append([]cron.CronJob{}, gs.cron.ListJobs(true)...)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":   jobs,
+			"status": gs.cron.Status(),
+		})
+	case http.MethodPost:
+		var req cronAddRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error(), "invalid_request_error")
+			return
+		}
+		if req.Payload.Kind == "" {
+			req.Payload.Kind = "agent_turn"
+		}
+		if msg := validateCronRequest(&req); msg != "" {
+			writeError(w, http.StatusBadRequest, msg, "invalid_request_error")
+			return
+		}
+
+		job, err := gs.cron.AddPayloadJob(req.Name, req.Schedule, req.Payload)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		logger.InfoCF("gateway", "Cron job added via API", map[string]interface{}{
+			"id":   job.ID,
+			"name": job.Name,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(job)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+	}
+}
+
+func validateCronRequest(req *cronAddRequest) string {
+	if strings.TrimSpace(req.Name) == "" {
+		return "name is required"
+	}
+	switch req.Payload.Kind {
+	case "agent_turn":
+		if req.Payload.Message == "" {
+			return "payload.message is required"
+		}
+	case "autonomy":
+		if req.Payload.Message == "" {
+			return "payload.message is required"
+		}
+		if req.Payload.Channel == "" || req.Payload.To == "" {
+			return "autonomy jobs need payload.channel and payload.to for the summary"
+		}
+	case "workflow":
+		if req.Payload.Workflow == "" {
+			return "payload.workflow is required"
+		}
+	default:
+		return "payload.kind must be agent_turn, autonomy or workflow"
+	}
+	if req.Payload.Deliver && (req.Payload.Channel == "" || req.Payload.To == "") {
+		return "payload.deliver needs payload.channel and payload.to"
+	}
+	return ""
+}
+
+// handleCronRoutes handles per-job actions.
+// GET    /v1/cron/{id}          get a job
+// DELETE /v1/cron/{id}          remove a job
+// POST   /v1/cron/{id}/enable   enable a job
+// POST   /v1/cron/{id}/disable  disable a job
+// POST   /v1/cron/{id}/run      run a job now (?wait=true returns the output)
+func (gs *GatewayServer) handleCronRoutes(w http.ResponseWriter, r *http.Request) {
+	if gs.cron == nil {
+		writeError(w, http.StatusServiceUnavailable, "cron service not available", "server_error")
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v1/cron/"), "/", 2)
+	id := parts[0]
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "job id required", "invalid_request_error")
+		return
+	}
+	job := gs.cron.GetJob(id)
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found", "not_found")
+		return
+	}
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	case action == "" && r.Method == http.MethodDelete:
+		if job.Source != "" {
+			writeError(w, http.StatusConflict, "job is defined by "+job.Source+"; remove it there (it would be re-created at the next gateway start)", "invalid_request_error")
+			return
+		}
+		gs.cron.RemoveJob(id)
+		logger.InfoCF("gateway", "Cron job removed via API", map[string]interface{}{"id": id})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"id":      id,
+		})
+	case (action == "enable" || action == "disable") && r.Method == http.MethodPost:
+		updated := gs.cron.EnableJob(id, action == "enable")
+		if updated == nil {
+			writeError(w, http.StatusNotFound, "job not found", "not_found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	case action == "run" && r.Method == http.MethodPost:
+		if r.URL.Query().Get("wait") != "true" {
+			go func() {
+				if _, err := gs.cron.RunJob(id); err != nil {
+					logger.WarnCF("gateway", "Manual cron run failed", map[string]interface{}{
+						"id":    id,
+						"error": err.Error(),
+					})
+				}
+			}()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"id":      id,
+				"status":  "started",
+			})
+			return
+		}
+
+		output, err := gs.cron.RunJob(id)
+		resp := map[string]interface{}{
+			"success": err == nil,
+			"id":      id,
+			"output":  output,
+		}
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+	}
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/cron"
+)
+
+func TestCronEndpoints(t *testing.T) {
+	var ran []string
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *cron.CronJob) (string, error) {
+		ran = append(ran, job.Name)
+		return "done: " + job.Payload.Message, nil
+	})
+	gs := &GatewayServer{cron: cs}
+
+	rec := httptest.NewRecorder()
+	gs.handleCron(rec, httptest.NewRequest("POST", "/v1/cron", strings.NewReader(`{"name":"standup","schedule":{"kind":"cron","expr":"0 9 * * 1-5"},"payload":{"message":"post the standup"}}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add: %d %s", rec.Code, rec.Body.String())
+	}
+	var job cron.CronJob
+	json.Unmarshal(rec.Body.Bytes(), &job)
+	if job.ID == "" || job.Payload.Kind != "agent_turn" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleCron(rec, httptest.NewRequest("POST", "/v1/cron", strings.NewReader(`{"name":"bad","schedule":{"kind":"cron","expr":"0 9 * * 1-5"},"payload":{"kind":"workflow"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("workflow job without a workflow: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleCronRoutes(rec, httptest.NewRequest("POST", "/v1/cron/"+job.ID+"/disable", nil))
+	if rec.Code != http.StatusOK || cs.GetJob(job.ID).Enabled {
+		t.Fatalf("disable: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleCronRoutes(rec, httptest.NewRequest("POST", "/v1/cron/"+job.ID+"/run?wait=true", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "done: post the standup") {
+		t.Fatalf("run: %d %s", rec.Code, rec.Body.String())
+	}
+	if len(ran) != 1 || cs.GetJob(job.ID).State.LastStatus != "ok" {
+		t.Errorf("run not recorded: %v %+v", ran, cs.GetJob(job.ID).State)
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleCron(rec, httptest.NewRequest("GET", "/v1/cron", nil))
+	if !strings.Contains(rec.Body.String(), `"standup"`) {
+		t.Errorf("list should include disabled jobs: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleCronRoutes(rec, httptest.NewRequest("DELETE", "/v1/cron/"+job.ID, nil))
+	if rec.Code != http.StatusOK || cs.GetJob(job.ID) != nil {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	gs.handleCronRoutes(rec, httptest.NewRequest("GET", "/v1/cron/"+job.ID, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("deleted job: %d", rec.Code)
+	}
+}
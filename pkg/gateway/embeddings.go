@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
+)
+
+// maxEmbeddingInputs matches OpenAI's per-request input limit
+const maxEmbeddingInputs = 2048
+
+type EmbeddingRequest struct {
+	Model          string      `json:"model"`
+	Input          interface{} `json:"input"`                     // string or array of strings
+	EncodingFormat string      `json:"encoding_format,omitempty"` // "float" (default) or "base64"
+	Dimensions     int         `json:"dimensions,omitempty"`
+}
+
+type EmbeddingResponse struct {
+	Object string          `json:"object"`
+	Data   []EmbeddingData `json:"data"`
+	Model  string          `json:"model"`
+	Usage  *UsageResponse  `json:"usage"`
+}
+
+type EmbeddingData struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"` // []float64, or a base64 string of float32s
+}
+
+// handleEmbeddings handles the OpenAI-compatible embeddings endpoint
+func (gs *GatewayServer) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+
+	var req EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "invalid_request_error")
+		return
+	}
+
+	input, err := embeddingInputs(req.Input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeError(w, http.StatusBadRequest, "encoding_format must be 'float' or 'base64'", "invalid_request_error")
+		return
+	}
+
+	model := req.Model
+	if model == "" {
+		model = gs.config.Embeddings.Model
+	}
+	if model == "" {
+		writeError(w, http.StatusBadRequest, "model is required (or set embeddings.model in config)", "invalid_request_error")
+		return
+	}
+
+	embedder, err := gs.embeddingProvider(model)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+		return
+	}
+
+	logger.DebugCF("gateway", "Embeddings request", map[string]interface{}{
+		"model":  model,
+		"inputs": len(input),
+	})
+
+	result, err := embedder.Embed(r.Context(), model, input, req.Dimensions)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "embedding error: "+err.Error(), "server_error")
+		return
+	}
+
+	resp := EmbeddingResponse{Object: "list", Model: model}
+	for i, vector := range result.Embeddings {
+		var embedding interface{} = vector
+		if req.EncodingFormat == "base64" {
+			embedding = encodeEmbedding(vector)
+		}
+		resp.Data = append(resp.Data, EmbeddingData{Object: "embedding", Index: i, Embedding: embedding})
+	}
+
+	promptTokens := 0
+	if result.Usage != nil && result.Usage.PromptTokens > 0 {
+		promptTokens = result.Usage.PromptTokens
+	} else {
+		for _, text := range input {
+			promptTokens += tokens.Count(model, text)
+		}
+	}
+	resp.Usage = &UsageResponse{PromptTokens: promptTokens, TotalTokens: promptTokens}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// embeddingProvider returns a cached provider for the embedding model
+func (gs *GatewayServer) embeddingProvider(model string) (providers.EmbeddingProvider, error) {
+	gs.embedMu.Lock()
+	defer gs.embedMu.Unlock()
+
+	if embedder, ok := gs.embedders[model]; ok {
+		return embedder, nil
+	}
+	embedder, err := providers.CreateEmbeddingProvider(gs.config, model)
+	if err != nil {
+		return nil, err
+	}
+	if gs.embedders == nil {
+		gs.embedders = make(map[string]providers.EmbeddingProvider)
+	}
+	gs.embedders[model] = embedder
+	return embedder, nil
+}
+
+// embeddingInputs accepts a string or an array of strings. Token arrays
+// are not supported since pepebot does not know the target vocabulary.
+func embeddingInputs(input interface{}) ([]string, error) {
+	var texts []string
+	switch v := input.(type) {
+	case string:
+		texts = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("input must be a string or an array of strings")
+			}
+			texts = append(texts, text)
+		}
+	default:
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	if len(texts) > maxEmbeddingInputs {
+		return nil, fmt.Errorf("input has %d items, the maximum is %d", len(texts), maxEmbeddingInputs)
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("input[%d] is empty", i)
+		}
+	}
+	return texts, nil
+}
+
+// encodeEmbedding packs a vector as little-endian float32s in base64, the
+// format OpenAI SDKs request by default
+func encodeEmbedding(vector []float64) string {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
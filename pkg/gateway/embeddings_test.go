@@ -0,0 +1,32 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestEmbeddingInputs(t *testing.T) {
+	if texts, err := embeddingInputs("hello"); err != nil || len(texts) != 1 {
+		t.Errorf("string input: %v, %v", texts, err)
+	}
+	if texts, err := embeddingInputs([]interface{}{"a", "b"}); err != nil || len(texts) != 2 {
+		t.Errorf("array input: %v, %v", texts, err)
+	}
+	for _, input := range []interface{}{nil, []interface{}{}, []interface{}{1.0, 2.0}, []interface{}{"a", ""}} {
+		if _, err := embeddingInputs(input); err == nil {
+			t.Errorf("expected %v to be rejected", input)
+		}
+	}
+}
+
+func TestEncodeEmbedding(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(encodeEmbedding([]float64{0.5, -1}))
+	if err != nil || len(data) != 8 {
+		t.Fatalf("decode: %v, %d bytes", err, len(data))
+	}
+	if v := math.Float32frombits(binary.LittleEndian.Uint32(data[4:])); v != -1 {
+		t.Errorf("second value = %v, want -1", v)
+	}
+}
@@ -1,18 +1,26 @@
 package gateway
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/pepebot-space/pepebot/pkg/broadcast"
 	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/hooks"
 	"github.com/pepebot-space/pepebot/pkg/logger"
 	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/session"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
+	"github.com/pepebot-space/pepebot/pkg/workflow"
 )
 
 // OpenAI-compatible request/response types
@@ -23,11 +31,27 @@ type ChatCompletionRequest struct {
 	Stream      bool          `json:"stream"`
 	Temperature *float64      `json:"temperature,omitempty"`
 	MaxTokens   *int          `json:"max_tokens,omitempty"`
+	// Temperature and Seed override the agent's settings for every call of
+	// the request
+	Seed *int `json:"seed,omitempty"`
+	// ResponseFormat is passed to the model on every agent call; with JSON
+	// formats the final reply is validated before it is returned
+	ResponseFormat *providers.ResponseFormat `json:"response_format,omitempty"`
+	// Tools and ToolChoice are only used in passthrough tool mode
+	Tools      []providers.ToolDefinition `json:"tools,omitempty"`
+	ToolChoice interface{}                `json:"tool_choice,omitempty"`
+}
+
+// sampling returns the request's temperature and seed overrides
+func (req *ChatCompletionRequest) sampling() *providers.Sampling {
+	return &providers.Sampling{Temperature: req.Temperature, Seed: req.Seed}
 }
 
 type ChatMessage struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
+	Role       string               `json:"role"`
+	Content    interface{}          `json:"content"`
+	ToolCalls  []providers.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string               `json:"tool_call_id,omitempty"`
 }
 
 // ChatContentBlock represents an OpenAI-compatible content block (text, image_url, file)
@@ -133,8 +157,16 @@ type StreamChunkChoice struct {
 }
 
 type StreamChunkDelta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string                `json:"role,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	ToolCalls []StreamToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+type StreamToolCallDelta struct {
+	Index    int                     `json:"index"`
+	ID       string                  `json:"id,omitempty"`
+	Type     string                  `json:"type,omitempty"`
+	Function *providers.FunctionCall `json:"function,omitempty"`
 }
 
 type ModelListResponse struct {
@@ -143,10 +175,25 @@ type ModelListResponse struct {
 }
 
 type ModelObject struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	OwnedBy string `json:"owned_by"`
+	ID           string                       `json:"id"`
+	Object       string                       `json:"object"`
+	Created      int64                        `json:"created"`
+	OwnedBy      string                       `json:"owned_by"`
+	Capabilities *providers.ModelCapabilities `json:"capabilities,omitempty"`
+}
+
+// newModelObject describes a model with its known capabilities
+func newModelObject(id string) ModelObject {
+	model := ModelObject{
+		ID:      id,
+		Object:  "model",
+		Created: time.Now().Unix(),
+		OwnedBy: "pepebot",
+	}
+	if caps, ok := providers.Capabilities(id); ok {
+		model.Capabilities = &caps
+	}
+	return model
 }
 
 type SessionListResponse struct {
@@ -208,6 +255,39 @@ func (gs *GatewayServer) handleChatCompletions(w http.ResponseWriter, r *http.Re
 		sessionKey = "web:" + agentName
 	}
 
+	if req.ResponseFormat != nil {
+		if err := req.ResponseFormat.Check(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		r = r.WithContext(providers.WithResponseFormat(r.Context(), req.ResponseFormat))
+	}
+	if sampling := req.sampling(); !sampling.IsEmpty() {
+		if err := sampling.Check(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		r = r.WithContext(providers.WithSampling(r.Context(), sampling))
+	}
+
+	toolMode := gs.toolMode(r)
+	switch toolMode {
+	case toolModeAgent:
+		if len(req.Tools) > 0 {
+			logger.WarnCF("gateway", "Client tools ignored in agent tool mode", map[string]interface{}{
+				"agent": agentName,
+				"tools": len(req.Tools),
+			})
+		}
+	case toolModePassthrough:
+		r = gs.startRequestLog(w, r, &req, agentName, sessionKey, toolMode)
+		gs.handlePassthrough(w, r, &req, agentName)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported tool mode '%s'", toolMode), "invalid_request_error")
+		return
+	}
+
 	// Get the last user message as the content to process
 	lastMessage := req.Messages[len(req.Messages)-1]
 	if lastMessage.Role != "user" {
@@ -224,22 +304,32 @@ func (gs *GatewayServer) handleChatCompletions(w http.ResponseWriter, r *http.Re
 		"stream":      req.Stream,
 		"model":       req.Model,
 		"has_media":   len(media) > 0,
+		"json_output": req.ResponseFormat.WantsJSON(),
 	})
 
 	completionID := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	r = gs.startRequestLog(w, r, &req, agentName, sessionKey, toolMode)
 
 	if req.Stream {
 		gs.handleStreamingResponse(w, r, textContent, media, sessionKey, agentName, req.Model, completionID)
 	} else {
-		gs.handleNonStreamingResponse(w, r, textContent, media, sessionKey, agentName, req.Model, completionID)
+		promptTokens := 0
+		for _, m := range req.Messages {
+			text, _ := parseMessageContent(m)
+			promptTokens += tokens.Count(req.Model, text)
+		}
+		gs.handleNonStreamingResponse(w, r, textContent, media, sessionKey, agentName, req.Model, completionID, promptTokens)
 	}
 }
 
 // handleNonStreamingResponse handles non-streaming chat completions
-func (gs *GatewayServer) handleNonStreamingResponse(w http.ResponseWriter, r *http.Request, content string, media []string, sessionKey, agentName, model, completionID string) {
+// promptTokens counts the request's messages; usage does not include the
+// agent's own system prompt, history or tool calls.
+func (gs *GatewayServer) handleNonStreamingResponse(w http.ResponseWriter, r *http.Request, content string, media []string, sessionKey, agentName, model, completionID string, promptTokens int) {
 	ctx := r.Context()
 
 	response, err := gs.agentManager.ProcessDirect(ctx, content, media, sessionKey, agentName)
+	gs.finishRequestLog(r, response, nil, err)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "processing error: "+err.Error(), "server_error")
 		return
@@ -261,6 +351,12 @@ func (gs *GatewayServer) handleNonStreamingResponse(w http.ResponseWriter, r *ht
 			},
 		},
 	}
+	completionTokens := tokens.Count(model, response)
+	resp.Usage = &UsageResponse{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -299,6 +395,7 @@ func (gs *GatewayServer) handleStreamingResponse(w http.ResponseWriter, r *http.
 
 	ctx := r.Context()
 
+	var reply strings.Builder
 	err := gs.agentManager.ProcessDirectStream(ctx, content, media, sessionKey, agentName, func(chunk providers.StreamChunk) {
 		if chunk.Done {
 			// Send finish chunk
@@ -323,6 +420,7 @@ func (gs *GatewayServer) handleStreamingResponse(w http.ResponseWriter, r *http.
 		}
 
 		if chunk.Content != "" {
+			reply.WriteString(chunk.Content)
 			contentChunk := StreamChunkResponse{
 				ID:      completionID,
 				Object:  "chat.completion.chunk",
@@ -341,6 +439,7 @@ func (gs *GatewayServer) handleStreamingResponse(w http.ResponseWriter, r *http.
 			flusher.Flush()
 		}
 	})
+	gs.finishRequestLog(r, reply.String(), nil, err)
 
 	if err != nil {
 		logger.ErrorCF("gateway", "Stream processing error", map[string]interface{}{
@@ -366,23 +465,13 @@ func (gs *GatewayServer) handleListModels(w http.ResponseWriter, r *http.Request
 			continue
 		}
 		seen[agentDef.Model] = true
-		models = append(models, ModelObject{
-			ID:      agentDef.Model,
-			Object:  "model",
-			Created: time.Now().Unix(),
-			OwnedBy: "pepebot",
-		})
+		models = append(models, newModelObject(agentDef.Model))
 	}
 
 	// Add default model from config if not already present
 	defaultModel := gs.config.Agents.Defaults.Model
 	if !seen[defaultModel] {
-		models = append(models, ModelObject{
-			ID:      defaultModel,
-			Object:  "model",
-			Created: time.Now().Unix(),
-			OwnedBy: "pepebot",
-		})
+		models = append(models, newModelObject(defaultModel))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -424,13 +513,18 @@ func (gs *GatewayServer) handleListSessions(w http.ResponseWriter, r *http.Reque
 
 // handleSessionRoutes dispatches session sub-routes
 func (gs *GatewayServer) handleSessionRoutes(w http.ResponseWriter, r *http.Request) {
-	// Parse: /v1/sessions/{key}/new, /v1/sessions/{key}/stop, /v1/sessions/{key}
+	// Parse: /v1/sessions/search, /v1/sessions/{key}/new, /v1/sessions/{key}/stop, /v1/sessions/{key}/fork, /v1/sessions/{key}/pins, /v1/sessions/{key}
 	path := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
 	if path == "" {
 		gs.handleListSessions(w, r)
 		return
 	}
 
+	if path == "search" && r.Method == http.MethodGet {
+		gs.handleSessionSearch(w, r)
+		return
+	}
+
 	// Check for sub-actions
 	if strings.HasSuffix(path, "/new") {
 		sessionKey := strings.TrimSuffix(path, "/new")
@@ -444,6 +538,18 @@ func (gs *GatewayServer) handleSessionRoutes(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if strings.HasSuffix(path, "/pins") {
+		sessionKey := strings.TrimSuffix(path, "/pins")
+		gs.handleSessionPins(w, r, sessionKey)
+		return
+	}
+
+	if strings.HasSuffix(path, "/fork") {
+		sessionKey := strings.TrimSuffix(path, "/fork")
+		gs.handleSessionFork(w, r, sessionKey)
+		return
+	}
+
 	// Direct session key - GET to get history, DELETE to delete
 	sessionKey := path
 	if r.Method == http.MethodGet {
@@ -524,6 +630,146 @@ func (gs *GatewayServer) handleSessionStop(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// handleSessionSearch searches messages across all sessions.
+// Query parameters: q (required) and limit (default 20).
+func (gs *GatewayServer) handleSessionSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q is required", "invalid_request_error")
+		return
+	}
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer", "invalid_request_error")
+			return
+		}
+		limit = n
+	}
+
+	results := gs.agentManager.SearchSessions(query, limit)
+	if results == nil {
+		results = []session.SearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   query,
+		"results": results,
+	})
+}
+
+// handleSessionPins lists, adds and removes pinned facts of a session.
+// GET lists them, POST {"text": "..."} adds one, DELETE removes the pin
+// given by the 1-based ?index= or all pins without it. ?agent= selects the
+// agent; web:<agent> keys default to that agent.
+func (gs *GatewayServer) handleSessionPins(w http.ResponseWriter, r *http.Request, sessionKey string) {
+	agentName := r.URL.Query().Get("agent")
+	if agentName == "" && strings.HasPrefix(sessionKey, "web:") {
+		agentName = strings.SplitN(strings.TrimPrefix(sessionKey, "web:"), ":", 2)[0]
+	}
+	sessions, err := gs.agentManager.AgentSessions(agentName)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error(), "invalid_request_error")
+		return
+	}
+
+	var pins []string
+	switch r.Method {
+	case http.MethodGet:
+		pins = sessions.GetPins(sessionKey)
+	case http.MethodPost:
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error(), "invalid_request_error")
+			return
+		}
+		if pins, err = sessions.Pin(sessionKey, req.Text); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+	case http.MethodDelete:
+		index := 0
+		if v := r.URL.Query().Get("index"); v != "" {
+			if index, err = strconv.Atoi(v); err != nil || index < 1 {
+				writeError(w, http.StatusBadRequest, "index must be a positive integer", "invalid_request_error")
+				return
+			}
+		}
+		if pins, err = sessions.Unpin(sessionKey, index); err != nil {
+			writeError(w, http.StatusNotFound, err.Error(), "invalid_request_error")
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+
+	if pins == nil {
+		pins = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session_key": sessionKey,
+		"pins":        pins,
+	})
+}
+
+// handleSessionFork copies a session's history into a new session key.
+// The body may set "new_key" and "agent"; the key defaults to
+// {key}:fork-{unix time}.
+func (gs *GatewayServer) handleSessionFork(w http.ResponseWriter, r *http.Request, sessionKey string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+
+	var req struct {
+		NewKey string `json:"new_key"`
+		Agent  string `json:"agent"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error(), "invalid_request_error")
+			return
+		}
+	}
+	if req.NewKey == "" {
+		req.NewKey = fmt.Sprintf("%s:fork-%d", sessionKey, time.Now().Unix())
+	}
+	if req.Agent == "" && strings.HasPrefix(sessionKey, "web:") {
+		req.Agent = strings.SplitN(strings.TrimPrefix(sessionKey, "web:"), ":", 2)[0]
+	}
+
+	fork, err := gs.agentManager.ForkSession(sessionKey, req.NewKey, req.Agent)
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "already exists") {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error(), "invalid_request_error")
+		return
+	}
+
+	logger.InfoCF("gateway", "Session forked", map[string]interface{}{
+		"session_key": sessionKey,
+		"new_key":     fork.Key,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "ok",
+		"session_key":   fork.Key,
+		"forked_from":   sessionKey,
+		"message_count": len(fork.Messages),
+	})
+}
+
 // handleDeleteSession deletes a specific session
 func (gs *GatewayServer) handleDeleteSession(w http.ResponseWriter, r *http.Request, sessionKey string) {
 	agentName := "default"
@@ -927,14 +1173,23 @@ func (gs *GatewayServer) handleListWorkflows(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-// handleGetWorkflow returns a full workflow definition
+// handleGetWorkflow returns a full workflow definition. It also serves
+// /v1/workflows/{name}/export and POST /v1/workflows/import.
 func (gs *GatewayServer) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/v1/workflows/")
+	if name == "import" && r.Method == http.MethodPost {
+		gs.handleImportWorkflow(w, r)
+		return
+	}
+	if strings.HasSuffix(name, "/export") {
+		gs.handleExportWorkflow(w, r, strings.TrimSuffix(name, "/export"))
+		return
+	}
+
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
 		return
 	}
-
-	name := strings.TrimPrefix(r.URL.Path, "/v1/workflows/")
 	if name == "" {
 		writeError(w, http.StatusBadRequest, "workflow name required", "invalid_request_error")
 		return
@@ -953,6 +1208,68 @@ func (gs *GatewayServer) handleGetWorkflow(w http.ResponseWriter, r *http.Reques
 	w.Write(data)
 }
 
+// handleExportWorkflow downloads a workflow bundle (.tar.gz)
+func (gs *GatewayServer) handleExportWorkflow(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+	helper := workflow.NewWorkflowHelper(gs.config.WorkspacePath(), nil)
+	if _, err := helper.LoadWorkflow(name); err != nil || strings.Contains(name, "/") {
+		writeError(w, http.StatusNotFound, "workflow not found", "not_found")
+		return
+	}
+
+	var buf bytes.Buffer
+	if _, err := helper.ExportBundle(name, &buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".workflow.tar.gz"))
+	w.Write(buf.Bytes())
+}
+
+// maxWorkflowBundle caps the size of an uploaded workflow bundle
+const maxWorkflowBundle = 200 << 20
+
+// handleImportWorkflow imports a workflow bundle sent as the request body.
+// Query: name (import under another name), on_conflict (fail, skip, overwrite, rename)
+func (gs *GatewayServer) handleImportWorkflow(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWorkflowBundle)
+	helper := workflow.NewWorkflowHelper(gs.config.WorkspacePath(), nil)
+	result, err := helper.ImportBundle(r.Body, workflow.ImportOptions{
+		Name:       r.URL.Query().Get("name"),
+		OnConflict: r.URL.Query().Get("on_conflict"),
+	})
+
+	var conflict *workflow.ConflictError
+	if errors.As(err, &conflict) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"message":   err.Error(),
+				"type":      "conflict",
+				"code":      http.StatusText(http.StatusConflict),
+				"conflicts": conflict.Paths,
+			},
+		})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+		return
+	}
+
+	logger.InfoCF("gateway", "Workflow bundle imported", map[string]interface{}{
+		"workflow": result.Workflow,
+		"files":    len(result.Written),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // configPath returns the path to config.json
 func configPath() string {
 	home, _ := os.UserHomeDir()
@@ -1039,7 +1356,7 @@ func maskAPIKeys(obj map[string]interface{}) {
 		case map[string]interface{}:
 			maskAPIKeys(v)
 		case string:
-			if (strings.Contains(key, "api_key") || strings.Contains(key, "token") || strings.Contains(key, "secret")) && v != "" {
+			if (strings.Contains(key, "api_key") || strings.Contains(key, "token") || strings.Contains(key, "secret") || strings.Contains(key, "password")) && v != "" {
 				if len(v) > 8 {
 					obj[key] = v[:4] + "****" + v[len(v)-4:]
 				} else {
@@ -1143,6 +1460,201 @@ func (gs *GatewayServer) handleSend(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// maxHookBody limits webhook payload size
+const maxHookBody = 1 << 20
+
+// handleHook converts a verified webhook payload into an inbound message for the hook's agent.
+// POST /v1/hooks/{name}
+func (gs *GatewayServer) handleHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/hooks/"), "/")
+	hook, ok := gs.config.Hooks[name]
+	if name == "" || !ok {
+		writeError(w, http.StatusNotFound, "hook not found", "not_found")
+		return
+	}
+	if hook.Channel == "" || hook.ChatID == "" {
+		writeError(w, http.StatusInternalServerError, "hook has no channel/chat_id configured", "server_error")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxHookBody+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read body", "invalid_request_error")
+		return
+	}
+	if len(body) > maxHookBody {
+		writeError(w, http.StatusRequestEntityTooLarge, "payload too large", "invalid_request_error")
+		return
+	}
+
+	if err := hooks.Verify(hook, r.Header, body); err != nil {
+		logger.WarnCF("gateway", "Rejected webhook", map[string]interface{}{
+			"hook":  name,
+			"error": err.Error(),
+		})
+		writeError(w, http.StatusUnauthorized, "signature verification failed", "authentication_error")
+		return
+	}
+
+	data := hooks.NewData(name, r.Header, body)
+	prompt, err := hooks.Render(hook.Template, data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	if prompt == "" {
+		// Templates can filter out events by rendering nothing
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "skipped": true})
+		return
+	}
+
+	gs.bus.PublishInbound(bus.InboundMessage{
+		Channel:    hook.Channel,
+		SenderID:   "hook:" + name,
+		ChatID:     hook.ChatID,
+		Content:    prompt,
+		SessionKey: "hook:" + name,
+		Metadata: map[string]string{
+			"agent": hook.Agent,
+			"hook":  name,
+			"event": data.Event,
+		},
+	})
+
+	logger.InfoCF("gateway", "Webhook accepted", map[string]interface{}{
+		"hook":  name,
+		"event": data.Event,
+		"bytes": len(body),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// handleBroadcast sends a templated message to many recipients.
+// GET  /v1/broadcast  lists configured recipient lists and templates
+// POST /v1/broadcast  {"list":"team","message":"Hi {{name}}","vars":{},"recipients":[],"throttle_ms":1000}
+func (gs *GatewayServer) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	if gs.broadcaster == nil {
+		writeError(w, http.StatusServiceUnavailable, "broadcast not available", "server_error")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lists":     gs.broadcaster.Lists(),
+			"templates": gs.broadcaster.Templates(),
+		})
+	case http.MethodPost:
+		var req broadcast.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "invalid_request_error")
+			return
+		}
+
+		result, err := gs.broadcaster.Send(r.Context(), req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+	}
+}
+
+// handleListOutbox returns outbox entries.
+// GET /v1/outbox                  counts plus pending and dead-lettered entries
+// GET /v1/outbox?status=delivered  entries with a single status (pending, dead, delivered)
+func (gs *GatewayServer) handleListOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+	if gs.outbox == nil {
+		writeError(w, http.StatusServiceUnavailable, "outbox not enabled", "server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		entries, err := gs.outbox.List(status)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  status,
+			"entries": entries,
+		})
+		return
+	}
+
+	pending, _ := gs.outbox.List(bus.OutboxPending)
+	dead, _ := gs.outbox.List(bus.OutboxDead)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats":   gs.outbox.Stats(),
+		"pending": pending,
+		"dead":    dead,
+	})
+}
+
+// handleOutboxRoutes handles per-entry outbox actions.
+// POST   /v1/outbox/{id}/retry  move a dead-lettered message back to pending
+// DELETE /v1/outbox/{id}        drop a pending or dead-lettered message
+func (gs *GatewayServer) handleOutboxRoutes(w http.ResponseWriter, r *http.Request) {
+	if gs.outbox == nil {
+		writeError(w, http.StatusServiceUnavailable, "outbox not enabled", "server_error")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/outbox/")
+	parts := strings.SplitN(path, "/", 2)
+	id := parts[0]
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "message id required", "invalid_request_error")
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "retry" && r.Method == http.MethodPost:
+		entry, err := gs.outbox.Retry(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error(), "invalid_request_error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"entry":   entry,
+		})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := gs.outbox.Delete(id); err != nil {
+			writeError(w, http.StatusNotFound, err.Error(), "invalid_request_error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"id":      id,
+		})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+	}
+}
+
 // writeError writes a JSON error response
 func writeError(w http.ResponseWriter, statusCode int, message, errType string) {
 	w.Header().Set("Content-Type", "application/json")
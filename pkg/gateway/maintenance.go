@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleMaintenance reports (GET) or changes (POST {"enabled": true,
+// "message": "..."}) maintenance mode
+func (gs *GatewayServer) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if gs.agentManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent manager not available", "server_error")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(gs.agentManager.Maintenance())
+	case http.MethodPost:
+		var req struct {
+			Enabled *bool  `json:"enabled"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error(), "invalid_request_error")
+			return
+		}
+		if req.Enabled == nil {
+			writeError(w, http.StatusBadRequest, "enabled is required", "invalid_request_error")
+			return
+		}
+		state, err := gs.agentManager.SetMaintenance(*req.Enabled, req.Message)
+		if err != nil {
+			// The mode is changed, it just won't survive a restart
+			writeError(w, http.StatusInternalServerError, "maintenance mode changed but not saved: "+err.Error(), "server_error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+	}
+}
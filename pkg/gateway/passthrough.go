@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tokens"
+)
+
+// Tool modes for /v1/chat/completions
+const (
+	toolModeAgent       = "agent"       // the agent loop runs pepebot's own tools
+	toolModePassthrough = "passthrough" // client tools are forwarded, tool_calls returned
+)
+
+// toolMode returns the request's tool mode: the X-Tool-Mode header, then
+// gateway.tool_mode, then agent
+func (gs *GatewayServer) toolMode(r *http.Request) string {
+	mode := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Tool-Mode")))
+	if mode == "" {
+		mode = strings.ToLower(strings.TrimSpace(gs.config.Gateway.ToolMode))
+	}
+	if mode == "" {
+		return toolModeAgent
+	}
+	return mode
+}
+
+// handlePassthrough forwards the whole conversation and the client's tools
+// to the agent's model and returns its reply, including tool_calls, as is.
+// The agent's prompt, session and tools are not used, and nothing is stored.
+func (gs *GatewayServer) handlePassthrough(w http.ResponseWriter, r *http.Request, req *ChatCompletionRequest, agentName string) {
+	messages := make([]providers.Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, providers.Message{
+			Role:       m.Role,
+			Content:    toProviderContent(m.Content),
+			ToolCalls:  m.ToolCalls,
+			ToolCallID: m.ToolCallID,
+		})
+	}
+
+	// Temperature and seed come with the request context
+	options := map[string]interface{}{}
+	if req.MaxTokens != nil {
+		options["max_tokens"] = *req.MaxTokens
+	}
+	if req.ToolChoice != nil {
+		options["tool_choice"] = req.ToolChoice
+	}
+
+	logger.DebugCF("gateway", "Passthrough chat completion", map[string]interface{}{
+		"agent":    agentName,
+		"model":    req.Model,
+		"messages": len(messages),
+		"tools":    len(req.Tools),
+		"stream":   req.Stream,
+	})
+
+	response, err := gs.agentManager.Passthrough(r.Context(), agentName, messages, req.Tools, options)
+	if err != nil {
+		gs.finishRequestLog(r, "", nil, err)
+		writeError(w, http.StatusInternalServerError, "processing error: "+err.Error(), "server_error")
+		return
+	}
+
+	gs.finishRequestLog(r, response.Content, response.ToolCalls, nil)
+
+	completionID := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	toolCalls := toOpenAIToolCalls(response.ToolCalls)
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	} else if response.FinishReason == "length" {
+		finishReason = "length"
+	}
+
+	if req.Stream {
+		writePassthroughStream(w, completionID, req.Model, response.Content, toolCalls, finishReason)
+		return
+	}
+
+	message := &ChatMessage{Role: "assistant", Content: response.Content, ToolCalls: toolCalls}
+	if response.Content == "" && len(toolCalls) > 0 {
+		message.Content = nil
+	}
+
+	usage := response.Usage
+	if usage == nil {
+		prompt := tokens.CountMessages(req.Model, messages)
+		completion := tokens.Count(req.Model, response.Content)
+		usage = &providers.UsageInfo{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChatCompletionResponse{
+		ID:      completionID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []ChatCompletionChoice{
+			{Index: 0, Message: message, FinishReason: finishReason},
+		},
+		Usage: &UsageResponse{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		},
+	})
+}
+
+// writePassthroughStream sends a completed reply as SSE chunks. Providers
+// do not stream tool calls, so the reply arrives in one piece.
+func writePassthroughStream(w http.ResponseWriter, completionID, model, content string, toolCalls []providers.ToolCall, finishReason string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	chunk := func(delta StreamChunkDelta, finish *string) StreamChunkResponse {
+		return StreamChunkResponse{
+			ID:      completionID,
+			Object:  "chat.completion.chunk",
+			Created: time.Now().Unix(),
+			Model:   model,
+			Choices: []StreamChunkChoice{{Index: 0, Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	writeSSEChunk(w, chunk(StreamChunkDelta{Role: "assistant"}, nil))
+	if content != "" {
+		writeSSEChunk(w, chunk(StreamChunkDelta{Content: content}, nil))
+	}
+	if len(toolCalls) > 0 {
+		deltas := make([]StreamToolCallDelta, 0, len(toolCalls))
+		for i, tc := range toolCalls {
+			deltas = append(deltas, StreamToolCallDelta{Index: i, ID: tc.ID, Type: tc.Type, Function: tc.Function})
+		}
+		writeSSEChunk(w, chunk(StreamChunkDelta{ToolCalls: deltas}, nil))
+	}
+	writeSSEChunk(w, chunk(StreamChunkDelta{}, &finishReason))
+	fmt.Fprintf(w, "data: [DONE]\n\n")
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// toProviderContent converts OpenAI message content (a string, content
+// blocks or null) to the provider format
+func toProviderContent(content interface{}) interface{} {
+	switch v := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		data, err := json.Marshal(v)
+		if err == nil {
+			var blocks []providers.ContentBlock
+			if json.Unmarshal(data, &blocks) == nil {
+				return blocks
+			}
+		}
+	}
+	return getContentString(content)
+}
+
+// toOpenAIToolCalls converts parsed provider tool calls to the OpenAI wire
+// format, with arguments as a JSON string
+func toOpenAIToolCalls(calls []providers.ToolCall) []providers.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	result := make([]providers.ToolCall, 0, len(calls))
+	for _, tc := range calls {
+		name := tc.Name
+		arguments := "{}"
+		if tc.Arguments != nil {
+			if data, err := json.Marshal(tc.Arguments); err == nil {
+				arguments = string(data)
+			}
+		}
+		if tc.Function != nil {
+			if name == "" {
+				name = tc.Function.Name
+			}
+			if tc.Arguments == nil && tc.Function.Arguments != "" {
+				arguments = tc.Function.Arguments
+			}
+		}
+		result = append(result, providers.ToolCall{
+			ID:       tc.ID,
+			Type:     "function",
+			Function: &providers.FunctionCall{Name: name, Arguments: arguments},
+		})
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestToolMode(t *testing.T) {
+	gs := &GatewayServer{config: config.DefaultConfig()}
+
+	r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	if got := gs.toolMode(r); got != toolModeAgent {
+		t.Errorf("default mode = %q, want %q", got, toolModeAgent)
+	}
+
+	gs.config.Gateway.ToolMode = "passthrough"
+	if got := gs.toolMode(r); got != toolModePassthrough {
+		t.Errorf("config mode = %q, want %q", got, toolModePassthrough)
+	}
+
+	r.Header.Set("X-Tool-Mode", "Agent")
+	if got := gs.toolMode(r); got != toolModeAgent {
+		t.Errorf("header mode = %q, want %q", got, toolModeAgent)
+	}
+}
+
+func TestToOpenAIToolCalls(t *testing.T) {
+	calls := toOpenAIToolCalls([]providers.ToolCall{
+		{ID: "call_1", Name: "get_weather", Arguments: map[string]interface{}{"location": "Jakarta"}},
+		{ID: "call_2", Name: "ping"},
+	})
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[0].Type != "function" || calls[0].Function.Name != "get_weather" || calls[0].Function.Arguments != `{"location":"Jakarta"}` {
+		t.Errorf("unexpected call: %+v %+v", calls[0], calls[0].Function)
+	}
+	if calls[1].Function.Arguments != "{}" {
+		t.Errorf("expected empty arguments object, got %q", calls[1].Function.Arguments)
+	}
+	if calls[0].Name != "" || calls[0].Arguments != nil {
+		t.Errorf("parsed fields should not be sent to clients: %+v", calls[0])
+	}
+}
+
+func TestToProviderContent(t *testing.T) {
+	if got := toProviderContent(nil); got != "" {
+		t.Errorf("nil content = %v, want empty string", got)
+	}
+	blocks, ok := toProviderContent([]interface{}{
+		map[string]interface{}{"type": "text", "text": "look"},
+		map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+	}).([]providers.ContentBlock)
+	if !ok || len(blocks) != 2 || blocks[1].ImageURL == nil || blocks[1].ImageURL.URL != "https://example.com/a.png" {
+		t.Errorf("unexpected blocks: %+v", blocks)
+	}
+}
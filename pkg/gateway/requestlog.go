@@ -0,0 +1,320 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/middleware"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/tools"
+)
+
+// RequestLogFile is the request log in the pepebot home directory
+const RequestLogFile = "requests.jsonl"
+
+// RequestLogEntry is a logged /v1/chat/completions request and the reply
+type RequestLogEntry struct {
+	ID         string                `json:"id"`
+	Time       time.Time             `json:"time"`
+	Agent      string                `json:"agent"`
+	Session    string                `json:"session"`
+	ToolMode   string                `json:"tool_mode"`
+	Request    ChatCompletionRequest `json:"request"`
+	Response   string                `json:"response,omitempty"`
+	ToolCalls  []providers.ToolCall  `json:"tool_calls,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	DurationMS int64                 `json:"duration_ms"`
+}
+
+// requestLog appends entries to the request log. Text is redacted and
+// inline files are left out before anything is written.
+type requestLog struct {
+	path     string
+	redactor *middleware.Redactor // nil logs text as is
+	mu       sync.Mutex
+}
+
+func newRequestLog(path string, redact []string) *requestLog {
+	l := &requestLog{path: path}
+	if len(redact) != 1 || redact[0] != "none" {
+		l.redactor = middleware.NewRedactor(redact)
+	}
+	return l
+}
+
+type requestLogKey struct{}
+
+// pendingRequest is a request whose reply is not logged yet
+type pendingRequest struct {
+	entry RequestLogEntry
+	start time.Time
+}
+
+// startRequestLog begins the log entry of a request and returns the request
+// carrying it. The entry's ID is sent in the X-Request-Id header.
+func (gs *GatewayServer) startRequestLog(w http.ResponseWriter, r *http.Request, req *ChatCompletionRequest, agentName, sessionKey, toolMode string) *http.Request {
+	if gs.requestLog == nil {
+		return r
+	}
+	now := time.Now()
+	pending := &pendingRequest{
+		entry: RequestLogEntry{
+			ID:       fmt.Sprintf("req-%d", now.UnixNano()),
+			Time:     now,
+			Agent:    agentName,
+			Session:  sessionKey,
+			ToolMode: toolMode,
+			Request:  *req,
+		},
+		start: now,
+	}
+	w.Header().Set("X-Request-Id", pending.entry.ID)
+	return r.WithContext(context.WithValue(r.Context(), requestLogKey{}, pending))
+}
+
+// finishRequestLog writes the entry of a request with its reply
+func (gs *GatewayServer) finishRequestLog(r *http.Request, content string, toolCalls []providers.ToolCall, err error) {
+	pending, ok := r.Context().Value(requestLogKey{}).(*pendingRequest)
+	if !ok || gs.requestLog == nil {
+		return
+	}
+	e := pending.entry
+	e.Response = content
+	e.ToolCalls = toolCalls
+	if err != nil {
+		e.Error = err.Error()
+	}
+	e.DurationMS = time.Since(pending.start).Milliseconds()
+	if err := gs.requestLog.record(e); err != nil {
+		logger.WarnCF("gateway", "Failed to write request log", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// Prompt returns the text of the request's last message
+func (e *RequestLogEntry) Prompt() string {
+	if len(e.Request.Messages) == 0 {
+		return ""
+	}
+	text, _ := parseMessageContent(e.Request.Messages[len(e.Request.Messages)-1])
+	return text
+}
+
+func (l *requestLog) record(e RequestLogEntry) error {
+	e.Request.Messages = l.scrubMessages(e.Request.Messages)
+	e.Response = l.redact(e.Response)
+	e.Error = l.redact(e.Error)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (l *requestLog) redact(s string) string {
+	if l.redactor == nil || s == "" {
+		return s
+	}
+	return l.redactor.Redact(s)
+}
+
+// scrubMessages returns copies of messages with their text redacted and
+// inline data URLs replaced by a note of their size
+func (l *requestLog) scrubMessages(messages []ChatMessage) []ChatMessage {
+	out := make([]ChatMessage, len(messages))
+	for i, m := range messages {
+		out[i] = m
+		switch content := m.Content.(type) {
+		case string:
+			out[i].Content = l.redact(content)
+		case []interface{}:
+			blocks := make([]interface{}, len(content))
+			for j, item := range content {
+				block, ok := item.(map[string]interface{})
+				if !ok {
+					blocks[j] = item
+					continue
+				}
+				blocks[j] = l.scrubBlock(block)
+			}
+			out[i].Content = blocks
+		}
+	}
+	return out
+}
+
+func (l *requestLog) scrubBlock(block map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(block))
+	for k, v := range block {
+		out[k] = v
+	}
+	if text, ok := out["text"].(string); ok {
+		out["text"] = l.redact(text)
+	}
+	for _, field := range []string{"image_url", "file"} {
+		inner, ok := out[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		copied := make(map[string]interface{}, len(inner))
+		for k, v := range inner {
+			if s, ok := v.(string); ok && strings.HasPrefix(s, "data:") {
+				v = fmt.Sprintf("[inline data omitted, %d bytes]", len(s))
+			}
+			copied[k] = v
+		}
+		out[field] = copied
+	}
+	return out
+}
+
+// ReadRequestLog returns the entries of the request log at path, oldest
+// first. A missing log has no entries; unreadable lines are skipped.
+func ReadRequestLog(path string) ([]RequestLogEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []RequestLogEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var e RequestLogEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.ID != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// FindRequest returns the logged request with an ID, or the newest one for
+// "last"
+func FindRequest(path, id string) (*RequestLogEntry, error) {
+	entries, err := ReadRequestLog(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == id || (id == "last" && i == len(entries)-1) {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("request %s not found in %s", id, path)
+}
+
+// ReplayResult is the reply to a replayed request
+type ReplayResult struct {
+	Agent      string               `json:"agent"`
+	Model      string               `json:"model"`
+	Response   string               `json:"response,omitempty"`
+	ToolCalls  []providers.ToolCall `json:"tool_calls,omitempty"`
+	DurationMS int64                `json:"duration_ms"`
+}
+
+// Replay runs a logged request again on an agent, by default the one that
+// answered it, optionally with another model. Agent mode requests run in a
+// new session seeded with the request's earlier messages, which is deleted
+// afterwards; passthrough requests go to the model as they were. Unless live
+// is set, only read-only tools run, so a replay doesn't run commands, write
+// files or send messages a second time.
+func Replay(ctx context.Context, am *agent.AgentManager, entry *RequestLogEntry, agentName, model string, live bool) (*ReplayResult, error) {
+	if agentName == "" {
+		agentName = entry.Agent
+	}
+	agentLoop, err := am.ReplayAgent(agentName, model)
+	if err != nil {
+		return nil, err
+	}
+	req := entry.Request
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("request %s has no messages", entry.ID)
+	}
+	if req.ResponseFormat != nil {
+		ctx = providers.WithResponseFormat(ctx, req.ResponseFormat)
+	}
+	if sampling := req.sampling(); !sampling.IsEmpty() {
+		ctx = providers.WithSampling(ctx, sampling)
+	}
+
+	if !live {
+		ctx = tools.WithReadOnly(ctx)
+	}
+
+	result := &ReplayResult{Agent: agentName, Model: agentLoop.Model()}
+	start := time.Now()
+	if entry.ToolMode == toolModePassthrough {
+		messages := make([]providers.Message, 0, len(req.Messages))
+		for _, m := range req.Messages {
+			messages = append(messages, providers.Message{
+				Role:       m.Role,
+				Content:    toProviderContent(m.Content),
+				ToolCalls:  m.ToolCalls,
+				ToolCallID: m.ToolCallID,
+			})
+		}
+		options := map[string]interface{}{}
+		if req.MaxTokens != nil {
+			options["max_tokens"] = *req.MaxTokens
+		}
+		if req.ToolChoice != nil {
+			options["tool_choice"] = req.ToolChoice
+		}
+		resp, err := agentLoop.Passthrough(ctx, messages, req.Tools, options)
+		if err != nil {
+			return nil, err
+		}
+		result.Response, result.ToolCalls = resp.Content, resp.ToolCalls
+	} else {
+		sessionKey := "replay:" + entry.ID
+		sessions := agentLoop.Sessions()
+		defer sessions.DeleteSession(sessionKey)
+		for _, m := range req.Messages[:len(req.Messages)-1] {
+			if text, _ := parseMessageContent(m); text != "" && (m.Role == "user" || m.Role == "assistant") {
+				sessions.AddMessage(sessionKey, m.Role, text)
+			}
+		}
+		text, media := parseMessageContent(req.Messages[len(req.Messages)-1])
+		var kept []string
+		for _, m := range media {
+			if !strings.HasPrefix(m, "[inline data omitted") {
+				kept = append(kept, m)
+			}
+		}
+		result.Response, err = agentLoop.ProcessDirect(ctx, text, kept, sessionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result, nil
+}
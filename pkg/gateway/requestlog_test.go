@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func TestRequestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), RequestLogFile)
+	gs := &GatewayServer{config: config.DefaultConfig(), requestLog: newRequestLog(path, nil)}
+
+	req := &ChatCompletionRequest{
+		Model: "default",
+		Messages: []ChatMessage{
+			{Role: "user", Content: "Mail me at budi@example.com"},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "What is this?"},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,AAAA"}},
+			}},
+		},
+	}
+	w := httptest.NewRecorder()
+	r := gs.startRequestLog(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), req, "default", "api:1", toolModeAgent)
+	id := w.Header().Get("X-Request-Id")
+	if id == "" {
+		t.Fatal("missing X-Request-Id header")
+	}
+	gs.finishRequestLog(r, "A frog.", nil, nil)
+
+	w = httptest.NewRecorder()
+	r = gs.startRequestLog(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), req, "default", "api:1", toolModeAgent)
+	gs.finishRequestLog(r, "", nil, errors.New("provider down"))
+
+	entries, err := ReadRequestLog(path)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("entries = %d, err = %v", len(entries), err)
+	}
+	e := entries[0]
+	if e.ID != id || e.Agent != "default" || e.Session != "api:1" || e.Response != "A frog." || e.Prompt() != "What is this?" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if text := e.Request.Messages[0].Content.(string); strings.Contains(text, "budi@example.com") {
+		t.Errorf("email not redacted: %q", text)
+	}
+	image := e.Request.Messages[1].Content.([]interface{})[1].(map[string]interface{})["image_url"].(map[string]interface{})
+	if url := image["url"].(string); !strings.HasPrefix(url, "[inline data omitted") {
+		t.Errorf("inline image logged: %q", url)
+	}
+	if req.Messages[0].Content.(string) != "Mail me at budi@example.com" {
+		t.Error("the request itself should not be redacted")
+	}
+
+	last, err := FindRequest(path, "last")
+	if err != nil || last.Error != "provider down" {
+		t.Errorf("last = %+v, err = %v", last, err)
+	}
+	if _, err := FindRequest(path, "req-0"); err == nil {
+		t.Error("expected an error for an unknown request")
+	}
+}
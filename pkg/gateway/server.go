@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/broadcast"
 	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/channels"
 	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/cron"
 	"github.com/pepebot-space/pepebot/pkg/live"
 	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/voice"
 )
 
 // GatewayServer is the HTTP API server for OpenAI-compatible endpoints
@@ -21,6 +28,16 @@ type GatewayServer struct {
 	httpServer   *http.Server
 	liveServer   *live.LiveServer
 	restartFunc  func() // called to trigger graceful restart
+	broadcaster  *broadcast.Service
+	outbox       *bus.Outbox
+	embedMu      sync.Mutex
+	embedders    map[string]providers.EmbeddingProvider // by model
+	transcriber  voice.Transcriber
+	cron         *cron.CronService
+	channels     *channels.Manager
+	version      string
+	startedAt    time.Time
+	requestLog   *requestLog // nil unless gateway.request_log is enabled
 }
 
 // SetRestartFunc sets the function called when a restart is requested via API or chat command
@@ -28,12 +45,46 @@ func (gs *GatewayServer) SetRestartFunc(fn func()) {
 	gs.restartFunc = fn
 }
 
+// SetBroadcaster enables the /v1/broadcast endpoint
+func (gs *GatewayServer) SetBroadcaster(b *broadcast.Service) {
+	gs.broadcaster = b
+}
+
+// SetTranscriber enables the /v1/audio/transcriptions endpoint
+func (gs *GatewayServer) SetTranscriber(t voice.Transcriber) {
+	gs.transcriber = t
+}
+
+// SetCron enables the /v1/cron job management endpoints
+func (gs *GatewayServer) SetCron(cs *cron.CronService) {
+	gs.cron = cs
+}
+
+// SetChannels adds channel state to /v1/status
+func (gs *GatewayServer) SetChannels(cm *channels.Manager) {
+	gs.channels = cm
+}
+
+// SetVersion sets the version reported by /v1/status
+func (gs *GatewayServer) SetVersion(version string) {
+	gs.version = version
+}
+
+// SetOutbox enables the /v1/outbox inspection endpoints
+func (gs *GatewayServer) SetOutbox(outbox *bus.Outbox) {
+	gs.outbox = outbox
+}
+
 // NewGatewayServer creates a new gateway HTTP server
 func NewGatewayServer(cfg *config.Config, agentManager *agent.AgentManager, msgBus *bus.MessageBus) *GatewayServer {
 	gs := &GatewayServer{
 		config:       cfg,
 		agentManager: agentManager,
 		bus:          msgBus,
+		startedAt:    time.Now(),
+	}
+	if cfg.Gateway.RequestLog.Enabled {
+		gs.requestLog = newRequestLog(filepath.Join(filepath.Dir(cfg.WorkspacePath()), RequestLogFile), cfg.Gateway.RequestLog.Redact)
 	}
 
 	// Initialize Live API server if enabled
@@ -96,8 +147,11 @@ func (gs *GatewayServer) Start(ctx context.Context) error {
 
 	// Register routes
 	mux.HandleFunc("/health", gs.corsMiddleware(gs.handleHealth))
+	mux.HandleFunc("/v1/status", gs.corsMiddleware(gs.handleStatus))
 	mux.HandleFunc("/v1/chat/completions", gs.corsMiddleware(gs.handleChatCompletions))
 	mux.HandleFunc("/v1/models", gs.corsMiddleware(gs.handleListModels))
+	mux.HandleFunc("/v1/embeddings", gs.corsMiddleware(gs.handleEmbeddings))
+	mux.HandleFunc("/v1/audio/transcriptions", gs.corsMiddleware(gs.handleTranscriptions))
 	mux.HandleFunc("/v1/sessions", gs.corsMiddleware(gs.handleListSessions))
 	mux.HandleFunc("/v1/sessions/", gs.corsMiddleware(gs.handleSessionRoutes))
 	mux.HandleFunc("/v1/agents", gs.corsMiddleware(gs.handleListAgents))
@@ -107,7 +161,14 @@ func (gs *GatewayServer) Start(ctx context.Context) error {
 	mux.HandleFunc("/v1/workflows/", gs.corsMiddleware(gs.handleGetWorkflow))
 	mux.HandleFunc("/v1/config", gs.corsMiddleware(gs.handleConfig))
 	mux.HandleFunc("/v1/restart", gs.corsMiddleware(gs.handleRestart))
+	mux.HandleFunc("/v1/maintenance", gs.corsMiddleware(gs.handleMaintenance))
 	mux.HandleFunc("/v1/send", gs.corsMiddleware(gs.handleSend))
+	mux.HandleFunc("/v1/broadcast", gs.corsMiddleware(gs.handleBroadcast))
+	mux.HandleFunc("/v1/outbox", gs.corsMiddleware(gs.handleListOutbox))
+	mux.HandleFunc("/v1/outbox/", gs.corsMiddleware(gs.handleOutboxRoutes))
+	mux.HandleFunc("/v1/cron", gs.corsMiddleware(gs.handleCron))
+	mux.HandleFunc("/v1/cron/", gs.corsMiddleware(gs.handleCronRoutes))
+	mux.HandleFunc("/v1/hooks/", gs.handleHook)
 
 	// Live API WebSocket endpoint
 	if gs.liveServer != nil {
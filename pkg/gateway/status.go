@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/agent"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// recentErrorLimit is the number of log errors included in /v1/status
+const recentErrorLimit = 5
+
+// handleStatus reports what the running gateway is doing: uptime, active
+// sessions, channels, queue depths, recent errors and today's token usage.
+// `pepebot status` reads it.
+func (gs *GatewayServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "invalid_request_error")
+		return
+	}
+
+	status := map[string]interface{}{
+		"status":     "ok",
+		"version":    gs.version,
+		"started_at": gs.startedAt.UTC().Format(time.RFC3339),
+		"uptime_s":   int64(time.Since(gs.startedAt).Seconds()),
+		"model":      gs.config.Agents.Defaults.Model,
+		"errors":     logger.RecentErrors(recentErrorLimit),
+		"usage":      agent.UsageToday(),
+	}
+
+	queues := map[string]interface{}{}
+	if gs.bus != nil {
+		inbound, outbound := gs.bus.QueueDepths()
+		queues["inbound"] = inbound
+		queues["outbound"] = outbound
+	}
+	if gs.agentManager != nil {
+		active := gs.agentManager.ActiveSessions()
+		if active == nil {
+			active = []string{}
+		}
+		status["sessions"] = map[string]interface{}{
+			"active": active,
+		}
+		queues["processing"] = gs.agentManager.PendingMessages()
+		status["maintenance"] = gs.agentManager.Maintenance()
+	}
+	if gs.outbox != nil {
+		queues["outbox"] = gs.outbox.Stats()
+	}
+	status["queues"] = queues
+
+	if gs.channels != nil {
+		status["channels"] = gs.channels.GetStatus()
+	}
+	if gs.cron != nil {
+		status["cron"] = gs.cron.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+func TestHandleStatus(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "hi"})
+	logger.ErrorCF("test", "Something broke", map[string]interface{}{"error": "boom"})
+
+	gs := &GatewayServer{
+		config:    config.DefaultConfig(),
+		bus:       msgBus,
+		version:   "1.2.3",
+		startedAt: time.Now().Add(-90 * time.Second),
+	}
+	rec := httptest.NewRecorder()
+	gs.handleStatus(rec, httptest.NewRequest("GET", "/v1/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var status struct {
+		Version string `json:"version"`
+		UptimeS int64  `json:"uptime_s"`
+		Queues  struct {
+			Outbound int `json:"outbound"`
+		} `json:"queues"`
+		Errors []logger.LogEntry `json:"errors"`
+		Usage  struct {
+			Date string `json:"date"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != "1.2.3" || status.UptimeS < 90 || status.Queues.Outbound != 1 || status.Usage.Date == "" {
+		t.Errorf("unexpected status: %s", rec.Body.String())
+	}
+	if len(status.Errors) == 0 || status.Errors[0].Message != "Something broke" {
+		t.Errorf("recent error missing: %+v", status.Errors)
+	}
+}
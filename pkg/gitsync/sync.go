@@ -0,0 +1,300 @@
+// Package gitsync keeps the workspace in a git repository: changes to
+// memory, skills and workflows are committed automatically and, when a
+// remote is configured, pulled and pushed on a schedule.
+package gitsync
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+const remoteName = "origin"
+
+// Result describes one sync run
+type Result struct {
+	Committed bool
+	Message   string // commit subject, when something was committed
+	Pulled    bool
+	Pushed    bool
+}
+
+// Service commits and syncs workspace changes
+type Service struct {
+	cfg       config.SyncConfig
+	workspace string
+	mu        sync.Mutex
+	stopChan  chan struct{}
+}
+
+func NewService(cfg config.SyncConfig, workspace string) *Service {
+	if cfg.Branch == "" {
+		cfg.Branch = "main"
+	}
+	if len(cfg.Paths) == 0 {
+		cfg.Paths = []string{"memory", "skills", "workflows"}
+	}
+	if cfg.AuthorName == "" {
+		cfg.AuthorName = "Pepebot"
+	}
+	if cfg.AuthorEmail == "" {
+		cfg.AuthorEmail = "pepebot@localhost"
+	}
+	return &Service{cfg: cfg, workspace: workspace}
+}
+
+func (s *Service) Start() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is not installed")
+	}
+	s.stopChan = make(chan struct{})
+	go s.runLoop()
+	return nil
+}
+
+func (s *Service) Stop() {
+	if s.stopChan != nil {
+		close(s.stopChan)
+		s.stopChan = nil
+	}
+}
+
+func (s *Service) runLoop() {
+	interval := time.Duration(s.cfg.IntervalS) * time.Second
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stop := s.stopChan
+	s.runAndLog()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.runAndLog()
+		}
+	}
+}
+
+func (s *Service) runAndLog() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result, err := s.Run(ctx)
+	if err != nil {
+		logger.ErrorCF("sync", "Workspace sync failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if result.Committed || result.Pushed {
+		logger.InfoCF("sync", "Workspace synced", map[string]interface{}{
+			"commit": result.Message,
+			"pushed": result.Pushed,
+		})
+	}
+}
+
+// Run commits changes under the configured paths, then pulls and pushes
+// when a remote is configured
+func (s *Service) Run(ctx context.Context) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureRepo(ctx); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	message, err := s.commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Message = message
+	result.Committed = message != ""
+
+	if s.cfg.Remote == "" {
+		return result, nil
+	}
+
+	if _, err := s.git(ctx, "pull", "--rebase", remoteName, s.cfg.Branch); err != nil {
+		// A new remote has no branch to pull yet
+		if !strings.Contains(err.Error(), "couldn't find remote ref") {
+			s.git(ctx, "rebase", "--abort")
+			return result, err
+		}
+	} else {
+		result.Pulled = true
+	}
+
+	if _, err := s.git(ctx, "push", remoteName, "HEAD:refs/heads/"+s.cfg.Branch); err != nil {
+		return result, err
+	}
+	result.Pushed = true
+	return result, nil
+}
+
+// ensureRepo initializes the workspace repository and points origin at the
+// configured remote. A workspace inside another repository gets its own.
+func (s *Service) ensureRepo(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.workspace, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(s.workspace, 0755); err != nil {
+			return err
+		}
+		if _, err := s.git(ctx, "init"); err != nil {
+			return err
+		}
+		if _, err := s.git(ctx, "symbolic-ref", "HEAD", "refs/heads/"+s.cfg.Branch); err != nil {
+			return err
+		}
+		logger.InfoCF("sync", "Initialized workspace repository", map[string]interface{}{
+			"workspace": s.workspace,
+		})
+	}
+
+	if s.cfg.Remote == "" {
+		return nil
+	}
+	current, err := s.git(ctx, "remote", "get-url", remoteName)
+	switch {
+	case err != nil:
+		_, err = s.git(ctx, "remote", "add", remoteName, s.cfg.Remote)
+	case current != s.cfg.Remote:
+		_, err = s.git(ctx, "remote", "set-url", remoteName, s.cfg.Remote)
+	}
+	return err
+}
+
+// commit stages the configured paths and commits them with a message that
+// names what changed. It returns the subject, or "" when nothing changed.
+func (s *Service) commit(ctx context.Context) (string, error) {
+	var paths []string
+	for _, p := range s.cfg.Paths {
+		if _, err := os.Stat(filepath.Join(s.workspace, p)); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return "", nil
+	}
+
+	if _, err := s.git(ctx, append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return "", err
+	}
+	status, err := s.git(ctx, append([]string{"diff", "--cached", "--name-status", "--no-renames", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	changes := parseNameStatus(status)
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	subject, body := commitMessage(changes)
+	args := []string{"commit", "-q", "-m", subject, "-m", body, "--"}
+	if _, err := s.git(ctx, append(args, paths...)...); err != nil {
+		return "", err
+	}
+	return subject, nil
+}
+
+// change is one file in a commit
+type change struct {
+	action string // "Add", "Update" or "Delete"
+	path   string
+}
+
+func parseNameStatus(output string) []change {
+	var changes []change
+	for _, line := range strings.Split(output, "\n") {
+		status, path, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		action := "Update"
+		switch status {
+		case "A":
+			action = "Add"
+		case "D":
+			action = "Delete"
+		}
+		changes = append(changes, change{action: action, path: path})
+	}
+	return changes
+}
+
+// commitMessage names the files when there are few, otherwise counts them
+// per top-level directory. The body lists every file.
+func commitMessage(changes []change) (string, string) {
+	var body strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&body, "- %s %s\n", strings.ToLower(c.action), c.path)
+	}
+
+	if len(changes) <= 3 {
+		parts := make([]string, len(changes))
+		for i, c := range changes {
+			action := c.action
+			if i > 0 {
+				action = strings.ToLower(action)
+			}
+			parts[i] = action + " " + c.path
+		}
+		return strings.Join(parts, ", "), strings.TrimSpace(body.String())
+	}
+
+	counts := make(map[string]int)
+	for _, c := range changes {
+		dir, _, _ := strings.Cut(c.path, "/")
+		counts[dir]++
+	}
+	dirs := make([]string, 0, len(counts))
+	for dir := range counts {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	parts := make([]string, len(dirs))
+	for i, dir := range dirs {
+		parts[i] = fmt.Sprintf("%d in %s", counts[dir], dir)
+	}
+	return fmt.Sprintf("Update %d files (%s)", len(changes), strings.Join(parts, ", ")), strings.TrimSpace(body.String())
+}
+
+// git runs a git command in the workspace. Commits use the configured
+// identity, and the token (if any) is sent as HTTP basic auth.
+func (s *Service) git(ctx context.Context, args ...string) (string, error) {
+	subcommand := args[0]
+	prefix := []string{
+		"-c", "user.name=" + s.cfg.AuthorName,
+		"-c", "user.email=" + s.cfg.AuthorEmail,
+	}
+	if s.cfg.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + s.cfg.Token))
+		prefix = append(prefix, "-c", "http.extraHeader=Authorization: Basic "+auth)
+	}
+	cmd := exec.CommandContext(ctx, "git", append(prefix, args...)...)
+	cmd.Dir = s.workspace
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if s.cfg.Token != "" {
+			msg = strings.ReplaceAll(msg, s.cfg.Token, "***")
+		}
+		return "", fmt.Errorf("git %s failed: %s", subcommand, msg)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
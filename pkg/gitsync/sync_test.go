@@ -0,0 +1,74 @@
+package gitsync
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func TestRunCommitsAndPushes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("init remote: %s", out)
+	}
+
+	workspace := filepath.Join(root, "workspace")
+	os.MkdirAll(filepath.Join(workspace, "memory"), 0755)
+	os.WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte("# Memory\n"), 0644)
+	os.WriteFile(filepath.Join(workspace, "scratch.txt"), []byte("not synced"), 0644)
+
+	s := NewService(config.SyncConfig{Remote: remote}, workspace)
+	result, err := s.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed || result.Message != "Add memory/MEMORY.md" || !result.Pushed {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// Nothing changed, nothing to commit
+	if result, err = s.Run(ctx); err != nil || result.Committed {
+		t.Fatalf("second run: %+v, %v", result, err)
+	}
+
+	os.WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte("# Memory\n- fact\n"), 0644)
+	if result, err = s.Run(ctx); err != nil || result.Message != "Update memory/MEMORY.md" {
+		t.Fatalf("third run: %+v, %v", result, err)
+	}
+
+	files, _ := exec.Command("git", "--git-dir", remote, "ls-tree", "-r", "--name-only", "main").Output()
+	if got := strings.TrimSpace(string(files)); got != "memory/MEMORY.md" {
+		t.Errorf("remote has %q, want only memory/MEMORY.md", got)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	changes := []change{
+		{"Add", "memory/daily/2026-03-02.md"},
+		{"Update", "memory/MEMORY.md"},
+		{"Add", "skills/weather/SKILL.md"},
+		{"Delete", "workflows/old.json"},
+	}
+	subject, body := commitMessage(changes)
+	if subject != "Update 4 files (2 in memory, 1 in skills, 1 in workflows)" {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(body, "- delete workflows/old.json") {
+		t.Errorf("body = %q", body)
+	}
+
+	subject, _ = commitMessage(changes[:2])
+	if subject != "Add memory/daily/2026-03-02.md, update memory/MEMORY.md" {
+		t.Errorf("subject = %q", subject)
+	}
+}
@@ -0,0 +1,284 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// Check is a single heartbeat probe. Implementations should respect ctx and
+// return quickly; a failing result is routed as an alert.
+type Check interface {
+	Name() string
+	Run(ctx context.Context) CheckResult
+}
+
+// CheckResult is the outcome of one check run
+type CheckResult struct {
+	OK      bool      `json:"ok"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// CheckFactory builds a check from its config entry
+type CheckFactory func(spec config.HeartbeatCheckConfig, cfg *config.Config) (Check, error)
+
+var (
+	checkFactories = map[string]CheckFactory{
+		"disk":        newDiskCheck,
+		"adb_battery": newADBBatteryCheck,
+		"gateway":     newGatewayCheck,
+		"provider":    newProviderCheck,
+	}
+	factoriesMu sync.RWMutex
+)
+
+// RegisterCheck adds a check type that can be referenced from heartbeat.checks
+func RegisterCheck(kind string, factory CheckFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	checkFactories[kind] = factory
+}
+
+// BuildChecks creates the checks configured in heartbeat.checks. Invalid
+// entries are skipped and reported in the returned errors.
+func BuildChecks(cfg *config.Config) ([]Check, []error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	checks := []Check{}
+	errs := []error{}
+	for i, spec := range cfg.Heartbeat.Checks {
+		factory, ok := checkFactories[spec.Type]
+		if !ok {
+			errs = append(errs, fmt.Errorf("check %d: unknown type '%s'", i+1, spec.Type))
+			continue
+		}
+		check, err := factory(spec, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("check %d (%s): %w", i+1, spec.Type, err))
+			continue
+		}
+		checks = append(checks, check)
+	}
+	return checks, errs
+}
+
+// NewCheck builds a single check from a spec, outside heartbeat.checks
+func NewCheck(spec config.HeartbeatCheckConfig, cfg *config.Config) (Check, error) {
+	factoriesMu.RLock()
+	factory, ok := checkFactories[spec.Type]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown check type '%s'", spec.Type)
+	}
+	return factory(spec, cfg)
+}
+
+// checkName returns the configured name or a default
+func checkName(spec config.HeartbeatCheckConfig, fallback string) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	return fallback
+}
+
+func paramString(spec config.HeartbeatCheckConfig, key, fallback string) string {
+	if v, ok := spec.Params[key]; ok {
+		if s := fmt.Sprintf("%v", v); s != "" {
+			return s
+		}
+	}
+	return fallback
+}
+
+func paramFloat(spec config.HeartbeatCheckConfig, key string, fallback float64) float64 {
+	switch v := spec.Params[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+func result(ok bool, format string, args ...interface{}) CheckResult {
+	return CheckResult{OK: ok, Message: fmt.Sprintf(format, args...), Time: time.Now()}
+}
+
+// ==================== Disk Check ====================
+
+// diskCheck fails when free space on a path drops below a percentage
+type diskCheck struct {
+	name           string
+	path           string
+	minFreePercent float64
+}
+
+func newDiskCheck(spec config.HeartbeatCheckConfig, cfg *config.Config) (Check, error) {
+	path := paramString(spec, "path", cfg.WorkspacePath())
+	return &diskCheck{
+		name:           checkName(spec, "disk "+filepath.Base(path)),
+		path:           path,
+		minFreePercent: paramFloat(spec, "min_free_percent", 10),
+	}, nil
+}
+
+func (c *diskCheck) Name() string { return c.name }
+
+func (c *diskCheck) Run(ctx context.Context) CheckResult {
+	free, total, err := diskUsage(c.path)
+	if err != nil {
+		return result(false, "cannot read disk usage of %s: %v", c.path, err)
+	}
+	if total == 0 {
+		return result(true, "%s: size unknown", c.path)
+	}
+
+	percent := float64(free) / float64(total) * 100
+	if percent < c.minFreePercent {
+		return result(false, "%s has %.1f%% free (%s), below %.0f%%", c.path, percent, formatBytes(free), c.minFreePercent)
+	}
+	return result(true, "%s has %.1f%% free (%s)", c.path, percent, formatBytes(free))
+}
+
+func formatBytes(n uint64) string {
+	const gb = 1 << 30
+	const mb = 1 << 20
+	if n >= gb {
+		return fmt.Sprintf("%.1f GB", float64(n)/gb)
+	}
+	return fmt.Sprintf("%.0f MB", float64(n)/mb)
+}
+
+// ==================== ADB Battery Check ====================
+
+// adbBatteryCheck fails when an Android device's battery drops below a level
+// or the device is unreachable
+type adbBatteryCheck struct {
+	name     string
+	device   string
+	minLevel float64
+}
+
+func newADBBatteryCheck(spec config.HeartbeatCheckConfig, cfg *config.Config) (Check, error) {
+	device := paramString(spec, "device", "")
+	name := "battery"
+	if device != "" {
+		name = "battery " + device
+	}
+	return &adbBatteryCheck{
+		name:     checkName(spec, name),
+		device:   device,
+		minLevel: paramFloat(spec, "min_level", 20),
+	}, nil
+}
+
+func (c *adbBatteryCheck) Name() string { return c.name }
+
+var batteryLevelPattern = regexp.MustCompile(`(?m)^\s*level:\s*(\d+)`)
+var batteryPoweredPattern = regexp.MustCompile(`(?m)^\s*(AC|USB|Wireless) powered:\s*true`)
+
+func (c *adbBatteryCheck) Run(ctx context.Context) CheckResult {
+	args := []string{}
+	if c.device != "" {
+		args = append(args, "-s", c.device)
+	}
+	args = append(args, "shell", "dumpsys", "battery")
+
+	out, err := exec.CommandContext(ctx, adbBinary(), args...).CombinedOutput()
+	if err != nil {
+		return result(false, "device unreachable: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	match := batteryLevelPattern.FindSubmatch(out)
+	if match == nil {
+		return result(false, "could not read battery level")
+	}
+	level, _ := strconv.Atoi(string(match[1]))
+	charging := batteryPoweredPattern.Match(out)
+
+	if float64(level) < c.minLevel && !charging {
+		return result(false, "battery at %d%%, below %.0f%% and not charging", level, c.minLevel)
+	}
+	state := "discharging"
+	if charging {
+		state = "charging"
+	}
+	return result(true, "battery at %d%% (%s)", level, state)
+}
+
+// adbBinary prefers ANDROID_HOME/platform-tools/adb, like the adb tools
+func adbBinary() string {
+	if androidHome := os.Getenv("ANDROID_HOME"); androidHome != "" {
+		path := filepath.Join(androidHome, "platform-tools", "adb")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return "adb"
+}
+
+// ==================== HTTP Checks ====================
+
+// httpCheck fails when a URL cannot be reached. With wantOK set, any
+// non-2xx status also fails; otherwise any HTTP response counts as reachable
+// (provider APIs answer 401/404 without credentials).
+type httpCheck struct {
+	name   string
+	url    string
+	wantOK bool
+}
+
+func newGatewayCheck(spec config.HeartbeatCheckConfig, cfg *config.Config) (Check, error) {
+	host := cfg.Gateway.Host
+	if host == "" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	url := paramString(spec, "url", fmt.Sprintf("http://%s:%d/health", host, cfg.Gateway.Port))
+	return &httpCheck{name: checkName(spec, "gateway"), url: url, wantOK: true}, nil
+}
+
+func newProviderCheck(spec config.HeartbeatCheckConfig, cfg *config.Config) (Check, error) {
+	url := paramString(spec, "url", cfg.GetAPIBase())
+	if url == "" {
+		return nil, fmt.Errorf("url is required (no provider api_base configured)")
+	}
+	return &httpCheck{name: checkName(spec, "provider"), url: url}, nil
+}
+
+func (c *httpCheck) Name() string { return c.name }
+
+func (c *httpCheck) Run(ctx context.Context) CheckResult {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return result(false, "invalid url %s: %v", c.url, err)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return result(false, "%s unreachable: %v", c.url, err)
+	}
+	resp.Body.Close()
+	latency := time.Since(start).Milliseconds()
+
+	if c.wantOK && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return result(false, "%s returned HTTP %d", c.url, resp.StatusCode)
+	}
+	return result(true, "%s reachable (HTTP %d, %dms)", c.url, resp.StatusCode, latency)
+}
@@ -0,0 +1,103 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+type fakeCheck struct {
+	name string
+	ok   bool
+}
+
+func (c *fakeCheck) Name() string { return c.name }
+
+func (c *fakeCheck) Run(ctx context.Context) CheckResult {
+	if c.ok {
+		return result(true, "fine")
+	}
+	return result(false, "broken")
+}
+
+type recordingSender struct {
+	messages []string
+}
+
+func (s *recordingSender) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
+	s.messages = append(s.messages, content)
+	return nil
+}
+
+func TestRunChecksAlertsOnTransitions(t *testing.T) {
+	check := &fakeCheck{name: "fake", ok: true}
+	sender := &recordingSender{}
+
+	hs := NewHeartbeatService(t.TempDir(), nil, 60, true)
+	hs.SetChecks([]Check{check}, 0)
+	hs.SetAlertSink(sender, "telegram", "123")
+
+	hs.RunChecks(context.Background())
+	if len(sender.messages) != 0 {
+		t.Fatalf("passing check alerted: %v", sender.messages)
+	}
+
+	check.ok = false
+	hs.RunChecks(context.Background())
+	hs.RunChecks(context.Background())
+	if len(sender.messages) != 1 || !strings.Contains(sender.messages[0], "failed: fake") {
+		t.Fatalf("expected one failure alert, got %v", sender.messages)
+	}
+
+	check.ok = true
+	hs.RunChecks(context.Background())
+	if len(sender.messages) != 2 || !strings.Contains(sender.messages[1], "recovered: fake") {
+		t.Fatalf("expected recovery notice, got %v", sender.messages)
+	}
+
+	if res := hs.Results()["fake"]; !res.OK {
+		t.Fatalf("latest result not recorded: %+v", res)
+	}
+}
+
+func TestBuildChecks(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Heartbeat.Checks = []config.HeartbeatCheckConfig{
+		{Type: "disk", Params: map[string]interface{}{"path": t.TempDir()}},
+		{Type: "gateway", Name: "api"},
+		{Type: "nope"},
+	}
+
+	checks, errs := BuildChecks(cfg)
+	if len(checks) != 2 || len(errs) != 1 {
+		t.Fatalf("got %d checks, %d errors", len(checks), len(errs))
+	}
+	if checks[1].Name() != "api" {
+		t.Errorf("name = %q, want api", checks[1].Name())
+	}
+
+	if res := checks[0].Run(context.Background()); !res.OK && !strings.Contains(res.Message, "below") {
+		t.Errorf("disk check errored: %s", res.Message)
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	reachable := &httpCheck{name: "provider", url: srv.URL}
+	if res := reachable.Run(context.Background()); !res.OK {
+		t.Errorf("provider check should accept 401: %s", res.Message)
+	}
+
+	health := &httpCheck{name: "gateway", url: srv.URL, wantOK: true}
+	if res := health.Run(context.Background()); res.OK {
+		t.Errorf("gateway check should reject 401")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package heartbeat
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// diskUsage returns the free (available to the caller) and total bytes of the volume holding path
+func diskUsage(path string) (free, total uint64, err error) {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
+
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var freeAvailable, totalBytes, totalFree uint64
+	ret, _, callErr := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&freeAvailable)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFree)),
+	)
+	if ret == 0 {
+		return 0, 0, callErr
+	}
+	return freeAvailable, totalBytes, nil
+}
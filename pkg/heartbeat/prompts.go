@@ -0,0 +1,93 @@
+package heartbeat
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// NothingToReport is the reply an agent gives when a heartbeat prompt finds
+// nothing worth a message
+const NothingToReport = "HEARTBEAT_OK"
+
+// Prompt is a proactive check defined in HEARTBEAT.md
+type Prompt struct {
+	Title string
+	Text  string
+	Every time.Duration // zero runs it on every tick
+}
+
+// everyPattern matches an interval at the end of a heading, "(every 2h)"
+var everyPattern = regexp.MustCompile(`\s*\(every\s+([0-9hms]+)\)\s*$`)
+
+// ParsePrompts splits HEARTBEAT.md into prompts. Every "## " section is a
+// prompt named by its heading, which may end with an interval such as
+// "(every 2h)". Text before the first section is shared context added to
+// every prompt; without sections the whole file is one prompt. HTML
+// comments are ignored, so a file of only comments defines nothing.
+func ParsePrompts(markdown string) []Prompt {
+	markdown = stripComments(markdown)
+
+	var preamble []string
+	var prompts []Prompt
+	var current *Prompt
+	var body []string
+	flush := func() {
+		if current != nil {
+			current.Text = strings.TrimSpace(strings.Join(body, "\n"))
+			if current.Text != "" {
+				prompts = append(prompts, *current)
+			}
+		}
+		body = nil
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if title, ok := strings.CutPrefix(line, "## "); ok {
+			flush()
+			current = &Prompt{Title: strings.TrimSpace(title)}
+			if m := everyPattern.FindStringSubmatch(current.Title); m != nil {
+				if d, err := time.ParseDuration(m[1]); err == nil {
+					current.Every = d
+					current.Title = strings.TrimSpace(everyPattern.ReplaceAllString(current.Title, ""))
+				}
+			}
+			continue
+		}
+		if current == nil {
+			// The file's "# " title is not context
+			if !strings.HasPrefix(line, "# ") {
+				preamble = append(preamble, line)
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	shared := strings.TrimSpace(strings.Join(preamble, "\n"))
+	if len(prompts) == 0 {
+		if shared == "" {
+			return nil
+		}
+		return []Prompt{{Text: shared}}
+	}
+	if shared != "" {
+		for i := range prompts {
+			prompts[i].Text = shared + "\n\n" + prompts[i].Text
+		}
+	}
+	return prompts
+}
+
+var commentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+func stripComments(s string) string {
+	return commentPattern.ReplaceAllString(s, "")
+}
+
+// isNothingToReport reports whether a reply only says all is well
+func isNothingToReport(reply string) bool {
+	reply = strings.Trim(strings.TrimSpace(reply), "*`.")
+	return reply == "" || strings.EqualFold(reply, NothingToReport)
+}
@@ -0,0 +1,80 @@
+package heartbeat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleHeartbeat = `# Heartbeat
+
+I use a Pixel 8 (serial 38201FDJH00AB4).
+
+<!-- ## Disabled
+Not a prompt. -->
+
+## Phone notifications
+Any unread important notifications on the phone?
+
+## Battery (every 2h)
+Is the phone battery below 20%?
+
+## Empty
+`
+
+func TestParsePrompts(t *testing.T) {
+	prompts := ParsePrompts(sampleHeartbeat)
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 prompts, got %+v", prompts)
+	}
+	if prompts[0].Title != "Phone notifications" || prompts[0].Every != 0 {
+		t.Errorf("unexpected first prompt %+v", prompts[0])
+	}
+	if !strings.HasPrefix(prompts[0].Text, "I use a Pixel 8") || !strings.HasSuffix(prompts[0].Text, "on the phone?") {
+		t.Errorf("shared context missing: %q", prompts[0].Text)
+	}
+	if prompts[1].Title != "Battery" || prompts[1].Every != 2*time.Hour {
+		t.Errorf("unexpected second prompt %+v", prompts[1])
+	}
+
+	if p := ParsePrompts("Check my calendar for conflicts."); len(p) != 1 || p[0].Title != "" {
+		t.Errorf("expected the whole file as one prompt, got %+v", p)
+	}
+	if p := ParsePrompts("# Heartbeat\n<!-- add checks here -->\n"); p != nil {
+		t.Errorf("expected no prompts, got %+v", p)
+	}
+}
+
+func TestRunPromptsDeliversReports(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "memory"), 0755)
+	os.WriteFile(filepath.Join(workspace, "memory", "HEARTBEAT.md"), []byte(sampleHeartbeat), 0644)
+
+	var prompts []string
+	sender := &recordingSender{}
+	hs := NewHeartbeatService(workspace, nil, 60, true)
+	hs.SetProactive(func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		if strings.Contains(prompt, "Battery") {
+			return "Battery is at 12% and not charging.", nil
+		}
+		return NothingToReport, nil
+	}, sender, "telegram", "1")
+
+	hs.RunPrompts(context.Background())
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "# Heartbeat Check: Phone notifications") {
+		t.Fatalf("unexpected prompts %q", prompts)
+	}
+	if len(sender.messages) != 1 || sender.messages[0] != "💡 Battery\n\nBattery is at 12% and not charging." {
+		t.Fatalf("unexpected messages %q", sender.messages)
+	}
+
+	// The battery prompt only runs every 2h
+	hs.RunPrompts(context.Background())
+	if len(prompts) != 3 || len(sender.messages) != 1 {
+		t.Fatalf("expected only the notifications prompt to run again, got %d prompts, %d messages", len(prompts), len(sender.messages))
+	}
+}
@@ -1,13 +1,24 @@
 package heartbeat
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
 )
 
+// checkTimeout bounds a single check run
+const checkTimeout = 30 * time.Second
+
+// AlertSender delivers check alerts. Implemented by channels.Manager.
+type AlertSender interface {
+	SendToChannel(ctx context.Context, channelName, chatID, content string) error
+}
+
 type HeartbeatService struct {
 	workspace   string
 	onHeartbeat func(string) (string, error)
@@ -15,6 +26,18 @@ type HeartbeatService struct {
 	enabled     bool
 	mu          sync.RWMutex
 	stopChan    chan struct{}
+
+	checks        []Check
+	checkInterval time.Duration
+	results       map[string]CheckResult
+	alerts        AlertSender
+	alertChannel  string
+	alertChatID   string
+
+	delivery        AlertSender
+	deliveryChannel string
+	deliveryChatID  string
+	lastRun         map[string]time.Time
 }
 
 func NewHeartbeatService(workspace string, onHeartbeat func(string) (string, error), intervalS int, enabled bool) *HeartbeatService {
@@ -24,7 +47,51 @@ func NewHeartbeatService(workspace string, onHeartbeat func(string) (string, err
 		interval:    time.Duration(intervalS) * time.Second,
 		enabled:     enabled,
 		stopChan:    make(chan struct{}),
+		results:     make(map[string]CheckResult),
+		lastRun:     make(map[string]time.Time),
+	}
+}
+
+// SetProactive runs the HEARTBEAT.md prompts through handler on every tick
+// and sends replies to a channel chat, so the agent can bring things up on
+// its own. Replies that are only NothingToReport are not sent. Must be
+// called before Start.
+func (hs *HeartbeatService) SetProactive(handler func(string) (string, error), sender AlertSender, channel, chatID string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.onHeartbeat = handler
+	hs.delivery = sender
+	hs.deliveryChannel = channel
+	hs.deliveryChatID = chatID
+}
+
+// SetChecks registers the health checks to run every interval. Must be
+// called before Start.
+func (hs *HeartbeatService) SetChecks(checks []Check, interval time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.checks = checks
+	hs.checkInterval = interval
+}
+
+// SetAlertSink routes check failures and recoveries to a channel chat
+func (hs *HeartbeatService) SetAlertSink(sender AlertSender, channel, chatID string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.alerts = sender
+	hs.alertChannel = channel
+	hs.alertChatID = chatID
+}
+
+// Results returns the latest result of every check by name
+func (hs *HeartbeatService) Results() map[string]CheckResult {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	results := make(map[string]CheckResult, len(hs.results))
+	for name, r := range hs.results {
+		results[name] = r
 	}
+	return results
 }
 
 func (hs *HeartbeatService) Start() error {
@@ -40,6 +107,9 @@ func (hs *HeartbeatService) Start() error {
 	}
 
 	go hs.runLoop()
+	if len(hs.checks) > 0 && hs.checkInterval > 0 {
+		go hs.checkLoop()
+	}
 
 	return nil
 }
@@ -86,37 +156,149 @@ func (hs *HeartbeatService) checkHeartbeat() {
 	}
 	hs.mu.RUnlock()
 
-	prompt := hs.buildPrompt()
+	hs.RunPrompts(context.Background())
+}
+
+// RunPrompts runs the HEARTBEAT.md prompts that are due and delivers the
+// replies that report something
+func (hs *HeartbeatService) RunPrompts(ctx context.Context) {
+	hs.mu.RLock()
+	handler := hs.onHeartbeat
+	sender, channel, chatID := hs.delivery, hs.deliveryChannel, hs.deliveryChatID
+	hs.mu.RUnlock()
+	if handler == nil {
+		return
+	}
 
-	if hs.onHeartbeat != nil {
-		_, err := hs.onHeartbeat(prompt)
+	for _, p := range hs.duePrompts(time.Now()) {
+		reply, err := handler(hs.buildPrompt(p))
 		if err != nil {
 			hs.log(fmt.Sprintf("Heartbeat error: %v", err))
+			continue
+		}
+		if isNothingToReport(reply) {
+			continue
+		}
+		hs.log(fmt.Sprintf("Heartbeat %q reported: %s", p.Title, reply))
+		if sender == nil || channel == "" || chatID == "" {
+			continue
+		}
+		if p.Title != "" {
+			reply = fmt.Sprintf("💡 %s\n\n%s", p.Title, reply)
+		}
+		if err := sender.SendToChannel(ctx, channel, chatID, reply); err != nil {
+			logger.ErrorCF("heartbeat", "Failed to deliver heartbeat message", map[string]interface{}{
+				"channel": channel,
+				"error":   err.Error(),
+			})
+		}
+	}
+}
+
+// duePrompts reads HEARTBEAT.md and returns the prompts whose interval has
+// passed, marking them as run
+func (hs *HeartbeatService) duePrompts(now time.Time) []Prompt {
+	data, err := os.ReadFile(filepath.Join(hs.workspace, "memory", "HEARTBEAT.md"))
+	if err != nil {
+		return nil
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	var due []Prompt
+	for _, p := range ParsePrompts(string(data)) {
+		if last, ok := hs.lastRun[p.Title]; ok && p.Every > 0 && now.Sub(last) < p.Every {
+			continue
+		}
+		hs.lastRun[p.Title] = now
+		due = append(due, p)
+	}
+	return due
+}
+
+func (hs *HeartbeatService) checkLoop() {
+	ticker := time.NewTicker(hs.checkInterval)
+	defer ticker.Stop()
+
+	hs.RunChecks(context.Background())
+	for {
+		select {
+		case <-hs.stopChan:
+			return
+		case <-ticker.C:
+			hs.RunChecks(context.Background())
 		}
 	}
 }
 
-func (hs *HeartbeatService) buildPrompt() string {
-	notesDir := filepath.Join(hs.workspace, "memory")
-	notesFile := filepath.Join(notesDir, "HEARTBEAT.md")
+// RunChecks runs every registered check once and alerts on state changes:
+// a check that starts failing raises an alert, one that passes again sends
+// a recovery notice. Checks that keep failing are not re-alerted.
+func (hs *HeartbeatService) RunChecks(ctx context.Context) {
+	hs.mu.RLock()
+	checks := hs.checks
+	hs.mu.RUnlock()
+
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		res := check.Run(checkCtx)
+		cancel()
+		if res.Time.IsZero() {
+			res.Time = time.Now()
+		}
+
+		hs.mu.Lock()
+		prev, seen := hs.results[check.Name()]
+		hs.results[check.Name()] = res
+		hs.mu.Unlock()
+
+		if !res.OK {
+			hs.log(fmt.Sprintf("Check %s failed: %s", check.Name(), res.Message))
+		}
+
+		switch {
+		case !res.OK && (!seen || prev.OK):
+			hs.alert(ctx, fmt.Sprintf("⚠️ Heartbeat check failed: %s\n%s", check.Name(), res.Message))
+		case res.OK && seen && !prev.OK:
+			hs.alert(ctx, fmt.Sprintf("✅ Heartbeat check recovered: %s\n%s", check.Name(), res.Message))
+		}
+	}
+}
+
+func (hs *HeartbeatService) alert(ctx context.Context, message string) {
+	hs.mu.RLock()
+	sender, channel, chatID := hs.alerts, hs.alertChannel, hs.alertChatID
+	hs.mu.RUnlock()
 
-	var notes string
-	if data, err := os.ReadFile(notesFile); err == nil {
-		notes = string(data)
+	if sender == nil || channel == "" || chatID == "" {
+		logger.WarnCF("heartbeat", message, nil)
+		return
+	}
+	if err := sender.SendToChannel(ctx, channel, chatID, message); err != nil {
+		logger.ErrorCF("heartbeat", "Failed to send heartbeat alert", map[string]interface{}{
+			"channel": channel,
+			"error":   err.Error(),
+		})
 	}
+}
 
+func (hs *HeartbeatService) buildPrompt(p Prompt) string {
 	now := time.Now().Format("2006-01-02 15:04")
 
-	prompt := fmt.Sprintf(`# Heartbeat Check
+	title := "Heartbeat Check"
+	if p.Title != "" {
+		title += ": " + p.Title
+	}
+	prompt := fmt.Sprintf(`# %s
 
 Current time: %s
 
-Check if there are any tasks I should be aware of or actions I should take.
-Review the memory file for any important updates or changes.
-Be proactive in identifying potential issues or improvements.
+This is a scheduled check, not a message from the user. Do what it asks
+and reply with only what the user should know. If nothing needs their
+attention, or you already told them about it, reply exactly %s.
 
 %s
-`, now, notes)
+`, title, now, NothingToReport, p.Text)
 
 	return prompt
 }
@@ -0,0 +1,230 @@
+// Package hooks verifies inbound webhook requests and renders their payloads
+// into agent prompts for the gateway's /v1/hooks/{name} endpoint.
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// Signature schemes
+const (
+	SchemeHMAC   = "hmac-sha256" // hex or base64 HMAC-SHA256 of the body in SignatureHeader
+	SchemeGitHub = "github"      // X-Hub-Signature-256: sha256=<hex>
+	SchemeStripe = "stripe"      // Stripe-Signature: t=<ts>,v1=<hex> over "<ts>.<body>"
+	SchemeToken  = "token"       // shared secret in SignatureHeader or Authorization: Bearer
+	SchemeNone   = "none"        // no verification (only for trusted networks)
+)
+
+// stripeTolerance is how old a Stripe signature timestamp may be
+const stripeTolerance = 5 * time.Minute
+
+// DefaultTemplate is used when a hook has no template
+const DefaultTemplate = "Webhook '{{.Hook}}' received{{with .Event}} ({{.}} event){{end}}:\n\n```json\n{{json .Payload}}\n```"
+
+// Verify checks the request signature against the hook's secret
+func Verify(hook config.HookConfig, header http.Header, body []byte) error {
+	scheme := hook.Signature
+	if scheme == "" {
+		scheme = SchemeHMAC
+	}
+	if scheme == SchemeNone {
+		return nil
+	}
+	if hook.Secret == "" {
+		return fmt.Errorf("hook has no secret configured")
+	}
+	secret := []byte(hook.Secret)
+
+	switch scheme {
+	case SchemeGitHub:
+		got := header.Get("X-Hub-Signature-256")
+		if got == "" {
+			return fmt.Errorf("missing X-Hub-Signature-256 header")
+		}
+		return compareHex(strings.TrimPrefix(got, "sha256="), sign(sha256.New, secret, body))
+
+	case SchemeStripe:
+		return verifyStripe(secret, header.Get("Stripe-Signature"), body, time.Now())
+
+	case SchemeHMAC:
+		name := hook.SignatureHeader
+		if name == "" {
+			name = "X-Signature"
+		}
+		got := header.Get(name)
+		if got == "" {
+			return fmt.Errorf("missing %s header", name)
+		}
+		got = strings.TrimPrefix(got, "sha256=")
+		expected := sign(sha256.New, secret, body)
+		if compareHex(got, expected) == nil {
+			return nil
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(got); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+		return fmt.Errorf("signature mismatch")
+
+	case SchemeToken:
+		got := ""
+		if hook.SignatureHeader != "" {
+			got = header.Get(hook.SignatureHeader)
+		}
+		if got == "" {
+			got = strings.TrimPrefix(header.Get("Authorization"), "Bearer ")
+		}
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), secret) != 1 {
+			return fmt.Errorf("invalid token")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown signature scheme '%s'", scheme)
+	}
+}
+
+func verifyStripe(secret []byte, value string, body []byte, now time.Time) error {
+	if value == "" {
+		return fmt.Errorf("missing Stripe-Signature header")
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = val
+		case "v1":
+			signatures = append(signatures, val)
+		}
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Stripe-Signature timestamp")
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > stripeTolerance || age < -stripeTolerance {
+		return fmt.Errorf("signature timestamp outside tolerance")
+	}
+
+	expected := sign(sha256.New, secret, []byte(timestamp+"."+string(body)))
+	for _, sig := range signatures {
+		if compareHex(sig, expected) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature mismatch")
+}
+
+func sign(h func() hash.Hash, secret, data []byte) []byte {
+	mac := hmac.New(h, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func compareHex(got string, expected []byte) error {
+	decoded, err := hex.DecodeString(strings.TrimSpace(got))
+	if err != nil || !hmac.Equal(decoded, expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// Data is what hook templates are rendered with
+type Data struct {
+	Hook    string
+	Event   string            // from X-GitHub-Event, X-Event-Type or the payload "type" field
+	Payload interface{}       // decoded JSON body, or the raw body as a string
+	Headers map[string]string // first value of each request header
+	Raw     string
+}
+
+// NewData decodes a request body for template rendering
+func NewData(name string, header http.Header, body []byte) Data {
+	data := Data{Hook: name, Raw: string(body), Headers: map[string]string{}}
+	for key, values := range header {
+		if len(values) > 0 {
+			data.Headers[key] = values[0]
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err == nil {
+		data.Payload = payload
+	} else {
+		data.Payload = string(body)
+	}
+
+	for _, h := range []string{"X-GitHub-Event", "X-Event-Type", "X-Gitlab-Event"} {
+		if v := header.Get(h); v != "" {
+			data.Event = v
+			break
+		}
+	}
+	if data.Event == "" {
+		if m, ok := data.Payload.(map[string]interface{}); ok {
+			if t, ok := m["type"].(string); ok {
+				data.Event = t
+			}
+		}
+	}
+	return data
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) string {
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(out)
+	},
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if len(r) <= n {
+			return s
+		}
+		return string(r[:n]) + "..."
+	},
+	"default": func(fallback, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+}
+
+// Render executes a hook's payload-to-prompt template. Fields of a JSON
+// payload are available as {{.Payload.field}}; see Data for the rest.
+func Render(tmpl string, data Data) (string, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := template.New(data.Hook).Funcs(templateFuncs).Option("missingkey=zero").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("template failed: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,111 @@
+package hooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func hexMAC(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyGitHub(t *testing.T) {
+	hook := config.HookConfig{Secret: "s3cret", Signature: SchemeGitHub}
+	body := []byte(`{"action":"opened"}`)
+
+	header := http.Header{}
+	header.Set("X-Hub-Signature-256", "sha256="+hexMAC("s3cret", string(body)))
+	if err := Verify(hook, header, body); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	header.Set("X-Hub-Signature-256", "sha256="+hexMAC("wrong", string(body)))
+	if err := Verify(hook, header, body); err == nil {
+		t.Fatal("invalid signature accepted")
+	}
+}
+
+func TestVerifyStripe(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte(`{"type":"charge.succeeded"}`)
+	now := time.Now()
+	ts := fmt.Sprintf("%d", now.Unix())
+	value := "t=" + ts + ",v1=" + hexMAC("whsec", ts+"."+string(body))
+
+	if err := verifyStripe(secret, value, body, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := verifyStripe(secret, value, body, now.Add(10*time.Minute)); err == nil {
+		t.Fatal("stale signature accepted")
+	}
+}
+
+func TestVerifyHMACAndToken(t *testing.T) {
+	body := []byte("payload")
+
+	header := http.Header{}
+	header.Set("X-Signature", hexMAC("k", "payload"))
+	if err := Verify(config.HookConfig{Secret: "k"}, header, body); err != nil {
+		t.Fatalf("default hmac scheme rejected valid signature: %v", err)
+	}
+	if err := Verify(config.HookConfig{Secret: "k"}, http.Header{}, body); err == nil {
+		t.Fatal("missing signature accepted")
+	}
+
+	token := config.HookConfig{Secret: "tok", Signature: SchemeToken}
+	header = http.Header{}
+	header.Set("Authorization", "Bearer tok")
+	if err := Verify(token, header, body); err != nil {
+		t.Fatalf("bearer token rejected: %v", err)
+	}
+	header.Set("Authorization", "Bearer nope")
+	if err := Verify(token, header, body); err == nil {
+		t.Fatal("wrong token accepted")
+	}
+
+	if err := Verify(config.HookConfig{Signature: SchemeGitHub}, http.Header{}, body); err == nil {
+		t.Fatal("hook without secret accepted")
+	}
+}
+
+func TestRender(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "pull_request")
+	data := NewData("github", header, []byte(`{"action":"opened","pull_request":{"title":"Fix bug","number":12}}`))
+
+	if data.Event != "pull_request" {
+		t.Errorf("event = %q", data.Event)
+	}
+
+	out, err := Render(`PR #{{.Payload.pull_request.number}} {{.Payload.action}}: {{.Payload.pull_request.title}}`, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "PR #12 opened: Fix bug" {
+		t.Errorf("rendered %q", out)
+	}
+
+	out, err = Render("", data)
+	if err != nil || !strings.Contains(out, "pull_request event") || !strings.Contains(out, `"action": "opened"`) {
+		t.Errorf("default template rendered %q (%v)", out, err)
+	}
+
+	data = NewData("github", header, []byte(`{"title":"Réparer le bogue"}`))
+	if out, err := Render(`{{truncate 2 .Payload.title}}`, data); err != nil || out != "Ré..." {
+		t.Errorf("truncate rendered %q (%v)", out, err)
+	}
+
+	if _, err := Render("{{.Payload.", data); err == nil {
+		t.Error("invalid template accepted")
+	}
+}
@@ -0,0 +1,222 @@
+// Package instance keeps one gateway running per configuration. The running
+// gateway holds a lock file and refreshes a heartbeat in it; another gateway
+// either refuses to start or, as a standby, waits until the heartbeat goes
+// stale and takes over.
+package instance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Holder describes the gateway holding the lock
+type Holder struct {
+	ID        string    `json:"id"`
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Started   time.Time `json:"started"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// HeldError is returned when a live gateway holds the lock
+type HeldError struct {
+	Holder Holder
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("another gateway is running (pid %d on %s, last heartbeat %s ago)",
+		e.Holder.PID, e.Holder.Host, time.Since(e.Holder.Heartbeat).Round(time.Second))
+}
+
+// Lock is the instance lock of one gateway process
+type Lock struct {
+	path       string
+	interval   time.Duration
+	staleAfter time.Duration
+	me         Holder
+
+	mu       sync.Mutex
+	held     bool
+	lost     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	lostOnce sync.Once
+}
+
+// New prepares a lock at path. The holder refreshes its heartbeat every
+// interval, and a heartbeat older than staleAfter is taken over.
+func New(path string, interval, staleAfter time.Duration) *Lock {
+	host, _ := os.Hostname()
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &Lock{
+		path:       path,
+		interval:   interval,
+		staleAfter: staleAfter,
+		me: Holder{
+			ID:      hex.EncodeToString(id),
+			PID:     os.Getpid(),
+			Host:    host,
+			Started: time.Now(),
+		},
+		lost: make(chan struct{}),
+	}
+}
+
+// Read returns the current holder of the lock file at path
+func Read(path string) (Holder, error) {
+	var h Holder
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, fmt.Errorf("invalid lock file %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// TryAcquire takes the lock if it is free, stale or left behind by a
+// process that has exited. It returns a *HeldError when a live gateway
+// holds it.
+func (l *Lock) TryAcquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held {
+		return nil
+	}
+
+	current, err := Read(l.path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		// Not a lock file we can read: replace it
+	case !l.abandoned(current):
+		return &HeldError{Holder: current}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	if err := l.write(); err != nil {
+		return err
+	}
+	// Another gateway may have written at the same moment; the last
+	// rename wins
+	time.Sleep(50 * time.Millisecond)
+	if current, err := Read(l.path); err != nil || current.ID != l.me.ID {
+		if err == nil {
+			return &HeldError{Holder: current}
+		}
+		return err
+	}
+
+	l.held = true
+	l.stop = make(chan struct{})
+	l.stopped = make(chan struct{})
+	go l.heartbeat()
+	return nil
+}
+
+// Wait polls until the lock can be taken or ctx ends. waiting is called
+// with the holder each time the lock is found held.
+func (l *Lock) Wait(ctx context.Context, waiting func(Holder)) error {
+	for {
+		err := l.TryAcquire()
+		held, isHeld := err.(*HeldError)
+		if !isHeld {
+			return err
+		}
+		if waiting != nil {
+			waiting(held.Holder)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.interval):
+		}
+	}
+}
+
+// Lost is closed when another gateway takes the lock over from this one
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops the heartbeat and removes the lock file if it is still ours
+func (l *Lock) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return
+	}
+	l.held = false
+	close(l.stop)
+	<-l.stopped
+	if current, err := Read(l.path); err == nil && current.ID == l.me.ID {
+		os.Remove(l.path)
+	}
+}
+
+// abandoned reports whether the holder stopped refreshing its heartbeat or
+// its process is gone
+func (l *Lock) abandoned(h Holder) bool {
+	if h.ID == l.me.ID {
+		return true
+	}
+	if time.Since(h.Heartbeat) > l.staleAfter {
+		return true
+	}
+	if h.Host == l.me.Host {
+		// A previous run of this process, or one that has exited
+		return h.PID == l.me.PID || !processAlive(h.PID)
+	}
+	return false
+}
+
+func (l *Lock) write() error {
+	h := l.me
+	h.Heartbeat = time.Now()
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + "." + l.me.ID + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (l *Lock) heartbeat() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// A deleted lock file is written again; another holder means a
+		// standby decided this gateway was gone
+		current, err := Read(l.path)
+		if err == nil && current.ID != l.me.ID {
+			l.lostOnce.Do(func() { close(l.lost) })
+			return
+		}
+		l.write()
+	}
+}
@@ -0,0 +1,88 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.lock")
+	first := New(path, 20*time.Millisecond, time.Second)
+	if err := first.TryAcquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := New(path, 20*time.Millisecond, time.Second)
+	second.me.Host = "other-host"
+	var held *HeldError
+	if err := second.TryAcquire(); !errors.As(err, &held) || held.Holder.ID != first.me.ID {
+		t.Fatalf("second acquire = %v", err)
+	}
+
+	first.Release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("lock file left behind after release")
+	}
+	if err := second.TryAcquire(); err != nil {
+		t.Fatalf("acquire after release = %v", err)
+	}
+	second.Release()
+}
+
+func TestLockTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.lock")
+
+	// A gateway on another host that stopped refreshing its heartbeat
+	stale := Holder{ID: "old", PID: 1, Host: "other-host", Heartbeat: time.Now()}
+	data, _ := json.Marshal(stale)
+	os.WriteFile(path, data, 0644)
+
+	standby := New(path, 20*time.Millisecond, 150*time.Millisecond)
+	waits := 0
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := standby.Wait(ctx, func(Holder) { waits++ }); err != nil {
+		t.Fatal(err)
+	}
+	defer standby.Release()
+	if waits == 0 {
+		t.Error("standby took over a live lock")
+	}
+
+	// The old primary comes back and finds it lost the lock
+	primary := New(path, 20*time.Millisecond, time.Second)
+	primary.me.Host = "other-host"
+	if err := primary.TryAcquire(); err == nil {
+		t.Fatal("old primary reacquired a live lock")
+	}
+
+	// Overwriting the holder is noticed by the heartbeat
+	stale.Heartbeat = time.Now()
+	data, _ = json.Marshal(stale)
+	os.WriteFile(path, data, 0644)
+	select {
+	case <-standby.Lost():
+	case <-time.After(2 * time.Second):
+		t.Error("lost lock not reported")
+	}
+}
+
+func TestLockDeadProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.lock")
+	l := New(path, time.Second, time.Hour)
+
+	// Same host, fresh heartbeat, but the process has exited
+	dead := Holder{ID: "dead", PID: 1 << 30, Host: l.me.Host, Heartbeat: time.Now()}
+	data, _ := json.Marshal(dead)
+	os.WriteFile(path, data, 0644)
+
+	if err := l.TryAcquire(); err != nil {
+		t.Fatalf("lock of an exited process not taken: %v", err)
+	}
+	l.Release()
+}
@@ -0,0 +1,127 @@
+// Package journal keeps daily notes as dated markdown files in
+// workspace/memory/daily/YYYY-MM-DD.md.
+package journal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Journal reads and writes the daily note files of a workspace
+type Journal struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// New returns the journal of a workspace
+func New(workspace string) *Journal {
+	return &Journal{dir: filepath.Join(workspace, "memory", "daily")}
+}
+
+// Path returns the note file for a day
+func (j *Journal) Path(day time.Time) string {
+	return filepath.Join(j.dir, day.Format(dateLayout)+".md")
+}
+
+// Ensure creates the note for a day with its heading if it does not exist
+func (j *Journal) Ensure(day time.Time) (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.ensure(day)
+}
+
+func (j *Journal) ensure(day time.Time) (string, error) {
+	path := j.Path(day)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return "", err
+	}
+	heading := fmt.Sprintf("# %s (%s)\n\n", day.Format(dateLayout), day.Weekday())
+	return path, os.WriteFile(path, []byte(heading), 0644)
+}
+
+// Append adds a timestamped entry to the note of the day of now
+func (j *Journal) Append(now time.Time, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("entry is empty")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	path, err := j.ensure(now)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// Indent continuation lines so multi-line entries stay in one bullet
+	entry := strings.ReplaceAll(text, "\n", "\n  ")
+	if _, err := fmt.Fprintf(f, "- %s %s\n", now.Format("15:04"), entry); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Read returns the note of a day, or "" when there is none
+func (j *Journal) Read(day time.Time) string {
+	data, err := os.ReadFile(j.Path(day))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// HasEntries reports whether a note has more than its heading
+func HasEntries(note string) bool {
+	for _, line := range strings.Split(note, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "# ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Range returns the notes of the given number of days up to and including
+// the day of now, oldest first. Days without entries are skipped.
+func (j *Journal) Range(now time.Time, days int) string {
+	var sb strings.Builder
+	for i := days - 1; i >= 0; i-- {
+		note := j.Read(now.AddDate(0, 0, -i))
+		if !HasEntries(note) {
+			continue
+		}
+		sb.WriteString(strings.TrimSpace(note))
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// ParseDay reads "today", "yesterday" or a YYYY-MM-DD date relative to now
+func ParseDay(value string, now time.Time) (time.Time, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+	day, err := time.ParseInLocation(dateLayout, strings.TrimSpace(value), now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD, today or yesterday", value)
+	}
+	return day, nil
+}
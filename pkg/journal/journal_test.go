@@ -0,0 +1,55 @@
+package journal
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndRange(t *testing.T) {
+	j := New(t.TempDir())
+	monday := time.Date(2026, 3, 2, 9, 30, 0, 0, time.Local)
+
+	path, err := j.Append(monday, "Standup: shipped the\nrelease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Append(monday.Add(2*time.Hour), "Lunch with Sari"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Append(monday, "  "); err == nil {
+		t.Error("empty entry should fail")
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "# 2026-03-02 (Monday)\n\n- 09:30 Standup: shipped the\n  release\n- 11:30 Lunch with Sari\n"
+	if string(data) != want {
+		t.Errorf("note =\n%q\nwant\n%q", data, want)
+	}
+
+	// An empty note for Tuesday is skipped in the range
+	tuesday := monday.AddDate(0, 0, 1)
+	if _, err := j.Ensure(tuesday); err != nil {
+		t.Fatal(err)
+	}
+	j.Append(tuesday.AddDate(0, 0, 1), "Dentist")
+
+	week := j.Range(tuesday.AddDate(0, 0, 1), 7)
+	if !strings.Contains(week, "2026-03-02 (Monday)") || !strings.Contains(week, "Dentist") || strings.Contains(week, "Tuesday") {
+		t.Errorf("unexpected range:\n%s", week)
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	if d, _ := ParseDay("yesterday", now); d.Format(dateLayout) != "2026-03-01" {
+		t.Errorf("yesterday = %s", d)
+	}
+	if d, _ := ParseDay("2026-02-14", now); d.Format(dateLayout) != "2026-02-14" {
+		t.Errorf("date = %s", d)
+	}
+	if _, err := ParseDay("last week", now); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,56 @@
+// Package kv is a small persistent key–value store in the workspace for
+// agent and workflow state (counters, cursors, last-seen IDs).
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MaxValueSize caps a single stored value; the store is meant for small state
+const MaxValueSize = 64 * 1024
+
+// DefaultNamespace is used when no namespace is given
+const DefaultNamespace = "default"
+
+// ErrNotNumber is returned when incrementing a value that is not a number
+var ErrNotNumber = errors.New("value is not a number")
+
+// Entry is a stored value
+type Entry struct {
+	Namespace string          `json:"namespace"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+var (
+	stores   = map[string]*Store{}
+	storesMu sync.Mutex
+)
+
+// Open returns the store at path, opening it on first use. Stores are shared
+// per path so every agent loop in the process uses the same connection.
+func Open(path string) (*Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[path]; ok {
+		return s, nil
+	}
+	s, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	stores[path] = s
+	return s, nil
+}
+
+func namespaceOrDefault(ns string) string {
+	if ns == "" {
+		return DefaultNamespace
+	}
+	return ns
+}
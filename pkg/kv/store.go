@@ -0,0 +1,202 @@
+//go:build !mips && !mipsle && !mips64 && !mips64le
+// +build !mips,!mipsle,!mips64,!mips64le
+
+package kv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store is a SQLite-backed key–value store
+type Store struct {
+	db *sql.DB
+}
+
+func open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create kv directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open kv store: %w", err)
+	}
+	// A single connection serialises writers and keeps increments atomic
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS kv (
+		namespace TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (namespace, key)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialise kv store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Get returns the value of key, and false if it is not set
+func (s *Store) Get(namespace, key string) (json.RawMessage, bool, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM kv WHERE namespace = ? AND key = ?`, namespaceOrDefault(namespace), key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read key: %w", err)
+	}
+	return json.RawMessage(value), true, nil
+}
+
+// Set stores a JSON value under key, replacing any previous value
+func (s *Store) Set(namespace, key string, value json.RawMessage) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if len(value) > MaxValueSize {
+		return fmt.Errorf("value is %d bytes, limit is %d", len(value), MaxValueSize)
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("value is not valid JSON")
+	}
+
+	_, err := s.db.Exec(`INSERT INTO kv (namespace, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(namespace, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		namespaceOrDefault(namespace), key, string(value), time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	return nil
+}
+
+// Increment adds delta to a numeric value (missing keys start at 0) and
+// returns the new value
+func (s *Store) Increment(namespace, key string, delta float64) (float64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("key is required")
+	}
+	namespace = namespaceOrDefault(namespace)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var current float64
+	var raw string
+	err = tx.QueryRow(`SELECT value FROM kv WHERE namespace = ? AND key = ?`, namespace, key).Scan(&raw)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return 0, fmt.Errorf("failed to read key: %w", err)
+	default:
+		current, err = strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", key, ErrNotNumber)
+		}
+	}
+
+	next := current + delta
+	_, err = tx.Exec(`INSERT INTO kv (namespace, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(namespace, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		namespace, key, strconv.FormatFloat(next, 'f', -1, 64), time.Now().UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to write key: %w", err)
+	}
+	return next, tx.Commit()
+}
+
+// Delete removes key and reports whether it existed
+func (s *Store) Delete(namespace, key string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM kv WHERE namespace = ? AND key = ?`, namespaceOrDefault(namespace), key)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete key: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// List returns the entries in namespace whose key starts with prefix, sorted by key
+func (s *Store) List(namespace, prefix string, limit int) ([]Entry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	// Keys compare bytewise, so every key with the prefix sorts between the
+	// prefix and its successor; substr would count characters, not bytes.
+	query := `SELECT namespace, key, value, updated_at FROM kv WHERE namespace = ? AND key >= ?`
+	args := []interface{}{namespaceOrDefault(namespace), prefix}
+	if end, ok := prefixEnd(prefix); ok {
+		query += ` AND key < ?`
+		args = append(args, end)
+	}
+	rows, err := s.db.Query(query+` ORDER BY key LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var value string
+		var updated int64
+		if err := rows.Scan(&e.Namespace, &e.Key, &value, &updated); err != nil {
+			return nil, err
+		}
+		e.Value = json.RawMessage(value)
+		e.UpdatedAt = time.UnixMilli(updated)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// prefixEnd returns the smallest string greater than every string starting
+// with prefix, or false when there is none (empty or all 0xff bytes)
+func prefixEnd(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// Namespaces returns every namespace that holds at least one key
+func (s *Store) Namespaces() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT namespace FROM kv ORDER BY namespace`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,46 @@
+//go:build mips || mipsle || mips64 || mips64le
+// +build mips mipsle mips64 mips64le
+
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var errUnsupported = fmt.Errorf("kv store is not supported on MIPS architecture (SQLite dependency unavailable)")
+
+// Store stub for MIPS architectures (SQLite not supported)
+type Store struct{}
+
+func open(path string) (*Store, error) {
+	return nil, errUnsupported
+}
+
+func (s *Store) Get(namespace, key string) (json.RawMessage, bool, error) {
+	return nil, false, errUnsupported
+}
+
+func (s *Store) Set(namespace, key string, value json.RawMessage) error {
+	return errUnsupported
+}
+
+func (s *Store) Increment(namespace, key string, delta float64) (float64, error) {
+	return 0, errUnsupported
+}
+
+func (s *Store) Delete(namespace, key string) (bool, error) {
+	return false, errUnsupported
+}
+
+func (s *Store) List(namespace, prefix string, limit int) ([]Entry, error) {
+	return nil, errUnsupported
+}
+
+func (s *Store) Namespaces() ([]string, error) {
+	return nil, errUnsupported
+}
+
+func (s *Store) Close() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+//go:build !mips && !mipsle && !mips64 && !mips64le
+// +build !mips,!mipsle,!mips64,!mips64le
+
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreSetGetList(t *testing.T) {
+	s, err := open(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Set("", "cursor", json.RawMessage(`"abc"`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("", "seen", json.RawMessage(`{"id":42}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("other", "cursor", json.RawMessage(`1`)); err != nil {
+		t.Fatal(err)
+	}
+
+	value, found, err := s.Get(DefaultNamespace, "cursor")
+	if err != nil || !found || string(value) != `"abc"` {
+		t.Fatalf("Get = %s, %v, %v", value, found, err)
+	}
+	if _, found, _ := s.Get("", "missing"); found {
+		t.Fatal("missing key reported as found")
+	}
+
+	entries, err := s.List("", "cu", 0)
+	if err != nil || len(entries) != 1 || entries[0].Key != "cursor" {
+		t.Fatalf("List = %+v, %v", entries, err)
+	}
+	if all, _ := s.List("", "", 0); len(all) != 2 {
+		t.Fatalf("expected 2 keys in default namespace, got %d", len(all))
+	}
+
+	if existed, _ := s.Delete("", "seen"); !existed {
+		t.Fatal("Delete did not find key")
+	}
+	if err := s.Set("", "bad", json.RawMessage(`{`)); err == nil {
+		t.Fatal("invalid JSON accepted")
+	}
+}
+
+func TestStoreIncrement(t *testing.T) {
+	s, err := open(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Increment("", "count", 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := s.Increment("", "count", -1); n != 5 {
+		t.Fatalf("count = %v, want 5", n)
+	}
+
+	s.Set("", "name", json.RawMessage(`"x"`))
+	if _, err := s.Increment("", "name", 1); !errors.Is(err, ErrNotNumber) {
+		t.Fatalf("expected ErrNotNumber, got %v", err)
+	}
+}
+
+func TestStoreListNonASCIIPrefix(t *testing.T) {
+	s, err := open(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, key := range []string{"café:1", "café:2", "cafe:1", "Café:1", "caf"} {
+		if err := s.Set("", key, json.RawMessage(`1`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := s.List("", "café:", 0)
+	if err != nil || len(entries) != 2 || entries[0].Key != "café:1" || entries[1].Key != "café:2" {
+		t.Fatalf("List = %+v, %v", entries, err)
+	}
+	if entries, _ := s.List("", "caf", 0); len(entries) != 4 {
+		t.Fatalf("expected 4 keys with prefix caf, got %+v", entries)
+	}
+}
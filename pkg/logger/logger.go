@@ -34,8 +34,15 @@ var (
 	logger       *Logger
 	once         sync.Once
 	mu           sync.RWMutex
+
+	// recentErrors keeps the last maxRecentErrors error entries for status
+	// reporting, oldest first
+	recentErrors []LogEntry
+	errorsMu     sync.Mutex
 )
 
+const maxRecentErrors = 20
+
 type Logger struct {
 	file *os.File
 }
@@ -85,6 +92,21 @@ func EnableFileLogging(filePath string) error {
 	return nil
 }
 
+// RecentErrors returns the most recent error entries, newest first
+func RecentErrors(limit int) []LogEntry {
+	errorsMu.Lock()
+	defer errorsMu.Unlock()
+
+	if limit <= 0 || limit > len(recentErrors) {
+		limit = len(recentErrors)
+	}
+	entries := make([]LogEntry, 0, limit)
+	for i := len(recentErrors) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, recentErrors[i])
+	}
+	return entries
+}
+
 func DisableFileLogging() {
 	mu.Lock()
 	defer mu.Unlock()
@@ -116,6 +138,15 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		}
 	}
 
+	if level >= ERROR {
+		errorsMu.Lock()
+		recentErrors = append(recentErrors, entry)
+		if len(recentErrors) > maxRecentErrors {
+			recentErrors = recentErrors[len(recentErrors)-maxRecentErrors:]
+		}
+		errorsMu.Unlock()
+	}
+
 	if logger.file != nil {
 		jsonData, err := json.Marshal(entry)
 		if err == nil {
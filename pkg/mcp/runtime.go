@@ -136,6 +136,25 @@ func (r *Runtime) Close() {
 	r.tools = []RuntimeTool{}
 }
 
+// Probe starts a server, initializes it and lists its tools, then shuts it
+// down again. It returns the number of tools the server offers.
+func Probe(ctx context.Context, def *ServerDefinition) (int, error) {
+	client, err := createClient(def)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	if err := client.Initialize(ctx); err != nil {
+		return 0, fmt.Errorf("initialize failed: %w", err)
+	}
+	remoteTools, err := client.ListTools(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("listing tools failed: %w", err)
+	}
+	return len(remoteTools), nil
+}
+
 func createClient(def *ServerDefinition) (Client, error) {
 	if err := ValidateServerDefinition("runtime", def); err != nil {
 		return nil, err
@@ -0,0 +1,249 @@
+// Package merge refreshes files that came from upstream (builtin skills,
+// workspace templates) while keeping the user's edits, using a line-based
+// three-way merge against the upstream version last installed.
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UpstreamDir is the directory in the workspace that keeps the upstream
+// version of each refreshed file, the base of the next merge
+const UpstreamDir = ".upstream"
+
+// maxMergeCells bounds the LCS table; larger files are treated as conflicts
+const maxMergeCells = 4_000_000
+
+// ThreeWay merges the changes from base to ours and from base to theirs.
+// Regions both sides changed differently are kept with conflict markers, and
+// the number of such regions is returned.
+func ThreeWay(base, ours, theirs string) (string, int) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	if len(b)*len(o) > maxMergeCells || len(b)*len(t) > maxMergeCells {
+		return conflict(o, t), 1
+	}
+	mo, mt := matchLines(b, o), matchLines(b, t)
+
+	var out []string
+	conflicts := 0
+	i, j, k := 0, 0, 0
+	for {
+		// Lines unchanged on both sides
+		for i < len(b) && mo[i] == j && mt[i] == k {
+			out = append(out, b[i])
+			i, j, k = i+1, j+1, k+1
+		}
+		if i == len(b) && j == len(o) && k == len(t) {
+			break
+		}
+
+		// The changed region ends at the next base line both sides kept
+		end := i
+		for end < len(b) && (mo[end] < 0 || mt[end] < 0) {
+			end++
+		}
+		oEnd, tEnd := len(o), len(t)
+		if end < len(b) {
+			oEnd, tEnd = mo[end], mt[end]
+		}
+		bc, oc, tc := b[i:end], o[j:oEnd], t[k:tEnd]
+
+		switch {
+		case equal(oc, bc):
+			out = append(out, tc...)
+		case equal(tc, bc), equal(oc, tc):
+			out = append(out, oc...)
+		default:
+			out = append(out, splitLines(conflict(oc, tc))...)
+			conflicts++
+		}
+		i, j, k = end, oEnd, tEnd
+	}
+	return strings.Join(out, ""), conflicts
+}
+
+// splitLines splits s after each newline, keeping the newlines
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// matchLines returns, for each line of a, the index of the line of b it is
+// matched with by a longest common subsequence, or -1
+func matchLines(a, b []string) []int {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	match := make([]int, n)
+	for i := range match {
+		match[i] = -1
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case a[i] == b[j]:
+			match[i] = j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
+
+func conflict(ours, theirs []string) string {
+	var b strings.Builder
+	b.WriteString("<<<<<<< yours\n")
+	writeLines(&b, ours)
+	b.WriteString("=======\n")
+	writeLines(&b, theirs)
+	b.WriteString(">>>>>>> update\n")
+	return b.String()
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, l := range lines {
+		b.WriteString(l)
+		if !strings.HasSuffix(l, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Outcome says what RefreshFile did
+type Outcome string
+
+const (
+	Created   Outcome = "created"   // the file did not exist
+	Unchanged Outcome = "unchanged" // upstream did not change, or the file already matches
+	Updated   Outcome = "updated"   // the file had no local edits and was replaced
+	Merged    Outcome = "merged"    // local edits were merged with the update
+	Conflict  Outcome = "conflict"  // edits overlap; the merge with markers is in <file>.new
+	Kept      Outcome = "kept"      // no known base to merge against; the update is in <file>.new
+)
+
+// Options control RefreshFile
+type Options struct {
+	Mode os.FileMode // mode of created files, 0644 when zero
+	// OverwriteUntracked replaces files that have no recorded base instead
+	// of keeping them, for files that used to be overwritten on every update
+	OverwriteUntracked bool
+}
+
+// NewPath is where RefreshFile leaves an update it could not apply
+func NewPath(path string) string {
+	return path + ".new"
+}
+
+// RefreshFile brings the file at path up to date with latest. basePath holds
+// the upstream version the file was last refreshed from and is updated when
+// the file is.
+func RefreshFile(path, basePath string, latest []byte, opts Options) (Outcome, error) {
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := writeFile(path, latest, mode); err != nil {
+			return "", err
+		}
+		return Created, writeFile(basePath, latest, 0644)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	base, err := os.ReadFile(basePath)
+	switch {
+	case os.IsNotExist(err):
+		if bytes.Equal(current, latest) || opts.OverwriteUntracked {
+			if err := writeFile(path, latest, mode); err != nil {
+				return "", err
+			}
+			outcome := Updated
+			if bytes.Equal(current, latest) {
+				outcome = Unchanged
+			}
+			return outcome, writeFile(basePath, latest, 0644)
+		}
+		if err := writeFile(NewPath(path), latest, mode); err != nil {
+			return "", err
+		}
+		// Merge against this version from now on
+		return Kept, writeFile(basePath, latest, 0644)
+	case err != nil:
+		return "", err
+	}
+
+	switch {
+	case bytes.Equal(current, latest):
+		os.Remove(NewPath(path))
+		return Unchanged, writeFile(basePath, latest, 0644)
+	case bytes.Equal(base, latest):
+		return Unchanged, nil
+	case bytes.Equal(current, base):
+		if err := writeFile(path, latest, mode); err != nil {
+			return "", err
+		}
+		os.Remove(NewPath(path))
+		return Updated, writeFile(basePath, latest, 0644)
+	}
+
+	merged, conflicts := ThreeWay(string(base), string(current), string(latest))
+	if conflicts > 0 {
+		// Leave the file alone; the base stays so the next update merges again
+		return Conflict, writeFile(NewPath(path), []byte(merged), mode)
+	}
+	if err := writeFile(path, []byte(merged), mode); err != nil {
+		return "", err
+	}
+	os.Remove(NewPath(path))
+	return Merged, writeFile(basePath, latest, 0644)
+}
+
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, data, mode)
+}
@@ -0,0 +1,91 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThreeWay(t *testing.T) {
+	base := "# Title\n\nintro\n\n## Rules\n- be nice\n- be brief\n"
+	ours := "# Title\n\nintro, edited by me\n\n## Rules\n- be nice\n- be brief\n"
+	theirs := "# Title\n\nintro\n\n## Rules\n- be nice\n- be brief\n- cite sources\n"
+
+	merged, conflicts := ThreeWay(base, ours, theirs)
+	want := "# Title\n\nintro, edited by me\n\n## Rules\n- be nice\n- be brief\n- cite sources\n"
+	if conflicts != 0 || merged != want {
+		t.Errorf("clean merge = %q (%d conflicts), want %q", merged, conflicts, want)
+	}
+
+	ours = strings.Replace(base, "- be brief", "- be detailed", 1)
+	theirs = strings.Replace(base, "- be brief", "- be very brief", 1)
+	merged, conflicts = ThreeWay(base, ours, theirs)
+	if conflicts != 1 {
+		t.Fatalf("overlapping edits should conflict, got %d:\n%s", conflicts, merged)
+	}
+	if !strings.Contains(merged, "<<<<<<< yours\n- be detailed\n=======\n- be very brief\n>>>>>>> update\n") {
+		t.Errorf("conflict markers missing:\n%s", merged)
+	}
+
+	// Same change on both sides is not a conflict
+	if merged, conflicts := ThreeWay(base, theirs, theirs); conflicts != 0 || merged != theirs {
+		t.Errorf("identical edits: %q, %d", merged, conflicts)
+	}
+	// Deleting a line the update left alone
+	ours = strings.Replace(base, "intro\n", "", 1)
+	theirs = base + "footer\n"
+	if merged, _ := ThreeWay(base, ours, theirs); merged != ours+"footer\n" {
+		t.Errorf("delete + append = %q", merged)
+	}
+}
+
+func TestRefreshFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "AGENTS.md")
+	basePath := filepath.Join(dir, ".upstream", "AGENTS.md")
+
+	refresh := func(latest string, opts Options) Outcome {
+		t.Helper()
+		outcome, err := RefreshFile(path, basePath, []byte(latest), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return outcome
+	}
+	read := func(p string) string {
+		data, _ := os.ReadFile(p)
+		return string(data)
+	}
+
+	if got := refresh("a\nb\n", Options{}); got != Created || read(basePath) != "a\nb\n" {
+		t.Fatalf("first refresh = %s", got)
+	}
+	if got := refresh("a\nb\nc\n", Options{}); got != Updated || read(path) != "a\nb\nc\n" {
+		t.Errorf("unedited file should be replaced, got %s", got)
+	}
+
+	os.WriteFile(path, []byte("mine\nb\nc\n"), 0644)
+	if got := refresh("a\nb\nc\n", Options{}); got != Unchanged || read(path) != "mine\nb\nc\n" {
+		t.Errorf("edits without an upstream change should stay, got %s", got)
+	}
+	if got := refresh("a\nb\nc\nd\n", Options{}); got != Merged || read(path) != "mine\nb\nc\nd\n" {
+		t.Errorf("merge = %s %q", got, read(path))
+	}
+	if got := refresh("theirs\nb\nc\nd\n", Options{}); got != Conflict || read(path) != "mine\nb\nc\nd\n" {
+		t.Errorf("conflict should leave the file alone, got %s", got)
+	}
+	if !strings.Contains(read(NewPath(path)), "<<<<<<< yours") {
+		t.Error("conflict should write the merge to .new")
+	}
+
+	// Files installed before bases were recorded
+	os.Remove(basePath)
+	if got := refresh("new\n", Options{}); got != Kept || read(NewPath(path)) != "new\n" || read(path) != "mine\nb\nc\nd\n" {
+		t.Errorf("untracked edited file = %s", got)
+	}
+	os.Remove(basePath)
+	if got := refresh("new\n", Options{OverwriteUntracked: true}); got != Updated || read(path) != "new\n" {
+		t.Errorf("OverwriteUntracked = %s", got)
+	}
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// Redaction types
+const (
+	RedactPhone      = "phone"
+	RedactEmail      = "email"
+	RedactCreditCard = "credit_card"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	phonePattern = regexp.MustCompile(`(?:\+|\b)\d(?:[\d\s().-]{6,18})\d\b`)
+)
+
+// filters returns the built-in filters enabled in cfg
+func filters(cfg *config.Config, provider providers.LLMProvider) []entry {
+	var out []entry
+	redact := cfg.Filter.Redact
+	if redact.Inbound || redact.Outbound {
+		r := NewRedactor(redact.Types)
+		var stages []string
+		if redact.Inbound {
+			stages = append(stages, StagePreLLM)
+		}
+		if redact.Outbound {
+			stages = append(stages, StagePreSend)
+		}
+		out = append(out, entry{
+			MiddlewareConfig: config.MiddlewareConfig{Name: "redact", Stages: stages},
+			handle:           r.handle,
+		})
+	}
+
+	if inj := cfg.Filter.Injection; inj.Enabled {
+		s := NewSanitizer(inj, provider, cfg.Agents.Defaults.Model)
+		out = append(out, entry{
+			MiddlewareConfig: config.MiddlewareConfig{Name: "injection", Stages: []string{StagePostTool}, Tools: inj.Tools},
+			handle:           s.handle,
+		})
+	}
+
+	mod := cfg.Filter.Moderation
+	if mod.Enabled && provider != nil {
+		m := &moderator{cfg: mod, provider: provider, model: mod.Model}
+		if m.model == "" {
+			m.model = cfg.Agents.Defaults.Model
+		}
+		out = append(out, entry{
+			// Fails closed: an unchecked message is not sent to a group
+			MiddlewareConfig: config.MiddlewareConfig{Name: "moderation", Stages: []string{StagePreSend}, OnError: "block"},
+			handle:           m.handle,
+		})
+	}
+	return out
+}
+
+// Redactor masks phone numbers, email addresses and credit card numbers
+type Redactor struct {
+	types map[string]bool
+}
+
+// NewRedactor masks the given types, or all of them when types is empty
+func NewRedactor(types []string) *Redactor {
+	if len(types) == 0 {
+		types = []string{RedactPhone, RedactEmail, RedactCreditCard}
+	}
+	r := &Redactor{types: map[string]bool{}}
+	for _, t := range types {
+		r.types[t] = true
+	}
+	return r
+}
+
+// Redact returns s with personal data replaced by placeholders like
+// [redacted email]
+func (r *Redactor) Redact(s string) string {
+	if r.types[RedactEmail] {
+		s = emailPattern.ReplaceAllString(s, "[redacted email]")
+	}
+	// Cards before phones, which would match their digits too
+	if r.types[RedactCreditCard] {
+		s = cardPattern.ReplaceAllStringFunc(s, func(m string) string {
+			if luhn(m) {
+				return "[redacted card]"
+			}
+			return m
+		})
+	}
+	if r.types[RedactPhone] {
+		s = phonePattern.ReplaceAllStringFunc(s, func(m string) string {
+			if isPhone(m) {
+				return "[redacted phone]"
+			}
+			return m
+		})
+	}
+	return s
+}
+
+func (r *Redactor) handle(ctx context.Context, e *Event) (Response, error) {
+	switch e.Stage {
+	case StagePreLLM:
+		messages := make([]providers.Message, len(e.Messages))
+		for i, m := range e.Messages {
+			if m.Role == "user" {
+				m.Content = r.redactContent(m.Content)
+			}
+			messages[i] = m
+		}
+		return Response{Messages: messages}, nil
+	case StagePreSend:
+		content := r.Redact(e.Content)
+		return Response{Content: &content}, nil
+	}
+	return Response{}, nil
+}
+
+func (r *Redactor) redactContent(content interface{}) interface{} {
+	switch c := content.(type) {
+	case string:
+		return r.Redact(c)
+	case []providers.ContentBlock:
+		blocks := make([]providers.ContentBlock, len(c))
+		for i, b := range c {
+			if b.Type == "text" {
+				b.Text = r.Redact(b.Text)
+			}
+			blocks[i] = b
+		}
+		return blocks
+	}
+	return content
+}
+
+// luhn reports whether the digits of s pass the card number checksum
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// isPhone tells phone numbers from other digit runs: they have 9 to 15
+// digits, or start with + and have at least 8. Dates and times are left
+// alone.
+func isPhone(s string) bool {
+	digits := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits > 15 {
+		return false
+	}
+	if strings.HasPrefix(s, "+") {
+		return digits >= 8
+	}
+	if datePattern.MatchString(s) {
+		return false
+	}
+	return digits >= 9
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}|^\d{1,2}[./-]\d{1,2}[./-]\d{2,4}`)
+
+// moderator asks a model whether a message may be posted to a group chat
+type moderator struct {
+	cfg      config.ModerationConfig
+	provider providers.LLMProvider
+	model    string
+}
+
+const moderationPrompt = `You review messages a chat bot is about to post in a public group chat. Reply with SAFE if the message is fine to post. Reply with UNSAFE followed by a short reason if it contains hate, harassment, sexual content, violence, self-harm encouragement, or private personal data.
+
+Message:
+%s`
+
+func (m *moderator) handle(ctx context.Context, e *Event) (Response, error) {
+	if !m.applies(e.Channel, e.ChatID) || strings.TrimSpace(e.Content) == "" {
+		return Response{}, nil
+	}
+	resp, err := m.provider.Chat(ctx, []providers.Message{
+		{Role: "user", Content: fmt.Sprintf(moderationPrompt, e.Content)},
+	}, nil, m.model, map[string]interface{}{
+		"max_tokens":  100,
+		"temperature": 0.0,
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("moderation check failed: %w", err)
+	}
+	verdict := strings.TrimSpace(resp.Content)
+	if !strings.HasPrefix(strings.ToUpper(verdict), "UNSAFE") {
+		return Response{}, nil
+	}
+
+	reason := strings.TrimLeft(verdict[len("UNSAFE"):], " :-\n")
+	logger.WarnCF("middleware", "Moderation flagged outbound message", map[string]interface{}{
+		"channel": e.Channel,
+		"chat_id": e.ChatID,
+		"reason":  reason,
+	})
+	if m.cfg.Replacement != "" {
+		replacement := m.cfg.Replacement
+		return Response{Content: &replacement}, nil
+	}
+	return Response{Block: true, Reason: reason}, nil
+}
+
+// applies reports whether messages to the chat are checked: group chats
+// that can be told apart by their ID, and the configured channels and chats
+func (m *moderator) applies(channel, chatID string) bool {
+	if contains(m.cfg.Channels, channel) || contains(m.cfg.ChatIDs, chatID) {
+		return true
+	}
+	return IsGroupChat(channel, chatID)
+}
+
+// IsGroupChat recognizes group chats from their ID: negative Telegram chat
+// IDs and WhatsApp group JIDs. Other channels' IDs don't say.
+func IsGroupChat(channel, chatID string) bool {
+	switch channel {
+	case "telegram":
+		return strings.HasPrefix(chatID, "-")
+	case "whatsapp":
+		return strings.HasSuffix(chatID, "@g.us")
+	}
+	return false
+}
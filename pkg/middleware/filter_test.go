@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestRedact(t *testing.T) {
+	r := NewRedactor(nil)
+	tests := []struct {
+		in, want string
+	}{
+		{"mail me at jane.doe@example.co.uk", "mail me at [redacted email]"},
+		{"call +62 812-3456-7890 now", "call [redacted phone] now"},
+		{"office 021 555 0199", "office [redacted phone]"},
+		{"card 4111 1111 1111 1111 exp", "card [redacted card] exp"},
+		{"order 4111 1111 1111 1112", "order 4111 1111 1111 1112"}, // fails the checksum, too long for a phone
+		{"meeting on 2026-10-15 at 14:30", "meeting on 2026-10-15 at 14:30"},
+		{"it costs 12500 rupiah", "it costs 12500 rupiah"},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	emailsOnly := NewRedactor([]string{RedactEmail})
+	if got := emailsOnly.Redact("a@b.io +628123456789"); got != "[redacted email] +628123456789" {
+		t.Errorf("types not honoured: %q", got)
+	}
+}
+
+func TestRedactBothDirections(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Filter.Redact = config.RedactConfig{Inbound: true, Outbound: true}
+	chain := New(cfg, nil)
+
+	fake := &fakeProvider{}
+	messages := []providers.Message{
+		{Role: "system", Content: "support@pepebot.dev is our address"},
+		{Role: "user", Content: "my email is me@home.net"},
+	}
+	if _, err := WrapProvider(fake, chain).Chat(context.Background(), messages, nil, "m", nil); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if fake.messages[0].Content != "support@pepebot.dev is our address" || fake.messages[1].Content != "my email is [redacted email]" {
+		t.Fatalf("only user messages should be masked, got %+v", fake.messages)
+	}
+	if messages[1].Content != "my email is me@home.net" {
+		t.Fatal("the caller's messages were modified")
+	}
+
+	msg, err := chain.BeforeSend(context.Background(), bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "Sent to me@home.net"})
+	if err != nil || msg.Content != "Sent to [redacted email]" {
+		t.Fatalf("expected outbound masking, got %q, %v", msg.Content, err)
+	}
+}
+
+func TestModerationChecksGroupChats(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Filter.Moderation = config.ModerationConfig{Enabled: true, ChatIDs: []string{"dev-room"}}
+	judge := &fakeProvider{reply: "UNSAFE: insults a member"}
+	chain := New(cfg, judge)
+
+	// Private chats are not checked
+	private := bus.OutboundMessage{Channel: "telegram", ChatID: "12345", Content: "you fool"}
+	if _, err := chain.BeforeSend(context.Background(), private); err != nil || judge.messages != nil {
+		t.Fatalf("private chat should not be moderated, got %v", err)
+	}
+
+	for _, chat := range []bus.OutboundMessage{
+		{Channel: "telegram", ChatID: "-100123", Content: "you fool"},
+		{Channel: "whatsapp", ChatID: "1203@g.us", Content: "you fool"},
+		{Channel: "discord", ChatID: "dev-room", Content: "you fool"},
+	} {
+		if _, err := chain.BeforeSend(context.Background(), chat); !IsBlocked(err) {
+			t.Fatalf("expected %s/%s to be blocked, got %v", chat.Channel, chat.ChatID, err)
+		}
+	}
+
+	judge.reply = "SAFE"
+	if _, err := chain.BeforeSend(context.Background(), bus.OutboundMessage{Channel: "telegram", ChatID: "-100123", Content: "hello all"}); err != nil {
+		t.Fatalf("safe message blocked: %v", err)
+	}
+
+	cfg.Filter.Moderation.Replacement = "(message withheld)"
+	judge.reply = "UNSAFE"
+	msg, err := New(cfg, judge).BeforeSend(context.Background(), bus.OutboundMessage{Channel: "telegram", ChatID: "-1", Content: "you fool"})
+	if err != nil || msg.Content != "(message withheld)" {
+		t.Fatalf("expected the replacement, got %q, %v", msg.Content, err)
+	}
+}
@@ -0,0 +1,342 @@
+// Package middleware runs user scripts and webhooks on agent traffic. A
+// middleware registered for a stage receives each event of that stage as
+// JSON and may answer with changes to it or block it, which allows custom
+// moderation, scrubbing and logging without changing pepebot. The built-in
+// PII redaction and moderation filters run as middleware too.
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// Stages
+const (
+	StagePreLLM   = "pre_llm"   // messages about to be sent to the model
+	StagePostLLM  = "post_llm"  // the model's reply
+	StagePreTool  = "pre_tool"  // a tool call about to run
+	StagePostTool = "post_tool" // a tool call's result
+	StagePreSend  = "pre_send"  // a message about to be sent to a channel
+)
+
+// Stages lists the valid stages in the order traffic passes them
+var Stages = []string{StagePreLLM, StagePostLLM, StagePreTool, StagePostTool, StagePreSend}
+
+// DefaultTimeout bounds a middleware call without timeout_s
+const DefaultTimeout = 10 * time.Second
+
+// blockExitCode is the script exit status that blocks the event, with
+// stderr as the reason
+const blockExitCode = 2
+
+// Event is what a middleware receives. Fields that don't apply to the stage
+// are left out.
+type Event struct {
+	Stage   string `json:"stage"`
+	Agent   string `json:"agent,omitempty"`
+	Session string `json:"session,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chat_id,omitempty"`
+
+	Model     string               `json:"model,omitempty"`      // pre_llm, post_llm
+	Messages  []providers.Message  `json:"messages,omitempty"`   // pre_llm
+	Content   string               `json:"content,omitempty"`    // post_llm reply, pre_send text
+	ToolCalls []providers.ToolCall `json:"tool_calls,omitempty"` // post_llm, read only
+
+	Tool   string                 `json:"tool,omitempty"`   // pre_tool, post_tool
+	Args   map[string]interface{} `json:"args,omitempty"`   // pre_tool, post_tool
+	Result string                 `json:"result,omitempty"` // post_tool
+	Error  string                 `json:"error,omitempty"`  // post_tool
+}
+
+// Response is what a middleware may answer. An empty answer lets the event
+// through unchanged; set fields replace the matching event fields.
+type Response struct {
+	Block    bool                   `json:"block,omitempty"`
+	Reason   string                 `json:"reason,omitempty"`
+	Messages []providers.Message    `json:"messages,omitempty"`
+	Content  *string                `json:"content,omitempty"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+	Result   *string                `json:"result,omitempty"`
+}
+
+// BlockedError is returned when a middleware blocks an event
+type BlockedError struct {
+	Middleware string
+	Stage      string
+	Reason     string
+}
+
+func (e *BlockedError) Error() string {
+	reason := e.Reason
+	if reason == "" {
+		reason = "no reason given"
+	}
+	return fmt.Sprintf("blocked by middleware '%s' at %s: %s", e.Middleware, e.Stage, reason)
+}
+
+// IsBlocked reports whether err comes from a blocking middleware
+func IsBlocked(err error) bool {
+	var blocked *BlockedError
+	return errors.As(err, &blocked)
+}
+
+// Chain runs the built-in filters and the configured middleware in order
+type Chain struct {
+	entries []entry
+	client  *http.Client
+}
+
+// entry is one middleware of the chain. Built-in filters handle events
+// themselves; the others are called as scripts or webhooks.
+type entry struct {
+	config.MiddlewareConfig
+	handle func(ctx context.Context, e *Event) (Response, error)
+}
+
+// New builds the chain of cfg's built-in filters and enabled middleware.
+// provider runs the moderation check. Invalid middleware are logged and
+// skipped. It returns nil when nothing is enabled.
+func New(cfg *config.Config, provider providers.LLMProvider) *Chain {
+	c := &Chain{client: &http.Client{}}
+	c.entries = append(c.entries, filters(cfg, provider)...)
+	for _, m := range cfg.Middleware {
+		if m.Disabled {
+			continue
+		}
+		if err := Validate(m); err != nil {
+			logger.WarnCF("middleware", "Skipping middleware", map[string]interface{}{
+				"name":  m.Name,
+				"error": err.Error(),
+			})
+			continue
+		}
+		c.entries = append(c.entries, entry{MiddlewareConfig: m})
+	}
+	if len(c.entries) == 0 {
+		return nil
+	}
+	return c
+}
+
+// Validate checks a middleware definition
+func Validate(m config.MiddlewareConfig) error {
+	if m.Name == "" {
+		return fmt.Errorf("middleware needs a name")
+	}
+	if (m.Command == "") == (m.URL == "") {
+		return fmt.Errorf("set either command or url")
+	}
+	if len(m.Stages) == 0 {
+		return fmt.Errorf("no stages set")
+	}
+	for _, stage := range m.Stages {
+		if !contains(Stages, stage) {
+			return fmt.Errorf("unknown stage %q (use %s)", stage, strings.Join(Stages, ", "))
+		}
+	}
+	if m.OnError != "" && m.OnError != "allow" && m.OnError != "block" {
+		return fmt.Errorf("on_error must be allow or block")
+	}
+	return nil
+}
+
+// Has reports whether any middleware runs at one of stages
+func (c *Chain) Has(stages ...string) bool {
+	if c == nil {
+		return false
+	}
+	for _, m := range c.entries {
+		for _, stage := range stages {
+			if contains(m.Stages, stage) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Run passes e through the middleware of its stage, applying their changes
+// to e. It returns a *BlockedError when one of them blocks it.
+func (c *Chain) Run(ctx context.Context, e *Event) error {
+	if c == nil {
+		return nil
+	}
+	for _, m := range c.entries {
+		if !c.applies(m, e) {
+			continue
+		}
+		var resp Response
+		var err error
+		if m.handle != nil {
+			resp, err = m.handle(ctx, e)
+		} else {
+			resp, err = c.call(ctx, m.MiddlewareConfig, e)
+		}
+		if err != nil {
+			var blocked *BlockedError
+			if errors.As(err, &blocked) {
+				return err
+			}
+			logger.WarnCF("middleware", "Middleware failed", map[string]interface{}{
+				"name":  m.Name,
+				"stage": e.Stage,
+				"error": err.Error(),
+			})
+			if m.OnError == "block" {
+				return &BlockedError{Middleware: m.Name, Stage: e.Stage, Reason: err.Error()}
+			}
+			continue
+		}
+		if resp.Block {
+			logger.InfoCF("middleware", "Middleware blocked event", map[string]interface{}{
+				"name":    m.Name,
+				"stage":   e.Stage,
+				"session": e.Session,
+				"reason":  resp.Reason,
+			})
+			return &BlockedError{Middleware: m.Name, Stage: e.Stage, Reason: resp.Reason}
+		}
+		apply(e, resp)
+	}
+	return nil
+}
+
+func (c *Chain) applies(m entry, e *Event) bool {
+	if !contains(m.Stages, e.Stage) {
+		return false
+	}
+	if len(m.Agents) > 0 && !contains(m.Agents, e.Agent) {
+		return false
+	}
+	if e.Tool != "" && len(m.Tools) > 0 && !contains(m.Tools, e.Tool) {
+		return false
+	}
+	return true
+}
+
+// apply copies the changes a middleware made for the event's stage
+func apply(e *Event, resp Response) {
+	switch e.Stage {
+	case StagePreLLM:
+		if resp.Messages != nil {
+			e.Messages = resp.Messages
+		}
+	case StagePostLLM, StagePreSend:
+		if resp.Content != nil {
+			e.Content = *resp.Content
+		}
+	case StagePreTool:
+		if resp.Args != nil {
+			e.Args = resp.Args
+		}
+	case StagePostTool:
+		if resp.Result != nil {
+			e.Result = *resp.Result
+		}
+	}
+}
+
+func (c *Chain) call(ctx context.Context, m config.MiddlewareConfig, e *Event) (Response, error) {
+	var resp Response
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return resp, err
+	}
+
+	timeout := DefaultTimeout
+	if m.TimeoutS > 0 {
+		timeout = time.Duration(m.TimeoutS) * time.Second
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out []byte
+	if m.Command != "" {
+		out, err = runCommand(callCtx, m, e.Stage, payload)
+	} else {
+		out, err = c.post(callCtx, m, payload)
+	}
+	if err != nil {
+		return resp, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return resp, nil
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return resp, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp, nil
+}
+
+// runCommand runs a script with the event on stdin. Exit status 2 blocks
+// the event with stderr as the reason.
+func runCommand(ctx context.Context, m config.MiddlewareConfig, stage string, payload []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", m.Command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(cmd.Environ(), "PEPEBOT_MIDDLEWARE_STAGE="+stage)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == blockExitCode {
+		return nil, &BlockedError{Middleware: m.Name, Stage: stage, Reason: strings.TrimSpace(stderr.String())}
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out")
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func (c *Chain) post(ctx context.Context, m config.MiddlewareConfig, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range m.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("webhook returned %s", res.Status)
+	}
+	return body, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestScriptBlocksToolWithExitCode(t *testing.T) {
+	chain := chainOf([]config.MiddlewareConfig{{
+		Name:    "no-rm",
+		Stages:  []string{StagePreTool},
+		Tools:   []string{"exec"},
+		Command: `grep -q '"rm ' && { echo "deleting files is not allowed" >&2; exit 2; }; exit 0`,
+	}})
+
+	_, err := chain.BeforeTool(context.Background(), "exec", map[string]interface{}{"command": "rm -rf build"})
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.Middleware != "no-rm" || blocked.Reason != "deleting files is not allowed" {
+		t.Fatalf("expected the call to be blocked, got %v", err)
+	}
+
+	args, err := chain.BeforeTool(context.Background(), "exec", map[string]interface{}{"command": "ls"})
+	if err != nil || args["command"] != "ls" {
+		t.Fatalf("harmless command should pass unchanged, got %v, %v", args, err)
+	}
+
+	// Other tools are not sent to the script
+	if _, err := chain.BeforeTool(context.Background(), "read_file", map[string]interface{}{"path": "rm x"}); err != nil {
+		t.Fatalf("tool filter ignored: %v", err)
+	}
+}
+
+func TestScriptRewritesToolResult(t *testing.T) {
+	chain := chainOf([]config.MiddlewareConfig{{
+		Name:    "scrub",
+		Stages:  []string{StagePostTool},
+		Command: `echo '{"result": "[redacted]"}'`,
+	}})
+	result, err := chain.AfterTool(context.Background(), "read_file", nil, "secret", nil)
+	if err != nil || result != "[redacted]" {
+		t.Fatalf("expected the replaced result, got %q, %v", result, err)
+	}
+}
+
+func TestWebhookRewritesPrompt(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0k" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		msgs := append(got.Messages, providers.Message{Role: "system", Content: "Be brief."})
+		json.NewEncoder(w).Encode(Response{Messages: msgs})
+	}))
+	defer server.Close()
+
+	chain := chainOf([]config.MiddlewareConfig{{
+		Name:    "policy",
+		Stages:  []string{StagePreLLM},
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer t0k"},
+	}})
+	fake := &fakeProvider{}
+	p := WrapProvider(fake, chain)
+
+	ctx := WithSource(context.Background(), Source{Agent: "default", Session: "telegram:1", Channel: "telegram", ChatID: "1"})
+	if _, err := p.Chat(ctx, []providers.Message{{Role: "user", Content: "hi"}}, nil, "test-model", nil); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got.Stage != StagePreLLM || got.Session != "telegram:1" || got.Agent != "default" || got.Model != "test-model" {
+		t.Fatalf("unexpected event %+v", got)
+	}
+	if len(fake.messages) != 2 || fake.messages[1].Content != "Be brief." {
+		t.Fatalf("provider did not get the rewritten messages: %+v", fake.messages)
+	}
+}
+
+func TestPostLLMAndPreSend(t *testing.T) {
+	chain := chainOf([]config.MiddlewareConfig{
+		{Name: "upper", Stages: []string{StagePostLLM}, Command: `echo '{"content": "HELLO"}'`},
+		{Name: "sign", Stages: []string{StagePreSend}, Command: `sed 's/.*"content":"\([^"]*\)".*/{"content": "\1 -- bot"}/'`},
+	})
+
+	resp, err := WrapProvider(&fakeProvider{reply: "hello"}, chain).Chat(context.Background(), nil, nil, "m", nil)
+	if err != nil || resp.Content != "HELLO" {
+		t.Fatalf("expected the reply rewritten, got %+v, %v", resp, err)
+	}
+
+	msg, err := chain.BeforeSend(context.Background(), bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "done"})
+	if err != nil || msg.Content != "done -- bot" || msg.ChatID != "1" {
+		t.Fatalf("expected the message rewritten, got %+v, %v", msg, err)
+	}
+}
+
+func TestOnErrorPolicy(t *testing.T) {
+	failing := config.MiddlewareConfig{Name: "down", Stages: []string{StagePreSend}, Command: "exit 1"}
+	msg := bus.OutboundMessage{Channel: "cli", ChatID: "x", Content: "hi"}
+
+	if _, err := chainOf([]config.MiddlewareConfig{failing}).BeforeSend(context.Background(), msg); err != nil {
+		t.Fatalf("failures should be allowed by default, got %v", err)
+	}
+	failing.OnError = "block"
+	if _, err := chainOf([]config.MiddlewareConfig{failing}).BeforeSend(context.Background(), msg); !IsBlocked(err) {
+		t.Fatalf("expected on_error block to block, got %v", err)
+	}
+}
+
+func TestNewSkipsInvalidAndDisabled(t *testing.T) {
+	chain := chainOf([]config.MiddlewareConfig{
+		{Name: "bad-stage", Stages: []string{"pre_everything"}, Command: "cat"},
+		{Name: "both", Stages: []string{StagePreLLM}, Command: "cat", URL: "http://x"},
+		{Name: "off", Stages: []string{StagePreLLM}, Command: "cat", Disabled: true},
+	})
+	if chain != nil {
+		t.Fatalf("expected no middleware, got %+v", chain.entries)
+	}
+	var nilChain *Chain
+	if nilChain.Has(StagePreLLM) {
+		t.Fatal("a nil chain has no stages")
+	}
+	p := &fakeProvider{}
+	if WrapProvider(p, nil) != providers.LLMProvider(p) {
+		t.Fatal("provider should not be wrapped without middleware")
+	}
+	if !strings.Contains(Validate(config.MiddlewareConfig{Name: "x", Command: "cat"}).Error(), "stages") {
+		t.Fatal("expected a missing stages error")
+	}
+}
+
+type fakeProvider struct {
+	messages []providers.Message
+	reply    string
+}
+
+func (f *fakeProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	f.messages = messages
+	return &providers.LLMResponse{Content: f.reply}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, messages []providers.Message, model string, options map[string]interface{}, callback providers.StreamCallback) error {
+	f.messages = messages
+	callback(providers.StreamChunk{Content: f.reply, Done: true})
+	return nil
+}
+
+func (f *fakeProvider) GetDefaultModel() string {
+	return "fake"
+}
+
+func chainOf(middleware []config.MiddlewareConfig) *Chain {
+	return New(&config.Config{Middleware: middleware}, nil)
+}
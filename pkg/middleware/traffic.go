@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// Source identifies the conversation traffic belongs to
+type Source struct {
+	Agent   string
+	Session string
+	Channel string
+	ChatID  string
+}
+
+type sourceContextKey struct{}
+
+// WithSource tags ctx with the conversation being handled
+func WithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceContextKey{}, src)
+}
+
+// event starts an event of stage for the conversation in ctx
+func event(ctx context.Context, stage string) *Event {
+	src, _ := ctx.Value(sourceContextKey{}).(Source)
+	return &Event{
+		Stage:   stage,
+		Agent:   src.Agent,
+		Session: src.Session,
+		Channel: src.Channel,
+		ChatID:  src.ChatID,
+	}
+}
+
+// BeforeTool runs the pre_tool middleware and returns the arguments to
+// call the tool with
+func (c *Chain) BeforeTool(ctx context.Context, name string, args map[string]interface{}) (map[string]interface{}, error) {
+	if !c.Has(StagePreTool) {
+		return args, nil
+	}
+	e := event(ctx, StagePreTool)
+	e.Tool, e.Args = name, args
+	if err := c.Run(ctx, e); err != nil {
+		return nil, err
+	}
+	return e.Args, nil
+}
+
+// AfterTool runs the post_tool middleware and returns the result to give
+// the model
+func (c *Chain) AfterTool(ctx context.Context, name string, args map[string]interface{}, result string, callErr error) (string, error) {
+	if !c.Has(StagePostTool) {
+		return result, callErr
+	}
+	e := event(ctx, StagePostTool)
+	e.Tool, e.Args, e.Result = name, args, result
+	if callErr != nil {
+		e.Error = callErr.Error()
+	}
+	if err := c.Run(ctx, e); err != nil {
+		return "", err
+	}
+	return e.Result, callErr
+}
+
+// BeforeSend runs the pre_send middleware on a message for a channel
+func (c *Chain) BeforeSend(ctx context.Context, msg bus.OutboundMessage) (bus.OutboundMessage, error) {
+	if !c.Has(StagePreSend) {
+		return msg, nil
+	}
+	e := event(ctx, StagePreSend)
+	e.Channel, e.ChatID, e.Content = msg.Channel, msg.ChatID, msg.Content
+	if e.Session == "" {
+		e.Session = msg.Channel + ":" + msg.ChatID
+	}
+	if err := c.Run(ctx, e); err != nil {
+		return msg, err
+	}
+	msg.Content = e.Content
+	return msg, nil
+}
+
+// WrapProvider runs the pre_llm and post_llm middleware of c around the
+// calls to p. It returns p itself when there are none.
+func WrapProvider(p providers.LLMProvider, c *Chain) providers.LLMProvider {
+	if !c.Has(StagePreLLM, StagePostLLM) {
+		return p
+	}
+	return &provider{LLMProvider: p, chain: c}
+}
+
+type provider struct {
+	providers.LLMProvider
+	chain *Chain
+}
+
+func (p *provider) before(ctx context.Context, messages []providers.Message, model string) ([]providers.Message, error) {
+	if !p.chain.Has(StagePreLLM) {
+		return messages, nil
+	}
+	e := event(ctx, StagePreLLM)
+	e.Model, e.Messages = model, messages
+	if err := p.chain.Run(ctx, e); err != nil {
+		return nil, err
+	}
+	return e.Messages, nil
+}
+
+func (p *provider) after(ctx context.Context, resp *providers.LLMResponse, model string) error {
+	if resp == nil || !p.chain.Has(StagePostLLM) {
+		return nil
+	}
+	e := event(ctx, StagePostLLM)
+	e.Model, e.Content, e.ToolCalls = model, resp.Content, resp.ToolCalls
+	if err := p.chain.Run(ctx, e); err != nil {
+		return err
+	}
+	resp.Content = e.Content
+	return nil
+}
+
+func (p *provider) Chat(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	messages, err := p.before(ctx, messages, model)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.LLMProvider.Chat(ctx, messages, toolDefs, model, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.after(ctx, resp, model); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ChatStream can only show post_llm middleware the reply once it has been
+// streamed; they may still block it, but their changes are not applied
+func (p *provider) ChatStream(ctx context.Context, messages []providers.Message, model string, options map[string]interface{}, callback providers.StreamCallback) error {
+	messages, err := p.before(ctx, messages, model)
+	if err != nil {
+		return err
+	}
+	var content strings.Builder
+	err = p.LLMProvider.ChatStream(ctx, messages, model, options, func(chunk providers.StreamChunk) {
+		content.WriteString(chunk.Content)
+		callback(chunk)
+	})
+	if err != nil {
+		return err
+	}
+	return p.after(ctx, &providers.LLMResponse{Content: content.String()}, model)
+}
+
+func (p *provider) ChatStreamWithTools(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, model string, options map[string]interface{}, callback providers.StreamCallback) (*providers.LLMResponse, error) {
+	messages, err := p.before(ctx, messages, model)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := providers.StreamWithTools(ctx, p.LLMProvider, messages, toolDefs, model, options, callback)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.after(ctx, resp, model); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
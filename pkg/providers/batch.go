@@ -0,0 +1,396 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// BatchRequest is one chat request in a provider batch. Requests in a batch
+// must not use tools.
+type BatchRequest struct {
+	ID          string    `json:"id"` // letters, digits, - and _ (at most 64)
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+}
+
+// BatchResult is the outcome of one request of a finished batch
+type BatchResult struct {
+	ID       string
+	Response *LLMResponse
+	Error    string
+}
+
+// Batcher is implemented by providers with an asynchronous batch API, which
+// answers within 24 hours at about half the price of regular requests
+type Batcher interface {
+	// SubmitBatch sends requests as one batch and returns its ID
+	SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error)
+	// BatchResults returns done=false while the batch is still running, and
+	// the results of all requests once it has ended
+	BatchResults(ctx context.Context, id string) (results []BatchResult, done bool, err error)
+}
+
+// BatcherFor returns the batch API that serves model, and the model name to
+// use with it. ok is false when the provider has no batch API.
+func BatcherFor(provider LLMProvider, model string) (b Batcher, batchModel string, ok bool) {
+	if r, isRouter := provider.(*Router); isRouter {
+		target := r.Resolve(model, nil, nil)
+		p, err := r.provider(model, target)
+		if err != nil {
+			return nil, "", false
+		}
+		provider, model = p, target.Model
+	}
+	if hp, isHTTP := provider.(*HTTPProvider); isHTTP && !hp.supportsBatches() {
+		return nil, "", false
+	}
+	b, ok = provider.(Batcher)
+	return b, model, ok
+}
+
+// supportsBatches reports whether the API behind apiBase has a batch
+// endpoint. Routers such as OpenRouter do not.
+func (p *HTTPProvider) supportsBatches() bool {
+	return p.isAnthropic() || strings.Contains(p.apiBase, "api.openai.com")
+}
+
+func (p *HTTPProvider) isAnthropic() bool {
+	return strings.Contains(p.apiBase, "anthropic.com")
+}
+
+func (p *HTTPProvider) SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	if len(requests) == 0 {
+		return "", fmt.Errorf("empty batch")
+	}
+	if p.isAnthropic() {
+		return p.submitAnthropicBatch(ctx, requests)
+	}
+	return p.submitOpenAIBatch(ctx, requests)
+}
+
+func (p *HTTPProvider) BatchResults(ctx context.Context, id string) ([]BatchResult, bool, error) {
+	if p.isAnthropic() {
+		return p.anthropicBatchResults(ctx, id)
+	}
+	return p.openAIBatchResults(ctx, id)
+}
+
+// batchCall sends a request to the provider's API and decodes the JSON reply
+// into out, or returns the raw body when out is nil
+func (p *HTTPProvider) batchCall(ctx context.Context, method, url, contentType string, body io.Reader, out interface{}) ([]byte, error) {
+	if !strings.HasPrefix(url, "http") {
+		url = p.apiBase + url
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if p.isAnthropic() {
+		req.Header.Set("x-api-key", p.apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	} else if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, truncateString(string(data), 500))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// ==================== OpenAI Batch API ====================
+
+func (p *HTTPProvider) submitOpenAIBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	var lines bytes.Buffer
+	for _, r := range requests {
+		body := map[string]interface{}{
+			"model":    r.Model,
+			"messages": r.Messages,
+		}
+		if r.MaxTokens > 0 {
+			body["max_tokens"] = r.MaxTokens
+		}
+		if r.Temperature != nil && !openAIReasoningModel(r.Model) {
+			body["temperature"] = *r.Temperature
+		}
+		line, err := json.Marshal(map[string]interface{}{
+			"custom_id": r.ID,
+			"method":    "POST",
+			"url":       "/v1/chat/completions",
+			"body":      body,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal request %s: %w", r.ID, err)
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	w.WriteField("purpose", "batch")
+	part, err := w.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	part.Write(lines.Bytes())
+	w.Close()
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if _, err := p.batchCall(ctx, "POST", "/files", w.FormDataContentType(), &form, &file); err != nil {
+		return "", fmt.Errorf("failed to upload batch file: %w", err)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	var batch struct {
+		ID string `json:"id"`
+	}
+	if _, err := p.batchCall(ctx, "POST", "/batches", "application/json", bytes.NewReader(payload), &batch); err != nil {
+		return "", fmt.Errorf("failed to create batch: %w", err)
+	}
+	return batch.ID, nil
+}
+
+func (p *HTTPProvider) openAIBatchResults(ctx context.Context, id string) ([]BatchResult, bool, error) {
+	var batch struct {
+		Status       string `json:"status"`
+		OutputFileID string `json:"output_file_id"`
+		ErrorFileID  string `json:"error_file_id"`
+		Errors       *struct {
+			Data []struct {
+				Message string `json:"message"`
+			} `json:"data"`
+		} `json:"errors"`
+	}
+	if _, err := p.batchCall(ctx, "GET", "/batches/"+id, "", nil, &batch); err != nil {
+		return nil, false, err
+	}
+
+	switch batch.Status {
+	case "completed", "expired", "cancelled":
+	case "failed":
+		reason := "batch failed"
+		if batch.Errors != nil && len(batch.Errors.Data) > 0 {
+			reason = batch.Errors.Data[0].Message
+		}
+		return nil, true, fmt.Errorf("%s", reason)
+	default:
+		return nil, false, nil
+	}
+
+	// Requests that were not run (expired or cancelled) are missing from
+	// both files and are reported as failed by the caller
+	var results []BatchResult
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		data, err := p.batchCall(ctx, "GET", "/files/"+fileID+"/content", "", nil, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to download batch results: %w", err)
+		}
+		results = append(results, p.parseOpenAIBatchOutput(data)...)
+	}
+	return results, true, nil
+}
+
+func (p *HTTPProvider) parseOpenAIBatchOutput(data []byte) []BatchResult {
+	var results []BatchResult
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Response *struct {
+				StatusCode int             `json:"status_code"`
+				Body       json.RawMessage `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &line) != nil || line.CustomID == "" {
+			continue
+		}
+
+		result := BatchResult{ID: line.CustomID}
+		switch {
+		case line.Error != nil:
+			result.Error = line.Error.Message
+		case line.Response == nil:
+			result.Error = "no response"
+		case line.Response.StatusCode != http.StatusOK:
+			result.Error = fmt.Sprintf("API error (%d): %s", line.Response.StatusCode, truncateString(string(line.Response.Body), 500))
+		default:
+			resp, err := p.parseResponse(line.Response.Body)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Response = resp
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ==================== Anthropic Message Batches ====================
+
+func (p *HTTPProvider) submitAnthropicBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	entries := make([]map[string]interface{}, 0, len(requests))
+	for _, r := range requests {
+		var system []string
+		var messages []map[string]interface{}
+		for _, m := range r.Messages {
+			text := getMessageContentString(m.Content)
+			if m.Role == "system" {
+				system = append(system, text)
+				continue
+			}
+			messages = append(messages, map[string]interface{}{"role": m.Role, "content": text})
+		}
+
+		maxTokens := r.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = 4096
+		}
+		params := map[string]interface{}{
+			"model":      strings.TrimPrefix(r.Model, "anthropic/"),
+			"max_tokens": maxTokens,
+			"messages":   messages,
+		}
+		if len(system) > 0 {
+			params["system"] = strings.Join(system, "\n\n")
+		}
+		if r.Temperature != nil {
+			params["temperature"] = *r.Temperature
+		}
+		entries = append(entries, map[string]interface{}{"custom_id": r.ID, "params": params})
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"requests": entries})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	var batch struct {
+		ID string `json:"id"`
+	}
+	if _, err := p.batchCall(ctx, "POST", "/messages/batches", "application/json", bytes.NewReader(payload), &batch); err != nil {
+		return "", fmt.Errorf("failed to create batch: %w", err)
+	}
+	return batch.ID, nil
+}
+
+func (p *HTTPProvider) anthropicBatchResults(ctx context.Context, id string) ([]BatchResult, bool, error) {
+	var batch struct {
+		ProcessingStatus string `json:"processing_status"`
+		ResultsURL       string `json:"results_url"`
+	}
+	if _, err := p.batchCall(ctx, "GET", "/messages/batches/"+id, "", nil, &batch); err != nil {
+		return nil, false, err
+	}
+	if batch.ProcessingStatus != "ended" {
+		return nil, false, nil
+	}
+	if batch.ResultsURL == "" {
+		return nil, true, nil
+	}
+
+	data, err := p.batchCall(ctx, "GET", batch.ResultsURL, "", nil, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to download batch results: %w", err)
+	}
+	return parseAnthropicBatchOutput(data), true, nil
+}
+
+func parseAnthropicBatchOutput(data []byte) []BatchResult {
+	var results []BatchResult
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string `json:"type"` // succeeded, errored, canceled or expired
+				Message struct {
+					Content []struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"content"`
+					StopReason string `json:"stop_reason"`
+					Usage      struct {
+						InputTokens  int `json:"input_tokens"`
+						OutputTokens int `json:"output_tokens"`
+					} `json:"usage"`
+				} `json:"message"`
+				Error struct {
+					Error struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &line) != nil || line.CustomID == "" {
+			continue
+		}
+
+		result := BatchResult{ID: line.CustomID}
+		switch line.Result.Type {
+		case "succeeded":
+			var text strings.Builder
+			for _, block := range line.Result.Message.Content {
+				if block.Type == "text" {
+					text.WriteString(block.Text)
+				}
+			}
+			usage := line.Result.Message.Usage
+			result.Response = &LLMResponse{
+				Content:      text.String(),
+				FinishReason: line.Result.Message.StopReason,
+				Usage: &UsageInfo{
+					PromptTokens:     usage.InputTokens,
+					CompletionTokens: usage.OutputTokens,
+					TotalTokens:      usage.InputTokens + usage.OutputTokens,
+				},
+			}
+		case "errored":
+			result.Error = line.Result.Error.Error.Message
+		default:
+			result.Error = "request " + line.Result.Type
+		}
+		results = append(results, result)
+	}
+	return results
+}
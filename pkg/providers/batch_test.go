@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProvider_OpenAIBatch(t *testing.T) {
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /files":
+			if r.FormValue("purpose") != "batch" {
+				t.Errorf("unexpected purpose %q", r.FormValue("purpose"))
+			}
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(file)
+			uploaded = string(data)
+			w.Write([]byte(`{"id":"file-in"}`))
+		case "POST /batches":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["input_file_id"] != "file-in" || body["endpoint"] != "/v1/chat/completions" {
+				t.Errorf("unexpected batch: %v", body)
+			}
+			w.Write([]byte(`{"id":"batch_1","status":"validating"}`))
+		case "GET /batches/batch_1":
+			w.Write([]byte(`{"id":"batch_1","status":"completed","output_file_id":"file-out","error_file_id":"file-err"}`))
+		case "GET /files/file-out/content":
+			w.Write([]byte(`{"custom_id":"a","response":{"status_code":200,"body":{"choices":[{"message":{"content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}}}` + "\n"))
+		case "GET /files/file-err/content":
+			w.Write([]byte(`{"custom_id":"b","response":{"status_code":400,"body":{"error":{"message":"bad"}}}}` + "\n"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL)
+	temperature := 0.1
+	id, err := p.SubmitBatch(context.Background(), []BatchRequest{
+		{ID: "a", Model: "gpt-4o-mini", Messages: []Message{{Role: "user", Content: "hi"}}, MaxTokens: 10, Temperature: &temperature},
+		{ID: "b", Model: "gpt-4o-mini", Messages: []Message{{Role: "user", Content: "bye"}}},
+	})
+	if err != nil || id != "batch_1" {
+		t.Fatalf("SubmitBatch: %q %v", id, err)
+	}
+	lines := strings.Split(strings.TrimSpace(uploaded), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"custom_id":"a"`) || !strings.Contains(lines[0], `"max_tokens":10`) {
+		t.Errorf("unexpected batch file:\n%s", uploaded)
+	}
+
+	results, done, err := p.BatchResults(context.Background(), id)
+	if err != nil || !done || len(results) != 2 {
+		t.Fatalf("BatchResults: %+v %v %v", results, done, err)
+	}
+	if results[0].ID != "a" || results[0].Response.Content != "hello" || results[0].Response.Usage.TotalTokens != 4 {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if results[1].ID != "b" || results[1].Error == "" {
+		t.Errorf("failed request should carry its error: %+v", results[1])
+	}
+}
+
+func TestParseAnthropicBatchOutput(t *testing.T) {
+	output := `{"custom_id":"a","result":{"type":"succeeded","message":{"content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}}}
+{"custom_id":"b","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"too long"}}}}
+{"custom_id":"c","result":{"type":"expired"}}`
+
+	results := parseAnthropicBatchOutput([]byte(output))
+	if len(results) != 3 {
+		t.Fatalf("got %d results", len(results))
+	}
+	if r := results[0]; r.Response == nil || r.Response.Content != "hi" || r.Response.Usage.TotalTokens != 7 {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if results[1].Error != "too long" || results[2].Error != "request expired" {
+		t.Errorf("unexpected errors: %q %q", results[1].Error, results[2].Error)
+	}
+}
+
+func TestBatcherFor(t *testing.T) {
+	if _, _, ok := BatcherFor(NewHTTPProvider("k", "https://api.openai.com/v1"), "gpt-4o-mini"); !ok {
+		t.Error("OpenAI should support batches")
+	}
+	if _, _, ok := BatcherFor(NewHTTPProvider("k", "https://api.anthropic.com/v1"), "claude-haiku"); !ok {
+		t.Error("Anthropic should support batches")
+	}
+	if _, _, ok := BatcherFor(NewHTTPProvider("k", "https://openrouter.ai/api/v1"), "openai/gpt-4o-mini"); ok {
+		t.Error("OpenRouter has no batch API")
+	}
+}
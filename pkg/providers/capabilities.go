@@ -0,0 +1,329 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// ModelCapabilities describes what a model accepts and returns
+type ModelCapabilities struct {
+	ContextWindow int  `json:"context_window"` // prompt and reply together, in tokens
+	MaxOutput     int  `json:"max_output"`     // longest reply, in tokens
+	Vision        bool `json:"vision"`
+	Tools         bool `json:"tools"`
+}
+
+// knownModels are the capabilities of common models, matched by the longest
+// prefix of the model name. Values fetched from a provider's /models
+// endpoint take precedence.
+var knownModels = map[string]ModelCapabilities{
+	"gpt-5":             {400000, 128000, true, true},
+	"gpt-4.1":           {1047576, 32768, true, true},
+	"gpt-4o":            {128000, 16384, true, true},
+	"gpt-4-turbo":       {128000, 4096, true, true},
+	"gpt-4":             {8192, 8192, false, true},
+	"gpt-3.5-turbo":     {16385, 4096, false, true},
+	"o1":                {200000, 100000, true, true},
+	"o3":                {200000, 100000, true, true},
+	"o4-mini":           {200000, 100000, true, true},
+	"claude":            {200000, 8192, true, true},
+	"claude-3-haiku":    {200000, 4096, true, true},
+	"claude-3-opus":     {200000, 4096, true, true},
+	"claude-3-7-sonnet": {200000, 64000, true, true},
+	"claude-sonnet-4":   {200000, 64000, true, true},
+	"claude-haiku-4":    {200000, 64000, true, true},
+	"claude-opus-4":     {200000, 32000, true, true},
+	"gemini":            {1048576, 8192, true, true},
+	"gemini-1.5-pro":    {2097152, 8192, true, true},
+	"gemini-2.5":        {1048576, 65536, true, true},
+	"llama-3.1":         {131072, 8192, false, true},
+	"llama-3.2":         {131072, 8192, false, true},
+	"llama-3.3":         {131072, 8192, false, true},
+	"llama-4":           {131072, 8192, true, true},
+	"deepseek-chat":     {65536, 8192, false, true},
+	"deepseek-reasoner": {65536, 32768, false, true},
+	"glm-4":             {128000, 4096, false, true},
+	"glm-4v":            {8192, 1024, true, false},
+	"qwen":              {32768, 8192, false, true},
+	"mistral":           {32768, 8192, false, true},
+	"mistral-large":     {131072, 8192, false, true},
+}
+
+// fetchedModels holds capabilities reported by providers' /models
+// endpoints, cached in ~/.pepebot/cache/models.json
+var fetchedModels = struct {
+	sync.RWMutex
+	loaded    bool
+	Models    map[string]ModelCapabilities
+	Refreshed map[string]time.Time // by API base
+}{}
+
+// capabilitiesRefreshInterval is how long fetched capabilities are trusted
+const capabilitiesRefreshInterval = 24 * time.Hour
+
+// capabilitiesCacheFile is a variable so tests can move it
+var capabilitiesCacheFile = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "pepebot-models.json")
+	}
+	return filepath.Join(home, ".pepebot", "cache", "models.json")
+}
+
+// normalizeModelName drops provider prefixes ("openrouter/anthropic/") and
+// writes versions with dashes, so "claude-3.5-sonnet" and
+// "claude-3-5-sonnet" match
+func normalizeModelName(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.ReplaceAll(strings.ToLower(model), ".", "-")
+}
+
+// Capabilities returns what is known about a model: values fetched from its
+// provider, or else the built-in table. ok is false for unknown models.
+func Capabilities(model string) (ModelCapabilities, bool) {
+	name := normalizeModelName(model)
+	if name == "" {
+		return ModelCapabilities{}, false
+	}
+
+	loadFetchedModels()
+	fetchedModels.RLock()
+	caps, ok := fetchedModels.Models[name]
+	fetchedModels.RUnlock()
+	if ok {
+		return caps, true
+	}
+	return knownCapabilities(name)
+}
+
+func knownCapabilities(name string) (ModelCapabilities, bool) {
+	best, found := "", ModelCapabilities{}
+	for prefix, caps := range knownModels {
+		p := normalizeModelName(prefix)
+		if strings.HasPrefix(name, p) && len(p) > len(best) {
+			best, found = p, caps
+		}
+	}
+	return found, best != ""
+}
+
+func loadFetchedModels() {
+	fetchedModels.Lock()
+	defer fetchedModels.Unlock()
+	if fetchedModels.loaded {
+		return
+	}
+	fetchedModels.loaded = true
+	fetchedModels.Models = make(map[string]ModelCapabilities)
+	fetchedModels.Refreshed = make(map[string]time.Time)
+
+	data, err := os.ReadFile(capabilitiesCacheFile())
+	if err != nil {
+		return
+	}
+	var cached struct {
+		Models    map[string]ModelCapabilities `json:"models"`
+		Refreshed map[string]time.Time         `json:"refreshed"`
+	}
+	if json.Unmarshal(data, &cached) != nil {
+		return
+	}
+	for name, caps := range cached.Models {
+		fetchedModels.Models[name] = caps
+	}
+	for base, at := range cached.Refreshed {
+		fetchedModels.Refreshed[base] = at
+	}
+}
+
+func saveFetchedModels() {
+	fetchedModels.RLock()
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"models":    fetchedModels.Models,
+		"refreshed": fetchedModels.Refreshed,
+	}, "", "  ")
+	fetchedModels.RUnlock()
+	if err != nil {
+		return
+	}
+	path := capabilitiesCacheFile()
+	if os.MkdirAll(filepath.Dir(path), 0755) != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, data, 0644) == nil {
+		os.Rename(tmp, path)
+	}
+}
+
+// RefreshCapabilities updates the capabilities of the models served by
+// provider from its /models endpoint, at most once a day per API base.
+// Providers whose listing has no sizes (such as OpenAI) leave the built-in
+// table in charge.
+func RefreshCapabilities(ctx context.Context, provider LLMProvider) {
+	var endpoints []*HTTPProvider
+	switch p := provider.(type) {
+	case *HTTPProvider:
+		endpoints = append(endpoints, p)
+	case *Router:
+		if hp, ok := p.fallback.(*HTTPProvider); ok {
+			endpoints = append(endpoints, hp)
+		}
+		p.mu.Lock()
+		for _, target := range p.targets {
+			if hp, ok := target.(*HTTPProvider); ok {
+				endpoints = append(endpoints, hp)
+			}
+		}
+		p.mu.Unlock()
+	}
+
+	loadFetchedModels()
+	for _, p := range endpoints {
+		fetchedModels.RLock()
+		last := fetchedModels.Refreshed[p.apiBase]
+		fetchedModels.RUnlock()
+		if time.Since(last) < capabilitiesRefreshInterval {
+			continue
+		}
+
+		models, err := p.fetchCapabilities(ctx)
+		if err != nil {
+			logger.DebugCF("provider", "Failed to fetch model capabilities", map[string]interface{}{
+				"api_base": p.apiBase,
+				"error":    err.Error(),
+			})
+			continue
+		}
+		fetchedModels.Lock()
+		for name, caps := range models {
+			fetchedModels.Models[name] = caps
+		}
+		fetchedModels.Refreshed[p.apiBase] = time.Now()
+		fetchedModels.Unlock()
+		saveFetchedModels()
+		logger.DebugCF("provider", "Model capabilities refreshed", map[string]interface{}{
+			"api_base": p.apiBase,
+			"models":   len(models),
+		})
+	}
+}
+
+// fetchCapabilities lists the provider's models with the sizes they report
+func (p *HTTPProvider) fetchCapabilities(ctx context.Context) (map[string]ModelCapabilities, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.apiBase, "/")+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.isAnthropic() {
+		req.Header.Set("x-api-key", p.apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	} else if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return parseModelList(body)
+}
+
+// parseModelList reads the sizes in a /models listing. It understands the
+// fields of OpenRouter, Anthropic, Groq, vLLM and Gemini; models without a
+// context size are skipped.
+func parseModelList(body []byte) (map[string]ModelCapabilities, error) {
+	type entry struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		ContextLength int    `json:"context_length"`   // OpenRouter
+		ContextWindow int    `json:"context_window"`   // Groq
+		MaxModelLen   int    `json:"max_model_len"`    // vLLM
+		MaxInput      int    `json:"max_input_tokens"` // Anthropic
+		InputLimit    int    `json:"inputTokenLimit"`  // Gemini
+		MaxTokens     int    `json:"max_tokens"`
+		MaxCompletion int    `json:"max_completion_tokens"`
+		OutputLimit   int    `json:"outputTokenLimit"`
+		TopProvider   struct {
+			MaxCompletion int `json:"max_completion_tokens"`
+		} `json:"top_provider"`
+		Architecture *struct {
+			InputModalities []string `json:"input_modalities"`
+		} `json:"architecture"`
+		SupportedParameters []string `json:"supported_parameters"`
+	}
+	var list struct {
+		Data   []entry `json:"data"`
+		Models []entry `json:"models"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("invalid model list: %w", err)
+	}
+
+	models := make(map[string]ModelCapabilities)
+	for _, e := range append(list.Data, list.Models...) {
+		id := e.ID
+		if id == "" {
+			id = e.Name
+		}
+		window := firstPositive(e.ContextLength, e.ContextWindow, e.MaxModelLen, e.MaxInput, e.InputLimit)
+		if id == "" || window == 0 {
+			continue
+		}
+
+		name := normalizeModelName(id)
+		caps, _ := knownCapabilities(name)
+		caps.ContextWindow = window
+		if output := firstPositive(e.TopProvider.MaxCompletion, e.MaxCompletion, e.MaxTokens, e.OutputLimit); output > 0 {
+			caps.MaxOutput = output
+		}
+		if e.Architecture != nil {
+			caps.Vision = containsString(e.Architecture.InputModalities, "image")
+		}
+		if e.SupportedParameters != nil {
+			caps.Tools = containsString(e.SupportedParameters, "tools")
+		}
+		models[name] = caps
+	}
+	return models, nil
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
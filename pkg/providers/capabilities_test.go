@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestKnownCapabilities(t *testing.T) {
+	tests := []struct {
+		model  string
+		window int
+		output int
+	}{
+		{"maia/gemini-2.5-flash", 1048576, 65536},
+		{"openrouter/anthropic/claude-3.5-sonnet", 200000, 8192},
+		{"claude-sonnet-4-20250514", 200000, 64000},
+		{"gpt-4o-mini", 128000, 16384},
+		{"gpt-4.1-nano", 1047576, 32768},
+		{"gpt-4", 8192, 8192},
+	}
+	for _, tt := range tests {
+		caps, ok := knownCapabilities(normalizeModelName(tt.model))
+		if !ok || caps.ContextWindow != tt.window || caps.MaxOutput != tt.output {
+			t.Errorf("%s: got %+v %v", tt.model, caps, ok)
+		}
+	}
+	if _, ok := knownCapabilities(normalizeModelName("my-local-model")); ok {
+		t.Error("unknown model should not match")
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	openrouter := `{"data":[
+		{"id":"anthropic/claude-3.5-sonnet","context_length":200000,"top_provider":{"max_completion_tokens":8192},
+		 "architecture":{"input_modalities":["text","image"]},"supported_parameters":["tools","temperature"]},
+		{"id":"some/text-model","context_length":32768,"architecture":{"input_modalities":["text"]},"supported_parameters":["temperature"]},
+		{"id":"gpt-4o"}
+	]}`
+	models, err := parseModelList([]byte(openrouter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("models without sizes should be skipped: %v", models)
+	}
+	if caps := models["claude-3-5-sonnet"]; caps.ContextWindow != 200000 || caps.MaxOutput != 8192 || !caps.Vision || !caps.Tools {
+		t.Errorf("claude: %+v", caps)
+	}
+	if caps := models["text-model"]; caps.ContextWindow != 32768 || caps.Vision || caps.Tools {
+		t.Errorf("text model: %+v", caps)
+	}
+
+	gemini := `{"models":[{"name":"models/gemini-2.0-flash","inputTokenLimit":1048576,"outputTokenLimit":8192}]}`
+	models, err = parseModelList([]byte(gemini))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps := models["gemini-2-0-flash"]; caps.ContextWindow != 1048576 || caps.MaxOutput != 8192 || !caps.Vision {
+		t.Errorf("gemini: %+v", caps)
+	}
+}
+
+func TestRefreshCapabilities(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "models.json")
+	original := capabilitiesCacheFile
+	capabilitiesCacheFile = func() string { return cache }
+	defer func() {
+		capabilitiesCacheFile = original
+		fetchedModels.Lock()
+		fetchedModels.loaded = false
+		fetchedModels.Unlock()
+	}()
+	fetchedModels.Lock()
+	fetchedModels.loaded = false
+	fetchedModels.Unlock()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/models" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"qwen2.5-7b-instruct","max_model_len":16384}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("", server.URL)
+	RefreshCapabilities(context.Background(), provider)
+	RefreshCapabilities(context.Background(), provider)
+	if requests != 1 {
+		t.Errorf("expected one request per day, got %d", requests)
+	}
+	caps, ok := Capabilities("vllm/qwen2.5-7b-instruct")
+	if !ok || caps.ContextWindow != 16384 || caps.MaxOutput != 8192 {
+		t.Errorf("fetched window should override the table: %+v", caps)
+	}
+
+	// A new process reads the cache
+	fetchedModels.Lock()
+	fetchedModels.loaded = false
+	fetchedModels.Unlock()
+	if caps, _ := Capabilities("qwen2.5-7b-instruct"); caps.ContextWindow != 16384 {
+		t.Errorf("cache not used: %+v", caps)
+	}
+}
@@ -0,0 +1,193 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// EmbeddingProvider is implemented by providers that can embed text
+type EmbeddingProvider interface {
+	// Embed returns one vector per input, in input order. dimensions is
+	// passed to models that support shortened vectors; 0 keeps the default.
+	Embed(ctx context.Context, model string, input []string, dimensions int) (*EmbeddingResult, error)
+}
+
+type EmbeddingResult struct {
+	Embeddings [][]float64
+	Usage      *UsageInfo
+}
+
+// CreateEmbeddingProvider returns the provider for embedding requests.
+// embeddings.api_base points at any OpenAI-compatible server (e.g. a local
+// Ollama or vLLM); otherwise the provider is chosen like a chat provider,
+// from embeddings.provider or the model name.
+func CreateEmbeddingProvider(cfg *config.Config, model string) (EmbeddingProvider, error) {
+	if cfg.Embeddings.APIBase != "" {
+		return NewHTTPProvider(cfg.Embeddings.APIKey, cfg.Embeddings.APIBase), nil
+	}
+
+	provider, err := createProvider(cfg, model, cfg.Embeddings.Provider, "", "")
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := provider.(EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider for model '%s' does not support embeddings", model)
+	}
+	return embedder, nil
+}
+
+// Embed calls the OpenAI-compatible /embeddings endpoint
+func (p *HTTPProvider) Embed(ctx context.Context, model string, input []string, dimensions int) (*EmbeddingResult, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+
+	logger.DebugCF("provider", "HTTP embeddings request", map[string]interface{}{
+		"model":    model,
+		"api_base": p.apiBase,
+		"inputs":   len(input),
+	})
+
+	requestBody := map[string]interface{}{
+		"model": model,
+		"input": input,
+	}
+	if dimensions > 0 {
+		requestBody["dimensions"] = dimensions
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/embeddings", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage *UsageInfo `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(parsed.Data) != len(input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(parsed.Data))
+	}
+
+	sort.Slice(parsed.Data, func(i, j int) bool { return parsed.Data[i].Index < parsed.Data[j].Index })
+	result := &EmbeddingResult{Usage: parsed.Usage}
+	for _, d := range parsed.Data {
+		result.Embeddings = append(result.Embeddings, d.Embedding)
+	}
+	return result, nil
+}
+
+// Embed calls the Vertex AI predict endpoint of a text embedding model
+func (p *VertexProvider) Embed(ctx context.Context, model string, input []string, dimensions int) (*EmbeddingResult, error) {
+	modelName := strings.TrimPrefix(model, "vertex/")
+
+	logger.DebugCF("provider", "Vertex AI embeddings request", map[string]interface{}{
+		"model":  modelName,
+		"inputs": len(input),
+	})
+
+	instances := make([]map[string]interface{}, 0, len(input))
+	for _, text := range input {
+		instances = append(instances, map[string]interface{}{"content": text})
+	}
+	requestBody := map[string]interface{}{"instances": instances}
+	if dimensions > 0 {
+		requestBody["parameters"] = map[string]interface{}{"outputDimensionality": dimensions}
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("vertex: failed to marshal request: %w", err)
+	}
+
+	token, err := p.getToken()
+	if err != nil {
+		return nil, fmt.Errorf("vertex: failed to get auth token: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(p.buildEndpointURL(modelName, false), ":generateContent") + ":predict"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("vertex: failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vertex: failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("vertex: failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vertex API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var parsed struct {
+		Predictions []struct {
+			Embeddings struct {
+				Values     []float64 `json:"values"`
+				Statistics struct {
+					TokenCount float64 `json:"token_count"`
+				} `json:"statistics"`
+			} `json:"embeddings"`
+		} `json:"predictions"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("vertex: failed to parse response: %w", err)
+	}
+	if len(parsed.Predictions) != len(input) {
+		return nil, fmt.Errorf("vertex: expected %d embeddings, got %d", len(input), len(parsed.Predictions))
+	}
+
+	result := &EmbeddingResult{Usage: &UsageInfo{}}
+	for _, prediction := range parsed.Predictions {
+		result.Embeddings = append(result.Embeddings, prediction.Embeddings.Values)
+		result.Usage.PromptTokens += int(prediction.Embeddings.Statistics.TokenCount)
+	}
+	result.Usage.TotalTokens = result.Usage.PromptTokens
+	return result, nil
+}
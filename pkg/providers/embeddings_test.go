@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPProvider_Embed(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"data":[
+			{"index":1,"embedding":[0.3,0.4]},
+			{"index":0,"embedding":[0.1,0.2]}
+		],"usage":{"prompt_tokens":5,"total_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("", server.URL)
+	result, err := provider.Embed(context.Background(), "nomic-embed-text", []string{"a", "b"}, 2)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if got["model"] != "nomic-embed-text" || got["dimensions"] != float64(2) {
+		t.Errorf("unexpected request body: %v", got)
+	}
+	if len(result.Embeddings) != 2 || result.Embeddings[0][0] != 0.1 || result.Embeddings[1][0] != 0.3 {
+		t.Errorf("embeddings not in input order: %v", result.Embeddings)
+	}
+	if result.Usage == nil || result.Usage.PromptTokens != 5 {
+		t.Errorf("unexpected usage: %+v", result.Usage)
+	}
+}
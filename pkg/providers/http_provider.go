@@ -28,11 +28,9 @@ type HTTPProvider struct {
 
 func NewHTTPProvider(apiKey, apiBase string) *HTTPProvider {
 	return &HTTPProvider{
-		apiKey:  apiKey,
-		apiBase: apiBase,
-		httpClient: &http.Client{
-			Timeout: 0,
-		},
+		apiKey:     apiKey,
+		apiBase:    apiBase,
+		httpClient: newHTTPClient(),
 	}
 }
 
@@ -64,6 +62,9 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 	if len(tools) > 0 {
 		requestBody["tools"] = tools
 		requestBody["tool_choice"] = "auto"
+		if choice, ok := options["tool_choice"]; ok && choice != nil {
+			requestBody["tool_choice"] = choice
+		}
 	}
 
 	if maxTokens, ok := options["max_tokens"].(int); ok {
@@ -73,6 +74,15 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 	if temperature, ok := options["temperature"].(float64); ok {
 		requestBody["temperature"] = temperature
 	}
+	if seed, ok := options["seed"].(int); ok && !strings.Contains(p.apiBase, "anthropic.com") {
+		requestBody["seed"] = seed
+	}
+
+	if rf, ok := options["response_format"].(*ResponseFormat); ok && rf != nil {
+		requestBody["response_format"] = rf
+	}
+
+	p.applyReasoning(requestBody, model, options)
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
@@ -130,8 +140,10 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
 			Message struct {
-				Content   string `json:"content"`
-				ToolCalls []struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"` // DeepSeek
+				Reasoning        string `json:"reasoning"`         // OpenRouter, Groq
+				ToolCalls        []struct {
 					ID       string `json:"id"`
 					Type     string `json:"type"`
 					Function *struct {
@@ -188,17 +200,82 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 		})
 	}
 
+	content, thinking := SplitThinking(choice.Message.Content)
 	return &LLMResponse{
-		Content:      choice.Message.Content,
+		Content:      content,
 		ToolCalls:    toolCalls,
 		FinishReason: choice.FinishReason,
 		Usage:        apiResponse.Usage,
+		Reasoning:    joinReasoning(choice.Message.ReasoningContent, choice.Message.Reasoning, thinking),
 	}, nil
 }
 
+// applyReasoning adds the reasoning parameters in the dialect of the API
+// behind apiBase. Reasoning models reject a custom temperature.
+func (p *HTTPProvider) applyReasoning(requestBody map[string]interface{}, model string, options map[string]interface{}) {
+	r := reasoningOption(options)
+	if r == nil {
+		return
+	}
+	delete(requestBody, "temperature")
+
+	switch {
+	case strings.Contains(p.apiBase, "anthropic.com"):
+		// Claude needs max_tokens above the thinking budget
+		budget := r.Budget()
+		requestBody["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		if maxTokens, _ := requestBody["max_tokens"].(int); maxTokens <= budget {
+			requestBody["max_tokens"] = budget + max(maxTokens, 1024)
+		}
+	case strings.Contains(p.apiBase, "openrouter.ai"):
+		if r.BudgetTokens > 0 {
+			requestBody["reasoning"] = map[string]interface{}{"max_tokens": r.BudgetTokens}
+		} else {
+			requestBody["reasoning"] = map[string]interface{}{"effort": r.EffortLevel()}
+		}
+	default:
+		requestBody["reasoning_effort"] = r.EffortLevel()
+		// OpenAI reasoning models only accept max_completion_tokens
+		if maxTokens, ok := requestBody["max_tokens"]; ok && openAIReasoningModel(model) {
+			delete(requestBody, "max_tokens")
+			requestBody["max_completion_tokens"] = maxTokens
+		}
+	}
+}
+
+func openAIReasoningModel(model string) bool {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinReasoning(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
 func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, model string, options map[string]interface{}, callback StreamCallback) error {
+	_, err := p.ChatStreamWithTools(ctx, messages, nil, model, options, callback)
+	return err
+}
+
+// ChatStreamWithTools streams a chat completion, passing tool-call deltas to
+// the callback and returning the assembled response
+func (p *HTTPProvider) ChatStreamWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callback StreamCallback) (*LLMResponse, error) {
 	if p.apiBase == "" {
-		return fmt.Errorf("API base not configured")
+		return nil, fmt.Errorf("API base not configured")
 	}
 
 	requestBody := map[string]interface{}{
@@ -207,6 +284,14 @@ func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, model
 		"stream":   true,
 	}
 
+	if len(tools) > 0 {
+		requestBody["tools"] = tools
+		requestBody["tool_choice"] = "auto"
+		if choice, ok := options["tool_choice"]; ok && choice != nil {
+			requestBody["tool_choice"] = choice
+		}
+	}
+
 	if maxTokens, ok := options["max_tokens"].(int); ok {
 		requestBody["max_tokens"] = maxTokens
 	}
@@ -214,15 +299,24 @@ func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, model
 	if temperature, ok := options["temperature"].(float64); ok {
 		requestBody["temperature"] = temperature
 	}
+	if seed, ok := options["seed"].(int); ok && !strings.Contains(p.apiBase, "anthropic.com") {
+		requestBody["seed"] = seed
+	}
+
+	if rf, ok := options["response_format"].(*ResponseFormat); ok && rf != nil {
+		requestBody["response_format"] = rf
+	}
+
+	p.applyReasoning(requestBody, model, options)
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -232,16 +326,27 @@ func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, model
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error: %s", string(body))
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	collector := newStreamCollector(callback)
+	var splitter thinkSplitter
+	finish := func() *LLMResponse {
+		content, reasoning := splitter.Flush()
+		collector.emit(StreamChunk{Content: content, Reasoning: reasoning})
+		collector.emit(StreamChunk{Done: true})
+		return collector.response()
 	}
 
 	scanner := bufio.NewScanner(resp.Body)
+	// Tool-call arguments can make single events large
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	for scanner.Scan() {
 		line := scanner.Text()
 
@@ -256,41 +361,61 @@ func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, model
 		data := strings.TrimPrefix(line, "data: ")
 
 		if data == "[DONE]" {
-			callback(StreamChunk{Done: true})
-			return nil
+			return finish(), nil
 		}
 
 		var chunk struct {
 			Choices []struct {
 				Delta struct {
-					Content string `json:"content"`
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+					Reasoning        string `json:"reasoning"`
+					ToolCalls        []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function *struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
 				} `json:"delta"`
 				FinishReason *string `json:"finish_reason"`
 			} `json:"choices"`
+			Usage *UsageInfo `json:"usage"`
 		}
 
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			collector.usage = chunk.Usage
+		}
 
 		if len(chunk.Choices) > 0 {
 			delta := chunk.Choices[0].Delta
-			if delta.Content != "" {
-				callback(StreamChunk{Content: delta.Content})
+			content, reasoning := splitter.Push(delta.Content)
+			out := StreamChunk{Content: content, Reasoning: delta.ReasoningContent + delta.Reasoning + reasoning}
+			for _, tc := range delta.ToolCalls {
+				d := ToolCallDelta{Index: tc.Index, ID: tc.ID}
+				if tc.Function != nil {
+					d.Name = tc.Function.Name
+					d.Arguments = tc.Function.Arguments
+				}
+				out.ToolCalls = append(out.ToolCalls, d)
 			}
-			if chunk.Choices[0].FinishReason != nil && *chunk.Choices[0].FinishReason == "stop" {
-				callback(StreamChunk{Done: true})
-				return nil
+			collector.emit(out)
+			if reason := chunk.Choices[0].FinishReason; reason != nil && *reason != "" {
+				collector.finishReason = *reason
+				return finish(), nil
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading stream: %w", err)
+		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
-	callback(StreamChunk{Done: true})
-	return nil
+	return finish(), nil
 }
 
 func (p *HTTPProvider) GetDefaultModel() string {
@@ -304,6 +429,41 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 // CreateProviderWithOverrides creates a provider with optional model and provider overrides.
 // If overrideModel/overrideProvider are empty, falls back to config defaults.
 func CreateProviderWithOverrides(cfg *config.Config, overrideModel, overrideProvider string) (LLMProvider, error) {
+	return CreateProviderWithCredentials(cfg, overrideModel, overrideProvider, "", "")
+}
+
+// CreateProviderWithCredentials is CreateProviderWithOverrides with an API key
+// and base that replace the ones configured for the provider. An API base
+// without a provider name is treated as an OpenAI-compatible server. When
+// models.aliases or models.rules are configured the provider is wrapped in a
+// Router.
+func CreateProviderWithCredentials(cfg *config.Config, overrideModel, overrideProvider, overrideAPIKey, overrideAPIBase string) (LLMProvider, error) {
+	model := overrideModel
+	if model == "" {
+		model = cfg.Agents.Defaults.Model
+	}
+	// An alias as the model name selects the alias' provider and credentials
+	if alias, ok := cfg.Models.Aliases[model]; ok {
+		overrideModel = alias.Model
+		if overrideProvider == "" {
+			overrideProvider = alias.Provider
+		}
+		if overrideAPIKey == "" {
+			overrideAPIKey = expandSecret(alias.APIKey)
+		}
+		if overrideAPIBase == "" {
+			overrideAPIBase = alias.APIBase
+		}
+	}
+
+	provider, err := createProvider(cfg, overrideModel, overrideProvider, overrideAPIKey, overrideAPIBase)
+	if err != nil {
+		return nil, err
+	}
+	return NewRouter(cfg, provider), nil
+}
+
+func createProvider(cfg *config.Config, overrideModel, overrideProvider, overrideAPIKey, overrideAPIBase string) (LLMProvider, error) {
 	model := cfg.Agents.Defaults.Model
 	if overrideModel != "" {
 		model = overrideModel
@@ -373,15 +533,27 @@ func CreateProviderWithOverrides(cfg *config.Config, overrideModel, overrideProv
 		case "vllm":
 			apiKey = cfg.Providers.VLLM.APIKey
 			apiBase = cfg.Providers.VLLM.APIBase
+		case "mock":
+			return newConfiguredMock(cfg)
 		case "opencodego":
-			return NewOpenCodeProvider(
-				cfg.Providers.OpenCodeGo.APIKey,
-				cfg.Providers.OpenCodeGo.APIBase,
-			), nil
+			apiKey, apiBase = cfg.Providers.OpenCodeGo.APIKey, cfg.Providers.OpenCodeGo.APIBase
+			if overrideAPIKey != "" {
+				apiKey = overrideAPIKey
+			}
+			if overrideAPIBase != "" {
+				apiBase = overrideAPIBase
+			}
+			return NewOpenCodeProvider(apiKey, apiBase), nil
 		default:
 			return nil, fmt.Errorf("unknown provider: %s", provider)
 		}
 
+		if overrideAPIKey != "" {
+			apiKey = overrideAPIKey
+		}
+		if overrideAPIBase != "" {
+			apiBase = overrideAPIBase
+		}
 		if apiKey == "" {
 			return nil, fmt.Errorf("no API key configured for provider: %s", provider)
 		}
@@ -391,8 +563,15 @@ func CreateProviderWithOverrides(cfg *config.Config, overrideModel, overrideProv
 		return NewHTTPProvider(apiKey, apiBase), nil
 	}
 
+	if overrideAPIBase != "" {
+		return NewHTTPProvider(overrideAPIKey, overrideAPIBase), nil
+	}
+
 	// Fallback: auto-detect provider from model prefix/name
 	switch {
+	case strings.HasPrefix(model, "mock/"):
+		return newConfiguredMock(cfg)
+
 	case strings.HasPrefix(model, "vertex/"):
 		return NewVertexProvider(
 			cfg.Providers.Vertex.CredentialsFile,
@@ -470,11 +649,17 @@ func CreateProviderWithOverrides(cfg *config.Config, overrideModel, overrideProv
 			} else {
 				apiBase = "https://openrouter.ai/api/v1"
 			}
-		} else {
+		} else if overrideAPIKey == "" {
 			return nil, fmt.Errorf("no API key configured for model: %s", model)
+		} else {
+			return nil, fmt.Errorf("cannot detect provider for model '%s'; set provider or api_base with api_key", model)
 		}
 	}
 
+	if overrideAPIKey != "" {
+		apiKey = overrideAPIKey
+	}
+
 	if apiKey == "" && !strings.HasPrefix(model, "bedrock/") && !strings.HasPrefix(model, "vertex/") {
 		return nil, fmt.Errorf("no API key configured for provider (model: %s)", model)
 	}
@@ -0,0 +1,184 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// Mock is an LLMProvider that answers from a script instead of a model, for
+// tests and offline eval runs. Each request is answered by the first rule
+// whose Match is in the last message (ignoring case), else by the next
+// unused entry of Replies, else with Default. Select it with the "mock"
+// provider or a "mock/" model; providers.mock.file holds the script.
+type Mock struct {
+	Rules   []MockRule  `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Replies []MockReply `json:"replies,omitempty" yaml:"replies,omitempty"`
+	Default string      `json:"default,omitempty" yaml:"default,omitempty"`
+
+	mu    sync.Mutex
+	next  int
+	calls []MockCall
+}
+
+// MockRule answers requests whose last message contains Match
+type MockRule struct {
+	Match string    `json:"match" yaml:"match"`
+	Reply MockReply `json:"reply" yaml:"reply"`
+}
+
+// MockReply is a scripted response. Error makes the request fail.
+type MockReply struct {
+	Content   string         `json:"content,omitempty" yaml:"content,omitempty"`
+	ToolCalls []MockToolCall `json:"tool_calls,omitempty" yaml:"tool_calls,omitempty"`
+	Error     string         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// MockToolCall is a tool call in a scripted response
+type MockToolCall struct {
+	Name      string                 `json:"name" yaml:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+}
+
+// MockCall is a request the mock received
+type MockCall struct {
+	Messages []Message
+	Tools    []ToolDefinition
+	Model    string
+}
+
+// NewMock returns a mock that answers with replies in order
+func NewMock(replies ...MockReply) *Mock {
+	return &Mock{Replies: replies}
+}
+
+// LoadMock reads a mock script, YAML or JSON, from file
+func LoadMock(file string) (*Mock, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("mock provider: %w", err)
+	}
+	m := &Mock{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("mock provider: invalid script %s: %w", file, err)
+	}
+	return m, nil
+}
+
+// newConfiguredMock loads the script in providers.mock.file, or returns a
+// mock that always gives its default reply
+func newConfiguredMock(cfg *config.Config) (*Mock, error) {
+	if cfg.Providers.Mock.File == "" {
+		return &Mock{}, nil
+	}
+	return LoadMock(cfg.Providers.Mock.File)
+}
+
+// On adds a rule and returns the mock, for building scripts in tests
+func (m *Mock) On(match string, reply MockReply) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Rules = append(m.Rules, MockRule{Match: match, Reply: reply})
+	return m
+}
+
+// Calls returns the requests received so far
+func (m *Mock) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+func (m *Mock) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reply, n := m.reply(messages, tools, model)
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+
+	resp := &LLMResponse{Content: reply.Content, FinishReason: "stop"}
+	for i, tc := range reply.ToolCalls {
+		args := tc.Arguments
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_mock_%d_%d", n, i+1),
+			Name:      tc.Name,
+			Arguments: args,
+		})
+	}
+	if len(resp.ToolCalls) > 0 {
+		resp.FinishReason = "tool_calls"
+	}
+	return resp, nil
+}
+
+// ChatStream sends the scripted content as a single chunk
+func (m *Mock) ChatStream(ctx context.Context, messages []Message, model string, options map[string]interface{}, callback StreamCallback) error {
+	resp, err := m.Chat(ctx, messages, nil, model, options)
+	if err != nil {
+		return err
+	}
+	if resp.Content != "" {
+		callback(StreamChunk{Content: resp.Content})
+	}
+	callback(StreamChunk{Done: true})
+	return nil
+}
+
+func (m *Mock) GetDefaultModel() string {
+	return "mock"
+}
+
+// reply picks the scripted reply to a request and returns it with the
+// request's number
+func (m *Mock) reply(messages []Message, tools []ToolDefinition, model string) (MockReply, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, MockCall{Messages: messages, Tools: tools, Model: model})
+	n := len(m.calls)
+
+	last := ""
+	if len(messages) > 0 {
+		last = strings.ToLower(messageText(messages[len(messages)-1]))
+	}
+	for _, rule := range m.Rules {
+		if strings.Contains(last, strings.ToLower(rule.Match)) {
+			return rule.Reply, n
+		}
+	}
+	if m.next < len(m.Replies) {
+		m.next++
+		return m.Replies[m.next-1], n
+	}
+	if m.Default != "" {
+		return MockReply{Content: m.Default}, n
+	}
+	return MockReply{Content: "mock reply"}, n
+}
+
+// messageText returns the text of a message's content
+func messageText(msg Message) string {
+	switch content := msg.Content.(type) {
+	case string:
+		return content
+	case []ContentBlock:
+		var parts []string
+		for _, block := range content {
+			if block.Text != "" {
+				parts = append(parts, block.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMock(t *testing.T) {
+	m := NewMock(MockReply{Content: "first"}, MockReply{Content: "second"}).
+		On("weather", MockReply{ToolCalls: []MockToolCall{{Name: "web_search", Arguments: map[string]interface{}{"query": "weather"}}}}).
+		On("broken", MockReply{Error: "rate limited"})
+	ctx := context.Background()
+	user := func(text string) []Message { return []Message{{Role: "user", Content: text}} }
+
+	resp, _ := m.Chat(ctx, user("What's the WEATHER?"), nil, "mock", nil)
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "web_search" || resp.FinishReason != "tool_calls" {
+		t.Errorf("rule reply = %+v", resp)
+	}
+	for _, want := range []string{"first", "second", "mock reply"} {
+		if resp, _ := m.Chat(ctx, user("hello"), nil, "mock", nil); resp.Content != want {
+			t.Errorf("reply = %q, want %q", resp.Content, want)
+		}
+	}
+	if _, err := m.Chat(ctx, user("broken"), nil, "mock", nil); err == nil || err.Error() != "rate limited" {
+		t.Errorf("err = %v", err)
+	}
+	if calls := m.Calls(); len(calls) != 5 || calls[0].Model != "mock" {
+		t.Errorf("calls = %d", len(calls))
+	}
+
+	var streamed string
+	m.ChatStream(ctx, user("hello"), "mock", nil, func(c StreamChunk) { streamed += c.Content })
+	if streamed != "mock reply" {
+		t.Errorf("streamed %q", streamed)
+	}
+}
+
+func TestLoadMock(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mock.yaml")
+	os.WriteFile(file, []byte(`
+rules:
+  - match: shopping
+    reply:
+      tool_calls:
+        - name: kv_get
+          arguments: {key: shopping}
+  - match: milk
+    reply: {content: You need milk.}
+default: Sorry?
+`), 0644)
+	m, err := LoadMock(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	resp, _ := m.Chat(ctx, []Message{{Role: "user", Content: "What's on the shopping list?"}}, nil, "mock", nil)
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments["key"] != "shopping" {
+		t.Errorf("tool call = %+v", resp.ToolCalls)
+	}
+	// The tool result is the last message of the next request
+	resp, _ = m.Chat(ctx, []Message{{Role: "tool", Content: "milk, eggs"}}, nil, "mock", nil)
+	if resp.Content != "You need milk." {
+		t.Errorf("reply = %q", resp.Content)
+	}
+	if resp, _ := m.Chat(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "mock", nil); resp.Content != "Sorry?" {
+		t.Errorf("default = %q", resp.Content)
+	}
+}
@@ -30,11 +30,9 @@ func NewOpenCodeProvider(apiKey, apiBase string) *OpenCodeProvider {
 		apiBase = "https://opencode.ai/zen/go"
 	}
 	return &OpenCodeProvider{
-		apiKey:  apiKey,
-		apiBase: apiBase,
-		httpClient: &http.Client{
-			Timeout: 0,
-		},
+		apiKey:     apiKey,
+		apiBase:    apiBase,
+		httpClient: newHTTPClient(),
 	}
 }
 
@@ -107,19 +105,26 @@ func (p *OpenCodeProvider) Chat(ctx context.Context, messages []Message, tools [
 }
 
 func (p *OpenCodeProvider) ChatStream(ctx context.Context, messages []Message, model string, options map[string]interface{}, callback StreamCallback) error {
-	requestBody := p.buildAnthropicRequest(messages, nil, model, options)
+	_, err := p.ChatStreamWithTools(ctx, messages, nil, model, options, callback)
+	return err
+}
+
+// ChatStreamWithTools streams a Messages API response, passing tool_use
+// input deltas to the callback and returning the assembled response
+func (p *OpenCodeProvider) ChatStreamWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callback StreamCallback) (*LLMResponse, error) {
+	requestBody := p.buildAnthropicRequest(messages, tools, model, options)
 	requestBody["stream"] = true
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return fmt.Errorf("opencode: failed to marshal request: %w", err)
+		return nil, fmt.Errorf("opencode: failed to marshal request: %w", err)
 	}
 
 	endpoint := p.apiBase + "/v1/messages"
 
 	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
 	if err != nil {
-		return fmt.Errorf("opencode: failed to create request: %w", err)
+		return nil, fmt.Errorf("opencode: failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -128,16 +133,32 @@ func (p *OpenCodeProvider) ChatStream(ctx context.Context, messages []Message, m
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("opencode: failed to send request: %w", err)
+		return nil, fmt.Errorf("opencode: failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("opencode API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("opencode API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	collector := newStreamCollector(callback)
+	var splitter thinkSplitter
+	finish := func() *LLMResponse {
+		content, reasoning := splitter.Flush()
+		collector.emit(StreamChunk{Content: content, Reasoning: reasoning})
+		collector.emit(StreamChunk{Done: true})
+		return collector.response()
 	}
 
+	// Content block index -> tool call index, and -> thinking block index
+	toolIndex := make(map[int]int)
+	thinkingIndex := make(map[int]int)
+	usage := &UsageInfo{}
+
 	scanner := bufio.NewScanner(resp.Body)
+	// Tool input and thinking signatures can make single events large
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	for scanner.Scan() {
 		line := scanner.Text()
 
@@ -159,15 +180,26 @@ func (p *OpenCodeProvider) ChatStream(ctx context.Context, messages []Message, m
 			Type  string `json:"type"`
 			Index int    `json:"index,omitempty"`
 			Delta *struct {
-				Type string `json:"type"`
-				Text string `json:"text,omitempty"`
+				Type        string `json:"type"`
+				Text        string `json:"text,omitempty"`
+				Thinking    string `json:"thinking,omitempty"`
+				Signature   string `json:"signature,omitempty"`
+				PartialJSON string `json:"partial_json,omitempty"`
+				StopReason  string `json:"stop_reason,omitempty"`
 			} `json:"delta,omitempty"`
+			ContentBlock *struct {
+				Type string `json:"type"`
+				ID   string `json:"id,omitempty"`
+				Name string `json:"name,omitempty"`
+			} `json:"content_block,omitempty"`
 			Message *struct {
-				Content []struct {
-					Type string `json:"type"`
-					Text string `json:"text,omitempty"`
-				} `json:"content"`
+				Usage *struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"usage,omitempty"`
 			} `json:"message,omitempty"`
+			Usage *struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage,omitempty"`
 		}
 
 		if err := json.Unmarshal([]byte(data), &event); err != nil {
@@ -175,22 +207,69 @@ func (p *OpenCodeProvider) ChatStream(ctx context.Context, messages []Message, m
 		}
 
 		switch event.Type {
+		case "message_start":
+			if event.Message != nil && event.Message.Usage != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+			}
+		case "content_block_start":
+			if event.ContentBlock == nil {
+				continue
+			}
+			switch event.ContentBlock.Type {
+			case "tool_use":
+				toolIndex[event.Index] = len(toolIndex)
+				collector.emit(StreamChunk{ToolCalls: []ToolCallDelta{{
+					Index: toolIndex[event.Index],
+					ID:    event.ContentBlock.ID,
+					Name:  event.ContentBlock.Name,
+				}}})
+			case "thinking":
+				thinkingIndex[event.Index] = len(collector.thinking)
+				collector.thinking = append(collector.thinking, ThinkingBlock{})
+			}
 		case "content_block_delta":
-			if event.Delta != nil && event.Delta.Type == "text_delta" && event.Delta.Text != "" {
-				callback(StreamChunk{Content: event.Delta.Text})
+			if event.Delta == nil {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				content, reasoning := splitter.Push(event.Delta.Text)
+				collector.emit(StreamChunk{Content: content, Reasoning: reasoning})
+			case "thinking_delta":
+				if i, ok := thinkingIndex[event.Index]; ok {
+					collector.thinking[i].Thinking += event.Delta.Thinking
+				}
+				collector.emit(StreamChunk{Reasoning: event.Delta.Thinking})
+			case "signature_delta":
+				if i, ok := thinkingIndex[event.Index]; ok {
+					collector.thinking[i].Signature += event.Delta.Signature
+				}
+			case "input_json_delta":
+				if i, ok := toolIndex[event.Index]; ok {
+					collector.emit(StreamChunk{ToolCalls: []ToolCallDelta{{Index: i, Arguments: event.Delta.PartialJSON}}})
+				}
+			}
+		case "message_delta":
+			if event.Delta != nil {
+				collector.finishReason = anthropicFinishReason(event.Delta.StopReason)
+			}
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
 			}
 		case "message_stop":
-			callback(StreamChunk{Done: true})
-			return nil
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			if usage.TotalTokens > 0 {
+				collector.usage = usage
+			}
+			return finish(), nil
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("opencode: error reading stream: %w", err)
+		return nil, fmt.Errorf("opencode: error reading stream: %w", err)
 	}
 
-	callback(StreamChunk{Done: true})
-	return nil
+	return finish(), nil
 }
 
 func (p *OpenCodeProvider) GetDefaultModel() string {
@@ -240,6 +319,14 @@ func (p *OpenCodeProvider) buildAnthropicRequest(messages []Message, tools []Too
 
 		if len(msg.ToolCalls) > 0 {
 			contentArray := []map[string]interface{}{}
+			// Thinking blocks must precede the tool_use they led to
+			for _, block := range msg.Thinking {
+				contentArray = append(contentArray, map[string]interface{}{
+					"type":      "thinking",
+					"thinking":  block.Thinking,
+					"signature": block.Signature,
+				})
+			}
 			contentStr := getMessageContentString(msg.Content)
 			if contentStr != "" {
 				contentArray = append(contentArray, map[string]interface{}{
@@ -249,14 +336,21 @@ func (p *OpenCodeProvider) buildAnthropicRequest(messages []Message, tools []Too
 			}
 			for _, tc := range msg.ToolCalls {
 				name := tc.Name
+				input := tc.Arguments
 				if name == "" && tc.Function != nil {
 					name = tc.Function.Name
+					if input == nil && tc.Function.Arguments != "" {
+						json.Unmarshal([]byte(tc.Function.Arguments), &input)
+					}
+				}
+				if input == nil {
+					input = map[string]interface{}{}
 				}
 				contentArray = append(contentArray, map[string]interface{}{
 					"type":  "tool_use",
 					"id":    tc.ID,
 					"name":  name,
-					"input": tc.Arguments,
+					"input": input,
 				})
 			}
 			anthropicMsg["content"] = contentArray
@@ -265,6 +359,14 @@ func (p *OpenCodeProvider) buildAnthropicRequest(messages []Message, tools []Too
 		anthropicMessages = append(anthropicMessages, anthropicMsg)
 	}
 
+	// The Messages API has no response_format, so describe it instead
+	if rf, ok := options["response_format"].(*ResponseFormat); ok && rf.WantsJSON() {
+		if systemPrompt != "" {
+			systemPrompt += "\n\n"
+		}
+		systemPrompt += rf.Instruction()
+	}
+
 	if systemPrompt != "" {
 		request["system"] = systemPrompt
 	}
@@ -293,6 +395,16 @@ func (p *OpenCodeProvider) buildAnthropicRequest(messages []Message, tools []Too
 		request["temperature"] = temperature
 	}
 
+	// Extended thinking needs max_tokens above the budget and no temperature
+	if r := reasoningOption(options); r != nil {
+		budget := r.Budget()
+		request["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		delete(request, "temperature")
+		if maxTokens := request["max_tokens"].(int); maxTokens <= budget {
+			request["max_tokens"] = budget + max(maxTokens, 1024)
+		}
+	}
+
 	return request
 }
 
@@ -350,11 +462,13 @@ func (p *OpenCodeProvider) buildContent(msg Message) interface{} {
 func (p *OpenCodeProvider) parseAnthropicResponse(body []byte) (*LLMResponse, error) {
 	var resp struct {
 		Content []struct {
-			Type  string                 `json:"type"`
-			Text  string                 `json:"text,omitempty"`
-			ID    string                 `json:"id,omitempty"`
-			Name  string                 `json:"name,omitempty"`
-			Input map[string]interface{} `json:"input,omitempty"`
+			Type      string                 `json:"type"`
+			Text      string                 `json:"text,omitempty"`
+			Thinking  string                 `json:"thinking,omitempty"`
+			Signature string                 `json:"signature,omitempty"`
+			ID        string                 `json:"id,omitempty"`
+			Name      string                 `json:"name,omitempty"`
+			Input     map[string]interface{} `json:"input,omitempty"`
 		} `json:"content"`
 		StopReason string `json:"stop_reason"`
 		Usage      *struct {
@@ -369,6 +483,8 @@ func (p *OpenCodeProvider) parseAnthropicResponse(body []byte) (*LLMResponse, er
 
 	var contentParts []string
 	var toolCalls []ToolCall
+	var thinking []ThinkingBlock
+	var thoughts []string
 
 	for _, block := range resp.Content {
 		switch block.Type {
@@ -376,6 +492,9 @@ func (p *OpenCodeProvider) parseAnthropicResponse(body []byte) (*LLMResponse, er
 			if block.Text != "" {
 				contentParts = append(contentParts, block.Text)
 			}
+		case "thinking":
+			thinking = append(thinking, ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature})
+			thoughts = append(thoughts, block.Thinking)
 		case "tool_use":
 			toolCalls = append(toolCalls, ToolCall{
 				ID:        block.ID,
@@ -385,17 +504,7 @@ func (p *OpenCodeProvider) parseAnthropicResponse(body []byte) (*LLMResponse, er
 		}
 	}
 
-	finishReason := "stop"
-	switch resp.StopReason {
-	case "end_turn":
-		finishReason = "stop"
-	case "max_tokens":
-		finishReason = "length"
-	case "tool_use":
-		finishReason = "tool_calls"
-	case "stop_sequence":
-		finishReason = "stop"
-	}
+	finishReason := anthropicFinishReason(resp.StopReason)
 
 	var usage *UsageInfo
 	if resp.Usage != nil {
@@ -406,10 +515,27 @@ func (p *OpenCodeProvider) parseAnthropicResponse(body []byte) (*LLMResponse, er
 		}
 	}
 
+	content, tagged := SplitThinking(strings.Join(contentParts, ""))
+	thoughts = append(thoughts, tagged)
+
 	return &LLMResponse{
-		Content:      strings.Join(contentParts, ""),
+		Content:      content,
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 		Usage:        usage,
+		Reasoning:    joinReasoning(thoughts...),
+		Thinking:     thinking,
 	}, nil
 }
+
+// anthropicFinishReason maps a Messages API stop_reason to an OpenAI finish_reason
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
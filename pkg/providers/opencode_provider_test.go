@@ -108,3 +108,32 @@ func TestOpenCodeProvider_BuildAnthropicRequestWithTools(t *testing.T) {
 		t.Errorf("Expected tool name 'get_weather', got %v", toolsArray[0]["name"])
 	}
 }
+
+func TestOpenCodeProvider_BuildAnthropicRequestWithOpenAIToolCall(t *testing.T) {
+	provider := NewOpenCodeProvider("test-key", "")
+
+	messages := []Message{
+		{Role: "user", Content: "What is the weather?"},
+		{Role: "assistant", Content: "", ToolCalls: []ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: &FunctionCall{Name: "get_weather", Arguments: `{"location":"Jakarta"}`},
+		}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "Sunny"},
+	}
+
+	request := provider.buildAnthropicRequest(messages, nil, "minimax-m2.5", nil)
+
+	anthropicMessages, ok := request["messages"].([]map[string]interface{})
+	if !ok || len(anthropicMessages) != 3 {
+		t.Fatalf("Expected 3 messages, got %v", request["messages"])
+	}
+	content, _ := anthropicMessages[1]["content"].([]map[string]interface{})
+	if len(content) != 1 || content[0]["name"] != "get_weather" {
+		t.Fatalf("Expected tool_use block, got %v", anthropicMessages[1]["content"])
+	}
+	input, _ := content[0]["input"].(map[string]interface{})
+	if input["location"] != "Jakarta" {
+		t.Errorf("Expected parsed arguments, got %v", content[0]["input"])
+	}
+}
@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// ProviderSpec selects a provider and, optionally, the credentials to use
+// instead of the ones in providers.* of the config
+type ProviderSpec struct {
+	Model    string
+	Provider string
+	APIKey   string
+	APIBase  string
+}
+
+// IsDefault reports whether the spec uses the global provider settings
+func (s ProviderSpec) IsDefault(cfg *config.Config) bool {
+	return s.Provider == "" && s.APIKey == "" && s.APIBase == "" &&
+		(s.Model == "" || s.Model == cfg.Agents.Defaults.Model)
+}
+
+// ProviderPool hands out one provider per agent. Agents with the same
+// provider settings share a provider instance.
+type ProviderPool struct {
+	cfg      *config.Config
+	fallback LLMProvider
+	mu       sync.Mutex
+	byAgent  map[string]LLMProvider
+	bySpec   map[ProviderSpec]LLMProvider
+}
+
+// NewProviderPool creates a pool. fallback is used for agents without their
+// own provider settings.
+func NewProviderPool(cfg *config.Config, fallback LLMProvider) *ProviderPool {
+	return &ProviderPool{
+		cfg:      cfg,
+		fallback: fallback,
+		byAgent:  make(map[string]LLMProvider),
+		bySpec:   make(map[ProviderSpec]LLMProvider),
+	}
+}
+
+// Get returns the provider for an agent, creating it on first use
+func (p *ProviderPool) Get(agent string, spec ProviderSpec) (LLMProvider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if provider, ok := p.byAgent[agent]; ok {
+		return provider, nil
+	}
+	if spec.IsDefault(p.cfg) && p.fallback != nil {
+		p.byAgent[agent] = p.fallback
+		return p.fallback, nil
+	}
+
+	spec.APIKey = expandSecret(spec.APIKey)
+	provider, ok := p.bySpec[spec]
+	if !ok {
+		var err error
+		provider, err = CreateProviderWithCredentials(p.cfg, spec.Model, spec.Provider, spec.APIKey, spec.APIBase)
+		if err != nil {
+			return nil, fmt.Errorf("agent '%s': %w", agent, err)
+		}
+		p.bySpec[spec] = provider
+	}
+	p.byAgent[agent] = provider
+	return provider, nil
+}
+
+// expandSecret resolves "${VAR}" to the environment variable VAR so API keys
+// need not be stored in the agent registry
+func expandSecret(value string) string {
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		return os.Getenv(value[2 : len(value)-1])
+	}
+	return value
+}
@@ -0,0 +1,58 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func TestProviderPool(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Groq.APIKey = "global-groq"
+	fallback := NewHTTPProvider("global", "https://example.com/v1")
+	pool := NewProviderPool(cfg, fallback)
+
+	got, err := pool.Get("default", ProviderSpec{Model: cfg.Agents.Defaults.Model})
+	if err != nil || got != fallback {
+		t.Fatalf("default agent should use the fallback provider, got %v (%v)", got, err)
+	}
+
+	t.Setenv("PEPEBOT_TEST_GROQ_KEY", "agent-groq")
+	chat, err := pool.Get("chat", ProviderSpec{Model: "llama-3.3-70b-versatile", Provider: "groq", APIKey: "${PEPEBOT_TEST_GROQ_KEY}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hp := chat.(*HTTPProvider)
+	if hp.apiKey != "agent-groq" || hp.apiBase != "https://api.groq.com/openai/v1" {
+		t.Errorf("chat provider = %q %q, want agent key and groq base", hp.apiKey, hp.apiBase)
+	}
+
+	// Same settings share a provider; the agent keeps its own entry
+	other, err := pool.Get("other", ProviderSpec{Model: "llama-3.3-70b-versatile", Provider: "groq", APIKey: "${PEPEBOT_TEST_GROQ_KEY}"})
+	if err != nil || other != chat {
+		t.Errorf("agents with identical settings should share a provider")
+	}
+
+	local, err := pool.Get("coder", ProviderSpec{Model: "qwen2.5-coder", APIBase: "http://localhost:11434/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hp := local.(*HTTPProvider); hp.apiBase != "http://localhost:11434/v1" || hp.apiKey != "" {
+		t.Errorf("api_base without provider should use an OpenAI-compatible provider, got %q %q", hp.apiKey, hp.apiBase)
+	}
+}
+
+func TestCreateProviderWithCredentials(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	if _, err := CreateProviderWithCredentials(cfg, "claude-sonnet-4", "anthropic", "", ""); err == nil {
+		t.Error("expected an error without any anthropic key")
+	}
+	p, err := CreateProviderWithCredentials(cfg, "claude-sonnet-4", "anthropic", "sk-agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hp := p.(*HTTPProvider); hp.apiKey != "sk-agent" || hp.apiBase != "https://api.anthropic.com/v1" {
+		t.Errorf("got %q %q", hp.apiKey, hp.apiBase)
+	}
+}
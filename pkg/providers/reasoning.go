@@ -0,0 +1,163 @@
+package providers
+
+import (
+	"strings"
+)
+
+// ReasoningOptions asks reasoning models to think before answering. Pass it
+// to Chat as options["reasoning"]. Providers map it to their own parameter:
+// reasoning_effort (OpenAI o-series), a thinking budget (Claude, Gemini) or
+// OpenRouter's reasoning object.
+type ReasoningOptions struct {
+	Effort       string // "low", "medium" or "high"
+	BudgetTokens int    // explicit thinking budget; derived from Effort when 0
+}
+
+// Thinking budgets used when only an effort level is configured
+var effortBudgets = map[string]int{
+	"low":    1024,
+	"medium": 4096,
+	"high":   16384,
+}
+
+// Enabled reports whether reasoning was requested
+func (r *ReasoningOptions) Enabled() bool {
+	return r != nil && (r.Effort != "" || r.BudgetTokens > 0)
+}
+
+// Budget returns the thinking budget in tokens
+func (r *ReasoningOptions) Budget() int {
+	if r.BudgetTokens > 0 {
+		return r.BudgetTokens
+	}
+	if budget, ok := effortBudgets[r.Effort]; ok {
+		return budget
+	}
+	return effortBudgets["medium"]
+}
+
+// EffortLevel returns the effort level, derived from the budget when unset
+func (r *ReasoningOptions) EffortLevel() string {
+	if _, ok := effortBudgets[r.Effort]; ok {
+		return r.Effort
+	}
+	switch budget := r.Budget(); {
+	case budget <= effortBudgets["low"]:
+		return "low"
+	case budget <= effortBudgets["medium"]:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+func reasoningOption(options map[string]interface{}) *ReasoningOptions {
+	r, _ := options["reasoning"].(*ReasoningOptions)
+	if !r.Enabled() {
+		return nil
+	}
+	return r
+}
+
+// ThinkingBlock is a Claude thinking block. Claude requires the blocks of
+// the previous assistant turn, with their signatures, during tool use.
+type ThinkingBlock struct {
+	Thinking  string
+	Signature string
+}
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// SplitThinking separates <think>...</think> sections, which DeepSeek-R1
+// and similar models put in the content, from the answer. Some servers
+// drop the opening tag, so text before a lone closing tag is reasoning too.
+func SplitThinking(content string) (answer, thinking string) {
+	if !strings.Contains(content, thinkClose) && !strings.Contains(content, thinkOpen) {
+		return content, ""
+	}
+
+	var out, thoughts []string
+	rest := content
+	if i, j := strings.Index(rest, thinkClose), strings.Index(rest, thinkOpen); i >= 0 && (j < 0 || i < j) {
+		thoughts = append(thoughts, rest[:i])
+		rest = rest[i+len(thinkClose):]
+	}
+	for {
+		i := strings.Index(rest, thinkOpen)
+		if i < 0 {
+			out = append(out, rest)
+			break
+		}
+		out = append(out, rest[:i])
+		rest = rest[i+len(thinkOpen):]
+		j := strings.Index(rest, thinkClose)
+		if j < 0 {
+			// Unterminated: the model ran out of tokens while thinking
+			thoughts = append(thoughts, rest)
+			break
+		}
+		thoughts = append(thoughts, rest[:j])
+		rest = rest[j+len(thinkClose):]
+	}
+
+	for i := range thoughts {
+		thoughts[i] = strings.TrimSpace(thoughts[i])
+	}
+	return strings.TrimSpace(strings.Join(out, "")), strings.TrimSpace(strings.Join(thoughts, "\n\n"))
+}
+
+// thinkSplitter separates <think> sections on a stream, where tags may be
+// split across chunks. Unlike SplitThinking it needs the opening tag.
+type thinkSplitter struct {
+	inThink bool
+	pending string // possible start of a tag
+}
+
+// Push returns the answer and reasoning text that can be emitted so far
+func (s *thinkSplitter) Push(text string) (content, reasoning string) {
+	text = s.pending + text
+	s.pending = ""
+	var answer, thought strings.Builder
+
+	for text != "" {
+		out, tag := &answer, thinkOpen
+		if s.inThink {
+			out, tag = &thought, thinkClose
+		}
+		if i := strings.Index(text, tag); i >= 0 {
+			out.WriteString(text[:i])
+			s.inThink = !s.inThink
+			text = text[i+len(tag):]
+			continue
+		}
+		keep := partialTagSuffix(text, tag)
+		out.WriteString(text[:len(text)-keep])
+		s.pending = text[len(text)-keep:]
+		break
+	}
+	return answer.String(), thought.String()
+}
+
+// Flush returns text held back at the end of the stream
+func (s *thinkSplitter) Flush() (content, reasoning string) {
+	text := s.pending
+	s.pending = ""
+	if s.inThink {
+		return "", text
+	}
+	return text, ""
+}
+
+// partialTagSuffix returns the length of the longest suffix of text that is
+// a proper prefix of tag
+func partialTagSuffix(text, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
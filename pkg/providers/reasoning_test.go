@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"testing"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		content, answer, thinking string
+	}{
+		{"Plain answer", "Plain answer", ""},
+		{"<think>\nLet me add.\n</think>\n\n4", "4", "Let me add."},
+		{"Let me add.\n</think>\n\n4", "4", "Let me add."},
+		{"<think>a</think>x<think>b</think>y", "xy", "a\n\nb"},
+		{"<think>ran out of tokens", "", "ran out of tokens"},
+	}
+	for _, tt := range tests {
+		answer, thinking := SplitThinking(tt.content)
+		if answer != tt.answer || thinking != tt.thinking {
+			t.Errorf("SplitThinking(%q) = %q, %q; want %q, %q", tt.content, answer, thinking, tt.answer, tt.thinking)
+		}
+	}
+}
+
+func TestThinkSplitterAcrossChunks(t *testing.T) {
+	var s thinkSplitter
+	var answer, thinking string
+	for _, chunk := range []string{"<thi", "nk>step one", " step two</th", "ink>The answer", " is 4 <", "b>"} {
+		a, r := s.Push(chunk)
+		answer += a
+		thinking += r
+	}
+	a, r := s.Flush()
+	answer += a
+	thinking += r
+
+	if answer != "The answer is 4 <b>" {
+		t.Errorf("answer = %q", answer)
+	}
+	if thinking != "step one step two" {
+		t.Errorf("thinking = %q", thinking)
+	}
+}
+
+func TestReasoningOptions(t *testing.T) {
+	if (*ReasoningOptions)(nil).Enabled() || (&ReasoningOptions{}).Enabled() {
+		t.Error("empty options should be disabled")
+	}
+	r := &ReasoningOptions{Effort: "high"}
+	if r.Budget() != 16384 || r.EffortLevel() != "high" {
+		t.Errorf("high effort: budget %d, level %s", r.Budget(), r.EffortLevel())
+	}
+	r = &ReasoningOptions{BudgetTokens: 2000}
+	if r.Budget() != 2000 || r.EffortLevel() != "medium" {
+		t.Errorf("budget 2000: budget %d, level %s", r.Budget(), r.EffortLevel())
+	}
+}
+
+func TestHTTPProviderReasoningDialects(t *testing.T) {
+	options := map[string]interface{}{"reasoning": &ReasoningOptions{Effort: "low"}}
+	body := func(apiBase, model string) map[string]interface{} {
+		b := map[string]interface{}{"max_tokens": 512, "temperature": 0.7}
+		NewHTTPProvider("key", apiBase).applyReasoning(b, model, options)
+		return b
+	}
+
+	openai := body("https://api.openai.com/v1", "o3-mini")
+	if openai["reasoning_effort"] != "low" || openai["max_completion_tokens"] != 512 || openai["max_tokens"] != nil || openai["temperature"] != nil {
+		t.Errorf("openai body: %v", openai)
+	}
+
+	anthropic := body("https://api.anthropic.com/v1", "claude-sonnet-4")
+	thinking, _ := anthropic["thinking"].(map[string]interface{})
+	if thinking["budget_tokens"] != 1024 || anthropic["max_tokens"] != 2048 {
+		t.Errorf("anthropic body: %v", anthropic)
+	}
+
+	openrouter := body("https://openrouter.ai/api/v1", "deepseek/deepseek-r1")
+	if reasoning, _ := openrouter["reasoning"].(map[string]interface{}); reasoning["effort"] != "low" {
+		t.Errorf("openrouter body: %v", openrouter)
+	}
+}
+
+func TestHTTPProviderParseReasoning(t *testing.T) {
+	p := NewHTTPProvider("key", "https://api.deepseek.com/v1")
+	resp, err := p.parseResponse([]byte(`{"choices":[{"message":{"content":"<think>hmm</think>42","reasoning_content":"native"},"finish_reason":"stop"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "42" || resp.Reasoning != "native\n\nhmm" {
+		t.Errorf("content %q, reasoning %q", resp.Content, resp.Reasoning)
+	}
+}
+
+func TestOpenCodeProvider_Thinking(t *testing.T) {
+	provider := NewOpenCodeProvider("test-key", "")
+	messages := []Message{
+		{Role: "user", Content: "Weather?"},
+		{Role: "assistant", Content: "", Thinking: []ThinkingBlock{{Thinking: "use the tool", Signature: "sig"}},
+			ToolCalls: []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: map[string]interface{}{}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "Sunny"},
+	}
+	req := provider.buildAnthropicRequest(messages, nil, "claude", map[string]interface{}{
+		"max_tokens":  8192,
+		"temperature": 0.7,
+		"reasoning":   &ReasoningOptions{Effort: "medium"},
+	})
+	if req["temperature"] != nil || req["thinking"] == nil {
+		t.Errorf("expected thinking without temperature: %v", req)
+	}
+	content := req["messages"].([]map[string]interface{})[1]["content"].([]map[string]interface{})
+	if content[0]["type"] != "thinking" || content[0]["signature"] != "sig" {
+		t.Errorf("thinking block should come first: %v", content)
+	}
+
+	resp, err := provider.parseAnthropicResponse([]byte(`{"content":[{"type":"thinking","thinking":"let me see","signature":"s1"},{"type":"text","text":"Sunny"}],"stop_reason":"end_turn"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "Sunny" || resp.Reasoning != "let me see" || len(resp.Thinking) != 1 || resp.Thinking[0].Signature != "s1" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Recorder modes
+const (
+	RecordMode = "record"
+	ReplayMode = "replay"
+)
+
+// transport carries the requests of providers created after SetTransport;
+// nil uses http.DefaultTransport
+var (
+	transportMu sync.RWMutex
+	transport   http.RoundTripper
+)
+
+// SetTransport makes providers created from now on send their requests
+// through rt, such as a Recorder. nil restores the default.
+func SetTransport(rt http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	transport = rt
+}
+
+func newHTTPClient() *http.Client {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return &http.Client{Timeout: 0, Transport: transport}
+}
+
+// Interaction is a recorded request and its response. Request headers,
+// which hold the API keys, are not recorded.
+type Interaction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	Key         string `json:"key"` // hash of method, URL and body
+	Request     string `json:"request"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Response    string `json:"response"`
+}
+
+// Recorder is an http.RoundTripper that saves provider responses to a
+// cassette file (one interaction per line) and plays them back, so a run
+// against real models can be repeated offline. In replay mode a request is
+// answered by the recording with the same body, else by the next unplayed
+// recording for the same URL, since prompts that mention the current time
+// change between runs.
+type Recorder struct {
+	path  string
+	mode  string
+	next  http.RoundTripper
+	mu    sync.Mutex
+	saved []Interaction
+	used  []bool
+}
+
+// NewRecorder opens a cassette. Record mode starts it afresh; replay mode
+// reads it.
+func NewRecorder(path, mode string) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, next: http.DefaultTransport}
+	switch mode {
+	case RecordMode:
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			return nil, err
+		}
+	case ReplayMode:
+		saved, err := readCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		r.saved, r.used = saved, make([]bool, len(saved))
+	default:
+		return nil, fmt.Errorf("unknown recorder mode %q (want record or replay)", mode)
+	}
+	return r, nil
+}
+
+func readCassette(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var saved []Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var in Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+		saved = append(saved, in)
+	}
+	return saved, scanner.Err()
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String() + "\n" + string(body)))
+	key := hex.EncodeToString(sum[:8])
+
+	if r.mode == ReplayMode {
+		in, ok := r.play(req.Method, req.URL.String(), key)
+		if !ok {
+			return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL, r.path)
+		}
+		header := http.Header{}
+		if in.ContentType != "" {
+			header.Set("Content-Type", in.ContentType)
+		}
+		return &http.Response{
+			StatusCode:    in.Status,
+			Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(in.Response))),
+			ContentLength: int64(len(in.Response)),
+			Request:       req,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+		}, nil
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err := r.record(Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		Key:         key,
+		Request:     string(body),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Response:    string(data),
+	}); err != nil {
+		logger.WarnCF("providers", "Failed to record response", map[string]interface{}{
+			"path":  r.path,
+			"error": err.Error(),
+		})
+	}
+	return resp, nil
+}
+
+// play returns the recording for a request and marks it played
+func (r *Recorder) play(method, url, key string) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.saved {
+		if !r.used[i] && in.Key == key {
+			r.used[i] = true
+			return in, true
+		}
+	}
+	for i, in := range r.saved {
+		if !r.used[i] && in.Method == method && in.URL == url {
+			r.used[i] = true
+			logger.DebugCF("providers", "Replaying recording with a different request body", map[string]interface{}{
+				"url": url,
+			})
+			return in, true
+		}
+	}
+	return Interaction{}, false
+}
+
+func (r *Recorder) record(in Interaction) error {
+	line, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
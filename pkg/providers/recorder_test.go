@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		content := "Hi there"
+		if strings.Contains(string(body), "weather") {
+			content = "Sunny"
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + content + `"},"finish_reason":"stop"}]}`))
+	}))
+	cassette := filepath.Join(t.TempDir(), "cassette.jsonl")
+	ctx := context.Background()
+	chat := func(text string) string {
+		t.Helper()
+		resp, err := NewHTTPProvider("secret-key", server.URL).Chat(ctx, []Message{{Role: "user", Content: text}}, nil, "gpt-4o", nil)
+		if err != nil {
+			t.Fatalf("Chat(%q): %v", text, err)
+		}
+		return resp.Content
+	}
+	defer SetTransport(nil)
+
+	recorder, err := NewRecorder(cassette, RecordMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTransport(recorder)
+	if chat("hello") != "Hi there" || chat("weather?") != "Sunny" {
+		t.Fatal("unexpected live replies")
+	}
+	server.Close()
+	if data, _ := os.ReadFile(cassette); strings.Contains(string(data), "secret-key") {
+		t.Error("cassette contains the API key")
+	}
+
+	// Replays are matched by body, in any order, without the server
+	recorder, err = NewRecorder(cassette, ReplayMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTransport(recorder)
+	if got := chat("weather?"); got != "Sunny" {
+		t.Errorf("replay = %q", got)
+	}
+	// A changed body takes the next unplayed recording
+	if got := chat("hello at 10:42"); got != "Hi there" {
+		t.Errorf("replay = %q", got)
+	}
+	if _, err := NewHTTPProvider("", server.URL).Chat(ctx, []Message{{Role: "user", Content: "more"}}, nil, "gpt-4o", nil); err == nil {
+		t.Error("expected an error once the cassette is used up")
+	}
+	if requests != 2 {
+		t.Errorf("server got %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,234 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResponseFormat is the OpenAI response_format parameter. Pass it to Chat
+// as options["response_format"]; providers without a native equivalent add
+// an instruction to the system prompt instead.
+type ResponseFormat struct {
+	Type       string            `json:"type"` // "text", "json_object" or "json_schema"
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type JSONSchemaFormat struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      bool                   `json:"strict,omitempty"`
+}
+
+// WantsJSON reports whether the format asks for JSON output
+func (rf *ResponseFormat) WantsJSON() bool {
+	return rf != nil && (rf.Type == "json_object" || rf.Type == "json_schema")
+}
+
+// Check validates the format itself
+func (rf *ResponseFormat) Check() error {
+	switch rf.Type {
+	case "text", "json_object":
+		return nil
+	case "json_schema":
+		if rf.JSONSchema == nil || rf.JSONSchema.Name == "" {
+			return fmt.Errorf("response_format.json_schema.name is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported response_format type '%s'", rf.Type)
+	}
+}
+
+// Instruction describes the format in words, for providers that cannot
+// enforce it
+func (rf *ResponseFormat) Instruction() string {
+	if !rf.WantsJSON() {
+		return ""
+	}
+	text := "Respond with a single valid JSON value only: no prose, no markdown code fences."
+	if rf.JSONSchema != nil && rf.JSONSchema.Schema != nil {
+		schema, _ := json.Marshal(rf.JSONSchema.Schema)
+		text += " The JSON must match this JSON Schema:\n" + string(schema)
+	}
+	return text
+}
+
+// Validate extracts the JSON from a reply (tolerating a surrounding code
+// fence) and checks it against the schema. Returns the bare JSON.
+func (rf *ResponseFormat) Validate(content string) (string, error) {
+	if !rf.WantsJSON() {
+		return content, nil
+	}
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return "", fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+	if rf.Type == "json_object" {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return "", fmt.Errorf("reply must be a JSON object")
+		}
+	}
+	if rf.JSONSchema != nil && rf.JSONSchema.Schema != nil {
+		if err := validateSchema(value, rf.JSONSchema.Schema, "$"); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// validateSchema checks the JSON Schema keywords models are asked to follow:
+// type, enum, const, properties, required, additionalProperties, items,
+// anyOf and min/max bounds. Unknown keywords are ignored.
+func validateSchema(value interface{}, schema map[string]interface{}, path string) error {
+	if options, ok := schema["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, option := range options {
+			sub, _ := option.(map[string]interface{})
+			err := validateSchema(value, sub, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s matches none of anyOf: %s", path, strings.Join(errs, "; "))
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(value, t) {
+		return fmt.Errorf("%s: expected %v, got %s", path, t, jsonType(value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of %v", path, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		return fmt.Errorf("%s: value must be %v", path, c)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, present := v[name]; !present {
+					return fmt.Errorf("%s: missing required property '%s'", path, name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if sub, ok := props[k].(map[string]interface{}); ok {
+				if err := validateSchema(v[k], sub, path+"."+k); err != nil {
+					return err
+				}
+			} else if extra, ok := schema["additionalProperties"].(bool); ok && !extra {
+				return fmt.Errorf("%s: unexpected property '%s'", path, k)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			return fmt.Errorf("%s: needs at least %v items", path, min)
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			return fmt.Errorf("%s: allows at most %v items", path, max)
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			return fmt.Errorf("%s: must be >= %v", path, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			return fmt.Errorf("%s: must be <= %v", path, max)
+		}
+	}
+	return nil
+}
+
+func matchesType(value interface{}, t interface{}) bool {
+	if list, ok := t.([]interface{}); ok {
+		for _, item := range list {
+			if matchesType(value, item) {
+				return true
+			}
+		}
+		return false
+	}
+	name, _ := t.(string)
+	actual := jsonType(value)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+	return name == actual
+}
+
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+type responseFormatKey struct{}
+
+// WithResponseFormat attaches a response format to a request context so the
+// agent loop applies it to its model calls
+func WithResponseFormat(ctx context.Context, rf *ResponseFormat) context.Context {
+	return context.WithValue(ctx, responseFormatKey{}, rf)
+}
+
+// ResponseFormatFromContext returns the response format set on ctx, if any
+func ResponseFormatFromContext(ctx context.Context) *ResponseFormat {
+	rf, _ := ctx.Value(responseFormatKey{}).(*ResponseFormat)
+	return rf
+}
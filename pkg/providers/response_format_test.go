@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func personFormat() *ResponseFormat {
+	return &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchemaFormat{
+			Name: "person",
+			Schema: map[string]interface{}{
+				"type":                 "object",
+				"required":             []interface{}{"name", "age"},
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{"type": "string"},
+					"age":  map[string]interface{}{"type": "integer", "minimum": float64(0)},
+					"role": map[string]interface{}{"enum": []interface{}{"admin", "user"}},
+				},
+			},
+		},
+	}
+}
+
+func TestResponseFormatValidate(t *testing.T) {
+	rf := personFormat()
+
+	tests := []struct {
+		content string
+		wantErr string
+	}{
+		{`{"name":"Ana","age":30}`, ""},
+		{"```json\n{\"name\":\"Ana\",\"age\":30,\"role\":\"admin\"}\n```", ""},
+		{`not json`, "not valid JSON"},
+		{`{"name":"Ana"}`, "missing required property 'age'"},
+		{`{"name":"Ana","age":"30"}`, "$.age: expected integer"},
+		{`{"name":"Ana","age":-1}`, "must be >= 0"},
+		{`{"name":"Ana","age":30,"role":"root"}`, "not one of"},
+		{`{"name":"Ana","age":30,"email":"a@b"}`, "unexpected property 'email'"},
+	}
+	for _, tt := range tests {
+		got, err := rf.Validate(tt.content)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Validate(%q) error: %v", tt.content, err)
+			} else if strings.Contains(got, "```") {
+				t.Errorf("Validate(%q) kept the code fence: %q", tt.content, got)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Validate(%q) error = %v, want %q", tt.content, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResponseFormatJSONObject(t *testing.T) {
+	rf := &ResponseFormat{Type: "json_object"}
+	if _, err := rf.Validate(`[1, 2]`); err == nil {
+		t.Error("expected an array to be rejected for json_object")
+	}
+	if _, err := rf.Validate(`{"ok": true}`); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var none *ResponseFormat
+	if got, err := none.Validate("plain text"); err != nil || got != "plain text" {
+		t.Errorf("nil format should pass content through, got %q, %v", got, err)
+	}
+}
+
+func TestResponseFormatCheck(t *testing.T) {
+	if err := personFormat().Check(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (&ResponseFormat{Type: "json_schema"}).Check(); err == nil {
+		t.Error("expected json_schema without a name to be rejected")
+	}
+	if err := (&ResponseFormat{Type: "xml"}).Check(); err == nil {
+		t.Error("expected unknown type to be rejected")
+	}
+}
+
+func TestOpenCodeProvider_ResponseFormatInstruction(t *testing.T) {
+	provider := NewOpenCodeProvider("test-key", "")
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Who?"},
+	}
+	req := provider.buildAnthropicRequest(messages, nil, "claude", map[string]interface{}{
+		"response_format": personFormat(),
+	})
+	system, _ := req["system"].(string)
+	if !strings.Contains(system, "You are helpful.") || !strings.Contains(system, `"required":["name","age"]`) {
+		t.Errorf("system prompt missing format instruction: %q", system)
+	}
+}
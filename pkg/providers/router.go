@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Router resolves model aliases and routing rules (models.* in config) on
+// every request and forwards it to the provider of the resulting model.
+// Requests that are not rerouted go to the wrapped provider unchanged.
+type Router struct {
+	cfg      *config.Config
+	fallback LLMProvider
+	mu       sync.Mutex
+	targets  map[config.ModelAlias]LLMProvider
+}
+
+// NewRouter wraps provider in a Router, or returns it as is when no aliases
+// or rules are configured
+func NewRouter(cfg *config.Config, provider LLMProvider) LLMProvider {
+	if !cfg.Models.Routing() {
+		return provider
+	}
+	if _, ok := provider.(*Router); ok {
+		return provider
+	}
+	return &Router{
+		cfg:      cfg,
+		fallback: provider,
+		targets:  make(map[config.ModelAlias]LLMProvider),
+	}
+}
+
+// Resolve returns the model a request should use. The first matching rule
+// may replace the requested model, then aliases are expanded.
+func (r *Router) Resolve(model string, messages []Message, tools []ToolDefinition) config.ModelAlias {
+	for _, rule := range r.cfg.Models.Rules {
+		if rule.Model == "" || (rule.Match != "" && rule.Match != model) {
+			continue
+		}
+		if rule.Tools && len(tools) == 0 {
+			continue
+		}
+		if rule.MinTokens > 0 && estimatePromptTokens(messages) < rule.MinTokens {
+			continue
+		}
+		model = rule.Model
+		break
+	}
+	if alias, ok := r.cfg.Models.Aliases[model]; ok {
+		return alias
+	}
+	return config.ModelAlias{Model: model}
+}
+
+func (r *Router) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	target := r.Resolve(model, messages, tools)
+	provider, err := r.provider(model, target)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Chat(ctx, messages, tools, target.Model, options)
+}
+
+func (r *Router) ChatStream(ctx context.Context, messages []Message, model string, options map[string]interface{}, callback StreamCallback) error {
+	target := r.Resolve(model, messages, nil)
+	provider, err := r.provider(model, target)
+	if err != nil {
+		return err
+	}
+	return provider.ChatStream(ctx, messages, target.Model, options, callback)
+}
+
+func (r *Router) ChatStreamWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callback StreamCallback) (*LLMResponse, error) {
+	target := r.Resolve(model, messages, tools)
+	provider, err := r.provider(model, target)
+	if err != nil {
+		return nil, err
+	}
+	return StreamWithTools(ctx, provider, messages, tools, target.Model, options, callback)
+}
+
+func (r *Router) GetDefaultModel() string {
+	return r.fallback.GetDefaultModel()
+}
+
+// provider returns the provider for a resolved model, created on first use
+func (r *Router) provider(requested string, target config.ModelAlias) (LLMProvider, error) {
+	if target.Model == requested && target.Provider == "" && target.APIKey == "" && target.APIBase == "" {
+		return r.fallback, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if provider, ok := r.targets[target]; ok {
+		return provider, nil
+	}
+
+	provider, err := createProvider(r.cfg, target.Model, target.Provider, expandSecret(target.APIKey), target.APIBase)
+	if err != nil {
+		return nil, err
+	}
+	r.targets[target] = provider
+	logger.DebugCF("provider", "Created provider for routed model", map[string]interface{}{
+		"requested": requested,
+		"model":     target.Model,
+		"provider":  target.Provider,
+	})
+	return provider, nil
+}
+
+// estimatePromptTokens approximates the prompt size at four characters per
+// token, which is close enough for routing thresholds. Images are not counted.
+func estimatePromptTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		chars := 0
+		if blocks, ok := m.Content.([]ContentBlock); ok {
+			for _, block := range blocks {
+				chars += len(block.Text)
+			}
+		} else {
+			chars = len(getMessageContentString(m.Content))
+		}
+		total += (chars+3)/4 + 4
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+type fakeProvider struct{ models []string }
+
+func (f *fakeProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	f.models = append(f.models, model)
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, messages []Message, model string, options map[string]interface{}, callback StreamCallback) error {
+	f.models = append(f.models, model)
+	return nil
+}
+
+func (f *fakeProvider) GetDefaultModel() string { return "" }
+
+func routerConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Models = config.ModelsConfig{
+		Aliases: map[string]config.ModelAlias{
+			"fast":  {Model: "llama-3.1-8b-instant", Provider: "groq", APIKey: "groq-key"},
+			"smart": {Model: "claude-sonnet-4", Provider: "anthropic", APIKey: "anthropic-key"},
+		},
+		Rules: []config.ModelRule{
+			{Match: "fast", Tools: true, Model: "smart"},
+			{Match: "fast", MinTokens: 100, Model: "long-context"},
+		},
+	}
+	return cfg
+}
+
+func TestRouterResolve(t *testing.T) {
+	r := NewRouter(routerConfig(), &fakeProvider{}).(*Router)
+	short := []Message{{Role: "user", Content: "hi"}}
+	long := []Message{{Role: "user", Content: strings.Repeat("word ", 200)}}
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "exec"}}}
+
+	tests := []struct {
+		name     string
+		model    string
+		messages []Message
+		tools    []ToolDefinition
+		want     string
+	}{
+		{"alias", "fast", short, nil, "llama-3.1-8b-instant"},
+		{"tools rule", "fast", short, tools, "claude-sonnet-4"},
+		{"token rule", "fast", long, nil, "long-context"},
+		{"rule scoped to alias", "gpt-4o", long, tools, "gpt-4o"},
+		{"smart alias", "smart", short, tools, "claude-sonnet-4"},
+	}
+	for _, tt := range tests {
+		if got := r.Resolve(tt.model, tt.messages, tt.tools).Model; got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRouterChat(t *testing.T) {
+	fallback := &fakeProvider{}
+	r := NewRouter(routerConfig(), fallback).(*Router)
+
+	// Models without aliases or rules go to the wrapped provider unchanged
+	if _, err := r.Chat(context.Background(), nil, nil, "gpt-4o", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(fallback.models) != 1 || fallback.models[0] != "gpt-4o" {
+		t.Errorf("fallback got %v", fallback.models)
+	}
+
+	provider, err := r.provider("fast", r.Resolve("fast", nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hp := provider.(*HTTPProvider); hp.apiKey != "groq-key" || hp.apiBase != "https://api.groq.com/openai/v1" {
+		t.Errorf("alias provider = %q %q", hp.apiKey, hp.apiBase)
+	}
+}
+
+func TestNewRouterWithoutRouting(t *testing.T) {
+	fallback := &fakeProvider{}
+	if got := NewRouter(config.DefaultConfig(), fallback); got != fallback {
+		t.Error("expected the provider unchanged when no aliases or rules are configured")
+	}
+}
+
+func TestCreateProviderWithAliasModel(t *testing.T) {
+	cfg := routerConfig()
+	cfg.Agents.Defaults.Model = "smart"
+	p, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := p.(*Router)
+	if !ok {
+		t.Fatalf("expected a Router, got %T", p)
+	}
+	if hp := r.fallback.(*HTTPProvider); hp.apiBase != "https://api.anthropic.com/v1" {
+		t.Errorf("fallback api base = %q", hp.apiBase)
+	}
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// Sampling overrides the temperature and seed of model calls, for runs that
+// should be repeatable such as tests and evals. Nil fields keep the agent's
+// settings. The seed is passed as options["seed"] to OpenAI-compatible APIs
+// and Gemini; Anthropic has no seed and ignores it.
+type Sampling struct {
+	Temperature *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	Seed        *int     `json:"seed,omitempty" yaml:"seed,omitempty"`
+}
+
+// IsEmpty reports whether the sampling overrides nothing
+func (s *Sampling) IsEmpty() bool {
+	return s == nil || (s.Temperature == nil && s.Seed == nil)
+}
+
+// Check validates the overrides
+func (s *Sampling) Check() error {
+	if s != nil && s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	return nil
+}
+
+// Apply sets the overrides in provider options
+func (s *Sampling) Apply(options map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	if s.Temperature != nil {
+		options["temperature"] = *s.Temperature
+	}
+	if s.Seed != nil {
+		options["seed"] = *s.Seed
+	}
+}
+
+type samplingKey struct{}
+
+// WithSampling attaches sampling overrides to a request context so the agent
+// loop applies them to its model calls
+func WithSampling(ctx context.Context, s *Sampling) context.Context {
+	return context.WithValue(ctx, samplingKey{}, s)
+}
+
+// SamplingFromContext returns the sampling overrides set on ctx, if any
+func SamplingFromContext(ctx context.Context) *Sampling {
+	s, _ := ctx.Value(samplingKey{}).(*Sampling)
+	return s
+}
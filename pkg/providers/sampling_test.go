@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSamplingOptions(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	temperature, seed := 0.0, 42
+	options := map[string]interface{}{"temperature": 0.7}
+	(&Sampling{Temperature: &temperature, Seed: &seed}).Apply(options)
+	(*Sampling)(nil).Apply(options)
+
+	messages := []Message{{Role: "user", Content: "hi"}}
+	if _, err := NewHTTPProvider("key", server.URL).Chat(context.Background(), messages, nil, "gpt-4o", options); err != nil {
+		t.Fatal(err)
+	}
+	if got["temperature"] != 0.0 || got["seed"] != 42.0 {
+		t.Errorf("request = %v", got)
+	}
+
+	if err := (&Sampling{Temperature: &[]float64{2.5}[0]}).Check(); err == nil {
+		t.Error("expected an error for temperature 2.5")
+	}
+	if !(&Sampling{}).IsEmpty() || (&Sampling{Seed: &seed}).IsEmpty() {
+		t.Error("IsEmpty")
+	}
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// StreamWithTools streams a response from any provider. Providers without
+// ToolStreamer get a regular Chat call whose content is sent as one chunk.
+func StreamWithTools(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callback StreamCallback) (*LLMResponse, error) {
+	if streamer, ok := provider.(ToolStreamer); ok {
+		return streamer.ChatStreamWithTools(ctx, messages, tools, model, options, callback)
+	}
+
+	response, err := provider.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	if response.Content != "" || response.Reasoning != "" {
+		callback(StreamChunk{Content: response.Content, Reasoning: response.Reasoning})
+	}
+	callback(StreamChunk{Done: true})
+	return response, nil
+}
+
+// streamCollector forwards chunks to the callback and assembles the full
+// response from them
+type streamCollector struct {
+	callback     StreamCallback
+	content      strings.Builder
+	reasoning    strings.Builder
+	calls        []*streamedToolCall
+	thinking     []ThinkingBlock
+	finishReason string
+	usage        *UsageInfo
+}
+
+// maxToolCallGap is how far past the calls seen so far a tool call delta's
+// index may point
+const maxToolCallGap = 16
+
+type streamedToolCall struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+func newStreamCollector(callback StreamCallback) *streamCollector {
+	return &streamCollector{callback: callback}
+}
+
+func (c *streamCollector) emit(chunk StreamChunk) {
+	c.content.WriteString(chunk.Content)
+	c.reasoning.WriteString(chunk.Reasoning)
+	for _, delta := range chunk.ToolCalls {
+		// Indexes count up from 0; skip ones that would panic or allocate
+		// without bound
+		if delta.Index < 0 || delta.Index > len(c.calls)+maxToolCallGap {
+			continue
+		}
+		for len(c.calls) <= delta.Index {
+			c.calls = append(c.calls, &streamedToolCall{})
+		}
+		call := c.calls[delta.Index]
+		if delta.ID != "" {
+			call.id = delta.ID
+		}
+		if delta.Name != "" {
+			call.name = delta.Name
+		}
+		call.arguments.WriteString(delta.Arguments)
+	}
+	if chunk.Content != "" || chunk.Reasoning != "" || len(chunk.ToolCalls) > 0 || chunk.Done {
+		c.callback(chunk)
+	}
+}
+
+// response returns the assembled response. Arguments that are not valid
+// JSON are kept under "raw", as in non-streaming responses.
+func (c *streamCollector) response() *LLMResponse {
+	response := &LLMResponse{
+		Content:      c.content.String(),
+		FinishReason: c.finishReason,
+		Usage:        c.usage,
+		Reasoning:    joinReasoning(c.reasoning.String()),
+		Thinking:     c.thinking,
+	}
+	for _, call := range c.calls {
+		if call.name == "" {
+			continue
+		}
+		arguments := make(map[string]interface{})
+		if raw := call.arguments.String(); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+				arguments["raw"] = raw
+			}
+		}
+		if arguments == nil { // "null"
+			arguments = make(map[string]interface{})
+		}
+		response.ToolCalls = append(response.ToolCalls, ToolCall{ID: call.id, Name: call.name, Arguments: arguments})
+	}
+	if response.FinishReason == "" {
+		response.FinishReason = "stop"
+		if len(response.ToolCalls) > 0 {
+			response.FinishReason = "tool_calls"
+		}
+	}
+	return response
+}
@@ -0,0 +1,124 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sseServer(t *testing.T, events ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPProvider_ChatStreamWithTools(t *testing.T) {
+	server := sseServer(t,
+		`{"choices":[{"delta":{"content":"Checking"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Jakarta\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`[DONE]`,
+	)
+
+	var content strings.Builder
+	deltas, done := 0, 0
+	p := NewHTTPProvider("key", server.URL)
+	response, err := p.ChatStreamWithTools(context.Background(), nil, []ToolDefinition{{Type: "function"}}, "gpt-4o", nil, func(chunk StreamChunk) {
+		content.WriteString(chunk.Content)
+		deltas += len(chunk.ToolCalls)
+		if chunk.Done {
+			done++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content.String() != "Checking" || response.Content != "Checking" {
+		t.Errorf("content = %q / %q", content.String(), response.Content)
+	}
+	if deltas != 3 || done != 1 {
+		t.Errorf("got %d tool-call deltas and %d done chunks", deltas, done)
+	}
+	if response.FinishReason != "tool_calls" || len(response.ToolCalls) != 1 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	call := response.ToolCalls[0]
+	if call.ID != "call_1" || call.Name != "get_weather" || call.Arguments["city"] != "Jakarta" {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+}
+
+func TestOpenCodeProvider_ChatStreamWithTools(t *testing.T) {
+	server := sseServer(t,
+		`{"type":"message_start","message":{"usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need weather."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":\"Jak"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"arta\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	)
+
+	p := NewOpenCodeProvider("key", server.URL)
+	response, err := p.ChatStreamWithTools(context.Background(), nil, nil, "minimax-m2.5", nil, func(StreamChunk) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.FinishReason != "tool_calls" || len(response.ToolCalls) != 1 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if call := response.ToolCalls[0]; call.ID != "toolu_1" || call.Arguments["city"] != "Jakarta" {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+	if len(response.Thinking) != 1 || response.Thinking[0].Signature != "sig" || response.Reasoning != "Need weather." {
+		t.Errorf("unexpected thinking: %+v %q", response.Thinking, response.Reasoning)
+	}
+	if response.Usage == nil || response.Usage.TotalTokens != 42 {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+}
+
+func TestStreamWithToolsFallback(t *testing.T) {
+	var chunks []StreamChunk
+	response, err := StreamWithTools(context.Background(), &fakeProvider{}, nil, nil, "m", nil, func(chunk StreamChunk) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Content != "ok" || len(chunks) != 2 || chunks[0].Content != "ok" || !chunks[1].Done {
+		t.Errorf("unexpected fallback stream: %+v", chunks)
+	}
+}
+
+func TestStreamCollectorSkipsBadToolCallIndexes(t *testing.T) {
+	c := newStreamCollector(func(StreamChunk) {})
+	c.emit(StreamChunk{ToolCalls: []ToolCallDelta{
+		{Index: -1, ID: "bad", Name: "exec"},
+		{Index: 1 << 30, ID: "huge", Name: "exec"},
+		{Index: 0, ID: "call_1", Name: "read_file", Arguments: `{"path":"a.txt"}`},
+	}})
+
+	if len(c.calls) != 1 {
+		t.Fatalf("expected 1 collected call, got %d", len(c.calls))
+	}
+	response := c.response()
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].ID != "call_1" || response.ToolCalls[0].Arguments["path"] != "a.txt" {
+		t.Errorf("tool calls = %+v", response.ToolCalls)
+	}
+}
@@ -20,6 +20,9 @@ type LLMResponse struct {
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
 	FinishReason string     `json:"finish_reason"`
 	Usage        *UsageInfo `json:"usage,omitempty"`
+	// Reasoning is the model's thinking, kept out of Content
+	Reasoning string          `json:"reasoning,omitempty"`
+	Thinking  []ThinkingBlock `json:"-"`
 }
 
 type UsageInfo struct {
@@ -33,6 +36,8 @@ type Message struct {
 	Content    interface{} `json:"content"` // Can be string or []ContentBlock for multimodal
 	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
 	ToolCallID string      `json:"tool_call_id,omitempty"`
+	// Thinking carries Claude thinking blocks back during tool use
+	Thinking []ThinkingBlock `json:"-"`
 }
 
 // ContentBlock represents a piece of content (text, image, or file)
@@ -59,8 +64,19 @@ type FileData struct {
 
 // StreamChunk represents a single chunk of streamed LLM output
 type StreamChunk struct {
-	Content string `json:"content"`
-	Done    bool   `json:"done"`
+	Content   string          `json:"content"`
+	Reasoning string          `json:"reasoning,omitempty"` // thinking delta, not part of the answer
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+	Done      bool            `json:"done"`
+}
+
+// ToolCallDelta is a fragment of a streamed tool call. ID and Name come with
+// the first fragment of a call; Arguments are pieces of its JSON arguments.
+type ToolCallDelta struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
 // StreamCallback is called for each chunk during streaming
@@ -72,6 +88,13 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// ToolStreamer is implemented by providers that can stream a response while
+// offering tools. The returned response holds the assembled content and
+// tool calls once the stream has ended.
+type ToolStreamer interface {
+	ChatStreamWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callback StreamCallback) (*LLMResponse, error)
+}
+
 type ToolDefinition struct {
 	Type     string                 `json:"type"`
 	Function ToolFunctionDefinition `json:"function"`
@@ -64,10 +64,8 @@ func NewVertexProvider(credentialsFile, projectID, region string) (*VertexProvid
 		projectID:       projectID,
 		region:          region,
 		credentialsFile: credentialsFile,
-		httpClient: &http.Client{
-			Timeout: 0,
-		},
-		tokenSource: creds.TokenSource,
+		httpClient:      newHTTPClient(),
+		tokenSource:     creds.TokenSource,
 	}, nil
 }
 
@@ -154,25 +152,32 @@ func (p *VertexProvider) Chat(ctx context.Context, messages []Message, tools []T
 
 // ChatStream sends a streaming chat completion request to Vertex AI
 func (p *VertexProvider) ChatStream(ctx context.Context, messages []Message, model string, options map[string]interface{}, callback StreamCallback) error {
+	_, err := p.ChatStreamWithTools(ctx, messages, nil, model, options, callback)
+	return err
+}
+
+// ChatStreamWithTools streams a Vertex AI response. Gemini sends each
+// function call whole, so every call arrives as a single delta.
+func (p *VertexProvider) ChatStreamWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callback StreamCallback) (*LLMResponse, error) {
 	modelName := strings.TrimPrefix(model, "vertex/")
 
-	requestBody := p.buildGenerateContentRequest(messages, nil, options)
+	requestBody := p.buildGenerateContentRequest(messages, tools, options)
 
 	endpoint := p.buildEndpointURL(modelName, true)
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return fmt.Errorf("vertex: failed to marshal request: %w", err)
+		return nil, fmt.Errorf("vertex: failed to marshal request: %w", err)
 	}
 
 	token, err := p.getToken()
 	if err != nil {
-		return fmt.Errorf("vertex: failed to get auth token: %w", err)
+		return nil, fmt.Errorf("vertex: failed to get auth token: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
 	if err != nil {
-		return fmt.Errorf("vertex: failed to create request: %w", err)
+		return nil, fmt.Errorf("vertex: failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -180,15 +185,22 @@ func (p *VertexProvider) ChatStream(ctx context.Context, messages []Message, mod
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("vertex: failed to send request: %w", err)
+		return nil, fmt.Errorf("vertex: failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("vertex API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("vertex API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
+	collector := newStreamCollector(callback)
+	finish := func() *LLMResponse {
+		collector.emit(StreamChunk{Done: true})
+		return collector.response()
+	}
+	calls := 0
+
 	// Vertex AI streaming returns newline-delimited JSON array chunks
 	scanner := bufio.NewScanner(resp.Body)
 	// Increase scanner buffer for large responses
@@ -214,8 +226,7 @@ func (p *VertexProvider) ChatStream(ctx context.Context, messages []Message, mod
 			if strings.HasPrefix(line, "data: ") {
 				line = strings.TrimPrefix(line, "data: ")
 				if line == "[DONE]" {
-					callback(StreamChunk{Done: true})
-					return nil
+					return finish(), nil
 				}
 			}
 		}
@@ -229,25 +240,47 @@ func (p *VertexProvider) ChatStream(ctx context.Context, messages []Message, mod
 			continue
 		}
 
+		if chunk.UsageMetadata != nil {
+			collector.usage = &UsageInfo{
+				PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
+				CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
+				TotalTokens:      chunk.UsageMetadata.TotalTokenCount,
+			}
+		}
+
 		for _, candidate := range chunk.Candidates {
 			for _, part := range candidate.Content.Parts {
-				if part.Text != "" {
-					callback(StreamChunk{Content: part.Text})
+				if part.Thought {
+					collector.emit(StreamChunk{Reasoning: part.Text})
+				} else if part.Text != "" {
+					collector.emit(StreamChunk{Content: part.Text})
+				}
+				if part.FunctionCall != nil {
+					args, _ := json.Marshal(part.FunctionCall.Args)
+					collector.emit(StreamChunk{ToolCalls: []ToolCallDelta{{
+						Index:     calls,
+						ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()),
+						Name:      part.FunctionCall.Name,
+						Arguments: string(args),
+					}}})
+					calls++
 				}
 			}
-			if candidate.FinishReason == "STOP" || candidate.FinishReason == "MAX_TOKENS" {
-				callback(StreamChunk{Done: true})
-				return nil
+			switch candidate.FinishReason {
+			case "STOP":
+				return finish(), nil
+			case "MAX_TOKENS":
+				collector.finishReason = "length"
+				return finish(), nil
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("vertex: error reading stream: %w", err)
+		return nil, fmt.Errorf("vertex: error reading stream: %w", err)
 	}
 
-	callback(StreamChunk{Done: true})
-	return nil
+	return finish(), nil
 }
 
 // GetDefaultModel returns the default model for Vertex AI
@@ -307,6 +340,7 @@ type vertexContent struct {
 
 type vertexPart struct {
 	Text         string              `json:"text,omitempty"`
+	Thought      bool                `json:"thought,omitempty"` // Text is a thought summary
 	FunctionCall *vertexFunctionCall `json:"functionCall,omitempty"`
 	InlineData   *vertexInlineData   `json:"inlineData,omitempty"`
 }
@@ -467,6 +501,31 @@ func (p *VertexProvider) buildGenerateContentRequest(messages []Message, tools [
 	if temperature, ok := options["temperature"].(float64); ok {
 		genConfig["temperature"] = temperature
 	}
+	if seed, ok := options["seed"].(int); ok {
+		genConfig["seed"] = seed
+	}
+	// Gemini rejects a JSON mime type together with function declarations,
+	// so with tools the format is described in the system instruction
+	if rf, ok := options["response_format"].(*ResponseFormat); ok && rf.WantsJSON() {
+		if len(tools) == 0 {
+			genConfig["responseMimeType"] = "application/json"
+			if rf.JSONSchema != nil && rf.JSONSchema.Schema != nil {
+				genConfig["responseJsonSchema"] = rf.JSONSchema.Schema
+			}
+		} else {
+			parts := []map[string]interface{}{{"text": rf.Instruction()}}
+			if systemInstruction != nil {
+				parts = append((*systemInstruction)["parts"].([]map[string]interface{}), parts...)
+			}
+			request["systemInstruction"] = map[string]interface{}{"parts": parts}
+		}
+	}
+	if r := reasoningOption(options); r != nil {
+		genConfig["thinkingConfig"] = map[string]interface{}{
+			"thinkingBudget":  r.Budget(),
+			"includeThoughts": true,
+		}
+	}
 	if len(genConfig) > 0 {
 		request["generationConfig"] = genConfig
 	}
@@ -490,11 +549,13 @@ func (p *VertexProvider) parseResponse(body []byte) (*LLMResponse, error) {
 
 	candidate := resp.Candidates[0]
 
-	var contentParts []string
+	var contentParts, thoughts []string
 	var toolCalls []ToolCall
 
 	for _, part := range candidate.Content.Parts {
-		if part.Text != "" {
+		if part.Thought {
+			thoughts = append(thoughts, part.Text)
+		} else if part.Text != "" {
 			contentParts = append(contentParts, part.Text)
 		}
 		if part.FunctionCall != nil {
@@ -532,6 +593,7 @@ func (p *VertexProvider) parseResponse(body []byte) (*LLMResponse, error) {
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 		Usage:        usage,
+		Reasoning:    joinReasoning(thoughts...),
 	}, nil
 }
 
@@ -0,0 +1,359 @@
+package report
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+// Report formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+// toolOutputLimit caps each tool output in a report
+const toolOutputLimit = 4000
+
+// Options selects what goes into a report
+type Options struct {
+	Title  string
+	Format string // markdown (default), html or pdf
+	// Tools includes tool calls and their output, not just the conversation
+	Tools bool
+	// Images are screenshots and other image files added at the end
+	Images []string
+}
+
+// entry is one message of a transcript as it appears in a report
+type entry struct {
+	role   string
+	text   string
+	tool   string   // tool name, for tool calls and results
+	images []string // image URLs or paths attached to the message
+}
+
+type section struct {
+	key     string
+	created time.Time
+	updated time.Time
+	summary string
+	entries []entry
+}
+
+// Generate renders the sessions in the requested format. PDF output needs
+// wkhtmltopdf on the PATH.
+func Generate(sessions []*session.Session, opts Options) ([]byte, error) {
+	if opts.Title == "" {
+		opts.Title = "Conversation Report"
+	}
+	sections := make([]section, 0, len(sessions))
+	for _, s := range sessions {
+		sections = append(sections, collect(s, opts.Tools))
+	}
+
+	switch opts.Format {
+	case "", FormatMarkdown:
+		return []byte(markdown(opts, sections)), nil
+	case FormatHTML:
+		return []byte(renderHTML(opts, sections)), nil
+	case FormatPDF:
+		return pdf(renderHTML(opts, sections))
+	}
+	return nil, fmt.Errorf("unknown report format %q (use markdown, html or pdf)", opts.Format)
+}
+
+// Save generates a report and writes it to workspace/reports, returning the
+// file path
+func Save(workspace string, sessions []*session.Session, opts Options) (string, error) {
+	data, err := Generate(sessions, opts)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(workspace, "reports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name := slug(opts.Title)
+	if name == "" {
+		name = "report"
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), extension(opts.Format)))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func extension(format string) string {
+	switch format {
+	case FormatHTML:
+		return "html"
+	case FormatPDF:
+		return "pdf"
+	}
+	return "md"
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(title string) string {
+	s := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(s) > 50 {
+		s = strings.TrimRight(s[:50], "-")
+	}
+	return s
+}
+
+// collect turns a session's history into report entries
+func collect(s *session.Session, tools bool) section {
+	sec := section{key: s.Key, created: s.Created, updated: s.Updated, summary: s.Summary}
+	for _, m := range s.Messages {
+		text, images := parts(m.Content)
+		switch m.Role {
+		case "system":
+			continue
+		case "tool":
+			if tools {
+				sec.entries = append(sec.entries, entry{role: "tool", tool: toolName(s.Messages, m.ToolCallID), text: truncate(text)})
+			}
+			continue
+		case "assistant":
+			if tools {
+				for _, tc := range m.ToolCalls {
+					name, args := callOf(tc)
+					sec.entries = append(sec.entries, entry{role: "call", tool: name, text: args})
+				}
+			}
+		}
+		if strings.TrimSpace(text) == "" && len(images) == 0 {
+			continue
+		}
+		sec.entries = append(sec.entries, entry{role: m.Role, text: text, images: images})
+	}
+	return sec
+}
+
+// parts splits message content into text and image URLs. Content is a
+// string or content blocks, which come back as maps from saved sessions.
+func parts(content interface{}) (string, []string) {
+	switch c := content.(type) {
+	case string:
+		return c, nil
+	case []providers.ContentBlock:
+		var texts, images []string
+		for _, b := range c {
+			if b.Type == "text" {
+				texts = append(texts, b.Text)
+			} else if b.Type == "image_url" && b.ImageURL != nil {
+				images = append(images, b.ImageURL.URL)
+			}
+		}
+		return strings.Join(texts, "\n"), images
+	case []interface{}:
+		var texts, images []string
+		for _, item := range c {
+			b, _ := item.(map[string]interface{})
+			switch b["type"] {
+			case "text":
+				if t, ok := b["text"].(string); ok {
+					texts = append(texts, t)
+				}
+			case "image_url":
+				if img, ok := b["image_url"].(map[string]interface{}); ok {
+					if u, ok := img["url"].(string); ok {
+						images = append(images, u)
+					}
+				}
+			}
+		}
+		return strings.Join(texts, "\n"), images
+	}
+	return "", nil
+}
+
+func callOf(tc providers.ToolCall) (string, string) {
+	if tc.Function != nil {
+		return tc.Function.Name, tc.Function.Arguments
+	}
+	var args []string
+	for k, v := range tc.Arguments {
+		args = append(args, fmt.Sprintf("%s=%v", k, v))
+	}
+	return tc.Name, strings.Join(args, ", ")
+}
+
+// toolName finds the name of the tool call a result answers
+func toolName(messages []providers.Message, id string) string {
+	for _, m := range messages {
+		for _, tc := range m.ToolCalls {
+			if tc.ID == id {
+				name, _ := callOf(tc)
+				return name
+			}
+		}
+	}
+	return "tool"
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= toolOutputLimit {
+		return s
+	}
+	return string(runes[:toolOutputLimit]) + fmt.Sprintf("\n... (%d more characters)", len(runes)-toolOutputLimit)
+}
+
+func roleLabel(e entry) string {
+	switch e.role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "call":
+		return "Tool call: " + e.tool
+	case "tool":
+		return "Tool output: " + e.tool
+	}
+	return e.role
+}
+
+func markdown(opts Options, sections []section) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", opts.Title)
+	fmt.Fprintf(&sb, "_Generated %s_\n", time.Now().Format("2006-01-02 15:04"))
+
+	for _, sec := range sections {
+		fmt.Fprintf(&sb, "\n## %s\n\n", sec.key)
+		fmt.Fprintf(&sb, "%s to %s, %d messages\n", sec.created.Format("2006-01-02 15:04"), sec.updated.Format("2006-01-02 15:04"), len(sec.entries))
+		if sec.summary != "" {
+			fmt.Fprintf(&sb, "\n> %s\n", strings.ReplaceAll(sec.summary, "\n", "\n> "))
+		}
+		for _, e := range sec.entries {
+			fmt.Fprintf(&sb, "\n### %s\n\n", roleLabel(e))
+			if e.role == "call" || e.role == "tool" {
+				fmt.Fprintf(&sb, "```\n%s\n```\n", strings.ReplaceAll(e.text, "```", "'''"))
+			} else if e.text != "" {
+				sb.WriteString(e.text + "\n")
+			}
+			for _, img := range e.images {
+				if strings.HasPrefix(img, "data:") {
+					sb.WriteString("\n_(image attached)_\n")
+				} else {
+					fmt.Fprintf(&sb, "\n![image](%s)\n", img)
+				}
+			}
+		}
+	}
+
+	if len(opts.Images) > 0 {
+		sb.WriteString("\n## Screenshots\n")
+		for _, img := range opts.Images {
+			fmt.Fprintf(&sb, "\n![%s](%s)\n", filepath.Base(img), img)
+		}
+	}
+	return sb.String()
+}
+
+const stylesheet = `body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;max-width:860px;margin:2em auto;padding:0 1em;color:#1f2328;line-height:1.5}
+h1{border-bottom:2px solid #2da44e;padding-bottom:.3em}
+h2{margin-top:2em;border-bottom:1px solid #d0d7de;padding-bottom:.2em}
+.meta{color:#656d76;font-size:.9em}
+.summary{border-left:4px solid #d0d7de;padding:.2em 1em;color:#424a53}
+.msg{border-radius:8px;padding:.6em 1em;margin:.8em 0}
+.msg .role{font-weight:600;font-size:.85em;color:#656d76;margin-bottom:.3em}
+.user{background:#ddf4ff}
+.assistant{background:#f6f8fa}
+.call,.tool{background:#fff8c5;font-size:.9em}
+pre{white-space:pre-wrap;word-break:break-word;margin:0;font-family:ui-monospace,Menlo,Consolas,monospace}
+.text{white-space:pre-wrap}
+img{max-width:100%;border:1px solid #d0d7de;border-radius:6px;margin-top:.5em}`
+
+func renderHTML(opts Options, sections []section) string {
+	var sb strings.Builder
+	title := html.EscapeString(opts.Title)
+	fmt.Fprintf(&sb, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", title, stylesheet)
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n<p class=\"meta\">Generated %s</p>\n", title, time.Now().Format("2006-01-02 15:04"))
+
+	for _, sec := range sections {
+		fmt.Fprintf(&sb, "<h2>%s</h2>\n", html.EscapeString(sec.key))
+		fmt.Fprintf(&sb, "<p class=\"meta\">%s to %s, %d messages</p>\n", sec.created.Format("2006-01-02 15:04"), sec.updated.Format("2006-01-02 15:04"), len(sec.entries))
+		if sec.summary != "" {
+			fmt.Fprintf(&sb, "<blockquote class=\"summary text\">%s</blockquote>\n", html.EscapeString(sec.summary))
+		}
+		for _, e := range sec.entries {
+			fmt.Fprintf(&sb, "<div class=\"msg %s\">\n<div class=\"role\">%s</div>\n", e.role, html.EscapeString(roleLabel(e)))
+			if e.role == "call" || e.role == "tool" {
+				fmt.Fprintf(&sb, "<pre>%s</pre>\n", html.EscapeString(e.text))
+			} else if e.text != "" {
+				fmt.Fprintf(&sb, "<div class=\"text\">%s</div>\n", html.EscapeString(e.text))
+			}
+			for _, img := range e.images {
+				fmt.Fprintf(&sb, "<img src=\"%s\" alt=\"image\">\n", html.EscapeString(embed(img)))
+			}
+			sb.WriteString("</div>\n")
+		}
+	}
+
+	if len(opts.Images) > 0 {
+		sb.WriteString("<h2>Screenshots</h2>\n")
+		for _, img := range opts.Images {
+			fmt.Fprintf(&sb, "<figure><img src=\"%s\" alt=\"%s\"><figcaption class=\"meta\">%s</figcaption></figure>\n",
+				html.EscapeString(embed(img)), html.EscapeString(filepath.Base(img)), html.EscapeString(filepath.Base(img)))
+		}
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// embed turns a local image into a data URL so the HTML file stands alone.
+// URLs and unreadable files are left as they are.
+func embed(src string) string {
+	if strings.Contains(src, "://") || strings.HasPrefix(src, "data:") {
+		return src
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return src
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(src)))
+	if mimeType == "" {
+		mimeType = "image/png"
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// pdf converts the HTML report with wkhtmltopdf
+func pdf(page string) ([]byte, error) {
+	bin, err := exec.LookPath("wkhtmltopdf")
+	if err != nil {
+		return nil, fmt.Errorf("PDF reports need wkhtmltopdf, which was not found; install it or use the html format")
+	}
+	dir, err := os.MkdirTemp("", "pepebot-report-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "report.html")
+	out := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(in, []byte(page), 0644); err != nil {
+		return nil, err
+	}
+	if output, err := exec.Command(bin, "--quiet", "--encoding", "utf-8", in, out).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("wkhtmltopdf failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(out)
+}
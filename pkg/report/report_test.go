@@ -0,0 +1,102 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+func testSession() *session.Session {
+	return &session.Session{
+		Key:     "telegram:42",
+		Summary: "User asked about the weather.",
+		Created: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		Updated: time.Date(2026, 10, 1, 9, 5, 0, 0, time.UTC),
+		Messages: []providers.Message{
+			{Role: "system", Content: "You are pepebot"},
+			{Role: "user", Content: "Weather in <Jakarta>?"},
+			{Role: "assistant", ToolCalls: []providers.ToolCall{{
+				ID:       "call_1",
+				Function: &providers.FunctionCall{Name: "web_search", Arguments: `{"query":"jakarta weather"}`},
+			}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "32°C, sunny"},
+			{Role: "assistant", Content: "It is 32°C and sunny."},
+		},
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	data, err := Generate([]*session.Session{testSession()}, Options{Title: "Weather"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	md := string(data)
+	for _, want := range []string{"# Weather", "## telegram:42", "> User asked about the weather.", "### User\n\nWeather in <Jakarta>?", "It is 32°C and sunny."} {
+		if !strings.Contains(md, want) {
+			t.Errorf("missing %q in:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "You are pepebot") || strings.Contains(md, "web_search") {
+		t.Errorf("system prompt and tool calls should be left out:\n%s", md)
+	}
+
+	data, _ = Generate([]*session.Session{testSession()}, Options{Tools: true})
+	md = string(data)
+	if !strings.Contains(md, "### Tool call: web_search") || !strings.Contains(md, "### Tool output: web_search\n\n```\n32°C, sunny\n```") {
+		t.Errorf("expected tool calls and output:\n%s", md)
+	}
+}
+
+func TestHTMLReportEmbedsImages(t *testing.T) {
+	dir := t.TempDir()
+	shot := filepath.Join(dir, "screen.png")
+	os.WriteFile(shot, []byte("png"), 0644)
+
+	// Saved sessions come back with content blocks as maps
+	s := testSession()
+	var blocks interface{}
+	json.Unmarshal([]byte(`[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,AAAA"}}]`), &blocks)
+	s.Messages = append(s.Messages, providers.Message{Role: "user", Content: blocks})
+
+	data, err := Generate([]*session.Session{s}, Options{Title: "A & B", Format: FormatHTML, Images: []string{shot}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	page := string(data)
+	for _, want := range []string{"<title>A &amp; B</title>", "Weather in &lt;Jakarta&gt;?", "what is this?", `src="data:image/jpeg;base64,AAAA"`, `src="data:image/png;base64,cG5n"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
+
+func TestSave(t *testing.T) {
+	workspace := t.TempDir()
+	path, err := Save(workspace, []*session.Session{testSession()}, Options{Title: "Trip: Bali 2026!", Format: FormatHTML})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if filepath.Dir(path) != filepath.Join(workspace, "reports") || !strings.HasPrefix(filepath.Base(path), "trip-bali-2026-") || filepath.Ext(path) != ".html" {
+		t.Fatalf("unexpected path %s", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+
+	if _, err := Generate(nil, Options{Format: "docx"}); err == nil {
+		t.Fatal("expected an unknown format error")
+	}
+}
+
+func TestTruncateKeepsRunes(t *testing.T) {
+	got := truncate(strings.Repeat("é", toolOutputLimit+5))
+	if want := strings.Repeat("é", toolOutputLimit) + "\n... (5 more characters)"; got != want {
+		t.Errorf("truncate = ...%q", got[len(got)-40:])
+	}
+}
@@ -0,0 +1,419 @@
+// Package rules routes inbound bus events with conditions and actions read
+// from workspace/rules.yaml, without involving the LLM. A rule can notify a
+// chat, run a workflow or hand the event to an agent, and can stop the
+// event from reaching the default agent.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+// Event sources, derived from the sender of an inbound message
+const (
+	SourceMessage = "message"
+	SourceWebhook = "webhook"
+	SourceLogcat  = "logcat"
+	SourceFeed    = "feed"
+)
+
+// Sender delivers notifications. Implemented by channels.Manager.
+type Sender interface {
+	SendToChannel(ctx context.Context, channelName, chatID, content string) error
+}
+
+// Publisher injects messages for agents. Implemented by bus.MessageBus.
+type Publisher interface {
+	PublishInbound(msg bus.InboundMessage)
+}
+
+// WorkflowRunner runs saved workflows. Implemented by agent.AgentManager.
+type WorkflowRunner interface {
+	RunWorkflow(ctx context.Context, name string, vars map[string]string) (string, error)
+}
+
+// File is the layout of rules.yaml
+type File struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule runs Actions for events matching When. Stop keeps matched events
+// from the default agent.
+type Rule struct {
+	Name      string    `json:"name" yaml:"name"`
+	When      Condition `json:"when" yaml:"when"`
+	Actions   []Action  `json:"actions" yaml:"actions"`
+	Stop      bool      `json:"stop,omitempty" yaml:"stop,omitempty"`
+	CooldownS int       `json:"cooldown_s,omitempty" yaml:"cooldown_s,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+
+	match *regexp.Regexp
+}
+
+// Condition matches events. All fields that are set must match. Channel,
+// ChatID, Sender and Metadata values are glob patterns ("hook:*"); Contains
+// is a case-insensitive substring and Match a regular expression of the
+// content.
+type Condition struct {
+	Source   string            `json:"source,omitempty" yaml:"source,omitempty"`
+	Channel  string            `json:"channel,omitempty" yaml:"channel,omitempty"`
+	ChatID   string            `json:"chat_id,omitempty" yaml:"chat_id,omitempty"`
+	Sender   string            `json:"sender,omitempty" yaml:"sender,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Contains string            `json:"contains,omitempty" yaml:"contains,omitempty"`
+	Match    string            `json:"match,omitempty" yaml:"match,omitempty"`
+}
+
+// Action is one of notify, workflow or agent. Text fields take
+// {{placeholders}}: content, channel, chat_id, sender, source, rule, match,
+// group1, group2, ... and meta.<key>.
+type Action struct {
+	Notify   *NotifyAction   `json:"notify,omitempty" yaml:"notify,omitempty"`
+	Workflow *WorkflowAction `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	Agent    *AgentAction    `json:"agent,omitempty" yaml:"agent,omitempty"`
+}
+
+// NotifyAction sends Message (default: the event content) to a chat
+type NotifyAction struct {
+	Channel string `json:"channel" yaml:"channel"`
+	ChatID  string `json:"chat_id" yaml:"chat_id"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// WorkflowAction runs a workflow and, with a chat, posts its result
+type WorkflowAction struct {
+	Name    string            `json:"name" yaml:"name"`
+	Vars    map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Channel string            `json:"channel,omitempty" yaml:"channel,omitempty"`
+	ChatID  string            `json:"chat_id,omitempty" yaml:"chat_id,omitempty"`
+}
+
+// AgentAction sends Prompt (default: the event content) to an agent, whose
+// reply goes to the chat (default: the event's chat)
+type AgentAction struct {
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Prompt  string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	ChatID  string `json:"chat_id,omitempty" yaml:"chat_id,omitempty"`
+}
+
+// Load reads and validates a rules file
+func Load(file string) ([]Rule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	for i := range f.Rules {
+		if err := f.Rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return f.Rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("every rule needs a name")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("rule %s: no actions", r.Name)
+	}
+	for _, a := range r.Actions {
+		n := 0
+		if a.Notify != nil {
+			n++
+			if a.Notify.Channel == "" || a.Notify.ChatID == "" {
+				return fmt.Errorf("rule %s: notify needs channel and chat_id", r.Name)
+			}
+		}
+		if a.Workflow != nil {
+			n++
+			if a.Workflow.Name == "" {
+				return fmt.Errorf("rule %s: workflow needs a name", r.Name)
+			}
+		}
+		if a.Agent != nil {
+			n++
+		}
+		if n != 1 {
+			return fmt.Errorf("rule %s: each action must be exactly one of notify, workflow or agent", r.Name)
+		}
+	}
+	switch r.When.Source {
+	case "", SourceMessage, SourceWebhook, SourceLogcat, SourceFeed:
+	default:
+		return fmt.Errorf("rule %s: unknown source %q", r.Name, r.When.Source)
+	}
+	if r.When.Match != "" {
+		re, err := regexp.Compile(r.When.Match)
+		if err != nil {
+			return fmt.Errorf("rule %s: invalid match: %w", r.Name, err)
+		}
+		r.match = re
+	}
+	return nil
+}
+
+// SourceOf tells where an inbound message came from
+func SourceOf(msg bus.InboundMessage) string {
+	switch {
+	case strings.HasPrefix(msg.SenderID, "hook:"):
+		return SourceWebhook
+	case strings.HasPrefix(msg.SenderID, "logcat:"):
+		return SourceLogcat
+	case msg.SenderID == "feeds":
+		return SourceFeed
+	}
+	return SourceMessage
+}
+
+// Matches reports whether the rule applies to msg and returns the
+// placeholder values for its actions
+func (r *Rule) Matches(msg bus.InboundMessage) (map[string]string, bool) {
+	w := r.When
+	source := SourceOf(msg)
+	if w.Source != "" && w.Source != source {
+		return nil, false
+	}
+	if !glob(w.Channel, msg.Channel) || !glob(w.ChatID, msg.ChatID) || !glob(w.Sender, msg.SenderID) {
+		return nil, false
+	}
+	for k, pattern := range w.Metadata {
+		if !glob(pattern, msg.Metadata[k]) {
+			return nil, false
+		}
+	}
+	if w.Contains != "" && !strings.Contains(strings.ToLower(msg.Content), strings.ToLower(w.Contains)) {
+		return nil, false
+	}
+
+	vars := map[string]string{
+		"content": msg.Content,
+		"channel": msg.Channel,
+		"chat_id": msg.ChatID,
+		"sender":  msg.SenderID,
+		"source":  source,
+		"rule":    r.Name,
+	}
+	for k, v := range msg.Metadata {
+		vars["meta."+k] = v
+	}
+	if r.match != nil {
+		m := r.match.FindStringSubmatch(msg.Content)
+		if m == nil {
+			return nil, false
+		}
+		for i, g := range m {
+			if i == 0 {
+				vars["match"] = g
+			} else {
+				vars[fmt.Sprintf("group%d", i)] = g
+			}
+		}
+	}
+	return vars, true
+}
+
+func glob(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
+
+// expand replaces {{name}} placeholders with vars
+func expand(s string, vars map[string]string) string {
+	for k, v := range vars {
+		s = strings.ReplaceAll(s, "{{"+k+"}}", v)
+	}
+	return s
+}
+
+// Engine evaluates the rules in workspace/rules.yaml, reloading the file
+// when it changes
+type Engine struct {
+	file      string
+	sender    Sender
+	publisher Publisher
+	workflows WorkflowRunner
+
+	mu       sync.Mutex
+	rules    []Rule
+	modTime  time.Time
+	lastFire map[string]time.Time
+}
+
+func NewEngine(workspace string, sender Sender, publisher Publisher, workflows WorkflowRunner) *Engine {
+	return &Engine{
+		file:      filepath.Join(workspace, "rules.yaml"),
+		sender:    sender,
+		publisher: publisher,
+		workflows: workflows,
+		lastFire:  make(map[string]time.Time),
+	}
+}
+
+// Rules returns the current rules, reloading the file if it changed. A
+// file that fails to load keeps the previous rules.
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info, err := os.Stat(e.file)
+	if err != nil {
+		e.rules, e.modTime = nil, time.Time{}
+		return nil
+	}
+	if !info.ModTime().Equal(e.modTime) {
+		e.modTime = info.ModTime()
+		rules, err := Load(e.file)
+		if err != nil {
+			logger.WarnCF("rules", "Failed to load rules, keeping the previous ones", map[string]interface{}{
+				"file":  e.file,
+				"error": err.Error(),
+			})
+		} else {
+			e.rules = rules
+			logger.InfoCF("rules", "Rules loaded", map[string]interface{}{"count": len(rules)})
+		}
+	}
+	return e.rules
+}
+
+// Handle evaluates the rules for an inbound message, starts the actions of
+// every matching rule and reports whether one of them stops the message
+// from reaching the agent. Messages that rules sent to agents are not
+// evaluated again.
+func (e *Engine) Handle(ctx context.Context, msg bus.InboundMessage) bool {
+	if msg.Metadata["rule"] != "" {
+		return false
+	}
+
+	stop := false
+	now := time.Now()
+	for _, r := range e.Rules() {
+		if r.Disabled {
+			continue
+		}
+		vars, ok := r.Matches(msg)
+		if !ok {
+			continue
+		}
+
+		e.mu.Lock()
+		cooling := r.CooldownS > 0 && now.Sub(e.lastFire[r.Name]) < time.Duration(r.CooldownS)*time.Second
+		if !cooling {
+			e.lastFire[r.Name] = now
+		}
+		e.mu.Unlock()
+		if cooling {
+			continue
+		}
+
+		logger.InfoCF("rules", "Rule matched", map[string]interface{}{
+			"rule":    r.Name,
+			"channel": msg.Channel,
+			"sender":  msg.SenderID,
+		})
+		stop = stop || r.Stop
+		go e.run(ctx, r, msg, vars)
+	}
+	return stop
+}
+
+func (e *Engine) run(ctx context.Context, r Rule, msg bus.InboundMessage, vars map[string]string) {
+	for _, a := range r.Actions {
+		var err error
+		switch {
+		case a.Notify != nil:
+			err = e.notify(ctx, a.Notify, vars)
+		case a.Workflow != nil:
+			err = e.runWorkflow(ctx, r, a.Workflow, vars)
+		case a.Agent != nil:
+			err = e.invokeAgent(r, a.Agent, msg, vars)
+		}
+		if err != nil {
+			logger.ErrorCF("rules", "Rule action failed", map[string]interface{}{
+				"rule":  r.Name,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+func (e *Engine) notify(ctx context.Context, a *NotifyAction, vars map[string]string) error {
+	if e.sender == nil {
+		return fmt.Errorf("no channel sender available")
+	}
+	message := "{{content}}"
+	if a.Message != "" {
+		message = a.Message
+	}
+	return e.sender.SendToChannel(ctx, expand(a.Channel, vars), expand(a.ChatID, vars), expand(message, vars))
+}
+
+func (e *Engine) runWorkflow(ctx context.Context, r Rule, a *WorkflowAction, vars map[string]string) error {
+	if e.workflows == nil {
+		return fmt.Errorf("workflows are not available")
+	}
+	wfVars := make(map[string]string, len(a.Vars))
+	for k, v := range a.Vars {
+		wfVars[k] = expand(v, vars)
+	}
+	output, err := e.workflows.RunWorkflow(ctx, a.Name, wfVars)
+	if a.Channel == "" || a.ChatID == "" || e.sender == nil {
+		return err
+	}
+	content := fmt.Sprintf("📋 Workflow '%s' (rule: %s)\n\n%s", a.Name, r.Name, output)
+	if err != nil {
+		content = fmt.Sprintf("⚠️ Workflow '%s' (rule: %s) failed: %v", a.Name, r.Name, err)
+	}
+	if sendErr := e.sender.SendToChannel(ctx, expand(a.Channel, vars), expand(a.ChatID, vars), content); sendErr != nil && err == nil {
+		err = sendErr
+	}
+	return err
+}
+
+func (e *Engine) invokeAgent(r Rule, a *AgentAction, msg bus.InboundMessage, vars map[string]string) error {
+	if e.publisher == nil {
+		return fmt.Errorf("no agent bus available")
+	}
+	prompt := "{{content}}"
+	if a.Prompt != "" {
+		prompt = a.Prompt
+	}
+	channel, chatID := msg.Channel, msg.ChatID
+	if a.Channel != "" {
+		channel, chatID = expand(a.Channel, vars), expand(a.ChatID, vars)
+	}
+	e.publisher.PublishInbound(bus.InboundMessage{
+		Channel:    channel,
+		SenderID:   "rule:" + r.Name,
+		ChatID:     chatID,
+		Content:    expand(prompt, vars),
+		Media:      msg.Media,
+		SessionKey: "rule:" + r.Name,
+		Metadata: map[string]string{
+			"agent": a.Name,
+			"rule":  r.Name,
+		},
+	})
+	return nil
+}
@@ -0,0 +1,166 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+)
+
+const sampleRules = `rules:
+  - name: crashes
+    when:
+      source: logcat
+      match: 'FATAL EXCEPTION: (\w+)'
+    actions:
+      - notify: {channel: telegram, chat_id: "1", message: "Crash in {{group1}} ({{meta.watcher}})"}
+      - workflow: {name: triage, vars: {line: "{{content}}"}}
+    stop: true
+  - name: github-pushes
+    when:
+      sender: "hook:*"
+      metadata: {event: push}
+    actions:
+      - agent: {name: ops, prompt: "Summarize: {{content}}"}
+    cooldown_s: 60
+  - name: muted
+    disabled: true
+    when: {contains: fatal}
+    actions:
+      - notify: {channel: telegram, chat_id: "1"}
+`
+
+type recorder struct {
+	mu        sync.Mutex
+	sent      []string
+	published []bus.InboundMessage
+	workflows []string
+	done      chan struct{}
+}
+
+func (r *recorder) SendToChannel(ctx context.Context, channel, chatID, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, channel+":"+chatID+" "+content)
+	return nil
+}
+
+func (r *recorder) PublishInbound(msg bus.InboundMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, msg)
+	r.done <- struct{}{}
+}
+
+func (r *recorder) RunWorkflow(ctx context.Context, name string, vars map[string]string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workflows = append(r.workflows, name+" "+vars["line"])
+	r.done <- struct{}{}
+	return "ok", nil
+}
+
+func (r *recorder) wait(t *testing.T) {
+	select {
+	case <-r.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("action did not run")
+	}
+}
+
+func newTestEngine(t *testing.T) (*Engine, *recorder) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rules.yaml"), []byte(sampleRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rec := &recorder{done: make(chan struct{}, 4)}
+	return NewEngine(dir, rec, rec, rec), rec
+}
+
+func TestHandleRunsMatchingRules(t *testing.T) {
+	engine, rec := newTestEngine(t)
+	if n := len(engine.Rules()); n != 3 {
+		t.Fatalf("expected 3 rules, got %d", n)
+	}
+
+	crash := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "logcat:crashes",
+		ChatID:   "1",
+		Content:  "E AndroidRuntime: FATAL EXCEPTION: main",
+		Metadata: map[string]string{"watcher": "crashes"},
+	}
+	if !engine.Handle(context.Background(), crash) {
+		t.Error("the crash rule should stop the message")
+	}
+	rec.wait(t)
+	rec.mu.Lock()
+	if len(rec.sent) != 1 || rec.sent[0] != "telegram:1 Crash in main (crashes)" {
+		t.Errorf("unexpected notifications %q", rec.sent)
+	}
+	if len(rec.workflows) != 1 || rec.workflows[0] != "triage "+crash.Content {
+		t.Errorf("unexpected workflows %q", rec.workflows)
+	}
+	rec.mu.Unlock()
+
+	push := bus.InboundMessage{Channel: "discord", ChatID: "42", SenderID: "hook:github", Content: "3 commits", Metadata: map[string]string{"event": "push"}}
+	if engine.Handle(context.Background(), push) {
+		t.Error("the push rule should let the message through")
+	}
+	rec.wait(t)
+	rec.mu.Lock()
+	msg := rec.published[0]
+	rec.mu.Unlock()
+	if msg.Content != "Summarize: 3 commits" || msg.ChatID != "42" || msg.Metadata["agent"] != "ops" || msg.Metadata["rule"] != "github-pushes" {
+		t.Errorf("unexpected agent message %+v", msg)
+	}
+
+	// Cooldown, and messages from rules are not evaluated again
+	engine.Handle(context.Background(), push)
+	engine.Handle(context.Background(), msg)
+	time.Sleep(50 * time.Millisecond)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.published) != 1 {
+		t.Errorf("expected one agent message, got %d", len(rec.published))
+	}
+}
+
+func TestLoadRejectsInvalidRules(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rules.yaml")
+	for _, content := range []string{
+		"rules:\n  - name: x\n    actions: []\n",
+		"rules:\n  - name: x\n    when: {match: '('}\n    actions:\n      - agent: {}\n",
+		"rules:\n  - name: x\n    actions:\n      - notify: {channel: telegram}\n",
+		"rules:\n  - name: x\n    when: {source: email}\n    actions:\n      - agent: {}\n",
+	} {
+		os.WriteFile(file, []byte(content), 0644)
+		if _, err := Load(file); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+func TestRulesReloadAndKeepLastGood(t *testing.T) {
+	engine, _ := newTestEngine(t)
+	engine.Rules()
+
+	// A broken edit keeps the loaded rules
+	os.WriteFile(engine.file, []byte("rules: ["), 0644)
+	os.Chtimes(engine.file, time.Now(), time.Now().Add(time.Second))
+	if n := len(engine.Rules()); n != 3 {
+		t.Fatalf("expected the previous 3 rules, got %d", n)
+	}
+
+	os.WriteFile(engine.file, []byte(strings.SplitN(sampleRules, "  - name: github-pushes", 2)[0]), 0644)
+	os.Chtimes(engine.file, time.Now(), time.Now().Add(2*time.Second))
+	if n := len(engine.Rules()); n != 1 {
+		t.Fatalf("expected 1 rule after reload, got %d", n)
+	}
+}
@@ -0,0 +1,223 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// KeyEnv overrides the key file with a passphrase, for hosts where the key
+// should not live on disk next to the store.
+const KeyEnv = "PEPEBOT_SECRETS_KEY"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Store keeps named secrets AES-GCM encrypted in secrets.json. The key is a
+// random 32 bytes in secrets.key (created on the first Set) or derived from
+// PEPEBOT_SECRETS_KEY. Both files live in the pepebot home directory, outside
+// the workspace, so they are never picked up by workspace sync.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// sealed is the on-disk format of secrets.json
+type sealed struct {
+	Version int    `json:"version"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// ValidName reports whether name can be used as a secret name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Resolve returns the secret from the store, falling back to the environment
+// variable of the same name.
+func (s *Store) Resolve(name string) (string, error) {
+	s.mu.Lock()
+	values, err := s.load(false)
+	s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if v, ok := values[name]; ok {
+		return v, nil
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("secret '%s' not found (set it with 'pepebot secrets set %s' or the %s environment variable)", name, name, name)
+}
+
+// Set stores or replaces a secret
+func (s *Store) Set(name, value string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid secret name '%s' (use letters, digits and underscores)", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(true)
+	if err != nil {
+		return err
+	}
+	values[name] = value
+	return s.save(values)
+}
+
+// Delete removes a secret and reports whether it existed
+func (s *Store) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(false)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := values[name]; !ok {
+		return false, nil
+	}
+	delete(values, name)
+	return true, s.save(values)
+}
+
+// Names lists the stored secret names, sorted. Values are never listed.
+func (s *Store) Names() ([]string, error) {
+	s.mu.Lock()
+	values, err := s.load(false)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *Store) path() string {
+	return filepath.Join(s.dir, "secrets.json")
+}
+
+func (s *Store) keyPath() string {
+	return filepath.Join(s.dir, "secrets.key")
+}
+
+// key returns the encryption key. With create set, a missing key file is
+// generated; otherwise it is an error.
+func (s *Store) key(create bool) ([]byte, error) {
+	if passphrase := os.Getenv(KeyEnv); passphrase != "" {
+		sum := sha256.Sum256([]byte(passphrase))
+		return sum[:], nil
+	}
+	key, err := os.ReadFile(s.keyPath())
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid key file %s", s.keyPath())
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.keyPath(), key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secrets key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *Store) load(create bool) (map[string]string, error) {
+	values := map[string]string{}
+	data, err := os.ReadFile(s.path())
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var box sealed
+	if err := json.Unmarshal(data, &box); err != nil {
+		return nil, fmt.Errorf("invalid secrets file: %w", err)
+	}
+	key, err := s.key(create)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, box.Nonce, box.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets (wrong key?)")
+	}
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("invalid secrets file: %w", err)
+	}
+	return values, nil
+}
+
+func (s *Store) save(values map[string]string) error {
+	key, err := s.key(true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sealed{Version: 1, Nonce: nonce, Data: gcm.Seal(nil, nonce, plain, nil)}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	tmp := s.path() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path())
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	if err := store.Set("API_TOKEN", "tok-123"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Set("bad-name", "x"); err == nil {
+		t.Error("invalid name should be rejected")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "secrets.json"))
+	if strings.Contains(string(data), "tok-123") {
+		t.Error("secret value stored in plaintext")
+	}
+
+	// A fresh store reads the same files
+	got, err := NewStore(dir).Resolve("API_TOKEN")
+	if err != nil || got != "tok-123" {
+		t.Errorf("Resolve = %q, %v", got, err)
+	}
+	names, _ := store.Names()
+	if len(names) != 1 || names[0] != "API_TOKEN" {
+		t.Errorf("Names = %v", names)
+	}
+
+	if removed, _ := store.Delete("API_TOKEN"); !removed {
+		t.Error("Delete should report the secret existed")
+	}
+	if _, err := store.Resolve("API_TOKEN"); err == nil {
+		t.Error("deleted secret should not resolve")
+	}
+}
+
+func TestResolveFallsBackToEnv(t *testing.T) {
+	t.Setenv("PEPEBOT_TEST_SECRET", "from-env")
+	got, err := NewStore(t.TempDir()).Resolve("PEPEBOT_TEST_SECRET")
+	if err != nil || got != "from-env" {
+		t.Errorf("Resolve = %q, %v", got, err)
+	}
+}
+
+func TestPassphraseKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(KeyEnv, "correct horse")
+	if err := NewStore(dir).Set("A", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secrets.key")); !os.IsNotExist(err) {
+		t.Error("no key file should be written when the passphrase is set")
+	}
+
+	t.Setenv(KeyEnv, "wrong")
+	if _, err := NewStore(dir).Resolve("A"); err == nil {
+		t.Error("wrong passphrase should fail to decrypt")
+	}
+}
@@ -0,0 +1,240 @@
+// Package selfupdate finds pepebot releases on GitHub, verifies downloaded
+// archives and swaps the running binary, keeping the previous one for
+// rollback.
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release channels
+const (
+	ChannelStable = "stable" // published releases only
+	ChannelBeta   = "beta"   // pre-releases as well
+)
+
+// DefaultAPIBase is the GitHub API of the pepebot repository
+const DefaultAPIBase = "https://api.github.com/repos/pepebot-space/pepebot"
+
+// maxDownloadSize guards against runaway downloads
+const maxDownloadSize = 200 << 20
+
+// Release is a GitHub release
+type Release struct {
+	TagName    string  `json:"tag_name"`
+	Name       string  `json:"name"`
+	Body       string  `json:"body"`
+	Draft      bool    `json:"draft"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Asset returns the asset with the given name, or nil
+func (r *Release) Asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Client talks to the GitHub releases API
+type Client struct {
+	APIBase string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for the pepebot repository
+func NewClient() *Client {
+	return &Client{APIBase: DefaultAPIBase, HTTP: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+// Releases returns the most recent releases, newest first
+func (c *Client) Releases(ctx context.Context) ([]Release, error) {
+	data, err := c.get(ctx, c.APIBase+"/releases?per_page=50", "application/vnd.github+json", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	var releases []Release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases: %w", err)
+	}
+	return releases, nil
+}
+
+// Latest picks the newest release of a channel from releases
+func Latest(releases []Release, channel string) (*Release, error) {
+	if channel != ChannelStable && channel != ChannelBeta {
+		return nil, fmt.Errorf("unknown channel %q (use %s or %s)", channel, ChannelStable, ChannelBeta)
+	}
+	var latest *Release
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || (r.Prerelease && channel == ChannelStable) {
+			continue
+		}
+		if latest == nil || CompareVersions(r.TagName, latest.TagName) > 0 {
+			latest = r
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no %s release found", channel)
+	}
+	return latest, nil
+}
+
+// Changelog returns the releases newer than current up to and including
+// target, newest first. Pre-releases are left out unless target is one.
+func Changelog(releases []Release, current, target string) []Release {
+	targetRelease := false
+	for _, r := range releases {
+		if r.TagName == target {
+			targetRelease = r.Prerelease
+		}
+	}
+	var notes []Release
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && !targetRelease) {
+			continue
+		}
+		if CompareVersions(r.TagName, current) > 0 && CompareVersions(r.TagName, target) <= 0 {
+			notes = append(notes, r)
+		}
+	}
+	for i := 1; i < len(notes); i++ {
+		for j := i; j > 0 && CompareVersions(notes[j].TagName, notes[j-1].TagName) > 0; j-- {
+			notes[j], notes[j-1] = notes[j-1], notes[j]
+		}
+	}
+	return notes
+}
+
+// Download fetches a release asset
+func (c *Client) Download(ctx context.Context, url string) ([]byte, error) {
+	return c.get(ctx, url, "application/octet-stream", maxDownloadSize)
+}
+
+func (c *Client) get(ctx context.Context, url, accept string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response larger than %d MB", url, limit>>20)
+	}
+	return data, nil
+}
+
+// CompareVersions compares two semantic versions such as "v0.5.1" or
+// "0.6.0-beta.2" and returns -1, 0 or 1. A release sorts after its
+// pre-releases. Versions that don't parse, like "dev", sort first.
+func CompareVersions(a, b string) int {
+	ca, pa, oka := parseVersion(a)
+	cb, pb, okb := parseVersion(b)
+	switch {
+	case !oka && !okb:
+		return strings.Compare(a, b)
+	case !oka:
+		return -1
+	case !okb:
+		return 1
+	}
+	for i := 0; i < 3; i++ {
+		if ca[i] != cb[i] {
+			return sign(ca[i] - cb[i])
+		}
+	}
+	switch {
+	case pa == "" && pb == "":
+		return 0
+	case pa == "":
+		return 1
+	case pb == "":
+		return -1
+	}
+	return comparePrerelease(pa, pb)
+}
+
+func parseVersion(v string) (core [3]int, pre string, ok bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return core, "", false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return core, "", false
+		}
+		core[i] = n
+	}
+	return core, pre, true
+}
+
+// comparePrerelease compares dot-separated identifiers, numerically where
+// both are numbers
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		na, errA := strconv.Atoi(as[i])
+		nb, errB := strconv.Atoi(bs[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return sign(na - nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
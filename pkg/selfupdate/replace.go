@@ -0,0 +1,75 @@
+package selfupdate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BackupPath is where the previous binary is kept: pepebot.bak next to
+// pepebot (pepebot.bak for pepebot.exe too)
+func BackupPath(execPath string) string {
+	return strings.TrimSuffix(execPath, ".exe") + ".bak"
+}
+
+// ReplaceBinary installs data as the binary at execPath and keeps the
+// current binary at BackupPath. Moving the running file aside instead of
+// overwriting it also works on Windows.
+func ReplaceBinary(execPath string, data []byte) error {
+	dir := filepath.Dir(execPath)
+	tmp, err := os.CreateTemp(dir, "pepebot-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	tmp.Close()
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	backup := BackupPath(execPath)
+	os.Remove(backup)
+	if err := os.Rename(execPath, backup); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to keep the current binary as %s: %w", backup, err)
+	}
+	if err := os.Rename(tmpPath, execPath); err != nil {
+		os.Rename(backup, execPath)
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+	return nil
+}
+
+// Rollback swaps the binary at execPath with its backup, so running it
+// again undoes the rollback
+func Rollback(execPath string) error {
+	backup := BackupPath(execPath)
+	if _, err := os.Stat(backup); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no previous binary at %s", backup)
+		}
+		return err
+	}
+
+	aside := execPath + ".rollback"
+	os.Remove(aside)
+	if err := os.Rename(execPath, aside); err != nil {
+		return fmt.Errorf("failed to move the current binary aside: %w", err)
+	}
+	if err := os.Rename(backup, execPath); err != nil {
+		os.Rename(aside, execPath)
+		return fmt.Errorf("failed to restore %s: %w", backup, err)
+	}
+	if err := os.Rename(aside, backup); err != nil {
+		return fmt.Errorf("restored the previous binary, but failed to keep the current one as %s: %w", backup, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"v0.5.1", "0.5.1", 0},
+		{"v0.5.10", "v0.5.9", 1},
+		{"v1.0.0", "v1.0.0-beta.2", 1},
+		{"v1.0.0-beta.10", "v1.0.0-beta.9", 1},
+		{"v1.0.0-alpha", "v1.0.0-beta", -1},
+		{"v1.0.0-beta", "v1.0.0-beta.1", -1},
+		{"dev", "v0.0.1", -1},
+		{"v0.6", "v0.6.0", 0},
+	}
+	for _, c := range cases {
+		if got := CompareVersions(c.a, c.b); got != c.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestLatestAndChangelog(t *testing.T) {
+	releases := []Release{
+		{TagName: "v0.7.0-beta.1", Prerelease: true, Body: "beta"},
+		{TagName: "v0.6.1", Body: "fixes"},
+		{TagName: "v0.8.0", Draft: true},
+		{TagName: "v0.6.0", Body: "features"},
+		{TagName: "v0.5.0", Body: "old"},
+	}
+
+	stable, err := Latest(releases, ChannelStable)
+	if err != nil || stable.TagName != "v0.6.1" {
+		t.Fatalf("stable = %v, %v", stable, err)
+	}
+	beta, _ := Latest(releases, ChannelBeta)
+	if beta.TagName != "v0.7.0-beta.1" {
+		t.Errorf("beta = %s", beta.TagName)
+	}
+	if _, err := Latest(releases, "nightly"); err == nil {
+		t.Error("unknown channel should fail")
+	}
+
+	notes := Changelog(releases, "v0.5.0", "v0.6.1")
+	if len(notes) != 2 || notes[0].TagName != "v0.6.1" || notes[1].TagName != "v0.6.0" {
+		t.Errorf("stable changelog = %+v", notes)
+	}
+	if notes := Changelog(releases, "v0.6.0", "v0.7.0-beta.1"); len(notes) != 2 || notes[0].TagName != "v0.7.0-beta.1" {
+		t.Errorf("beta changelog = %+v", notes)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	data := []byte("archive")
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	got, err := ParseChecksum([]byte(hash+"  pepebot-linux-amd64.tar.gz\n"), "pepebot-linux-amd64.tar.gz")
+	if err != nil || got != hash {
+		t.Fatalf("ParseChecksum = %q, %v", got, err)
+	}
+	if _, err := ParseChecksum([]byte(hash+"  other.tar.gz\nabc  x.tar.gz"), "pepebot.tar.gz"); !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("missing entry should be ErrNoChecksum, got %v", err)
+	}
+	if err := VerifySHA256(data, hash); err != nil {
+		t.Error(err)
+	}
+	if err := VerifySHA256([]byte("tampered"), hash); err == nil {
+		t.Error("tampered data should fail")
+	}
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	key := base64.StdEncoding.EncodeToString(pub)
+	sig := ed25519.Sign(priv, data)
+	if err := VerifySignature(data, sig, key); err != nil {
+		t.Errorf("raw signature: %v", err)
+	}
+	if err := VerifySignature(data, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), key); err != nil {
+		t.Errorf("base64 signature: %v", err)
+	}
+	if err := VerifySignature([]byte("tampered"), sig, key); err == nil {
+		t.Error("signature of other data should fail")
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"README.txt": "readme", "pepebot-linux-amd64": "binary"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	data, err := ExtractBinary(buf.Bytes(), "pepebot-linux-amd64")
+	if err != nil || string(data) != "binary" {
+		t.Fatalf("ExtractBinary = %q, %v", data, err)
+	}
+	if _, err := ExtractBinary(buf.Bytes(), "pepebot-darwin-arm64"); err == nil {
+		t.Error("missing binary should fail")
+	}
+}
+
+func TestReplaceAndRollback(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "pepebot")
+	os.WriteFile(exe, []byte("old"), 0755)
+
+	if err := ReplaceBinary(exe, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, exe, "new")
+	assertFile(t, filepath.Join(dir, "pepebot.bak"), "old")
+
+	if err := Rollback(exe); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, exe, "old")
+	assertFile(t, filepath.Join(dir, "pepebot.bak"), "new")
+
+	os.Remove(filepath.Join(dir, "pepebot.bak"))
+	if err := Rollback(exe); err == nil {
+		t.Error("rollback without a backup should fail")
+	}
+}
+
+func TestReleases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Release{{TagName: "v1.0.0", Assets: []Asset{{Name: "a.tar.gz", URL: "http://x/a"}}}})
+	}))
+	defer srv.Close()
+
+	c := &Client{APIBase: srv.URL, HTTP: srv.Client()}
+	releases, err := c.Releases(context.Background())
+	if err != nil || len(releases) != 1 || releases[0].Asset("a.tar.gz") == nil {
+		t.Fatalf("Releases = %+v, %v", releases, err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != want {
+		t.Errorf("%s = %q, %v; want %q", path, data, err, want)
+	}
+}
@@ -0,0 +1,101 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// PublicKey is the base64 encoded ed25519 key release archives are signed
+// with. Release builds set it with
+// -ldflags "-X github.com/pepebot-space/pepebot/pkg/selfupdate.PublicKey=...";
+// when empty, signatures are not checked.
+var PublicKey = ""
+
+// ErrNoChecksum is returned when a checksum file lists no hash for the asset
+var ErrNoChecksum = errors.New("no checksum for asset")
+
+// ParseChecksum finds the SHA256 of name in sha256sum output. A file that
+// holds a single bare hash is accepted too.
+func ParseChecksum(data []byte, name string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1 && len(lines) == 1:
+			return strings.ToLower(fields[0]), nil
+		case len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name:
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%w %s", ErrNoChecksum, name)
+}
+
+// VerifySHA256 checks data against a hex encoded SHA256
+func VerifySHA256(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", want, got)
+	}
+	return nil
+}
+
+// VerifySignature checks an ed25519 signature of data. The signature may be
+// raw bytes or base64; publicKey is base64.
+func VerifySignature(data, signature []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key")
+	}
+	sig := signature
+	if len(sig) != ed25519.SignatureSize {
+		sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || len(sig) != ed25519.SignatureSize {
+			return fmt.Errorf("invalid signature")
+		}
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return fmt.Errorf("signature does not match the release signing key")
+	}
+	return nil
+}
+
+// ExtractBinary returns the file called binaryName from a tar.gz archive
+func ExtractBinary(archive []byte, binaryName string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("gzip error: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tar error: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("read error: %w", err)
+			}
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("binary %q not found in archive", binaryName)
+}
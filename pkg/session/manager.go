@@ -2,6 +2,7 @@ package session
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,8 +16,13 @@ type Session struct {
 	Key      string              `json:"key"`
 	Messages []providers.Message `json:"messages"`
 	Summary  string              `json:"summary,omitempty"`
+	Pins     []string            `json:"pins,omitempty"`
 	Created  time.Time           `json:"created"`
 	Updated  time.Time           `json:"updated"`
+
+	// Structured is the summary as separate lists; Summary holds its
+	// rendering
+	Structured *StructuredSummary `json:"structured_summary,omitempty"`
 }
 
 type SessionManager struct {
@@ -112,6 +118,7 @@ func (sm *SessionManager) GetSummary(key string) string {
 	return session.Summary
 }
 
+// SetSummary stores a flat text summary, replacing any structured one
 func (sm *SessionManager) SetSummary(key string, summary string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -119,10 +126,73 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	session, ok := sm.sessions[key]
 	if ok {
 		session.Summary = summary
+		session.Structured = nil
 		session.Updated = time.Now()
 	}
 }
 
+// GetPins returns the pinned facts of a session
+func (sm *SessionManager) GetPins(key string) []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return nil
+	}
+	pins := make([]string, len(session.Pins))
+	copy(pins, session.Pins)
+	return pins
+}
+
+// Pin adds a fact to a session and saves it. Pins are kept apart from the
+// history, so summarization and truncation never drop them.
+func (sm *SessionManager) Pin(key, text string) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("nothing to pin")
+	}
+
+	session := sm.GetOrCreate(key)
+	sm.mu.Lock()
+	session.Pins = append(session.Pins, text)
+	session.Updated = time.Now()
+	sm.mu.Unlock()
+
+	if err := sm.Save(session); err != nil {
+		return nil, err
+	}
+	return sm.GetPins(key), nil
+}
+
+// Unpin removes the pin at a 1-based index and saves the session.
+// An index of 0 removes all pins.
+func (sm *SessionManager) Unpin(key string, index int) ([]string, error) {
+	sm.mu.Lock()
+	session, ok := sm.sessions[key]
+	if !ok || len(session.Pins) == 0 {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("session has no pins: %s", key)
+	}
+	switch {
+	case index == 0:
+		session.Pins = nil
+	case index < 0 || index > len(session.Pins):
+		n := len(session.Pins)
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("pin %d does not exist (session has %d)", index, n)
+	default:
+		session.Pins = append(session.Pins[:index-1:index-1], session.Pins[index:]...)
+	}
+	session.Updated = time.Now()
+	sm.mu.Unlock()
+
+	if err := sm.Save(session); err != nil {
+		return nil, err
+	}
+	return sm.GetPins(key), nil
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -183,6 +253,50 @@ func (sm *SessionManager) ListSessions(prefix string) []*Session {
 	return result
 }
 
+// Fork copies the history, summary and pins of a session into a new session and
+// saves it. The new key must not be in use.
+func (sm *SessionManager) Fork(key, newKey string) (*Session, error) {
+	if newKey == "" || newKey == key {
+		return nil, fmt.Errorf("fork needs a new session key")
+	}
+	if strings.ContainsAny(newKey, `/\`) {
+		return nil, fmt.Errorf("invalid session key: %s", newKey)
+	}
+
+	sm.mu.Lock()
+	source, ok := sm.sessions[key]
+	if !ok {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("session not found: %s", key)
+	}
+	if _, exists := sm.sessions[newKey]; exists {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("session already exists: %s", newKey)
+	}
+
+	now := time.Now()
+	fork := &Session{
+		Key:      newKey,
+		Messages: make([]providers.Message, len(source.Messages)),
+		Summary:  source.Summary,
+		Pins:     append([]string(nil), source.Pins...),
+		Created:  now,
+		Updated:  now,
+	}
+	copy(fork.Messages, source.Messages)
+	if source.Structured != nil {
+		structured := *source.Structured
+		fork.Structured = &structured
+	}
+	sm.sessions[newKey] = fork
+	sm.mu.Unlock()
+
+	if err := sm.Save(fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
 // DeleteSession deletes a session completely
 func (sm *SessionManager) DeleteSession(key string) {
 	sm.ClearSession(key)
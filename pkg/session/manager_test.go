@@ -0,0 +1,82 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFork(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("chat", "user", "Plan a trip")
+	sm.AddMessage("chat", "assistant", "Bali or Lombok?")
+	sm.SetSummary("chat", "Trip planning")
+
+	fork, err := sm.Fork("chat", "chat-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fork.Messages) != 2 || fork.Summary != "Trip planning" {
+		t.Fatalf("unexpected fork: %+v", fork)
+	}
+
+	// The branches are independent
+	sm.AddMessage("chat-b", "user", "Lombok")
+	if n := len(sm.GetHistory("chat")); n != 2 {
+		t.Errorf("original changed, %d messages", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chat-b.json")); err != nil {
+		t.Errorf("fork not saved: %v", err)
+	}
+
+	if _, err := sm.Fork("chat", "chat-b"); err == nil {
+		t.Error("fork onto an existing key should fail")
+	}
+	if _, err := sm.Fork("missing", "x"); err == nil {
+		t.Error("fork of a missing session should fail")
+	}
+	if _, err := sm.Fork("chat", "../escape"); err == nil {
+		t.Error("keys with path separators should be rejected")
+	}
+}
+
+func TestPins(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	for _, fact := range []string{"server IP is 10.0.0.5", "deploy on Fridays", "user is in UTC+7"} {
+		if _, err := sm.Pin("ops", fact); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sm.Pin("ops", "  "); err == nil {
+		t.Error("empty pin should fail")
+	}
+
+	// Pins survive summarization and truncation
+	sm.AddMessage("ops", "user", "hi")
+	sm.AddMessage("ops", "assistant", "hello")
+	sm.SetSummary("ops", "Greetings")
+	sm.TruncateHistory("ops", 1)
+
+	pins, err := sm.Unpin("ops", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 2 || pins[0] != "server IP is 10.0.0.5" || pins[1] != "user is in UTC+7" {
+		t.Fatalf("unexpected pins: %v", pins)
+	}
+	if _, err := sm.Unpin("ops", 5); err == nil {
+		t.Error("unpin of a missing index should fail")
+	}
+
+	// Pins are saved with the session
+	reloaded := NewSessionManager(dir)
+	if got := reloaded.GetPins("ops"); len(got) != 2 {
+		t.Fatalf("pins not persisted: %v", got)
+	}
+
+	if pins, _ := sm.Unpin("ops", 0); len(pins) != 0 {
+		t.Errorf("unpin all left %v", pins)
+	}
+}
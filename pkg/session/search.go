@@ -0,0 +1,130 @@
+package session
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// SearchResult is a session with messages matching a search query
+type SearchResult struct {
+	Key      string          `json:"key"`
+	Updated  time.Time       `json:"updated"`
+	Matches  int             `json:"matches"`
+	Snippets []SearchSnippet `json:"snippets"`
+}
+
+// SearchSnippet is the text around a match in one message
+type SearchSnippet struct {
+	Index   int    `json:"index"`
+	Role    string `json:"role"`
+	Snippet string `json:"snippet"`
+}
+
+const (
+	snippetRadius      = 60
+	snippetsPerSession = 3
+)
+
+// Search finds messages that contain every word of the query, ignoring
+// case. Results are ordered by most recently updated session and hold up to
+// three snippets each; limit caps the number of sessions (0 = no limit).
+func (sm *SessionManager) Search(query string, limit int) []SearchResult {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	return searchSessions(sm.sessions, query, limit)
+}
+
+// MergeSearchResults combines results from several session managers,
+// keeping the most recently updated copy of each session
+func MergeSearchResults(limit int, groups ...[]SearchResult) []SearchResult {
+	byKey := make(map[string]SearchResult)
+	for _, group := range groups {
+		for _, r := range group {
+			if existing, ok := byKey[r.Key]; !ok || r.Updated.After(existing.Updated) {
+				byKey[r.Key] = r
+			}
+		}
+	}
+	results := make([]SearchResult, 0, len(byKey))
+	for _, r := range byKey {
+		results = append(results, r)
+	}
+	return sortAndLimit(results, limit)
+}
+
+func searchSessions(sessions map[string]*Session, query string, limit int) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []SearchResult
+	for key, s := range sessions {
+		result := SearchResult{Key: key, Updated: s.Updated}
+		for i, msg := range s.Messages {
+			content, ok := msg.Content.(string)
+			if !ok || !containsAll(strings.ToLower(content), terms) {
+				continue
+			}
+			result.Matches++
+			if len(result.Snippets) < snippetsPerSession {
+				result.Snippets = append(result.Snippets, SearchSnippet{
+					Index:   i,
+					Role:    msg.Role,
+					Snippet: snippet(content, terms[0]),
+				})
+			}
+		}
+		if result.Matches > 0 {
+			results = append(results, result)
+		}
+	}
+	return sortAndLimit(results, limit)
+}
+
+func sortAndLimit(results []SearchResult, limit int) []SearchResult {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Updated.After(results[j].Updated)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func containsAll(text string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet returns the text around the first occurrence of term on one line
+func snippet(content, term string) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	at := strings.Index(string(lower), term)
+	if at < 0 {
+		at = 0
+	}
+	// Convert the byte offset in the lowercased text to a rune offset
+	pos := len([]rune(string(lower)[:at]))
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+
+	start, end := pos-snippetRadius, pos+len([]rune(term))+snippetRadius
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(runes) {
+		end, suffix = len(runes), ""
+	}
+	text := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	return prefix + text + suffix
+}
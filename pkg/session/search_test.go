@@ -0,0 +1,46 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	sm.AddMessage("trip", "user", "Find flights to Bali in March")
+	sm.AddMessage("trip", "assistant", "Garuda has a direct flight to BALI on March 3.")
+	sm.AddMessage("work", "user", "Summarize the March report")
+	sm.AddMessage("work", "assistant", "Revenue grew 4%.")
+
+	results := sm.Search("bali march", 0)
+	if len(results) != 1 || results[0].Key != "trip" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[0].Matches != 2 || len(results[0].Snippets) != 2 {
+		t.Fatalf("expected 2 matches with snippets, got %+v", results[0])
+	}
+	if results[0].Snippets[1].Role != "assistant" || results[0].Snippets[1].Index != 1 {
+		t.Errorf("unexpected snippet: %+v", results[0].Snippets[1])
+	}
+
+	if got := sm.Search("march", 1); len(got) != 1 {
+		t.Errorf("limit not applied, got %d results", len(got))
+	}
+	if got := sm.Search("  ", 0); got != nil {
+		t.Errorf("empty query should match nothing, got %+v", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("a ", 100) + "needle\nin the\thaystack " + strings.Repeat("b ", 100)
+	got := snippet(long, "needle")
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("expected ellipses: %q", got)
+	}
+	if !strings.Contains(got, "needle in the haystack") {
+		t.Errorf("whitespace not collapsed: %q", got)
+	}
+	if got := snippet("héllo wörld", "wörld"); got != "héllo wörld" {
+		t.Errorf("short text should be whole: %q", got)
+	}
+}
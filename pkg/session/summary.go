@@ -0,0 +1,165 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Limits that keep a structured summary compact in the prompt. The oldest
+// entries are dropped first.
+const (
+	maxSummaryEntities  = 30
+	maxSummaryDecisions = 20
+	maxSummaryTodos     = 20
+	maxSummaryTopics    = 15
+)
+
+// StructuredSummary is what the summarized part of a conversation
+// established, kept as separate lists so details such as names and open
+// tasks survive repeated summarization
+type StructuredSummary struct {
+	Overview  string   `json:"overview,omitempty"`
+	Entities  []Entity `json:"entities,omitempty"`
+	Decisions []string `json:"decisions,omitempty"`
+	Todos     []string `json:"todos,omitempty"` // open tasks only
+	Topics    []string `json:"topics,omitempty"`
+}
+
+// Entity is a person, place, project, device or other named thing that came
+// up in a conversation
+type Entity struct {
+	Name  string `json:"name"`
+	Type  string `json:"type,omitempty"`
+	Notes string `json:"notes,omitempty"`
+}
+
+// IsEmpty reports whether the summary holds nothing
+func (s *StructuredSummary) IsEmpty() bool {
+	return s == nil || (strings.TrimSpace(s.Overview) == "" && len(s.Entities) == 0 &&
+		len(s.Decisions) == 0 && len(s.Todos) == 0 && len(s.Topics) == 0)
+}
+
+// Normalize trims entries, drops empty and duplicate ones and keeps the
+// newest entries of each list within its limit
+func (s *StructuredSummary) Normalize() {
+	s.Overview = strings.TrimSpace(s.Overview)
+
+	seen := make(map[string]int)
+	var entities []Entity
+	for _, e := range s.Entities {
+		e.Name, e.Type, e.Notes = strings.TrimSpace(e.Name), strings.TrimSpace(e.Type), strings.TrimSpace(e.Notes)
+		if e.Name == "" {
+			continue
+		}
+		key := strings.ToLower(e.Name)
+		if i, ok := seen[key]; ok {
+			entities[i] = e // a later mention has the newer notes
+			continue
+		}
+		seen[key] = len(entities)
+		entities = append(entities, e)
+	}
+	s.Entities = lastN(entities, maxSummaryEntities)
+	s.Decisions = lastN(cleanList(s.Decisions), maxSummaryDecisions)
+	s.Todos = lastN(cleanList(s.Todos), maxSummaryTodos)
+	s.Topics = lastN(cleanList(s.Topics), maxSummaryTopics)
+}
+
+// Render writes the summary as compact markdown for the prompt
+func (s *StructuredSummary) Render() string {
+	if s.IsEmpty() {
+		return ""
+	}
+
+	var b strings.Builder
+	if s.Overview != "" {
+		b.WriteString(s.Overview + "\n")
+	}
+	if len(s.Topics) > 0 {
+		fmt.Fprintf(&b, "\nTopics: %s\n", strings.Join(s.Topics, ", "))
+	}
+	if len(s.Entities) > 0 {
+		b.WriteString("\nPeople and things:\n")
+		for _, e := range s.Entities {
+			b.WriteString("- " + e.Name)
+			if e.Type != "" {
+				b.WriteString(" (" + e.Type + ")")
+			}
+			if e.Notes != "" {
+				b.WriteString(": " + e.Notes)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(s.Decisions) > 0 {
+		b.WriteString("\nDecisions:\n")
+		for _, d := range s.Decisions {
+			b.WriteString("- " + d + "\n")
+		}
+	}
+	if len(s.Todos) > 0 {
+		b.WriteString("\nOpen tasks:\n")
+		for _, t := range s.Todos {
+			b.WriteString("- [ ] " + t + "\n")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func cleanList(items []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, item := range items {
+		item = strings.Join(strings.Fields(item), " ")
+		key := strings.ToLower(item)
+		if item == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, item)
+	}
+	return out
+}
+
+func lastN[T any](items []T, n int) []T {
+	if len(items) > n {
+		return items[len(items)-n:]
+	}
+	return items
+}
+
+// GetStructuredSummary returns a copy of a session's structured summary, or
+// nil when it has none (a new session, or one summarized by an older
+// release)
+func (sm *SessionManager) GetStructuredSummary(key string) *StructuredSummary {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok || session.Structured == nil {
+		return nil
+	}
+	s := *session.Structured
+	s.Entities = append([]Entity(nil), s.Entities...)
+	s.Decisions = append([]string(nil), s.Decisions...)
+	s.Todos = append([]string(nil), s.Todos...)
+	s.Topics = append([]string(nil), s.Topics...)
+	return &s
+}
+
+// SetStructuredSummary stores a structured summary and its rendering as the
+// session's summary, which is what the prompt, digests and exports show
+func (sm *SessionManager) SetStructuredSummary(key string, summary *StructuredSummary) {
+	summary.Normalize()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if ok {
+		session.Structured = summary
+		session.Summary = summary.Render()
+		session.Updated = time.Now()
+	}
+}
@@ -0,0 +1,49 @@
+package session
+
+import "testing"
+
+func TestStructuredSummary(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("chat", "user", "Plan a trip")
+	sm.SetStructuredSummary("chat", &StructuredSummary{
+		Overview: " Trip planning ",
+		Entities: []Entity{
+			{Name: "Budi", Type: "person"},
+			{Name: "Lombok", Type: "place"},
+			{Name: "budi", Type: "person", Notes: "brother"},
+		},
+		Decisions: []string{"Fly on 12 May", "fly on 12  may"},
+		Todos:     []string{"Book the hotel", ""},
+		Topics:    []string{"travel", "budget"},
+	})
+
+	want := "Trip planning\n\n" +
+		"Topics: travel, budget\n\n" +
+		"People and things:\n- budi (person): brother\n- Lombok (place)\n\n" +
+		"Decisions:\n- Fly on 12 May\n\n" +
+		"Open tasks:\n- [ ] Book the hotel"
+	if got := sm.GetSummary("chat"); got != want {
+		t.Errorf("summary =\n%s\nwant\n%s", got, want)
+	}
+
+	// The structured summary survives a restart and a fork
+	sm.Save(sm.GetOrCreate("chat"))
+	sm = NewSessionManager(dir)
+	if s := sm.GetStructuredSummary("chat"); s == nil || len(s.Entities) != 2 || s.Todos[0] != "Book the hotel" {
+		t.Fatalf("structured summary after reload = %+v", s)
+	}
+	fork, err := sm.Fork("chat", "chat-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.Structured == nil || fork.Structured.Overview != "Trip planning" {
+		t.Errorf("fork structured summary = %+v", fork.Structured)
+	}
+
+	// A flat summary replaces the structured one
+	sm.SetSummary("chat", "Just text")
+	if sm.GetStructuredSummary("chat") != nil {
+		t.Error("structured summary kept after SetSummary")
+	}
+}
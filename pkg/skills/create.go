@@ -0,0 +1,105 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// maxSkillSize caps agent-created SKILL.md files
+const maxSkillSize = 64 * 1024
+
+// ValidateSkillName checks that a name is lowercase words joined by hyphens
+func ValidateSkillName(name string) error {
+	if len(name) > 64 {
+		return fmt.Errorf("skill name must be at most 64 characters")
+	}
+	if !skillNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid skill name '%s': use lowercase letters, digits and hyphens (e.g. 'deploy-blog')", name)
+	}
+	return nil
+}
+
+// RenderSkill builds a SKILL.md from metadata and a markdown body, and
+// validates the result the same way the loader will read it
+func RenderSkill(meta SkillMetadata, body string) (string, error) {
+	var fm strings.Builder
+	fm.WriteString("name: " + meta.Name + "\n")
+	fm.WriteString("description: " + strconv.Quote(strings.TrimSpace(meta.Description)) + "\n")
+	if meta.Always {
+		fm.WriteString("always: true\n")
+	}
+	if meta.Requires != nil {
+		data, err := json.Marshal(meta.Requires)
+		if err != nil {
+			return "", err
+		}
+		fm.WriteString("requires: " + string(data) + "\n")
+	}
+
+	content := "---\n" + fm.String() + "---\n\n" + strings.TrimSpace(body) + "\n"
+	if err := ValidateSkill(meta.Name, content); err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// ValidateSkill checks that a SKILL.md has parseable frontmatter whose name
+// matches the skill directory, a description and a non-empty body
+func ValidateSkill(name, content string) error {
+	if err := ValidateSkillName(name); err != nil {
+		return err
+	}
+	if len(content) > maxSkillSize {
+		return fmt.Errorf("skill is larger than %d KB", maxSkillSize/1024)
+	}
+
+	sl := &SkillsLoader{}
+	frontmatter := sl.extractFrontmatter(content)
+	if frontmatter == "" {
+		return fmt.Errorf("SKILL.md must start with a --- frontmatter block")
+	}
+	meta, err := parseFrontmatter(frontmatter)
+	if err != nil {
+		return fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	if meta.Name != name {
+		return fmt.Errorf("frontmatter name '%s' does not match skill name '%s'", meta.Name, name)
+	}
+	if strings.TrimSpace(meta.Description) == "" {
+		return fmt.Errorf("frontmatter description is required")
+	}
+	if strings.Contains(meta.Description, "\n") {
+		return fmt.Errorf("description must be a single line")
+	}
+	if strings.TrimSpace(sl.stripFrontmatter(content)) == "" {
+		return fmt.Errorf("skill instructions are empty")
+	}
+	return nil
+}
+
+// CreateSkill validates content and writes it to workspace/skills/<name>/SKILL.md.
+// An existing workspace skill is only replaced when overwrite is set.
+func (sl *SkillsLoader) CreateSkill(name, content string, overwrite bool) (string, error) {
+	if err := ValidateSkill(name, content); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(sl.workspaceSkills, name, "SKILL.md")
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return "", fmt.Errorf("skill '%s' already exists; set overwrite to replace it", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create skill directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write skill: %w", err)
+	}
+	return path, nil
+}
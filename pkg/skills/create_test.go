@@ -0,0 +1,70 @@
+package skills
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderAndCreateSkill(t *testing.T) {
+	workspace := t.TempDir()
+	loader := NewSkillsLoader(workspace, "")
+
+	content, err := RenderSkill(SkillMetadata{
+		Name:        "deploy-blog",
+		Description: `Build and publish the blog: "hugo" then rsync`,
+		Requires:    &SkillRequirements{Bins: []string{"hugo"}},
+	}, "1. Run `hugo`\n2. rsync public/ to the server")
+	if err != nil {
+		t.Fatalf("RenderSkill: %v", err)
+	}
+
+	path, err := loader.CreateSkill("deploy-blog", content, false)
+	if err != nil {
+		t.Fatalf("CreateSkill: %v", err)
+	}
+	if !strings.HasSuffix(path, "skills/deploy-blog/SKILL.md") {
+		t.Errorf("unexpected path %s", path)
+	}
+
+	meta := loader.getSkillMetadata(path)
+	if meta == nil || meta.Description != `Build and publish the blog: "hugo" then rsync` || meta.Requires == nil || meta.Requires.Bins[0] != "hugo" {
+		t.Errorf("frontmatter did not round-trip: %+v", meta)
+	}
+	if body, ok := loader.LoadSkill("deploy-blog"); !ok || !strings.HasPrefix(strings.TrimSpace(body), "1. Run") {
+		t.Errorf("LoadSkill = %q, %v", body, ok)
+	}
+
+	if _, err := loader.CreateSkill("deploy-blog", content, false); err == nil {
+		t.Error("expected an error when the skill already exists")
+	}
+	if _, err := loader.CreateSkill("deploy-blog", content, true); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+}
+
+func TestValidateSkill(t *testing.T) {
+	cases := map[string]struct {
+		meta    SkillMetadata
+		body    string
+		wantErr string
+	}{
+		"bad name":        {SkillMetadata{Name: "Deploy Blog", Description: "x"}, "steps", "invalid skill name"},
+		"traversal":       {SkillMetadata{Name: "../etc", Description: "x"}, "steps", "invalid skill name"},
+		"no description":  {SkillMetadata{Name: "ok"}, "steps", "description is required"},
+		"multi-line desc": {SkillMetadata{Name: "ok", Description: "a\nb"}, "steps", "single line"},
+		"empty body":      {SkillMetadata{Name: "ok", Description: "x"}, "  ", "instructions are empty"},
+	}
+	for label, tc := range cases {
+		_, err := RenderSkill(tc.meta, tc.body)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: got %v, want error containing %q", label, err, tc.wantErr)
+		}
+	}
+
+	if err := ValidateSkill("other", "---\nname: mine\ndescription: x\n---\nbody"); err == nil {
+		t.Error("expected name mismatch error")
+	}
+	if err := ValidateSkill("mine", "no frontmatter"); err == nil {
+		t.Error("expected missing frontmatter error")
+	}
+}
@@ -8,14 +8,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/merge"
 )
 
 type SkillInstaller struct {
 	workspace string
+	apiBase   string // GitHub REST API, used to resolve commit SHAs
+	rawBase   string // raw file host
+	webBase   string // github.com, for source archives
+	indexURL  string // marketplace index
+	token     string // sent to GitHub hosts and git remotes for private repositories
 }
 
 type AvailableSkill struct {
@@ -24,7 +33,10 @@ type AvailableSkill struct {
 	Path        string                 `json:"path"`
 	Repository  string                 `json:"repository,omitempty"`
 	Author      string                 `json:"author,omitempty"`
+	Category    string                 `json:"category,omitempty"`
 	Tags        []string               `json:"tags,omitempty"`
+	Downloads   int                    `json:"downloads,omitempty"`
+	Rating      float64                `json:"rating,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -44,39 +56,199 @@ type BuiltinSkill struct {
 func NewSkillInstaller(workspace string) *SkillInstaller {
 	return &SkillInstaller{
 		workspace: workspace,
+		apiBase:   "https://api.github.com",
+		rawBase:   "https://raw.githubusercontent.com",
+		webBase:   "https://github.com",
+		indexURL:  "https://raw.githubusercontent.com/pepebot-space/skills/refs/heads/main/skills.json",
 	}
 }
 
-func (si *SkillInstaller) InstallFromGitHub(ctx context.Context, repo string) error {
-	skillDir := filepath.Join(si.workspace, "skills", filepath.Base(repo))
+// Configure applies the skills section of the config (index URL and token)
+func (si *SkillInstaller) Configure(cfg config.SkillsConfig) {
+	if cfg.IndexURL != "" {
+		si.indexURL = cfg.IndexURL
+	}
+	si.token = cfg.Token
+}
 
-	if _, err := os.Stat(skillDir); err == nil {
-		return fmt.Errorf("skill '%s' already exists", filepath.Base(repo))
+// builtinSource is the repository InstallBuiltinSkills downloads from
+var builtinSource = SkillSource{Owner: "pepebot-space", Repo: "skills-builtin", Ref: "main"}
+
+// SkillSource is a parsed install argument: a GitHub "owner/repo[/path][@ref]"
+// or a git URL in "https://host/repo.git[//path][?ref=tag]" form
+type SkillSource struct {
+	Owner  string
+	Repo   string
+	GitURL string // set for non-GitHub-shorthand sources, cloned with git
+	Path   string // directory of SKILL.md inside the repository
+	Ref    string // branch or tag, default "main" (git: remote HEAD)
+}
+
+// ParseSkillSource parses "owner/repo", "owner/repo/path/to/skill" and an
+// optional "@ref" suffix, or a git URL (see SkillSource)
+func ParseSkillSource(value string) (SkillSource, error) {
+	if isGitURL(value) {
+		return parseGitSource(value)
 	}
 
-	url := fmt.Sprintf("https://raw.githubusercontent.com/%s/main/SKILL.md", repo)
+	src := SkillSource{Ref: "main"}
+	if at := strings.LastIndex(value, "@"); at >= 0 {
+		src.Ref = value[at+1:]
+		value = value[:at]
+	}
+	parts := strings.Split(strings.Trim(value, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" || src.Ref == "" {
+		return src, fmt.Errorf("invalid skill source '%s' (expected owner/repo[/path][@ref])", value)
+	}
+	src.Owner, src.Repo = parts[0], parts[1]
+	src.Path = strings.Join(parts[2:], "/")
+	return src, nil
+}
+
+// String returns the source without the ref
+func (s SkillSource) String() string {
+	if s.GitURL != "" {
+		if s.Path == "" {
+			return s.GitURL
+		}
+		return s.GitURL + "//" + s.Path
+	}
+	if s.Path == "" {
+		return s.Owner + "/" + s.Repo
+	}
+	return s.Owner + "/" + s.Repo + "/" + s.Path
+}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// Name is the directory the skill is installed into
+func (s SkillSource) Name() string {
+	if s.Path != "" {
+		return filepath.Base(s.Path)
+	}
+	if s.GitURL != "" {
+		return gitRepoName(s.GitURL)
+	}
+	return s.Repo
+}
+
+// latestCommit resolves the newest commit SHA on the source's ref that
+// touches its path
+func (si *SkillInstaller) latestCommit(ctx context.Context, src SkillSource) (string, error) {
+	if src.GitURL != "" {
+		return si.gitLatestCommit(ctx, src)
+	}
+
+	u := fmt.Sprintf("%s/repos/%s/%s/commits?per_page=1&sha=%s", si.apiBase, src.Owner, src.Repo, url.QueryEscape(src.Ref))
+	if src.Path != "" {
+		u += "&path=" + url.QueryEscape(src.Path)
+	}
+
+	body, err := si.get(ctx, u, 15*time.Second)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to resolve version: %w", err)
+	}
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.Unmarshal(body, &commits); err != nil {
+		return "", fmt.Errorf("failed to parse commits: %w", err)
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no commits found for %s@%s", src, src.Ref)
+	}
+	return commits[0].SHA, nil
+}
+
+func (si *SkillInstaller) get(ctx context.Context, u string, timeout time.Duration) ([]byte, error) {
+	client := &http.Client{Timeout: timeout}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Only GitHub hosts get the token, never the marketplace index or other URLs
+	if si.token != "" && (strings.HasPrefix(u, si.apiBase+"/") || strings.HasPrefix(u, si.rawBase+"/")) {
+		req.Header.Set("Authorization", "Bearer "+si.token)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch skill: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to fetch skill: HTTP %d", resp.StatusCode)
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchSkillFile downloads SKILL.md at the given commit (or the ref when
+// the commit is unknown)
+func (si *SkillInstaller) fetchSkillFile(ctx context.Context, src SkillSource, version string) ([]byte, error) {
+	ref := version
+	if ref == "" {
+		ref = src.Ref
+	}
+	file := "SKILL.md"
+	if src.Path != "" {
+		file = src.Path + "/SKILL.md"
+	}
+	body, err := si.get(ctx, fmt.Sprintf("%s/%s/%s/%s/%s", si.rawBase, src.Owner, src.Repo, ref, file), 15*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch skill: %w", err)
+	}
+	return body, nil
+}
+
+// InstallFromGitHub installs a skill from "owner/repo[/path][@ref]" and
+// records the installed commit in the lockfile
+func (si *SkillInstaller) InstallFromGitHub(ctx context.Context, repo string) error {
+	src, err := ParseSkillSource(repo)
+	if err != nil {
+		return err
+	}
+	skillDir := filepath.Join(si.workspace, "skills", src.Name())
+
+	if _, err := os.Stat(skillDir); err == nil {
+		return fmt.Errorf("skill '%s' already exists", src.Name())
+	}
+
+	if src.GitURL != "" {
+		version, err := si.installFromGit(ctx, src, src.Name())
+		if err != nil {
+			return err
+		}
+		return si.lockSkill(src, version)
+	}
+
+	// Pin to a commit when GitHub's API is reachable; otherwise install the
+	// ref and leave the version empty so the next update refreshes it
+	version, _ := si.latestCommit(ctx, src)
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := si.fetchSkillFile(ctx, src, version)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		return err
+	}
+
+	if err := si.writeSkill(src.Name(), body); err != nil {
+		return err
 	}
 
+	return si.lockSkill(src, version)
+}
+
+func (si *SkillInstaller) lockSkill(src SkillSource, version string) error {
+	return si.updateLock(func(lock *Lockfile) {
+		lock.Skills[src.Name()] = LockedSkill{
+			Source:      src.String(),
+			Ref:         src.Ref,
+			Version:     version,
+			InstalledAt: time.Now(),
+		}
+	})
+}
+
+func (si *SkillInstaller) writeSkill(name string, body []byte) error {
+	skillDir := filepath.Join(si.workspace, "skills", name)
 	if err := os.MkdirAll(skillDir, 0755); err != nil {
 		return fmt.Errorf("failed to create skill directory: %w", err)
 	}
@@ -85,46 +257,147 @@ func (si *SkillInstaller) InstallFromGitHub(ctx context.Context, repo string) er
 	if err := os.WriteFile(skillPath, body, 0644); err != nil {
 		return fmt.Errorf("failed to write skill file: %w", err)
 	}
-
 	return nil
 }
 
-func (si *SkillInstaller) Uninstall(skillName string) error {
-	skillDir := filepath.Join(si.workspace, "skills", skillName)
+// SkillUpdate describes an installed skill with a newer upstream commit
+type SkillUpdate struct {
+	Name    string
+	Source  string
+	Current string
+	Latest  string
+}
 
-	if _, err := os.Stat(skillDir); os.IsNotExist(err) {
-		return fmt.Errorf("skill '%s' not found", skillName)
+// CheckUpdates compares every locked skill against its upstream ref
+func (si *SkillInstaller) CheckUpdates(ctx context.Context) ([]SkillUpdate, error) {
+	lock, err := si.LoadLock()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := os.RemoveAll(skillDir); err != nil {
-		return fmt.Errorf("failed to remove skill: %w", err)
+	latest := map[string]string{} // source@ref -> sha
+	var updates []SkillUpdate
+	var firstErr error
+	for _, name := range lock.Names() {
+		entry := lock.Skills[name]
+		src, err := si.lockedSource(entry)
+		if err != nil {
+			continue
+		}
+
+		key := src.String() + "@" + src.Ref
+		sha, ok := latest[key]
+		if !ok {
+			sha, err = si.latestCommit(ctx, src)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			latest[key] = sha
+		}
+		if sha != entry.Version {
+			updates = append(updates, SkillUpdate{Name: name, Source: entry.Source, Current: entry.Version, Latest: sha})
+		}
 	}
 
-	return nil
+	if len(updates) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return updates, nil
 }
 
-func (si *SkillInstaller) ListAvailableSkills(ctx context.Context) ([]AvailableSkill, error) {
-	url := "https://raw.githubusercontent.com/pepebot-space/skills/refs/heads/main/skills.json"
+func (si *SkillInstaller) lockedSource(entry LockedSkill) (SkillSource, error) {
+	if entry.Source == "builtin" {
+		return builtinSource, nil
+	}
+	src, err := ParseSkillSource(entry.Source)
+	if err != nil {
+		return src, err
+	}
+	if entry.Ref != "" {
+		src.Ref = entry.Ref
+	}
+	return src, nil
+}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// Update brings an installed skill to the latest commit on its ref.
+// Returns false when it was already up to date. Builtin skills are updated
+// together by re-installing the builtin archive.
+func (si *SkillInstaller) Update(ctx context.Context, name string) (bool, error) {
+	lock, err := si.LoadLock()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return false, err
+	}
+	entry, ok := lock.Skills[name]
+	if !ok {
+		return false, fmt.Errorf("skill '%s' is not tracked in the lockfile (reinstall it to enable updates)", name)
 	}
 
-	resp, err := client.Do(req)
+	src, err := si.lockedSource(entry)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch skills list: %w", err)
+		return false, err
+	}
+	version, err := si.latestCommit(ctx, src)
+	if err != nil {
+		return false, err
+	}
+	if version == entry.Version {
+		return false, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("failed to fetch skills list: HTTP %d", resp.StatusCode)
+	if entry.Source == "builtin" {
+		return true, si.InstallBuiltinSkills(ctx)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	if src.GitURL != "" {
+		if version, err = si.installFromGit(ctx, src, name); err != nil {
+			return false, err
+		}
+		return true, si.updateLock(func(lock *Lockfile) {
+			entry.Version = version
+			entry.InstalledAt = time.Now()
+			lock.Skills[name] = entry
+		})
+	}
+
+	body, err := si.fetchSkillFile(ctx, src, version)
+	if err != nil {
+		return false, err
+	}
+	if err := si.writeSkill(name, body); err != nil {
+		return false, err
+	}
+
+	return true, si.updateLock(func(lock *Lockfile) {
+		entry.Version = version
+		entry.InstalledAt = time.Now()
+		lock.Skills[name] = entry
+	})
+}
+
+func (si *SkillInstaller) Uninstall(skillName string) error {
+	skillDir := filepath.Join(si.workspace, "skills", skillName)
+
+	if _, err := os.Stat(skillDir); os.IsNotExist(err) {
+		return fmt.Errorf("skill '%s' not found", skillName)
+	}
+
+	if err := os.RemoveAll(skillDir); err != nil {
+		return fmt.Errorf("failed to remove skill: %w", err)
+	}
+
+	return si.updateLock(func(lock *Lockfile) {
+		delete(lock.Skills, skillName)
+	})
+}
+
+// ListAvailableSkills fetches the marketplace index
+func (si *SkillInstaller) ListAvailableSkills(ctx context.Context) ([]AvailableSkill, error) {
+	body, err := si.get(ctx, si.indexURL, 15*time.Second)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to fetch skills list: %w", err)
 	}
 
 	var registry SkillsRegistry
@@ -136,8 +409,12 @@ func (si *SkillInstaller) ListAvailableSkills(ctx context.Context) ([]AvailableS
 }
 
 func (si *SkillInstaller) InstallBuiltinSkills(ctx context.Context) error {
-	// Download ZIP file from GitHub
-	zipURL := "https://github.com/pepebot-space/skills-builtin/archive/refs/heads/main.zip"
+	// Download ZIP file from GitHub, pinned to the latest commit when it can be resolved
+	version, _ := si.latestCommit(ctx, builtinSource)
+	zipURL := fmt.Sprintf("%s/%s/%s/archive/refs/heads/%s.zip", si.webBase, builtinSource.Owner, builtinSource.Repo, builtinSource.Ref)
+	if version != "" {
+		zipURL = fmt.Sprintf("%s/%s/%s/archive/%s.zip", si.webBase, builtinSource.Owner, builtinSource.Repo, version)
+	}
 
 	fmt.Println("  Downloading skills archive...")
 	client := &http.Client{Timeout: 30 * time.Second}
@@ -205,25 +482,28 @@ func (si *SkillInstaller) InstallBuiltinSkills(ctx context.Context) error {
 			return fmt.Errorf("failed to create directory: %w", err)
 		}
 
-		// Extract file
+		// Extract file, merging it with local edits to the previous version
 		srcFile, err := file.Open()
 		if err != nil {
 			return fmt.Errorf("failed to open file in archive: %w", err)
 		}
-
-		dstFile, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+		data, err := io.ReadAll(srcFile)
+		srcFile.Close()
 		if err != nil {
-			srcFile.Close()
-			return fmt.Errorf("failed to create file: %w", err)
+			return fmt.Errorf("failed to read file in archive: %w", err)
 		}
 
-		_, err = io.Copy(dstFile, srcFile)
-		srcFile.Close()
-		dstFile.Close()
-
+		basePath := filepath.Join(si.workspace, merge.UpstreamDir, "skills", relPath)
+		outcome, err := merge.RefreshFile(dstPath, basePath, data, merge.Options{Mode: file.Mode().Perm(), OverwriteUntracked: true})
 		if err != nil {
 			return fmt.Errorf("failed to write file: %w", err)
 		}
+		switch outcome {
+		case merge.Merged:
+			fmt.Printf("  ⇄ Kept your edits in %s\n", relPath)
+		case merge.Conflict:
+			fmt.Printf("  ! Your edits to %s conflict with the update; merged version saved as %s\n", relPath, filepath.Base(merge.NewPath(dstPath)))
+		}
 
 		// Track installed skills
 		if filepath.Base(dstPath) == "SKILL.md" && !installedSkills[skillName] {
@@ -237,7 +517,16 @@ func (si *SkillInstaller) InstallBuiltinSkills(ctx context.Context) error {
 		return fmt.Errorf("no skills found in repository")
 	}
 
-	return nil
+	return si.updateLock(func(lock *Lockfile) {
+		for name := range installedSkills {
+			lock.Skills[name] = LockedSkill{
+				Source:      "builtin",
+				Ref:         builtinSource.Ref,
+				Version:     version,
+				InstalledAt: time.Now(),
+			}
+		}
+	})
 }
 
 func copyDir(src, dst string) error {
@@ -0,0 +1,89 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSkillSource(t *testing.T) {
+	src, err := ParseSkillSource("pepebot-space/skills/weather@v1.2")
+	if err != nil {
+		t.Fatalf("ParseSkillSource: %v", err)
+	}
+	if src.Owner != "pepebot-space" || src.Repo != "skills" || src.Path != "weather" || src.Ref != "v1.2" || src.Name() != "weather" {
+		t.Errorf("unexpected source: %+v", src)
+	}
+
+	src, _ = ParseSkillSource("someone/my-skill")
+	if src.Ref != "main" || src.Name() != "my-skill" || src.String() != "someone/my-skill" {
+		t.Errorf("unexpected source: %+v", src)
+	}
+
+	if _, err := ParseSkillSource("just-a-name"); err == nil {
+		t.Errorf("expected an error for a bare name")
+	}
+}
+
+func TestInstallAndUpdate(t *testing.T) {
+	head := "aaaaaaaaaaaa"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/someone/skills/commits":
+			if r.URL.Query().Get("path") != "weather" || r.URL.Query().Get("sha") != "main" {
+				t.Errorf("unexpected commits query: %s", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `[{"sha": %q}]`, head)
+		case "/someone/skills/" + head + "/weather/SKILL.md":
+			fmt.Fprintf(w, "---\nname: weather\n---\nversion %s\n", head)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	workspace := t.TempDir()
+	si := NewSkillInstaller(workspace)
+	si.apiBase, si.rawBase = server.URL, server.URL
+	ctx := context.Background()
+
+	if err := si.InstallFromGitHub(ctx, "someone/skills/weather"); err != nil {
+		t.Fatalf("InstallFromGitHub: %v", err)
+	}
+	lock, _ := si.LoadLock()
+	if entry := lock.Skills["weather"]; entry.Source != "someone/skills/weather" || entry.Version != head {
+		t.Fatalf("unexpected lock entry: %+v", entry)
+	}
+
+	if updates, err := si.CheckUpdates(ctx); err != nil || len(updates) != 0 {
+		t.Fatalf("expected no updates, got %v (%v)", updates, err)
+	}
+	if updated, err := si.Update(ctx, "weather"); err != nil || updated {
+		t.Fatalf("expected up to date, got %v (%v)", updated, err)
+	}
+
+	head = "bbbbbbbbbbbb"
+	updates, err := si.CheckUpdates(ctx)
+	if err != nil || len(updates) != 1 || updates[0].Latest != head {
+		t.Fatalf("expected one update, got %v (%v)", updates, err)
+	}
+	if updated, err := si.Update(ctx, "weather"); err != nil || !updated {
+		t.Fatalf("Update: %v (%v)", updated, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(workspace, "skills", "weather", "SKILL.md"))
+	if string(data) != "---\nname: weather\n---\nversion "+head+"\n" {
+		t.Errorf("skill not updated: %q", data)
+	}
+
+	if err := si.Uninstall("weather"); err != nil {
+		t.Fatalf("Uninstall: %v", err)
+	}
+	lock, _ = si.LoadLock()
+	if _, ok := lock.Skills["weather"]; ok {
+		t.Errorf("uninstall should drop the lock entry")
+	}
+}
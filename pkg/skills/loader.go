@@ -1,10 +1,8 @@
 package skills
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -32,8 +30,11 @@ type SkillMCPConfig struct {
 }
 
 type SkillRequirements struct {
-	Bins []string `json:"bins"`
-	Env  []string `json:"env"`
+	Bins    []string `json:"bins"`
+	AnyBins []string `json:"anyBins,omitempty"` // at least one must be on PATH
+	Env     []string `json:"env"`
+	Skills  []string `json:"skills,omitempty"`  // other skills that must be installed
+	Install string   `json:"install,omitempty"` // script or command that installs missing dependencies
 }
 
 type SkillInfo struct {
@@ -43,12 +44,14 @@ type SkillInfo struct {
 	Description string `json:"description"`
 	Available   bool   `json:"available"`
 	Missing     string `json:"missing,omitempty"`
+	Install     string `json:"install,omitempty"`
 }
 
 type SkillsLoader struct {
 	workspace       string
 	workspaceSkills string
 	builtinSkills   string
+	allowed         map[string]bool // nil means every skill is visible
 }
 
 func NewSkillsLoader(workspace string, builtinSkills string) *SkillsLoader {
@@ -59,13 +62,31 @@ func NewSkillsLoader(workspace string, builtinSkills string) *SkillsLoader {
 	}
 }
 
+// SetAllowedSkills restricts the loader to the named skills. An empty list
+// lifts the restriction.
+func (sl *SkillsLoader) SetAllowedSkills(names []string) {
+	if len(names) == 0 {
+		sl.allowed = nil
+		return
+	}
+	sl.allowed = make(map[string]bool, len(names))
+	for _, name := range names {
+		sl.allowed[strings.TrimSpace(name)] = true
+	}
+}
+
+// IsAllowed reports whether a skill passes the allow-list
+func (sl *SkillsLoader) IsAllowed(name string) bool {
+	return sl.allowed == nil || sl.allowed[name]
+}
+
 func (sl *SkillsLoader) ListSkills(filterUnavailable bool) []SkillInfo {
 	skills := make([]SkillInfo, 0)
 
 	if sl.workspaceSkills != "" {
 		if dirs, err := os.ReadDir(sl.workspaceSkills); err == nil {
 			for _, dir := range dirs {
-				if dir.IsDir() {
+				if dir.IsDir() && sl.IsAllowed(dir.Name()) {
 					skillFile := filepath.Join(sl.workspaceSkills, dir.Name(), "SKILL.md")
 					if _, err := os.Stat(skillFile); err == nil {
 						info := SkillInfo{
@@ -73,16 +94,7 @@ func (sl *SkillsLoader) ListSkills(filterUnavailable bool) []SkillInfo {
 							Path:   skillFile,
 							Source: "workspace",
 						}
-						metadata := sl.getSkillMetadata(skillFile)
-						if metadata != nil {
-							info.Description = metadata.Description
-							info.Available = sl.checkRequirements(metadata.Requires)
-							if !info.Available {
-								info.Missing = sl.getMissingRequirements(metadata.Requires)
-							}
-						} else {
-							info.Available = true
-						}
+						sl.fillInfo(&info)
 						skills = append(skills, info)
 					}
 				}
@@ -93,7 +105,7 @@ func (sl *SkillsLoader) ListSkills(filterUnavailable bool) []SkillInfo {
 	if sl.builtinSkills != "" {
 		if dirs, err := os.ReadDir(sl.builtinSkills); err == nil {
 			for _, dir := range dirs {
-				if dir.IsDir() {
+				if dir.IsDir() && sl.IsAllowed(dir.Name()) {
 					skillFile := filepath.Join(sl.builtinSkills, dir.Name(), "SKILL.md")
 					if _, err := os.Stat(skillFile); err == nil {
 						exists := false
@@ -112,16 +124,7 @@ func (sl *SkillsLoader) ListSkills(filterUnavailable bool) []SkillInfo {
 							Path:   skillFile,
 							Source: "builtin",
 						}
-						metadata := sl.getSkillMetadata(skillFile)
-						if metadata != nil {
-							info.Description = metadata.Description
-							info.Available = sl.checkRequirements(metadata.Requires)
-							if !info.Available {
-								info.Missing = sl.getMissingRequirements(metadata.Requires)
-							}
-						} else {
-							info.Available = true
-						}
+						sl.fillInfo(&info)
 						skills = append(skills, info)
 					}
 				}
@@ -143,6 +146,9 @@ func (sl *SkillsLoader) ListSkills(filterUnavailable bool) []SkillInfo {
 }
 
 func (sl *SkillsLoader) LoadSkill(name string) (string, bool) {
+	if !sl.IsAllowed(name) {
+		return "", false
+	}
 	if sl.workspaceSkills != "" {
 		skillFile := filepath.Join(sl.workspaceSkills, name, "SKILL.md")
 		if content, err := os.ReadFile(skillFile); err == nil {
@@ -202,6 +208,9 @@ func (sl *SkillsLoader) BuildSkillsSummary() string {
 		if !s.Available && s.Missing != "" {
 			escapedMissing := escapeXML(s.Missing)
 			lines = append(lines, fmt.Sprintf("    <requires>%s</requires>", escapedMissing))
+			if s.Install != "" {
+				lines = append(lines, fmt.Sprintf("    <install>%s</install>", escapeXML(s.Install)))
+			}
 		}
 
 		lines = append(lines, "  </skill>")
@@ -236,24 +245,25 @@ func (sl *SkillsLoader) getSkillMetadata(skillPath string) *SkillMetadata {
 		}
 	}
 
-	var metadata struct {
-		Name        string             `json:"name"`
-		Description string             `json:"description"`
-		Always      bool               `json:"always"`
-		Requires    *SkillRequirements `json:"requires"`
-		MCP         []SkillMCPConfig   `json:"mcp"`
-	}
-
-	if err := json.Unmarshal([]byte(frontmatter), &metadata); err != nil {
+	metadata, err := parseFrontmatter(frontmatter)
+	if err != nil {
 		return nil
 	}
+	return metadata
+}
 
-	return &SkillMetadata{
-		Name:        metadata.Name,
-		Description: metadata.Description,
-		Always:      metadata.Always,
-		Requires:    metadata.Requires,
-		MCP:         metadata.MCP,
+// fillInfo adds the description and requirement status from the skill's frontmatter
+func (sl *SkillsLoader) fillInfo(info *SkillInfo) {
+	info.Available = true
+	metadata := sl.getSkillMetadata(info.Path)
+	if metadata == nil {
+		return
+	}
+	info.Description = metadata.Description
+	if missing := missingRequirements(metadata.Requires, sl.hasSkill); len(missing) > 0 {
+		info.Available = false
+		info.Missing = strings.Join(missing, ", ")
+		info.Install = metadata.Requires.Install
 	}
 }
 
@@ -294,7 +304,7 @@ func (sl *SkillsLoader) SyncMCPRegistry() error {
 }
 
 func (sl *SkillsLoader) extractFrontmatter(content string) string {
-	re := regexp.MustCompile(`^---\n(.*?)\n---`)
+	re := regexp.MustCompile(`(?s)^---\n(.*?)\n---`)
 	match := re.FindStringSubmatch(content)
 	if len(match) > 1 {
 		return match[1]
@@ -303,53 +313,10 @@ func (sl *SkillsLoader) extractFrontmatter(content string) string {
 }
 
 func (sl *SkillsLoader) stripFrontmatter(content string) string {
-	re := regexp.MustCompile(`^---\n.*?\n---\n`)
+	re := regexp.MustCompile(`(?s)^---\n.*?\n---\n`)
 	return re.ReplaceAllString(content, "")
 }
 
-func (sl *SkillsLoader) checkRequirements(requires *SkillRequirements) bool {
-	if requires == nil {
-		return true
-	}
-
-	for _, bin := range requires.Bins {
-		if _, err := exec.LookPath(bin); err != nil {
-			continue
-		} else {
-			return true
-		}
-	}
-
-	for _, env := range requires.Env {
-		if os.Getenv(env) == "" {
-			return false
-		}
-	}
-
-	return true
-}
-
-func (sl *SkillsLoader) getMissingRequirements(requires *SkillRequirements) string {
-	if requires == nil {
-		return ""
-	}
-
-	var missing []string
-	for _, bin := range requires.Bins {
-		if _, err := exec.LookPath(bin); err != nil {
-			missing = append(missing, fmt.Sprintf("CLI: %s", bin))
-		}
-	}
-
-	for _, env := range requires.Env {
-		if os.Getenv(env) == "" {
-			missing = append(missing, fmt.Sprintf("ENV: %s", env))
-		}
-	}
-
-	return strings.Join(missing, ", ")
-}
-
 func escapeXML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
 	s = strings.ReplaceAll(s, "<", "&lt;")
@@ -0,0 +1,38 @@
+package skills
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowedSkills(t *testing.T) {
+	workspace := t.TempDir()
+	writeSkill(t, workspace, "weather", "---\nname: weather\ndescription: Forecasts\n---\nCall the weather API.")
+	writeSkill(t, workspace, "github", "---\nname: github\ndescription: GitHub helper\n---\nUse gh.")
+	writeSkill(t, workspace, "rules", "---\nname: rules\ndescription: House rules\nalways: true\n---\nBe polite.")
+
+	loader := NewSkillsLoader(workspace, "")
+	if got := len(loader.ListSkills(false)); got != 3 {
+		t.Fatalf("expected 3 skills without allow-list, got %d", got)
+	}
+
+	loader.SetAllowedSkills([]string{"weather", "rules"})
+	summary := loader.BuildSkillsSummary()
+	if !strings.Contains(summary, "<name>weather</name>") || strings.Contains(summary, "<name>github</name>") {
+		t.Errorf("summary ignores allow-list:\n%s", summary)
+	}
+	if _, ok := loader.LoadSkill("github"); ok {
+		t.Error("LoadSkill returned a skill outside the allow-list")
+	}
+	if body, ok := loader.LoadSkill("weather"); !ok || strings.TrimSpace(body) != "Call the weather API." {
+		t.Errorf("LoadSkill(weather) = %q, %v", body, ok)
+	}
+	if always := loader.GetAlwaysSkills(); len(always) != 1 || always[0] != "rules" {
+		t.Errorf("GetAlwaysSkills = %v", always)
+	}
+
+	loader.SetAllowedSkills(nil)
+	if _, ok := loader.LoadSkill("github"); !ok {
+		t.Error("clearing the allow-list should expose all skills")
+	}
+}
@@ -0,0 +1,86 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// LockedSkill records where an installed skill came from and which commit
+// it was installed at
+type LockedSkill struct {
+	Source      string    `json:"source"`  // owner/repo[/path] or "builtin"
+	Ref         string    `json:"ref"`     // branch or tag that is tracked for updates
+	Version     string    `json:"version"` // commit SHA the files were taken from
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// Lockfile is stored at workspace/skills/skills-lock.json
+type Lockfile struct {
+	Skills map[string]LockedSkill `json:"skills"`
+}
+
+func (si *SkillInstaller) lockPath() string {
+	return filepath.Join(si.workspace, "skills", "skills-lock.json")
+}
+
+// LoadLock reads the lockfile. A missing file yields an empty lock.
+func (si *SkillInstaller) LoadLock() (*Lockfile, error) {
+	lock := &Lockfile{Skills: map[string]LockedSkill{}}
+	data, err := os.ReadFile(si.lockPath())
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid skills lockfile: %w", err)
+	}
+	if lock.Skills == nil {
+		lock.Skills = map[string]LockedSkill{}
+	}
+	return lock, nil
+}
+
+func (si *SkillInstaller) saveLock(lock *Lockfile) error {
+	if err := os.MkdirAll(filepath.Dir(si.lockPath()), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(si.lockPath(), data, 0644)
+}
+
+// updateLock applies fn to the lockfile and saves it
+func (si *SkillInstaller) updateLock(fn func(lock *Lockfile)) error {
+	lock, err := si.LoadLock()
+	if err != nil {
+		return err
+	}
+	fn(lock)
+	return si.saveLock(lock)
+}
+
+// Names returns the locked skill names in order
+func (l *Lockfile) Names() []string {
+	names := make([]string, 0, len(l.Skills))
+	for name := range l.Skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ShortVersion abbreviates a commit SHA for display
+func ShortVersion(version string) string {
+	if len(version) > 7 {
+		return version[:7]
+	}
+	return version
+}
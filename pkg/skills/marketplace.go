@@ -0,0 +1,227 @@
+package skills
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SearchSkills filters the marketplace index by keywords and category.
+// Every keyword must match the name, description, category, author or tags;
+// results are ranked by relevance, then downloads and rating.
+func (si *SkillInstaller) SearchSkills(ctx context.Context, query, category string) ([]AvailableSkill, error) {
+	all, err := si.ListAvailableSkills(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterSkills(all, query, category), nil
+}
+
+func filterSkills(all []AvailableSkill, query, category string) []AvailableSkill {
+	keywords := strings.Fields(strings.ToLower(query))
+
+	type scored struct {
+		skill AvailableSkill
+		score int
+	}
+	var matches []scored
+	for _, skill := range all {
+		if category != "" && !strings.EqualFold(skill.Category, category) {
+			continue
+		}
+
+		name := strings.ToLower(skill.Name)
+		text := strings.ToLower(strings.Join(append([]string{skill.Description, skill.Category, skill.Author}, skill.Tags...), " "))
+
+		score := 0
+		matched := true
+		for _, kw := range keywords {
+			switch {
+			case name == kw:
+				score += 10
+			case strings.Contains(name, kw):
+				score += 5
+			case strings.Contains(text, kw):
+				score++
+			default:
+				matched = false
+			}
+		}
+		if matched {
+			matches = append(matches, scored{skill, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.skill.Downloads != b.skill.Downloads {
+			return a.skill.Downloads > b.skill.Downloads
+		}
+		return a.skill.Rating > b.skill.Rating
+	})
+
+	out := make([]AvailableSkill, len(matches))
+	for i, m := range matches {
+		out[i] = m.skill
+	}
+	return out
+}
+
+// InstallCommand returns the argument for `pepebot skills install` for a
+// marketplace entry
+func (s AvailableSkill) InstallCommand() string {
+	if s.Repository != "" {
+		if s.Path != "" && !isGitURL(s.Repository) {
+			return strings.TrimSuffix(s.Repository, "/") + "/" + s.Path
+		}
+		if s.Path != "" {
+			return s.Repository + "//" + s.Path
+		}
+		return s.Repository
+	}
+	return "pepebot-space/skills/" + s.Path
+}
+
+func isGitURL(value string) bool {
+	base := strings.SplitN(value, "?", 2)[0]
+	return strings.Contains(base, "://") || strings.HasPrefix(base, "git@") || strings.HasSuffix(base, ".git") || strings.Contains(base, ".git//")
+}
+
+// parseGitSource parses "https://host/repo.git[//path][?ref=tag]"
+func parseGitSource(value string) (SkillSource, error) {
+	src := SkillSource{}
+	if base, query, ok := strings.Cut(value, "?"); ok {
+		value = base
+		for _, param := range strings.Split(query, "&") {
+			if k, v, _ := strings.Cut(param, "="); k == "ref" {
+				src.Ref = v
+			}
+		}
+	}
+
+	// The subdirectory follows a "//" after the scheme's own "//"
+	offset := 0
+	if i := strings.Index(value, "://"); i >= 0 {
+		offset = i + 3
+	}
+	if i := strings.Index(value[offset:], "//"); i >= 0 {
+		src.Path = strings.Trim(value[offset+i+2:], "/")
+		value = value[:offset+i]
+	}
+
+	src.GitURL = strings.TrimSuffix(value, "/")
+	if src.GitURL == "" {
+		return src, fmt.Errorf("invalid git source '%s'", value)
+	}
+	return src, nil
+}
+
+// gitRepoName is the last path element of a git URL without ".git"
+func gitRepoName(gitURL string) string {
+	name := gitURL
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+// gitCommand runs git with the token (if any) sent as HTTP basic auth, which
+// GitHub, GitLab and Gitea all accept for personal access tokens
+func (si *SkillInstaller) gitCommand(ctx context.Context, dir string, args ...string) (string, error) {
+	subcommand := args[0]
+	if si.token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + si.token))
+		args = append([]string{"-c", "http.extraHeader=Authorization: Basic " + auth}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if si.token != "" {
+			msg = strings.ReplaceAll(msg, si.token, "***")
+		}
+		return "", fmt.Errorf("git %s failed: %s", subcommand, msg)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (si *SkillInstaller) gitLatestCommit(ctx context.Context, src SkillSource) (string, error) {
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := si.gitCommand(ctx, "", "ls-remote", src.GitURL, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve version: %w", err)
+	}
+	// Annotated tags are listed twice; the peeled "^{}" entry is the commit
+	found := ""
+	for _, line := range strings.Split(out, "\n") {
+		sha, name, ok := strings.Cut(line, "\t")
+		if !ok || sha == "" {
+			continue
+		}
+		if strings.HasSuffix(name, "^{}") {
+			return sha, nil
+		}
+		if found == "" {
+			found = sha
+		}
+	}
+	if found != "" {
+		return found, nil
+	}
+	return "", fmt.Errorf("ref '%s' not found in %s", ref, src.GitURL)
+}
+
+// installFromGit clones the source and copies its skill directory to
+// workspace/skills/<name>, replacing any previous copy. Returns the commit SHA.
+func (si *SkillInstaller) installFromGit(ctx context.Context, src SkillSource, name string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is required to install from %s", src.GitURL)
+	}
+
+	tmp, err := os.MkdirTemp("", "pepebot-skill-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	args := []string{"clone", "--depth", "1"}
+	if src.Ref != "" {
+		args = append(args, "--branch", src.Ref)
+	}
+	args = append(args, src.GitURL, tmp)
+	if _, err := si.gitCommand(ctx, "", args...); err != nil {
+		return "", err
+	}
+	version, err := si.gitCommand(ctx, tmp, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	skillSrc := filepath.Join(tmp, filepath.FromSlash(src.Path))
+	if _, err := os.Stat(filepath.Join(skillSrc, "SKILL.md")); err != nil {
+		return "", fmt.Errorf("no SKILL.md found in %s", src)
+	}
+	os.RemoveAll(filepath.Join(skillSrc, ".git"))
+
+	skillDir := filepath.Join(si.workspace, "skills", name)
+	if err := os.RemoveAll(skillDir); err != nil {
+		return "", fmt.Errorf("failed to replace skill: %w", err)
+	}
+	if err := copyDir(skillSrc, skillDir); err != nil {
+		return "", fmt.Errorf("failed to copy skill: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,113 @@
+package skills
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func TestSearchSkills(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("token must not be sent to the index host")
+		}
+		w.Write([]byte(`{"version": 1, "skills": [
+			{"name": "pdf-tools", "description": "Merge and split PDFs", "path": "pdf-tools", "category": "documents", "downloads": 10},
+			{"name": "invoice", "description": "Create PDF invoices", "path": "invoice", "category": "documents", "downloads": 500, "rating": 4.5},
+			{"name": "weather", "description": "Forecasts", "path": "weather", "category": "utilities", "tags": ["pdf"]}
+		]}`))
+	}))
+	defer server.Close()
+
+	si := NewSkillInstaller(t.TempDir())
+	si.Configure(config.SkillsConfig{IndexURL: server.URL, Token: "secret"})
+
+	results, err := si.SearchSkills(context.Background(), "pdf", "documents")
+	if err != nil {
+		t.Fatalf("SearchSkills: %v", err)
+	}
+	if len(results) != 2 || results[0].Name != "pdf-tools" || results[1].Name != "invoice" {
+		t.Fatalf("expected name match first, got %+v", results)
+	}
+
+	results, _ = si.SearchSkills(context.Background(), "", "")
+	if len(results) != 3 || results[0].Name != "invoice" {
+		t.Fatalf("empty query should list everything by downloads, got %+v", results)
+	}
+}
+
+func TestParseGitSource(t *testing.T) {
+	src, err := ParseSkillSource("https://gitlab.com/me/skills.git//tools/weather?ref=v1")
+	if err != nil {
+		t.Fatalf("ParseSkillSource: %v", err)
+	}
+	if src.GitURL != "https://gitlab.com/me/skills.git" || src.Path != "tools/weather" || src.Ref != "v1" || src.Name() != "weather" {
+		t.Errorf("unexpected source: %+v", src)
+	}
+	if src.String() != "https://gitlab.com/me/skills.git//tools/weather" {
+		t.Errorf("unexpected string: %s", src.String())
+	}
+
+	src, _ = ParseSkillSource("git@github.com:me/my-skill.git")
+	if src.GitURL != "git@github.com:me/my-skill.git" || src.Name() != "my-skill" {
+		t.Errorf("unexpected ssh source: %+v", src)
+	}
+}
+
+func TestInstallFromGitURL(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	os.MkdirAll(filepath.Join(repo, "weather", "scripts"), 0755)
+	os.WriteFile(filepath.Join(repo, "weather", "SKILL.md"), []byte("---\nname: weather\n---\nv1\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "weather", "scripts", "fetch.sh"), []byte("echo sunny\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "v1")
+
+	workspace := t.TempDir()
+	si := NewSkillInstaller(workspace)
+	ctx := context.Background()
+	source := "file://" + filepath.ToSlash(repo) + "//weather"
+
+	if err := si.InstallFromGitHub(ctx, source); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "skills", "weather", "scripts", "fetch.sh")); err != nil {
+		t.Fatalf("skill directory not copied: %v", err)
+	}
+	lock, _ := si.LoadLock()
+	if entry := lock.Skills["weather"]; entry.Source != "file://"+filepath.ToSlash(repo)+"//weather" || len(entry.Version) != 40 {
+		t.Fatalf("unexpected lock entry: %+v", entry)
+	}
+
+	os.WriteFile(filepath.Join(repo, "weather", "SKILL.md"), []byte("---\nname: weather\n---\nv2\n"), 0644)
+	git("commit", "-q", "-am", "v2")
+
+	if updated, err := si.Update(ctx, "weather"); err != nil || !updated {
+		t.Fatalf("Update: %v (%v)", updated, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(workspace, "skills", "weather", "SKILL.md"))
+	if string(data) != "---\nname: weather\n---\nv2\n" {
+		t.Errorf("skill not updated: %q", data)
+	}
+	if updated, err := si.Update(ctx, "weather"); err != nil || updated {
+		t.Errorf("second update should be a no-op: %v (%v)", updated, err)
+	}
+}
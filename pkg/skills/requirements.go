@@ -0,0 +1,199 @@
+package skills
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseFrontmatter reads skill metadata from either a JSON object or the
+// flat "key: value" YAML used by most SKILL.md files. Nested values must be
+// inline JSON, e.g. requires: {"bins": ["ffmpeg"]}. Requirements may also
+// be nested under metadata.pepebot.requires (the OpenClaw layout).
+func parseFrontmatter(frontmatter string) (*SkillMetadata, error) {
+	var meta SkillMetadata
+	if strings.HasPrefix(strings.TrimSpace(frontmatter), "{") {
+		if err := json.Unmarshal([]byte(frontmatter), &meta); err != nil {
+			return nil, err
+		}
+		return &meta, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	for _, line := range strings.Split(frontmatter, "\n") {
+		if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = yamlScalarToJSON(strings.TrimSpace(value))
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+
+	if meta.Requires == nil {
+		if raw, ok := fields["metadata"]; ok {
+			var nested struct {
+				Pepebot struct {
+					Requires *SkillRequirements `json:"requires"`
+				} `json:"pepebot"`
+			}
+			if json.Unmarshal(raw, &nested) == nil {
+				meta.Requires = nested.Pepebot.Requires
+			}
+		}
+	}
+	return &meta, nil
+}
+
+// yamlScalarToJSON converts a single-line YAML value into JSON
+func yamlScalarToJSON(value string) json.RawMessage {
+	switch {
+	case value == "":
+		return json.RawMessage(`null`)
+	case strings.HasPrefix(value, "{") || strings.HasPrefix(value, "["):
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+	case value == "true" || value == "false":
+		return json.RawMessage(value)
+	case strings.HasPrefix(value, `"`):
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+	case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1:
+		value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// missingRequirements lists unmet requirements as "CLI: x", "ENV: x" and
+// "SKILL: x". hasSkill reports whether another skill is installed.
+func missingRequirements(requires *SkillRequirements, hasSkill func(string) bool) []string {
+	if requires == nil {
+		return nil
+	}
+
+	var missing []string
+	for _, bin := range requires.Bins {
+		if _, err := exec.LookPath(bin); err != nil {
+			missing = append(missing, fmt.Sprintf("CLI: %s", bin))
+		}
+	}
+
+	if len(requires.AnyBins) > 0 {
+		found := false
+		for _, bin := range requires.AnyBins {
+			if _, err := exec.LookPath(bin); err == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, fmt.Sprintf("CLI: one of %s", strings.Join(requires.AnyBins, ", ")))
+		}
+	}
+
+	for _, env := range requires.Env {
+		if os.Getenv(env) == "" {
+			missing = append(missing, fmt.Sprintf("ENV: %s", env))
+		}
+	}
+
+	for _, skill := range requires.Skills {
+		if hasSkill == nil || !hasSkill(skill) {
+			missing = append(missing, fmt.Sprintf("SKILL: %s", skill))
+		}
+	}
+
+	return missing
+}
+
+// RequirementReport is the result of checking one skill
+type RequirementReport struct {
+	Name    string
+	Path    string
+	Missing []string
+	Install string // install hook declared by the skill, if any
+}
+
+// CheckSkill validates a skill's declared requirements
+func (sl *SkillsLoader) CheckSkill(name string) (*RequirementReport, error) {
+	path := sl.skillPath(name)
+	if path == "" {
+		return nil, fmt.Errorf("skill '%s' not found", name)
+	}
+
+	report := &RequirementReport{Name: name, Path: path}
+	meta := sl.getSkillMetadata(path)
+	if meta == nil || meta.Requires == nil {
+		return report, nil
+	}
+	report.Missing = missingRequirements(meta.Requires, sl.hasSkill)
+	report.Install = meta.Requires.Install
+	return report, nil
+}
+
+// RunInstallHook runs the skill's install hook from the skill directory.
+// The hook is a script path relative to the skill (e.g. scripts/install.sh)
+// or a shell command.
+func (sl *SkillsLoader) RunInstallHook(ctx context.Context, name string) (string, error) {
+	report, err := sl.CheckSkill(name)
+	if err != nil {
+		return "", err
+	}
+	if report.Install == "" {
+		return "", fmt.Errorf("skill '%s' has no install hook", name)
+	}
+
+	dir := filepath.Dir(report.Path)
+	command := report.Install
+	if script := filepath.Join(dir, filepath.FromSlash(command)); !strings.ContainsAny(command, " \t") {
+		if _, err := os.Stat(script); err == nil {
+			command = "sh '" + strings.ReplaceAll(script, "'", `'\''`) + "'"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("install hook failed: %w", err)
+	}
+	return string(output), nil
+}
+
+func (sl *SkillsLoader) skillPath(name string) string {
+	for _, dir := range []string{sl.workspaceSkills, sl.builtinSkills} {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name, "SKILL.md")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func (sl *SkillsLoader) hasSkill(name string) bool {
+	return sl.skillPath(name) != ""
+}
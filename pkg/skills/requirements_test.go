@@ -0,0 +1,102 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeSkill(t *testing.T, workspace, name, content string) {
+	t.Helper()
+	dir := filepath.Join(workspace, "skills", name)
+	os.MkdirAll(dir, 0755)
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	meta, err := parseFrontmatter(`name: video
+description: "Cut and convert videos: fast"
+always: true
+requires: {"bins": ["ffmpeg"], "env": ["VIDEO_TOKEN"], "install": "scripts/install.sh"}`)
+	if err != nil {
+		t.Fatalf("parseFrontmatter: %v", err)
+	}
+	if meta.Name != "video" || meta.Description != "Cut and convert videos: fast" || !meta.Always {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if meta.Requires == nil || meta.Requires.Bins[0] != "ffmpeg" || meta.Requires.Install != "scripts/install.sh" {
+		t.Errorf("unexpected requires: %+v", meta.Requires)
+	}
+
+	meta, _ = parseFrontmatter(`name: nested
+metadata: {"pepebot":{"emoji":"🔄","requires":{"env":["X"]}}}`)
+	if meta.Requires == nil || len(meta.Requires.Env) != 1 {
+		t.Errorf("requires under metadata.pepebot not read: %+v", meta.Requires)
+	}
+}
+
+func TestCheckSkillRequirements(t *testing.T) {
+	workspace := t.TempDir()
+	writeSkill(t, workspace, "base", "---\nname: base\ndescription: Base skill\n---\n")
+	writeSkill(t, workspace, "needy", `---
+name: needy
+description: Needs things
+requires: {"bins": ["definitely-not-a-real-binary"], "env": ["PEPEBOT_TEST_SKILL_TOKEN"], "skills": ["base", "absent"]}
+---
+`)
+	t.Setenv("PEPEBOT_TEST_SKILL_TOKEN", "")
+
+	loader := NewSkillsLoader(workspace, "")
+	report, err := loader.CheckSkill("needy")
+	if err != nil {
+		t.Fatalf("CheckSkill: %v", err)
+	}
+	want := []string{"CLI: definitely-not-a-real-binary", "ENV: PEPEBOT_TEST_SKILL_TOKEN", "SKILL: absent"}
+	if strings.Join(report.Missing, "|") != strings.Join(want, "|") {
+		t.Errorf("missing = %v, want %v", report.Missing, want)
+	}
+
+	for _, info := range loader.ListSkills(false) {
+		switch info.Name {
+		case "base":
+			if !info.Available || info.Description != "Base skill" {
+				t.Errorf("unexpected base info: %+v", info)
+			}
+		case "needy":
+			if info.Available || !strings.Contains(info.Missing, "SKILL: absent") {
+				t.Errorf("unexpected needy info: %+v", info)
+			}
+		}
+	}
+}
+
+func TestRunInstallHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("install hooks run through sh")
+	}
+	workspace := t.TempDir()
+	writeSkill(t, workspace, "tool", `---
+name: tool
+requires: {"env": ["PEPEBOT_TEST_NEVER_SET"], "install": "scripts/install.sh"}
+---
+`)
+	os.MkdirAll(filepath.Join(workspace, "skills", "tool", "scripts"), 0755)
+	os.WriteFile(filepath.Join(workspace, "skills", "tool", "scripts", "install.sh"), []byte("echo installing\ntouch installed\n"), 0644)
+
+	loader := NewSkillsLoader(workspace, "")
+	output, err := loader.RunInstallHook(context.Background(), "tool")
+	if err != nil {
+		t.Fatalf("RunInstallHook: %v (%s)", err, output)
+	}
+	if !strings.Contains(output, "installing") {
+		t.Errorf("unexpected output: %q", output)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "skills", "tool", "installed")); err != nil {
+		t.Errorf("hook did not run in the skill directory: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package skills
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TestsDir is the folder of a skill holding its eval suites, YAML files in
+// the format of pkg/eval with example prompts and the expected behaviour
+const TestsDir = "tests"
+
+// TestSuites returns the suite files in a skill's tests folder, sorted by
+// name. A skill without tests returns none.
+func (sl *SkillsLoader) TestSuites(name string) ([]string, error) {
+	dir, ok := sl.skillDir(name)
+	if !ok {
+		return nil, fmt.Errorf("skill '%s' not found", name)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, TestsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, TestsDir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// skillDir returns the folder of a skill, preferring the workspace copy as
+// LoadSkill does
+func (sl *SkillsLoader) skillDir(name string) (string, bool) {
+	if !sl.IsAllowed(name) || name == "" || strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", false
+	}
+	for _, root := range []string{sl.workspaceSkills, sl.builtinSkills} {
+		if root == "" {
+			continue
+		}
+		dir := filepath.Join(root, name)
+		if _, err := os.Stat(filepath.Join(dir, "SKILL.md")); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
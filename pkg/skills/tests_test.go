@@ -0,0 +1,38 @@
+package skills
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestSuites(t *testing.T) {
+	workspace := t.TempDir()
+	writeSkill(t, workspace, "weather", "---\nname: weather\ndescription: Forecasts\n---\nCall the weather API.")
+	writeSkill(t, workspace, "github", "---\nname: github\ndescription: GitHub helper\n---\nUse gh.")
+
+	tests := filepath.Join(workspace, "skills", "weather", TestsDir)
+	os.MkdirAll(tests, 0755)
+	for _, name := range []string{"rain.yml", "basic.yaml", "notes.md"} {
+		os.WriteFile(filepath.Join(tests, name), []byte("cases: []\n"), 0644)
+	}
+
+	loader := NewSkillsLoader(workspace, "")
+	files, err := loader.TestSuites("weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != "basic.yaml" || filepath.Base(files[1]) != "rain.yml" {
+		t.Errorf("TestSuites(weather) = %v", files)
+	}
+
+	if files, err := loader.TestSuites("github"); err != nil || len(files) != 0 {
+		t.Errorf("TestSuites(github) = %v, %v; want none", files, err)
+	}
+	if _, err := loader.TestSuites("missing"); err == nil {
+		t.Error("expected an error for an unknown skill")
+	}
+	if _, err := loader.TestSuites("../skills/weather"); err == nil {
+		t.Error("expected an error for a path instead of a name")
+	}
+}
@@ -0,0 +1,278 @@
+// Package tokens counts tokens with tiktoken encodings chosen by model family.
+//
+// Encoding files are downloaded once into ~/.pepebot/cache/tiktoken (or
+// $TIKTOKEN_CACHE_DIR) in the background. Until an encoding is available,
+// and when it cannot be fetched, counts fall back to a script-aware estimate.
+// PEPEBOT_TOKENIZER=estimate skips the download and always estimates.
+package tokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/pkoukk/tiktoken-go"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+const (
+	EncodingO200K  = "o200k_base"
+	EncodingCL100K = "cl100k_base"
+)
+
+// imageTokens is a rough cost for one image block; it varies by model
+const imageTokens = 1000
+
+// messageOverhead covers the role and separators around each message
+const messageOverhead = 4
+
+var (
+	mu       sync.Mutex
+	encoders = map[string]*tiktoken.Tiktoken{}
+	loading  = map[string]bool{}
+	failed   = map[string]time.Time{}
+
+	// retryAfter limits how often a failed encoding download is retried
+	retryAfter = 10 * time.Minute
+)
+
+func init() {
+	tiktoken.SetBpeLoader(&cachedLoader{})
+}
+
+// EncodingForModel picks the tiktoken encoding for a model. OpenAI's newer
+// families use o200k_base; everything else (older GPT, Claude, Gemini,
+// Llama, ...) is counted with cl100k_base, the closest public vocabulary.
+func EncodingForModel(model string) string {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "chatgpt-4o", "o1", "o3", "o4", "gpt-oss"} {
+		if strings.HasPrefix(name, prefix) {
+			return EncodingO200K
+		}
+	}
+	return EncodingCL100K
+}
+
+// Count returns the number of tokens in text for the given model
+func Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	if enc := encoder(EncodingForModel(model)); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	return Estimate(text)
+}
+
+// CountContent counts a message's content: a string or multimodal blocks
+func CountContent(model string, content interface{}) int {
+	switch v := content.(type) {
+	case string:
+		return Count(model, v)
+	case []providers.ContentBlock:
+		total := 0
+		for _, block := range v {
+			switch block.Type {
+			case "text":
+				total += Count(model, block.Text)
+			case "image_url":
+				total += imageTokens
+			}
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// CountMessages counts the content of messages plus per-message overhead
+func CountMessages(model string, messages []providers.Message) int {
+	total := 0
+	for _, m := range messages {
+		total += CountContent(model, m.Content) + messageOverhead
+	}
+	return total
+}
+
+// Estimate approximates a token count without a vocabulary. ASCII text
+// averages about four characters per token, while CJK characters are
+// usually one token each and other scripts fall in between.
+func Estimate(text string) int {
+	ascii, cjk, other := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case r < 128:
+			ascii++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		default:
+			other++
+		}
+	}
+	return (ascii+3)/4 + cjk + (other+1)/2
+}
+
+// encoder returns a loaded encoding, starting a background load on first use
+func encoder(name string) *tiktoken.Tiktoken {
+	if os.Getenv("PEPEBOT_TOKENIZER") == "estimate" {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if enc, ok := encoders[name]; ok {
+		return enc
+	}
+	if loading[name] || time.Since(failed[name]) < retryAfter {
+		return nil
+	}
+	loading[name] = true
+	go load(name)
+	return nil
+}
+
+func load(name string) {
+	enc, err := tiktoken.GetEncoding(name)
+
+	mu.Lock()
+	defer mu.Unlock()
+	delete(loading, name)
+	if err != nil {
+		failed[name] = time.Now()
+		logger.WarnCF("tokens", "Tokenizer unavailable, using estimates", map[string]interface{}{
+			"encoding": name,
+			"error":    err.Error(),
+		})
+		return
+	}
+	encoders[name] = enc
+	logger.DebugCF("tokens", "Tokenizer loaded", map[string]interface{}{"encoding": name})
+}
+
+func cacheDir() string {
+	if dir := strings.TrimSpace(os.Getenv("TIKTOKEN_CACHE_DIR")); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "pepebot-tiktoken")
+	}
+	return filepath.Join(home, ".pepebot", "cache", "tiktoken")
+}
+
+// knownHashes are the sha256 sums of the encoding files, as pinned by
+// OpenAI's tiktoken. Files that don't match are never cached or used.
+var knownHashes = map[string]string{
+	"r50k_base.tiktoken":   "306cd27f03c1a714eca7108e03d66b7dc042abe8c258b44c199a7ed9838dd930",
+	"p50k_base.tiktoken":   "94b5ca7dff4d00767bc256fdd1b27e5b17361d7b8a5f968547f9f23eb70d2069",
+	"cl100k_base.tiktoken": "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	"o200k_base.tiktoken":  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+// cachedLoader reads encoding files from the cache directory and downloads
+// missing ones with a timeout. A cached file that fails its checksum or
+// doesn't parse is deleted and downloaded again.
+type cachedLoader struct{}
+
+func (l *cachedLoader) LoadTiktokenBpe(url string) (map[string]int, error) {
+	name := filepath.Base(url)
+	path := filepath.Join(cacheDir(), name)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := verify(name, data); err == nil {
+			if ranks, err := parseBpe(data); err == nil {
+				return ranks, nil
+			}
+		}
+		os.Remove(path)
+	}
+
+	data, err := download(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(name, data); err != nil {
+		return nil, err
+	}
+	ranks, err := parseBpe(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		tmp := path + ".tmp"
+		if os.WriteFile(tmp, data, 0644) == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	return ranks, nil
+}
+
+// verify checks data against the known hash of the encoding file name
+func verify(name string, data []byte) error {
+	want, ok := knownHashes[name]
+	if !ok {
+		return nil
+	}
+	if got := fmt.Sprintf("%x", sha256.Sum256(data)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	return nil
+}
+
+func download(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseBpe reads the "<base64 token> <rank>" lines of a .tiktoken file
+func parseBpe(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid encoding line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rank))
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(decoded)] = n
+	}
+	return ranks, nil
+}
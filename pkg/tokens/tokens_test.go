@@ -0,0 +1,127 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]string{
+		"gpt-4o-mini":           EncodingO200K,
+		"openai/gpt-4.1":        EncodingO200K,
+		"o3-mini":               EncodingO200K,
+		"gpt-4":                 EncodingCL100K,
+		"maia/gemini-2.5-flash": EncodingCL100K,
+		"claude-3-5-sonnet":     EncodingCL100K,
+	}
+	for model, want := range cases {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %s, want %s", model, got, want)
+		}
+	}
+}
+
+func TestEstimateCountsCJKPerCharacter(t *testing.T) {
+	if got := Estimate("hello world!"); got != 3 {
+		t.Errorf("Estimate(ascii) = %d, want 3", got)
+	}
+	// len/4 would give 9 for these 12 bytes; each character is about a token
+	if got := Estimate("你好世界"); got != 4 {
+		t.Errorf("Estimate(cjk) = %d, want 4", got)
+	}
+}
+
+func TestCountUsesCachedEncoding(t *testing.T) {
+	// A byte-level vocabulary makes every byte one token
+	dir := t.TempDir()
+	var lines []string
+	for b := 0; b < 256; b++ {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b))
+	}
+	data := []byte(strings.Join(lines, "\n"))
+	if err := os.WriteFile(filepath.Join(dir, "cl100k_base.tiktoken"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TIKTOKEN_CACHE_DIR", dir)
+	pinned := knownHashes["cl100k_base.tiktoken"]
+	knownHashes["cl100k_base.tiktoken"] = fmt.Sprintf("%x", sha256.Sum256(data))
+	t.Cleanup(func() {
+		knownHashes["cl100k_base.tiktoken"] = pinned
+		mu.Lock()
+		delete(encoders, EncodingCL100K)
+		mu.Unlock()
+	})
+
+	load(EncodingCL100K)
+	if got := Count("claude-3-5-sonnet", "hello"); got != 5 {
+		t.Errorf("Count = %d, want 5 from the cached encoding", got)
+	}
+
+	messages := []providers.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "yo"}}
+	if got := CountMessages("gpt-4", messages); got != 2*(2+messageOverhead) {
+		t.Errorf("CountMessages = %d", got)
+	}
+
+	t.Setenv("PEPEBOT_TOKENIZER", "estimate")
+	if got := Count("gpt-4", "hello"); got != Estimate("hello") {
+		t.Errorf("PEPEBOT_TOKENIZER=estimate should bypass the tokenizer, got %d", got)
+	}
+}
+
+func TestCachedLoaderRejectsTamperedFile(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("aGk= 0\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	t.Setenv("TIKTOKEN_CACHE_DIR", dir)
+	path := filepath.Join(dir, "cl100k_base.tiktoken")
+	if err := os.WriteFile(path, []byte("aGk= 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &cachedLoader{}
+	if _, err := loader.LoadTiktokenBpe(server.URL + "/cl100k_base.tiktoken"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum error, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the bad cache file to be downloaded again, got %d requests", requests)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("tampered cache file was kept: %v", err)
+	}
+}
+
+func TestCachedLoaderDropsUnparsableCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("aGk= 0\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	t.Setenv("TIKTOKEN_CACHE_DIR", dir)
+	path := filepath.Join(dir, "custom.tiktoken")
+	if err := os.WriteFile(path, []byte("truncated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ranks, err := (&cachedLoader{}).LoadTiktokenBpe(server.URL + "/custom.tiktoken")
+	if err != nil || ranks["hi"] != 0 || len(ranks) != 1 {
+		t.Fatalf("LoadTiktokenBpe = %v, %v", ranks, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "aGk= 0\n" {
+		t.Errorf("cache file not replaced, got %q", data)
+	}
+}
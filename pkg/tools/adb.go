@@ -258,6 +258,11 @@ func (t *AdbShellTool) Name() string {
 	return "adb_shell"
 }
 
+// Sequential keeps device actions in the order the model issued them
+func (t *AdbShellTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbShellTool) Description() string {
 	return "Execute a shell command on the connected Android device via ADB. Useful for running system commands, checking properties, or interacting with the device."
 }
@@ -314,6 +319,10 @@ func (t *AdbTapTool) Name() string {
 	return "adb_tap"
 }
 
+func (t *AdbTapTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbTapTool) Description() string {
 	return "Simulate a tap or long press gesture at specific screen coordinates on the Android device. Supports single tap, multi-tap, and long press."
 }
@@ -406,6 +415,10 @@ func (t *AdbInputTextTool) Name() string {
 	return "adb_input_text"
 }
 
+func (t *AdbInputTextTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbInputTextTool) Description() string {
 	return "Input text into the currently focused field on the Android device. Text is automatically chunked and escaped for reliable input. Optionally sends Enter key after input."
 }
@@ -503,6 +516,10 @@ func (t *AdbScreenshotTool) Name() string {
 	return "adb_screenshot"
 }
 
+func (t *AdbScreenshotTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbScreenshotTool) Description() string {
 	return "Capture a screenshot from the Android device. Uses exec-out for direct PNG capture. Can save to file or return as base64."
 }
@@ -579,6 +596,10 @@ func (t *AdbUIDumpTool) Name() string {
 	return "adb_ui_dump"
 }
 
+func (t *AdbUIDumpTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbUIDumpTool) Description() string {
 	return "Get the UI hierarchy (accessibility tree) of the current screen on the Android device. Returns XML structure with UI element information including bounds, text, and resource IDs."
 }
@@ -598,6 +619,22 @@ func (t *AdbUIDumpTool) Parameters() map[string]interface{} {
 func (t *AdbUIDumpTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	device, _ := args["device"].(string)
 
+	output, err := t.helper.dumpUI(ctx, device)
+	if err != nil {
+		return "", err
+	}
+
+	// Truncate if too long
+	maxLen := 20000
+	if len(output) > maxLen {
+		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
+	}
+
+	return output, nil
+}
+
+// dumpUI returns the UI hierarchy XML of the current screen
+func (h *AdbHelper) dumpUI(ctx context.Context, device string) (string, error) {
 	// Try multiple dump paths - /sdcard/ is not always writable on some devices
 	dumpPaths := []string{
 		"/sdcard/window_dump.xml",
@@ -609,23 +646,23 @@ func (t *AdbUIDumpTool) Execute(ctx context.Context, args map[string]interface{}
 	for _, dumpPath := range dumpPaths {
 		// Try dump (ignore command output - it varies across Android versions/devices)
 		// Some output to stdout, some to stderr, some output nothing
-		t.helper.execAdb(ctx, device, 15*time.Second,
+		h.execAdb(ctx, device, 15*time.Second,
 			"shell", "uiautomator", "dump", dumpPath)
 
 		// Small delay to ensure file is fully written
 		time.Sleep(200 * time.Millisecond)
 
 		// Try to read the dumped file - this is the real success check
-		content, err := t.helper.execAdb(ctx, device, 12*time.Second,
+		content, err := h.execAdb(ctx, device, 12*time.Second,
 			"exec-out", "cat", dumpPath)
 		if err != nil || len(strings.TrimSpace(content)) == 0 {
 			// Fallback to shell cat
-			content, err = t.helper.execAdb(ctx, device, 12*time.Second,
+			content, err = h.execAdb(ctx, device, 12*time.Second,
 				"shell", "cat", dumpPath)
 		}
 
 		// Clean up (best effort)
-		t.helper.execAdb(ctx, device, 5*time.Second, "shell", "rm", dumpPath)
+		h.execAdb(ctx, device, 5*time.Second, "shell", "rm", dumpPath)
 
 		if err != nil || len(strings.TrimSpace(content)) == 0 {
 			continue
@@ -645,12 +682,12 @@ func (t *AdbUIDumpTool) Execute(ctx context.Context, args map[string]interface{}
 
 	// If all paths failed, try one more time with default path (no explicit path arg)
 	if output == "" {
-		t.helper.execAdb(ctx, device, 15*time.Second,
+		h.execAdb(ctx, device, 15*time.Second,
 			"shell", "uiautomator", "dump")
 		time.Sleep(200 * time.Millisecond)
 
 		// uiautomator dump without path defaults to /sdcard/window_dump.xml
-		content, err := t.helper.execAdb(ctx, device, 12*time.Second,
+		content, err := h.execAdb(ctx, device, 12*time.Second,
 			"shell", "cat", "/sdcard/window_dump.xml")
 		if err == nil {
 			if idx := strings.Index(content, "<?xml"); idx > 0 {
@@ -660,19 +697,12 @@ func (t *AdbUIDumpTool) Execute(ctx context.Context, args map[string]interface{}
 				output = strings.TrimSpace(content)
 			}
 		}
-		t.helper.execAdb(ctx, device, 5*time.Second, "shell", "rm", "/sdcard/window_dump.xml")
+		h.execAdb(ctx, device, 5*time.Second, "shell", "rm", "/sdcard/window_dump.xml")
 	}
 
 	if output == "" {
 		return "", fmt.Errorf("failed to dump UI hierarchy: uiautomator dump returned no valid XML. Device screen may be locked or accessibility service unavailable")
 	}
-
-	// Truncate if too long
-	maxLen := 20000
-	if len(output) > maxLen {
-		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
-	}
-
 	return output, nil
 }
 
@@ -690,6 +720,10 @@ func (t *AdbSwipeTool) Name() string {
 	return "adb_swipe"
 }
 
+func (t *AdbSwipeTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbSwipeTool) Description() string {
 	return "Simulate a swipe gesture on the Android device. Can use explicit coordinates (x1,y1 to x2,y2) or direction-based swipe from a starting point. Useful for scrolling, swiping between screens, or dragging."
 }
@@ -830,6 +864,10 @@ func (t *AdbOpenAppTool) Name() string {
 	return "adb_open_app"
 }
 
+func (t *AdbOpenAppTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbOpenAppTool) Description() string {
 	return "Launch an application on the Android device by package name. Uses am start with fallback to monkey launcher. Common packages: com.android.settings, com.android.chrome, com.whatsapp, com.google.android.apps.photos."
 }
@@ -891,6 +929,10 @@ func (t *AdbKeyEventTool) Name() string {
 	return "adb_keyevent"
 }
 
+func (t *AdbKeyEventTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbKeyEventTool) Description() string {
 	return "Send a key event to the Android device. Common keycodes: 3=Home, 4=Back, 24=Volume Up, 25=Volume Down, 26=Power, 66=Enter, 67=Backspace, 82=Menu, 187=Recent Apps."
 }
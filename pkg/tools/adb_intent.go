@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quoteDeviceArg quotes an argument for the device shell, which adb shell
+// joins the arguments into
+func quoteDeviceArg(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-/:=,@%+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// intentArgs builds the am arguments for an intent
+func intentArgs(args map[string]interface{}) ([]string, error) {
+	mode, _ := args["type"].(string)
+	var am []string
+	switch mode {
+	case "", "start":
+		am = []string{"am", "start"}
+		if wait, _ := args["wait"].(bool); wait {
+			am = append(am, "-W")
+		}
+	case "broadcast":
+		am = []string{"am", "broadcast"}
+	case "service":
+		am = []string{"am", "startservice"}
+	default:
+		return nil, fmt.Errorf("unknown intent type %q (use start, broadcast or service)", mode)
+	}
+
+	action, _ := args["action"].(string)
+	data, _ := args["data"].(string)
+	component, _ := args["component"].(string)
+	if action == "" && data == "" && component == "" {
+		return nil, fmt.Errorf("give at least an action, data URI or component")
+	}
+	if action != "" {
+		am = append(am, "-a", action)
+	}
+	if data != "" {
+		am = append(am, "-d", data)
+	}
+	if mimeType, _ := args["mime_type"].(string); mimeType != "" {
+		am = append(am, "-t", mimeType)
+	}
+	for _, c := range stringList(args["categories"]) {
+		am = append(am, "-c", c)
+	}
+	if component != "" {
+		am = append(am, "-n", component)
+	} else if pkg, _ := args["package"].(string); pkg != "" {
+		am = append(am, "-p", pkg)
+	}
+
+	extras, _ := args["extras"].(map[string]interface{})
+	keys := make([]string, 0, len(extras))
+	for k := range extras {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := extras[k].(type) {
+		case string:
+			am = append(am, "--es", k, v)
+		case bool:
+			am = append(am, "--ez", k, strconv.FormatBool(v))
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+				am = append(am, "--ei", k, strconv.FormatInt(int64(v), 10))
+			} else if v == math.Trunc(v) {
+				am = append(am, "--el", k, strconv.FormatInt(int64(v), 10))
+			} else {
+				am = append(am, "--ef", k, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		default:
+			return nil, fmt.Errorf("extra %q must be a string, number or boolean", k)
+		}
+	}
+
+	quoted := []string{"shell"}
+	for _, a := range am {
+		quoted = append(quoted, quoteDeviceArg(a))
+	}
+	return quoted, nil
+}
+
+// ==================== ADB Send Intent Tool ====================
+
+type AdbSendIntentTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbSendIntentTool(helper *AdbHelper) *AdbSendIntentTool {
+	return &AdbSendIntentTool{helper: helper}
+}
+
+func (t *AdbSendIntentTool) Name() string {
+	return "adb_send_intent"
+}
+
+func (t *AdbSendIntentTool) Sequential() bool {
+	return true
+}
+
+func (t *AdbSendIntentTool) Description() string {
+	return "Send an Android intent with am start (activity), am broadcast or am startservice. Use it to open deep links (action android.intent.action.VIEW with a data URI), share text (android.intent.action.SEND with mime_type text/plain and extra android.intent.extra.TEXT), or trigger Tasker/automation apps with a broadcast, instead of simulating taps."
+}
+
+func (t *AdbSendIntentTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"start", "broadcast", "service"},
+				"description": "start an activity (default), send a broadcast or start a service",
+			},
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "Intent action, e.g. 'android.intent.action.VIEW'",
+			},
+			"data": map[string]interface{}{
+				"type":        "string",
+				"description": "Data URI, e.g. 'https://example.com/item/42' or 'geo:0,0?q=coffee'",
+			},
+			"mime_type": map[string]interface{}{
+				"type":        "string",
+				"description": "MIME type, e.g. 'text/plain'",
+			},
+			"categories": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Intent categories, e.g. ['android.intent.category.BROWSABLE']",
+			},
+			"component": map[string]interface{}{
+				"type":        "string",
+				"description": "Explicit component 'package/.Activity' or 'package/.Receiver'",
+			},
+			"package": map[string]interface{}{
+				"type":        "string",
+				"description": "Limit the intent to this app package (ignored with component)",
+			},
+			"extras": map[string]interface{}{
+				"type":        "object",
+				"description": "Extras by key. Strings, whole numbers and booleans are sent as --es, --ei and --ez; other numbers as --ef.",
+			},
+			"wait": map[string]interface{}{
+				"type":        "boolean",
+				"description": "start only: wait for the activity to launch and report timing",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbSendIntentTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+	cmdArgs, err := intentArgs(args)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := t.helper.execAdb(ctx, device, 20*time.Second, cmdArgs...)
+	if err != nil {
+		return "", err
+	}
+	output = strings.TrimSpace(output)
+	// am reports most failures on stdout with a zero exit code
+	for _, marker := range []string{"Error:", "Error type", "Exception", "Unable to resolve"} {
+		if strings.Contains(output, marker) {
+			return "", fmt.Errorf("intent failed: %s", output)
+		}
+	}
+	return fmt.Sprintf("Intent sent\n%s", output), nil
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntentArgs(t *testing.T) {
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{
+			map[string]interface{}{"action": "android.intent.action.VIEW", "data": "https://example.com/item?id=42&ref=bot", "wait": true},
+			"shell am start -W -a android.intent.action.VIEW -d 'https://example.com/item?id=42&ref=bot'",
+		},
+		{
+			map[string]interface{}{
+				"action":    "android.intent.action.SEND",
+				"mime_type": "text/plain",
+				"package":   "com.whatsapp",
+				"extras":    map[string]interface{}{"android.intent.extra.TEXT": "It's done", "count": float64(3), "urgent": true, "ratio": 0.5},
+			},
+			`shell am start -a android.intent.action.SEND -t text/plain -p com.whatsapp --es android.intent.extra.TEXT 'It'\''s done' --ei count 3 --ef ratio 0.5 --ez urgent true`,
+		},
+		{
+			map[string]interface{}{"type": "broadcast", "action": "net.dinglisch.android.tasker.ACTION_TASK", "component": "net.dinglisch.android.taskerm/.Receiver", "categories": []interface{}{"android.intent.category.DEFAULT"}},
+			"shell am broadcast -a net.dinglisch.android.tasker.ACTION_TASK -c android.intent.category.DEFAULT -n net.dinglisch.android.taskerm/.Receiver",
+		},
+	}
+	for _, tt := range tests {
+		got, err := intentArgs(tt.args)
+		if err != nil {
+			t.Fatalf("intentArgs(%v): %v", tt.args, err)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("got  %s\nwant %s", strings.Join(got, " "), tt.want)
+		}
+	}
+
+	if _, err := intentArgs(map[string]interface{}{"type": "start"}); err == nil {
+		t.Error("expected an error without action, data or component")
+	}
+	if _, err := intentArgs(map[string]interface{}{"action": "x", "extras": map[string]interface{}{"list": []interface{}{1}}}); err == nil {
+		t.Error("expected an error for an unsupported extra")
+	}
+}
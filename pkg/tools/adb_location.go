@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deviceLocation is a location fix reported by the device
+type deviceLocation struct {
+	Provider  string  `json:"provider"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy_m,omitempty"`
+	MapsURL   string  `json:"maps_url"`
+}
+
+// dumpsys location prints fixes like
+// "Location[gps 37.421998,-122.084000 hAcc=20 et=+1d2h ...]"
+var locationPattern = regexp.MustCompile(`Location\[(\w+) (-?\d+(?:\.\d+)?),(-?\d+(?:\.\d+)?)(?:[^\]]*?\b(?:hAcc|acc)=([\d.]+))?`)
+
+// locationPreference orders providers from most to least useful
+var locationPreference = []string{"fused", "gps", "network", "passive"}
+
+// parseLocations returns the location fixes in dumpsys location output,
+// one per provider, best provider first
+func parseLocations(dump string) []deviceLocation {
+	byProvider := make(map[string]deviceLocation)
+	var order []string
+	for _, m := range locationPattern.FindAllStringSubmatch(dump, -1) {
+		lat, err1 := strconv.ParseFloat(m[2], 64)
+		lon, err2 := strconv.ParseFloat(m[3], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		loc := deviceLocation{
+			Provider:  m[1],
+			Latitude:  lat,
+			Longitude: lon,
+			MapsURL:   fmt.Sprintf("https://maps.google.com/?q=%s,%s", m[2], m[3]),
+		}
+		if m[4] != "" {
+			loc.Accuracy, _ = strconv.ParseFloat(m[4], 64)
+		}
+		if _, seen := byProvider[loc.Provider]; !seen {
+			order = append(order, loc.Provider)
+			byProvider[loc.Provider] = loc
+		}
+	}
+
+	var out []deviceLocation
+	for _, p := range locationPreference {
+		if loc, ok := byProvider[p]; ok {
+			out = append(out, loc)
+			delete(byProvider, p)
+		}
+	}
+	for _, p := range order {
+		if loc, ok := byProvider[p]; ok {
+			out = append(out, loc)
+		}
+	}
+	return out
+}
+
+// ==================== ADB Get Location Tool ====================
+
+type AdbGetLocationTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbGetLocationTool(helper *AdbHelper) *AdbGetLocationTool {
+	return &AdbGetLocationTool{helper: helper}
+}
+
+func (t *AdbGetLocationTool) Name() string {
+	return "adb_get_location"
+}
+
+func (t *AdbGetLocationTool) Description() string {
+	return "Get the Android device's last known location (latitude, longitude, accuracy in meters and a map link) from dumpsys location. Useful for location-aware automations like checking in on arrival."
+}
+
+func (t *AdbGetLocationTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return the last fix of every provider instead of only the best one",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbGetLocationTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+	all, _ := args["all"].(bool)
+
+	output, err := t.helper.execAdb(ctx, device, 15*time.Second, "shell", "dumpsys", "location")
+	if err != nil {
+		return "", err
+	}
+	locations := parseLocations(output)
+	if len(locations) == 0 {
+		return "", fmt.Errorf("the device has no known location. Check that location is turned on and an app has requested a fix recently")
+	}
+
+	var result interface{} = locations[0]
+	if all {
+		result = locations
+	}
+	data, _ := json.MarshalIndent(result, "", "  ")
+	return string(data), nil
+}
+
+// ==================== ADB Set Mock Location Tool ====================
+
+type AdbSetMockLocationTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbSetMockLocationTool(helper *AdbHelper) *AdbSetMockLocationTool {
+	return &AdbSetMockLocationTool{helper: helper}
+}
+
+func (t *AdbSetMockLocationTool) Name() string {
+	return "adb_set_mock_location"
+}
+
+// Sequential keeps the location change in order with the actions around it
+func (t *AdbSetMockLocationTool) Sequential() bool {
+	return true
+}
+
+func (t *AdbSetMockLocationTool) Description() string {
+	return "Set a fake GPS location on the Android device for testing location-aware apps. Emulators use 'emu geo fix'; real devices (Android 12+) get a test location provider. Set clear=true to remove the mock location."
+}
+
+func (t *AdbSetMockLocationTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"latitude": map[string]interface{}{
+				"type":        "number",
+				"description": "Latitude in degrees (-90 to 90)",
+			},
+			"longitude": map[string]interface{}{
+				"type":        "number",
+				"description": "Longitude in degrees (-180 to 180)",
+			},
+			"accuracy": map[string]interface{}{
+				"type":        "number",
+				"description": "Accuracy in meters (default 5)",
+			},
+			"clear": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Remove the mock location and go back to the real one",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbSetMockLocationTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+	emulator := strings.HasPrefix(device, "emulator-")
+	if device == "" {
+		// With one device attached, ask what it is
+		if qemu, err := t.helper.execAdb(ctx, device, 10*time.Second, "shell", "getprop", "ro.kernel.qemu"); err == nil {
+			emulator = strings.TrimSpace(qemu) == "1"
+		}
+	}
+
+	if clear, _ := args["clear"].(bool); clear {
+		if emulator {
+			return "Emulators keep the last 'geo fix' location; set the location again or change it in the emulator's extended controls", nil
+		}
+		if _, err := t.helper.execAdb(ctx, device, 10*time.Second, "shell", "cmd", "location", "providers", "remove-test-provider", "gps"); err != nil {
+			return "", err
+		}
+		return "Mock location removed", nil
+	}
+
+	lat, okLat := args["latitude"].(float64)
+	lon, okLon := args["longitude"].(float64)
+	if !okLat || !okLon {
+		return "", fmt.Errorf("latitude and longitude are required")
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return "", fmt.Errorf("coordinates out of range: %g,%g", lat, lon)
+	}
+	accuracy := 5.0
+	if a, ok := args["accuracy"].(float64); ok && a > 0 {
+		accuracy = a
+	}
+	latS := strconv.FormatFloat(lat, 'f', -1, 64)
+	lonS := strconv.FormatFloat(lon, 'f', -1, 64)
+
+	if emulator {
+		// geo fix takes longitude first
+		if _, err := t.helper.execAdb(ctx, device, 10*time.Second, "emu", "geo", "fix", lonS, latS); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Emulator location set to %s,%s", latS, lonS), nil
+	}
+
+	steps := [][]string{
+		{"shell", "appops", "set", "com.android.shell", "android:mock_location", "allow"},
+		{"shell", "cmd", "location", "providers", "add-test-provider", "gps"},
+		{"shell", "cmd", "location", "providers", "set-test-provider-enabled", "gps", "true"},
+		{"shell", "cmd", "location", "providers", "set-test-provider-location", "gps",
+			"--location", latS + "," + lonS, "--accuracy", strconv.FormatFloat(accuracy, 'f', -1, 64)},
+	}
+	for i, step := range steps {
+		output, err := t.helper.execAdb(ctx, device, 10*time.Second, step...)
+		// add-test-provider fails when the provider is already there
+		if err != nil && i != 1 {
+			return "", fmt.Errorf("%w (test location providers need Android 12 or later)", err)
+		}
+		if strings.Contains(output, "Unknown command") || strings.Contains(output, "SecurityException") {
+			return "", fmt.Errorf("device refused the mock location: %s", strings.TrimSpace(output))
+		}
+	}
+	return fmt.Sprintf("Mock location set to %s,%s (accuracy %gm). Use clear=true to remove it.", latS, lonS, accuracy), nil
+}
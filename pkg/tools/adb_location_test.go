@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const sampleDumpsysLocation = `Location Manager State:
+  gps provider:
+    last location=Location[gps 37.421998,-122.084000 hAcc=20.0 et=+1d2h3m alt=5.0 vel=0.0 {Bundle[{satellites=8}]}]
+  network provider:
+    last location=Location[network -6.175392,106.827153 hAcc=1200 et=+1d2h1m]
+  fused provider:
+    last location=Location[fused -6.200000,106.816666 hAcc=14 et=+1d2h4m alt=20.1]
+`
+
+func TestParseLocations(t *testing.T) {
+	locations := parseLocations(sampleDumpsysLocation)
+	if len(locations) != 3 {
+		t.Fatalf("expected 3 locations, got %+v", locations)
+	}
+	best := locations[0]
+	if best.Provider != "fused" || best.Latitude != -6.2 || best.Longitude != 106.816666 || best.Accuracy != 14 {
+		t.Fatalf("unexpected best location %+v", best)
+	}
+	if best.MapsURL != "https://maps.google.com/?q=-6.200000,106.816666" {
+		t.Errorf("unexpected maps URL %s", best.MapsURL)
+	}
+	if locations[1].Provider != "gps" || locations[2].Provider != "network" {
+		t.Errorf("providers not ordered: %+v", locations)
+	}
+	if parseLocations("no fixes here") != nil {
+		t.Error("expected no locations")
+	}
+}
+
+func TestAdbSetMockLocation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	adb := filepath.Join(dir, "adb")
+	os.WriteFile(adb, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\n"), 0755)
+	tool := NewAdbSetMockLocationTool(&AdbHelper{adbPath: adb, workspace: dir})
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"latitude": -8.65, "longitude": 115.2167, "device": "emulator-5554"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	calls, _ := os.ReadFile(log)
+	if strings.TrimSpace(string(calls)) != "-s emulator-5554 emu geo fix 115.2167 -8.65" {
+		t.Fatalf("unexpected emulator call %q", calls)
+	}
+
+	os.Remove(log)
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"latitude": -8.65, "longitude": 115.2167, "device": "R58M123"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	calls, _ = os.ReadFile(log)
+	if !strings.Contains(string(calls), "set-test-provider-location gps --location -8.65,115.2167 --accuracy 5") {
+		t.Fatalf("expected a test provider location, got:\n%s", calls)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"latitude": 95.0, "longitude": 0.0, "device": "R58M123"}); err == nil {
+		t.Fatal("expected an out of range error")
+	}
+}
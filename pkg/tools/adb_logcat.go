@@ -0,0 +1,382 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+)
+
+var logcatBuffers = []string{"main", "system", "crash", "events", "radio", "all"}
+
+// logcatArgs builds a logcat command line. filter is a logcat filter spec
+// such as "ActivityManager:I *:S".
+func logcatArgs(buffer, filter string, extra ...string) ([]string, error) {
+	args := []string{"logcat", "-v", "threadtime"}
+	if buffer != "" {
+		if !containsString(logcatBuffers, buffer) {
+			return nil, fmt.Errorf("unknown logcat buffer %q (use %s)", buffer, strings.Join(logcatBuffers, ", "))
+		}
+		args = append(args, "-b", buffer)
+	}
+	args = append(args, extra...)
+	return append(args, strings.Fields(filter)...), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scanLogcat calls fn with every line matching re until fn returns false or
+// the stream ends
+func scanLogcat(r io.Reader, re *regexp.Regexp, fn func(line string, match []string) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if !fn(line, match) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// ==================== ADB Logcat Tool ====================
+
+type AdbLogcatTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbLogcatTool(helper *AdbHelper) *AdbLogcatTool {
+	return &AdbLogcatTool{helper: helper}
+}
+
+func (t *AdbLogcatTool) Name() string {
+	return "adb_logcat"
+}
+
+func (t *AdbLogcatTool) Description() string {
+	return "Read the Android device log (logcat). Mode 'dump' (default) returns recent lines, optionally narrowed with a logcat filter spec and a regex. Mode 'watch' follows the log until lines match the regex pattern or the timeout passes, e.g. to wait for a crash ('FATAL EXCEPTION') or an incoming call."
+}
+
+func (t *AdbLogcatTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"dump", "watch"},
+				"description": "dump returns recent lines; watch waits for new lines matching pattern (default dump)",
+			},
+			"filter": map[string]interface{}{
+				"type":        "string",
+				"description": "Logcat filter spec, e.g. 'ActivityManager:I *:S' (tag:priority, priorities V D I W E F S)",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression lines must match. Required for watch.",
+			},
+			"buffer": map[string]interface{}{
+				"type":        "string",
+				"enum":        logcatBuffers,
+				"description": "Log buffer to read (default main and system)",
+			},
+			"lines": map[string]interface{}{
+				"type":        "integer",
+				"description": "dump: number of most recent lines to read before matching (default 200, max 5000)",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": "watch: stop after this many matching lines (default 1)",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "watch: seconds to wait for matches (default 60, max 600)",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbLogcatTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+	filter, _ := args["filter"].(string)
+	buffer, _ := args["buffer"].(string)
+	pattern, _ := args["pattern"].(string)
+
+	re := regexp.MustCompile("")
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return "", fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	if mode, _ := args["mode"].(string); mode == "watch" {
+		if pattern == "" {
+			return "", fmt.Errorf("pattern is required for watch mode")
+		}
+		return t.watch(ctx, args, device, buffer, filter, re)
+	}
+
+	lines := 200
+	if n, ok := args["lines"].(float64); ok && n > 0 {
+		lines = int(n)
+	}
+	if lines > 5000 {
+		lines = 5000
+	}
+	cmdArgs, err := logcatArgs(buffer, filter, "-t", fmt.Sprint(lines))
+	if err != nil {
+		return "", err
+	}
+	output, err := t.helper.execAdb(ctx, device, 30*time.Second, cmdArgs...)
+	if err != nil {
+		return "", err
+	}
+
+	var matched []string
+	scanLogcat(strings.NewReader(output), re, func(line string, _ []string) bool {
+		matched = append(matched, line)
+		return true
+	})
+	if len(matched) == 0 {
+		return "No matching log lines", nil
+	}
+
+	result := strings.Join(matched, "\n")
+	maxLen := 10000
+	if len(result) > maxLen {
+		// Keep the newest lines
+		result = fmt.Sprintf("... (truncated, %d earlier chars)\n", len(result)-maxLen) + result[len(result)-maxLen:]
+	}
+	return result, nil
+}
+
+func (t *AdbLogcatTool) watch(ctx context.Context, args map[string]interface{}, device, buffer, filter string, re *regexp.Regexp) (string, error) {
+	count := 1
+	if n, ok := args["count"].(float64); ok && n > 0 {
+		count = int(n)
+	}
+	timeout := 60
+	if n, ok := args["timeout"].(float64); ok && n > 0 {
+		timeout = int(n)
+	}
+	if timeout > 600 {
+		timeout = 600
+	}
+
+	// -T 1 starts at the newest line instead of replaying the whole buffer
+	cmdArgs, err := logcatArgs(buffer, filter, "-T", "1")
+	if err != nil {
+		return "", err
+	}
+	watchCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	cmd, stdout, err := t.helper.execAdbStreaming(watchCtx, device, cmdArgs...)
+	if err != nil {
+		return "", err
+	}
+	var matched []string
+	scanLogcat(stdout, re, func(line string, _ []string) bool {
+		matched = append(matched, line)
+		return len(matched) < count
+	})
+	cancel()
+	cmd.Wait()
+
+	if len(matched) == 0 {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return fmt.Sprintf("No log line matched %q within %ds", re.String(), timeout), nil
+	}
+	return fmt.Sprintf("Matched %d line(s) after %s:\n%s", len(matched), time.Since(start).Round(time.Second), strings.Join(matched, "\n")), nil
+}
+
+// ==================== Logcat Watchers ====================
+
+// LogcatEvent is a log line that matched a watcher's pattern
+type LogcatEvent struct {
+	Watcher config.LogcatWatcherConfig
+	Line    string
+	Match   []string // the match and its capture groups
+	Time    time.Time
+}
+
+// Vars returns the event as template variables: line, match, device and
+// group1, group2, ... for capture groups
+func (e LogcatEvent) Vars() map[string]string {
+	vars := map[string]string{"line": e.Line, "device": e.Watcher.Device}
+	for i, g := range e.Match {
+		if i == 0 {
+			vars["match"] = g
+		} else {
+			vars[fmt.Sprintf("group%d", i)] = g
+		}
+	}
+	return vars
+}
+
+// Prompt is the message for the watcher's agent
+func (e LogcatEvent) Prompt() string {
+	prompt := e.Watcher.Prompt
+	if prompt == "" {
+		prompt = "Logcat watcher '" + e.Watcher.Name + "' matched this line:\n\n{{line}}"
+	}
+	for k, v := range e.Vars() {
+		prompt = strings.ReplaceAll(prompt, "{{"+k+"}}", v)
+	}
+	return prompt
+}
+
+// LogcatHandler acts on a matched line
+type LogcatHandler func(ctx context.Context, event LogcatEvent)
+
+// ValidateLogcatWatcher reports configuration errors of a watcher
+func ValidateLogcatWatcher(w config.LogcatWatcherConfig) error {
+	if w.Name == "" {
+		return fmt.Errorf("logcat watcher needs a name")
+	}
+	if w.Pattern == "" {
+		return fmt.Errorf("logcat watcher %s: pattern is required", w.Name)
+	}
+	if _, err := regexp.Compile(w.Pattern); err != nil {
+		return fmt.Errorf("logcat watcher %s: invalid pattern: %w", w.Name, err)
+	}
+	if w.Buffer != "" && !containsString(logcatBuffers, w.Buffer) {
+		return fmt.Errorf("logcat watcher %s: unknown buffer %q", w.Name, w.Buffer)
+	}
+	if w.Workflow == "" && (w.Channel == "" || w.ChatID == "") {
+		return fmt.Errorf("logcat watcher %s: set a workflow, or a channel and chat_id for the agent's reply", w.Name)
+	}
+	return nil
+}
+
+// LogcatWatcher follows device logs in the background and calls the
+// handler for lines matching each watcher's pattern. Streams that end, for
+// example when a device disconnects, are restarted.
+type LogcatWatcher struct {
+	helper   *AdbHelper
+	watchers []config.LogcatWatcherConfig
+	handler  LogcatHandler
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewLogcatWatcher(helper *AdbHelper, watchers []config.LogcatWatcherConfig, handler LogcatHandler) *LogcatWatcher {
+	return &LogcatWatcher{helper: helper, watchers: watchers, handler: handler}
+}
+
+// Start starts the enabled, valid watchers and returns how many run
+func (lw *LogcatWatcher) Start() int {
+	ctx, cancel := context.WithCancel(context.Background())
+	lw.cancel = cancel
+
+	started := 0
+	for _, w := range lw.watchers {
+		if w.Disabled {
+			continue
+		}
+		if err := ValidateLogcatWatcher(w); err != nil {
+			logger.WarnCF("adb", "Skipping logcat watcher", map[string]interface{}{
+				"error": err.Error(),
+			})
+			continue
+		}
+		lw.wg.Add(1)
+		go func(w config.LogcatWatcherConfig) {
+			defer lw.wg.Done()
+			lw.follow(ctx, w)
+		}(w)
+		started++
+	}
+	return started
+}
+
+// Stop ends all watchers and waits for their streams to close
+func (lw *LogcatWatcher) Stop() {
+	if lw.cancel != nil {
+		lw.cancel()
+		lw.wg.Wait()
+	}
+}
+
+const (
+	logcatRetryMin        = 5 * time.Second
+	logcatRetryMax        = time.Minute
+	logcatDefaultCooldown = 30 * time.Second
+)
+
+func (lw *LogcatWatcher) follow(ctx context.Context, w config.LogcatWatcherConfig) {
+	re := regexp.MustCompile(w.Pattern)
+	cooldown := logcatDefaultCooldown
+	if w.CooldownS > 0 {
+		cooldown = time.Duration(w.CooldownS) * time.Second
+	}
+	args, _ := logcatArgs(w.Buffer, w.Filter, "-T", "1")
+
+	var last time.Time
+	retry := logcatRetryMin
+	for ctx.Err() == nil {
+		started := time.Now()
+		cmd, stdout, err := lw.helper.execAdbStreaming(ctx, w.Device, args...)
+		if err == nil {
+			scanLogcat(stdout, re, func(line string, match []string) bool {
+				if time.Since(last) < cooldown {
+					return true
+				}
+				last = time.Now()
+				logger.InfoCF("adb", "Logcat watcher matched", map[string]interface{}{
+					"watcher": w.Name,
+					"line":    line,
+				})
+				go lw.handler(ctx, LogcatEvent{Watcher: w, Line: line, Match: match, Time: last})
+				return true
+			})
+			cmd.Wait()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) > logcatRetryMax {
+			retry = logcatRetryMin
+		}
+		fields := map[string]interface{}{"watcher": w.Name, "retry_in": retry.String()}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		logger.WarnCF("adb", "Logcat stream ended", fields)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		if retry *= 2; retry > logcatRetryMax {
+			retry = logcatRetryMax
+		}
+	}
+}
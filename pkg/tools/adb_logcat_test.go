@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+const sampleLogcat = `10-15 09:00:01.100  1234  1234 I ActivityManager: Start proc com.example
+10-15 09:00:02.200  1234  1250 E AndroidRuntime: FATAL EXCEPTION: main
+10-15 09:00:02.201  1234  1250 E AndroidRuntime: Process: com.example, PID: 1234
+10-15 09:00:03.300   900   900 I Telecom: Incoming call from +628123456789
+`
+
+// fakeAdb returns a helper whose adb prints the sample log and records its
+// arguments
+func fakeAdb(t *testing.T) (*AdbHelper, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	logFile := filepath.Join(dir, "log")
+	os.WriteFile(logFile, []byte(sampleLogcat), 0644)
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat " + logFile + "\n"
+	adb := filepath.Join(dir, "adb")
+	if err := os.WriteFile(adb, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &AdbHelper{adbPath: adb, workspace: dir}, argsFile
+}
+
+func TestAdbLogcatDump(t *testing.T) {
+	helper, argsFile := fakeAdb(t)
+	tool := NewAdbLogcatTool(helper)
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{
+		"filter":  "AndroidRuntime:E *:S",
+		"pattern": "FATAL|Process:",
+		"buffer":  "crash",
+		"lines":   float64(50),
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if strings.Count(out, "\n") != 1 || !strings.Contains(out, "FATAL EXCEPTION") || strings.Contains(out, "Incoming call") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if got := strings.TrimSpace(string(args)); got != "logcat -v threadtime -b crash -t 50 AndroidRuntime:E *:S" {
+		t.Fatalf("unexpected adb arguments %q", got)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"buffer": "kernel"}); err == nil {
+		t.Fatal("expected an unknown buffer error")
+	}
+}
+
+func TestAdbLogcatWatch(t *testing.T) {
+	helper, _ := fakeAdb(t)
+	out, err := NewAdbLogcatTool(helper).Execute(context.Background(), map[string]interface{}{
+		"mode":    "watch",
+		"pattern": `Incoming call from (\+\d+)`,
+		"timeout": float64(5),
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.HasPrefix(out, "Matched 1 line(s)") || !strings.Contains(out, "+628123456789") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestLogcatWatcherPublishesMatches(t *testing.T) {
+	helper, _ := fakeAdb(t)
+	events := make(chan LogcatEvent, 4)
+	watcher := NewLogcatWatcher(helper, []config.LogcatWatcherConfig{
+		{Name: "calls", Pattern: `Incoming call from (\+\d+)`, Channel: "telegram", ChatID: "1", Prompt: "Call from {{group1}} on {{device}}", Device: "emulator-5554"},
+		{Name: "broken", Pattern: "(", Workflow: "x"},
+		{Name: "no-target", Pattern: "x"},
+	}, func(ctx context.Context, e LogcatEvent) { events <- e })
+
+	if n := watcher.Start(); n != 1 {
+		t.Fatalf("expected 1 valid watcher, got %d", n)
+	}
+	defer watcher.Stop()
+
+	select {
+	case e := <-events:
+		if e.Watcher.Name != "calls" || e.Prompt() != "Call from +628123456789 on emulator-5554" {
+			t.Fatalf("unexpected event %+v, prompt %q", e, e.Prompt())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+}
@@ -579,6 +579,10 @@ func (t *AdbRecordWorkflowTool) Name() string {
 	return "adb_record_workflow"
 }
 
+func (t *AdbRecordWorkflowTool) Sequential() bool {
+	return true
+}
+
 func (t *AdbRecordWorkflowTool) Description() string {
 	return "Record user interactions on an Android device (taps, swipes) and auto-generate a workflow JSON file. " +
 		"IMPORTANT: Only use this tool when the user EXPLICITLY asks to record or create a workflow from device actions. " +
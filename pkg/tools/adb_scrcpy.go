@@ -0,0 +1,331 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scrcpyMirror is a running scrcpy process
+type scrcpyMirror struct {
+	device  string
+	cmd     *exec.Cmd
+	record  string
+	started time.Time
+	ended   time.Time
+	output  *limitedBuffer
+	done    chan struct{}
+	err     error
+}
+
+// scrcpyMirrors holds the running mirrors by device serial ("" for the
+// default device). It is shared by all agents so one can stop what another
+// started.
+var scrcpyMirrors = struct {
+	sync.Mutex
+	m map[string]*scrcpyMirror
+}{m: make(map[string]*scrcpyMirror)}
+
+// limitedBuffer keeps the last bytes written to it
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p)
+	if over := b.buf.Len() - b.max; over > 0 {
+		b.buf.Next(over)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(b.buf.String())
+}
+
+var (
+	cropPattern    = regexp.MustCompile(`^\d+:\d+:\d+:\d+$`)
+	bitratePattern = regexp.MustCompile(`^\d+(\.\d+)?[KkMm]?$`)
+)
+
+// scrcpyArgs builds the scrcpy command line for a mirror
+func scrcpyArgs(args map[string]interface{}, device, record string) ([]string, error) {
+	display, _ := args["display"].(bool)
+	if !display && record == "" {
+		return nil, fmt.Errorf("nothing to do: set display=true, or leave record on")
+	}
+
+	var out []string
+	if device != "" {
+		out = append(out, "--serial="+device)
+	}
+	if !display {
+		out = append(out, "--no-display")
+	}
+	if record != "" {
+		out = append(out, "--record="+record)
+	}
+	if bitrate, _ := args["bitrate"].(string); bitrate != "" {
+		if !bitratePattern.MatchString(bitrate) {
+			return nil, fmt.Errorf("invalid bitrate %q (e.g. 8M or 2500K)", bitrate)
+		}
+		out = append(out, "--video-bit-rate="+strings.ToUpper(bitrate))
+	}
+	if n, ok := args["max_size"].(float64); ok && n > 0 {
+		out = append(out, fmt.Sprintf("--max-size=%d", int(n)))
+	}
+	if n, ok := args["max_fps"].(float64); ok && n > 0 {
+		out = append(out, fmt.Sprintf("--max-fps=%d", int(n)))
+	}
+	if crop, _ := args["crop"].(string); crop != "" {
+		if !cropPattern.MatchString(crop) {
+			return nil, fmt.Errorf("invalid crop %q (use width:height:x:y)", crop)
+		}
+		out = append(out, "--crop="+crop)
+	}
+	if n, ok := args["time_limit"].(float64); ok && n > 0 {
+		out = append(out, fmt.Sprintf("--time-limit=%d", int(n)))
+	}
+	if audio, ok := args["audio"].(bool); ok && !audio {
+		out = append(out, "--no-audio")
+	}
+	return out, nil
+}
+
+// ==================== ADB Scrcpy Start Tool ====================
+
+type AdbScrcpyStartTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbScrcpyStartTool(helper *AdbHelper) *AdbScrcpyStartTool {
+	return &AdbScrcpyStartTool{helper: helper}
+}
+
+func (t *AdbScrcpyStartTool) Name() string {
+	return "adb_scrcpy_start"
+}
+
+func (t *AdbScrcpyStartTool) Sequential() bool {
+	return true
+}
+
+func (t *AdbScrcpyStartTool) Description() string {
+	return "Start mirroring the Android screen with scrcpy in the background. By default it records without a window (video at the device's full frame rate, unlike screenshots) to an MP4 in the workspace; set display=true to also show a live window on this machine. Stop it with adb_scrcpy_stop, which finalizes the file. Requires scrcpy 2.0 or later."
+}
+
+func (t *AdbScrcpyStartTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"record": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Record the screen to a file (default true)",
+			},
+			"filename": map[string]interface{}{
+				"type":        "string",
+				"description": "Recording path, .mp4 or .mkv (default recordings/scrcpy-<device>-<time>.mp4 in the workspace)",
+			},
+			"display": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Show a mirror window on this machine (needs a desktop session, default false)",
+			},
+			"bitrate": map[string]interface{}{
+				"type":        "string",
+				"description": "Video bit rate, e.g. '8M' (scrcpy default) or '2M' for smaller files",
+			},
+			"max_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "Limit width and height to this many pixels, e.g. 1024",
+			},
+			"max_fps": map[string]interface{}{
+				"type":        "integer",
+				"description": "Limit the frame rate",
+			},
+			"crop": map[string]interface{}{
+				"type":        "string",
+				"description": "Mirror only part of the screen, as width:height:x:y in device pixels",
+			},
+			"time_limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Stop automatically after this many seconds",
+			},
+			"audio": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Capture device audio (Android 11+, default true)",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbScrcpyStartTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+	scrcpyPath, err := exec.LookPath("scrcpy")
+	if err != nil {
+		return "", fmt.Errorf("scrcpy not found in PATH; install it from https://github.com/Genymobile/scrcpy")
+	}
+
+	record := ""
+	if r, ok := args["record"].(bool); !ok || r {
+		filename, _ := args["filename"].(string)
+		if filename == "" {
+			name := device
+			if name == "" {
+				name = "device"
+			}
+			filename = filepath.Join("recordings", fmt.Sprintf("scrcpy-%s-%s.mp4",
+				strings.NewReplacer(":", "_", "/", "_").Replace(name), time.Now().Format("20060102-150405")))
+		}
+		ext := strings.ToLower(filepath.Ext(filename))
+		if ext != ".mp4" && ext != ".mkv" {
+			return "", fmt.Errorf("recording must be an .mp4 or .mkv file")
+		}
+		record = t.helper.resolvePath(filename)
+		if err := os.MkdirAll(filepath.Dir(record), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	cmdArgs, err := scrcpyArgs(args, device, record)
+	if err != nil {
+		return "", err
+	}
+
+	scrcpyMirrors.Lock()
+	defer scrcpyMirrors.Unlock()
+	if m, ok := scrcpyMirrors.m[device]; ok {
+		select {
+		case <-m.done:
+		default:
+			return "", fmt.Errorf("scrcpy is already running for this device since %s; stop it first", m.started.Format("15:04:05"))
+		}
+	}
+
+	// Not tied to ctx: the mirror outlives this tool call
+	cmd := exec.Command(scrcpyPath, cmdArgs...)
+	cmd.Env = append(os.Environ(), "ADB="+t.helper.adbPath)
+	output := &limitedBuffer{max: 4096}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start scrcpy: %w", err)
+	}
+
+	m := &scrcpyMirror{device: device, cmd: cmd, record: record, started: time.Now(), output: output, done: make(chan struct{})}
+	go func() {
+		m.err = cmd.Wait()
+		m.ended = time.Now()
+		close(m.done)
+	}()
+
+	// Most problems (no device, unsupported option) end scrcpy right away
+	select {
+	case <-m.done:
+		return "", fmt.Errorf("scrcpy exited: %v: %s", m.err, output.String())
+	case <-time.After(2 * time.Second):
+	case <-ctx.Done():
+		interruptProcess(cmd.Process)
+		return "", ctx.Err()
+	}
+	scrcpyMirrors.m[device] = m
+
+	result := fmt.Sprintf("scrcpy started (pid %d)", cmd.Process.Pid)
+	if record != "" {
+		result += fmt.Sprintf("\nRecording to: %s", record)
+	}
+	return result + "\nStop it with adb_scrcpy_stop to finalize the recording.", nil
+}
+
+// ==================== ADB Scrcpy Stop Tool ====================
+
+type AdbScrcpyStopTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbScrcpyStopTool(helper *AdbHelper) *AdbScrcpyStopTool {
+	return &AdbScrcpyStopTool{helper: helper}
+}
+
+func (t *AdbScrcpyStopTool) Name() string {
+	return "adb_scrcpy_stop"
+}
+
+func (t *AdbScrcpyStopTool) Sequential() bool {
+	return true
+}
+
+func (t *AdbScrcpyStopTool) Description() string {
+	return "Stop a scrcpy mirror started with adb_scrcpy_start and return the recording's path, duration and size. Without a device, stops the only running mirror."
+}
+
+func (t *AdbScrcpyStopTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbScrcpyStopTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+
+	scrcpyMirrors.Lock()
+	m, ok := scrcpyMirrors.m[device]
+	if !ok && device == "" && len(scrcpyMirrors.m) == 1 {
+		for _, only := range scrcpyMirrors.m {
+			m, ok = only, true
+		}
+	}
+	if ok {
+		delete(scrcpyMirrors.m, m.device)
+	}
+	scrcpyMirrors.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no scrcpy mirror is running for this device")
+	}
+
+	select {
+	case <-m.done:
+		// Ended on its own, e.g. time_limit or the device disconnected
+	default:
+		// An interrupt lets scrcpy write the end of the recording
+		interruptProcess(m.cmd.Process)
+		select {
+		case <-m.done:
+		case <-time.After(10 * time.Second):
+			m.cmd.Process.Kill()
+			<-m.done
+		}
+	}
+	duration := m.ended.Sub(m.started).Round(time.Second)
+
+	if m.record == "" {
+		return fmt.Sprintf("scrcpy stopped after %s", duration), nil
+	}
+	info, err := os.Stat(m.record)
+	if err != nil {
+		return "", fmt.Errorf("scrcpy stopped but no recording was written: %s", m.output.String())
+	}
+	return fmt.Sprintf("scrcpy stopped after %s\nRecording saved to: %s (%d bytes)", duration, m.record, info.Size()), nil
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScrcpyArgs(t *testing.T) {
+	got, err := scrcpyArgs(map[string]interface{}{
+		"bitrate":  "2m",
+		"max_size": float64(1024),
+		"crop":     "1080:1200:0:400",
+		"audio":    false,
+	}, "emulator-5554", "/tmp/out.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--serial=emulator-5554 --no-display --record=/tmp/out.mp4 --video-bit-rate=2M --max-size=1024 --crop=1080:1200:0:400 --no-audio"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got  %s\nwant %s", strings.Join(got, " "), want)
+	}
+
+	if _, err := scrcpyArgs(map[string]interface{}{}, "", ""); err == nil {
+		t.Error("expected an error without display or recording")
+	}
+	if _, err := scrcpyArgs(map[string]interface{}{"crop": "100x100"}, "", "/tmp/out.mp4"); err == nil {
+		t.Error("expected an invalid crop error")
+	}
+}
+
+func TestScrcpyStartStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	// The fake scrcpy writes the recording when interrupted, like scrcpy
+	dir := t.TempDir()
+	script := `#!/bin/sh
+for a in "$@"; do case "$a" in --record=*) out="${a#--record=}";; esac; done
+trap 'echo video > "$out"; exit 0' INT
+while true; do sleep 0.1; done
+`
+	if err := os.WriteFile(filepath.Join(dir, "scrcpy"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	helper := &AdbHelper{adbPath: "adb", workspace: dir}
+
+	out, err := NewAdbScrcpyStartTool(helper).Execute(context.Background(), map[string]interface{}{"filename": "clip.mp4"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !strings.Contains(out, filepath.Join(dir, "clip.mp4")) {
+		t.Errorf("unexpected start output %q", out)
+	}
+	if _, err := NewAdbScrcpyStartTool(helper).Execute(context.Background(), map[string]interface{}{}); err == nil {
+		t.Error("expected an error starting a second mirror for the same device")
+	}
+
+	out, err = NewAdbScrcpyStopTool(helper).Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if !strings.Contains(out, "Recording saved to: "+filepath.Join(dir, "clip.mp4")) {
+		t.Errorf("unexpected stop output %q", out)
+	}
+	if _, err := NewAdbScrcpyStopTool(helper).Execute(context.Background(), map[string]interface{}{}); err == nil {
+		t.Error("expected an error with no mirror running")
+	}
+}
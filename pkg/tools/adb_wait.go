@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// uiNode is an element of a uiautomator dump
+type uiNode struct {
+	Text        string   `xml:"text,attr"`
+	ResourceID  string   `xml:"resource-id,attr"`
+	ContentDesc string   `xml:"content-desc,attr"`
+	Class       string   `xml:"class,attr"`
+	Bounds      string   `xml:"bounds,attr"`
+	Nodes       []uiNode `xml:"node"`
+}
+
+var boundsPattern = regexp.MustCompile(`^\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]$`)
+
+// center returns the middle of the element, where a tap lands
+func (n uiNode) center() (int, int, bool) {
+	m := boundsPattern.FindStringSubmatch(n.Bounds)
+	if m == nil {
+		return 0, 0, false
+	}
+	var b [4]int
+	for i := range b {
+		fmt.Sscan(m[i+1], &b[i])
+	}
+	return (b[0] + b[2]) / 2, (b[1] + b[3]) / 2, true
+}
+
+func (n uiNode) describe() string {
+	label := n.Text
+	if label == "" {
+		label = n.ContentDesc
+	}
+	s := fmt.Sprintf("%s %q", n.Class, label)
+	if n.ResourceID != "" {
+		s += " id=" + n.ResourceID
+	}
+	if x, y, ok := n.center(); ok {
+		s += fmt.Sprintf(" bounds=%s center=(%d,%d)", n.Bounds, x, y)
+	}
+	return s
+}
+
+// findUINode returns the first element whose text or content description
+// contains text (ignoring case) and whose resource ID matches resourceID.
+// A resource ID without a package ("login_button") matches any package.
+func findUINode(dump, text, resourceID string) (*uiNode, error) {
+	var root struct {
+		Nodes []uiNode `xml:"node"`
+	}
+	if err := xml.Unmarshal([]byte(dump), &root); err != nil {
+		return nil, fmt.Errorf("invalid UI dump: %w", err)
+	}
+	text = strings.ToLower(text)
+
+	var walk func(nodes []uiNode) *uiNode
+	walk = func(nodes []uiNode) *uiNode {
+		for i := range nodes {
+			n := &nodes[i]
+			textOK := text == "" || strings.Contains(strings.ToLower(n.Text), text) || strings.Contains(strings.ToLower(n.ContentDesc), text)
+			idOK := resourceID == "" || n.ResourceID == resourceID || strings.HasSuffix(n.ResourceID, ":id/"+resourceID)
+			if textOK && idOK {
+				return n
+			}
+			if found := walk(n.Nodes); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return walk(root.Nodes), nil
+}
+
+// ==================== ADB Wait For Tool ====================
+
+type AdbWaitForTool struct {
+	helper *AdbHelper
+}
+
+func NewAdbWaitForTool(helper *AdbHelper) *AdbWaitForTool {
+	return &AdbWaitForTool{helper: helper}
+}
+
+func (t *AdbWaitForTool) Name() string {
+	return "adb_wait_for"
+}
+
+// Sequential keeps waits between the device actions they separate
+func (t *AdbWaitForTool) Sequential() bool {
+	return true
+}
+
+func (t *AdbWaitForTool) Description() string {
+	return "Wait until something is true on the Android device instead of sleeping a fixed time: an element with the given text or resource ID appears on screen (or disappears with gone=true), or a shell command's output matches a regex. Polls until the timeout and fails if the condition is not met. Returns the element's bounds and center for adb_tap."
+}
+
+func (t *AdbWaitForTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text or content description the element contains (case-insensitive)",
+			},
+			"resource_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Resource ID of the element, e.g. 'com.example:id/login' or just 'login'",
+			},
+			"gone": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Wait for the element to disappear instead (e.g. a loading spinner)",
+			},
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Shell command to poll instead of the screen, e.g. 'dumpsys power | grep mWakefulness'",
+			},
+			"match": map[string]interface{}{
+				"type":        "string",
+				"description": "Regex the command output must match. Without it, waits for the command to succeed.",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "number",
+				"description": "Seconds to wait before failing (default 30, max 300)",
+			},
+			"interval": map[string]interface{}{
+				"type":        "number",
+				"description": "Seconds between checks (default 1)",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+	}
+}
+
+func (t *AdbWaitForTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	device, _ := args["device"].(string)
+	text, _ := args["text"].(string)
+	resourceID, _ := args["resource_id"].(string)
+	gone, _ := args["gone"].(bool)
+	command, _ := args["command"].(string)
+	pattern, _ := args["match"].(string)
+
+	timeout := 30 * time.Second
+	if n, ok := args["timeout"].(float64); ok && n > 0 {
+		timeout = time.Duration(n * float64(time.Second))
+	}
+	if timeout > 5*time.Minute {
+		timeout = 5 * time.Minute
+	}
+	interval := time.Second
+	if n, ok := args["interval"].(float64); ok && n > 0 {
+		interval = time.Duration(n * float64(time.Second))
+	}
+
+	var check func() (string, bool)
+	var what string
+	switch {
+	case command != "":
+		var re *regexp.Regexp
+		if pattern != "" {
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				return "", fmt.Errorf("invalid match pattern: %w", err)
+			}
+			what = fmt.Sprintf("output of %q to match %q", command, pattern)
+		} else {
+			what = fmt.Sprintf("%q to succeed", command)
+		}
+		check = func() (string, bool) {
+			output, err := t.helper.execAdb(ctx, device, 15*time.Second, "shell", command)
+			if re == nil {
+				return strings.TrimSpace(output), err == nil
+			}
+			if err != nil {
+				return "", false
+			}
+			loc := re.FindStringIndex(output)
+			if loc == nil {
+				return "", false
+			}
+			return output[loc[0]:loc[1]], true
+		}
+	case text != "" || resourceID != "":
+		what = describeTarget(text, resourceID, gone)
+		check = func() (string, bool) {
+			dump, err := t.helper.dumpUI(ctx, device)
+			if err != nil {
+				// The screen can be briefly undumpable while it changes
+				return "", false
+			}
+			node, err := findUINode(dump, text, resourceID)
+			if err != nil {
+				return "", false
+			}
+			if gone {
+				return "", node == nil
+			}
+			if node == nil {
+				return "", false
+			}
+			return node.describe(), true
+		}
+	default:
+		return "", fmt.Errorf("give text, resource_id or command to wait for")
+	}
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	for {
+		if detail, ok := check(); ok {
+			result := fmt.Sprintf("Condition met after %.1fs: %s", time.Since(start).Seconds(), what)
+			if detail != "" {
+				result += "\n" + detail
+			}
+			return result, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return "", fmt.Errorf("timed out after %s waiting for %s", timeout, what)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func describeTarget(text, resourceID string, gone bool) string {
+	var parts []string
+	if text != "" {
+		parts = append(parts, fmt.Sprintf("text %q", text))
+	}
+	if resourceID != "" {
+		parts = append(parts, "id "+resourceID)
+	}
+	s := "element with " + strings.Join(parts, " and ")
+	if gone {
+		return s + " to disappear"
+	}
+	return s + " to appear"
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const sampleUIDump = `<?xml version='1.0' encoding='UTF-8' standalone='yes' ?><hierarchy rotation="0"><node index="0" text="" resource-id="" class="android.widget.FrameLayout" content-desc="" bounds="[0,0][1080,2400]"><node index="0" text="Welcome back" resource-id="com.example:id/title" class="android.widget.TextView" content-desc="" bounds="[40,200][1040,300]" /><node index="1" text="" resource-id="com.example:id/login" class="android.widget.Button" content-desc="Sign in" bounds="[100,1000][980,1120]" /></node></hierarchy>`
+
+func TestFindUINode(t *testing.T) {
+	tests := []struct {
+		text, id string
+		want     string
+	}{
+		{"sign IN", "", "com.example:id/login"},
+		{"", "title", "com.example:id/title"},
+		{"welcome", "com.example:id/title", "com.example:id/title"},
+		{"welcome", "login", ""},
+		{"Register", "", ""},
+	}
+	for _, tt := range tests {
+		node, err := findUINode(sampleUIDump, tt.text, tt.id)
+		if err != nil {
+			t.Fatalf("findUINode: %v", err)
+		}
+		got := ""
+		if node != nil {
+			got = node.ResourceID
+		}
+		if got != tt.want {
+			t.Errorf("findUINode(%q, %q) = %q, want %q", tt.text, tt.id, got, tt.want)
+		}
+	}
+
+	node, _ := findUINode(sampleUIDump, "sign in", "")
+	if x, y, ok := node.center(); !ok || x != 540 || y != 1060 {
+		t.Errorf("center = %d,%d", x, y)
+	}
+}
+
+func TestAdbWaitForCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	dir := t.TempDir()
+	// The device wakes up on the third check
+	script := "#!/bin/sh\nn=$(cat " + dir + "/n 2>/dev/null || echo 0)\nn=$((n+1))\necho $n > " + dir + "/n\n" +
+		"if [ $n -ge 3 ]; then echo 'mWakefulness=Awake'; else echo 'mWakefulness=Asleep'; fi\n"
+	adb := filepath.Join(dir, "adb")
+	os.WriteFile(adb, []byte(script), 0755)
+	tool := NewAdbWaitForTool(&AdbHelper{adbPath: adb, workspace: dir})
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{
+		"command":  "dumpsys power | grep mWakefulness",
+		"match":    "Awake",
+		"interval": 0.01,
+	})
+	if err != nil || !strings.HasPrefix(out, "Condition met") || !strings.HasSuffix(out, "\nAwake") {
+		t.Fatalf("got %q, %v", out, err)
+	}
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{
+		"command":  "dumpsys power",
+		"match":    "Dozing",
+		"timeout":  0.05,
+		"interval": 0.01,
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{}); err == nil {
+		t.Fatal("expected an error without a condition")
+	}
+}
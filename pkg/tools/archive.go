@@ -0,0 +1,599 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Limits of a single extraction, against archive bombs
+	maxExtractBytes = 4 << 30
+	maxExtractFiles = 20000
+)
+
+// archiveFormat returns "zip", "tar" or "tar.gz" from a file name
+func archiveFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", nil
+	}
+	return "", fmt.Errorf("unsupported archive %s (use .zip, .tar, .tar.gz or .tgz)", filepath.Base(name))
+}
+
+// workspacePath resolves p against the session's working directory, or the
+// workspace, and rejects paths outside the workspace
+func workspacePath(ctx context.Context, workspace, p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(WorkDir(ctx, workspace), p)
+	}
+	ws, err := filepath.Abs(workspace)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	if !within(ws, abs) {
+		return "", fmt.Errorf("%s is outside the workspace", p)
+	}
+	return abs, nil
+}
+
+// within reports whether p is dir or inside it
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+type ArchiveCreateTool struct {
+	workspace string
+}
+
+func NewArchiveCreateTool(workspace string) *ArchiveCreateTool {
+	return &ArchiveCreateTool{workspace: workspace}
+}
+
+func (t *ArchiveCreateTool) Name() string {
+	return "archive_create"
+}
+
+func (t *ArchiveCreateTool) Sequential() bool {
+	return true
+}
+
+func (t *ArchiveCreateTool) Description() string {
+	return "Bundle files and directories into a .zip, .tar or .tar.gz archive in the workspace, e.g. to send screenshots or logs as one file."
+}
+
+func (t *ArchiveCreateTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Archive to create, inside the workspace; the extension picks the format (.zip, .tar, .tar.gz, .tgz)",
+			},
+			"sources": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Files, directories or glob patterns (e.g. screenshots/*.png) to add, relative to the workspace",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace an existing archive (default false)",
+			},
+		},
+		"required": []string{"path", "sources"},
+	}
+}
+
+func (t *ArchiveCreateTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	out, err := workspacePath(ctx, t.workspace, stringArg(args, "path"))
+	if err != nil || stringArg(args, "path") == "" {
+		return "", fmt.Errorf("path must be an archive file inside the workspace")
+	}
+	format, err := archiveFormat(out)
+	if err != nil {
+		return "", err
+	}
+	if overwrite, _ := args["overwrite"].(bool); !overwrite {
+		if _, err := os.Stat(out); err == nil {
+			return "", fmt.Errorf("%s already exists; set overwrite to replace it", stringArg(args, "path"))
+		}
+	}
+
+	rawSources, _ := args["sources"].([]interface{})
+	var sources []string
+	for _, s := range rawSources {
+		pattern, _ := s.(string)
+		if pattern == "" {
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(WorkDir(ctx, t.workspace), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern %q: %w", s, err)
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("nothing matches %q", s)
+		}
+		sources = append(sources, matches...)
+	}
+	if len(sources) == 0 {
+		return "", fmt.Errorf("sources is required")
+	}
+
+	entries, err := t.collect(ctx, sources, out)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	if format == "zip" {
+		err = writeZip(ctx, f, entries)
+	} else {
+		err = writeTar(ctx, f, entries, format == "tar.gz")
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, out)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	info, _ := os.Stat(out)
+	result := map[string]interface{}{
+		"path":   out,
+		"format": format,
+		"files":  countFiles(entries),
+		"size":   info.Size(),
+	}
+	b, _ := json.MarshalIndent(result, "", "  ")
+	return string(b), nil
+}
+
+// archiveEntry is a file or directory to archive under name
+type archiveEntry struct {
+	path string
+	name string // slash separated
+	info fs.FileInfo
+}
+
+// collect walks the sources. Entries are named relative to the workspace, or
+// by their base name for sources outside it. Symlinks are not followed.
+func (t *ArchiveCreateTool) collect(ctx context.Context, sources []string, out string) ([]archiveEntry, error) {
+	ws, _ := filepath.Abs(t.workspace)
+	seen := map[string]bool{}
+	var entries []archiveEntry
+	for _, src := range sources {
+		src, _ = filepath.Abs(src)
+		root := filepath.Dir(src)
+		if within(ws, src) {
+			root = ws
+		}
+		err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if p == out || p == out+".tmp" || seen[p] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				return nil // symlinks, devices, sockets
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil || rel == "." {
+				return nil
+			}
+			seen[p] = true
+			entries = append(entries, archiveEntry{path: p, name: filepath.ToSlash(rel), info: info})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if countFiles(entries) == 0 {
+		return nil, fmt.Errorf("no files to archive")
+	}
+	return entries, nil
+}
+
+func countFiles(entries []archiveEntry) int {
+	n := 0
+	for _, e := range entries {
+		if !e.info.IsDir() {
+			n++
+		}
+	}
+	return n
+}
+
+func writeZip(ctx context.Context, w io.Writer, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		header, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return err
+		}
+		header.Name = e.name
+		if e.info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if !e.info.IsDir() {
+			if err := copyFile(fw, e.path); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func writeTar(ctx context.Context, w io.Writer, entries []archiveEntry, compress bool) error {
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		header, err := tar.FileInfoHeader(e.info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = e.name
+		if e.info.IsDir() {
+			header.Name += "/"
+		}
+		// Don't leak local account names
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !e.info.IsDir() {
+			if err := copyFile(tw, e.path); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+type ArchiveExtractTool struct {
+	workspace string
+}
+
+func NewArchiveExtractTool(workspace string) *ArchiveExtractTool {
+	return &ArchiveExtractTool{workspace: workspace}
+}
+
+func (t *ArchiveExtractTool) Name() string {
+	return "archive_extract"
+}
+
+func (t *ArchiveExtractTool) Sequential() bool {
+	return true
+}
+
+func (t *ArchiveExtractTool) Description() string {
+	return "Unpack a .zip, .tar or .tar.gz archive into a directory in the workspace. Entries that would land outside the directory, links and devices are skipped."
+}
+
+func (t *ArchiveExtractTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Archive to unpack",
+			},
+			"dest": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to unpack into, inside the workspace. Default: the archive's name without extension, next to it",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace files that already exist (default false: they are skipped)",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ArchiveExtractTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	src := stringArg(args, "path")
+	if src == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(WorkDir(ctx, t.workspace), src)
+	}
+	format, err := archiveFormat(src)
+	if err != nil {
+		return "", err
+	}
+
+	dest := stringArg(args, "dest")
+	if dest == "" {
+		base := filepath.Base(src)
+		for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+			if strings.HasSuffix(strings.ToLower(base), ext) {
+				base = base[:len(base)-len(ext)]
+				break
+			}
+		}
+		dest = filepath.Join(filepath.Dir(src), base)
+	}
+	dest, err = workspacePath(ctx, t.workspace, dest)
+	if err != nil {
+		return "", fmt.Errorf("dest must be inside the workspace")
+	}
+	overwrite, _ := args["overwrite"].(bool)
+
+	x := &extractor{ctx: ctx, dest: dest, overwrite: overwrite}
+	if format == "zip" {
+		err = x.zip(src)
+	} else {
+		err = x.tar(src, format == "tar.gz")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	result := map[string]interface{}{
+		"dest":      dest,
+		"extracted": x.files,
+		"bytes":     x.bytes,
+	}
+	if len(x.skipped) > 0 {
+		result["skipped"] = x.skipped
+	}
+	b, _ := json.MarshalIndent(result, "", "  ")
+	return string(b), nil
+}
+
+// extractor writes archive entries below dest
+type extractor struct {
+	ctx       context.Context
+	dest      string
+	overwrite bool
+	files     int
+	bytes     int64
+	skipped   []string
+}
+
+func (x *extractor) skip(name, reason string) {
+	if len(x.skipped) < 50 {
+		x.skipped = append(x.skipped, name+": "+reason)
+	}
+}
+
+// target returns where an entry goes, or "" when its name escapes dest
+func (x *extractor) target(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || path.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return ""
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return ""
+	}
+	target := filepath.Join(x.dest, filepath.FromSlash(clean))
+	if !within(x.dest, target) || target == x.dest {
+		return ""
+	}
+	return target
+}
+
+func (x *extractor) write(name string, mode fs.FileMode, r io.Reader) error {
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+	target := x.target(name)
+	if target == "" {
+		x.skip(name, "outside the destination")
+		return nil
+	}
+	if x.files >= maxExtractFiles {
+		return fmt.Errorf("archive has more than %d files", maxExtractFiles)
+	}
+	if _, err := os.Lstat(target); err == nil && !x.overwrite {
+		x.skip(name, "exists")
+		return nil
+	}
+	if err := x.mkdir(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	perm := mode.Perm() & 0755
+	if perm == 0 {
+		perm = 0644
+	}
+	os.Remove(target) // never write through an existing symlink
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_EXCL, perm|0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	n, err := io.Copy(f, io.LimitReader(r, maxExtractBytes-x.bytes+1))
+	closeErr := f.Close()
+	x.bytes += n
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if x.bytes > maxExtractBytes {
+		os.Remove(target)
+		return fmt.Errorf("archive expands to more than %s", formatBytes(maxExtractBytes))
+	}
+	x.files++
+	return nil
+}
+
+// mkdir creates dir below dest, refusing to go through symlinks
+func (x *extractor) mkdir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.EvalSymlinks(x.dest)
+	if err != nil {
+		return err
+	}
+	if !within(root, resolved) {
+		return fmt.Errorf("%s leads outside the destination", dir)
+	}
+	return nil
+}
+
+func (x *extractor) zip(src string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	if err := x.mkdir(x.dest); err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if target := x.target(f.Name); target != "" {
+				if err := x.mkdir(target); err != nil {
+					return err
+				}
+			}
+			continue
+		case !mode.IsRegular():
+			x.skip(f.Name, "not a regular file")
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		err = x.write(f.Name, mode, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) tar(src string, compressed bool) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("gzip error: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if err := x.mkdir(x.dest); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("tar error: %w", err)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if target := x.target(header.Name); target != "" {
+				if err := x.mkdir(target); err != nil {
+					return err
+				}
+			}
+		case tar.TypeReg:
+			if err := x.write(header.Name, fs.FileMode(header.Mode), tr); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+		default:
+			x.skip(header.Name, "not a regular file")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "screenshots"), 0755)
+	os.WriteFile(filepath.Join(workspace, "screenshots", "a.png"), []byte("png a"), 0644)
+	os.WriteFile(filepath.Join(workspace, "screenshots", "b.png"), []byte("png b"), 0644)
+	os.WriteFile(filepath.Join(workspace, "app.log"), []byte("log"), 0644)
+
+	create := NewArchiveCreateTool(workspace)
+	extract := NewArchiveExtractTool(workspace)
+	ctx := context.Background()
+
+	for _, name := range []string{"bundle.zip", "bundle.tar.gz", "bundle.tar"} {
+		args := map[string]interface{}{"path": "out/" + name, "sources": []interface{}{"screenshots/*.png", "app.log"}}
+		if _, err := create.Execute(ctx, args); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := create.Execute(ctx, args); err == nil {
+			t.Errorf("%s: existing archive replaced without overwrite", name)
+		}
+
+		if _, err := extract.Execute(ctx, map[string]interface{}{"path": "out/" + name, "dest": "unpacked/" + name}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		dir := filepath.Join(workspace, "unpacked", name)
+		assertFile(t, filepath.Join(dir, "screenshots", "b.png"), "png b")
+		assertFile(t, filepath.Join(dir, "app.log"), "log")
+	}
+
+	if _, err := create.Execute(ctx, map[string]interface{}{"path": "../x.zip", "sources": []interface{}{"app.log"}}); err == nil {
+		t.Error("archive outside the workspace should be rejected")
+	}
+	if _, err := extract.Execute(ctx, map[string]interface{}{"path": "out/bundle.zip", "dest": "/tmp"}); err == nil {
+		t.Error("extracting outside the workspace should be rejected")
+	}
+}
+
+func TestArchiveExtractTraversal(t *testing.T) {
+	workspace := t.TempDir()
+
+	zipPath := filepath.Join(workspace, "evil.zip")
+	f, _ := os.Create(zipPath)
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"../escape.txt", "/abs.txt", "ok/../../escape2.txt", "fine/ok.txt"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte("x"))
+	}
+	zw.Close()
+	f.Close()
+
+	tarPath := filepath.Join(workspace, "evil.tar")
+	f, _ = os.Create(tarPath)
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"})
+	tw.WriteHeader(&tar.Header{Name: "..\\win.txt", Typeflag: tar.TypeReg, Size: 1, Mode: 0644})
+	tw.Write([]byte("x"))
+	tw.WriteHeader(&tar.Header{Name: "fine.txt", Typeflag: tar.TypeReg, Size: 1, Mode: 0644})
+	tw.Write([]byte("x"))
+	tw.Close()
+	f.Close()
+
+	tool := NewArchiveExtractTool(workspace)
+	for _, archive := range []string{"evil.zip", "evil.tar"} {
+		out, err := tool.Execute(context.Background(), map[string]interface{}{"path": archive})
+		if err != nil {
+			t.Fatalf("%s: %v", archive, err)
+		}
+		if !strings.Contains(out, `"extracted": 1`) {
+			t.Errorf("%s: %s", archive, out)
+		}
+	}
+
+	filepath.Walk(filepath.Dir(workspace), func(p string, info os.FileInfo, err error) error {
+		if info != nil && (strings.Contains(info.Name(), "escape") || info.Name() == "win.txt") {
+			t.Errorf("entry escaped: %s", p)
+		}
+		return nil
+	})
+	if _, err := os.Lstat(filepath.Join(workspace, "evil", "link")); !os.IsNotExist(err) {
+		t.Error("symlink was extracted")
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "evil", "fine", "ok.txt")); err != nil {
+		t.Error(err)
+	}
+}
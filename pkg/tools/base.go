@@ -9,6 +9,13 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]interface{}) (string, error)
 }
 
+// SequentialTool is implemented by tools whose calls must not overlap with
+// other tool calls, e.g. tools that drive a device or rewrite files. A batch
+// of tool calls that includes one runs one call at a time.
+type SequentialTool interface {
+	Sequential() bool
+}
+
 func ToolToSchema(tool Tool) map[string]interface{} {
 	return map[string]interface{}{
 		"type": "function",
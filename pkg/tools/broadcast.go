@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pepebot-space/pepebot/pkg/broadcast"
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+type BroadcastSendTool struct {
+	service *broadcast.Service
+}
+
+func NewBroadcastSendTool(service *broadcast.Service) *BroadcastSendTool {
+	return &BroadcastSendTool{service: service}
+}
+
+func (t *BroadcastSendTool) Name() string {
+	return "broadcast_send"
+}
+
+func (t *BroadcastSendTool) Sequential() bool {
+	return true
+}
+
+func (t *BroadcastSendTool) Description() string {
+	return "Send a templated message to many recipients across channels (telegram, discord, whatsapp, ...). Use {{name}}, {{date}}, {{time}} or custom {{vars}} in the message. Recipients come from a configured list and/or an inline list. Returns per-recipient delivery status."
+}
+
+func (t *BroadcastSendTool) Parameters() map[string]interface{} {
+	lists := []string{}
+	for name := range t.service.Lists() {
+		lists = append(lists, name)
+	}
+	templates := []string{}
+	for name := range t.service.Templates() {
+		templates = append(templates, name)
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Message template, e.g. 'Hi {{name}}, the meeting is at {{time}}'",
+			},
+			"template": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Name of a configured template to use instead of message. Available: %v", templates),
+			},
+			"list": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Name of a configured recipient list. Available: %v", lists),
+			},
+			"recipients": map[string]interface{}{
+				"type":        "array",
+				"description": "Inline recipients (in addition to list)",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"channel": map[string]interface{}{"type": "string"},
+						"chat_id": map[string]interface{}{"type": "string"},
+						"name":    map[string]interface{}{"type": "string"},
+						"vars":    map[string]interface{}{"type": "object"},
+					},
+					"required": []string{"channel", "chat_id"},
+				},
+			},
+			"vars": map[string]interface{}{
+				"type":        "object",
+				"description": "Template variables applied to every recipient",
+			},
+			"throttle_ms": map[string]interface{}{
+				"type":        "number",
+				"description": "Delay between sends in milliseconds (optional, defaults to broadcast.throttle_ms)",
+			},
+		},
+	}
+}
+
+func (t *BroadcastSendTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	req := broadcast.Request{}
+	req.Message, _ = args["message"].(string)
+	req.Template, _ = args["template"].(string)
+	req.List, _ = args["list"].(string)
+	if v, ok := args["throttle_ms"].(float64); ok {
+		req.ThrottleMS = int(v)
+	}
+
+	if vars, ok := args["vars"].(map[string]interface{}); ok {
+		req.Vars = stringMap(vars)
+	}
+
+	if items, ok := args["recipients"].([]interface{}); ok {
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			rcpt := config.BroadcastRecipient{}
+			rcpt.Channel, _ = m["channel"].(string)
+			rcpt.ChatID, _ = m["chat_id"].(string)
+			rcpt.Name, _ = m["name"].(string)
+			if vars, ok := m["vars"].(map[string]interface{}); ok {
+				rcpt.Vars = stringMap(vars)
+			}
+			req.Recipients = append(req.Recipients, rcpt)
+		}
+	}
+
+	result, err := t.service.Send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	out, _ := json.MarshalIndent(result, "", "  ")
+	return string(out), nil
+}
+
+func stringMap(m map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = fmt.Sprintf("%v", v)
+	}
+	return out
+}
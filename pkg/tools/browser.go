@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Headless Chrome renders pages that build their content with JavaScript.
+// It is driven through its command line (--dump-dom), so any Chromium based
+// browser works and no extra dependency is needed.
+
+const renderTimeout = 30 * time.Second
+
+// browserCandidates are the executables tried when no browser is configured
+func browserCandidates() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+			"/Applications/Brave Browser.app/Contents/MacOS/Brave Browser",
+		}
+	case "windows":
+		var paths []string
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "LocalAppData"} {
+			if dir := os.Getenv(env); dir != "" {
+				paths = append(paths,
+					filepath.Join(dir, "Google", "Chrome", "Application", "chrome.exe"),
+					filepath.Join(dir, "Microsoft", "Edge", "Application", "msedge.exe"),
+				)
+			}
+		}
+		return paths
+	}
+	return []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "microsoft-edge", "brave-browser"}
+}
+
+// FindBrowser returns the browser used for rendering: configured when set,
+// else the first installed Chromium based browser, or "" when there is none
+func FindBrowser(configured string) string {
+	if configured != "" {
+		if path, err := exec.LookPath(configured); err == nil {
+			return path
+		}
+		return ""
+	}
+	for _, candidate := range browserCandidates() {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// renderPage loads url in a headless browser and returns the DOM after its
+// scripts have run
+func renderPage(ctx context.Context, browser, url string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, renderTimeout)
+	defer cancel()
+
+	profile, err := os.MkdirTemp("", "pepebot-browser-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create browser profile: %w", err)
+	}
+	defer os.RemoveAll(profile)
+
+	cmd := exec.CommandContext(ctx, browser,
+		"--headless=new",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--mute-audio",
+		"--hide-scrollbars",
+		"--user-data-dir="+profile,
+		"--user-agent="+userAgent,
+		"--virtual-time-budget=10000",
+		"--dump-dom",
+		url,
+	)
+	if os.Geteuid() == 0 {
+		// Chrome refuses to start as root without it (e.g. in Docker)
+		cmd.Args = append(cmd.Args[:1], append([]string{"--no-sandbox"}, cmd.Args[1:]...)...)
+	}
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 2 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("rendering timed out after %v", renderTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return "", fmt.Errorf("browser failed: %v: %s", err, msg)
+	}
+	if stdout.Len() == 0 {
+		return "", fmt.Errorf("browser returned an empty page")
+	}
+	return stdout.String(), nil
+}
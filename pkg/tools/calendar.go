@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/calendar"
+)
+
+// NewCalendarListEventsTool creates the calendar_list_events tool.
+func NewCalendarListEventsTool(client *calendar.Client) *CalendarListEventsTool {
+	return &CalendarListEventsTool{client: client}
+}
+
+// NewCalendarCreateEventTool creates the calendar_create_event tool.
+func NewCalendarCreateEventTool(client *calendar.Client) *CalendarCreateEventTool {
+	return &CalendarCreateEventTool{client: client}
+}
+
+// parseCalendarTime accepts RFC3339, "YYYY-MM-DD HH:MM" (local time) or a
+// bare date, which is reported as all-day
+func parseCalendarTime(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q (use YYYY-MM-DD HH:MM, YYYY-MM-DD or RFC3339)", value)
+}
+
+// FormatEvents renders events as a readable agenda
+func FormatEvents(events []calendar.Event) string {
+	var b strings.Builder
+	lastDay := ""
+	for _, e := range events {
+		day := e.Start.Format("Mon 2006-01-02")
+		if day != lastDay {
+			fmt.Fprintf(&b, "\n%s\n", day)
+			lastDay = day
+		}
+		when := "all day"
+		if !e.AllDay {
+			when = e.Start.Format("15:04") + "-" + e.End.Format("15:04")
+		}
+		fmt.Fprintf(&b, "- %s %s", when, e.Summary)
+		if e.Location != "" {
+			fmt.Fprintf(&b, " @ %s", e.Location)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ==================== calendar_list_events ====================
+
+type CalendarListEventsTool struct {
+	client *calendar.Client
+}
+
+func (t *CalendarListEventsTool) Name() string { return "calendar_list_events" }
+
+func (t *CalendarListEventsTool) Description() string {
+	return "List events from the user's calendar. Defaults to today; use 'days' for the coming days or 'from'/'to' for an explicit range."
+}
+
+func (t *CalendarListEventsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"from": map[string]interface{}{
+				"type":        "string",
+				"description": "Range start (YYYY-MM-DD or YYYY-MM-DD HH:MM, default: start of today)",
+			},
+			"to": map[string]interface{}{
+				"type":        "string",
+				"description": "Range end (exclusive)",
+			},
+			"days": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of days from 'from' when 'to' is omitted (default: 1)",
+			},
+		},
+	}
+}
+
+func (t *CalendarListEventsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if s, _ := args["from"].(string); s != "" {
+		t, _, err := parseCalendarTime(s)
+		if err != nil {
+			return "", err
+		}
+		from = t
+	}
+
+	days := 1
+	if d, ok := args["days"].(float64); ok && d > 0 {
+		days = int(d)
+	}
+	to := from.AddDate(0, 0, days)
+	if s, _ := args["to"].(string); s != "" {
+		t, _, err := parseCalendarTime(s)
+		if err != nil {
+			return "", err
+		}
+		to = t
+	}
+	if !to.After(from) {
+		return "", fmt.Errorf("'to' must be after 'from'")
+	}
+
+	events, err := t.client.ListEvents(ctx, from, to)
+	if err != nil {
+		return "", err
+	}
+	if len(events) == 0 {
+		return fmt.Sprintf("No events between %s and %s", from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04")), nil
+	}
+	return FormatEvents(events), nil
+}
+
+// ==================== calendar_create_event ====================
+
+type CalendarCreateEventTool struct {
+	client *calendar.Client
+}
+
+func (t *CalendarCreateEventTool) Name() string { return "calendar_create_event" }
+
+func (t *CalendarCreateEventTool) Description() string {
+	return "Create an event in the user's calendar, optionally with a reminder alarm. Times without a timezone are local time."
+}
+
+func (t *CalendarCreateEventTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Event title",
+			},
+			"start": map[string]interface{}{
+				"type":        "string",
+				"description": "Start (YYYY-MM-DD HH:MM, or YYYY-MM-DD for an all-day event)",
+			},
+			"end": map[string]interface{}{
+				"type":        "string",
+				"description": "End (optional; defaults to start + duration_minutes)",
+			},
+			"duration_minutes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Duration when 'end' is omitted (default: 60)",
+			},
+			"location": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional location",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional notes",
+			},
+			"reminder_minutes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Add an alarm this many minutes before the start",
+			},
+		},
+		"required": []string{"title", "start"},
+	}
+}
+
+func (t *CalendarCreateEventTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	title, _ := args["title"].(string)
+	if strings.TrimSpace(title) == "" {
+		return "", fmt.Errorf("title is required")
+	}
+	startStr, _ := args["start"].(string)
+	start, allDay, err := parseCalendarTime(startStr)
+	if err != nil {
+		return "", err
+	}
+
+	event := calendar.Event{Summary: title, Start: start, AllDay: allDay}
+	event.Location, _ = args["location"].(string)
+	event.Description, _ = args["description"].(string)
+	if m, ok := args["reminder_minutes"].(float64); ok && m > 0 {
+		event.ReminderMinutes = int(m)
+	}
+
+	switch endStr, _ := args["end"].(string); {
+	case endStr != "":
+		end, _, err := parseCalendarTime(endStr)
+		if err != nil {
+			return "", err
+		}
+		if allDay {
+			// All-day end dates are exclusive; treat the given date as the last day
+			end = end.AddDate(0, 0, 1)
+		}
+		event.End = end
+	case allDay:
+		event.End = start.AddDate(0, 0, 1)
+	default:
+		minutes := 60
+		if m, ok := args["duration_minutes"].(float64); ok && m > 0 {
+			minutes = int(m)
+		}
+		event.End = start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	created, err := t.client.CreateEvent(ctx, event)
+	if err != nil {
+		return "", err
+	}
+	return "Created event:\n" + FormatEvents([]calendar.Event{created}), nil
+}
@@ -2,12 +2,23 @@ package tools
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
 type contextKey string
 
-const sessionKeyContextKey contextKey = "pepebot_session_key"
+const (
+	sessionKeyContextKey contextKey = "pepebot_session_key"
+	progressContextKey   contextKey = "pepebot_progress"
+	workDirContextKey    contextKey = "pepebot_work_dir"
+	callTraceContextKey  contextKey = "pepebot_call_trace"
+	readOnlyContextKey   contextKey = "pepebot_read_only"
+)
+
+// ProgressFunc receives status updates from long running tools
+type ProgressFunc func(tool, status string)
 
 // WithSessionKey stores a parent session key for tools executed in this context.
 func WithSessionKey(ctx context.Context, sessionKey string) context.Context {
@@ -25,3 +36,110 @@ func SessionKeyFromContext(ctx context.Context) string {
 	v, _ := ctx.Value(sessionKeyContextKey).(string)
 	return strings.TrimSpace(v)
 }
+
+// WithProgress makes tools executed in this context report their progress
+// to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressContextKey, fn)
+}
+
+// ReportProgress sends a status update of a long running tool, if the
+// caller asked for them.
+func ReportProgress(ctx context.Context, tool, status string) {
+	if ctx == nil {
+		return
+	}
+	if fn, ok := ctx.Value(progressContextKey).(ProgressFunc); ok {
+		fn(tool, status)
+	}
+}
+
+// WithWorkDir makes file and exec tools executed in this context resolve
+// relative paths against dir instead of the workspace root.
+func WithWorkDir(ctx context.Context, dir string) context.Context {
+	if dir == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, workDirContextKey, dir)
+}
+
+// WorkDir returns the working directory for tools executed in this
+// context, or fallback when there is none.
+func WorkDir(ctx context.Context, fallback string) string {
+	if ctx == nil {
+		return fallback
+	}
+	if dir, ok := ctx.Value(workDirContextKey).(string); ok && dir != "" {
+		return dir
+	}
+	return fallback
+}
+
+// WithReadOnly limits tools executed in this context to the read-only ones,
+// as in maintenance mode.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyContextKey, true)
+}
+
+// ReadOnlyContext reports whether tools executed in ctx are limited to the
+// read-only ones.
+func ReadOnlyContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	readOnly, _ := ctx.Value(readOnlyContextKey).(bool)
+	return readOnly
+}
+
+// SessionWorkDir is the private working directory of a session:
+// workspace/sessions/<key>, with the key made safe for file names.
+func SessionWorkDir(workspace, sessionKey string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(sessionKey))
+	if strings.Trim(safe, ".") == "" {
+		safe = "default"
+	}
+	return filepath.Join(workspace, "sessions", safe)
+}
+
+// CallTrace records the tools run through a registry, in order
+type CallTrace struct {
+	mu    sync.Mutex
+	calls []TracedCall
+}
+
+// TracedCall is one recorded tool call
+type TracedCall struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// WithCallTrace makes registries record the tools executed in this context,
+// including the tool steps of workflows run from it.
+func WithCallTrace(ctx context.Context) (context.Context, *CallTrace) {
+	trace := &CallTrace{}
+	return context.WithValue(ctx, callTraceContextKey, trace), trace
+}
+
+// Calls returns the recorded calls
+func (t *CallTrace) Calls() []TracedCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TracedCall(nil), t.calls...)
+}
+
+func traceCall(ctx context.Context, name string, args map[string]interface{}) {
+	if ctx == nil {
+		return
+	}
+	if t, ok := ctx.Value(callTraceContextKey).(*CallTrace); ok {
+		t.mu.Lock()
+		t.calls = append(t.calls, TracedCall{Name: name, Args: args})
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,898 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Table tools read CSV/TSV files and .xlsx worksheets, and write CSV. The
+// first row holds the column names.
+
+const (
+	defaultTableRows = 50
+	maxTableRows     = 1000
+	maxTableBytes    = 100 << 20
+)
+
+// table is a parsed file: column names and rows of cells
+type table struct {
+	columns []string
+	rows    [][]string
+}
+
+func (tb *table) index(column string) (int, error) {
+	for i, c := range tb.columns {
+		if c == column {
+			return i, nil
+		}
+	}
+	for i, c := range tb.columns {
+		if strings.EqualFold(strings.TrimSpace(c), strings.TrimSpace(column)) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no column %q (columns: %s)", column, strings.Join(tb.columns, ", "))
+}
+
+func (tb *table) cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// loadTable reads a CSV, TSV or XLSX file
+func loadTable(file, sheet, delimiter string) (*table, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.Size() > maxTableBytes {
+		return nil, fmt.Errorf("file is %s, over the %s limit", formatBytes(info.Size()), formatBytes(maxTableBytes))
+	}
+
+	var records [][]string
+	if strings.EqualFold(filepath.Ext(file), ".xlsx") {
+		if records, err = readXLSX(file, sheet); err != nil {
+			return nil, err
+		}
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		defer f.Close()
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		comma, err := tableDelimiter(file, delimiter)
+		if err != nil {
+			return nil, err
+		}
+		r.Comma = comma
+		if records, err = r.ReadAll(); err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+	}
+
+	// Skip leading blank rows; the first row left is the header
+	for len(records) > 0 && isBlankRow(records[0]) {
+		records = records[1:]
+	}
+	if len(records) == 0 {
+		return &table{}, nil
+	}
+	tb := &table{columns: records[0]}
+	if len(tb.columns) > 0 {
+		tb.columns[0] = strings.TrimPrefix(tb.columns[0], "\ufeff")
+	}
+	for _, row := range records[1:] {
+		if !isBlankRow(row) {
+			tb.rows = append(tb.rows, row)
+		}
+	}
+	return tb, nil
+}
+
+func tableDelimiter(file, delimiter string) (rune, error) {
+	switch delimiter {
+	case "":
+		if ext := strings.ToLower(filepath.Ext(file)); ext == ".tsv" || ext == ".tab" {
+			return '\t', nil
+		}
+		return ',', nil
+	case "\\t", "tab":
+		return '\t', nil
+	}
+	runes := []rune(delimiter)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character")
+	}
+	return runes[0], nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// tableJSON renders rows as JSON objects keyed by column
+func tableJSON(columns []string, rows [][]string, extra map[string]interface{}) string {
+	objects := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		obj := make(map[string]string, len(columns))
+		for i, c := range columns {
+			if i < len(row) {
+				obj[c] = row[i]
+			} else {
+				obj[c] = ""
+			}
+		}
+		objects = append(objects, obj)
+	}
+	result := map[string]interface{}{
+		"columns": columns,
+		"rows":    objects,
+	}
+	for k, v := range extra {
+		result[k] = v
+	}
+	b, _ := json.MarshalIndent(result, "", "  ")
+	return string(b)
+}
+
+func intArg(args map[string]interface{}, key string, def int) int {
+	if v, ok := args[key].(float64); ok {
+		return int(v)
+	}
+	return def
+}
+
+func stringListArg(args map[string]interface{}, key string) []string {
+	raw, _ := args[key].([]interface{})
+	var out []string
+	for _, v := range raw {
+		if s, ok := v.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func resolveTablePath(ctx context.Context, workspace, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(WorkDir(ctx, workspace), p)
+}
+
+var tableFileParams = map[string]interface{}{
+	"path": map[string]interface{}{
+		"type":        "string",
+		"description": "CSV, TSV or XLSX file (relative to the workspace or absolute)",
+	},
+	"sheet": map[string]interface{}{
+		"type":        "string",
+		"description": "XLSX worksheet name (default: the first sheet)",
+	},
+	"delimiter": map[string]interface{}{
+		"type":        "string",
+		"description": "CSV delimiter (default ',' or tab for .tsv), e.g. ';'",
+	},
+}
+
+func withTableFileParams(props map[string]interface{}) map[string]interface{} {
+	for k, v := range tableFileParams {
+		props[k] = v
+	}
+	return props
+}
+
+type CSVReadTool struct {
+	workspace string
+}
+
+func NewCSVReadTool(workspace string) *CSVReadTool {
+	return &CSVReadTool{workspace: workspace}
+}
+
+func (t *CSVReadTool) Name() string {
+	return "csv_read"
+}
+
+func (t *CSVReadTool) Description() string {
+	return "Read rows of a CSV/TSV file or XLSX worksheet as JSON objects keyed by column name, with paging. Use csv_query to filter, sort or aggregate."
+}
+
+func (t *CSVReadTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": withTableFileParams(map[string]interface{}{
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Rows to skip (default 0)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Rows to return (default %d, max %d)", defaultTableRows, maxTableRows),
+			},
+			"columns": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only these columns",
+			},
+		}),
+		"required": []string{"path"},
+	}
+}
+
+func (t *CSVReadTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	p := stringArg(args, "path")
+	if p == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	tb, err := loadTable(resolveTablePath(ctx, t.workspace, p), stringArg(args, "sheet"), stringArg(args, "delimiter"))
+	if err != nil {
+		return "", err
+	}
+
+	if cols := stringListArg(args, "columns"); len(cols) > 0 {
+		if tb, err = tb.project(cols); err != nil {
+			return "", err
+		}
+	}
+
+	offset := max(intArg(args, "offset", 0), 0)
+	limit := intArg(args, "limit", defaultTableRows)
+	if limit <= 0 || limit > maxTableRows {
+		limit = maxTableRows
+	}
+	rows := tb.rows[min(offset, len(tb.rows)):]
+	rows = rows[:min(limit, len(rows))]
+
+	return tableJSON(tb.columns, rows, map[string]interface{}{
+		"total_rows": len(tb.rows),
+		"offset":     offset,
+	}), nil
+}
+
+// project keeps the named columns, in that order
+func (tb *table) project(columns []string) (*table, error) {
+	idx := make([]int, len(columns))
+	for i, c := range columns {
+		j, err := tb.index(c)
+		if err != nil {
+			return nil, err
+		}
+		idx[i] = j
+	}
+	out := &table{columns: make([]string, len(columns))}
+	for i, j := range idx {
+		out.columns[i] = tb.columns[j]
+	}
+	for _, row := range tb.rows {
+		r := make([]string, len(idx))
+		for i, j := range idx {
+			r[i] = tb.cell(row, j)
+		}
+		out.rows = append(out.rows, r)
+	}
+	return out, nil
+}
+
+type CSVWriteTool struct {
+	workspace string
+}
+
+func NewCSVWriteTool(workspace string) *CSVWriteTool {
+	return &CSVWriteTool{workspace: workspace}
+}
+
+func (t *CSVWriteTool) Name() string {
+	return "csv_write"
+}
+
+func (t *CSVWriteTool) Sequential() bool {
+	return true
+}
+
+func (t *CSVWriteTool) Description() string {
+	return "Write rows to a CSV file in the workspace, or append them to an existing one. Rows are arrays in column order or objects keyed by column."
+}
+
+func (t *CSVWriteTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "CSV file inside the workspace",
+			},
+			"columns": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Column names. Required for new files; when appending, defaults to the file's header",
+			},
+			"rows": map[string]interface{}{
+				"type":        "array",
+				"description": "Rows to write: arrays of values or objects keyed by column name",
+				"items":       map[string]interface{}{},
+			},
+			"append": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Add the rows to the end of an existing file instead of replacing it",
+			},
+			"delimiter": map[string]interface{}{
+				"type":        "string",
+				"description": "Delimiter (default ',')",
+			},
+		},
+		"required": []string{"path", "rows"},
+	}
+}
+
+func (t *CSVWriteTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	p, err := workspacePath(ctx, t.workspace, stringArg(args, "path"))
+	if err != nil || stringArg(args, "path") == "" {
+		return "", fmt.Errorf("path must be a file inside the workspace")
+	}
+	comma, err := tableDelimiter(p, stringArg(args, "delimiter"))
+	if err != nil {
+		return "", err
+	}
+	columns := stringListArg(args, "columns")
+	appendRows, _ := args["append"].(bool)
+
+	existing := false
+	if info, err := os.Stat(p); err == nil && info.Size() > 0 {
+		existing = true
+	}
+	if appendRows && existing {
+		tb, err := loadTable(p, "", stringArg(args, "delimiter"))
+		if err != nil {
+			return "", err
+		}
+		if len(columns) == 0 {
+			columns = tb.columns
+		} else if strings.Join(columns, "\x00") != strings.Join(tb.columns, "\x00") {
+			return "", fmt.Errorf("columns don't match the file's header (%s)", strings.Join(tb.columns, ", "))
+		}
+	}
+
+	rawRows, _ := args["rows"].([]interface{})
+	rows := make([][]string, 0, len(rawRows))
+	for i, raw := range rawRows {
+		switch r := raw.(type) {
+		case []interface{}:
+			row := make([]string, len(r))
+			for j, v := range r {
+				row[j] = formatCell(v)
+			}
+			rows = append(rows, row)
+		case map[string]interface{}:
+			if len(columns) == 0 {
+				for k := range r {
+					columns = append(columns, k)
+				}
+				sort.Strings(columns)
+			}
+			row := make([]string, len(columns))
+			for j, c := range columns {
+				row[j] = formatCell(r[c])
+			}
+			rows = append(rows, row)
+		default:
+			return "", fmt.Errorf("row %d must be an array or an object", i+1)
+		}
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("columns is required for a new file")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendRows && existing {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(p, flags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	if appendRows && existing {
+		// The header may lack a trailing newline
+		if err := ensureTrailingNewline(p, f); err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	w := csv.NewWriter(f)
+	w.Comma = comma
+	if !(appendRows && existing) {
+		w.Write(columns)
+	}
+	w.WriteAll(rows)
+	err = w.Error()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	action := "Wrote"
+	if appendRows && existing {
+		action = "Appended"
+	}
+	return fmt.Sprintf("%s %d rows to %s", action, len(rows), p), nil
+}
+
+func ensureTrailingNewline(p string, f *os.File) error {
+	r, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := r.Seek(-1, io.SeekEnd); err != nil {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := r.Read(last); err == nil && last[0] != '\n' {
+		_, err = f.WriteString("\n")
+		return err
+	}
+	return nil
+}
+
+func formatCell(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+type CSVQueryTool struct {
+	workspace string
+}
+
+func NewCSVQueryTool(workspace string) *CSVQueryTool {
+	return &CSVQueryTool{workspace: workspace}
+}
+
+func (t *CSVQueryTool) Name() string {
+	return "csv_query"
+}
+
+func (t *CSVQueryTool) Description() string {
+	return "Filter, group, aggregate and sort a CSV/TSV file or XLSX worksheet without code. Values compare as numbers when both sides are numeric. Optionally saves the result as a new CSV."
+}
+
+func (t *CSVQueryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": withTableFileParams(map[string]interface{}{
+			"where": map[string]interface{}{
+				"type":        "array",
+				"description": "Conditions, all of which must hold",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"column": map[string]interface{}{"type": "string"},
+						"op": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"=", "!=", ">", ">=", "<", "<=", "contains", "starts_with", "ends_with", "empty", "not_empty"},
+						},
+						"value": map[string]interface{}{"description": "Value to compare with"},
+					},
+					"required": []string{"column", "op"},
+				},
+			},
+			"group_by": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Group rows by these columns; use with aggregates",
+			},
+			"aggregates": map[string]interface{}{
+				"type":        "array",
+				"description": "Aggregates per group (or over all rows without group_by)",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"op":     map[string]interface{}{"type": "string", "enum": []string{"count", "sum", "avg", "min", "max", "distinct"}},
+						"column": map[string]interface{}{"type": "string", "description": "Not needed for count"},
+						"as":     map[string]interface{}{"type": "string", "description": "Result column name"},
+					},
+					"required": []string{"op"},
+				},
+			},
+			"select": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Columns to return when not aggregating (default: all)",
+			},
+			"sort": map[string]interface{}{
+				"type":        "array",
+				"description": "Sort keys, applied in order",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"column": map[string]interface{}{"type": "string"},
+						"desc":   map[string]interface{}{"type": "boolean"},
+					},
+					"required": []string{"column"},
+				},
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Rows to return (default %d, max %d)", defaultTableRows, maxTableRows),
+			},
+			"output": map[string]interface{}{
+				"type":        "string",
+				"description": "Also write the full result to this CSV file in the workspace",
+			},
+		}),
+		"required": []string{"path"},
+	}
+}
+
+func (t *CSVQueryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	p := stringArg(args, "path")
+	if p == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	tb, err := loadTable(resolveTablePath(ctx, t.workspace, p), stringArg(args, "sheet"), stringArg(args, "delimiter"))
+	if err != nil {
+		return "", err
+	}
+
+	if tb, err = tb.filter(args["where"]); err != nil {
+		return "", err
+	}
+	matched := len(tb.rows)
+
+	groupBy := stringListArg(args, "group_by")
+	aggs, _ := args["aggregates"].([]interface{})
+	if len(groupBy) > 0 || len(aggs) > 0 {
+		if tb, err = tb.aggregate(groupBy, aggs); err != nil {
+			return "", err
+		}
+	} else if cols := stringListArg(args, "select"); len(cols) > 0 {
+		if tb, err = tb.project(cols); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tb.sort(args["sort"]); err != nil {
+		return "", err
+	}
+
+	extra := map[string]interface{}{
+		"matched_rows": matched,
+		"result_rows":  len(tb.rows),
+	}
+	if out := stringArg(args, "output"); out != "" {
+		dest, err := workspacePath(ctx, t.workspace, out)
+		if err != nil {
+			return "", fmt.Errorf("output must be inside the workspace")
+		}
+		if err := writeCSVFile(dest, tb); err != nil {
+			return "", err
+		}
+		extra["output"] = dest
+	}
+
+	limit := intArg(args, "limit", defaultTableRows)
+	if limit <= 0 || limit > maxTableRows {
+		limit = maxTableRows
+	}
+	return tableJSON(tb.columns, tb.rows[:min(limit, len(tb.rows))], extra), nil
+}
+
+func writeCSVFile(p string, tb *table) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", p, err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(tb.columns)
+	w.WriteAll(tb.rows)
+	err = w.Error()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// compareValues compares numerically when both values are numbers
+func compareValues(a, b string) int {
+	fa, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	fb, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func (tb *table) filter(raw interface{}) (*table, error) {
+	conds, _ := raw.([]interface{})
+	if len(conds) == 0 {
+		return tb, nil
+	}
+
+	type condition struct {
+		col   int
+		op    string
+		value string
+	}
+	var parsed []condition
+	for _, c := range conds {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("where entries must be objects")
+		}
+		col, err := tb.index(stringArg(m, "column"))
+		if err != nil {
+			return nil, err
+		}
+		op := stringArg(m, "op")
+		switch op {
+		case "=", "!=", ">", ">=", "<", "<=", "contains", "starts_with", "ends_with", "empty", "not_empty":
+		case "==":
+			op = "="
+		default:
+			return nil, fmt.Errorf("unknown operator %q", op)
+		}
+		parsed = append(parsed, condition{col: col, op: op, value: formatCell(m["value"])})
+	}
+
+	out := &table{columns: tb.columns}
+	for _, row := range tb.rows {
+		keep := true
+		for _, c := range parsed {
+			v := tb.cell(row, c.col)
+			lv, lc := strings.ToLower(v), strings.ToLower(c.value)
+			var ok bool
+			switch c.op {
+			case "=":
+				ok = compareValues(v, c.value) == 0
+			case "!=":
+				ok = compareValues(v, c.value) != 0
+			case ">":
+				ok = compareValues(v, c.value) > 0
+			case ">=":
+				ok = compareValues(v, c.value) >= 0
+			case "<":
+				ok = compareValues(v, c.value) < 0
+			case "<=":
+				ok = compareValues(v, c.value) <= 0
+			case "contains":
+				ok = strings.Contains(lv, lc)
+			case "starts_with":
+				ok = strings.HasPrefix(lv, lc)
+			case "ends_with":
+				ok = strings.HasSuffix(lv, lc)
+			case "empty":
+				ok = strings.TrimSpace(v) == ""
+			case "not_empty":
+				ok = strings.TrimSpace(v) != ""
+			}
+			if !ok {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out.rows = append(out.rows, row)
+		}
+	}
+	return out, nil
+}
+
+func (tb *table) aggregate(groupBy []string, raw []interface{}) (*table, error) {
+	groupIdx := make([]int, len(groupBy))
+	for i, c := range groupBy {
+		j, err := tb.index(c)
+		if err != nil {
+			return nil, err
+		}
+		groupIdx[i] = j
+	}
+
+	type aggregate struct {
+		op  string
+		col int
+		as  string
+	}
+	var aggs []aggregate
+	for _, a := range raw {
+		m, ok := a.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregates must be objects")
+		}
+		agg := aggregate{op: stringArg(m, "op"), col: -1, as: stringArg(m, "as")}
+		switch agg.op {
+		case "count":
+		case "sum", "avg", "min", "max", "distinct":
+			col, err := tb.index(stringArg(m, "column"))
+			if err != nil {
+				return nil, err
+			}
+			agg.col = col
+		default:
+			return nil, fmt.Errorf("unknown aggregate %q", agg.op)
+		}
+		if agg.as == "" {
+			agg.as = agg.op
+			if agg.col >= 0 {
+				agg.as += "_" + tb.columns[agg.col]
+			}
+		}
+		aggs = append(aggs, agg)
+	}
+	if len(aggs) == 0 {
+		aggs = []aggregate{{op: "count", col: -1, as: "count"}}
+	}
+
+	// Groups in order of first appearance
+	var keys []string
+	groups := map[string][][]string{}
+	for _, row := range tb.rows {
+		parts := make([]string, len(groupIdx))
+		for i, j := range groupIdx {
+			parts[i] = tb.cell(row, j)
+		}
+		key := strings.Join(parts, "\x00")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+	if len(groupIdx) == 0 && len(keys) == 0 {
+		keys = []string{""}
+	}
+
+	out := &table{}
+	for _, j := range groupIdx {
+		out.columns = append(out.columns, tb.columns[j])
+	}
+	for _, a := range aggs {
+		out.columns = append(out.columns, a.as)
+	}
+
+	for _, key := range keys {
+		rows := groups[key]
+		var result []string
+		if len(groupIdx) > 0 {
+			result = strings.Split(key, "\x00")
+		}
+		for _, a := range aggs {
+			result = append(result, aggregateValue(a.op, a.col, rows, tb))
+		}
+		out.rows = append(out.rows, result)
+	}
+	return out, nil
+}
+
+func aggregateValue(op string, col int, rows [][]string, tb *table) string {
+	if op == "count" {
+		return strconv.Itoa(len(rows))
+	}
+	if op == "distinct" {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			seen[tb.cell(row, col)] = true
+		}
+		return strconv.Itoa(len(seen))
+	}
+
+	var sum float64
+	n := 0
+	minV, maxV := math.Inf(1), math.Inf(-1)
+	minS, maxS := "", ""
+	for i, row := range rows {
+		v := tb.cell(row, col)
+		if i == 0 || compareValues(v, minS) < 0 {
+			minS = v
+		}
+		if i == 0 || compareValues(v, maxS) > 0 {
+			maxS = v
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			continue
+		}
+		sum += f
+		n++
+		minV, maxV = math.Min(minV, f), math.Max(maxV, f)
+	}
+
+	switch op {
+	case "sum":
+		return strconv.FormatFloat(sum, 'f', -1, 64)
+	case "avg":
+		if n == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(math.Round(sum/float64(n)*1e6)/1e6, 'f', -1, 64)
+	case "min":
+		if n > 0 {
+			return strconv.FormatFloat(minV, 'f', -1, 64)
+		}
+		return minS
+	case "max":
+		if n > 0 {
+			return strconv.FormatFloat(maxV, 'f', -1, 64)
+		}
+		return maxS
+	}
+	return ""
+}
+
+func (tb *table) sort(raw interface{}) error {
+	keys, _ := raw.([]interface{})
+	if len(keys) == 0 {
+		return nil
+	}
+	type sortKey struct {
+		col  int
+		desc bool
+	}
+	var parsed []sortKey
+	for _, k := range keys {
+		m, ok := k.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("sort entries must be objects")
+		}
+		col, err := tb.index(stringArg(m, "column"))
+		if err != nil {
+			return err
+		}
+		desc, _ := m["desc"].(bool)
+		parsed = append(parsed, sortKey{col: col, desc: desc})
+	}
+	sort.SliceStable(tb.rows, func(i, j int) bool {
+		for _, k := range parsed {
+			c := compareValues(tb.cell(tb.rows[i], k.col), tb.cell(tb.rows[j], k.col))
+			if c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
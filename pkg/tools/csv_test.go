@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const salesCSV = `region,product,units,price
+west,apple,10,1.5
+east,apple,4,1.5
+west,pear,7,2
+east,plum,12,0.5
+`
+
+func runTableTool(t *testing.T, tool Tool, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	out, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return result
+}
+
+func TestCSVReadAndQuery(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "sales.csv"), []byte(salesCSV), 0644)
+
+	read := runTableTool(t, NewCSVReadTool(workspace), map[string]interface{}{"path": "sales.csv", "offset": float64(1), "limit": float64(2)})
+	rows := read["rows"].([]interface{})
+	if read["total_rows"].(float64) != 4 || len(rows) != 2 || rows[0].(map[string]interface{})["region"] != "east" {
+		t.Errorf("csv_read = %v", read)
+	}
+
+	query := NewCSVQueryTool(workspace)
+	result := runTableTool(t, query, map[string]interface{}{
+		"path":  "sales.csv",
+		"where": []interface{}{map[string]interface{}{"column": "units", "op": ">", "value": float64(5)}},
+		"sort":  []interface{}{map[string]interface{}{"column": "units", "desc": true}},
+	})
+	rows = result["rows"].([]interface{})
+	if len(rows) != 3 || rows[0].(map[string]interface{})["product"] != "plum" || rows[2].(map[string]interface{})["product"] != "pear" {
+		t.Errorf("filter/sort = %v", rows)
+	}
+
+	result = runTableTool(t, query, map[string]interface{}{
+		"path":     "sales.csv",
+		"group_by": []interface{}{"region"},
+		"aggregates": []interface{}{
+			map[string]interface{}{"op": "sum", "column": "units"},
+			map[string]interface{}{"op": "avg", "column": "price", "as": "avg_price"},
+		},
+		"output": "out/by_region.csv",
+	})
+	rows = result["rows"].([]interface{})
+	west := rows[0].(map[string]interface{})
+	if len(rows) != 2 || west["region"] != "west" || west["sum_units"] != "17" || west["avg_price"] != "1.75" {
+		t.Errorf("aggregate = %v", rows)
+	}
+	assertFile(t, filepath.Join(workspace, "out", "by_region.csv"), "region,sum_units,avg_price\nwest,17,1.75\neast,16,1\n")
+}
+
+func TestCSVWrite(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewCSVWriteTool(workspace)
+	ctx := context.Background()
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{
+		"path":    "data/out.csv",
+		"columns": []interface{}{"name", "note"},
+		"rows":    []interface{}{[]interface{}{"a", "x, y"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{
+		"path":   "data/out.csv",
+		"rows":   []interface{}{map[string]interface{}{"name": "b", "note": float64(2)}},
+		"append": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(workspace, "data", "out.csv"), "name,note\na,\"x, y\"\nb,2\n")
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"path": "../out.csv", "columns": []interface{}{"a"}, "rows": []interface{}{}}); err == nil {
+		t.Error("writing outside the workspace should be rejected")
+	}
+}
+
+func TestCSVReadXLSX(t *testing.T) {
+	workspace := t.TempDir()
+	p := filepath.Join(workspace, "book.xlsx")
+	f, _ := os.Create(p)
+	zw := zip.NewWriter(f)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="Data" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>name</t></si><si><t>qty</t></si><si><r><t>wid</t></r><r><t>get</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>ignored</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><v>42</v></c></row>
+</sheetData></worksheet>`,
+	}
+	for name, body := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	f.Close()
+
+	result := runTableTool(t, NewCSVReadTool(workspace), map[string]interface{}{"path": "book.xlsx", "sheet": "data"})
+	rows := result["rows"].([]interface{})
+	if len(rows) != 1 {
+		t.Fatalf("rows = %v", rows)
+	}
+	row := rows[0].(map[string]interface{})
+	if row["name"] != "widget" || row["qty"] != "42" || row[""] != "" {
+		t.Errorf("row = %v", row)
+	}
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DiscordVoiceController is implemented by the running Discord channel.
+type DiscordVoiceController interface {
+	JoinVoice(channelID, textChannelID, mode string) (string, error)
+	LeaveVoice(guildID string) (int, error)
+}
+
+// ==================== Discord Voice Join Tool ====================
+
+type DiscordVoiceJoinTool struct {
+	controller DiscordVoiceController
+}
+
+func NewDiscordVoiceJoinTool(controller DiscordVoiceController) *DiscordVoiceJoinTool {
+	return &DiscordVoiceJoinTool{controller: controller}
+}
+
+func (t *DiscordVoiceJoinTool) Name() string {
+	return "discord_voice_join"
+}
+
+func (t *DiscordVoiceJoinTool) Description() string {
+	return "Join a Discord voice channel and transcribe speech with Groq Whisper. In 'respond' mode each spoken utterance is sent to the agent as a prompt and the reply is posted to the text channel; in 'transcribe' mode live transcripts are posted instead."
+}
+
+func (t *DiscordVoiceJoinTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"channel_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Discord voice channel ID to join",
+			},
+			"text_channel_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Text channel ID for transcripts/replies (optional, defaults to the current Discord chat)",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"respond", "transcribe"},
+				"description": "respond: answer voice prompts (default). transcribe: post live transcripts",
+			},
+		},
+		"required": []string{"channel_id"},
+	}
+}
+
+func (t *DiscordVoiceJoinTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	channelID, _ := args["channel_id"].(string)
+	if channelID == "" {
+		return "", fmt.Errorf("channel_id is required")
+	}
+
+	textChannelID, _ := args["text_channel_id"].(string)
+	if textChannelID == "" {
+		if sessionKey := SessionKeyFromContext(ctx); strings.HasPrefix(sessionKey, "discord:") {
+			textChannelID = strings.TrimPrefix(sessionKey, "discord:")
+		}
+	}
+	if textChannelID == "" {
+		return "", fmt.Errorf("text_channel_id is required outside of a Discord chat")
+	}
+
+	mode, _ := args["mode"].(string)
+
+	guildID, err := t.controller.JoinVoice(channelID, textChannelID, mode)
+	if err != nil {
+		return "", err
+	}
+
+	if mode == "" {
+		mode = "respond"
+	}
+	result, _ := json.Marshal(map[string]interface{}{
+		"success":         true,
+		"guild_id":        guildID,
+		"channel_id":      channelID,
+		"text_channel_id": textChannelID,
+		"mode":            mode,
+	})
+	return string(result), nil
+}
+
+// ==================== Discord Voice Leave Tool ====================
+
+type DiscordVoiceLeaveTool struct {
+	controller DiscordVoiceController
+}
+
+func NewDiscordVoiceLeaveTool(controller DiscordVoiceController) *DiscordVoiceLeaveTool {
+	return &DiscordVoiceLeaveTool{controller: controller}
+}
+
+func (t *DiscordVoiceLeaveTool) Name() string {
+	return "discord_voice_leave"
+}
+
+func (t *DiscordVoiceLeaveTool) Description() string {
+	return "Leave a Discord voice channel joined with discord_voice_join. Without guild_id, leaves all voice channels."
+}
+
+func (t *DiscordVoiceLeaveTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"guild_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Guild (server) ID to leave voice in (optional)",
+			},
+		},
+	}
+}
+
+func (t *DiscordVoiceLeaveTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	guildID, _ := args["guild_id"].(string)
+
+	left, err := t.controller.LeaveVoice(guildID)
+	if err != nil {
+		return "", err
+	}
+
+	result, _ := json.Marshal(map[string]interface{}{
+		"success": true,
+		"left":    left,
+	})
+	return string(result), nil
+}
@@ -0,0 +1,342 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxDownloadBytes is the largest file download_file accepts
+	// unless configured otherwise
+	DefaultMaxDownloadBytes = 1 << 30
+	downloadsDir            = "downloads"
+	// downloadProgressInterval rate-limits progress reports
+	downloadProgressInterval = 5 * time.Second
+)
+
+// DownloadFileTool streams a URL to a file in the workspace. Interrupted
+// downloads leave a .part file that the next call resumes with a Range
+// request.
+type DownloadFileTool struct {
+	workspace string
+	maxBytes  int64
+	client    *http.Client
+}
+
+func NewDownloadFileTool(workspace string) *DownloadFileTool {
+	return &DownloadFileTool{
+		workspace: workspace,
+		maxBytes:  DefaultMaxDownloadBytes,
+		client: &http.Client{
+			// No overall timeout: large files take long; stalls are cut by
+			// the transport and by the turn's context
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				TLSHandshakeTimeout:   15 * time.Second,
+				ResponseHeaderTimeout: 60 * time.Second,
+				IdleConnTimeout:       30 * time.Second,
+			},
+		},
+	}
+}
+
+// SetMaxBytes sets the size limit of a single download
+func (t *DownloadFileTool) SetMaxBytes(n int64) {
+	if n > 0 {
+		t.maxBytes = n
+	}
+}
+
+func (t *DownloadFileTool) Name() string {
+	return "download_file"
+}
+
+func (t *DownloadFileTool) Sequential() bool {
+	return true
+}
+
+func (t *DownloadFileTool) Description() string {
+	return "Download a file (binary or text, any size up to the configured limit) from an http(s) URL into the workspace. Resumes interrupted downloads and can verify a SHA256 checksum. Use web_fetch to read web pages instead."
+}
+
+func (t *DownloadFileTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "http or https URL of the file",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Destination inside the workspace, file or directory (ending in /). Default: downloads/<file name from the server>",
+			},
+			"sha256": map[string]interface{}{
+				"type":        "string",
+				"description": "Expected SHA256 (hex). The file is deleted when it doesn't match.",
+			},
+			"max_bytes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Refuse files larger than this (cannot exceed the configured limit)",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace an existing file at path (default false)",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *DownloadFileTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	rawURL, _ := args["url"].(string)
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("url must be an http or https URL")
+	}
+
+	limit := t.maxBytes
+	if mb, ok := args["max_bytes"].(float64); ok && mb > 0 && int64(mb) < limit {
+		limit = int64(mb)
+	}
+	wantSum := strings.ToLower(strings.TrimSpace(stringArg(args, "sha256")))
+	if wantSum != "" {
+		if b, err := hex.DecodeString(wantSum); err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("sha256 must be 64 hex characters")
+		}
+	}
+	overwrite, _ := args["overwrite"].(bool)
+
+	// A known destination lets an earlier .part be resumed before the
+	// response names the file
+	dest, err := t.destination(ctx, stringArg(args, "path"), u, "")
+	if err != nil {
+		return "", err
+	}
+
+	part := dest + ".part"
+	offset := int64(0)
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		offset = 0 // server ignored the range; start over
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The .part is already complete or stale; fetch it whole next time
+		os.Remove(part)
+		return "", fmt.Errorf("server rejected resuming at byte %d; the partial file was removed, call again to restart", offset)
+	default:
+		return "", fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	// Use the server's file name when no path was given. Only for fresh
+	// downloads: a resumed one keeps the name of its .part.
+	if p := stringArg(args, "path"); resp.StatusCode == http.StatusOK && (p == "" || strings.HasSuffix(p, "/")) {
+		if name := contentDispositionName(resp.Header.Get("Content-Disposition")); name != "" {
+			if named, err := t.destination(ctx, p, u, name); err == nil && named != dest {
+				os.Remove(part)
+				dest, part = named, named+".part"
+			}
+		}
+	}
+
+	if _, err := os.Stat(dest); err == nil && !overwrite {
+		return "", fmt.Errorf("%s already exists; set overwrite to replace it", t.relative(dest))
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+		if total > limit {
+			return "", fmt.Errorf("file is %s, over the %s limit", formatBytes(total), formatBytes(limit))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", t.relative(part), err)
+	}
+
+	hash := sha256.New()
+	if offset > 0 {
+		// Hash what is already on disk so the checksum covers the whole file
+		existing, err := os.Open(part)
+		if err == nil {
+			_, err = io.Copy(hash, existing)
+			existing.Close()
+		}
+		if err != nil {
+			f.Close()
+			return "", fmt.Errorf("failed to read partial download: %w", err)
+		}
+	}
+
+	progress := &downloadProgress{ctx: ctx, name: filepath.Base(dest), done: offset, total: total}
+	written, err := io.Copy(io.MultiWriter(f, hash, progress), io.LimitReader(resp.Body, limit-offset+1))
+	closeErr := f.Close()
+	size := offset + written
+	if err != nil {
+		// Keep the .part so the next call resumes
+		return "", fmt.Errorf("download interrupted after %s (call again to resume): %w", formatBytes(size), err)
+	}
+	if closeErr != nil {
+		return "", fmt.Errorf("failed to write %s: %w", t.relative(part), closeErr)
+	}
+	if size > limit {
+		os.Remove(part)
+		return "", fmt.Errorf("file exceeds the %s limit", formatBytes(limit))
+	}
+	if total >= 0 && size != total {
+		return "", fmt.Errorf("download incomplete: got %s of %s (call again to resume)", formatBytes(size), formatBytes(total))
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if wantSum != "" && sum != wantSum {
+		os.Remove(part)
+		return "", fmt.Errorf("checksum mismatch: expected %s, got %s; the file was deleted", wantSum, sum)
+	}
+
+	if err := os.Rename(part, dest); err != nil {
+		return "", fmt.Errorf("failed to save %s: %w", t.relative(dest), err)
+	}
+
+	result := map[string]interface{}{
+		"path":         dest,
+		"size":         size,
+		"sha256":       sum,
+		"verified":     wantSum != "",
+		"resumed":      offset > 0,
+		"content_type": resp.Header.Get("Content-Type"),
+	}
+	out, _ := json.MarshalIndent(result, "", "  ")
+	return string(out), nil
+}
+
+// destination resolves the target path inside the workspace. name is the
+// file name to use when path is empty or a directory.
+func (t *DownloadFileTool) destination(ctx context.Context, p string, u *url.URL, name string) (string, error) {
+	if name == "" {
+		name = path.Base(u.Path)
+		if name == "/" || name == "." || name == "" {
+			name = "download"
+		}
+	}
+	name = filepath.Base(filepath.Clean("/" + name)) // no directories from the server
+
+	switch {
+	case p == "":
+		p = filepath.Join(downloadsDir, name)
+	case strings.HasSuffix(p, "/") || strings.HasSuffix(p, string(filepath.Separator)):
+		p = filepath.Join(p, name)
+	}
+
+	abs, err := workspacePath(ctx, t.workspace, p)
+	if err != nil {
+		return "", fmt.Errorf("path must be inside the workspace")
+	}
+	if info, err := os.Stat(abs); err == nil && info.IsDir() {
+		return filepath.Join(abs, name), nil
+	}
+	return abs, nil
+}
+
+func (t *DownloadFileTool) relative(p string) string {
+	if rel, err := filepath.Rel(t.workspace, p); err == nil {
+		return rel
+	}
+	return p
+}
+
+func contentDispositionName(header string) string {
+	if header == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+func stringArg(args map[string]interface{}, key string) string {
+	s, _ := args[key].(string)
+	return s
+}
+
+// downloadProgress reports bytes written through ReportProgress
+type downloadProgress struct {
+	ctx      context.Context
+	name     string
+	done     int64
+	total    int64
+	reported time.Time
+}
+
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if time.Since(p.reported) >= downloadProgressInterval {
+		if p.reported.IsZero() {
+			// Skip the first chunk; small files finish before it matters
+			p.reported = time.Now()
+			return len(b), nil
+		}
+		p.reported = time.Now()
+		status := fmt.Sprintf("%s: %s", p.name, formatBytes(p.done))
+		if p.total > 0 {
+			status = fmt.Sprintf("%s: %d%% (%s of %s)", p.name, p.done*100/p.total, formatBytes(p.done), formatBytes(p.total))
+		}
+		ReportProgress(p.ctx, "download_file", status)
+	}
+	return len(b), nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadFile(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	sum := sha256.Sum256([]byte(content))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/named" {
+			w.Header().Set("Content-Disposition", `attachment; filename="../report.txt"`)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	workspace := t.TempDir()
+	tool := NewDownloadFileTool(workspace)
+	ctx := context.Background()
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/data.bin", "sha256": hex.EncodeToString(sum[:])}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(workspace, "downloads", "data.bin"), content)
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/data.bin"}); err == nil {
+		t.Error("existing file should not be replaced without overwrite")
+	}
+
+	// The server's file name is used, without its directories
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/named", "path": "out/"}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(workspace, "out", "report.txt"), content)
+
+	// Resume from a partial download
+	part := filepath.Join(workspace, "resumed.bin.part")
+	os.WriteFile(part, []byte(content[:4000]), 0644)
+	out, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/data.bin", "path": "resumed.bin", "sha256": hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"resumed": true`) {
+		t.Errorf("download was not resumed: %s", out)
+	}
+	assertFile(t, filepath.Join(workspace, "resumed.bin"), content)
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/data.bin", "path": "bad.bin", "sha256": strings.Repeat("0", 64)}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "bad.bin.part")); !os.IsNotExist(err) {
+		t.Error("mismatching download was kept")
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/data.bin", "path": "big.bin", "max_bytes": 1000.0}); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("expected the size limit to apply, got %v", err)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": srv.URL + "/data.bin", "path": "../escape.bin"}); err == nil {
+		t.Error("paths outside the workspace should be rejected")
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s has %d bytes, want %d", path, len(data), len(want))
+	}
+}
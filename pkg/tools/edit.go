@@ -18,6 +18,11 @@ func (t *EditFileTool) Name() string {
 	return "edit_file"
 }
 
+// Sequential prevents overlapping edits of the same file
+func (t *EditFileTool) Sequential() bool {
+	return true
+}
+
 func (t *EditFileTool) Description() string {
 	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
 }
@@ -60,6 +65,9 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	filePath := filepath.Clean(path)
+	if dir := WorkDir(ctx, ""); dir != "" && !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(dir, filePath)
+	}
 
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return "", fmt.Errorf("file not found: %s", path)
@@ -100,6 +108,10 @@ func (t *AppendFileTool) Name() string {
 	return "append_file"
 }
 
+func (t *AppendFileTool) Sequential() bool {
+	return true
+}
+
 func (t *AppendFileTool) Description() string {
 	return "Append content to the end of a file"
 }
@@ -133,6 +145,9 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 	}
 
 	filePath := filepath.Clean(path)
+	if dir := WorkDir(ctx, ""); dir != "" && !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(dir, filePath)
+	}
 
 	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
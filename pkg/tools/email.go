@@ -0,0 +1,331 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// maxEmailAttachmentSize caps the combined size of attachments
+const maxEmailAttachmentSize = 20 * 1024 * 1024
+
+type EmailSendTool struct {
+	cfg       config.EmailConfig
+	workspace string
+	send      func(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+func NewEmailSendTool(cfg config.EmailConfig, workspace string) *EmailSendTool {
+	t := &EmailSendTool{cfg: cfg, workspace: workspace}
+	t.send = t.sendSMTP
+	return t
+}
+
+func (t *EmailSendTool) Name() string {
+	return "email_send"
+}
+
+func (t *EmailSendTool) Sequential() bool {
+	return true
+}
+
+func (t *EmailSendTool) Description() string {
+	return "Send an email with optional file attachments through the configured SMTP account. Use it to deliver reports or files to people outside chat channels."
+}
+
+func (t *EmailSendTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"to": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Recipient addresses",
+			},
+			"cc": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional CC addresses",
+			},
+			"bcc": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional BCC addresses",
+			},
+			"subject": map[string]interface{}{
+				"type":        "string",
+				"description": "Email subject",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Email body",
+			},
+			"html": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Send the body as HTML instead of plain text (default: false)",
+			},
+			"attachments": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Local file paths to attach (absolute or relative to the workspace)",
+			},
+		},
+		"required": []string{"to", "subject", "body"},
+	}
+}
+
+func (t *EmailSendTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	to, err := parseAddresses(args["to"])
+	if err != nil {
+		return "", fmt.Errorf("invalid to: %w", err)
+	}
+	if len(to) == 0 {
+		return "", fmt.Errorf("at least one recipient is required")
+	}
+	cc, err := parseAddresses(args["cc"])
+	if err != nil {
+		return "", fmt.Errorf("invalid cc: %w", err)
+	}
+	bcc, err := parseAddresses(args["bcc"])
+	if err != nil {
+		return "", fmt.Errorf("invalid bcc: %w", err)
+	}
+
+	subject, _ := args["subject"].(string)
+	body, _ := args["body"].(string)
+	html, _ := args["html"].(bool)
+
+	var files []string
+	for _, path := range stringList(args["attachments"]) {
+		resolved := resolveFilePath(path, WorkDir(ctx, t.workspace))
+		if strings.Contains(resolved, "://") || strings.HasPrefix(resolved, "data:") {
+			return "", fmt.Errorf("attachment %s: only local files can be attached", path)
+		}
+		files = append(files, resolved)
+	}
+
+	from := t.cfg.From
+	if from == "" {
+		from = t.cfg.Username
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+
+	msg, err := buildEmail(sender, to, cc, subject, body, html, files)
+	if err != nil {
+		return "", err
+	}
+
+	var recipients []string
+	for _, list := range [][]*mail.Address{to, cc, bcc} {
+		for _, addr := range list {
+			recipients = append(recipients, addr.Address)
+		}
+	}
+	if err := t.send(ctx, sender.Address, recipients, msg); err != nil {
+		return "", fmt.Errorf("failed to send email: %w", err)
+	}
+
+	result := fmt.Sprintf("Email sent to %d recipient(s)", len(recipients))
+	if len(files) > 0 {
+		result += fmt.Sprintf(" with %d attachment(s)", len(files))
+	}
+	return result, nil
+}
+
+// parseAddresses accepts a list or a comma-separated string of addresses
+func parseAddresses(value interface{}) ([]*mail.Address, error) {
+	var addrs []*mail.Address
+	for _, item := range stringList(value) {
+		for _, part := range strings.Split(item, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			addr, err := mail.ParseAddress(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", part, err)
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// buildEmail renders a MIME message. Bcc recipients are deliberately left
+// out of the headers.
+func buildEmail(from *mail.Address, to, cc []*mail.Address, subject, body string, html bool, files []string) ([]byte, error) {
+	var buf bytes.Buffer
+	joinAddrs := func(list []*mail.Address) string {
+		parts := make([]string, len(list))
+		for i, a := range list {
+			parts[i] = a.String()
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", joinAddrs(to))
+	if len(cc) > 0 {
+		fmt.Fprintf(&buf, "Cc: %s\r\n", joinAddrs(cc))
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", randomToken(), domainOf(from.Address))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	contentType := "text/plain; charset=utf-8"
+	if html {
+		contentType = "text/html; charset=utf-8"
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", contentType)
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64Lines(&buf, []byte(body))
+		return buf.Bytes(), nil
+	}
+
+	boundary := "pepebot-" + randomToken()
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	writeBase64Lines(&buf, []byte(body))
+
+	total := 0
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		total += len(data)
+		if total > maxEmailAttachmentSize {
+			return nil, fmt.Errorf("attachments exceed %d MB", maxEmailAttachmentSize/1024/1024)
+		}
+
+		name := filepath.Base(path)
+		mimeType := mime.TypeByExtension(filepath.Ext(name))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", mimeType)
+		fmt.Fprintf(&buf, "Content-Disposition: %s\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64Lines(&buf, data)
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines writes base64 wrapped at 76 characters (RFC 2045)
+func writeBase64Lines(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+func randomToken() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "pepebot"
+}
+
+// sendSMTP delivers the message. Port 465 uses implicit TLS; other ports
+// upgrade with STARTTLS when the server offers it.
+func (t *EmailSendTool) sendSMTP(ctx context.Context, from string, to []string, msg []byte) error {
+	if t.cfg.Host == "" {
+		return fmt.Errorf("tools.email.host is not configured")
+	}
+	port := t.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(t.cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: t.cfg.Host}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	}
+
+	client, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if t.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
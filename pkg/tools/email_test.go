@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func TestEmailSendBuildsMessage(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "report.csv"), []byte("device,battery\nemulator-5554,87\n"), 0644)
+
+	tool := NewEmailSendTool(config.EmailConfig{Host: "smtp.example.com", Username: "bot@example.com"}, workspace)
+	var gotFrom string
+	var gotTo []string
+	var gotMsg string
+	tool.send = func(ctx context.Context, from string, to []string, msg []byte) error {
+		gotFrom, gotTo, gotMsg = from, to, string(msg)
+		return nil
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{
+		"to":          []interface{}{"Alice <alice@example.com>"},
+		"bcc":         "audit@example.com",
+		"subject":     "Daily report\r\nBcc: evil@example.com",
+		"body":        "See attached.",
+		"attachments": []interface{}{"report.csv"},
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(result, "2 recipient(s) with 1 attachment(s)") {
+		t.Errorf("unexpected result: %s", result)
+	}
+	if gotFrom != "bot@example.com" || strings.Join(gotTo, ",") != "alice@example.com,audit@example.com" {
+		t.Errorf("unexpected envelope: from=%s to=%v", gotFrom, gotTo)
+	}
+
+	if strings.Contains(gotMsg, "audit@example.com") || strings.Contains(gotMsg, "\r\nBcc:") {
+		t.Errorf("bcc leaked into headers:\n%s", gotMsg)
+	}
+	for _, want := range []string{
+		`To: "Alice" <alice@example.com>`,
+		"Content-Type: multipart/mixed",
+		`Content-Disposition: attachment; filename=report.csv`,
+		base64.StdEncoding.EncodeToString([]byte("See attached.")),
+	} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message missing %q:\n%s", want, gotMsg)
+		}
+	}
+}
+
+func TestEmailSendValidation(t *testing.T) {
+	tool := NewEmailSendTool(config.EmailConfig{Host: "smtp.example.com", From: "bot@example.com"}, t.TempDir())
+	tool.send = func(ctx context.Context, from string, to []string, msg []byte) error { return nil }
+
+	cases := []map[string]interface{}{
+		{"to": []interface{}{}, "subject": "x", "body": "x"},
+		{"to": []interface{}{"not an address"}, "subject": "x", "body": "x"},
+		{"to": []interface{}{"a@example.com"}, "subject": "x", "body": "x", "attachments": []interface{}{"https://example.com/f.pdf"}},
+		{"to": []interface{}{"a@example.com"}, "subject": "x", "body": "x", "attachments": []interface{}{"missing.pdf"}},
+	}
+	for i, args := range cases {
+		if _, err := tool.Execute(context.Background(), args); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
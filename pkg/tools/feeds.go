@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/feeds"
+)
+
+// NewFeedSubscribeTool creates the feed_subscribe tool.
+func NewFeedSubscribeTool(store *feeds.Store) *FeedSubscribeTool {
+	return &FeedSubscribeTool{store: store}
+}
+
+// NewFeedUnsubscribeTool creates the feed_unsubscribe tool.
+func NewFeedUnsubscribeTool(store *feeds.Store) *FeedUnsubscribeTool {
+	return &FeedUnsubscribeTool{store: store}
+}
+
+// NewFeedListTool creates the feed_list tool.
+func NewFeedListTool(store *feeds.Store) *FeedListTool {
+	return &FeedListTool{store: store}
+}
+
+// chatFromSession splits a "{channel}:{chatID}" session key
+func chatFromSession(ctx context.Context) (string, string) {
+	channel, chatID, ok := strings.Cut(SessionKeyFromContext(ctx), ":")
+	if !ok {
+		return "", ""
+	}
+	return channel, chatID
+}
+
+// ==================== feed_subscribe ====================
+
+type FeedSubscribeTool struct {
+	store *feeds.Store
+}
+
+func (t *FeedSubscribeTool) Name() string { return "feed_subscribe" }
+
+func (t *FeedSubscribeTool) Description() string {
+	return "Subscribe to an RSS or Atom feed. The gateway polls it and delivers new items to the current chat, either as a notification (mode 'notify') or by handing them to an agent with instructions (mode 'agent'), e.g. 'tell me when this blog posts'."
+}
+
+func (t *FeedSubscribeTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Feed URL (RSS or Atom)",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{feeds.ModeNotify, feeds.ModeAgent},
+				"description": "notify: post new items as a list (default). agent: send new items to an agent to act on",
+			},
+			"instructions": map[string]interface{}{
+				"type":        "string",
+				"description": "For agent mode: what to do with new items (e.g. 'summarise in two sentences')",
+			},
+			"agent": map[string]interface{}{
+				"type":        "string",
+				"description": "For agent mode: agent that handles new items (default agent if omitted)",
+			},
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional display name for the feed",
+			},
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: delivery channel (defaults to the current conversation)",
+			},
+			"chat_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: delivery chat ID (defaults to the current conversation)",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *FeedSubscribeTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	url, _ := args["url"].(string)
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return "", fmt.Errorf("url is required")
+	}
+
+	channel, _ := args["channel"].(string)
+	chatID, _ := args["chat_id"].(string)
+	if channel == "" || chatID == "" {
+		channel, chatID = chatFromSession(ctx)
+	}
+	if channel == "" || chatID == "" {
+		return "", fmt.Errorf("channel and chat_id are required outside a chat conversation")
+	}
+
+	// Validate the feed before saving it
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	feed, err := feeds.Fetch(fetchCtx, http.DefaultClient, url)
+	if err != nil {
+		return "", fmt.Errorf("could not read feed: %w", err)
+	}
+
+	sub := feeds.Subscription{URL: url, Channel: channel, ChatID: chatID}
+	sub.Mode, _ = args["mode"].(string)
+	sub.Agent, _ = args["agent"].(string)
+	sub.Instructions, _ = args["instructions"].(string)
+	sub.Title, _ = args["title"].(string)
+	if sub.Title == "" {
+		sub.Title = feed.Title
+	}
+
+	saved, err := t.store.Add(sub)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Subscribed to '%s' (%s, %d current items). New items will be delivered to %s:%s in %s mode. Subscription ID: %s",
+		saved.Title, saved.URL, len(feed.Items), saved.Channel, saved.ChatID, saved.Mode, saved.ID), nil
+}
+
+// ==================== feed_unsubscribe ====================
+
+type FeedUnsubscribeTool struct {
+	store *feeds.Store
+}
+
+func (t *FeedUnsubscribeTool) Name() string { return "feed_unsubscribe" }
+
+func (t *FeedUnsubscribeTool) Description() string {
+	return "Stop watching a feed. Pass the subscription ID or the feed URL."
+}
+
+func (t *FeedUnsubscribeTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Subscription ID or feed URL",
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *FeedUnsubscribeTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	if id == "" {
+		return "", fmt.Errorf("id is required")
+	}
+	removed, err := t.store.Remove(id)
+	if err != nil {
+		return "", err
+	}
+	if !removed {
+		return fmt.Sprintf("No subscription matches '%s'", id), nil
+	}
+	return fmt.Sprintf("Unsubscribed from %s", id), nil
+}
+
+// ==================== feed_list ====================
+
+type FeedListTool struct {
+	store *feeds.Store
+}
+
+func (t *FeedListTool) Name() string { return "feed_list" }
+
+func (t *FeedListTool) Description() string {
+	return "List RSS/Atom feed subscriptions with their delivery target and last poll status."
+}
+
+func (t *FeedListTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *FeedListTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	subs, err := t.store.List()
+	if err != nil {
+		return "", err
+	}
+	if len(subs) == 0 {
+		return "No feed subscriptions", nil
+	}
+
+	var b strings.Builder
+	for _, sub := range subs {
+		fmt.Fprintf(&b, "- %s [%s]\n  %s -> %s:%s (%s)\n", sub.Title, sub.ID, sub.URL, sub.Channel, sub.ChatID, sub.Mode)
+		switch {
+		case sub.LastError != "":
+			fmt.Fprintf(&b, "  last error: %s\n", sub.LastError)
+		case !sub.LastCheckedAt.IsZero():
+			fmt.Fprintf(&b, "  last checked: %s\n", sub.LastCheckedAt.Format("2006-01-02 15:04"))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
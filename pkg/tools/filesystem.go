@@ -43,7 +43,7 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	// Resolve relative paths to workspace
-	path = t.resolvePath(path)
+	path = t.resolvePath(ctx, path)
 
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -65,6 +65,11 @@ func (t *WriteFileTool) Name() string {
 	return "write_file"
 }
 
+// Sequential prevents two calls in one batch from writing the same file at once
+func (t *WriteFileTool) Sequential() bool {
+	return true
+}
+
 func (t *WriteFileTool) Description() string {
 	return "Write content to a file"
 }
@@ -98,7 +103,7 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 	}
 
 	// Resolve relative paths to workspace
-	path = t.resolvePath(path)
+	path = t.resolvePath(ctx, path)
 
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -148,7 +153,7 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	// Resolve relative paths to workspace
-	path = t.resolvePath(path)
+	path = t.resolvePath(ctx, path)
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -167,26 +172,24 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 	return result, nil
 }
 
-// resolvePath resolves relative paths to workspace directory
-func (t *ReadFileTool) resolvePath(path string) string {
-	// If already absolute, return as-is
-	if filepath.IsAbs(path) {
-		return path
-	}
-	// Resolve relative to workspace
-	return filepath.Join(t.workspace, path)
+// resolvePath resolves relative paths to the session's working directory,
+// or to the workspace
+func (t *ReadFileTool) resolvePath(ctx context.Context, path string) string {
+	return resolveToolPath(ctx, t.workspace, path)
 }
 
-func (t *WriteFileTool) resolvePath(path string) string {
-	if filepath.IsAbs(path) {
-		return path
-	}
-	return filepath.Join(t.workspace, path)
+func (t *WriteFileTool) resolvePath(ctx context.Context, path string) string {
+	return resolveToolPath(ctx, t.workspace, path)
 }
 
-func (t *ListDirTool) resolvePath(path string) string {
+func (t *ListDirTool) resolvePath(ctx context.Context, path string) string {
+	return resolveToolPath(ctx, t.workspace, path)
+}
+
+func resolveToolPath(ctx context.Context, workspace, path string) string {
+	// If already absolute, return as-is
 	if filepath.IsAbs(path) {
 		return path
 	}
-	return filepath.Join(t.workspace, path)
+	return filepath.Join(WorkDir(ctx, workspace), path)
 }
@@ -0,0 +1,598 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+// maxDiffSize caps how much of a pull request diff is returned to the model
+const maxDiffSize = 60000
+
+// maxGitHubJSONSize caps JSON responses, well above 100 issues with long
+// bodies
+const maxGitHubJSONSize = 32 << 20
+
+// GitHubClient is a minimal GitHub REST API client shared by the github_* tools
+type GitHubClient struct {
+	token       string
+	apiBase     string
+	defaultRepo string
+	client      *http.Client
+}
+
+func NewGitHubClient(cfg config.GitHubToolsConfig) *GitHubClient {
+	apiBase := strings.TrimRight(cfg.APIBase, "/")
+	if apiBase == "" {
+		apiBase = "https://api.github.com"
+	}
+	return &GitHubClient{
+		token:       cfg.Token,
+		apiBase:     apiBase,
+		defaultRepo: cfg.DefaultRepo,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+var repoPattern = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// repo returns the "owner/name" from args or the configured default
+func (c *GitHubClient) repo(args map[string]interface{}) (string, error) {
+	repo, _ := args["repo"].(string)
+	repo = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(repo, "https://github.com/"), ".git"))
+	if repo == "" {
+		repo = c.defaultRepo
+	}
+	if repo == "" {
+		return "", fmt.Errorf("repo is required (owner/name)")
+	}
+	if !repoPattern.MatchString(repo) {
+		return "", fmt.Errorf("invalid repo '%s' (expected owner/name)", repo)
+	}
+	return repo, nil
+}
+
+// do performs an API request and decodes a JSON response into out (if non-nil)
+func (c *GitHubClient) do(ctx context.Context, method, path string, body interface{}, accept string, out interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiBase+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	// Raw media types (diffs) are cut for the model anyway; JSON must be
+	// read whole to parse
+	limit := int64(4 * maxDiffSize)
+	if accept == "" {
+		limit = maxGitHubJSONSize
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "pepebot")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		if out != nil {
+			return nil, fmt.Errorf("GitHub response to %s %s is over %s", method, path, formatBytes(limit))
+		}
+		data = data[:limit]
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("GitHub API %s %s: %d %s", method, path, resp.StatusCode, apiErr.Message)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub response: %w", err)
+		}
+	}
+	return data, nil
+}
+
+func githubNumber(args map[string]interface{}, key string) (int, error) {
+	switch v := args[key].(type) {
+	case float64:
+		return int(v), nil
+	case string:
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(v, "#"), "%d", &n); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s is required", key)
+}
+
+var githubRepoParam = map[string]interface{}{
+	"type":        "string",
+	"description": "Repository as owner/name (defaults to tools.github.default_repo)",
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubLabel struct {
+	Name string `json:"name"`
+}
+
+// ==================== github_list_issues ====================
+
+type GitHubListIssuesTool struct {
+	client *GitHubClient
+}
+
+func NewGitHubListIssuesTool(client *GitHubClient) *GitHubListIssuesTool {
+	return &GitHubListIssuesTool{client: client}
+}
+
+func (t *GitHubListIssuesTool) Name() string { return "github_list_issues" }
+
+func (t *GitHubListIssuesTool) Description() string {
+	return "List issues and pull requests in a GitHub repository, filtered by state, labels or assignee. Use it to triage a repo."
+}
+
+func (t *GitHubListIssuesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": githubRepoParam,
+			"state": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"open", "closed", "all"},
+				"description": "Issue state (default: open)",
+			},
+			"labels": map[string]interface{}{
+				"type":        "string",
+				"description": "Comma-separated label names",
+			},
+			"assignee": map[string]interface{}{
+				"type":        "string",
+				"description": "Login of the assignee, 'none' or '*'",
+			},
+			"type": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"all", "issue", "pr"},
+				"description": "Only issues or only pull requests (default: all)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum results (default: 20, max: 100)",
+			},
+		},
+	}
+}
+
+func (t *GitHubListIssuesTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := t.client.repo(args)
+	if err != nil {
+		return "", err
+	}
+
+	limit := 20
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := url.Values{}
+	query.Set("per_page", fmt.Sprintf("%d", limit))
+	query.Set("state", "open")
+	if state, _ := args["state"].(string); state != "" {
+		query.Set("state", state)
+	}
+	if labels, _ := args["labels"].(string); labels != "" {
+		query.Set("labels", labels)
+	}
+	if assignee, _ := args["assignee"].(string); assignee != "" {
+		query.Set("assignee", assignee)
+	}
+	kind, _ := args["type"].(string)
+
+	var issues []struct {
+		Number      int           `json:"number"`
+		Title       string        `json:"title"`
+		State       string        `json:"state"`
+		User        githubUser    `json:"user"`
+		Labels      []githubLabel `json:"labels"`
+		Assignees   []githubUser  `json:"assignees"`
+		Comments    int           `json:"comments"`
+		UpdatedAt   time.Time     `json:"updated_at"`
+		PullRequest *struct{}     `json:"pull_request"`
+	}
+	if _, err := t.client.do(ctx, http.MethodGet, "/repos/"+repo+"/issues?"+query.Encode(), nil, "", &issues); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	count := 0
+	for _, issue := range issues {
+		isPR := issue.PullRequest != nil
+		if (kind == "issue" && isPR) || (kind == "pr" && !isPR) {
+			continue
+		}
+		count++
+
+		label := "issue"
+		if isPR {
+			label = "PR"
+		}
+		fmt.Fprintf(&b, "#%d [%s, %s] %s\n", issue.Number, label, issue.State, issue.Title)
+		fmt.Fprintf(&b, "  by @%s, %d comments, updated %s", issue.User.Login, issue.Comments, issue.UpdatedAt.Format("2006-01-02"))
+		if len(issue.Labels) > 0 {
+			names := make([]string, len(issue.Labels))
+			for i, l := range issue.Labels {
+				names[i] = l.Name
+			}
+			fmt.Fprintf(&b, ", labels: %s", strings.Join(names, ", "))
+		}
+		if len(issue.Assignees) > 0 {
+			logins := make([]string, len(issue.Assignees))
+			for i, a := range issue.Assignees {
+				logins[i] = "@" + a.Login
+			}
+			fmt.Fprintf(&b, ", assigned: %s", strings.Join(logins, " "))
+		}
+		b.WriteString("\n")
+	}
+
+	if count == 0 {
+		return fmt.Sprintf("No matching issues in %s", repo), nil
+	}
+	return fmt.Sprintf("%d result(s) in %s:\n%s", count, repo, strings.TrimRight(b.String(), "\n")), nil
+}
+
+// ==================== github_create_issue ====================
+
+type GitHubCreateIssueTool struct {
+	client *GitHubClient
+}
+
+func NewGitHubCreateIssueTool(client *GitHubClient) *GitHubCreateIssueTool {
+	return &GitHubCreateIssueTool{client: client}
+}
+
+func (t *GitHubCreateIssueTool) Name() string { return "github_create_issue" }
+
+func (t *GitHubCreateIssueTool) Description() string {
+	return "Create a new issue in a GitHub repository."
+}
+
+func (t *GitHubCreateIssueTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": githubRepoParam,
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Issue title",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Issue description (markdown)",
+			},
+			"labels": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Labels to apply",
+			},
+			"assignees": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Logins to assign",
+			},
+		},
+		"required": []string{"title"},
+	}
+}
+
+func (t *GitHubCreateIssueTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := t.client.repo(args)
+	if err != nil {
+		return "", err
+	}
+	title, _ := args["title"].(string)
+	if strings.TrimSpace(title) == "" {
+		return "", fmt.Errorf("title is required")
+	}
+
+	payload := map[string]interface{}{"title": title}
+	if body, _ := args["body"].(string); body != "" {
+		payload["body"] = body
+	}
+	if labels := stringList(args["labels"]); len(labels) > 0 {
+		payload["labels"] = labels
+	}
+	if assignees := stringList(args["assignees"]); len(assignees) > 0 {
+		payload["assignees"] = assignees
+	}
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if _, err := t.client.do(ctx, http.MethodPost, "/repos/"+repo+"/issues", payload, "", &created); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Created issue #%d: %s", created.Number, created.HTMLURL), nil
+}
+
+func stringList(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		if s, ok := v.(string); ok && s != "" {
+			return strings.Split(s, ",")
+		}
+		return nil
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			out = append(out, strings.TrimSpace(s))
+		}
+	}
+	return out
+}
+
+// ==================== github_comment ====================
+
+type GitHubCommentTool struct {
+	client *GitHubClient
+}
+
+func NewGitHubCommentTool(client *GitHubClient) *GitHubCommentTool {
+	return &GitHubCommentTool{client: client}
+}
+
+func (t *GitHubCommentTool) Name() string { return "github_comment" }
+
+func (t *GitHubCommentTool) Description() string {
+	return "Comment on a GitHub issue or pull request."
+}
+
+func (t *GitHubCommentTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": githubRepoParam,
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Issue or pull request number",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Comment text (markdown)",
+			},
+		},
+		"required": []string{"number", "body"},
+	}
+}
+
+func (t *GitHubCommentTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := t.client.repo(args)
+	if err != nil {
+		return "", err
+	}
+	number, err := githubNumber(args, "number")
+	if err != nil {
+		return "", err
+	}
+	body, _ := args["body"].(string)
+	if strings.TrimSpace(body) == "" {
+		return "", fmt.Errorf("body is required")
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number)
+	if _, err := t.client.do(ctx, http.MethodPost, path, map[string]string{"body": body}, "", &created); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Commented on #%d: %s", number, created.HTMLURL), nil
+}
+
+// ==================== github_pr_diff ====================
+
+type GitHubPRDiffTool struct {
+	client *GitHubClient
+}
+
+func NewGitHubPRDiffTool(client *GitHubClient) *GitHubPRDiffTool {
+	return &GitHubPRDiffTool{client: client}
+}
+
+func (t *GitHubPRDiffTool) Name() string { return "github_pr_diff" }
+
+func (t *GitHubPRDiffTool) Description() string {
+	return "Get a pull request's description, status and unified diff for review."
+}
+
+func (t *GitHubPRDiffTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": githubRepoParam,
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Pull request number",
+			},
+		},
+		"required": []string{"number"},
+	}
+}
+
+func (t *GitHubPRDiffTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := t.client.repo(args)
+	if err != nil {
+		return "", err
+	}
+	number, err := githubNumber(args, "number")
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d", repo, number)
+
+	var pr struct {
+		Title        string     `json:"title"`
+		Body         string     `json:"body"`
+		State        string     `json:"state"`
+		Draft        bool       `json:"draft"`
+		Merged       bool       `json:"merged"`
+		Mergeable    *bool      `json:"mergeable"`
+		User         githubUser `json:"user"`
+		Additions    int        `json:"additions"`
+		Deletions    int        `json:"deletions"`
+		ChangedFiles int        `json:"changed_files"`
+		Head         struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if _, err := t.client.do(ctx, http.MethodGet, path, nil, "", &pr); err != nil {
+		return "", err
+	}
+
+	diff, err := t.client.do(ctx, http.MethodGet, path, nil, "application/vnd.github.diff", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "PR #%d: %s\n", number, pr.Title)
+	status := pr.State
+	switch {
+	case pr.Merged:
+		status = "merged"
+	case pr.Draft:
+		status += ", draft"
+	}
+	if pr.Mergeable != nil && !*pr.Mergeable {
+		status += ", has conflicts"
+	}
+	fmt.Fprintf(&b, "Author: @%s | %s <- %s | %s\n", pr.User.Login, pr.Base.Ref, pr.Head.Ref, status)
+	fmt.Fprintf(&b, "%d files changed, +%d -%d\n", pr.ChangedFiles, pr.Additions, pr.Deletions)
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		fmt.Fprintf(&b, "\n%s\n", body)
+	}
+
+	text := string(diff)
+	if len(text) > maxDiffSize {
+		text = text[:maxDiffSize] + fmt.Sprintf("\n... (diff truncated, %d bytes total)", len(diff))
+	}
+	fmt.Fprintf(&b, "\n```diff\n%s\n```", strings.TrimRight(text, "\n"))
+	return b.String(), nil
+}
+
+// ==================== github_merge_pr ====================
+
+type GitHubMergePRTool struct {
+	client *GitHubClient
+}
+
+func NewGitHubMergePRTool(client *GitHubClient) *GitHubMergePRTool {
+	return &GitHubMergePRTool{client: client}
+}
+
+func (t *GitHubMergePRTool) Name() string { return "github_merge_pr" }
+
+func (t *GitHubMergePRTool) Description() string {
+	return "Merge a GitHub pull request. Only merge when the user has explicitly asked for it."
+}
+
+func (t *GitHubMergePRTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": githubRepoParam,
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Pull request number",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"merge", "squash", "rebase"},
+				"description": "Merge method (default: merge)",
+			},
+			"commit_title": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional commit title",
+			},
+		},
+		"required": []string{"number"},
+	}
+}
+
+func (t *GitHubMergePRTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := t.client.repo(args)
+	if err != nil {
+		return "", err
+	}
+	number, err := githubNumber(args, "number")
+	if err != nil {
+		return "", err
+	}
+
+	payload := map[string]string{"merge_method": "merge"}
+	if method, _ := args["method"].(string); method != "" {
+		payload["merge_method"] = method
+	}
+	if title, _ := args["commit_title"].(string); title != "" {
+		payload["commit_title"] = title
+	}
+
+	var merged struct {
+		SHA     string `json:"sha"`
+		Merged  bool   `json:"merged"`
+		Message string `json:"message"`
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d/merge", repo, number)
+	if _, err := t.client.do(ctx, http.MethodPut, path, payload, "", &merged); err != nil {
+		return "", err
+	}
+	if !merged.Merged {
+		return "", fmt.Errorf("PR #%d was not merged: %s", number, merged.Message)
+	}
+	return fmt.Sprintf("Merged PR #%d (%s)", number, merged.SHA), nil
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+)
+
+func newTestGitHub(t *testing.T, handler http.HandlerFunc) *GitHubClient {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewGitHubClient(config.GitHubToolsConfig{Token: "pat", APIBase: srv.URL, DefaultRepo: "acme/app"})
+}
+
+func TestGitHubListIssues(t *testing.T) {
+	client := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			t.Errorf("missing token")
+		}
+		if r.URL.Path != "/repos/acme/app/issues" || r.URL.Query().Get("state") != "open" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`[
+			{"number":1,"title":"Crash on start","state":"open","user":{"login":"ann"},"labels":[{"name":"bug"}],"updated_at":"2026-01-02T00:00:00Z"},
+			{"number":2,"title":"Add flag","state":"open","user":{"login":"bob"},"pull_request":{},"updated_at":"2026-01-03T00:00:00Z"}
+		]`))
+	})
+
+	out, err := NewGitHubListIssuesTool(client).Execute(context.Background(), map[string]interface{}{"type": "issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "#1 [issue, open] Crash on start") || !strings.Contains(out, "labels: bug") || strings.Contains(out, "#2") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestGitHubListIssuesLargeResponse(t *testing.T) {
+	body := strings.Repeat("Steps to reproduce. ", 200)
+	client := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		var issues []string
+		for i := 1; i <= 100; i++ {
+			issues = append(issues, fmt.Sprintf(`{"number":%d,"title":"Issue %d","state":"open","body":%q,"user":{"login":"ann"}}`, i, i, body))
+		}
+		w.Write([]byte("[" + strings.Join(issues, ",") + "]"))
+	})
+
+	out, err := NewGitHubListIssuesTool(client).Execute(context.Background(), map[string]interface{}{"limit": float64(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "#100 ") {
+		t.Errorf("last issue missing:\n%s", out)
+	}
+}
+
+func TestGitHubPRDiffAndErrors(t *testing.T) {
+	client := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/acme/app/pulls/7" && r.Header.Get("Accept") == "application/vnd.github.diff":
+			w.Write([]byte("diff --git a/x b/x\n+hello\n"))
+		case r.URL.Path == "/repos/acme/app/pulls/7":
+			w.Write([]byte(`{"title":"Greet","state":"open","user":{"login":"ann"},"head":{"ref":"greet"},"base":{"ref":"main"},"additions":1,"changed_files":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	})
+
+	out, err := NewGitHubPRDiffTool(client).Execute(context.Background(), map[string]interface{}{"number": float64(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "PR #7: Greet") || !strings.Contains(out, "main <- greet") || !strings.Contains(out, "+hello") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	_, err = NewGitHubCommentTool(client).Execute(context.Background(), map[string]interface{}{"number": "#9", "body": "hi"})
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected API error, got %v", err)
+	}
+
+	if _, err := client.repo(map[string]interface{}{"repo": "not a repo"}); err == nil {
+		t.Error("invalid repo accepted")
+	}
+}
@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/audit"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/workflow"
+)
+
+// DefaultMaxWriteBytes is the largest content a single file tool call may
+// write
+const DefaultMaxWriteBytes = 10 << 20
+
+// guardedPaths are the path arguments the guard checks, per tool. An
+// argument may hold one path or a list of them.
+var guardedPaths = map[string][]string{
+	"read_file":        {"path"},
+	"list_dir":         {"path"},
+	"write_file":       {"path"},
+	"edit_file":        {"path"},
+	"append_file":      {"path"},
+	"csv_read":         {"path"},
+	"csv_write":        {"path"},
+	"csv_query":        {"path", "output"},
+	"archive_create":   {"path", "sources"},
+	"archive_extract":  {"path", "dest"},
+	"download_file":    {"path"},
+	"email_send":       {"attachments"},
+	"telegram_send":    {"file_path"},
+	"discord_send":     {"file_path"},
+	"whatsapp_send":    {"file_path"},
+	"send_file":        {"file_url"},
+	"send_image":       {"image_url"},
+	"adb_screenshot":   {"filename"},
+	"adb_scrcpy_start": {"filename"},
+	"screen_locate":    {"image"},
+	"report_generate":  {"images"},
+}
+
+// readOnlyPaths are the path arguments that are only read. The extra read
+// directories apply to these alone, so paths tools write to, and arguments
+// missing here, stay in the workspace and the allowed directories.
+var readOnlyPaths = map[string]bool{
+	"read_file.path":          true,
+	"list_dir.path":           true,
+	"csv_read.path":           true,
+	"archive_create.sources":  true,
+	"archive_extract.path":    true,
+	"csv_query.path":          true,
+	"email_send.attachments":  true,
+	"telegram_send.file_path": true,
+	"discord_send.file_path":  true,
+	"whatsapp_send.file_path": true,
+	"send_file.file_url":      true,
+	"send_image.image_url":    true,
+	"screen_locate.image":     true,
+	"report_generate.images":  true,
+}
+
+// writeContent is the argument holding the text a tool writes
+var writeContent = map[string]string{
+	"write_file":  "content",
+	"edit_file":   "new_text",
+	"append_file": "content",
+}
+
+// auditedTools change files or run commands, with the argument naming
+// what they change
+var auditedTools = map[string]string{
+	"write_file":      "path",
+	"edit_file":       "path",
+	"append_file":     "path",
+	"csv_write":       "path",
+	"csv_query":       "output",
+	"archive_create":  "path",
+	"archive_extract": "dest",
+	"download_file":   "path",
+	"exec":            "",
+}
+
+// FileGuard is the filesystem policy of the agent's tools. Set on a
+// ToolRegistry, it checks the paths and sizes of file tool calls before they
+// run and records file changes and commands in the audit log.
+type FileGuard struct {
+	workspace string
+	restrict  bool
+	allow     []string // extra directories the file tools may use
+	allowRead []string // extra directories the file tools may read
+	maxWrite  int
+	audit     *audit.Log
+}
+
+func NewFileGuard(workspace string) *FileGuard {
+	return &FileGuard{
+		workspace: workspace,
+		maxWrite:  DefaultMaxWriteBytes,
+	}
+}
+
+// NewFileGuardFromConfig builds the guard for an agent working in
+// workspace. home is the pepebot home directory, which holds the audit log.
+func NewFileGuardFromConfig(cfg config.FilesystemConfig, workspace, home string) *FileGuard {
+	g := NewFileGuard(workspace)
+	g.SetRestrictToWorkspace(cfg.RestrictToWorkspace, cfg.AllowPaths)
+	// Builtin skill files are listed in the prompt by their location
+	g.AllowRead(filepath.Join(filepath.Dir(workspace), "pepebot", "skills"))
+	if cfg.MaxWriteMB > 0 {
+		g.SetMaxWriteBytes(cfg.MaxWriteMB << 20)
+	}
+	if cfg.Audit {
+		g.SetAuditLog(audit.New(filepath.Join(home, audit.FileName)))
+	}
+	return g
+}
+
+// SetRestrictToWorkspace denies file tool paths outside the workspace and
+// the allowed directories
+func (g *FileGuard) SetRestrictToWorkspace(restrict bool, allow []string) {
+	g.restrict = restrict
+	g.allow = cleanDirs(allow)
+}
+
+// AllowRead lets the file tools read from dirs even when restricted
+func (g *FileGuard) AllowRead(dirs ...string) {
+	g.allowRead = append(g.allowRead, cleanDirs(dirs)...)
+}
+
+// SetMaxWriteBytes limits the content of a single write; zero or less
+// removes the limit
+func (g *FileGuard) SetMaxWriteBytes(n int) {
+	g.maxWrite = n
+}
+
+// SetAuditLog records file changes and commands in log
+func (g *FileGuard) SetAuditLog(log *audit.Log) {
+	g.audit = log
+}
+
+// Restricted reports whether file tools are confined to the workspace
+func (g *FileGuard) Restricted() bool {
+	return g.restrict
+}
+
+// Check returns why a tool call is not allowed, or nil
+func (g *FileGuard) Check(ctx context.Context, name string, args map[string]interface{}) error {
+	if key, ok := writeContent[name]; ok && g.maxWrite > 0 {
+		if content, _ := args[key].(string); len(content) > g.maxWrite {
+			return fmt.Errorf("%s is %s, over the %s write limit", key, formatBytes(int64(len(content))), formatBytes(int64(g.maxWrite)))
+		}
+	}
+
+	// With session isolation the session directory is the tool's own;
+	// other sessions' directories are off limits even when unrestricted
+	keys, ok := guardedPaths[name]
+	if !ok || (!g.restrict && WorkDir(ctx, "") == "") {
+		return nil
+	}
+	for _, key := range keys {
+		for _, p := range pathArgs(args[key]) {
+			if err := g.checkPath(ctx, name, key, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPath returns an error when p, the key argument of the named tool,
+// is outside the directories the tool may use
+func (g *FileGuard) checkPath(ctx context.Context, name, key, p string) error {
+	if isRemotePath(p) {
+		return nil
+	}
+	resolved := realPath(g.resolve(ctx, name, p))
+	if session := WorkDir(ctx, ""); session != "" {
+		sessions := realPath(filepath.Join(g.workspace, "sessions"))
+		if within(sessions, resolved) && !within(realPath(session), resolved) {
+			return fmt.Errorf("access denied: %s is in another session's directory", p)
+		}
+	}
+	if !g.restrict {
+		return nil
+	}
+	dirs := append([]string{g.workspace}, g.allow...)
+	if readOnlyPaths[name+"."+key] {
+		dirs = append(dirs, g.allowRead...)
+	}
+	for _, dir := range dirs {
+		if within(realPath(dir), resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("access denied: %s is outside the workspace", p)
+}
+
+// pathArgs returns the non-empty paths of a string or list argument
+func pathArgs(v interface{}) []string {
+	var paths []string
+	switch v := v.(type) {
+	case string:
+		paths = append(paths, v)
+	case []string:
+		paths = append(paths, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				paths = append(paths, s)
+			}
+		}
+	}
+	out := paths[:0]
+	for _, p := range paths {
+		if strings.TrimSpace(p) != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// isRemotePath reports whether a send tool argument is a URL rather than a
+// local file
+func isRemotePath(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") || strings.HasPrefix(p, "data:")
+}
+
+// Record adds a finished tool call to the audit log, if it changes files or
+// runs a command
+func (g *FileGuard) Record(ctx context.Context, name string, args map[string]interface{}, result string, callErr error) {
+	key, ok := auditedTools[name]
+	if !ok || g.audit == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Session: SessionKeyFromContext(ctx),
+		Tool:    name,
+		Status:  audit.StatusOK,
+	}
+	if key != "" {
+		p, _ := args[key].(string)
+		if p == "" && name == "csv_query" {
+			return // a query without output changes nothing
+		}
+		if p != "" {
+			entry.Path = g.resolve(ctx, name, p)
+		}
+	}
+	if name == "exec" {
+		entry.Command, _ = args["command"].(string)
+		entry.Dir = WorkDir(ctx, g.workspace)
+		if wd, _ := args["working_dir"].(string); wd != "" {
+			entry.Dir = wd
+		}
+	}
+	if content, ok := args[writeContent[name]].(string); ok {
+		entry.Bytes = len(content)
+	}
+	if callErr == nil && strings.HasPrefix(result, "Error: Command blocked") {
+		entry.Status = audit.StatusDenied
+		entry.Error = strings.TrimPrefix(result, "Error: ")
+	}
+	if callErr != nil {
+		entry.Status = audit.StatusError
+		if strings.HasPrefix(callErr.Error(), "access denied") || strings.Contains(callErr.Error(), "write limit") {
+			entry.Status = audit.StatusDenied
+		}
+		entry.Error = callErr.Error()
+	}
+
+	// Workflow tool steps run with their {{secret:NAME}} references resolved;
+	// the log keeps the references, never the values
+	entry.Path = workflow.RedactSecrets(ctx, entry.Path)
+	entry.Command = workflow.RedactSecrets(ctx, entry.Command)
+	entry.Dir = workflow.RedactSecrets(ctx, entry.Dir)
+	entry.Error = workflow.RedactSecrets(ctx, entry.Error)
+
+	if err := g.audit.Record(entry); err != nil {
+		logger.WarnCF("tools", "Failed to write audit log", map[string]interface{}{
+			"path":  g.audit.Path(),
+			"error": err.Error(),
+		})
+	}
+}
+
+// resolve makes p absolute the way the named tool does
+func (g *FileGuard) resolve(ctx context.Context, name, p string) string {
+	switch name {
+	case "email_send", "telegram_send", "discord_send", "whatsapp_send", "send_file", "send_image":
+		// These search a few places for the file, check the one they pick
+		return filepath.Clean(resolveFilePath(p, WorkDir(ctx, g.workspace)))
+	}
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	switch name {
+	case "edit_file", "append_file":
+		// These resolve relative paths against the process directory
+		if dir := WorkDir(ctx, ""); dir != "" {
+			return filepath.Join(dir, p)
+		}
+		abs, _ := filepath.Abs(p)
+		return abs
+	case "adb_screenshot", "adb_scrcpy_start", "screen_locate":
+		return filepath.Join(g.workspace, p)
+	}
+	return filepath.Join(WorkDir(ctx, g.workspace), p)
+}
+
+// realPath resolves the symlinks in the existing part of p, so links inside
+// the workspace can't point the tools elsewhere
+func realPath(p string) string {
+	p, _ = filepath.Abs(p)
+	rest := ""
+	for dir := p; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest)
+		}
+		if filepath.Dir(dir) == dir {
+			return p
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+func cleanDirs(dirs []string) []string {
+	var out []string
+	for _, d := range dirs {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if d == "~" || strings.HasPrefix(d, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				d = filepath.Join(home, d[1:])
+			}
+		}
+		out = append(out, filepath.Clean(d))
+	}
+	return out
+}
@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/audit"
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/secrets"
+	"github.com/pepebot-space/pepebot/pkg/session"
+	"github.com/pepebot-space/pepebot/pkg/workflow"
+)
+
+func TestFileGuard(t *testing.T) {
+	home := t.TempDir()
+	workspace := filepath.Join(home, "workspace")
+	shared := filepath.Join(home, "shared")
+	outside := filepath.Join(home, "outside")
+	for _, dir := range []string{workspace, shared, outside} {
+		os.MkdirAll(dir, 0755)
+	}
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+
+	registry := NewToolRegistry()
+	registry.Register(NewReadFileTool(workspace))
+	registry.Register(NewWriteFileTool(workspace))
+	registry.Register(NewExecTool(workspace))
+	registry.SetGuard(NewFileGuardFromConfig(config.FilesystemConfig{
+		RestrictToWorkspace: true,
+		AllowPaths:          []string{shared},
+		MaxWriteMB:          1,
+		Audit:               true,
+	}, workspace, home))
+
+	ctx := WithSessionKey(context.Background(), "telegram:42")
+	write := func(path, content string) error {
+		_, err := registry.Execute(ctx, "write_file", map[string]interface{}{"path": path, "content": content})
+		return err
+	}
+
+	if err := write("notes.txt", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(filepath.Join(shared, "ok.txt"), "hi"); err != nil {
+		t.Errorf("allow-listed path denied: %v", err)
+	}
+	if err := write("../outside/x.txt", "hi"); err == nil {
+		t.Error("write outside the workspace allowed")
+	}
+	if _, err := registry.Execute(ctx, "read_file", map[string]interface{}{"path": filepath.Join(outside, "secret.txt")}); err == nil {
+		t.Error("read outside the workspace allowed")
+	}
+	if err := write("big.txt", strings.Repeat("x", 2<<20)); err == nil || !strings.Contains(err.Error(), "write limit") {
+		t.Errorf("oversized write = %v", err)
+	}
+
+	// A symlink inside the workspace can't lead outside it
+	if runtime.GOOS != "windows" {
+		os.Symlink(outside, filepath.Join(workspace, "link"))
+		if _, err := registry.Execute(ctx, "read_file", map[string]interface{}{"path": "link/secret.txt"}); err == nil {
+			t.Error("read through a symlink allowed")
+		}
+		registry.Execute(ctx, "exec", map[string]interface{}{"command": "echo hi"})
+	}
+
+	// Every tool that takes a path is checked, also list arguments
+	registry.Register(NewArchiveCreateTool(workspace))
+	registry.Register(NewEmailSendTool(config.EmailConfig{Host: "smtp.example.com", From: "bot@example.com"}, workspace))
+	if _, err := registry.Execute(ctx, "archive_create", map[string]interface{}{
+		"path": "out.zip", "sources": []interface{}{"notes.txt", filepath.Join(outside, "secret.txt")},
+	}); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("archiving a file outside the workspace = %v", err)
+	}
+	if _, err := registry.Execute(ctx, "email_send", map[string]interface{}{
+		"to": []interface{}{"a@example.com"}, "subject": "s", "body": "b", "attachments": []interface{}{filepath.Join(outside, "secret.txt")},
+	}); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("attaching a file outside the workspace = %v", err)
+	}
+
+	entries, err := audit.Query(filepath.Join(home, audit.FileName), audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var denied, execs int
+	for _, e := range entries {
+		if e.Session != "telegram:42" {
+			t.Errorf("entry without session: %+v", e)
+		}
+		if e.Status == audit.StatusDenied {
+			denied++
+		}
+		if e.Tool == "exec" {
+			execs++
+		}
+		if e.Tool == "read_file" {
+			t.Errorf("reads should not be audited: %+v", e)
+		}
+	}
+	if entries[0].Path != filepath.Join(workspace, "notes.txt") || entries[0].Bytes != 2 {
+		t.Errorf("first entry = %+v", entries[0])
+	}
+	if denied != 3 || (runtime.GOOS != "windows" && execs != 1) {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestFileGuardReadOnlyDirs(t *testing.T) {
+	home := t.TempDir()
+	workspace := filepath.Join(home, "workspace")
+	skills := filepath.Join(home, "skills")
+	os.MkdirAll(workspace, 0755)
+	os.MkdirAll(skills, 0755)
+	os.WriteFile(filepath.Join(skills, "data.csv"), []byte("a,b\n1,2\n"), 0644)
+
+	guard := NewFileGuard(workspace)
+	guard.SetRestrictToWorkspace(true, nil)
+	guard.AllowRead(skills)
+	registry := NewToolRegistry()
+	registry.Register(NewCSVReadTool(workspace))
+	registry.Register(NewCSVWriteTool(workspace))
+	registry.SetGuard(guard)
+
+	ctx := context.Background()
+	if _, err := registry.Execute(ctx, "csv_read", map[string]interface{}{"path": filepath.Join(skills, "data.csv")}); err != nil {
+		t.Errorf("reading a read-only directory denied: %v", err)
+	}
+	_, err := registry.Execute(ctx, "csv_write", map[string]interface{}{
+		"path": filepath.Join(skills, "data.csv"), "columns": []interface{}{"a", "b"}, "rows": []interface{}{[]interface{}{"3", "4"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("csv_write into a read-only directory = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(skills, "data.csv")); string(data) != "a,b\n1,2\n" {
+		t.Errorf("read-only file changed: %q", data)
+	}
+}
+
+func TestFileGuardReportImages(t *testing.T) {
+	home := t.TempDir()
+	workspace := filepath.Join(home, "workspace")
+	os.MkdirAll(workspace, 0755)
+	os.WriteFile(filepath.Join(workspace, "shot.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(home, "id_rsa"), []byte("key"), 0600)
+
+	sessions := session.NewSessionManager("")
+	sessions.AddMessage("telegram:42", "user", "make a report")
+	guard := NewFileGuard(workspace)
+	guard.SetRestrictToWorkspace(true, nil)
+	registry := NewToolRegistry()
+	registry.Register(NewReportGenerateTool(sessions, bus.NewMessageBus(), workspace))
+	registry.SetGuard(guard)
+
+	ctx := WithSessionKey(context.Background(), "telegram:42")
+	if _, err := registry.Execute(ctx, "report_generate", map[string]interface{}{
+		"format": "html", "images": []interface{}{"shot.png"},
+	}); err != nil {
+		t.Errorf("report with a workspace image: %v", err)
+	}
+	_, err := registry.Execute(ctx, "report_generate", map[string]interface{}{
+		"format": "html", "images": []interface{}{filepath.Join(home, "id_rsa")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("embedding a file outside the workspace = %v", err)
+	}
+}
+
+func TestFileGuardRedactsWorkflowSecrets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses echo")
+	}
+	home := t.TempDir()
+	workspace := filepath.Join(home, "workspace")
+	os.MkdirAll(workspace, 0755)
+	if err := secrets.NewStore(home).Set("API_TOKEN", "tok-5f2a"); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewToolRegistry()
+	registry.Register(NewExecTool(workspace))
+	registry.SetGuard(NewFileGuardFromConfig(config.FilesystemConfig{Audit: true}, workspace, home))
+
+	wf := &workflow.WorkflowDefinition{
+		Name:  "deploy",
+		Steps: []workflow.WorkflowStep{{Name: "call", Tool: "exec", Args: map[string]interface{}{"command": "echo {{secret:API_TOKEN}}"}}},
+	}
+	if _, err := workflow.NewWorkflowHelper(workspace, registry).ExecuteWorkflow(context.Background(), wf, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := audit.Query(filepath.Join(home, audit.FileName), audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Command != "echo {{secret:API_TOKEN}}" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Host tools reach the desktop pepebot runs on: its clipboard and its
+// notification center. They use the platform's command line utilities.
+
+const (
+	hostCommandTimeout = 10 * time.Second
+	maxClipboardChars  = 20000
+)
+
+// hostCommand is an external command, with its arguments
+type hostCommand []string
+
+// HostHelper runs the clipboard and notification commands of this machine
+type HostHelper struct {
+	copy   hostCommand // reads the text to copy from stdin
+	paste  hostCommand
+	notify func(title, message string) hostCommand
+}
+
+// NewHostHelper finds the clipboard and notification commands of this
+// machine. It fails when there is neither.
+func NewHostHelper() (*HostHelper, error) {
+	h := &HostHelper{}
+	switch runtime.GOOS {
+	case "darwin":
+		h.copy = hostCommand{"pbcopy"}
+		h.paste = hostCommand{"pbpaste"}
+		h.notify = func(title, message string) hostCommand {
+			script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+			return hostCommand{"osascript", "-e", script}
+		}
+	case "windows":
+		h.copy = hostCommand{"powershell", "-NoProfile", "-Command", "$input | Set-Clipboard"}
+		h.paste = hostCommand{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}
+		h.notify = func(title, message string) hostCommand {
+			script := `Add-Type -AssemblyName System.Windows.Forms;` +
+				`$n = New-Object System.Windows.Forms.NotifyIcon;` +
+				`$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true;` +
+				fmt.Sprintf(`$n.ShowBalloonTip(10000, %s, %s, 'Info'); Start-Sleep -Seconds 5; $n.Dispose()`, powerShellString(title), powerShellString(message))
+			return hostCommand{"powershell", "-NoProfile", "-Command", script}
+		}
+	default:
+		switch {
+		case os.Getenv("WAYLAND_DISPLAY") != "" && hasCommand("wl-copy"):
+			h.copy = hostCommand{"wl-copy"}
+			h.paste = hostCommand{"wl-paste", "--no-newline"}
+		case hasCommand("xclip"):
+			h.copy = hostCommand{"xclip", "-selection", "clipboard"}
+			h.paste = hostCommand{"xclip", "-selection", "clipboard", "-o"}
+		case hasCommand("xsel"):
+			h.copy = hostCommand{"xsel", "--clipboard", "--input"}
+			h.paste = hostCommand{"xsel", "--clipboard", "--output"}
+		case hasCommand("termux-clipboard-get"):
+			h.copy = hostCommand{"termux-clipboard-set"}
+			h.paste = hostCommand{"termux-clipboard-get"}
+		}
+		switch {
+		case hasCommand("notify-send"):
+			h.notify = func(title, message string) hostCommand {
+				return hostCommand{"notify-send", "--app-name=pepebot", "--", title, message}
+			}
+		case hasCommand("termux-notification"):
+			h.notify = func(title, message string) hostCommand {
+				return hostCommand{"termux-notification", "--title", title, "--content", message}
+			}
+		}
+	}
+
+	if h.copy == nil && h.notify == nil {
+		return nil, fmt.Errorf("no clipboard or notification command found")
+	}
+	return h, nil
+}
+
+// HasClipboard reports whether the clipboard can be used
+func (h *HostHelper) HasClipboard() bool {
+	return h.copy != nil
+}
+
+// HasNotify reports whether desktop notifications can be shown
+func (h *HostHelper) HasNotify() bool {
+	return h.notify != nil
+}
+
+func (h *HostHelper) run(ctx context.Context, command hostCommand, stdin string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, hostCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s timed out", command[0])
+		}
+		return "", fmt.Errorf("%s failed: %w: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// powerShellString quotes s as a single-quoted PowerShell string
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+type HostClipboardGetTool struct {
+	helper *HostHelper
+}
+
+func NewHostClipboardGetTool(helper *HostHelper) *HostClipboardGetTool {
+	return &HostClipboardGetTool{helper: helper}
+}
+
+func (t *HostClipboardGetTool) Name() string {
+	return "host_clipboard_get"
+}
+
+func (t *HostClipboardGetTool) Description() string {
+	return "Read the text on the clipboard of the computer pepebot runs on."
+}
+
+func (t *HostClipboardGetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *HostClipboardGetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	text, err := t.helper.run(ctx, t.helper.paste, "")
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "(clipboard is empty)", nil
+	}
+	if len(text) > maxClipboardChars {
+		text = text[:maxClipboardChars] + fmt.Sprintf("\n... (truncated, %d more chars)", len(text)-maxClipboardChars)
+	}
+	return text, nil
+}
+
+type HostClipboardSetTool struct {
+	helper *HostHelper
+}
+
+func NewHostClipboardSetTool(helper *HostHelper) *HostClipboardSetTool {
+	return &HostClipboardSetTool{helper: helper}
+}
+
+func (t *HostClipboardSetTool) Name() string {
+	return "host_clipboard_set"
+}
+
+func (t *HostClipboardSetTool) Description() string {
+	return "Put text on the clipboard of the computer pepebot runs on, ready to paste."
+}
+
+func (t *HostClipboardSetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to copy",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+func (t *HostClipboardSetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	text, ok := args["text"].(string)
+	if !ok || text == "" {
+		return "", fmt.Errorf("text is required")
+	}
+	if _, err := t.helper.run(ctx, t.helper.copy, text); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Copied %d characters to the clipboard", len(text)), nil
+}
+
+type HostNotifyTool struct {
+	helper *HostHelper
+}
+
+func NewHostNotifyTool(helper *HostHelper) *HostNotifyTool {
+	return &HostNotifyTool{helper: helper}
+}
+
+func (t *HostNotifyTool) Name() string {
+	return "host_notify"
+}
+
+func (t *HostNotifyTool) Description() string {
+	return "Show a desktop notification on the computer pepebot runs on, e.g. when a long task finishes."
+}
+
+func (t *HostNotifyTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Notification title (default: pepebot)",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Notification text",
+			},
+		},
+		"required": []string{"message"},
+	}
+}
+
+func (t *HostNotifyTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	message, ok := args["message"].(string)
+	if !ok || message == "" {
+		return "", fmt.Errorf("message is required")
+	}
+	title, _ := args["title"].(string)
+	if title == "" {
+		title = "pepebot"
+	}
+	if _, err := t.helper.run(ctx, t.helper.notify(title, message), ""); err != nil {
+		return "", err
+	}
+	return "Notification shown", nil
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestHostClipboard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	clip := filepath.Join(t.TempDir(), "clipboard")
+	helper := &HostHelper{
+		copy:  hostCommand{"sh", "-c", `cat > "$0"`, clip},
+		paste: hostCommand{"sh", "-c", `cat "$0" 2>/dev/null || true`, clip},
+	}
+	ctx := context.Background()
+
+	if out, err := NewHostClipboardGetTool(helper).Execute(ctx, nil); err != nil || out != "(clipboard is empty)" {
+		t.Errorf("empty clipboard = %q, %v", out, err)
+	}
+	if _, err := NewHostClipboardSetTool(helper).Execute(ctx, map[string]interface{}{"text": "it's \"quoted\""}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := NewHostClipboardGetTool(helper).Execute(ctx, nil); err != nil || out != `it's "quoted"` {
+		t.Errorf("clipboard = %q, %v", out, err)
+	}
+}
+
+func TestHostQuoting(t *testing.T) {
+	if got := appleScriptString(`say "hi" \o/`); got != `"say \"hi\" \\o/"` {
+		t.Errorf("appleScriptString = %s", got)
+	}
+	if got := powerShellString("it's done"); got != "'it''s done'" {
+		t.Errorf("powerShellString = %s", got)
+	}
+}
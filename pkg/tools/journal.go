@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/journal"
+)
+
+// NewJournalAppendTool creates the journal_append tool.
+func NewJournalAppendTool(j *journal.Journal) *JournalAppendTool {
+	return &JournalAppendTool{journal: j}
+}
+
+// NewJournalReadTool creates the journal_read tool.
+func NewJournalReadTool(j *journal.Journal) *JournalReadTool {
+	return &JournalReadTool{journal: j}
+}
+
+// ==================== journal_append ====================
+
+type JournalAppendTool struct {
+	journal *journal.Journal
+}
+
+func (t *JournalAppendTool) Name() string { return "journal_append" }
+
+func (t *JournalAppendTool) Description() string {
+	return "Add a timestamped entry to today's daily note (memory/daily/YYYY-MM-DD.md). Use it for what happened today: events, progress, decisions, moods, things to follow up. Long-lasting facts about the user belong in MEMORY.md instead."
+}
+
+func (t *JournalAppendTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Entry to add",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+func (t *JournalAppendTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	text, _ := args["text"].(string)
+	path, err := t.journal.Append(time.Now(), text)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Added to %s", path), nil
+}
+
+// ==================== journal_read ====================
+
+type JournalReadTool struct {
+	journal *journal.Journal
+}
+
+func (t *JournalReadTool) Name() string { return "journal_read" }
+
+func (t *JournalReadTool) Description() string {
+	return "Read daily notes. Give a date for one day, or days for the notes of the last N days (days=7 for the week). For a weekly digest, read days=7 and summarize the highlights, open items and patterns."
+}
+
+func (t *JournalReadTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"date": map[string]interface{}{
+				"type":        "string",
+				"description": "Day to read: YYYY-MM-DD, 'today' (default) or 'yesterday'",
+			},
+			"days": map[string]interface{}{
+				"type":        "integer",
+				"description": "Read the last N days up to today instead of a single date (max 31)",
+			},
+		},
+	}
+}
+
+func (t *JournalReadTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	now := time.Now()
+
+	if days, ok := args["days"].(float64); ok && days > 0 {
+		n := int(days)
+		if n > 31 {
+			n = 31
+		}
+		notes := t.journal.Range(now, n)
+		if notes == "" {
+			return fmt.Sprintf("No daily notes in the last %d days", n), nil
+		}
+		return notes, nil
+	}
+
+	date, _ := args["date"].(string)
+	day, err := journal.ParseDay(date, now)
+	if err != nil {
+		return "", err
+	}
+	note := t.journal.Read(day)
+	if !journal.HasEntries(note) {
+		return fmt.Sprintf("No notes for %s", day.Format("2006-01-02")), nil
+	}
+	return note, nil
+}
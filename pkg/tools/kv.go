@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/kv"
+)
+
+// OpenKVStore opens the workspace key–value store (workspace/state/kv.db)
+func OpenKVStore(workspace string) (*kv.Store, error) {
+	return kv.Open(filepath.Join(workspace, "state", "kv.db"))
+}
+
+// NewKVGetTool creates the kv_get tool.
+func NewKVGetTool(store *kv.Store) *KVGetTool {
+	return &KVGetTool{store: store}
+}
+
+// NewKVSetTool creates the kv_set tool.
+func NewKVSetTool(store *kv.Store) *KVSetTool {
+	return &KVSetTool{store: store}
+}
+
+// NewKVListTool creates the kv_list tool.
+func NewKVListTool(store *kv.Store) *KVListTool {
+	return &KVListTool{store: store}
+}
+
+var kvNamespaceParam = map[string]interface{}{
+	"type":        "string",
+	"description": "Namespace to keep keys of different agents or workflows apart (default: \"default\")",
+}
+
+func kvNamespace(args map[string]interface{}) string {
+	ns, _ := args["namespace"].(string)
+	return strings.TrimSpace(ns)
+}
+
+// ==================== kv_get ====================
+
+type KVGetTool struct {
+	store *kv.Store
+}
+
+func (t *KVGetTool) Name() string { return "kv_get" }
+
+func (t *KVGetTool) Description() string {
+	return "Read a value from the persistent key-value store. Use it for small structured state such as counters, cursors and last-seen IDs instead of MEMORY.md."
+}
+
+func (t *KVGetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Key to read",
+			},
+			"namespace": kvNamespaceParam,
+		},
+		"required": []string{"key"},
+	}
+}
+
+func (t *KVGetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+
+	value, found, err := t.store.Get(kvNamespace(args), key)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return fmt.Sprintf("Key '%s' is not set", key), nil
+	}
+	return string(value), nil
+}
+
+// ==================== kv_set ====================
+
+type KVSetTool struct {
+	store *kv.Store
+}
+
+func (t *KVSetTool) Name() string { return "kv_set" }
+
+func (t *KVSetTool) Description() string {
+	return "Write a value to the persistent key-value store. The value can be any JSON (string, number, object, array). Use 'increment' to atomically add to a numeric counter, or 'delete' to remove the key."
+}
+
+func (t *KVSetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Key to write",
+			},
+			"value": map[string]interface{}{
+				"description": "JSON value to store",
+			},
+			"increment": map[string]interface{}{
+				"type":        "number",
+				"description": "Add this amount to the numeric value instead of replacing it (missing keys start at 0)",
+			},
+			"delete": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Remove the key",
+			},
+			"namespace": kvNamespaceParam,
+		},
+		"required": []string{"key"},
+	}
+}
+
+func (t *KVSetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+	namespace := kvNamespace(args)
+
+	if del, _ := args["delete"].(bool); del {
+		existed, err := t.store.Delete(namespace, key)
+		if err != nil {
+			return "", err
+		}
+		if !existed {
+			return fmt.Sprintf("Key '%s' was not set", key), nil
+		}
+		return fmt.Sprintf("Deleted '%s'", key), nil
+	}
+
+	if delta, ok := args["increment"].(float64); ok {
+		value, err := t.store.Increment(namespace, key, delta)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s = %v", key, value), nil
+	}
+
+	raw, ok := args["value"]
+	if !ok {
+		return "", fmt.Errorf("value, increment or delete is required")
+	}
+	value, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid value: %w", err)
+	}
+	if err := t.store.Set(namespace, key, value); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Stored '%s' (%d bytes)", key, len(value)), nil
+}
+
+// ==================== kv_list ====================
+
+type KVListTool struct {
+	store *kv.Store
+}
+
+func (t *KVListTool) Name() string { return "kv_list" }
+
+func (t *KVListTool) Description() string {
+	return "List keys and values in the persistent key-value store, optionally filtered by key prefix."
+}
+
+func (t *KVListTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prefix": map[string]interface{}{
+				"type":        "string",
+				"description": "Only list keys starting with this prefix",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of entries (default: 100)",
+			},
+			"namespace": kvNamespaceParam,
+		},
+	}
+}
+
+func (t *KVListTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	prefix, _ := args["prefix"].(string)
+	limit := 100
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+	namespace := kvNamespace(args)
+
+	entries, err := t.store.List(namespace, prefix, limit)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 0 {
+		msg := "No keys found"
+		if namespaces, err := t.store.Namespaces(); err == nil && len(namespaces) > 0 {
+			msg += fmt.Sprintf(". Namespaces in use: %s", strings.Join(namespaces, ", "))
+		}
+		return msg, nil
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		value := string(e.Value)
+		if runes := []rune(value); len(runes) > 200 {
+			value = string(runes[:200]) + "..."
+		}
+		fmt.Fprintf(&b, "%s = %s (updated %s)\n", e.Key, value, e.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestKVListCutsLongValuesOnRunes(t *testing.T) {
+	store, err := OpenKVStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	value, _ := json.Marshal(strings.Repeat("日本", 150))
+	if err := store.Set("", "notes", value); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := NewKVListTool(store).Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(out) || !strings.Contains(out, "...") {
+		t.Errorf("kv_list output = %q", out)
+	}
+}
@@ -0,0 +1,61 @@
+package tools
+
+import "sync/atomic"
+
+// readOnlyTools only read: they change nothing outside pepebot and stay
+// available in maintenance mode. Tools not listed here, including MCP and
+// script tools, are assumed to have side effects.
+var readOnlyTools = map[string]bool{
+	"read_file":                true,
+	"list_dir":                 true,
+	"csv_read":                 true,
+	"csv_query":                true,
+	"web_search":               true,
+	"web_fetch":                true,
+	"kv_get":                   true,
+	"kv_list":                  true,
+	"journal_read":             true,
+	"feed_list":                true,
+	"workflow_list":            true,
+	"load_skill":               true,
+	"calendar_list_events":     true,
+	"github_list_issues":       true,
+	"github_pr_diff":           true,
+	"host_clipboard_get":       true,
+	"whatsapp_list_groups":     true,
+	"whatsapp_resolve_contact": true,
+	"whatsapp_group_members":   true,
+	"adb_devices":              true,
+	"adb_screenshot":           true,
+	"adb_ui_dump":              true,
+	"adb_logcat":               true,
+	"adb_get_location":         true,
+}
+
+// readOnlyUnless maps tools that only read to the argument that makes a call
+// run something: adb_wait_for with a command runs it through adb shell.
+var readOnlyUnless = map[string]string{
+	"adb_wait_for": "command",
+}
+
+var maintenance atomic.Bool
+
+// SetMaintenance turns maintenance mode on or off for every tool registry
+// in the process. In maintenance mode only read-only tools run.
+func SetMaintenance(on bool) {
+	maintenance.Store(on)
+}
+
+// InMaintenance reports whether maintenance mode is on
+func InMaintenance() bool {
+	return maintenance.Load()
+}
+
+// IsReadOnly reports whether a call of a tool with args only reads
+func IsReadOnly(name string, args map[string]interface{}) bool {
+	if arg, ok := readOnlyUnless[name]; ok {
+		value, _ := args[arg].(string)
+		return value == ""
+	}
+	return readOnlyTools[name]
+}
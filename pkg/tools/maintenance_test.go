@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaintenanceBlocksSideEffects(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("hi"), 0644)
+
+	registry := NewToolRegistry()
+	registry.Register(NewReadFileTool(workspace))
+	registry.Register(NewWriteFileTool(workspace))
+
+	SetMaintenance(true)
+	defer SetMaintenance(false)
+
+	ctx := context.Background()
+	if _, err := registry.Execute(ctx, "read_file", map[string]interface{}{"path": "notes.txt"}); err != nil {
+		t.Errorf("read_file should run in maintenance: %v", err)
+	}
+	_, err := registry.Execute(ctx, "write_file", map[string]interface{}{"path": "new.txt", "content": "x"})
+	if err == nil || !strings.Contains(err.Error(), "maintenance") {
+		t.Errorf("write_file should be blocked, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "new.txt")); err == nil {
+		t.Error("file written in maintenance mode")
+	}
+
+	SetMaintenance(false)
+	if _, err := registry.Execute(ctx, "write_file", map[string]interface{}{"path": "new.txt", "content": "x"}); err != nil {
+		t.Errorf("write_file after maintenance: %v", err)
+	}
+}
+
+func TestReadOnlyContext(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("hi"), 0644)
+
+	registry := NewToolRegistry()
+	registry.Register(NewReadFileTool(workspace))
+	registry.Register(NewWriteFileTool(workspace))
+
+	ctx := WithReadOnly(context.Background())
+	if _, err := registry.Execute(ctx, "read_file", map[string]interface{}{"path": "notes.txt"}); err != nil {
+		t.Errorf("read_file should run read-only: %v", err)
+	}
+	if _, err := registry.Execute(ctx, "write_file", map[string]interface{}{"path": "new.txt", "content": "x"}); err == nil {
+		t.Error("write_file should be blocked")
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "new.txt")); err == nil {
+		t.Error("file written in a read-only context")
+	}
+}
+
+func TestAdbWaitForCommandIsNotReadOnly(t *testing.T) {
+	if !IsReadOnly("adb_wait_for", map[string]interface{}{"text": "Login"}) {
+		t.Error("waiting for an element should be read-only")
+	}
+	if IsReadOnly("adb_wait_for", map[string]interface{}{"command": "pm clear com.example", "match": "Success"}) {
+		t.Error("a wait that runs a shell command should not be read-only")
+	}
+
+	registry := NewToolRegistry()
+	registry.Register(NewAdbWaitForTool(nil))
+	ctx := WithReadOnly(context.Background())
+	_, err := registry.Execute(ctx, "adb_wait_for", map[string]interface{}{"command": "rm -rf /sdcard/DCIM", "match": "x"})
+	if err == nil || !strings.Contains(err.Error(), "side effects") {
+		t.Errorf("adb_wait_for with a command should be blocked, got %v", err)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
 )
 
 // AgentCaller delegates a message to a named agent.
@@ -15,27 +18,51 @@ type AgentCaller interface {
 	ProcessDirect(ctx context.Context, content string, media []string, sessionKey, agentName string) (string, error)
 }
 
+// TeamRunner runs a task with a team of agents.
+// Implemented by agent.AgentManager alongside AgentCaller.
+type TeamRunner interface {
+	RunTeam(ctx context.Context, teamName, task, sessionKey string) (string, error)
+}
+
 // ManageAgentTool allows the bot to manage agents via tool calls.
 // It reads/writes registry.json directly to avoid circular dependency with the agent package.
 type ManageAgentTool struct {
 	workspace    string
 	registryPath string
 	agentCaller  AgentCaller
+	teamRunner   TeamRunner
 }
 
 type agentRegistry struct {
 	Version string                      `json:"version"`
 	Agents  map[string]*agentDefinition `json:"agents"`
+	Teams   map[string]*teamDefinition  `json:"teams,omitempty"`
+}
+
+type teamDefinition struct {
+	Description string       `json:"description,omitempty"`
+	Coordinator string       `json:"coordinator"`
+	Members     []teamMember `json:"members"`
+}
+
+type teamMember struct {
+	Agent string `json:"agent"`
+	Role  string `json:"role"`
 }
 
 type agentDefinition struct {
 	Enabled     bool    `json:"enabled"`
 	Model       string  `json:"model"`
 	Provider    string  `json:"provider,omitempty"`
+	APIKey      string  `json:"api_key,omitempty"`
+	APIBase     string  `json:"api_base,omitempty"`
 	Description string  `json:"description,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	PromptFile  string  `json:"prompt_file,omitempty"`
+	// Skills limits the agent to the named skills; empty means all skills
+	Skills    []string                `json:"skills,omitempty"`
+	Reasoning *config.ReasoningConfig `json:"reasoning,omitempty"`
 }
 
 func NewManageAgentTool(workspace string) *ManageAgentTool {
@@ -48,6 +75,9 @@ func NewManageAgentTool(workspace string) *ManageAgentTool {
 // SetAgentCaller injects a runtime agent caller (typically AgentManager).
 func (t *ManageAgentTool) SetAgentCaller(caller AgentCaller) {
 	t.agentCaller = caller
+	if runner, ok := caller.(TeamRunner); ok {
+		t.teamRunner = runner
+	}
 }
 
 func (t *ManageAgentTool) Name() string {
@@ -55,7 +85,7 @@ func (t *ManageAgentTool) Name() string {
 }
 
 func (t *ManageAgentTool) Description() string {
-	return "Manage bot agents: register/list/enable/disable/remove(delete) agents, create bootstrap files, assign skills into agent memory, and call a specific agent directly."
+	return "Manage bot agents: register/list/enable/disable/remove(delete) agents, create bootstrap files, assign skills into agent memory, and call a specific agent directly. Teams pair a coordinator agent with member agents: create_team/list_teams/remove_team manage them and run_team hands a task to a team (e.g. \"use the research team on X\")."
 }
 
 func (t *ManageAgentTool) Parameters() map[string]interface{} {
@@ -64,12 +94,12 @@ func (t *ManageAgentTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"register", "list", "enable", "disable", "remove", "delete", "create_bootstrap", "assign_skill", "call"},
-				"description": "Action to perform: register, list, enable, disable, remove/delete, create_bootstrap, assign_skill, call",
+				"enum":        []string{"register", "list", "enable", "disable", "remove", "delete", "create_bootstrap", "assign_skill", "call", "create_team", "list_teams", "remove_team", "run_team"},
+				"description": "Action to perform: register, list, enable, disable, remove/delete, create_bootstrap, assign_skill, call, create_team, list_teams, remove_team, run_team",
 			},
 			"name": map[string]interface{}{
 				"type":        "string",
-				"description": "Agent name (required for register, enable, disable, remove, create_bootstrap, assign_skill, call)",
+				"description": "Agent name (required for register, enable, disable, remove, create_bootstrap, assign_skill, call), or team name (required for create_team, remove_team, run_team)",
 			},
 			"model": map[string]interface{}{
 				"type":        "string",
@@ -79,6 +109,14 @@ func (t *ManageAgentTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Provider override for register (optional, e.g. 'vertex', 'openrouter', 'anthropic', 'opencodego')",
 			},
+			"api_base": map[string]interface{}{
+				"type":        "string",
+				"description": "API base URL for register (optional, e.g. 'https://api.groq.com/openai/v1' or a local OpenAI-compatible server)",
+			},
+			"api_key": map[string]interface{}{
+				"type":        "string",
+				"description": "API key for register (optional). Prefer '${ENV_VAR}' to read the key from an environment variable",
+			},
 			"description": map[string]interface{}{
 				"type":        "string",
 				"description": "Agent description (optional, for register)",
@@ -91,6 +129,16 @@ func (t *ManageAgentTool) Parameters() map[string]interface{} {
 				"type":        "integer",
 				"description": "Max tokens for responses (optional, for register)",
 			},
+			"skills": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Skills the agent may use (optional, for register; omit to allow all skills)",
+			},
+			"reasoning_effort": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"low", "medium", "high"},
+				"description": "Thinking effort for reasoning models (optional, for register)",
+			},
 			"remove_files": map[string]interface{}{
 				"type":        "boolean",
 				"description": "Also delete agent prompt directory on remove (optional, default false)",
@@ -101,11 +149,27 @@ func (t *ManageAgentTool) Parameters() map[string]interface{} {
 			},
 			"message": map[string]interface{}{
 				"type":        "string",
-				"description": "Message to send to target agent (required for call)",
+				"description": "Message to send to target agent (required for call), or the task for the team (required for run_team)",
 			},
 			"session_key": map[string]interface{}{
 				"type":        "string",
-				"description": "Optional session key for call action",
+				"description": "Optional session key for call and run_team actions",
+			},
+			"coordinator": map[string]interface{}{
+				"type":        "string",
+				"description": "Agent that plans and combines the team's work (required for create_team)",
+			},
+			"members": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"agent": map[string]interface{}{"type": "string"},
+						"role":  map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"agent", "role"},
+				},
+				"description": "Team members with their roles, e.g. [{\"agent\": \"searcher\", \"role\": \"web researcher\"}] (required for create_team)",
 			},
 		},
 		"required": []string{"action"},
@@ -135,6 +199,14 @@ func (t *ManageAgentTool) Execute(ctx context.Context, args map[string]interface
 		return t.assignSkill(args)
 	case "call":
 		return t.callAgent(ctx, args)
+	case "create_team":
+		return t.createTeam(args)
+	case "list_teams":
+		return t.listTeams()
+	case "remove_team":
+		return t.removeTeam(args)
+	case "run_team":
+		return t.runTeam(ctx, args)
 	default:
 		return "", fmt.Errorf("unknown action: %s", action)
 	}
@@ -198,6 +270,12 @@ func (t *ManageAgentTool) registerAgent(args map[string]interface{}) (string, er
 	if provider, ok := args["provider"].(string); ok {
 		def.Provider = strings.TrimSpace(provider)
 	}
+	if apiBase, ok := args["api_base"].(string); ok {
+		def.APIBase = strings.TrimSpace(apiBase)
+	}
+	if apiKey, ok := args["api_key"].(string); ok {
+		def.APIKey = strings.TrimSpace(apiKey)
+	}
 
 	if desc, ok := args["description"].(string); ok {
 		def.Description = desc
@@ -208,6 +286,16 @@ func (t *ManageAgentTool) registerAgent(args map[string]interface{}) (string, er
 	if mt, ok := args["max_tokens"].(float64); ok {
 		def.MaxTokens = int(mt)
 	}
+	if effort, ok := args["reasoning_effort"].(string); ok && effort != "" {
+		def.Reasoning = &config.ReasoningConfig{Effort: effort}
+	}
+	for _, skill := range stringList(args["skills"]) {
+		skill = strings.TrimSpace(skill)
+		if !t.skillExists(skill) {
+			return "", fmt.Errorf("skill '%s' not found in workspace/builtin skills", skill)
+		}
+		def.Skills = append(def.Skills, skill)
+	}
 
 	// Auto-set PromptFile to agent directory
 	agentDir := filepath.Join(filepath.Dir(t.registryPath), name)
@@ -256,6 +344,9 @@ func (t *ManageAgentTool) listAgents() (string, error) {
 		if def.Provider != "" {
 			agent["provider"] = def.Provider
 		}
+		if def.APIBase != "" {
+			agent["api_base"] = def.APIBase
+		}
 		if def.Description != "" {
 			agent["description"] = def.Description
 		}
@@ -268,6 +359,9 @@ func (t *ManageAgentTool) listAgents() (string, error) {
 		if def.PromptFile != "" {
 			agent["prompt_dir"] = def.PromptFile
 		}
+		if len(def.Skills) > 0 {
+			agent["skills"] = def.Skills
+		}
 		agents = append(agents, agent)
 	}
 
@@ -332,6 +426,11 @@ func (t *ManageAgentTool) removeAgent(args map[string]interface{}) (string, erro
 	if !exists {
 		return "", fmt.Errorf("agent '%s' not found", name)
 	}
+	for teamName, team := range reg.Teams {
+		if team.Coordinator == name || slices.Contains(teamAgents(team), name) {
+			return "", fmt.Errorf("agent '%s' is part of team '%s'; remove or change the team first", name, teamName)
+		}
+	}
 
 	delete(reg.Agents, name)
 	if err := t.saveRegistry(reg); err != nil {
@@ -468,6 +567,14 @@ func (t *ManageAgentTool) assignSkill(args map[string]interface{}) (string, erro
 		return "", fmt.Errorf("skill '%s' not found in workspace/builtin skills", skill)
 	}
 
+	// Agents with a skills allow-list need the skill added to it as well
+	if len(def.Skills) > 0 && !slices.Contains(def.Skills, skill) {
+		def.Skills = append(def.Skills, skill)
+		if err := t.saveRegistry(reg); err != nil {
+			return "", fmt.Errorf("failed to save registry: %w", err)
+		}
+	}
+
 	agentDir := def.PromptFile
 	if agentDir == "" {
 		agentDir = filepath.Join(filepath.Dir(t.registryPath), name)
@@ -573,3 +680,155 @@ func (t *ManageAgentTool) skillExists(skill string) bool {
 	}
 	return false
 }
+
+func (t *ManageAgentTool) createTeam(args map[string]interface{}) (string, error) {
+	name, ok := args["name"].(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("name is required for create_team action")
+	}
+	coordinator, ok := args["coordinator"].(string)
+	if !ok || strings.TrimSpace(coordinator) == "" {
+		return "", fmt.Errorf("coordinator is required for create_team action")
+	}
+
+	reg, err := t.loadRegistry()
+	if err != nil {
+		return "", err
+	}
+
+	team := &teamDefinition{Coordinator: strings.TrimSpace(coordinator)}
+	if desc, ok := args["description"].(string); ok {
+		team.Description = desc
+	}
+	items, _ := args["members"].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		agent, _ := m["agent"].(string)
+		role, _ := m["role"].(string)
+		if agent = strings.TrimSpace(agent); agent != "" {
+			team.Members = append(team.Members, teamMember{Agent: agent, Role: strings.TrimSpace(role)})
+		}
+	}
+	if len(team.Members) == 0 {
+		return "", fmt.Errorf("members is required for create_team action")
+	}
+
+	for _, agent := range append([]string{team.Coordinator}, teamAgents(team)...) {
+		if _, exists := reg.Agents[agent]; !exists {
+			return "", fmt.Errorf("agent '%s' not found; register it first", agent)
+		}
+	}
+
+	if reg.Teams == nil {
+		reg.Teams = make(map[string]*teamDefinition)
+	}
+	reg.Teams[name] = team
+	if err := t.saveRegistry(reg); err != nil {
+		return "", fmt.Errorf("failed to save registry: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Team '%s' created with coordinator '%s' and %d members", name, team.Coordinator, len(team.Members)),
+	}
+	resultJSON, _ := json.Marshal(result)
+	return string(resultJSON), nil
+}
+
+func (t *ManageAgentTool) listTeams() (string, error) {
+	reg, err := t.loadRegistry()
+	if err != nil {
+		return "", err
+	}
+
+	teams := make([]map[string]interface{}, 0, len(reg.Teams))
+	for name, def := range reg.Teams {
+		team := map[string]interface{}{
+			"name":        name,
+			"coordinator": def.Coordinator,
+			"members":     def.Members,
+		}
+		if def.Description != "" {
+			team["description"] = def.Description
+		}
+		teams = append(teams, team)
+	}
+
+	result := map[string]interface{}{
+		"teams": teams,
+		"total": len(teams),
+	}
+	resultJSON, _ := json.Marshal(result)
+	return string(resultJSON), nil
+}
+
+func (t *ManageAgentTool) removeTeam(args map[string]interface{}) (string, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("name is required for remove_team action")
+	}
+
+	reg, err := t.loadRegistry()
+	if err != nil {
+		return "", err
+	}
+	if _, exists := reg.Teams[name]; !exists {
+		return "", fmt.Errorf("team '%s' not found", name)
+	}
+
+	delete(reg.Teams, name)
+	if err := t.saveRegistry(reg); err != nil {
+		return "", fmt.Errorf("failed to save registry: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Team '%s' removed", name),
+	}
+	resultJSON, _ := json.Marshal(result)
+	return string(resultJSON), nil
+}
+
+func (t *ManageAgentTool) runTeam(ctx context.Context, args map[string]interface{}) (string, error) {
+	if t.teamRunner == nil {
+		return "", fmt.Errorf("team runner is not available in this runtime")
+	}
+
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("name is required for run_team action")
+	}
+	message, ok := args["message"].(string)
+	if !ok || strings.TrimSpace(message) == "" {
+		return "", fmt.Errorf("message is required for run_team action")
+	}
+
+	sessionKey, _ := args["session_key"].(string)
+	if strings.TrimSpace(sessionKey) == "" {
+		sessionKey = SessionKeyFromContext(ctx)
+	}
+
+	response, err := t.teamRunner.RunTeam(ctx, name, message, sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	result := map[string]interface{}{
+		"success":  true,
+		"team":     name,
+		"response": response,
+	}
+	resultJSON, _ := json.Marshal(result)
+	return string(resultJSON), nil
+}
+
+func teamAgents(team *teamDefinition) []string {
+	agents := make([]string, 0, len(team.Members))
+	for _, member := range team.Members {
+		agents = append(agents, member.Agent)
+	}
+	return agents
+}
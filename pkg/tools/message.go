@@ -21,6 +21,11 @@ func (t *MessageTool) Name() string {
 	return "message"
 }
 
+// Sequential keeps messages in the order the model sent them
+func (t *MessageTool) Sequential() bool {
+	return true
+}
+
 func (t *MessageTool) Description() string {
 	return "Send a message to user on a chat channel. Use this when you want to communicate something."
 }
@@ -0,0 +1,17 @@
+//go:build windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows; cancellation kills the shell only
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// interruptProcess kills the process; Windows has no interrupt signal for
+// other processes
+func interruptProcess(p *os.Process) error {
+	return p.Kill()
+}
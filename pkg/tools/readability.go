@@ -0,0 +1,486 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Readability-style extraction: drop page chrome, pick the element that holds
+// most of the paragraph text and render it as Markdown
+
+var (
+	// Class or id fragments of page chrome that never holds the article
+	unlikelyCandidate = regexp.MustCompile(`(?i)\b(comment|footer|header|menu|nav|sidebar|sponsor|advert|banner|cookie|popup|related|share|social|subscribe|newsletter|breadcrumb)`)
+	likelyCandidate   = regexp.MustCompile(`(?i)\b(article|content|main|post|story|entry|text|body)`)
+	spaceRun          = regexp.MustCompile(`[ \t\r\n]+`)
+	blankLines        = regexp.MustCompile(`\n{3,}`)
+)
+
+// Elements removed before scoring
+var strippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Form: true, atom.Button: true,
+	atom.Input: true, atom.Select: true, atom.Textarea: true, atom.Nav: true,
+	atom.Aside: true, atom.Footer: true, atom.Header: true, atom.Dialog: true,
+}
+
+// article is the result of extractArticle
+type article struct {
+	Title    string
+	Markdown string
+}
+
+// extractArticle finds the main content of an HTML page. base resolves
+// relative links and may be nil.
+func extractArticle(page string, base *url.URL) (article, error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return article{}, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	title := pageTitle(doc)
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	stripChrome(body)
+
+	root := findElement(body, atom.Article)
+	if root == nil || len(textOf(root)) < 200 {
+		if main := findElement(body, atom.Main); main != nil && len(textOf(main)) >= 200 {
+			root = main
+		} else {
+			root = bestCandidate(body)
+		}
+	}
+
+	w := &markdownWriter{base: base}
+	w.node(root)
+	md := strings.TrimSpace(blankLines.ReplaceAllString(w.b.String(), "\n\n"))
+	return article{Title: title, Markdown: md}, nil
+}
+
+// pageTextMarkdown renders the whole body, for pages that are not articles
+func pageTextMarkdown(page string, base *url.URL) (string, error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	removeElements(body, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Svg:
+			return true
+		}
+		return false
+	})
+	w := &markdownWriter{base: base}
+	w.node(body)
+	return strings.TrimSpace(blankLines.ReplaceAllString(w.b.String(), "\n\n")), nil
+}
+
+func pageTitle(doc *html.Node) string {
+	var title string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if n.DataAtom == atom.Meta && attr(n, "property") == "og:title" && attr(n, "content") != "" {
+			title = attr(n, "content")
+			return false
+		}
+		if n.DataAtom == atom.Title && title == "" {
+			title = strings.TrimSpace(textOf(n))
+		}
+		return true
+	})
+	return strings.TrimSpace(spaceRun.ReplaceAllString(title, " "))
+}
+
+func stripChrome(root *html.Node) {
+	removeElements(root, func(n *html.Node) bool {
+		if strippedElements[n.DataAtom] {
+			return true
+		}
+		if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" || attr(n, "role") == "navigation" {
+			return true
+		}
+		hint := attr(n, "class") + " " + attr(n, "id")
+		return n.DataAtom != atom.Body && n.DataAtom != atom.Article && n.DataAtom != atom.Main &&
+			unlikelyCandidate.MatchString(hint) && !likelyCandidate.MatchString(hint)
+	})
+}
+
+// bestCandidate scores the parents of paragraphs by the text they hold,
+// penalizing link-heavy blocks, and returns the highest scoring one
+func bestCandidate(body *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	walk(body, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || (n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td) {
+			return true
+		}
+		text := textOf(n)
+		if len(text) < 25 {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		if parent := n.Parent; parent != nil {
+			scores[parent] += score
+			if grand := parent.Parent; grand != nil {
+				scores[grand] += score / 2
+			}
+		}
+		return false
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		hint := attr(n, "class") + " " + attr(n, "id")
+		if likelyCandidate.MatchString(hint) {
+			score *= 1.25
+		}
+		score *= 1 - linkDensity(n)
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return body
+	}
+	return best
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := len(textOf(n))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			links += len(textOf(c))
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(total)
+}
+
+// markdownWriter renders a node tree as Markdown
+type markdownWriter struct {
+	b     strings.Builder
+	base  *url.URL
+	list  []int // item counter per open list; -1 for unordered
+	quote int
+	inPre bool
+}
+
+func (w *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		w.block()
+		w.b.WriteString(strings.Repeat("#", level) + " ")
+		w.write(strings.TrimSpace(spaceRun.ReplaceAllString(textOf(n), " ")))
+		w.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Figure, atom.Dl:
+		w.block()
+		w.children(n)
+		w.block()
+	case atom.Br:
+		w.newline()
+	case atom.Hr:
+		w.block()
+		w.b.WriteString("---")
+		w.block()
+	case atom.Pre:
+		w.block()
+		w.b.WriteString("```\n")
+		w.inPre = true
+		w.children(n)
+		w.inPre = false
+		if !strings.HasSuffix(w.b.String(), "\n") {
+			w.b.WriteString("\n")
+		}
+		w.b.WriteString("```")
+		w.block()
+	case atom.Code:
+		if w.inPre {
+			w.children(n)
+			return
+		}
+		w.write("`" + textOf(n) + "`")
+	case atom.Strong, atom.B:
+		w.wrap(n, "**")
+	case atom.Em, atom.I:
+		w.wrap(n, "_")
+	case atom.A:
+		text := strings.TrimSpace(spaceRun.ReplaceAllString(textOf(n), " "))
+		href := w.resolve(attr(n, "href"))
+		if href == "" || strings.HasPrefix(href, "javascript:") || text == "" {
+			w.children(n)
+			return
+		}
+		w.write("[" + text + "](" + href + ")")
+	case atom.Img:
+		if src := w.resolve(attr(n, "src")); src != "" && !strings.HasPrefix(src, "data:") {
+			w.write("![" + attr(n, "alt") + "](" + src + ")")
+		}
+	case atom.Ul, atom.Ol:
+		nested := len(w.list) > 0
+		if !nested {
+			w.block()
+		}
+		counter := -1
+		if n.DataAtom == atom.Ol {
+			counter = 0
+		}
+		w.list = append(w.list, counter)
+		w.children(n)
+		w.list = w.list[:len(w.list)-1]
+		if !nested {
+			w.block()
+		}
+	case atom.Li:
+		w.newline()
+		depth := len(w.list)
+		marker := "- "
+		if depth > 0 && w.list[depth-1] >= 0 {
+			w.list[depth-1]++
+			marker = fmt.Sprintf("%d. ", w.list[depth-1])
+		}
+		if depth > 1 {
+			w.b.WriteString(strings.Repeat("  ", depth-1))
+		}
+		w.b.WriteString(marker)
+		w.children(n)
+	case atom.Blockquote:
+		w.block()
+		w.quote++
+		w.b.WriteString("> ")
+		w.children(n)
+		w.quote--
+		w.block()
+	case atom.Table:
+		w.block()
+		w.table(n)
+		w.block()
+	case atom.Dt:
+		w.newline()
+		w.wrap(n, "**")
+	case atom.Dd:
+		w.newline()
+		w.b.WriteString(": ")
+		w.children(n)
+	case atom.Head, atom.Title, atom.Meta, atom.Link:
+	default:
+		w.children(n)
+	}
+}
+
+func (w *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *markdownWriter) wrap(n *html.Node, mark string) {
+	text := strings.TrimSpace(spaceRun.ReplaceAllString(textOf(n), " "))
+	if text == "" {
+		return
+	}
+	w.write(mark + text + mark)
+}
+
+func (w *markdownWriter) text(s string) {
+	if w.inPre {
+		w.b.WriteString(s)
+		return
+	}
+	s = spaceRun.ReplaceAllString(s, " ")
+	if s == " " || s == "" {
+		if out := w.b.String(); out != "" && !strings.HasSuffix(out, " ") && !strings.HasSuffix(out, "\n") {
+			w.b.WriteString(" ")
+		}
+		return
+	}
+	w.write(s)
+}
+
+// write appends inline text, dropping spaces at the start of a line
+func (w *markdownWriter) write(s string) {
+	out := w.b.String()
+	if out == "" || strings.HasSuffix(out, "\n") || strings.HasSuffix(out, "> ") {
+		s = strings.TrimLeft(s, " ")
+	}
+	w.b.WriteString(s)
+}
+
+func (w *markdownWriter) newline() {
+	out := w.b.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		w.b.WriteString("\n")
+	}
+	if w.quote > 0 {
+		w.b.WriteString(strings.Repeat("> ", w.quote))
+	}
+}
+
+func (w *markdownWriter) block() {
+	out := w.b.String()
+	if out == "" {
+		return
+	}
+	switch {
+	case strings.HasSuffix(out, "\n\n"):
+	case strings.HasSuffix(out, "\n"):
+		w.b.WriteString("\n")
+	default:
+		w.b.WriteString("\n\n")
+	}
+	if w.quote > 0 {
+		w.b.WriteString(strings.Repeat("> ", w.quote))
+	}
+}
+
+func (w *markdownWriter) table(n *html.Node) {
+	var rows [][]string
+	walk(n, func(c *html.Node) bool {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+				text := strings.TrimSpace(spaceRun.ReplaceAllString(textOf(cell), " "))
+				cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		w.b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			w.b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+}
+
+func (w *markdownWriter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+	if w.base == nil {
+		return ref
+	}
+	u, err := w.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// walk visits n and its descendants depth first; returning false from visit
+// skips the children of a node
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func removeElements(root *html.Node, match func(*html.Node) bool) {
+	var doomed []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n != root && n.Type == html.ElementNode && match(n) {
+			doomed = append(doomed, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range doomed {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -7,8 +7,18 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools       map[string]Tool
+	guard       *FileGuard
+	interceptor Interceptor
+	mu          sync.RWMutex
+}
+
+// Interceptor sees every tool call before and after it runs. BeforeTool
+// may replace the arguments or refuse the call; AfterTool may replace the
+// result.
+type Interceptor interface {
+	BeforeTool(ctx context.Context, name string, args map[string]interface{}) (map[string]interface{}, error)
+	AfterTool(ctx context.Context, name string, args map[string]interface{}, result string, err error) (string, error)
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -35,7 +45,71 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 	if !ok {
 		return "", fmt.Errorf("tool '%s' not found", name)
 	}
-	return tool.Execute(ctx, args)
+	traceCall(ctx, name, args)
+	if InMaintenance() && !IsReadOnly(name, args) {
+		return "", fmt.Errorf("tool '%s' is disabled while the gateway is in maintenance mode; only read-only tools can run", name)
+	}
+	if ReadOnlyContext(ctx) && !IsReadOnly(name, args) {
+		return "", fmt.Errorf("tool '%s' has side effects and is disabled in this run; only read-only tools can run", name)
+	}
+
+	r.mu.RLock()
+	interceptor := r.interceptor
+	r.mu.RUnlock()
+	if interceptor == nil {
+		return r.execute(ctx, tool, name, args)
+	}
+	args, err := interceptor.BeforeTool(ctx, name, args)
+	if err != nil {
+		return "", err
+	}
+	result, err := r.execute(ctx, tool, name, args)
+	return interceptor.AfterTool(ctx, name, args, result, err)
+}
+
+func (r *ToolRegistry) execute(ctx context.Context, tool Tool, name string, args map[string]interface{}) (string, error) {
+	guard := r.Guard()
+	if guard == nil {
+		return tool.Execute(ctx, args)
+	}
+	if err := guard.Check(ctx, name, args); err != nil {
+		guard.Record(ctx, name, args, "", err)
+		return "", err
+	}
+	result, err := tool.Execute(ctx, args)
+	guard.Record(ctx, name, args, result, err)
+	return result, err
+}
+
+// SetInterceptor runs interceptor around all tool calls
+func (r *ToolRegistry) SetInterceptor(interceptor Interceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interceptor = interceptor
+}
+
+// SetGuard applies a filesystem policy and audit log to all tool calls
+func (r *ToolRegistry) SetGuard(guard *FileGuard) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.guard = guard
+}
+
+// Guard returns the filesystem policy, or nil when there is none
+func (r *ToolRegistry) Guard() *FileGuard {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.guard
+}
+
+// IsSequential reports whether calls to the named tool must not overlap
+func (r *ToolRegistry) IsSequential(name string) bool {
+	tool, ok := r.Get(name)
+	if !ok {
+		return false
+	}
+	seq, ok := tool.(SequentialTool)
+	return ok && seq.Sequential()
 }
 
 // GetToolSchema returns the parameters schema for a named tool.
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pepebot-space/pepebot/pkg/bus"
+	"github.com/pepebot-space/pepebot/pkg/report"
+	"github.com/pepebot-space/pepebot/pkg/session"
+)
+
+type ReportGenerateTool struct {
+	sessions  *session.SessionManager
+	bus       *bus.MessageBus
+	workspace string
+}
+
+// NewReportGenerateTool creates the report_generate tool.
+func NewReportGenerateTool(sessions *session.SessionManager, bus *bus.MessageBus, workspace string) *ReportGenerateTool {
+	return &ReportGenerateTool{sessions: sessions, bus: bus, workspace: workspace}
+}
+
+func (t *ReportGenerateTool) Name() string { return "report_generate" }
+
+func (t *ReportGenerateTool) Description() string {
+	return "Compile conversation transcripts into a report saved under workspace/reports: Markdown, a self-contained HTML page, or PDF (needs wkhtmltopdf). Covers the current conversation unless sessions are given. Can include tool calls and their output, and screenshots. Set deliver to send the file to the current chat."
+}
+
+func (t *ReportGenerateTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Report title",
+			},
+			"sessions": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Session keys to include (e.g. 'telegram:12345'). Defaults to the current conversation.",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{report.FormatMarkdown, report.FormatHTML, report.FormatPDF},
+				"description": "Output format (default markdown)",
+			},
+			"include_tools": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include tool calls and their output (default false)",
+			},
+			"images": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Screenshots or other image files to add, relative to the workspace or absolute",
+			},
+			"deliver": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Send the report file to a chat (default false)",
+			},
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Channel to deliver to. Defaults to the current chat's channel.",
+			},
+			"chat_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Chat to deliver to. Defaults to the current chat.",
+			},
+		},
+	}
+}
+
+func (t *ReportGenerateTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	keys := stringList(args["sessions"])
+	if len(keys) == 0 {
+		current := SessionKeyFromContext(ctx)
+		if current == "" {
+			return "", fmt.Errorf("no current conversation; give the session keys to include")
+		}
+		keys = []string{current}
+	}
+
+	var selected []*session.Session
+	for _, key := range keys {
+		s := t.sessions.GetSession(key)
+		if s == nil {
+			return "", fmt.Errorf("session not found: %s", key)
+		}
+		selected = append(selected, s)
+	}
+
+	opts := report.Options{}
+	opts.Title, _ = args["title"].(string)
+	opts.Format, _ = args["format"].(string)
+	opts.Tools, _ = args["include_tools"].(bool)
+	for _, img := range stringList(args["images"]) {
+		if !filepath.IsAbs(img) {
+			img = filepath.Join(WorkDir(ctx, t.workspace), img)
+		}
+		opts.Images = append(opts.Images, img)
+	}
+
+	path, err := report.Save(t.workspace, selected, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if deliver, _ := args["deliver"].(bool); !deliver {
+		return fmt.Sprintf("Report saved to %s", path), nil
+	}
+	channel, chatID := chatFromSession(ctx)
+	if c, ok := args["channel"].(string); ok && c != "" {
+		channel = c
+	}
+	if c, ok := args["chat_id"].(string); ok && c != "" {
+		chatID = c
+	}
+	if channel == "" || chatID == "" {
+		return fmt.Sprintf("Report saved to %s, but there is no chat to deliver it to; give channel and chat_id", path), nil
+	}
+	t.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: opts.Title,
+		Media:   []string{path},
+	})
+	return fmt.Sprintf("Report saved to %s and sent to %s %s", path, channel, chatID), nil
+}
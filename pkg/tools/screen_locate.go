@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/config"
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+// Coordinate systems a vision model may answer in
+const (
+	CoordsPixels       = "pixels"
+	CoordsRelative     = "relative"      // 0 to 1
+	CoordsRelative1000 = "relative_1000" // 0 to 1000, used by several grounding models
+)
+
+var (
+	jsonObjectPattern = regexp.MustCompile(`\{[^{}]*\}`)
+	numberPattern     = regexp.MustCompile(`-?\d+(?:\.\d+)?`)
+)
+
+// parseLocatePoint reads the point a vision model answered with and
+// converts it to pixels of a width x height image. It accepts JSON
+// ({"x": 540, "y": 1820}, {"box": [x1, y1, x2, y2]} or {"found": false}),
+// or else the first two (point) or four (box) numbers in the text. found is
+// false when the model says the element is not there.
+func parseLocatePoint(text string, width, height int, coords string) (x, y int, found bool, err error) {
+	var values []float64
+	if m := jsonObjectPattern.FindString(text); m != "" {
+		var answer struct {
+			X     *float64  `json:"x"`
+			Y     *float64  `json:"y"`
+			Box   []float64 `json:"box"`
+			Found *bool     `json:"found"`
+		}
+		if json.Unmarshal([]byte(m), &answer) == nil {
+			switch {
+			case answer.Found != nil && !*answer.Found:
+				return 0, 0, false, nil
+			case answer.X != nil && answer.Y != nil:
+				values = []float64{*answer.X, *answer.Y}
+			case len(answer.Box) == 4:
+				values = answer.Box
+			}
+		}
+	}
+	if values == nil {
+		if strings.Contains(strings.ToUpper(text), "NOT_FOUND") {
+			return 0, 0, false, nil
+		}
+		for _, n := range numberPattern.FindAllString(text, 4) {
+			v, _ := strconv.ParseFloat(n, 64)
+			values = append(values, v)
+		}
+		if len(values) == 3 {
+			values = values[:2]
+		}
+	}
+
+	var fx, fy float64
+	switch len(values) {
+	case 2:
+		fx, fy = values[0], values[1]
+	case 4:
+		fx, fy = (values[0]+values[2])/2, (values[1]+values[3])/2
+	default:
+		return 0, 0, false, fmt.Errorf("no coordinates in the model's answer: %q", text)
+	}
+
+	if coords == "" && fx <= 1 && fy <= 1 {
+		coords = CoordsRelative
+	}
+	switch coords {
+	case CoordsRelative:
+		fx, fy = fx*float64(width), fy*float64(height)
+	case CoordsRelative1000:
+		fx, fy = fx/1000*float64(width), fy/1000*float64(height)
+	}
+
+	x, y = int(math.Round(fx)), int(math.Round(fy))
+	if x < 0 || y < 0 || x > width || y > height {
+		return 0, 0, false, fmt.Errorf("point (%d, %d) is outside the %dx%d screen", x, y, width, height)
+	}
+	return x, y, true, nil
+}
+
+// ==================== Screen Locate Tool ====================
+
+type ScreenLocateTool struct {
+	helper   *AdbHelper
+	provider providers.LLMProvider
+	model    string
+	coords   string
+}
+
+func NewScreenLocateTool(helper *AdbHelper, provider providers.LLMProvider, model, coords string) *ScreenLocateTool {
+	return &ScreenLocateTool{helper: helper, provider: provider, model: model, coords: coords}
+}
+
+// NewScreenLocateToolFromConfig uses the model in tools.vision, for example
+// a grounding model on a local OpenAI-compatible server, and falls back to
+// the agent's own provider and model when none is configured.
+func NewScreenLocateToolFromConfig(cfg *config.Config, helper *AdbHelper, provider providers.LLMProvider, model string) *ScreenLocateTool {
+	vision := cfg.Tools.Vision
+	if vision.Model != "" || vision.APIBase != "" {
+		p, err := providers.CreateProviderWithCredentials(cfg, vision.Model, vision.Provider, vision.APIKey, vision.APIBase)
+		if err == nil {
+			provider = p
+			if vision.Model != "" {
+				model = vision.Model
+			}
+		} else {
+			logger.WarnCF("tools", "Vision model unavailable, screen_locate uses the agent's model", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+	return NewScreenLocateTool(helper, provider, model, vision.Coordinates)
+}
+
+func (t *ScreenLocateTool) Name() string {
+	return "screen_locate"
+}
+
+func (t *ScreenLocateTool) Sequential() bool {
+	return true
+}
+
+func (t *ScreenLocateTool) Description() string {
+	return "Find a UI element on the Android screen from a description such as 'the blue Send button' and return its tap coordinates, using a vision model on a screenshot. Use it when adb_ui_dump does not expose the element (games, web views, custom drawn UIs). Set tap=true to tap it right away."
+}
+
+func (t *ScreenLocateTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "What to find, e.g. 'the blue Send button' or 'the search field at the top'",
+			},
+			"image": map[string]interface{}{
+				"type":        "string",
+				"description": "PNG or JPEG screenshot to search instead of capturing the device screen",
+			},
+			"tap": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Tap the element once found",
+			},
+			"device": map[string]interface{}{
+				"type":        "string",
+				"description": "Device serial number (optional, uses default device if not specified)",
+			},
+		},
+		"required": []string{"description"},
+	}
+}
+
+func (t *ScreenLocateTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	description, _ := args["description"].(string)
+	if strings.TrimSpace(description) == "" {
+		return "", fmt.Errorf("description is required")
+	}
+	device, _ := args["device"].(string)
+	tap, _ := args["tap"].(bool)
+
+	var data []byte
+	mime := "image/png"
+	if path, _ := args["image"].(string); path != "" {
+		var err error
+		if data, err = os.ReadFile(t.helper.resolvePath(path)); err != nil {
+			return "", fmt.Errorf("failed to read image: %w", err)
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".jpg" || ext == ".jpeg" {
+			mime = "image/jpeg"
+		}
+	} else {
+		var err error
+		data, err = t.helper.execAdbBinary(ctx, device, 15*time.Second, "exec-out", "screencap", "-p")
+		if err != nil {
+			return "", fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+	}
+	img, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid screenshot: %w", err)
+	}
+
+	messages := []providers.Message{
+		{Role: "system", Content: fmt.Sprintf("You locate UI elements in screenshots. The screenshot is %dx%d pixels. "+
+			`Reply with only JSON: {"x": <int>, "y": <int>} for the center of the element in pixels, or {"found": false} if it is not on the screen.`, img.Width, img.Height)},
+		{Role: "user", Content: []providers.ContentBlock{
+			{Type: "text", Text: "Find: " + description},
+			{Type: "image_url", ImageURL: &providers.ImageURL{
+				URL:    "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data),
+				Detail: "high",
+			}},
+		}},
+	}
+	resp, err := t.provider.Chat(ctx, messages, nil, t.model, map[string]interface{}{
+		"max_tokens":  200,
+		"temperature": 0.0,
+	})
+	if err != nil {
+		return "", fmt.Errorf("vision model failed: %w", err)
+	}
+
+	x, y, found, err := parseLocatePoint(resp.Content, img.Width, img.Height, t.coords)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("%q is not on the screen", description)
+	}
+
+	result := map[string]interface{}{
+		"x":      x,
+		"y":      y,
+		"width":  img.Width,
+		"height": img.Height,
+	}
+	if tap {
+		if _, err := t.helper.execAdb(ctx, device, 10*time.Second, "shell", "input", "tap", strconv.Itoa(x), strconv.Itoa(y)); err != nil {
+			return "", err
+		}
+		result["tapped"] = true
+	}
+	out, _ := json.Marshal(result)
+	return string(out), nil
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pepebot-space/pepebot/pkg/providers"
+)
+
+func TestParseLocatePoint(t *testing.T) {
+	tests := []struct {
+		text   string
+		coords string
+		x, y   int
+		found  bool
+	}{
+		{`{"x": 540, "y": 1820}`, "", 540, 1820, true},
+		{"```json\n{\"box\": [500, 1800, 580, 1840]}\n```", "", 540, 1820, true},
+		{`{"found": false}`, "", 0, 0, false},
+		{"NOT_FOUND", "", 0, 0, false},
+		{"<point>500 800</point>", CoordsRelative1000, 540, 1920, true},
+		{"(0.5, 0.25)", "", 540, 600, true},
+	}
+	for _, tt := range tests {
+		x, y, found, err := parseLocatePoint(tt.text, 1080, 2400, tt.coords)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if x != tt.x || y != tt.y || found != tt.found {
+			t.Errorf("%q: got (%d, %d, %v), want (%d, %d, %v)", tt.text, x, y, found, tt.x, tt.y, tt.found)
+		}
+	}
+
+	if _, _, _, err := parseLocatePoint("I can't tell", 1080, 2400, ""); err == nil {
+		t.Error("expected an error without coordinates")
+	}
+	if _, _, _, err := parseLocatePoint(`{"x": 5000, "y": 10}`, 1080, 2400, ""); err == nil {
+		t.Error("expected an error for a point outside the screen")
+	}
+}
+
+// visionProvider answers every request with a fixed reply and keeps the
+// last messages
+type visionProvider struct {
+	reply    string
+	messages []providers.Message
+}
+
+func (p *visionProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.messages = messages
+	return &providers.LLMResponse{Content: p.reply}, nil
+}
+
+func (p *visionProvider) ChatStream(ctx context.Context, messages []providers.Message, model string, options map[string]interface{}, callback providers.StreamCallback) error {
+	return nil
+}
+
+func (p *visionProvider) GetDefaultModel() string { return "vision" }
+
+func TestScreenLocateWithImage(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "screen.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewRGBA(image.Rect(0, 0, 200, 400)))
+	f.Close()
+
+	provider := &visionProvider{reply: `{"x": 100, "y": 350}`}
+	tool := NewScreenLocateTool(&AdbHelper{workspace: dir}, provider, "vision", "")
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"description": "the Send button", "image": "screen.png"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out != `{"height":400,"width":200,"x":100,"y":350}` {
+		t.Errorf("unexpected output %s", out)
+	}
+	if system, _ := provider.messages[0].Content.(string); !strings.Contains(system, "200x400") {
+		t.Errorf("system prompt misses the screen size: %q", system)
+	}
+
+	provider.reply = `{"found": false}`
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"description": "a unicorn", "image": "screen.png"}); err == nil {
+		t.Error("expected an error when the element is not found")
+	}
+}
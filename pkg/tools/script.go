@@ -0,0 +1,308 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// ScriptToolsDir is the workspace directory scanned for script tools
+const ScriptToolsDir = "tools"
+
+const (
+	defaultScriptTimeout = 60 * time.Second
+	maxScriptOutput      = 50000
+)
+
+var scriptToolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ScriptManifest describes a script tool. It lives next to the script as
+// <name>.json, <name>.yaml or <name>.yml.
+type ScriptManifest struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Description string                 `json:"description" yaml:"description"`
+	Script      string                 `json:"script,omitempty" yaml:"script,omitempty"`           // relative to the manifest; defaults to the file with the manifest's base name
+	Interpreter []string               `json:"interpreter,omitempty" yaml:"interpreter,omitempty"` // e.g. ["python3", "-u"]; guessed from the extension when empty
+	Parameters  map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty"`   // JSON Schema of the arguments
+	Timeout     int                    `json:"timeout,omitempty" yaml:"timeout,omitempty"`         // seconds, 60 when zero
+	Sequential  bool                   `json:"sequential,omitempty" yaml:"sequential,omitempty"`
+}
+
+// ScriptTool runs a workspace script with the call's arguments as JSON on
+// stdin and returns what it prints on stdout
+type ScriptTool struct {
+	manifest  ScriptManifest
+	command   []string
+	workspace string
+	timeout   time.Duration
+}
+
+func (t *ScriptTool) Name() string {
+	return t.manifest.Name
+}
+
+func (t *ScriptTool) Description() string {
+	return "[script] " + t.manifest.Description
+}
+
+func (t *ScriptTool) Parameters() map[string]interface{} {
+	return normalizeMCPParameters(t.manifest.Parameters)
+}
+
+func (t *ScriptTool) Sequential() bool {
+	return t.manifest.Sequential
+}
+
+func (t *ScriptTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, t.command[0], t.command[1:]...)
+	cmd.Dir = t.workspace
+	cmd.Env = append(os.Environ(),
+		"PEPEBOT_WORKSPACE="+t.workspace,
+		"PEPEBOT_TOOL="+t.manifest.Name,
+	)
+	cmd.Stdin = bytes.NewReader(input)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 2 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("script stopped: %w", ctx.Err())
+		}
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("script timed out after %v", t.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if len(msg) > 2000 {
+			msg = msg[len(msg)-2000:]
+		}
+		return "", fmt.Errorf("script failed (%v): %s", err, msg)
+	}
+
+	output := stdout.String()
+	if output == "" {
+		output = "(no output)"
+	}
+	if len(output) > maxScriptOutput {
+		output = output[:maxScriptOutput] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxScriptOutput)
+	}
+	return output, nil
+}
+
+// LoadScriptTools reads the manifests in workspace/tools. Manifests that
+// can't be used are reported in the returned errors and skipped.
+func LoadScriptTools(workspace string) ([]*ScriptTool, []error) {
+	dir := filepath.Join(workspace, ScriptToolsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	var loaded []*ScriptTool
+	var errs []error
+	seen := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isManifestFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		tool, err := loadScriptTool(workspace, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		if other, ok := seen[tool.Name()]; ok {
+			errs = append(errs, fmt.Errorf("%s: tool %q is already defined in %s", entry.Name(), tool.Name(), other))
+			continue
+		}
+		seen[tool.Name()] = entry.Name()
+		loaded = append(loaded, tool)
+	}
+	return loaded, errs
+}
+
+// RegisterScriptTools registers the script tools of the workspace. Tools
+// whose name is taken by a builtin tool are skipped.
+func RegisterScriptTools(workspace string, registry *ToolRegistry) int {
+	scripts, errs := LoadScriptTools(workspace)
+	for _, err := range errs {
+		logger.WarnCF("tools", "Skipping script tool", map[string]interface{}{"error": err.Error()})
+	}
+
+	count := 0
+	for _, tool := range scripts {
+		if _, exists := registry.Get(tool.Name()); exists {
+			logger.WarnCF("tools", "Script tool name is taken by another tool", map[string]interface{}{"name": tool.Name()})
+			continue
+		}
+		registry.Register(tool)
+		count++
+	}
+	if count > 0 {
+		logger.InfoCF("tools", "Registered script tools", map[string]interface{}{"count": count})
+	}
+	return count
+}
+
+func isManifestFile(name string) bool {
+	switch strings.ToLower(filepath.Base(name)) {
+	case "package.json", "package-lock.json", "tsconfig.json":
+		// Project files of Node tools, not manifests
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func loadScriptTool(workspace, manifestPath string) (*ScriptTool, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var m ScriptManifest
+	if strings.EqualFold(filepath.Ext(manifestPath), ".json") {
+		err = json.Unmarshal(data, &m)
+	} else {
+		err = yaml.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(manifestPath), filepath.Ext(manifestPath))
+	if m.Name == "" {
+		m.Name = base
+	}
+	if !scriptToolNamePattern.MatchString(m.Name) {
+		return nil, fmt.Errorf("invalid tool name %q (letters, digits, _ and - only)", m.Name)
+	}
+	if strings.TrimSpace(m.Description) == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+	if m.Parameters != nil {
+		if t, _ := m.Parameters["type"].(string); t != "" && t != "object" {
+			return nil, fmt.Errorf("parameters must be an object schema, got type %q", t)
+		}
+	}
+
+	dir := filepath.Dir(manifestPath)
+	script := m.Script
+	if script == "" {
+		script, err = findScript(dir, base)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if filepath.IsAbs(script) {
+			return nil, fmt.Errorf("script must be relative to the tools directory")
+		}
+		script = filepath.Join(dir, script)
+		if rel, err := filepath.Rel(dir, script); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("script must be inside the tools directory")
+		}
+	}
+	if info, err := os.Stat(script); err != nil {
+		return nil, fmt.Errorf("script not found: %w", err)
+	} else if info.IsDir() {
+		return nil, fmt.Errorf("script %s is a directory", script)
+	}
+
+	command := append([]string{}, m.Interpreter...)
+	if len(command) == 0 {
+		command = interpreterFor(script)
+	}
+	command = append(command, script)
+
+	timeout := defaultScriptTimeout
+	if m.Timeout > 0 {
+		timeout = time.Duration(m.Timeout) * time.Second
+	}
+
+	return &ScriptTool{
+		manifest:  m,
+		command:   command,
+		workspace: workspace,
+		timeout:   timeout,
+	}, nil
+}
+
+// findScript returns the file next to the manifest with the same base name
+func findScript(dir, base string) (string, error) {
+	matches, _ := filepath.Glob(filepath.Join(dir, base+".*"))
+	var scripts []string
+	for _, match := range matches {
+		if !isManifestFile(match) {
+			scripts = append(scripts, match)
+		}
+	}
+	if len(scripts) == 0 {
+		if _, err := os.Stat(filepath.Join(dir, base)); err == nil {
+			return filepath.Join(dir, base), nil
+		}
+		return "", fmt.Errorf("no script named %s.* next to the manifest; set \"script\"", base)
+	}
+	if len(scripts) > 1 {
+		return "", fmt.Errorf("several scripts named %s.*; set \"script\"", base)
+	}
+	return scripts[0], nil
+}
+
+// interpreterFor picks the interpreter from the script's extension. Other
+// scripts are run directly and need to be executable.
+func interpreterFor(script string) []string {
+	switch strings.ToLower(filepath.Ext(script)) {
+	case ".py":
+		if runtime.GOOS == "windows" {
+			return []string{"python"}
+		}
+		return []string{"python3"}
+	case ".js", ".mjs", ".cjs":
+		return []string{"node"}
+	case ".ts":
+		return []string{"npx", "tsx"}
+	case ".sh":
+		return []string{"sh"}
+	case ".bash":
+		return []string{"bash"}
+	case ".rb":
+		return []string{"ruby"}
+	case ".ps1":
+		return []string{"powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-File"}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScriptTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh scripts")
+	}
+
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, ScriptToolsDir)
+	os.MkdirAll(dir, 0755)
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// JSON manifest; the script is found by its base name
+	write("echo_args.json", `{
+	"description": "Echo the arguments",
+	"parameters": {"type": "object", "properties": {"text": {"type": "string"}}, "required": ["text"]}
+}`)
+	write("echo_args.sh", `cat; echo " in $(basename "$PWD")"`)
+
+	// YAML manifest with an explicit script and a failing command
+	write("fail.yaml", "name: always_fail\ndescription: Fails\nscript: lib/fail.sh\nsequential: true\n")
+	os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	write("lib/fail.sh", "echo boom >&2; exit 3")
+
+	write("broken.yml", "description: No script next to me\n")
+	write("package.json", `{"name": "deps"}`)
+
+	registry := NewToolRegistry()
+	registry.Register(NewExecTool(workspace))
+	write("exec.json", `{"description": "Shadows a builtin"}`)
+	write("exec.sh", "true")
+
+	if n := RegisterScriptTools(workspace, registry); n != 2 {
+		t.Fatalf("registered %d script tools, want 2", n)
+	}
+
+	tool, ok := registry.Get("echo_args")
+	if !ok {
+		t.Fatal("echo_args not registered")
+	}
+	props, _ := tool.Parameters()["properties"].(map[string]interface{})
+	if _, ok := props["text"]; !ok {
+		t.Errorf("parameters = %v", tool.Parameters())
+	}
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"text":"hi"} in ` + filepath.Base(workspace); strings.TrimSpace(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	if _, ok := registry.Get("exec"); !ok {
+		t.Fatal("builtin exec tool was removed")
+	}
+	if tool, _ := registry.Get("exec"); strings.HasPrefix(tool.Description(), "[script]") {
+		t.Error("script tool replaced the builtin exec tool")
+	}
+
+	failing, ok := registry.Get("always_fail")
+	if !ok {
+		t.Fatal("always_fail not registered")
+	}
+	if !registry.IsSequential("always_fail") {
+		t.Error("sequential flag ignored")
+	}
+	if _, err := failing.Execute(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected the script's stderr in the error, got %v", err)
+	}
+
+	_, errs := LoadScriptTools(workspace)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.yml") {
+		t.Errorf("load errors = %v", errs)
+	}
+}
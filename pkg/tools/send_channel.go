@@ -76,6 +76,11 @@ func (t *TelegramSendTool) Description() string {
 	return "Send a message, image, or file directly to a Telegram chat via the Bot API. Works without the gateway running. Use this for Telegram notifications in workflows."
 }
 
+// Sequential keeps messages in the order the model sent them
+func (t *TelegramSendTool) Sequential() bool {
+	return true
+}
+
 func (t *TelegramSendTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -118,7 +123,7 @@ func (t *TelegramSendTool) Execute(ctx context.Context, args map[string]interfac
 	apiBase := fmt.Sprintf("https://api.telegram.org/bot%s", t.token)
 
 	if filePath != "" {
-		filePath = resolveFilePath(filePath, t.workspace)
+		filePath = resolveFilePath(filePath, WorkDir(ctx, t.workspace))
 		return t.sendFile(ctx, apiBase, chatID, filePath, caption, text)
 	}
 
@@ -269,6 +274,10 @@ func (t *DiscordSendTool) Description() string {
 	return "Send a message or file directly to a Discord channel via the API. Works without the gateway running. Use this for Discord notifications in workflows."
 }
 
+func (t *DiscordSendTool) Sequential() bool {
+	return true
+}
+
 func (t *DiscordSendTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -309,7 +318,7 @@ func (t *DiscordSendTool) Execute(ctx context.Context, args map[string]interface
 	}
 
 	if filePath != "" {
-		filePath = resolveFilePath(filePath, t.workspace)
+		filePath = resolveFilePath(filePath, WorkDir(ctx, t.workspace))
 	}
 
 	apiURL := fmt.Sprintf("https://discord.com/api/v10/channels/%s/messages", channelID)
@@ -435,6 +444,10 @@ func (t *WhatsAppSendHTTPTool) Description() string {
 	return "Send a message or file to a WhatsApp contact or group. Requires the gateway to be running. Use the JID format: 628123456789@s.whatsapp.net for contacts, or groupid@g.us for groups."
 }
 
+func (t *WhatsAppSendHTTPTool) Sequential() bool {
+	return true
+}
+
 func (t *WhatsAppSendHTTPTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -476,7 +489,7 @@ func (t *WhatsAppSendHTTPTool) Execute(ctx context.Context, args map[string]inte
 
 	media := []string{}
 	if filePath != "" {
-		media = append(media, resolveFilePath(filePath, t.workspace))
+		media = append(media, resolveFilePath(filePath, WorkDir(ctx, t.workspace)))
 	}
 
 	content := text
@@ -528,6 +541,10 @@ func (t *WhatsAppSendTool) Description() string {
 	return "Send a message or file to a WhatsApp contact or group. Requires the gateway to be running. Use the JID format: 628123456789@s.whatsapp.net for contacts, or groupid@g.us for groups."
 }
 
+func (t *WhatsAppSendTool) Sequential() bool {
+	return true
+}
+
 func (t *WhatsAppSendTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -569,7 +586,7 @@ func (t *WhatsAppSendTool) Execute(ctx context.Context, args map[string]interfac
 
 	media := []string{}
 	if filePath != "" {
-		media = append(media, resolveFilePath(filePath, t.workspace))
+		media = append(media, resolveFilePath(filePath, WorkDir(ctx, t.workspace)))
 	}
 
 	content := text
@@ -28,6 +28,11 @@ func (t *SendFileTool) Name() string {
 	return "send_file"
 }
 
+// Sequential keeps files in order with the messages around them
+func (t *SendFileTool) Sequential() bool {
+	return true
+}
+
 func (t *SendFileTool) Description() string {
 	return "Send a file to a chat channel. Supports images, PDFs, documents, audio, video, and other file types. IMPORTANT: Always use the full absolute path for local files (e.g., /Users/.../.pepebot/workspace/file.pdf)."
 }
@@ -80,7 +85,7 @@ func (t *SendFileTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	// Resolve and validate path for local files
-	fileURL = t.resolveFilePath(fileURL)
+	fileURL = t.resolveFilePath(ctx, fileURL)
 
 	// Detect file type
 	fileType, mimeType := providers.DetectFileType(fileURL)
@@ -107,8 +112,11 @@ func (t *SendFileTool) Execute(ctx context.Context, args map[string]interface{})
 }
 
 // resolveFilePath resolves a file path to an absolute path.
-// If it's a URL, return as-is. If relative, resolve against workspace and common directories.
-func (t *SendFileTool) resolveFilePath(path string) string {
+// If it's a URL, return as-is. If relative, resolve against the session's
+// working directory or the workspace, and common directories.
+func (t *SendFileTool) resolveFilePath(ctx context.Context, path string) string {
+	workspace := WorkDir(ctx, t.workspace)
+
 	// URLs pass through unchanged
 	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "data:") {
 		return path
@@ -124,10 +132,10 @@ func (t *SendFileTool) resolveFilePath(path string) string {
 	// Try to find the file in common locations
 	basename := filepath.Base(path)
 	candidates := []string{
-		path,                                   // as given
-		filepath.Join(t.workspace, path),       // relative to workspace
-		filepath.Join(t.workspace, basename),   // just filename in workspace
-		filepath.Join("/tmp", basename),         // /tmp
+		path,                                             // as given
+		filepath.Join(workspace, path),                   // relative to workspace
+		filepath.Join(workspace, basename),               // just filename in workspace
+		filepath.Join("/tmp", basename),                  // /tmp
 		filepath.Join("/tmp/pepebot_whatsapp", basename), // whatsapp downloads
 	}
 
@@ -143,7 +151,7 @@ func (t *SendFileTool) resolveFilePath(path string) string {
 
 	// If nothing found but path is relative, at least make it absolute via workspace
 	if !filepath.IsAbs(path) {
-		return filepath.Join(t.workspace, path)
+		return filepath.Join(workspace, path)
 	}
 
 	return path
@@ -27,6 +27,11 @@ func (t *SendImageTool) Name() string {
 	return "send_image"
 }
 
+// Sequential keeps images in order with the messages around them
+func (t *SendImageTool) Sequential() bool {
+	return true
+}
+
 func (t *SendImageTool) Description() string {
 	return "Send an image to a chat channel. Use this when you need to show visual content to the user. IMPORTANT: Always use the full absolute path for local files (e.g., /Users/.../.pepebot/workspace/screenshot.png)."
 }
@@ -79,7 +84,7 @@ func (t *SendImageTool) Execute(ctx context.Context, args map[string]interface{}
 	}
 
 	// Resolve and validate path for local files
-	imageURL = t.resolveFilePath(imageURL)
+	imageURL = t.resolveFilePath(ctx, imageURL)
 
 	// Publish outbound message with media
 	t.bus.PublishOutbound(bus.OutboundMessage{
@@ -99,8 +104,11 @@ func (t *SendImageTool) Execute(ctx context.Context, args map[string]interface{}
 }
 
 // resolveFilePath resolves a file path to an absolute path.
-// If it's a URL, return as-is. If relative, resolve against workspace and common directories.
-func (t *SendImageTool) resolveFilePath(path string) string {
+// If it's a URL, return as-is. If relative, resolve against the session's
+// working directory or the workspace, and common directories.
+func (t *SendImageTool) resolveFilePath(ctx context.Context, path string) string {
+	workspace := WorkDir(ctx, t.workspace)
+
 	// URLs pass through unchanged
 	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "data:") {
 		return path
@@ -116,10 +124,10 @@ func (t *SendImageTool) resolveFilePath(path string) string {
 	// Try to find the file in common locations
 	basename := filepath.Base(path)
 	candidates := []string{
-		path,                                   // as given
-		filepath.Join(t.workspace, path),       // relative to workspace
-		filepath.Join(t.workspace, basename),   // just filename in workspace
-		filepath.Join("/tmp", basename),         // /tmp
+		path,                                             // as given
+		filepath.Join(workspace, path),                   // relative to workspace
+		filepath.Join(workspace, basename),               // just filename in workspace
+		filepath.Join("/tmp", basename),                  // /tmp
 		filepath.Join("/tmp/pepebot_whatsapp", basename), // whatsapp downloads
 	}
 
@@ -135,7 +143,7 @@ func (t *SendImageTool) resolveFilePath(path string) string {
 
 	// If nothing found but path is relative, at least make it absolute via workspace
 	if !filepath.IsAbs(path) {
-		return filepath.Join(t.workspace, path)
+		return filepath.Join(workspace, path)
 	}
 
 	return path
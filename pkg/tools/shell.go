@@ -45,6 +45,12 @@ func (t *ExecTool) Name() string {
 	return "exec"
 }
 
+// Sequential keeps commands in order; later ones often depend on earlier
+// ones, e.g. git add then git commit
+func (t *ExecTool) Sequential() bool {
+	return true
+}
+
 func (t *ExecTool) Description() string {
 	return "Execute a shell command and return its output. Use with caution."
 }
@@ -72,7 +78,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 		return "", fmt.Errorf("command is required")
 	}
 
-	cwd := t.workingDir
+	cwd := WorkDir(ctx, t.workingDir)
 	if wd, ok := args["working_dir"].(string); ok && wd != "" {
 		cwd = wd
 	}
@@ -95,6 +101,10 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	// Stop the command's children on timeout or /stop, and don't wait
+	// for background processes that keep the output pipes open
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 2 * time.Second
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -107,6 +117,9 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 	}
 
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("command stopped: %w", ctx.Err())
+		}
 		if cmdCtx.Err() == context.DeadlineExceeded {
 			return fmt.Sprintf("Error: Command timed out after %v", t.timeout), nil
 		}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecToolStopsOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+
+	tool := NewExecTool(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	// The pipeline's children keep stdout open after sh is killed
+	started := time.Now()
+	_, err := tool.Execute(ctx, map[string]interface{}{"command": "sleep 30 | cat"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("command kept running for %v after cancel", elapsed)
+	}
+}
+
+func TestSessionWorkDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	workspace := t.TempDir()
+	dir := SessionWorkDir(workspace, "telegram:123/../x")
+	if dir != filepath.Join(workspace, "sessions", "telegram_123_.._x") {
+		t.Fatalf("SessionWorkDir = %s", dir)
+	}
+	os.MkdirAll(dir, 0755)
+	ctx := WithWorkDir(context.Background(), dir)
+
+	if _, err := NewWriteFileTool(workspace).Execute(ctx, map[string]interface{}{"path": "notes.txt", "content": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "notes.txt"), "hi")
+	if _, err := os.Stat(filepath.Join(workspace, "notes.txt")); !os.IsNotExist(err) {
+		t.Error("file was written to the workspace root")
+	}
+
+	out, err := NewExecTool(workspace).Execute(ctx, map[string]interface{}{"command": "cat notes.txt"})
+	if err != nil || strings.TrimSpace(out) != "hi" {
+		t.Errorf("exec in session dir = %q, %v", out, err)
+	}
+	if _, err := NewReadFileTool(workspace).Execute(context.Background(), map[string]interface{}{"path": "notes.txt"}); err == nil {
+		t.Error("without a session dir, paths should resolve to the workspace root")
+	}
+
+	// The table tools find what the file tools wrote
+	if _, err := NewCSVWriteTool(workspace).Execute(ctx, map[string]interface{}{"path": "rows.csv", "columns": []interface{}{"a", "b"}, "rows": []interface{}{[]interface{}{"1", "2"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCSVReadTool(workspace).Execute(ctx, map[string]interface{}{"path": "rows.csv"}); err != nil {
+		t.Errorf("csv_read in session dir: %v", err)
+	}
+
+	// Other sessions' directories are off limits, the rest of the
+	// workspace is not
+	other := SessionWorkDir(workspace, "discord:7")
+	os.MkdirAll(other, 0755)
+	os.WriteFile(filepath.Join(other, "plan.txt"), []byte("theirs"), 0644)
+	registry := NewToolRegistry()
+	registry.Register(NewReadFileTool(workspace))
+	registry.Register(NewArchiveCreateTool(workspace))
+	registry.SetGuard(NewFileGuard(workspace))
+	for _, p := range []string{"../discord_7/plan.txt", filepath.Join(other, "plan.txt")} {
+		if _, err := registry.Execute(ctx, "read_file", map[string]interface{}{"path": p}); err == nil {
+			t.Errorf("read of %s from another session allowed", p)
+		}
+	}
+	if _, err := registry.Execute(ctx, "archive_create", map[string]interface{}{"path": "x.zip", "sources": []interface{}{"../discord_7"}}); err == nil {
+		t.Error("archive of another session allowed")
+	}
+	os.WriteFile(filepath.Join(workspace, "shared.txt"), []byte("all"), 0644)
+	if _, err := registry.Execute(ctx, "read_file", map[string]interface{}{"path": filepath.Join(workspace, "shared.txt")}); err != nil {
+		t.Errorf("read of a shared workspace file: %v", err)
+	}
+}
+
+func TestCommandsAndMessagesAreSequential(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(NewExecTool(t.TempDir()))
+	registry.Register(NewMessageTool())
+	registry.Register(NewTelegramSendTool("token", t.TempDir()))
+	registry.Register(NewReadFileTool(t.TempDir()))
+	for _, name := range []string{"exec", "message", "telegram_send"} {
+		if !registry.IsSequential(name) {
+			t.Errorf("%s should run sequentially", name)
+		}
+	}
+	if registry.IsSequential("read_file") {
+		t.Error("read_file can run in parallel")
+	}
+}
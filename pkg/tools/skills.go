@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/skills"
+)
+
+// SkillLoader returns the body of a skill the agent is allowed to use
+type SkillLoader interface {
+	LoadSkill(name string) (string, bool)
+}
+
+// ==================== load_skill ====================
+
+type LoadSkillTool struct {
+	loader SkillLoader
+}
+
+// NewLoadSkillTool creates the load_skill tool.
+func NewLoadSkillTool(loader SkillLoader) *LoadSkillTool {
+	return &LoadSkillTool{loader: loader}
+}
+
+func (t *LoadSkillTool) Name() string { return "load_skill" }
+
+func (t *LoadSkillTool) Description() string {
+	return "Load the full instructions of a skill listed under Available Skills. Call it before following a skill; only skill summaries are kept in context."
+}
+
+func (t *LoadSkillTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Skill name as shown in the skills list",
+			},
+		},
+		"required": []string{"name"},
+	}
+}
+
+func (t *LoadSkillTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid skill name '%s'", name)
+	}
+
+	content, ok := t.loader.LoadSkill(name)
+	if !ok {
+		return "", fmt.Errorf("skill '%s' not found or not enabled for this agent", name)
+	}
+	return fmt.Sprintf("# Skill: %s\n\n%s", name, content), nil
+}
+
+// ==================== skill_create ====================
+
+type SkillCreateTool struct {
+	loader *skills.SkillsLoader
+}
+
+// NewSkillCreateTool creates the skill_create tool.
+func NewSkillCreateTool(loader *skills.SkillsLoader) *SkillCreateTool {
+	return &SkillCreateTool{loader: loader}
+}
+
+func (t *SkillCreateTool) Name() string { return "skill_create" }
+
+func (t *SkillCreateTool) Description() string {
+	return "Save a reusable procedure as a new skill in workspace/skills/{name}/SKILL.md, e.g. after a multi-step task worked and the user wants it repeatable. " +
+		"Get user confirmation BEFORE calling with confirmed=true. " +
+		"Without confirmed=true, validates the skill and returns a preview only."
+}
+
+func (t *SkillCreateTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Skill name: lowercase letters, digits and hyphens (e.g. 'deploy-blog')",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "One-line summary shown in the skills list; say when to use the skill",
+			},
+			"instructions": map[string]interface{}{
+				"type":        "string",
+				"description": "Markdown body: the steps, commands and checks that made the task succeed",
+			},
+			"requires": map[string]interface{}{
+				"type":        "object",
+				"description": "Optional requirements, e.g. {\"bins\": [\"ffmpeg\"], \"env\": [\"API_KEY\"]}",
+			},
+			"always": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Always include the full skill in context (default: false)",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace an existing workspace skill with the same name (default: false)",
+			},
+			"confirmed": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Must be true to write the skill. First call without confirmed=true returns a preview for the user. Only set to true after the user has approved it.",
+			},
+		},
+		"required": []string{"name", "description", "instructions"},
+	}
+}
+
+func (t *SkillCreateTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	name, _ := args["name"].(string)
+	description, _ := args["description"].(string)
+	instructions, _ := args["instructions"].(string)
+	always, _ := args["always"].(bool)
+	overwrite, _ := args["overwrite"].(bool)
+	confirmed, _ := args["confirmed"].(bool)
+
+	meta := skills.SkillMetadata{
+		Name:        strings.TrimSpace(name),
+		Description: description,
+		Always:      always,
+	}
+	if raw, ok := args["requires"]; ok && raw != nil {
+		data, _ := json.Marshal(raw)
+		var requires skills.SkillRequirements
+		if err := json.Unmarshal(data, &requires); err != nil {
+			return "", fmt.Errorf("invalid requires: %w", err)
+		}
+		meta.Requires = &requires
+	}
+
+	content, err := skills.RenderSkill(meta, instructions)
+	if err != nil {
+		return "", err
+	}
+
+	// Confirmation gate: the first call returns a preview, the user must approve it
+	if !confirmed {
+		return fmt.Sprintf("Preview of skill '%s' (not saved yet):\n\n%s\n"+
+			"Show this to the user and ask them to confirm, then call skill_create again with the same arguments and confirmed=true.", meta.Name, content), nil
+	}
+
+	path, err := t.loader.CreateSkill(meta.Name, content, overwrite)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Skill '%s' saved to %s. It is listed under Available Skills from the next message.", meta.Name, path), nil
+}
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/pepebot-space/pepebot/pkg/feeds"
 )
 
 const (
@@ -132,8 +136,12 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}
 	return strings.Join(lines, "\n"), nil
 }
 
+// maxFetchBytes bounds how much of a response web_fetch reads
+const maxFetchBytes = 10 << 20
+
 type WebFetchTool struct {
 	maxChars int
+	browser  string
 }
 
 func NewWebFetchTool(maxChars int) *WebFetchTool {
@@ -145,12 +153,18 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 	}
 }
 
+// SetBrowser sets the browser used for render=true; when empty an installed
+// Chromium based browser is looked up
+func (t *WebFetchTool) SetBrowser(path string) {
+	t.browser = path
+}
+
 func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and extract readable content. HTML pages are reduced to the main article as Markdown, JSON is pretty-printed and RSS/Atom feeds are listed. Set render=true for pages that need JavaScript. Use this to get weather info, news, articles, or any web content."
 }
 
 func (t *WebFetchTool) Parameters() map[string]interface{} {
@@ -166,6 +180,15 @@ func (t *WebFetchTool) Parameters() map[string]interface{} {
 				"description": "Maximum characters to extract",
 				"minimum":     100.0,
 			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"article", "page", "html"},
+				"description": "For HTML: 'article' (default) extracts the main content as Markdown, 'page' converts the whole page, 'html' returns the raw HTML",
+			},
+			"render": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Render the page in a headless browser first, for sites built with JavaScript. By default pages that come back nearly empty are rendered when a browser is installed.",
+			},
 		},
 		"required": []string{"url"},
 	}
@@ -197,6 +220,16 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	}
 
+	format, _ := args["format"].(string)
+	switch format {
+	case "":
+		format = "article"
+	case "article", "page", "html":
+	default:
+		return "", fmt.Errorf("format must be article, page or html")
+	}
+	render, renderSet := args["render"].(bool)
+
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
@@ -226,16 +259,27 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
 	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
 
-	var text, extractor string
+	result := map[string]interface{}{
+		"url":          urlStr,
+		"status":       resp.StatusCode,
+		"content_type": mediaType,
+	}
 
-	if strings.Contains(contentType, "application/json") {
+	var text, extractor string
+	switch {
+	case isJSONType(mediaType):
 		var jsonData interface{}
 		if err := json.Unmarshal(body, &jsonData); err == nil {
 			formatted, _ := json.MarshalIndent(jsonData, "", "  ")
@@ -245,13 +289,53 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 			text = string(body)
 			extractor = "raw"
 		}
-	} else if strings.Contains(contentType, "text/html") || len(body) > 0 &&
-		(strings.HasPrefix(string(body), "<!DOCTYPE") || strings.HasPrefix(strings.ToLower(string(body)), "<html")) {
-		text = t.extractText(string(body))
-		extractor = "text"
-	} else {
+
+	case isFeed(mediaType, body):
+		if feed, err := feeds.Parse(body); err == nil {
+			result["title"] = feed.Title
+			text = feedMarkdown(feed)
+			extractor = "feed"
+		} else {
+			text = string(body)
+			extractor = "raw"
+		}
+
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || looksLikeHTML(body):
+		page := string(body)
+		base := resp.Request.URL
+		text, extractor = t.extractHTML(page, base, format, result)
+
+		// Pages built by JavaScript come back as an empty shell
+		needsJS := len(strings.TrimSpace(text)) < 200 && strings.Contains(strings.ToLower(page), "<script")
+		if render || (!renderSet && needsJS && format != "html") {
+			browser := FindBrowser(t.browser)
+			switch {
+			case browser != "":
+				rendered, err := renderPage(ctx, browser, base.String())
+				if err != nil {
+					if render {
+						return "", fmt.Errorf("failed to render page: %w", err)
+					}
+					result["note"] = "Rendering with a headless browser failed: " + err.Error()
+					break
+				}
+				text, extractor = t.extractHTML(rendered, base, format, result)
+				extractor += "+render"
+				result["rendered"] = true
+			case render:
+				return "", fmt.Errorf("render=true needs Chrome, Chromium or Edge; none was found (set tools.web.fetch.browser)")
+			default:
+				result["note"] = "The page looks like it needs JavaScript and no headless browser is installed (Chrome, Chromium or Edge)"
+			}
+		}
+
+	case strings.HasPrefix(mediaType, "text/") || isTextType(mediaType) || (utf8.Valid(body) && !isBinaryType(mediaType)):
 		text = string(body)
 		extractor = "raw"
+
+	default:
+		extractor = "binary"
+		text = fmt.Sprintf("(binary content, %s, %d bytes not shown)", mediaType, len(body))
 	}
 
 	truncated := len(text) > maxChars
@@ -259,19 +343,100 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		text = text[:maxChars]
 	}
 
-	result := map[string]interface{}{
-		"url":       urlStr,
-		"status":    resp.StatusCode,
-		"extractor": extractor,
-		"truncated": truncated,
-		"length":    len(text),
-		"text":      text,
-	}
+	result["extractor"] = extractor
+	result["truncated"] = truncated
+	result["length"] = len(text)
+	result["text"] = text
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	return string(resultJSON), nil
 }
 
+// extractHTML converts a page according to format, adding the title to result
+func (t *WebFetchTool) extractHTML(page string, base *url.URL, format string, result map[string]interface{}) (string, string) {
+	switch format {
+	case "html":
+		return page, "html"
+	case "page":
+		if md, err := pageTextMarkdown(page, base); err == nil {
+			return md, "page"
+		}
+	default:
+		if a, err := extractArticle(page, base); err == nil {
+			if a.Title != "" {
+				result["title"] = a.Title
+			}
+			return a.Markdown, "readability"
+		}
+	}
+	return t.extractText(page), "text"
+}
+
+func isJSONType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isFeed(mediaType string, body []byte) bool {
+	switch mediaType {
+	case "application/rss+xml", "application/atom+xml", "application/feed+xml":
+		return true
+	case "application/xml", "text/xml":
+		head := strings.ToLower(string(body[:min(len(body), 1024)]))
+		return strings.Contains(head, "<rss") || strings.Contains(head, "<feed")
+	}
+	return false
+}
+
+func isTextType(mediaType string) bool {
+	switch mediaType {
+	case "application/xml", "application/javascript", "application/x-yaml", "application/yaml", "application/csv":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+xml")
+}
+
+func isBinaryType(mediaType string) bool {
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	switch mediaType {
+	case "application/octet-stream", "application/pdf", "application/zip", "application/gzip", "application/x-tar":
+		return true
+	}
+	return false
+}
+
+func looksLikeHTML(body []byte) bool {
+	head := strings.ToLower(strings.TrimSpace(string(body[:min(len(body), 512)])))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html")
+}
+
+// feedMarkdown lists the items of a feed, newest first as published
+func feedMarkdown(feed *feeds.Feed) string {
+	var b strings.Builder
+	if feed.Title != "" {
+		b.WriteString("# " + feed.Title + "\n\n")
+	}
+	for _, item := range feed.Items {
+		b.WriteString("- ")
+		if item.Link != "" {
+			b.WriteString("[" + item.Title + "](" + item.Link + ")")
+		} else {
+			b.WriteString(item.Title)
+		}
+		if !item.Published.IsZero() {
+			b.WriteString(" (" + item.Published.Format("2006-01-02") + ")")
+		}
+		b.WriteString("\n")
+		if item.Summary != "" {
+			b.WriteString("  " + item.Summary + "\n")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
 func (t *WebFetchTool) extractText(htmlContent string) string {
 	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
 	result := re.ReplaceAllLiteralString(htmlContent, "")
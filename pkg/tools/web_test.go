@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title>Fallback</title><meta property="og:title" content="Home Batteries Compared"></head>
+<body>
+<header class="site-header"><nav><a href="/">Home</a> <a href="/news">News</a></nav></header>
+<div class="sidebar"><p>Subscribe to our newsletter for weekly updates, offers, and more news.</p></div>
+<div id="content">
+  <h1>Home Batteries Compared</h1>
+  <p>Home batteries store solar power for the evening, and prices have dropped by a third since last year, which changes the math for most households.</p>
+  <p>We compared <strong>five models</strong> on capacity, warranty, and price, using the <a href="/method">same method</a> as last year.</p>
+  <ul><li>Capacity</li><li>Warranty</li></ul>
+  <table><tr><th>Model</th><th>kWh</th></tr><tr><td>A</td><td>10</td></tr></table>
+</div>
+<footer>Copyright, all rights reserved, terms, privacy, cookies, and so on.</footer>
+<script>track()</script>
+</body></html>`
+
+const rssFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Release notes</title>
+<item><title>v1.2 released</title><link>https://example.com/v1.2</link><pubDate>Mon, 02 Feb 2026 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestWebFetchContentTypes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(articlePage))
+		case "/data":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"a":1}`))
+		case "/feed":
+			w.Header().Set("Content-Type", "text/xml")
+			w.Write([]byte(rssFeed))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G', 0, 0xff})
+		}
+	}))
+	defer srv.Close()
+
+	tool := NewWebFetchTool(0)
+	fetch := func(path string, extra map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		args := map[string]interface{}{"url": srv.URL + path, "render": false}
+		for k, v := range extra {
+			args[k] = v
+		}
+		out, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	article := fetch("/article", nil)
+	text, _ := article["text"].(string)
+	if article["extractor"] != "readability" || article["title"] != "Home Batteries Compared" {
+		t.Errorf("article result = %v", article)
+	}
+	for _, want := range []string{"# Home Batteries Compared", "**five models**", "[same method](" + srv.URL + "/method)", "- Capacity", "| Model | kWh |"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("article text lacks %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"newsletter", "Copyright", "track()", "News"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("article text contains %q:\n%s", unwanted, text)
+		}
+	}
+
+	if page := fetch("/article", map[string]interface{}{"format": "page"}); !strings.Contains(page["text"].(string), "Copyright") {
+		t.Errorf("page format should keep the whole page: %v", page["text"])
+	}
+
+	if data := fetch("/data", nil); data["extractor"] != "json" || data["text"] != "{\n  \"a\": 1\n}" {
+		t.Errorf("json result = %v", data)
+	}
+
+	feed := fetch("/feed", nil)
+	if feed["extractor"] != "feed" || !strings.Contains(feed["text"].(string), "- [v1.2 released](https://example.com/v1.2) (2026-02-02)") {
+		t.Errorf("feed result = %v", feed)
+	}
+
+	if img := fetch("/image", nil); img["extractor"] != "binary" {
+		t.Errorf("image result = %v", img)
+	}
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pepebot-space/pepebot/pkg/channels"
+)
+
+// WhatsAppDirectory is implemented by the running WhatsApp channel.
+type WhatsAppDirectory interface {
+	ListGroups(ctx context.Context) ([]channels.WhatsAppGroup, error)
+	ResolveContact(ctx context.Context, query string) ([]channels.WhatsAppContact, error)
+	GroupMembers(ctx context.Context, group string) ([]channels.WhatsAppGroupMember, error)
+}
+
+// ==================== WhatsApp List Groups Tool ====================
+
+type WhatsAppListGroupsTool struct {
+	directory WhatsAppDirectory
+}
+
+func NewWhatsAppListGroupsTool(directory WhatsAppDirectory) *WhatsAppListGroupsTool {
+	return &WhatsAppListGroupsTool{directory: directory}
+}
+
+func (t *WhatsAppListGroupsTool) Name() string {
+	return "whatsapp_list_groups"
+}
+
+func (t *WhatsAppListGroupsTool) Description() string {
+	return "List WhatsApp groups the bot has joined, with their JIDs and participant counts. Use the JID as chat_id when sending to a group."
+}
+
+func (t *WhatsAppListGroupsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *WhatsAppListGroupsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	groups, err := t.directory.ListGroups(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	result, _ := json.MarshalIndent(groups, "", "  ")
+	return string(result), nil
+}
+
+// ==================== WhatsApp Resolve Contact Tool ====================
+
+type WhatsAppResolveContactTool struct {
+	directory WhatsAppDirectory
+}
+
+func NewWhatsAppResolveContactTool(directory WhatsAppDirectory) *WhatsAppResolveContactTool {
+	return &WhatsAppResolveContactTool{directory: directory}
+}
+
+func (t *WhatsAppResolveContactTool) Name() string {
+	return "whatsapp_resolve_contact"
+}
+
+func (t *WhatsAppResolveContactTool) Description() string {
+	return "Look up WhatsApp contacts by name or phone number and return their JIDs. Use this to address people by name instead of asking the user for a raw JID."
+}
+
+func (t *WhatsAppResolveContactTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Contact name (partial, case-insensitive) or phone number",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *WhatsAppResolveContactTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	query, ok := args["query"].(string)
+	if !ok || query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	contacts, err := t.directory.ResolveContact(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	if len(contacts) == 0 {
+		return fmt.Sprintf("No WhatsApp contact matches %q", query), nil
+	}
+
+	result, _ := json.MarshalIndent(contacts, "", "  ")
+	return string(result), nil
+}
+
+// ==================== WhatsApp Group Members Tool ====================
+
+type WhatsAppGroupMembersTool struct {
+	directory WhatsAppDirectory
+}
+
+func NewWhatsAppGroupMembersTool(directory WhatsAppDirectory) *WhatsAppGroupMembersTool {
+	return &WhatsAppGroupMembersTool{directory: directory}
+}
+
+func (t *WhatsAppGroupMembersTool) Name() string {
+	return "whatsapp_group_members"
+}
+
+func (t *WhatsAppGroupMembersTool) Description() string {
+	return "List the members of a WhatsApp group with their JIDs, phone numbers, names and admin status."
+}
+
+func (t *WhatsAppGroupMembersTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"group": map[string]interface{}{
+				"type":        "string",
+				"description": "Group JID (ending in @g.us) or group name",
+			},
+		},
+		"required": []string{"group"},
+	}
+}
+
+func (t *WhatsAppGroupMembersTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	group, ok := args["group"].(string)
+	if !ok || group == "" {
+		return "", fmt.Errorf("group is required")
+	}
+
+	members, err := t.directory.GroupMembers(ctx, group)
+	if err != nil {
+		return "", err
+	}
+
+	result, _ := json.MarshalIndent(members, "", "  ")
+	return string(result), nil
+}
@@ -33,6 +33,9 @@ type WorkflowExecuteTool struct {
 
 func (t *WorkflowExecuteTool) Name() string { return "workflow_execute" }
 
+// Workflows usually drive a device, so they never overlap with other calls
+func (t *WorkflowExecuteTool) Sequential() bool { return true }
+
 func (t *WorkflowExecuteTool) Description() string {
 	return "Execute a workflow from a JSON file. Workflows are multi-step automations that can call any registered tools (ADB, shell, browser, messaging, etc.) with variable interpolation and goal-based steps. Messaging tools available in workflows: telegram_send, discord_send, whatsapp_send."
 }
@@ -94,13 +97,18 @@ func (t *WorkflowSaveTool) Name() string { return "workflow_save" }
 func (t *WorkflowSaveTool) Description() string {
 	return `Save a workflow JSON file. IMPORTANT: Only use this tool when the user EXPLICITLY asks to create or save a workflow. Do NOT proactively create workflows.
 
-4 STEP TYPES:
+7 STEP TYPES:
 - Tool step: {"name":"id", "tool":"tool_name", "args":{"param":"value"}} — Execute a registered tool. MUST have "args" even if empty {}.
 - Goal step: {"name":"id", "goal":"instruction"} — Natural language for LLM to interpret.
 - Skill step: {"name":"id", "skill":"skill_name", "goal":"instruction"} — Load a skill's content + combine with goal. IMPORTANT: When the user says "use skill X" or "with skill X", ALWAYS use this step type. Do NOT manually replicate the skill's commands via tool steps.
 - Agent step: {"name":"id", "agent":"agent_name", "goal":"instruction"} — Delegate goal to another agent. The agent processes independently and returns a response.
+- Team step: {"name":"id", "team":"team_name", "goal":"instruction"} — Hand the goal to a team (see manage_agent create_team). The coordinator splits it among the members and returns the combined answer.
+- Extract step: {"name":"id", "extract":".devices[0].serial", "from":"step_name"} — Pull a value out of a step's JSON output without an LLM call ("[]" iterates, "regex:<pattern>" for plain text; first group wins). "from" defaults to the previous step.
+- Workflow step: {"name":"id", "workflow":"other_workflow", "vars":{"key":"{{value}}"}} — Run another saved workflow with these variables. {{id_output}} is the output of its last step. Save the called workflow first; calls cannot loop and nest at most 5 deep.
+
+RULES: (1) "tool" cannot combine with "skill"/"agent"/"team". (2) "skill", "agent" and "team" are mutually exclusive. (3) "skill", "agent" and "team" REQUIRE "goal". (4) "extract" and "workflow" stand alone. (5) Use {{variable}} for interpolation. (6) Step outputs auto-stored as {{step_name_output}}. (7) Never put API keys or passwords in the JSON: use {{secret:NAME}} in tool args (the user stores it with 'pepebot secrets set NAME').
 
-RULES: (1) "tool" cannot combine with "skill"/"agent". (2) "skill" and "agent" are mutually exclusive. (3) "skill" and "agent" REQUIRE "goal". (4) Use {{variable}} for interpolation. (5) Step outputs auto-stored as {{step_name_output}}.
+SCHEDULE (only when the user asks for the workflow to run on its own, e.g. "every morning"): add {"schedule": {"cron":"0 7 * * *", "tz":"Asia/Jakarta", "channel":"telegram", "chat_id":"123"}} at the top level. "channel"/"chat_id" are optional and receive the result. The gateway picks up schedules when it starts.
 
 CHANNEL MESSAGING TOOLS (use in tool steps to send notifications):
 - telegram_send: {"chat_id":"123456789", "text":"msg"} or {"chat_id":"...", "file_path":"/path/img.png", "caption":"..."}
@@ -161,7 +169,11 @@ func (t *WorkflowSaveTool) Execute(ctx context.Context, args map[string]interfac
 	if !strings.HasSuffix(path, ".json") {
 		path += ".json"
 	}
-	return fmt.Sprintf("Workflow saved successfully to: %s\nName: %s\nDescription: %s\nSteps: %d", path, wf.Name, wf.Description, len(wf.Steps)), nil
+	result := fmt.Sprintf("Workflow saved successfully to: %s\nName: %s\nDescription: %s\nSteps: %d", path, wf.Name, wf.Description, len(wf.Steps))
+	if wf.Schedule != nil {
+		result += fmt.Sprintf("\nSchedule: %s (active after the next gateway restart)", wf.Schedule.Cron)
+	}
+	return result, nil
 }
 
 // ==================== workflow_list ====================
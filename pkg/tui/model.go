@@ -0,0 +1,629 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Message is one entry of a session's history
+type Message struct {
+	Role    string // "user" or "assistant"
+	Content string
+}
+
+// ToolEvent is a tool call starting, or finishing when Done is set
+type ToolEvent struct {
+	ID       string
+	Name     string
+	Args     string
+	Done     bool
+	Failed   bool
+	Duration time.Duration
+}
+
+type entryKind int
+
+const (
+	entryUser entryKind = iota
+	entryAgent
+	entryInfo
+	entryError
+)
+
+type entry struct {
+	kind entryKind
+	text string
+}
+
+const (
+	maxToolRows = 100
+	sidebarMin  = 60 // narrower terminals hide the tool sidebar
+	sidebarMax  = 34
+)
+
+var (
+	headerStyle   = lipgloss.NewStyle().Reverse(true)
+	userStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	agentStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2"))
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	runningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	boldStyle     = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	sidebarStyle  = lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			BorderForeground(lipgloss.Color("8"))
+)
+
+// Messages a running turn sends back to the model
+type (
+	answerMsg string
+	toolMsg   ToolEvent
+	turnDone  struct {
+		err     error
+		stopped bool
+	}
+)
+
+// picker is the session switcher
+type picker struct {
+	items    []string
+	selected int
+}
+
+// model is the bubbletea model of the interface. Turns run in a goroutine
+// and feed their output back through the turn channel.
+type model struct {
+	ctx     context.Context
+	backend Backend
+	session string
+
+	entries []entry
+	tools   []ToolEvent // newest last
+
+	input        textinput.Model
+	inputHistory []string
+	historyPos   int
+
+	history   viewport.Model
+	spinner   spinner.Model
+	picker    *picker
+	showTools bool
+
+	width, height int
+	busy          bool
+	turn          chan tea.Msg
+	cancelTurn    context.CancelFunc
+}
+
+func newModel(ctx context.Context, backend Backend, session string) *model {
+	input := textinput.New()
+	input.Prompt = "› "
+	input.PromptStyle = boldStyle
+	input.Focus()
+
+	m := &model{
+		ctx:       ctx,
+		backend:   backend,
+		input:     input,
+		history:   viewport.New(0, 0),
+		spinner:   spinner.New(spinner.WithSpinner(spinner.Dot)),
+		showTools: true,
+		width:     80,
+		height:    24,
+	}
+	m.loadSession(session)
+	return m
+}
+
+func (m *model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.refresh()
+		return m, nil
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+	case answerMsg:
+		m.appendAnswer(string(msg))
+		return m, m.listen()
+	case toolMsg:
+		m.toolEvent(ToolEvent(msg))
+		return m, m.listen()
+	case turnDone:
+		m.finishTurn(msg)
+		return m, nil
+	case spinner.TickMsg:
+		if !m.busy {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		m.refresh()
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *model) info(format string, a ...interface{}) {
+	m.entries = append(m.entries, entry{kind: entryInfo, text: fmt.Sprintf(format, a...)})
+	m.refresh()
+	m.history.GotoBottom()
+}
+
+func (m *model) fail(err error) {
+	m.entries = append(m.entries, entry{kind: entryError, text: err.Error()})
+	m.refresh()
+	m.history.GotoBottom()
+}
+
+// loadSession replaces the history with the stored messages of key
+func (m *model) loadSession(key string) {
+	m.session = key
+	m.entries = nil
+	m.tools = nil
+	for _, msg := range m.backend.History(key) {
+		switch msg.Role {
+		case "user":
+			m.entries = append(m.entries, entry{kind: entryUser, text: msg.Content})
+		case "assistant":
+			m.entries = append(m.entries, entry{kind: entryAgent, text: msg.Content})
+		}
+	}
+	m.refresh()
+	m.history.GotoBottom()
+}
+
+// send shows the user's message, runs the turn and streams the answer in
+func (m *model) send(text string) tea.Cmd {
+	m.entries = append(m.entries, entry{kind: entryUser, text: text}, entry{kind: entryAgent})
+	m.busy = true
+	m.refresh()
+	m.history.GotoBottom()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	events := make(chan tea.Msg, 64)
+	m.turn, m.cancelTurn = events, cancel
+	sessionKey := m.session
+
+	// Output of a stopped turn is dropped rather than blocking the backend
+	emit := func(msg tea.Msg) {
+		select {
+		case events <- msg:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		err := m.backend.Send(ctx, sessionKey, text,
+			func(s string) { emit(answerMsg(s)) },
+			func(ev ToolEvent) { emit(toolMsg(ev)) },
+		)
+		stopped := ctx.Err() != nil
+		cancel()
+		select {
+		case events <- turnDone{err: err, stopped: stopped}:
+		case <-m.ctx.Done():
+		}
+	}()
+	return tea.Batch(m.listen(), m.spinner.Tick)
+}
+
+// listen waits for the next message of the running turn
+func (m *model) listen() tea.Cmd {
+	events := m.turn
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+func (m *model) stopTurn() {
+	if m.cancelTurn != nil {
+		m.cancelTurn()
+	}
+}
+
+func (m *model) appendAnswer(text string) {
+	if n := len(m.entries); n > 0 && m.entries[n-1].kind == entryAgent {
+		m.entries[n-1].text += text
+	} else {
+		m.entries = append(m.entries, entry{kind: entryAgent, text: text})
+	}
+	m.refresh()
+}
+
+func (m *model) finishTurn(done turnDone) {
+	m.busy = false
+	m.turn, m.cancelTurn = nil, nil
+	// Drop the placeholder of an answer that never started
+	if n := len(m.entries); n > 0 && m.entries[n-1].kind == entryAgent && m.entries[n-1].text == "" {
+		m.entries = m.entries[:n-1]
+	}
+	switch {
+	case done.stopped:
+		m.info("Stopped")
+	case done.err != nil:
+		m.fail(done.err)
+	default:
+		m.refresh()
+	}
+}
+
+func (m *model) toolEvent(ev ToolEvent) {
+	defer m.refresh()
+	if ev.Done {
+		for i := len(m.tools) - 1; i >= 0; i-- {
+			if m.tools[i].ID == ev.ID && !m.tools[i].Done {
+				m.tools[i] = ev
+				return
+			}
+		}
+	}
+	m.tools = append(m.tools, ev)
+	if len(m.tools) > maxToolRows {
+		m.tools = m.tools[len(m.tools)-maxToolRows:]
+	}
+}
+
+func (m *model) openPicker(sessions []string) {
+	p := &picker{items: sessions}
+	for i, s := range sessions {
+		if s == m.session {
+			p.selected = i
+		}
+	}
+	m.picker = p
+	m.refresh()
+}
+
+// handleKey applies a key press
+func (m *model) handleKey(k tea.KeyMsg) tea.Cmd {
+	if m.picker != nil {
+		return m.pickerKey(k)
+	}
+
+	switch k.String() {
+	case "ctrl+c":
+		if m.busy {
+			m.stopTurn()
+			return nil
+		}
+		return m.quit()
+	case "ctrl+d":
+		if m.input.Value() == "" {
+			return m.quit()
+		}
+	case "esc":
+		m.stopTurn()
+		return nil
+	case "enter":
+		return m.submit()
+	case "up":
+		if m.historyPos > 0 {
+			m.historyPos--
+			m.setInput(m.inputHistory[m.historyPos])
+		}
+		return nil
+	case "down":
+		if m.historyPos < len(m.inputHistory)-1 {
+			m.historyPos++
+			m.setInput(m.inputHistory[m.historyPos])
+		} else {
+			m.historyPos = len(m.inputHistory)
+			m.setInput("")
+		}
+		return nil
+	case "pgup":
+		m.history.PageUp()
+		m.refresh()
+		return nil
+	case "pgdown":
+		m.history.PageDown()
+		m.refresh()
+		return nil
+	case "tab":
+		m.showTools = !m.showTools
+		m.refresh()
+		return nil
+	case "ctrl+s":
+		return m.command("/sessions")
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(k)
+	return cmd
+}
+
+func (m *model) submit() tea.Cmd {
+	text := strings.TrimSpace(m.input.Value())
+	if text == "" {
+		return nil
+	}
+	if m.busy && !strings.HasPrefix(text, "/") {
+		m.info("Still answering; press Esc to stop it first")
+		return nil
+	}
+	if len(m.inputHistory) == 0 || m.inputHistory[len(m.inputHistory)-1] != text {
+		m.inputHistory = append(m.inputHistory, text)
+	}
+	m.historyPos = len(m.inputHistory)
+	m.setInput("")
+
+	switch {
+	case text == "exit" || text == "quit":
+		return m.quit()
+	case strings.HasPrefix(text, "/"):
+		return m.command(text)
+	}
+	return m.send(text)
+}
+
+func (m *model) quit() tea.Cmd {
+	m.stopTurn()
+	return tea.Quit
+}
+
+func (m *model) pickerKey(k tea.KeyMsg) tea.Cmd {
+	p := m.picker
+	switch k.String() {
+	case "up":
+		if p.selected > 0 {
+			p.selected--
+		}
+	case "down":
+		if p.selected < len(p.items)-1 {
+			p.selected++
+		}
+	case "enter":
+		m.picker = nil
+		if len(p.items) > 0 {
+			m.switchSession(p.items[p.selected])
+		}
+	case "esc", "ctrl+c", "ctrl+s":
+		m.picker = nil
+	}
+	m.refresh()
+	if m.picker == nil {
+		m.history.GotoBottom()
+	}
+	return nil
+}
+
+func (m *model) switchSession(key string) {
+	if m.busy {
+		m.info("Wait for the answer to finish, or press Esc, before switching sessions")
+		return
+	}
+	m.loadSession(key)
+	m.info("Switched to session %s", key)
+}
+
+// command runs a slash command
+func (m *model) command(input string) tea.Cmd {
+	fields := strings.Fields(input)
+	name := strings.ToLower(fields[0])
+	arg := strings.TrimSpace(strings.TrimPrefix(input, fields[0]))
+
+	switch name {
+	case "/quit", "/exit":
+		return m.quit()
+	case "/help":
+		m.info("%s", helpText)
+	case "/status":
+		m.info("Agent: %s · Model: %s · Session: %s", m.backend.AgentName(), m.backend.Model(), m.session)
+	case "/tools":
+		m.showTools = !m.showTools
+		m.refresh()
+	case "/clear":
+		m.entries = nil
+		m.refresh()
+	case "/sessions":
+		if m.busy {
+			m.info("Wait for the answer to finish, or press Esc, before switching sessions")
+			break
+		}
+		m.openPicker(m.backend.Sessions())
+	case "/session":
+		if arg == "" {
+			m.info("Usage: /session <key>")
+			break
+		}
+		m.switchSession(arg)
+	case "/new":
+		if m.busy {
+			m.info("Wait for the answer to finish, or press Esc, before clearing the session")
+			break
+		}
+		m.backend.Clear(m.session)
+		m.loadSession(m.session)
+		m.info("Session cleared. Starting fresh conversation.")
+	case "/fork":
+		if arg == "" {
+			m.info("Usage: /fork <new-key>")
+			break
+		}
+		if m.busy {
+			m.info("Wait for the answer to finish, or press Esc, before forking")
+			break
+		}
+		from := m.session
+		if err := m.backend.Fork(from, arg); err != nil {
+			m.fail(err)
+			break
+		}
+		m.switchSession(arg)
+		m.info("Forked session %s into %s", from, arg)
+	default:
+		out, ok := m.backend.Command(m.session, input)
+		if !ok {
+			m.info("Unknown command %s; /help lists commands", name)
+			break
+		}
+		m.info("%s", out)
+	}
+	return nil
+}
+
+func (m *model) setInput(s string) {
+	m.input.SetValue(s)
+	m.input.CursorEnd()
+}
+
+// layout returns the widths of the history pane and the tool sidebar
+func (m *model) layout() (main, sidebar int) {
+	if m.showTools && m.width >= sidebarMin {
+		sidebar = min(m.width/3, sidebarMax)
+		return m.width - sidebar - 1, sidebar
+	}
+	return m.width, 0
+}
+
+// refresh sizes the panes for the terminal and re-renders the history,
+// keeping it at the bottom when it was there
+func (m *model) refresh() {
+	main, _ := m.layout()
+	follow := m.history.AtBottom()
+	m.history.Width = main
+	m.history.Height = max(m.height-3, 1)
+	m.input.Width = max(m.width-lipgloss.Width(m.input.Prompt)-1, 1)
+	if m.picker != nil {
+		m.history.SetContent(m.pickerView())
+		return
+	}
+	m.history.SetContent(m.historyView(main))
+	if follow {
+		m.history.GotoBottom()
+	}
+}
+
+func (m *model) View() string {
+	if m.width < 20 || m.height < 5 {
+		return "Terminal too small"
+	}
+
+	header := fmt.Sprintf(" 🐸 pepebot · %s · %s · session %s", m.backend.AgentName(), m.backend.Model(), m.session)
+	body := m.history.View()
+	if _, sidebar := m.layout(); sidebar > 0 {
+		tools := sidebarStyle.Width(sidebar).Height(m.history.Height).MaxHeight(m.history.Height).
+			Render(m.toolsView(sidebar, m.history.Height))
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, tools)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(m.width).MaxHeight(1).Render(header),
+		body,
+		dimStyle.Width(m.width).MaxHeight(1).Render(m.statusText()),
+		m.input.View(),
+	)
+}
+
+// historyView renders the conversation for a pane of width cells
+func (m *model) historyView(width int) string {
+	if len(m.entries) == 0 {
+		return dimStyle.Render("Type a message and press Enter. /help lists commands.")
+	}
+	text := lipgloss.NewStyle().Width(max(width-2, 1)).PaddingLeft(2)
+	var blocks []string
+	for _, e := range m.entries {
+		switch e.kind {
+		case entryUser:
+			blocks = append(blocks, userStyle.Render("You")+"\n"+text.Render(e.text))
+		case entryAgent:
+			answer := e.text
+			if answer == "" && m.busy {
+				answer = "…"
+			}
+			blocks = append(blocks, agentStyle.Render(m.backend.AgentName())+"\n"+text.Render(answer))
+		case entryInfo:
+			blocks = append(blocks, dimStyle.Width(width).Render("• "+e.text))
+		case entryError:
+			blocks = append(blocks, errorStyle.Width(width).Render("✗ "+e.text))
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func (m *model) pickerView() string {
+	lines := []string{boldStyle.Render("Sessions  (↑/↓ select · Enter switch · Esc close)")}
+	if len(m.picker.items) == 0 {
+		return strings.Join(append(lines, dimStyle.Render("  no stored sessions")), "\n")
+	}
+	visible := m.history.Height - 1
+	start := 0
+	if m.picker.selected >= visible {
+		start = m.picker.selected - visible + 1
+	}
+	for i := start; i < len(m.picker.items) && i < start+visible; i++ {
+		item := m.picker.items[i]
+		mark := "  "
+		if item == m.session {
+			mark = "* "
+		}
+		if i == m.picker.selected {
+			lines = append(lines, selectedStyle.Render(mark+item))
+		} else {
+			lines = append(lines, mark+item)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *model) toolsView(width, height int) string {
+	row := lipgloss.NewStyle().MaxWidth(width)
+	lines := []string{boldStyle.Render(" Tools")}
+	if len(m.tools) == 0 {
+		return strings.Join(append(lines, dimStyle.Render(" none yet")), "\n")
+	}
+	for i := len(m.tools) - 1; i >= 0 && len(lines) < height; i-- {
+		t := m.tools[i]
+		switch {
+		case !t.Done:
+			lines = append(lines, runningStyle.Render(row.Render(fmt.Sprintf(" %s%s", m.spinner.View(), t.Name))))
+		case t.Failed:
+			lines = append(lines, errorStyle.Render(row.Render(fmt.Sprintf(" ✗ %s %s", t.Name, formatDuration(t.Duration)))))
+		default:
+			lines = append(lines, row.Render(fmt.Sprintf(" ✓ %s %s", t.Name, formatDuration(t.Duration))))
+		}
+		if t.Args != "" && t.Args != "{}" && len(lines) < height {
+			lines = append(lines, dimStyle.Render(row.Render("   "+t.Args)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *model) statusText() string {
+	var parts []string
+	if m.busy {
+		parts = append(parts, fmt.Sprintf(" %sthinking… Esc to stop", m.spinner.View()))
+	} else {
+		parts = append(parts, " Enter send · PgUp/PgDn scroll · Ctrl+S sessions · Tab tools · /help")
+	}
+	if !m.history.AtBottom() {
+		parts = append(parts, fmt.Sprintf("↑ scrolled %.0f%%", m.history.ScrollPercent()*100))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
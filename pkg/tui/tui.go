@@ -0,0 +1,58 @@
+// Package tui is the full-screen terminal interface of "pepebot agent --tui",
+// built on bubbletea.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/chzyer/readline"
+)
+
+// Backend is the agent the interface talks to
+type Backend interface {
+	AgentName() string
+	Model() string
+	// Send runs one turn, calling onText with answer text as it streams and
+	// onTool as tool calls start and finish
+	Send(ctx context.Context, sessionKey, content string, onText func(string), onTool func(ToolEvent)) error
+	History(sessionKey string) []Message
+	// Sessions lists stored session keys, most recently used first
+	Sessions() []string
+	Clear(sessionKey string)
+	Fork(sessionKey, newKey string) error
+	// Command handles slash commands the interface does not know itself,
+	// returning false for unknown commands
+	Command(sessionKey, input string) (string, bool)
+}
+
+var helpText = `Commands:
+  /new              Clear this session and start fresh
+  /sessions         Pick a session to switch to (Ctrl+S)
+  /session <key>    Switch to a session, creating it if needed
+  /fork <key>       Copy this session into a new key and switch to it
+  /tools            Show or hide the tool sidebar (Tab)
+  /clear            Clear the screen, keeping the session
+  /status           Show agent, model and session
+  /pin, /pins, /unpin  Manage pinned facts
+  /outcome <tag>    Tag this conversation for prompt experiments
+  /quit             Leave (Ctrl+C when idle)
+Keys: Enter send · Esc stop answer · PgUp/PgDn scroll · ↑/↓ input history`
+
+// Run shows the interface on the terminal until the user quits or ctx ends
+func Run(ctx context.Context, backend Backend, sessionKey string) error {
+	if !readline.IsTerminal(int(os.Stdin.Fd())) || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("the TUI needs an interactive terminal")
+	}
+
+	m := newModel(ctx, backend, sessionKey)
+	_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	m.stopTurn()
+	if err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		return fmt.Errorf("TUI failed: %w", err)
+	}
+	return nil
+}
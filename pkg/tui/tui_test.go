@@ -0,0 +1,236 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type fakeBackend struct {
+	history  map[string][]Message
+	sessions []string
+	turn     func(ctx context.Context, onText func(string), onTool func(ToolEvent)) error
+}
+
+func (b *fakeBackend) AgentName() string { return "coder" }
+func (b *fakeBackend) Model() string     { return "gpt" }
+
+func (b *fakeBackend) Send(ctx context.Context, sessionKey, content string, onText func(string), onTool func(ToolEvent)) error {
+	return b.turn(ctx, onText, onTool)
+}
+
+func (b *fakeBackend) History(sessionKey string) []Message  { return b.history[sessionKey] }
+func (b *fakeBackend) Sessions() []string                   { return b.sessions }
+func (b *fakeBackend) Clear(sessionKey string)              { delete(b.history, sessionKey) }
+func (b *fakeBackend) Fork(sessionKey, newKey string) error { return nil }
+
+func (b *fakeBackend) Command(sessionKey, input string) (string, bool) {
+	return "", false
+}
+
+func newTestModel(backend *fakeBackend, session string) *model {
+	m := newModel(context.Background(), backend, session)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 12})
+	return m
+}
+
+func typeText(m *model, s string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+}
+
+func press(m *model, t tea.KeyType) tea.Cmd {
+	_, cmd := m.Update(tea.KeyMsg{Type: t})
+	return cmd
+}
+
+// finishTurn feeds the running turn's messages to the model until it ends
+func finishTurn(t *testing.T, m *model) {
+	t.Helper()
+	events := m.turn
+	if events == nil {
+		t.Fatal("no turn is running")
+	}
+	for msg := range events {
+		m.Update(msg)
+		if _, ok := msg.(turnDone); ok {
+			return
+		}
+	}
+}
+
+func isQuit(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func TestModelInput(t *testing.T) {
+	backend := &fakeBackend{turn: func(context.Context, func(string), func(ToolEvent)) error { return nil }}
+	m := newTestModel(backend, "cli:default")
+
+	typeText(m, "  hi  ")
+	press(m, tea.KeyEnter)
+	finishTurn(t, m)
+	if len(m.entries) != 1 || m.entries[0].kind != entryUser || m.entries[0].text != "hi" {
+		t.Errorf("Enter should send the trimmed message, entries = %+v", m.entries)
+	}
+
+	typeText(m, "/status")
+	press(m, tea.KeyEnter)
+	if last := m.entries[len(m.entries)-1]; last.kind != entryInfo || !strings.Contains(last.text, "Session: cli:default") {
+		t.Errorf("slash command = %+v", last)
+	}
+	press(m, tea.KeyUp)
+	press(m, tea.KeyUp)
+	if m.input.Value() != "hi" {
+		t.Errorf("input history = %q", m.input.Value())
+	}
+	press(m, tea.KeyDown)
+	press(m, tea.KeyDown)
+	if m.input.Value() != "" {
+		t.Errorf("Down past the newest entry should clear the input, got %q", m.input.Value())
+	}
+
+	if !isQuit(press(m, tea.KeyCtrlC)) {
+		t.Error("Ctrl+C when idle should quit")
+	}
+}
+
+func TestModelBusyTurn(t *testing.T) {
+	started := make(chan struct{})
+	backend := &fakeBackend{}
+	backend.turn = func(ctx context.Context, onText func(string), onTool func(ToolEvent)) error {
+		onText("partial")
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	m := newTestModel(backend, "cli:a")
+
+	typeText(m, "question")
+	press(m, tea.KeyEnter)
+	<-started
+	typeText(m, "next")
+	press(m, tea.KeyEnter)
+	if last := m.entries[len(m.entries)-1]; last.kind != entryInfo || !strings.Contains(last.text, "Still answering") {
+		t.Errorf("messages are refused while busy, got %+v", last)
+	}
+	if isQuit(press(m, tea.KeyCtrlC)) {
+		t.Error("Ctrl+C while busy should stop the turn, not quit")
+	}
+	finishTurn(t, m)
+	if m.busy {
+		t.Error("turn should be finished")
+	}
+	if last := m.entries[len(m.entries)-1]; last.text != "Stopped" {
+		t.Errorf("stopped turn should say so, got %+v", last)
+	}
+}
+
+func TestModelTurnAndView(t *testing.T) {
+	backend := &fakeBackend{}
+	backend.turn = func(ctx context.Context, onText func(string), onTool func(ToolEvent)) error {
+		onTool(ToolEvent{ID: "1", Name: "list_dir", Args: `{"path":"."}`})
+		onText("There are ")
+		onText("two files.")
+		onTool(ToolEvent{ID: "1", Name: "list_dir", Done: true, Duration: 1500 * time.Millisecond})
+		return nil
+	}
+	m := newTestModel(backend, "cli:a")
+
+	typeText(m, "list files")
+	press(m, tea.KeyEnter)
+	finishTurn(t, m)
+
+	if len(m.tools) != 1 || !m.tools[0].Done {
+		t.Fatalf("tool finish should update its row: %+v", m.tools)
+	}
+	screen := m.View()
+	if rows := strings.Count(screen, "\n") + 1; rows != 12 {
+		t.Errorf("view has %d rows, want 12:\n%s", rows, screen)
+	}
+	for _, want := range []string{"coder · gpt · session cli:a", "You", "list files", "There are two files.", "Tools", "✓ list_dir 1.5s"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+
+	press(m, tea.KeyTab)
+	if strings.Contains(m.View(), "Tools") {
+		t.Error("Tab should hide the tool sidebar")
+	}
+
+	backend.turn = func(context.Context, func(string), func(ToolEvent)) error { return errors.New("provider down") }
+	typeText(m, "again")
+	press(m, tea.KeyEnter)
+	finishTurn(t, m)
+	if last := m.entries[len(m.entries)-1]; last.kind != entryError || last.text != "provider down" {
+		t.Errorf("failed turn should end with the error, got %+v", last)
+	}
+	for _, e := range m.entries {
+		if e.kind == entryAgent && e.text == "" {
+			t.Error("empty answer placeholder should be dropped")
+		}
+	}
+}
+
+func TestModelScrollAndPicker(t *testing.T) {
+	backend := &fakeBackend{
+		history:  map[string][]Message{"cli:c": {{Role: "user", Content: "stored question"}}},
+		sessions: []string{"cli:a", "cli:b", "cli:c"},
+	}
+	m := newTestModel(backend, "cli:b")
+	for i := 0; i < 30; i++ {
+		m.info("line %d", i)
+	}
+	if !strings.Contains(m.View(), "line 29") {
+		t.Error("history should start at the bottom")
+	}
+	press(m, tea.KeyPgUp)
+	if m.history.AtBottom() || strings.Contains(m.View(), "line 29") {
+		t.Error("PgUp should scroll up")
+	}
+	m.appendAnswer("streamed")
+	if m.history.AtBottom() {
+		t.Error("new output should not pull a scrolled history back down")
+	}
+	press(m, tea.KeyPgDown)
+	press(m, tea.KeyPgDown)
+	if !m.history.AtBottom() {
+		t.Error("PgDn should scroll back to the bottom")
+	}
+
+	press(m, tea.KeyCtrlS)
+	if m.picker == nil || m.picker.selected != 1 {
+		t.Fatalf("picker should start on the current session, got %+v", m.picker)
+	}
+	press(m, tea.KeyDown)
+	press(m, tea.KeyEnter)
+	if m.picker != nil {
+		t.Error("picker should close after choosing")
+	}
+	if m.session != "cli:c" || m.entries[0].text != "stored question" {
+		t.Errorf("picker should switch to cli:c, got %s with %+v", m.session, m.entries)
+	}
+}
+
+func TestModelSmallTerminal(t *testing.T) {
+	m := newTestModel(&fakeBackend{}, "cli:a")
+	m.Update(tea.WindowSizeMsg{Width: 10, Height: 3})
+	if got := m.View(); got != "Terminal too small" {
+		t.Errorf("View = %q", got)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	for i, row := range strings.Split(m.View(), "\n") {
+		if w := lipgloss.Width(row); w > 40 {
+			t.Errorf("row %d is %d cells wide: %q", i, w, row)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Diarizer finds who speaks when in an audio file
+type Diarizer interface {
+	Diarize(ctx context.Context, audioFilePath string) ([]SpeakerTurn, error)
+}
+
+// SpeakerTurn is a stretch of audio spoken by one speaker. Speaker is any
+// label the diarizer uses, such as SPEAKER_00.
+type SpeakerTurn struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Speaker string  `json:"speaker"`
+}
+
+// HTTPDiarizer sends audio to a diarization service, such as a self-hosted
+// pyannote server. The file is POSTed as the multipart field "file"; the
+// service answers with {"turns": [{"start", "end", "speaker"}]} or the bare
+// list of turns.
+type HTTPDiarizer struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewHTTPDiarizer(url, apiKey string) *HTTPDiarizer {
+	return &HTTPDiarizer{
+		url:        url,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (d *HTTPDiarizer) Diarize(ctx context.Context, audioFilePath string) ([]SpeakerTurn, error) {
+	audio, err := os.ReadFile(audioFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(audioFilePath))
+	if err != nil {
+		return nil, err
+	}
+	part.Write(audio)
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if d.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+d.apiKey)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("diarization request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("diarization error (status %d): %s", resp.StatusCode, string(data))
+	}
+
+	var turns []SpeakerTurn
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &turns)
+	} else {
+		var wrapped struct {
+			Turns []SpeakerTurn `json:"turns"`
+		}
+		err = json.Unmarshal(data, &wrapped)
+		turns = wrapped.Turns
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid diarization response: %w", err)
+	}
+	return turns, nil
+}
+
+// LabelSpeakers sets the Speaker of each segment to the speaker whose turns
+// overlap it most, named "Speaker 1", "Speaker 2"... in order of first
+// appearance. It returns the number of speakers found.
+func LabelSpeakers(segments []TranscriptionSegment, turns []SpeakerTurn) int {
+	names := make(map[string]string)
+	for i := range segments {
+		seg := &segments[i]
+		overlap := make(map[string]float64)
+		best := ""
+		for _, turn := range turns {
+			o := min(seg.End, turn.End) - max(seg.Start, turn.Start)
+			if o <= 0 {
+				continue
+			}
+			overlap[turn.Speaker] += o
+			if best == "" || overlap[turn.Speaker] > overlap[best] {
+				best = turn.Speaker
+			}
+		}
+		if best == "" {
+			continue
+		}
+		if _, ok := names[best]; !ok {
+			names[best] = fmt.Sprintf("Speaker %d", len(names)+1)
+		}
+		seg.Speaker = names[best]
+	}
+	return len(names)
+}
+
+// SpeakerTranscript joins labeled segments into one line per change of
+// speaker, "Speaker 1: ...". Unlabeled segments continue the previous line.
+func SpeakerTranscript(segments []TranscriptionSegment) string {
+	var lines []string
+	current := ""
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		if len(lines) == 0 || (seg.Speaker != "" && seg.Speaker != current) {
+			current = seg.Speaker
+			if current != "" {
+				text = current + ": " + text
+			}
+			lines = append(lines, text)
+			continue
+		}
+		lines[len(lines)-1] += " " + text
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,88 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLabelSpeakers(t *testing.T) {
+	segments := []TranscriptionSegment{
+		{Start: 0, End: 2, Text: " Are you coming tonight?"},
+		{Start: 2, End: 3, Text: " Yes."},
+		{Start: 3, End: 4.5, Text: " I'll bring snacks."},
+		{Start: 4.5, End: 6, Text: " Great, see you."},
+		{Start: 9, End: 10, Text: " (silence)"},
+	}
+	turns := []SpeakerTurn{
+		{Start: 0, End: 2.2, Speaker: "SPEAKER_01"},
+		{Start: 2.2, End: 4.4, Speaker: "SPEAKER_00"},
+		{Start: 4.4, End: 6, Speaker: "SPEAKER_01"},
+	}
+	if n := LabelSpeakers(segments, turns); n != 2 {
+		t.Fatalf("LabelSpeakers found %d speakers, want 2", n)
+	}
+	want := []string{"Speaker 1", "Speaker 2", "Speaker 2", "Speaker 1", ""}
+	for i, seg := range segments {
+		if seg.Speaker != want[i] {
+			t.Errorf("segment %d speaker = %q, want %q", i, seg.Speaker, want[i])
+		}
+	}
+
+	got := SpeakerTranscript(segments)
+	wantText := "Speaker 1: Are you coming tonight?\nSpeaker 2: Yes. I'll bring snacks.\nSpeaker 1: Great, see you. (silence)"
+	if got != wantText {
+		t.Errorf("SpeakerTranscript:\n%s\nwant\n%s", got, wantText)
+	}
+	if vtt := FormatVTT(segments[:1]); !strings.Contains(vtt, "<v Speaker 1>Are you coming tonight?") {
+		t.Errorf("FormatVTT lacks the voice span:\n%s", vtt)
+	}
+}
+
+func TestTranscribeWithDiarization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/diarize":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"turns": [{"start": 0, "end": 1.5, "speaker": "A"}, {"start": 1.5, "end": 3, "speaker": "B"}]}`)
+		case "/audio/transcriptions":
+			if r.FormValue("response_format") != "verbose_json" {
+				t.Errorf("response_format = %q, want verbose_json for diarization", r.FormValue("response_format"))
+			}
+			fmt.Fprint(w, `{"text": "Hi. Hello.", "segments": [{"start": 0, "end": 1.4, "text": " Hi."}, {"start": 1.6, "end": 3, "text": " Hello."}]}`)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "call.ogg")
+	os.WriteFile(path, []byte("OggS"), 0644)
+
+	transcriber := NewGroqTranscriber("key")
+	transcriber.apiBase = server.URL
+	transcriber.SetDiarizer(NewHTTPDiarizer(server.URL+"/diarize", "secret"))
+	result, err := transcriber.Transcribe(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Speaker 1: Hi.\nSpeaker 2: Hello."; result.Text != want {
+		t.Errorf("Text = %q, want %q", result.Text, want)
+	}
+
+	// a failing diarizer keeps the plain transcript
+	transcriber.SetDiarizer(NewHTTPDiarizer(server.URL+"/diarize", "wrong"))
+	result, err = transcriber.Transcribe(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "Hi. Hello." || result.Segments[0].Speaker != "" {
+		t.Errorf("Text = %q, speaker = %q; want the plain transcript", result.Text, result.Segments[0].Speaker)
+	}
+}
@@ -0,0 +1,330 @@
+package voice
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// DefaultWakeWord starts a request in listen mode
+const DefaultWakeWord = "hey pepe"
+
+const (
+	listenPreRoll    = 10              // frames of audio kept from before speech starts
+	listenEndSilence = 27              // frames of silence ending an utterance, about 800 ms
+	listenMinVoiced  = 8               // shorter sounds (clicks, taps) are ignored
+	listenMaxSeconds = 30              // utterances are cut after this long
+	listenFollowUp   = 8 * time.Second // after a reply or a bare wake word, no wake word is needed
+	listenAck        = "Yes?"          // spoken after a bare wake word
+)
+
+// Listen events
+const (
+	EventHeard  = "heard"  // an utterance was transcribed
+	EventAwake  = "awake"  // the wake word was heard alone
+	EventPrompt = "prompt" // text sent to the agent
+	EventReply  = "reply"  // the agent's answer
+	EventError  = "error"
+)
+
+// ListenEvent reports what the listener is doing
+type ListenEvent struct {
+	Kind string
+	Text string
+}
+
+// Listener is a hands-free voice assistant. It splits microphone audio into
+// utterances with a voice activity detector, transcribes them, and sends
+// those that start with the wake word to Reply. The reply is spoken with
+// TTS. For a few seconds after a reply, follow-up questions need no wake
+// word.
+//
+// The wake word is spotted in the transcript, allowing a typo per word, so
+// every utterance is transcribed; a local Whisper server keeps that free and
+// private.
+type Listener struct {
+	Transcriber Transcriber
+	Reply       func(ctx context.Context, prompt string) (string, error)
+	TTS         TTS    // nil only reports replies
+	WakeWord    string // empty answers every utterance
+	Language    string // ISO-639-1 hint for the transcriber
+	OnEvent     func(ListenEvent)
+
+	awakeUntil time.Time
+}
+
+// Run listens to 16 kHz mono 16-bit PCM until ctx is done or audio ends.
+// Audio heard while a request is handled, including the spoken reply, is
+// dropped.
+func (l *Listener) Run(ctx context.Context, audio io.Reader) error {
+	frames := make(chan []float32, 64)
+	errc := make(chan error, 1)
+	var busy atomic.Bool
+	go func() {
+		defer close(frames)
+		buf := make([]byte, vadFrame*2)
+		for {
+			if _, err := io.ReadFull(audio, buf); err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					errc <- err
+				}
+				return
+			}
+			if busy.Load() {
+				continue
+			}
+			frame := make([]float32, vadFrame)
+			for i := range frame {
+				frame[i] = float32(int16(binary.LittleEndian.Uint16(buf[2*i:]))) / 32768
+			}
+			select {
+			case frames <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var seg segmenter
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case frame, ok := <-frames:
+			if !ok {
+				select {
+				case err := <-errc:
+					return err
+				default:
+					return nil
+				}
+			}
+			utterance := seg.push(frame)
+			if utterance == nil {
+				continue
+			}
+			busy.Store(true)
+			l.handle(ctx, utterance)
+			for len(frames) > 0 {
+				<-frames
+			}
+			seg = segmenter{floor: seg.floor}
+			busy.Store(false)
+		}
+	}
+}
+
+// handle transcribes an utterance and answers it when it is addressed to
+// the assistant
+func (l *Listener) handle(ctx context.Context, utterance []float32) {
+	text, err := l.transcribe(ctx, utterance)
+	if err != nil {
+		l.emit(EventError, "transcription failed: "+err.Error())
+		return
+	}
+	if text == "" {
+		return
+	}
+	l.emit(EventHeard, text)
+
+	prompt := text
+	if l.WakeWord != "" {
+		rest, woke := matchWakeWord(text, l.WakeWord)
+		switch {
+		case woke && rest == "":
+			l.awakeUntil = time.Now().Add(listenFollowUp)
+			l.emit(EventAwake, "")
+			l.speak(ctx, listenAck)
+			return
+		case woke:
+			prompt = rest
+		case time.Now().After(l.awakeUntil):
+			return
+		}
+	}
+
+	l.awakeUntil = time.Time{}
+	l.emit(EventPrompt, prompt)
+	reply, err := l.Reply(ctx, prompt)
+	if err != nil {
+		l.emit(EventError, err.Error())
+		return
+	}
+	l.emit(EventReply, reply)
+	l.speak(ctx, Speakable(reply))
+	l.awakeUntil = time.Now().Add(listenFollowUp)
+}
+
+func (l *Listener) transcribe(ctx context.Context, samples []float32) (string, error) {
+	f, err := os.CreateTemp("", "pepebot-listen-*.wav")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(encodeWAV(samples))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	result, err := l.Transcriber.TranscribeWithOptions(ctx, f.Name(), TranscribeOptions{Language: l.Language})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+func (l *Listener) speak(ctx context.Context, text string) {
+	if l.TTS == nil || text == "" {
+		return
+	}
+	if err := l.TTS.Speak(ctx, text); err != nil {
+		l.emit(EventError, err.Error())
+	}
+}
+
+func (l *Listener) emit(kind, text string) {
+	if l.OnEvent != nil {
+		l.OnEvent(ListenEvent{Kind: kind, Text: text})
+	}
+}
+
+// segmenter splits a stream of frames into utterances: speech louder than
+// the tracked noise floor, with some audio from before it, ending at a
+// pause
+type segmenter struct {
+	floor   float64
+	preRoll [][]float32
+	speech  []float32
+	voiced  int
+	silent  int
+}
+
+// push adds a frame and returns an utterance when one has ended
+func (s *segmenter) push(frame []float32) []float32 {
+	var sum float64
+	for _, v := range frame {
+		sum += float64(v) * float64(v)
+	}
+	level := math.Sqrt(sum / float64(len(frame)))
+	loud := level > math.Max(s.floor*3, vadMinLevel)
+
+	if s.speech == nil {
+		// the noise floor follows quiet stretches quickly and rises slowly
+		if s.floor == 0 || level < s.floor {
+			s.floor = level
+		} else {
+			s.floor += (level - s.floor) * 0.05
+		}
+		if !loud {
+			s.preRoll = append(s.preRoll, frame)
+			if len(s.preRoll) > listenPreRoll {
+				s.preRoll = s.preRoll[1:]
+			}
+			return nil
+		}
+		for _, f := range s.preRoll {
+			s.speech = append(s.speech, f...)
+		}
+		s.preRoll = nil
+		s.voiced, s.silent = 0, 0
+	}
+
+	s.speech = append(s.speech, frame...)
+	if loud {
+		s.voiced++
+		s.silent = 0
+	} else {
+		s.silent++
+	}
+	if s.silent < listenEndSilence && len(s.speech) < listenMaxSeconds*SampleRate {
+		return nil
+	}
+	utterance := s.speech
+	s.speech = nil
+	if s.voiced < listenMinVoiced {
+		return nil
+	}
+	return utterance
+}
+
+// matchWakeWord reports whether text starts with the wake word, allowing a
+// couple of words before it ("okay, hey pepe") and one typo per word of four
+// or more letters, and returns the text after it
+func matchWakeWord(text, wake string) (string, bool) {
+	type word struct {
+		norm string
+		end  int
+	}
+	var words []word
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, word{strings.ToLower(text[start:i]), i})
+			start = -1
+		}
+	}
+	wakeWords := strings.Fields(strings.ToLower(wake))
+	if len(wakeWords) == 0 {
+		return text, true
+	}
+
+	for offset := 0; offset <= 2 && offset+len(wakeWords) <= len(words); offset++ {
+		matched := true
+		for j, w := range wakeWords {
+			if !similarWord(words[offset+j].norm, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			rest := text[words[offset+len(wakeWords)-1].end:]
+			return strings.TrimLeftFunc(rest, func(r rune) bool {
+				return unicode.IsSpace(r) || unicode.IsPunct(r)
+			}), true
+		}
+	}
+	return "", false
+}
+
+// similarWord compares words allowing one edit when they are long enough
+// for a transcription slip
+func similarWord(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(rb) < 4 || len(ra)-len(rb) > 1 || len(rb)-len(ra) > 1 {
+		return false
+	}
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)] <= 1
+}
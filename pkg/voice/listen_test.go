@@ -0,0 +1,134 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMatchWakeWord(t *testing.T) {
+	tests := []struct {
+		text, rest string
+		ok         bool
+	}{
+		{"Hey Pepe, what's the weather tomorrow?", "what's the weather tomorrow?", true},
+		{"hey pepe", "", true},
+		{"Okay, hey Pepi. Turn off the lights.", "Turn off the lights.", true},
+		{"Hey pepé!", "", true},
+		{"What's the weather tomorrow?", "", false},
+		{"I told him hey pepe was busy", "", false},
+		{"hey pepper pot", "", false},
+	}
+	for _, tt := range tests {
+		rest, ok := matchWakeWord(tt.text, DefaultWakeWord)
+		if ok != tt.ok || rest != tt.rest {
+			t.Errorf("matchWakeWord(%q) = %q, %v; want %q, %v", tt.text, rest, ok, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestSpeakable(t *testing.T) {
+	got := Speakable("**Tomorrow**: rain, see [the forecast](https://example.com).\n\n```\ncode\n```\n- bring an _umbrella_")
+	if want := "Tomorrow: rain, see the forecast. - bring an umbrella"; got != want {
+		t.Errorf("Speakable = %q, want %q", got, want)
+	}
+}
+
+// pacedReader hands out audio a frame at a time with a pause, like a
+// microphone, so audio is not dropped while an utterance is handled
+type pacedReader struct{ data []byte }
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	if len(p.data) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(time.Millisecond)
+	n := copy(b[:min(len(b), vadFrame*2)], p.data)
+	p.data = p.data[n:]
+	return n, nil
+}
+
+type scriptedTranscriber struct {
+	texts []string
+	calls int
+}
+
+func (s *scriptedTranscriber) TranscribeWithOptions(ctx context.Context, path string, opts TranscribeOptions) (*TranscriptionResponse, error) {
+	s.calls++
+	if len(s.texts) == 0 {
+		return &TranscriptionResponse{}, nil
+	}
+	text := s.texts[0]
+	s.texts = s.texts[1:]
+	return &TranscriptionResponse{Text: text}, nil
+}
+
+func (s *scriptedTranscriber) IsAvailable() bool { return true }
+
+type recordingTTS struct{ spoken []string }
+
+func (r *recordingTTS) Speak(ctx context.Context, text string) error {
+	r.spoken = append(r.spoken, text)
+	return nil
+}
+
+// pcmStream renders alternating silence and tone stretches, in seconds, as
+// 16 kHz 16-bit PCM
+func pcmStream(stretches ...float64) []byte {
+	var buf bytes.Buffer
+	for i, seconds := range stretches {
+		for n := 0; n < int(seconds*SampleRate); n++ {
+			v := 0.0005 * math.Sin(float64(n)*2.3)
+			if i%2 == 1 {
+				v = 0.3 * math.Sin(2*math.Pi*300*float64(n)/SampleRate)
+			}
+			binary.Write(&buf, binary.LittleEndian, int16(v*32767))
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestListenerWakeWord(t *testing.T) {
+	transcriber := &scriptedTranscriber{texts: []string{
+		"Nice weather today.",                 // not addressed
+		"Hey Pepe",                            // wakes
+		"What's on my calendar?",              // within the follow-up window
+		"Hey pepe, remind me to buy **milk**", // wake word and request together
+	}}
+	tts := &recordingTTS{}
+	var prompts []string
+	l := &Listener{
+		Transcriber: transcriber,
+		TTS:         tts,
+		WakeWord:    DefaultWakeWord,
+		Reply: func(ctx context.Context, prompt string) (string, error) {
+			prompts = append(prompts, prompt)
+			return "Done: **" + prompt + "**", nil
+		},
+	}
+
+	audio := pcmStream(0.5, 1, 1.5, 0.6, 1.5, 1.2, 1.5, 1.5, 1.5)
+	if err := l.Run(context.Background(), &pacedReader{audio}); err != nil {
+		t.Fatal(err)
+	}
+
+	if transcriber.calls != 4 {
+		t.Fatalf("transcribed %d utterances, want 4", transcriber.calls)
+	}
+	if len(prompts) != 2 || prompts[0] != "What's on my calendar?" || prompts[1] != "remind me to buy **milk**" {
+		t.Errorf("prompts = %q", prompts)
+	}
+	want := []string{"Yes?", "Done: What's on my calendar?", "Done: remind me to buy milk"}
+	if len(tts.spoken) != len(want) {
+		t.Fatalf("spoken = %q, want %q", tts.spoken, want)
+	}
+	for i := range want {
+		if tts.spoken[i] != want[i] {
+			t.Errorf("spoken[%d] = %q, want %q", i, tts.spoken[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+)
+
+// micCommands capture 16 kHz mono 16-bit little-endian PCM to stdout, in
+// order of preference
+func micCommands() []struct{ bin, command string } {
+	ffmpegInput := "-f pulse -i default"
+	if runtime.GOOS == "darwin" {
+		ffmpegInput = "-f avfoundation -i :0"
+	}
+	return []struct{ bin, command string }{
+		{"arecord", "exec arecord -q -f S16_LE -r 16000 -c 1 -t raw"},
+		{"rec", "exec rec -q -t raw -r 16000 -e signed-integer -b 16 -c 1 -"},
+		{"ffmpeg", "exec ffmpeg -v error -nostdin " + ffmpegInput + " -ac 1 -ar 16000 -f s16le -"},
+	}
+}
+
+// Mic is a running microphone capture
+type Mic struct {
+	io.Reader
+	cmd *exec.Cmd
+}
+
+// OpenMic starts capturing the host microphone. command is a shell command
+// writing 16 kHz mono 16-bit little-endian PCM to stdout; empty uses
+// arecord, sox or ffmpeg, whichever is installed.
+func OpenMic(ctx context.Context, command string) (*Mic, error) {
+	if command == "" {
+		for _, c := range micCommands() {
+			if _, err := exec.LookPath(c.bin); err == nil {
+				command = c.command
+				break
+			}
+		}
+		if command == "" {
+			return nil, fmt.Errorf("no audio recorder found; install alsa-utils (arecord), sox or ffmpeg, or set voice.mic_command")
+		}
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %q: %w", command, err)
+	}
+	return &Mic{Reader: stdout, cmd: cmd}, nil
+}
+
+// Close stops the capture
+func (m *Mic) Close() error {
+	m.cmd.Process.Kill()
+	m.cmd.Wait()
+	return nil
+}
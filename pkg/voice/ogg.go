@@ -0,0 +1,100 @@
+package voice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// Opus frames from Discord are 20ms at 48kHz
+const opusFrameSamples = 960
+
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := 0; i < 256; i++ {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = (crc << 8) ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// WriteOggOpus wraps raw Opus packets in an Ogg container so the audio can be
+// uploaded to transcription APIs without decoding. One packet is written per page.
+func WriteOggOpus(path string, packets [][]byte, channels int) error {
+	if len(packets) == 0 {
+		return fmt.Errorf("no audio packets to write")
+	}
+
+	var buf bytes.Buffer
+	serial := uint32(0x70657065) // "pepe"
+	var seq uint32
+
+	writePage := func(packet []byte, granule uint64, headerType byte) {
+		segments := make([]byte, 0, len(packet)/255+1)
+		for n := len(packet); ; n -= 255 {
+			if n < 255 {
+				segments = append(segments, byte(n))
+				break
+			}
+			segments = append(segments, 255)
+		}
+
+		page := make([]byte, 27, 27+len(segments)+len(packet))
+		copy(page, "OggS")
+		page[4] = 0
+		page[5] = headerType
+		binary.LittleEndian.PutUint64(page[6:], granule)
+		binary.LittleEndian.PutUint32(page[14:], serial)
+		binary.LittleEndian.PutUint32(page[18:], seq)
+		page[26] = byte(len(segments))
+		page = append(page, segments...)
+		page = append(page, packet...)
+		binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+
+		buf.Write(page)
+		seq++
+	}
+
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = byte(channels)
+	binary.LittleEndian.PutUint16(head[10:], 312)
+	binary.LittleEndian.PutUint32(head[12:], 48000)
+	writePage(head, 0, 0x02)
+
+	vendor := "pepebot"
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+	writePage(tags, 0, 0)
+
+	var granule uint64
+	for i, packet := range packets {
+		granule += opusFrameSamples
+		headerType := byte(0)
+		if i == len(packets)-1 {
+			headerType = 0x04
+		}
+		writePage(packet, granule, headerType)
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
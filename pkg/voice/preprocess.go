@@ -0,0 +1,335 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SampleRate is the rate audio is converted to before transcription; Whisper
+// resamples everything to 16 kHz mono anyway
+const SampleRate = 16000
+
+// MaxUploadBytes is the largest file the transcription API accepts
+const MaxUploadBytes = 25 << 20
+
+// DefaultMaxPause is the longest pause left intact when no MaxPause is set
+const DefaultMaxPause = time.Second
+
+const (
+	vadFrame    = SampleRate * 30 / 1000  // 30 ms analysis frames
+	vadPad      = SampleRate * 250 / 1000 // audio kept around speech
+	vadMinLevel = 0.005                   // RMS below this (about -46 dBFS) is silence
+	targetPeak  = 0.9
+	maxGain     = 10.0 // 20 dB, so quiet notes are not turned into loud hiss
+)
+
+// ErrNoSpeech is returned when the voice activity detector finds no speech
+var ErrNoSpeech = errors.New("no speech in audio")
+
+// errNoDecoder is returned for compressed audio when ffmpeg is missing
+var errNoDecoder = errors.New("ffmpeg not found; only WAV audio can be preprocessed")
+
+// Preprocessor prepares audio for transcription. It converts it to 16 kHz
+// mono, finds speech with an energy-based voice activity detector, trims the
+// silence before and after it, shortens pauses longer than MaxPause, and
+// normalizes the volume. Whisper APIs bill by duration and answer faster on
+// shorter audio, so long voice notes full of pauses get cheaper.
+//
+// WAV files are decoded directly; other formats (Ogg Opus voice notes, MP3,
+// M4A) need ffmpeg.
+type Preprocessor struct {
+	FFmpeg   string        // ffmpeg binary; empty looks it up in PATH
+	MaxPause time.Duration // longer silences are cut to half a second; 0 uses DefaultMaxPause
+}
+
+// Preprocessed is processed audio ready for transcription
+type Preprocessed struct {
+	Path     string  // temporary WAV or FLAC file; remove it with Cleanup
+	Duration float64 // seconds of audio kept
+	Original float64 // seconds of audio before processing
+	spans    []span
+}
+
+// span is a stretch of the original audio copied to the output
+type span struct {
+	from, to float64 // output start and original start, in seconds
+}
+
+// Cleanup removes the processed file
+func (p *Preprocessed) Cleanup() {
+	os.Remove(p.Path)
+}
+
+// OriginalTime maps a time in the processed audio back to the original, for
+// segment timestamps
+func (p *Preprocessed) OriginalTime(t float64) float64 {
+	i := sort.Search(len(p.spans), func(i int) bool { return p.spans[i].from > t }) - 1
+	if i < 0 {
+		return t
+	}
+	return p.spans[i].to + t - p.spans[i].from
+}
+
+// Process converts the audio file at path and writes the result to a
+// temporary file
+func (p *Preprocessor) Process(ctx context.Context, path string) (*Preprocessed, error) {
+	samples, err := p.decode(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPause := p.MaxPause
+	if maxPause <= 0 {
+		maxPause = DefaultMaxPause
+	}
+	regions := detectSpeech(samples, int(maxPause.Seconds()*SampleRate))
+	if len(regions) == 0 {
+		return nil, ErrNoSpeech
+	}
+
+	result := &Preprocessed{Original: float64(len(samples)) / SampleRate}
+	var out []float32
+	for _, r := range regions {
+		result.spans = append(result.spans, span{
+			from: float64(len(out)) / SampleRate,
+			to:   float64(r[0]) / SampleRate,
+		})
+		out = append(out, samples[r[0]:r[1]]...)
+	}
+	normalize(out)
+	result.Duration = float64(len(out)) / SampleRate
+
+	data := encodeWAV(out)
+	ext := ".wav"
+	if len(data) > MaxUploadBytes {
+		// FLAC halves the size of long notes
+		if flac, err := p.ffmpeg(ctx, bytes.NewReader(data), "-i", "pipe:0", "-f", "flac", "pipe:1"); err == nil {
+			data, ext = flac, ".flac"
+		}
+	}
+
+	f, err := os.CreateTemp("", "pepebot-voice-*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	result.Path = f.Name()
+	return result, nil
+}
+
+// decode returns the audio as 16 kHz mono samples in [-1, 1]
+func (p *Preprocessor) decode(ctx context.Context, path string) ([]float32, error) {
+	if strings.EqualFold(filepath.Ext(path), ".wav") {
+		if data, err := os.ReadFile(path); err == nil {
+			if samples, err := decodeWAV(data); err == nil {
+				return samples, nil
+			}
+		}
+	}
+	pcm, err := p.ffmpeg(ctx, nil, "-i", path, "-ac", "1", "-ar", fmt.Sprint(SampleRate), "-f", "s16le", "pipe:1")
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]float32, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768
+	}
+	return samples, nil
+}
+
+// ffmpeg runs ffmpeg with args and returns what it writes to stdout
+func (p *Preprocessor) ffmpeg(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	bin := p.FFmpeg
+	if bin == "" {
+		bin = "ffmpeg"
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		return nil, errNoDecoder
+	}
+	cmd := exec.CommandContext(ctx, bin, append([]string{"-v", "error", "-nostdin"}, args...)...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// detectSpeech returns the [start, end) sample ranges to keep: frames louder
+// than a threshold between the noise floor and the speech level, padded, with
+// gaps up to maxPause merged
+func detectSpeech(samples []float32, maxPause int) [][2]int {
+	frames := len(samples) / vadFrame
+	if frames == 0 {
+		return nil
+	}
+	levels := make([]float64, frames)
+	for i := range levels {
+		var sum float64
+		for _, s := range samples[i*vadFrame : (i+1)*vadFrame] {
+			sum += float64(s) * float64(s)
+		}
+		levels[i] = math.Sqrt(sum / vadFrame)
+	}
+	sorted := append([]float64(nil), levels...)
+	sort.Float64s(sorted)
+	floor := sorted[frames/10]
+	loud := sorted[frames*9/10]
+	threshold := math.Max(floor+(loud-floor)*0.15, vadMinLevel)
+
+	var regions [][2]int
+	for i, level := range levels {
+		if level < threshold {
+			continue
+		}
+		start := max(i*vadFrame-vadPad, 0)
+		end := min((i+1)*vadFrame+vadPad, len(samples))
+		if n := len(regions); n > 0 && start-regions[n-1][1] <= maxPause {
+			regions[n-1][1] = end
+			continue
+		}
+		regions = append(regions, [2]int{start, end})
+	}
+	return regions
+}
+
+// normalize scales samples so the peak reaches targetPeak, by at most maxGain
+func normalize(samples []float32) {
+	var peak float32
+	for _, s := range samples {
+		if s < 0 {
+			s = -s
+		}
+		peak = max(peak, s)
+	}
+	if peak == 0 {
+		return
+	}
+	gain := math.Min(targetPeak/float64(peak), maxGain)
+	for i, s := range samples {
+		samples[i] = float32(float64(s) * gain)
+	}
+}
+
+// decodeWAV reads 8, 16, 24 or 32-bit PCM or 32-bit float WAV data, mixes it
+// down to mono and resamples it to SampleRate
+func decodeWAV(data []byte) ([]float32, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+	var format, channels, bits, rate int
+	var pcm []byte
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8 : min(pos+8+size, len(data))]
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, fmt.Errorf("invalid WAV format chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:]))
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			rate = int(binary.LittleEndian.Uint32(body[4:]))
+			bits = int(binary.LittleEndian.Uint16(body[14:]))
+			if format == 0xFFFE && len(body) >= 26 { // WAVE_FORMAT_EXTENSIBLE
+				format = int(binary.LittleEndian.Uint16(body[24:]))
+			}
+		case "data":
+			pcm = body
+		}
+		pos += 8 + size + size%2
+	}
+	if channels == 0 || rate == 0 || pcm == nil {
+		return nil, fmt.Errorf("WAV file has no audio")
+	}
+	if !(format == 1 && (bits == 8 || bits == 16 || bits == 24 || bits == 32)) && !(format == 3 && bits == 32) {
+		return nil, fmt.Errorf("unsupported WAV encoding (format %d, %d bits)", format, bits)
+	}
+
+	width := bits / 8
+	frames := len(pcm) / (width * channels)
+	mono := make([]float32, frames)
+	for i := range mono {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			b := pcm[(i*channels+c)*width:]
+			switch {
+			case format == 3:
+				sum += math.Float32frombits(binary.LittleEndian.Uint32(b))
+			case bits == 8:
+				sum += (float32(b[0]) - 128) / 128
+			case bits == 16:
+				sum += float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+			case bits == 24:
+				sum += float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+			default:
+				sum += float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+			}
+		}
+		mono[i] = sum / float32(channels)
+	}
+	return resample(mono, rate), nil
+}
+
+// resample converts samples from rate to SampleRate by linear interpolation
+func resample(samples []float32, rate int) []float32 {
+	if rate == SampleRate || len(samples) == 0 {
+		return samples
+	}
+	n := int(int64(len(samples)) * SampleRate / int64(rate))
+	out := make([]float32, n)
+	step := float64(rate) / SampleRate
+	for i := range out {
+		pos := float64(i) * step
+		j := int(pos)
+		if j+1 >= len(samples) {
+			out[i] = samples[len(samples)-1]
+			continue
+		}
+		frac := float32(pos - float64(j))
+		out[i] = samples[j]*(1-frac) + samples[j+1]*frac
+	}
+	return out
+}
+
+// encodeWAV writes samples as 16-bit mono PCM at SampleRate
+func encodeWAV(samples []float32) []byte {
+	var buf bytes.Buffer
+	size := len(samples) * 2
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+size))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{uint32(16), uint16(1), uint16(1), uint32(SampleRate), uint32(SampleRate * 2), uint16(2), uint16(16)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(size))
+	pcm := make([]byte, size)
+	for i, s := range samples {
+		v := math.Max(-1, math.Min(1, float64(s)))
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(v*32767)))
+	}
+	buf.Write(pcm)
+	return buf.Bytes()
+}
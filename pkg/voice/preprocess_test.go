@@ -0,0 +1,122 @@
+package voice
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestWAV writes 16-bit stereo audio at 48 kHz: a quiet 220 Hz tone
+// during the given [start, end) seconds and faint noise elsewhere
+func writeTestWAV(t *testing.T, seconds float64, tones ...[2]float64) string {
+	t.Helper()
+	const rate = 48000
+	n := int(seconds * rate)
+	pcm := make([]byte, n*4)
+	for i := 0; i < n; i++ {
+		sec := float64(i) / rate
+		v := 0.001 * math.Sin(float64(i)*1.7) // noise floor
+		for _, tone := range tones {
+			if sec >= tone[0] && sec < tone[1] {
+				v = 0.2 * math.Sin(2*math.Pi*220*sec)
+			}
+		}
+		s := uint16(int16(v * 32767))
+		binary.LittleEndian.PutUint16(pcm[4*i:], s)
+		binary.LittleEndian.PutUint16(pcm[4*i+2:], s)
+	}
+	header := make([]byte, 44)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+len(pcm)))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 2)
+	binary.LittleEndian.PutUint32(header[24:], rate)
+	binary.LittleEndian.PutUint32(header[28:], rate*4)
+	binary.LittleEndian.PutUint16(header[32:], 4)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(len(pcm)))
+
+	path := filepath.Join(t.TempDir(), "note.wav")
+	if err := os.WriteFile(path, append(header, pcm...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPreprocessTrimsSilence(t *testing.T) {
+	// speech at 2-3s and 6-7s in a 9s note
+	path := writeTestWAV(t, 9, [2]float64{2, 3}, [2]float64{6, 7})
+	p := &Preprocessor{FFmpeg: "pepebot-no-ffmpeg", MaxPause: time.Second}
+	result, err := p.Process(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Cleanup()
+
+	if math.Abs(result.Original-9) > 0.01 {
+		t.Errorf("Original = %.2f, want 9", result.Original)
+	}
+	// two seconds of speech plus 250 ms of padding on each side
+	if result.Duration < 2.9 || result.Duration > 3.2 {
+		t.Errorf("Duration = %.2f, want about 3", result.Duration)
+	}
+	if got := result.OriginalTime(0.3); math.Abs(got-2.05) > 0.05 {
+		t.Errorf("OriginalTime(0.3) = %.2f, want about 2.05", got)
+	}
+	if got := result.OriginalTime(result.Duration - 0.3); math.Abs(got-6.95) > 0.05 {
+		t.Errorf("OriginalTime(end) = %.2f, want about 6.95", got)
+	}
+
+	data, err := os.ReadFile(result.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := decodeWAV(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(len(samples))/SampleRate-result.Duration) > 0.01 {
+		t.Errorf("output has %d samples for %.2fs", len(samples), result.Duration)
+	}
+	var peak float32
+	for _, s := range samples {
+		peak = max(peak, s)
+	}
+	if math.Abs(float64(peak)-targetPeak) > 0.02 {
+		t.Errorf("peak = %.2f, want %.2f after normalization", peak, targetPeak)
+	}
+}
+
+func TestPreprocessKeepsShortPauses(t *testing.T) {
+	path := writeTestWAV(t, 5, [2]float64{1, 2}, [2]float64{2.8, 4})
+	p := &Preprocessor{FFmpeg: "pepebot-no-ffmpeg", MaxPause: time.Second}
+	result, err := p.Process(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Cleanup()
+	if result.Duration < 3.4 || result.Duration > 3.6 {
+		t.Errorf("Duration = %.2f, want about 3.5 with the pause kept", result.Duration)
+	}
+}
+
+func TestPreprocessErrors(t *testing.T) {
+	p := &Preprocessor{FFmpeg: "pepebot-no-ffmpeg"}
+	if _, err := p.Process(context.Background(), writeTestWAV(t, 3)); !errors.Is(err, ErrNoSpeech) {
+		t.Errorf("silent note: err = %v, want ErrNoSpeech", err)
+	}
+
+	ogg := filepath.Join(t.TempDir(), "note.ogg")
+	os.WriteFile(ogg, []byte("OggS"), 0644)
+	if _, err := p.Process(context.Background(), ogg); !errors.Is(err, errNoDecoder) {
+		t.Errorf("ogg without ffmpeg: err = %v, want errNoDecoder", err)
+	}
+}
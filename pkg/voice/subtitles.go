@@ -0,0 +1,39 @@
+package voice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatSRT renders transcription segments as SubRip subtitles
+func FormatSRT(segments []TranscriptionSegment) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if seg.Speaker != "" {
+			text = seg.Speaker + ": " + text
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(seg.Start, ","), subtitleTime(seg.End, ","), text)
+	}
+	return b.String()
+}
+
+// FormatVTT renders transcription segments as WebVTT subtitles
+func FormatVTT(segments []TranscriptionSegment) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if seg.Speaker != "" {
+			text = "<v " + seg.Speaker + ">" + text // WebVTT voice span
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", subtitleTime(seg.Start, "."), subtitleTime(seg.End, "."), text)
+	}
+	return b.String()
+}
+
+// subtitleTime formats seconds as HH:MM:SS plus milliseconds after sep
+func subtitleTime(seconds float64, sep string) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
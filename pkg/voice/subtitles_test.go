@@ -0,0 +1,22 @@
+package voice
+
+import "testing"
+
+func TestFormatSubtitles(t *testing.T) {
+	segments := []TranscriptionSegment{
+		{ID: 0, Start: 0, End: 2.5, Text: " Hello there."},
+		{ID: 1, Start: 3661.2, End: 3662, Text: "Bye."},
+	}
+
+	srt := FormatSRT(segments)
+	want := "1\n00:00:00,000 --> 00:00:02,500\nHello there.\n\n2\n01:01:01,200 --> 01:01:02,000\nBye.\n\n"
+	if srt != want {
+		t.Errorf("FormatSRT:\n%q\nwant\n%q", srt, want)
+	}
+
+	vtt := FormatVTT(segments[:1])
+	want = "WEBVTT\n\n00:00:00.000 --> 00:00:02.500\nHello there.\n\n"
+	if vtt != want {
+		t.Errorf("FormatVTT:\n%q\nwant\n%q", vtt, want)
+	}
+}
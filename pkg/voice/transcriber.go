@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,19 +12,50 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Transcriber converts speech in an audio file to text
+type Transcriber interface {
+	TranscribeWithOptions(ctx context.Context, audioFilePath string, opts TranscribeOptions) (*TranscriptionResponse, error)
+	IsAvailable() bool
+}
+
+// DefaultTranscriptionModel is the Whisper model used when none is given
+const DefaultTranscriptionModel = "whisper-large-v3"
+
+// TranscribeOptions are the optional OpenAI transcription parameters
+type TranscribeOptions struct {
+	Model       string   // defaults to DefaultTranscriptionModel
+	Language    string   // ISO-639-1 code; detected when empty
+	Prompt      string   // spelling hints or preceding text
+	Temperature *float64 // sampling temperature
+	Segments    bool     // request timestamped segments (verbose_json)
+}
+
 type GroqTranscriber struct {
-	apiKey     string
-	apiBase    string
-	httpClient *http.Client
+	apiKey       string
+	apiBase      string
+	httpClient   *http.Client
+	preprocessor *Preprocessor
+	diarizer     Diarizer
 }
 
 type TranscriptionResponse struct {
-	Text     string  `json:"text"`
-	Language string  `json:"language,omitempty"`
-	Duration float64 `json:"duration,omitempty"`
+	Text     string                 `json:"text"`
+	Language string                 `json:"language,omitempty"`
+	Duration float64                `json:"duration,omitempty"`
+	Segments []TranscriptionSegment `json:"segments,omitempty"`
+}
+
+type TranscriptionSegment struct {
+	ID      int     `json:"id"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+	Speaker string  `json:"speaker,omitempty"` // set by diarization
 }
 
 func NewGroqTranscriber(apiKey string) *GroqTranscriber {
@@ -37,9 +69,100 @@ func NewGroqTranscriber(apiKey string) *GroqTranscriber {
 	}
 }
 
+// SetAPIBase points the transcriber at another OpenAI-compatible
+// transcription server, such as a local Whisper server
+func (t *GroqTranscriber) SetAPIBase(apiBase string) {
+	t.apiBase = strings.TrimRight(apiBase, "/")
+}
+
+// SetPreprocessor trims silence and converts audio before it is uploaded.
+// nil uploads files as they are.
+func (t *GroqTranscriber) SetPreprocessor(p *Preprocessor) {
+	t.preprocessor = p
+}
+
+// SetDiarizer labels transcripts by speaker. When a recording has more than
+// one speaker, the text becomes one "Speaker N: ..." line per turn. nil
+// turns diarization off.
+func (t *GroqTranscriber) SetDiarizer(d Diarizer) {
+	t.diarizer = d
+}
+
 func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
+	return t.TranscribeWithOptions(ctx, audioFilePath, TranscribeOptions{})
+}
+
+func (t *GroqTranscriber) TranscribeWithOptions(ctx context.Context, audioFilePath string, opts TranscribeOptions) (*TranscriptionResponse, error) {
 	log.Printf("Starting transcription for audio file: %s", audioFilePath)
 
+	if t.diarizer == nil {
+		return t.preprocessAndTranscribe(ctx, audioFilePath, opts)
+	}
+
+	// Diarize the original audio while it is transcribed; segment times are
+	// mapped back to it
+	var turns []SpeakerTurn
+	var diarizeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		turns, diarizeErr = t.diarizer.Diarize(ctx, audioFilePath)
+	}()
+	opts.Segments = true
+	result, err := t.preprocessAndTranscribe(ctx, audioFilePath, opts)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	if diarizeErr != nil {
+		log.Printf("Speaker diarization failed, keeping the plain transcript: %v", diarizeErr)
+		return result, nil
+	}
+	if speakers := LabelSpeakers(result.Segments, turns); speakers > 1 {
+		result.Text = SpeakerTranscript(result.Segments)
+		log.Printf("Labeled transcript with %d speakers", speakers)
+	} else {
+		for i := range result.Segments {
+			result.Segments[i].Speaker = ""
+		}
+	}
+	return result, nil
+}
+
+// preprocessAndTranscribe runs the preprocessor, when set, before uploading
+func (t *GroqTranscriber) preprocessAndTranscribe(ctx context.Context, audioFilePath string, opts TranscribeOptions) (*TranscriptionResponse, error) {
+	if t.preprocessor == nil {
+		return t.transcribe(ctx, audioFilePath, opts)
+	}
+	processed, err := t.preprocessor.Process(ctx, audioFilePath)
+	if errors.Is(err, ErrNoSpeech) {
+		log.Printf("No speech detected in %s, skipping transcription", audioFilePath)
+		return &TranscriptionResponse{}, nil
+	}
+	if err != nil {
+		log.Printf("Audio preprocessing failed, uploading the original: %v", err)
+		return t.transcribe(ctx, audioFilePath, opts)
+	}
+	defer processed.Cleanup()
+	log.Printf("Preprocessed audio: %.1fs of %.1fs kept", processed.Duration, processed.Original)
+
+	result, err := t.transcribe(ctx, processed.Path, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Segments {
+		result.Segments[i].Start = processed.OriginalTime(result.Segments[i].Start)
+		result.Segments[i].End = processed.OriginalTime(result.Segments[i].End)
+	}
+	if result.Duration > 0 {
+		result.Duration = processed.Original
+	}
+	return result, nil
+}
+
+// transcribe uploads an audio file to the transcription API
+func (t *GroqTranscriber) transcribe(ctx context.Context, audioFilePath string, opts TranscribeOptions) (*TranscriptionResponse, error) {
+
 	audioFile, err := os.Open(audioFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open audio file: %w", err)
@@ -63,12 +186,30 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		return nil, fmt.Errorf("failed to copy file content: %w", err)
 	}
 
-	if err := writer.WriteField("model", "whisper-large-v3"); err != nil {
-		return nil, fmt.Errorf("failed to write model field: %w", err)
+	model := opts.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
 	}
-
-	if err := writer.WriteField("response_format", "json"); err != nil {
-		return nil, fmt.Errorf("failed to write response_format field: %w", err)
+	responseFormat := "json"
+	if opts.Segments {
+		responseFormat = "verbose_json"
+	}
+	fields := map[string]string{
+		"model":           model,
+		"response_format": responseFormat,
+		"language":        opts.Language,
+		"prompt":          opts.Prompt,
+	}
+	if opts.Temperature != nil {
+		fields["temperature"] = strconv.FormatFloat(*opts.Temperature, 'f', -1, 64)
+	}
+	for _, name := range []string{"model", "response_format", "language", "prompt", "temperature"} {
+		if fields[name] == "" {
+			continue
+		}
+		if err := writer.WriteField(name, fields[name]); err != nil {
+			return nil, fmt.Errorf("failed to write %s field: %w", name, err)
+		}
 	}
 
 	if err := writer.Close(); err != nil {
@@ -0,0 +1,65 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// TTS speaks text aloud on the host
+type TTS interface {
+	Speak(ctx context.Context, text string) error
+}
+
+// CommandTTS speaks by running a shell command with the text on stdin, such
+// as "espeak-ng --stdin" or a piper pipeline into aplay
+type CommandTTS struct {
+	Command string
+}
+
+func (c *CommandTTS) Speak(ctx context.Context, text string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tts command failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// DetectTTS returns the first speech synthesizer found on the host: say on
+// macOS, else espeak-ng or espeak. nil when there is none.
+func DetectTTS() TTS {
+	candidates := []struct{ bin, command string }{
+		{"espeak-ng", "espeak-ng --stdin"},
+		{"espeak", "espeak --stdin"},
+	}
+	if runtime.GOOS == "darwin" {
+		candidates = append([]struct{ bin, command string }{{"say", "say -f -"}}, candidates...)
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c.bin); err == nil {
+			return &CommandTTS{Command: c.command}
+		}
+	}
+	return nil
+}
+
+var (
+	codeBlockPattern = regexp.MustCompile("(?s)```.*?```")
+	linkPattern      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	urlPattern       = regexp.MustCompile(`https?://\S+`)
+	markupPattern    = regexp.MustCompile("[*_`#>|~]+")
+)
+
+// Speakable strips the Markdown of a reply so it reads naturally aloud. Code
+// blocks and URLs are left out.
+func Speakable(text string) string {
+	text = codeBlockPattern.ReplaceAllString(text, " ")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = urlPattern.ReplaceAllString(text, "")
+	text = markupPattern.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,426 @@
+package workflow
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Conflict modes for ImportBundle
+const (
+	ConflictFail      = "fail"      // import nothing if a file differs (default)
+	ConflictSkip      = "skip"      // keep the existing files
+	ConflictOverwrite = "overwrite" // replace the existing files
+	ConflictRename    = "rename"    // import the workflow under a free name, keep other existing files
+)
+
+const (
+	bundleFormat       = 1
+	bundleManifestName = "manifest.json"
+	maxBundleFileSize  = 50 << 20
+	maxBundleSize      = 200 << 20
+)
+
+// assetExtensions are the file types picked up from paths mentioned in a
+// workflow (screenshots from adb_record_workflow, UI dumps, data files)
+var assetExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".webp": true, ".gif": true,
+	".xml": true, ".csv": true, ".txt": true, ".json": true,
+}
+
+var pathToken = regexp.MustCompile(`[\w./-]+\.[A-Za-z0-9]+`)
+
+// BundleManifest describes a workflow bundle. It is the first entry of the
+// .tar.gz and lists every other entry by its workspace-relative path.
+type BundleManifest struct {
+	Format     int       `json:"format"`
+	Workflow   string    `json:"workflow"`
+	ExportedAt time.Time `json:"exported_at"`
+	Files      []string  `json:"files"`
+}
+
+// ImportOptions controls ImportBundle
+type ImportOptions struct {
+	Name       string // import under this name instead of the bundle's
+	OnConflict string // ConflictFail (default), ConflictSkip, ConflictOverwrite or ConflictRename
+}
+
+// ImportResult reports what ImportBundle did with each file
+type ImportResult struct {
+	Workflow  string   `json:"workflow"`
+	Written   []string `json:"written"`
+	Unchanged []string `json:"unchanged,omitempty"`
+	Skipped   []string `json:"skipped,omitempty"` // conflicting files that were kept as they were
+}
+
+// ConflictError lists the files that already exist with different content
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d file(s) already exist with different content: %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// ExportBundle writes the workflow, the workflows its workflow steps call,
+// the workspace skills their skill steps use and the workspace files they
+// mention (e.g. screenshots) as a .tar.gz bundle.
+func (h *WorkflowHelper) ExportBundle(name string, w io.Writer) (*BundleManifest, error) {
+	name = strings.TrimSuffix(name, ".json")
+	wf, err := h.LoadWorkflow(name)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{"workflows/" + name + ".json"}
+	visited := map[string]bool{name: true}
+	queue := []*WorkflowDefinition{wf}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		files = append(files, h.skillFiles(current)...)
+		files = append(files, h.assetFiles(current)...)
+		for _, step := range current.Steps {
+			if step.Workflow == "" || visited[step.Workflow] || !validWorkflowName(step.Workflow) {
+				continue
+			}
+			visited[step.Workflow] = true
+			sub, err := h.LoadWorkflow(step.Workflow)
+			if err != nil {
+				return nil, fmt.Errorf("step %s calls workflow '%s': %w", step.Name, step.Workflow, err)
+			}
+			files = append(files, "workflows/"+step.Workflow+".json")
+			queue = append(queue, sub)
+		}
+	}
+	files = dedupe(files)
+
+	manifest := &BundleManifest{Format: bundleFormat, Workflow: name, ExportedAt: time.Now().UTC(), Files: files}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeTarEntry(tw, bundleManifestName, manifestData, 0644); err != nil {
+		return nil, err
+	}
+	for _, rel := range files {
+		full := filepath.Join(h.workspace, filepath.FromSlash(rel))
+		info, err := os.Stat(full)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTarEntry(tw, rel, data, int64(info.Mode().Perm())); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ImportBundle unpacks a bundle made by ExportBundle into the workspace.
+// Files that already exist with the same content are left alone; other
+// conflicts are handled according to opts.OnConflict. Nothing is written
+// when the import fails.
+func (h *WorkflowHelper) ImportBundle(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	mode := opts.OnConflict
+	if mode == "" {
+		mode = ConflictFail
+	}
+	switch mode {
+	case ConflictFail, ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return nil, fmt.Errorf("invalid conflict mode '%s' (use fail, skip, overwrite or rename)", mode)
+	}
+
+	manifest, entries, modes, err := readBundle(r)
+	if err != nil {
+		return nil, err
+	}
+
+	source := "workflows/" + manifest.Workflow + ".json"
+	wfData, ok := entries[source]
+	if !ok {
+		return nil, fmt.Errorf("bundle does not contain %s", source)
+	}
+	var wf WorkflowDefinition
+	if err := json.Unmarshal(wfData, &wf); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow JSON: %w", err)
+	}
+	if err := ValidateDefinition(&wf); err != nil {
+		return nil, fmt.Errorf("invalid workflow in bundle: %w", err)
+	}
+	delete(entries, source)
+
+	target := strings.TrimSuffix(opts.Name, ".json")
+	if target == "" {
+		target = manifest.Workflow
+	}
+	if !validWorkflowName(target) {
+		return nil, fmt.Errorf("invalid workflow name '%s'", target)
+	}
+	if mode == ConflictRename {
+		target = h.freeName(target, wfData)
+	}
+	if target != manifest.Workflow {
+		if wf.Name == manifest.Workflow {
+			wf.Name = target
+		}
+		if wfData, err = json.MarshalIndent(&wf, "", "  "); err != nil {
+			return nil, err
+		}
+	}
+	entries["workflows/"+target+".json"] = wfData
+	modes["workflows/"+target+".json"] = 0644
+
+	result := &ImportResult{Workflow: target, Written: []string{}}
+	var conflicts []string
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		existing, err := os.ReadFile(filepath.Join(h.workspace, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(existing, entries[p]) {
+			result.Unchanged = append(result.Unchanged, p)
+			delete(entries, p)
+		} else {
+			conflicts = append(conflicts, p)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		switch mode {
+		case ConflictFail:
+			return nil, &ConflictError{Paths: conflicts}
+		case ConflictSkip, ConflictRename:
+			for _, p := range conflicts {
+				result.Skipped = append(result.Skipped, p)
+				delete(entries, p)
+			}
+		}
+	}
+
+	for _, p := range paths {
+		data, ok := entries[p]
+		if !ok {
+			continue
+		}
+		full := filepath.Join(h.workspace, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return result, err
+		}
+		if err := os.WriteFile(full, data, modes[p]); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", p, err)
+		}
+		result.Written = append(result.Written, p)
+	}
+	return result, nil
+}
+
+// freeName returns name, or name_2, name_3, ... if a different workflow is
+// already saved under it
+func (h *WorkflowHelper) freeName(name string, data []byte) string {
+	candidate := name
+	for i := 2; ; i++ {
+		existing, err := os.ReadFile(filepath.Join(h.WorkflowsDir(), candidate+".json"))
+		if err != nil || bytes.Equal(existing, data) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+}
+
+// skillFiles lists the files of workspace skills used by skill steps.
+// Built-in skills ship with pepebot and are not bundled.
+func (h *WorkflowHelper) skillFiles(wf *WorkflowDefinition) []string {
+	var files []string
+	for _, step := range wf.Steps {
+		if step.Skill == "" || !validWorkflowName(step.Skill) {
+			continue
+		}
+		dir := filepath.Join(h.workspace, "skills", step.Skill)
+		filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || info.Size() > maxBundleFileSize {
+				return nil
+			}
+			if rel, err := filepath.Rel(h.workspace, p); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// assetFiles finds workspace files mentioned in the workflow's variables,
+// goals and args, such as "workflows/login_final.png"
+func (h *WorkflowHelper) assetFiles(wf *WorkflowDefinition) []string {
+	var texts []string
+	for _, v := range wf.Variables {
+		texts = append(texts, v)
+	}
+	for _, step := range wf.Steps {
+		texts = append(texts, step.Goal)
+		for _, v := range step.Args {
+			if s, ok := v.(string); ok {
+				texts = append(texts, s)
+			}
+		}
+	}
+
+	var files []string
+	for _, text := range texts {
+		for _, token := range pathToken.FindAllString(text, -1) {
+			if filepath.IsAbs(token) {
+				// Absolute paths count when they point into the workspace
+				if r, err := filepath.Rel(h.workspace, token); err == nil {
+					token = r
+				}
+			}
+			rel := strings.TrimPrefix(path.Clean(filepath.ToSlash(token)), "./")
+			if !bundlePathAllowed(rel) || !assetExtensions[strings.ToLower(path.Ext(rel))] {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(h.workspace, filepath.FromSlash(rel)))
+			if err == nil && info.Mode().IsRegular() && info.Size() <= maxBundleFileSize {
+				files = append(files, rel)
+			}
+		}
+	}
+	return files
+}
+
+// bundlePathAllowed keeps bundles to workflows, skills and asset files inside
+// the workspace; memory and hidden files are never exported or overwritten.
+func bundlePathAllowed(rel string) bool {
+	if rel == "" || path.IsAbs(rel) || rel != path.Clean(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for _, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	switch parts[0] {
+	case "workflows", "skills":
+		return len(parts) > 1
+	case "memory":
+		return false
+	}
+	return assetExtensions[strings.ToLower(path.Ext(rel))]
+}
+
+func validWorkflowName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, ".")
+}
+
+func readBundle(r io.Reader) (*BundleManifest, map[string][]byte, map[string]os.FileMode, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("not a workflow bundle: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *BundleManifest
+	entries := map[string][]byte{}
+	modes := map[string]os.FileMode{}
+	total := int64(0)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxBundleFileSize {
+			return nil, nil, nil, fmt.Errorf("%s is larger than %d MB", hdr.Name, maxBundleFileSize>>20)
+		}
+		if total += hdr.Size; total > maxBundleSize {
+			return nil, nil, nil, fmt.Errorf("bundle is larger than %d MB", maxBundleSize>>20)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+
+		if hdr.Name == bundleManifestName {
+			manifest = &BundleManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid bundle manifest: %w", err)
+			}
+			continue
+		}
+		if !bundlePathAllowed(hdr.Name) {
+			return nil, nil, nil, fmt.Errorf("bundle contains a file outside workflows, skills and assets: %s", hdr.Name)
+		}
+		entries[hdr.Name] = data
+		modes[hdr.Name] = 0644
+		if hdr.Mode&0111 != 0 {
+			modes[hdr.Name] = 0755
+		}
+	}
+
+	if manifest == nil {
+		return nil, nil, nil, fmt.Errorf("not a workflow bundle: %s is missing", bundleManifestName)
+	}
+	if manifest.Format > bundleFormat {
+		return nil, nil, nil, fmt.Errorf("bundle format %d is newer than this pepebot supports (%d)", manifest.Format, bundleFormat)
+	}
+	if !validWorkflowName(manifest.Workflow) {
+		return nil, nil, nil, fmt.Errorf("invalid workflow name in manifest: '%s'", manifest.Workflow)
+	}
+	return manifest, entries, modes, nil
+}
+
+func writeTarEntry(tw *tar.Writer, name string, data []byte, mode int64) error {
+	hdr := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	out := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	src := NewWorkflowHelper(t.TempDir(), nil)
+	writeFile(t, filepath.Join(src.workspace, "skills", "login", "SKILL.md"), "# Login skill")
+	writeFile(t, filepath.Join(src.workspace, "workflows", "login_final.png"), "png-bytes")
+	writeFile(t, filepath.Join(src.workspace, "memory", "MEMORY.md"), "private")
+	wf := &WorkflowDefinition{
+		Name: "login",
+		Steps: []WorkflowStep{
+			{Name: "open", Tool: "adb_open_app", Args: map[string]interface{}{"package": "com.example"}},
+			{Name: "check", Skill: "login", Goal: "Compare with workflows/login_final.png. See memory/MEMORY.md"},
+			{Name: "report", Workflow: "notify"},
+		},
+	}
+	src.SaveWorkflow("notify", &WorkflowDefinition{Name: "notify", Steps: []WorkflowStep{{Name: "send", Goal: "Tell the team"}}})
+	if err := src.SaveWorkflow("login", wf); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := src.ExportBundle("login", &buf)
+	if err != nil {
+		t.Fatalf("ExportBundle: %v", err)
+	}
+	want := map[string]bool{"workflows/login.json": true, "skills/login/SKILL.md": true, "workflows/login_final.png": true, "workflows/notify.json": true}
+	if len(manifest.Files) != len(want) {
+		t.Errorf("bundle files = %v", manifest.Files)
+	}
+	for _, f := range manifest.Files {
+		if !want[f] {
+			t.Errorf("unexpected file in bundle: %s", f)
+		}
+	}
+	bundle := buf.Bytes()
+
+	dst := NewWorkflowHelper(t.TempDir(), nil)
+	result, err := dst.ImportBundle(bytes.NewReader(bundle), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+	if result.Workflow != "login" || len(result.Written) != 4 {
+		t.Errorf("result = %+v", result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst.workspace, "skills", "login", "SKILL.md")); string(data) != "# Login skill" {
+		t.Errorf("skill not imported: %q", data)
+	}
+
+	// Same content again: nothing to do
+	if result, err = dst.ImportBundle(bytes.NewReader(bundle), ImportOptions{}); err != nil || len(result.Written) != 0 {
+		t.Errorf("re-import = %+v, %v", result, err)
+	}
+
+	// A changed local copy is a conflict
+	writeFile(t, filepath.Join(dst.workspace, "skills", "login", "SKILL.md"), "# Edited")
+	_, err = dst.ImportBundle(bytes.NewReader(bundle), ImportOptions{})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Paths) != 1 {
+		t.Fatalf("expected one conflict, got %v", err)
+	}
+	if result, err = dst.ImportBundle(bytes.NewReader(bundle), ImportOptions{OnConflict: ConflictSkip}); err != nil || len(result.Skipped) != 1 {
+		t.Errorf("skip = %+v, %v", result, err)
+	}
+	if result, err = dst.ImportBundle(bytes.NewReader(bundle), ImportOptions{OnConflict: ConflictOverwrite}); err != nil || len(result.Written) != 1 {
+		t.Errorf("overwrite = %+v, %v", result, err)
+	}
+
+	// Rename picks a free name when a different workflow has the name
+	other := &WorkflowDefinition{Name: "login", Steps: []WorkflowStep{{Name: "x", Goal: "other"}}}
+	dst.SaveWorkflow("login", other)
+	result, err = dst.ImportBundle(bytes.NewReader(bundle), ImportOptions{OnConflict: ConflictRename})
+	if err != nil || result.Workflow != "login_2" {
+		t.Fatalf("rename = %+v, %v", result, err)
+	}
+	renamed, err := dst.LoadWorkflow("login_2")
+	if err != nil || renamed.Name != "login_2" {
+		t.Errorf("renamed workflow = %+v, %v", renamed, err)
+	}
+}
+
+func TestImportRejectsUnsafePaths(t *testing.T) {
+	for _, name := range []string{"../evil.png", "memory/MEMORY.md", "AGENTS.md", ".git/config"} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		writeTarEntry(tw, bundleManifestName, []byte(`{"format":1,"workflow":"w"}`), 0644)
+		writeTarEntry(tw, "workflows/w.json", []byte(`{"name":"w","steps":[{"name":"a","goal":"b"}]}`), 0644)
+		writeTarEntry(tw, name, []byte("x"), 0644)
+		tw.Close()
+		gz.Close()
+
+		h := NewWorkflowHelper(t.TempDir(), nil)
+		if _, err := h.ImportBundle(&buf, ImportOptions{}); err == nil {
+			t.Errorf("bundle with %s should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Extract pulls a value out of step output. expr is either a jq/JSONPath-style
+// path (".devices[0].serial", "$.items[*].url", `.["odd key"]`) applied to the
+// JSON in output, or "regex:<pattern>" applied to the raw text, in which case
+// the first capture group (or the whole match) is returned.
+//
+// Strings are returned unquoted, other values as compact JSON. Paths that
+// iterate with [] or [*] return one result per line.
+func Extract(output, expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if pattern, ok := strings.CutPrefix(expr, "regex:"); ok {
+		return extractRegex(output, pattern)
+	}
+
+	segments, err := parsePath(expr)
+	if err != nil {
+		return "", err
+	}
+	doc, err := decodeJSON(output)
+	if err != nil {
+		return "", err
+	}
+
+	values := []interface{}{doc}
+	for _, seg := range segments {
+		var next []interface{}
+		for _, v := range values {
+			out, err := seg.apply(v)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", expr, err)
+			}
+			next = append(next, out...)
+		}
+		values = next
+	}
+
+	if len(values) == 0 {
+		return "", fmt.Errorf("%s: no match", expr)
+	}
+	lines := make([]string, len(values))
+	for i, v := range values {
+		lines[i] = formatValue(v)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func extractRegex(output, pattern string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid regex: %w", err)
+	}
+	m := re.FindStringSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("regex %q: no match", pattern)
+	}
+	if len(m) > 1 {
+		return m[1], nil
+	}
+	return m[0], nil
+}
+
+// decodeJSON parses output as JSON. Tools sometimes put a line of text before
+// their JSON, so when the whole output doesn't parse, the first object or
+// array in it is used.
+func decodeJSON(output string) (interface{}, error) {
+	var doc interface{}
+	trimmed := strings.TrimSpace(output)
+	if err := json.Unmarshal([]byte(trimmed), &doc); err == nil {
+		return doc, nil
+	}
+	if start := strings.IndexAny(trimmed, "{["); start >= 0 {
+		dec := json.NewDecoder(strings.NewReader(trimmed[start:]))
+		if err := dec.Decode(&doc); err == nil {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("output is not JSON (use \"regex:<pattern>\" for plain text)")
+}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// pathSegment is one step of a path: a key, an index, or iteration.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+func (s pathSegment) apply(v interface{}) ([]interface{}, error) {
+	switch {
+	case s.iterate:
+		switch val := v.(type) {
+		case []interface{}:
+			return val, nil
+		case map[string]interface{}:
+			out := make([]interface{}, 0, len(val))
+			for _, item := range val {
+				out = append(out, item)
+			}
+			return out, nil
+		}
+		return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+	case s.isIndex:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with [%d]", typeName(v), s.index)
+		}
+		i := s.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil, fmt.Errorf("index %d out of range (length %d)", s.index, len(arr))
+		}
+		return []interface{}{arr[i]}, nil
+	default:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot get key %q from %s", s.key, typeName(v))
+		}
+		item, ok := obj[s.key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found", s.key)
+		}
+		return []interface{}{item}, nil
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// parsePath splits a path expression into segments. A leading "$" or "." is
+// optional, so "devices[0]", ".devices[0]" and "$.devices[0]" are the same.
+func parsePath(expr string) ([]pathSegment, error) {
+	p := strings.TrimPrefix(expr, "$")
+	if p == "" || p == "." {
+		return nil, nil
+	}
+
+	var segments []pathSegment
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			i++
+			if i < len(p) && p[i] == '*' {
+				segments = append(segments, pathSegment{iterate: true})
+				i++
+			}
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed '['", expr)
+			}
+			inner := strings.TrimSpace(p[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "" || inner == "*":
+				segments = append(segments, pathSegment{iterate: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad index [%s]", expr, inner)
+				}
+				segments = append(segments, pathSegment{index: n, isIndex: true})
+			}
+		default:
+			end := strings.IndexAny(p[i:], ".[")
+			if end < 0 {
+				end = len(p) - i
+			}
+			segments = append(segments, pathSegment{key: p[i : i+end]})
+			i += end
+		}
+	}
+	return segments, nil
+}
@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RunRecord is one completed workflow execution, appended to
+// workspace/workflows/runs.jsonl
+type RunRecord struct {
+	Workflow   string    `json:"workflow"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Status     string    `json:"status"` // ok or error
+	Error      string    `json:"error,omitempty"`
+}
+
+func runsPath(workspace string) string {
+	return filepath.Join(workspace, "workflows", "runs.jsonl")
+}
+
+// recordRun appends a run record; failures are ignored since the log is informational
+func (h *WorkflowHelper) recordRun(name string, started time.Time, runErr error) {
+	rec := RunRecord{
+		Workflow:   name,
+		StartedAt:  started,
+		DurationMS: time.Since(started).Milliseconds(),
+		Status:     "ok",
+	}
+	if runErr != nil {
+		rec.Status = "error"
+		rec.Error = runErr.Error()
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	path := runsPath(h.workspace)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// LoadRuns returns workflow runs started at or after since, oldest first
+func LoadRuns(workspace string, since time.Time) ([]RunRecord, error) {
+	f, err := os.Open(runsPath(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RunRecord{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	runs := []RunRecord{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if !rec.StartedAt.Before(since) {
+			runs = append(runs, rec)
+		}
+	}
+	return runs, scanner.Err()
+}
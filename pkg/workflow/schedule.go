@@ -0,0 +1,47 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pepebot-space/pepebot/pkg/cron"
+)
+
+// ScheduleSourcePrefix marks cron jobs created from workflow schedule blocks
+const ScheduleSourcePrefix = "workflow:"
+
+// ScheduledJobs returns a cron job for every workflow in the workspace that
+// has a schedule block. Workflows that fail to load or validate are
+// reported in errs and left out.
+func (h *WorkflowHelper) ScheduledJobs() (jobs []cron.CronJob, errs []error) {
+	for _, name := range h.ListWorkflows() {
+		wf, err := h.LoadWorkflow(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if wf.Schedule == nil {
+			continue
+		}
+		if err := ValidateDefinition(wf); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		s := wf.Schedule
+		jobs = append(jobs, cron.CronJob{
+			Name:     "workflow " + name,
+			Enabled:  s.IsEnabled(),
+			Source:   ScheduleSourcePrefix + name,
+			Schedule: s.cronSchedule(),
+			Payload: cron.CronPayload{
+				Kind:     "workflow",
+				Workflow: name,
+				Vars:     s.Vars,
+				Deliver:  s.Channel != "",
+				Channel:  s.Channel,
+				To:       s.ChatID,
+			},
+		})
+	}
+	return jobs, errs
+}
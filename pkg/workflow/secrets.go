@@ -0,0 +1,116 @@
+package workflow
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pepebot-space/pepebot/pkg/secrets"
+)
+
+// secretRef matches {{secret:NAME}} references in tool args
+var secretRef = regexp.MustCompile(`\{\{secret:([A-Za-z_][A-Za-z0-9_]*)\}\}`)
+
+// secretStore returns the secret store, which lives in the pepebot home
+// directory next to the workspace.
+func (h *WorkflowHelper) secretStore() *secrets.Store {
+	if h.secrets == nil {
+		h.secrets = secrets.NewStore(filepath.Dir(h.workspace))
+	}
+	return h.secrets
+}
+
+// resolveSecrets replaces {{secret:NAME}} references in string args with
+// their values. Each resolved value is recorded in used (name -> value) so it
+// can be redacted from the run output afterwards.
+func (h *WorkflowHelper) resolveSecrets(args map[string]interface{}, used map[string]string) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		strVal, ok := value.(string)
+		if !ok || !strings.Contains(strVal, "{{secret:") {
+			result[key] = value
+			continue
+		}
+		var resolveErr error
+		result[key] = secretRef.ReplaceAllStringFunc(strVal, func(ref string) string {
+			name := secretRef.FindStringSubmatch(ref)[1]
+			secret, err := h.secretStore().Resolve(name)
+			if err != nil {
+				if resolveErr == nil {
+					resolveErr = err
+				}
+				return ref
+			}
+			used[name] = secret
+			return secret
+		})
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+	}
+	return result, nil
+}
+
+// usedSecretsKey carries the secrets resolved for a tool step to the tool
+// call, so the tool layer can keep them out of what it logs
+type usedSecretsKey struct{}
+
+func withUsedSecrets(ctx context.Context, used map[string]string) context.Context {
+	if len(used) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, usedSecretsKey{}, used)
+}
+
+// RedactSecrets puts the {{secret:NAME}} reference back into text for every
+// secret a workflow resolved for the tool call running in ctx.
+func RedactSecrets(ctx context.Context, text string) string {
+	if ctx == nil {
+		return text
+	}
+	used, _ := ctx.Value(usedSecretsKey{}).(map[string]string)
+	return redactSecrets(text, used)
+}
+
+// redactSecrets puts the {{secret:NAME}} reference back wherever a resolved
+// value shows up in text, e.g. when a tool echoes its request in an error.
+func redactSecrets(text string, used map[string]string) string {
+	if len(used) == 0 {
+		return text
+	}
+	names := make([]string, 0, len(used))
+	for name, value := range used {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	// Longest values first so a secret containing another is replaced whole
+	sort.Slice(names, func(i, j int) bool { return len(used[names[i]]) > len(used[names[j]]) })
+	for _, name := range names {
+		text = strings.ReplaceAll(text, used[name], "{{secret:"+name+"}}")
+	}
+	return text
+}
+
+// redactedError keeps the wrapped error for errors.Is while hiding secrets
+// from its message.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+func redactError(err error, used map[string]string) error {
+	if err == nil || len(used) == 0 {
+		return err
+	}
+	msg := redactSecrets(err.Error(), used)
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
@@ -0,0 +1,110 @@
+package workflow
+
+import "strings"
+
+// Template is a built-in, parameterized workflow that `pepebot workflow new`
+// copies into the workspace. Params become the workflow's variables, so the
+// generated file can still be run with --var overrides.
+type Template struct {
+	Name        string
+	Description string
+	Params      []TemplateParam
+	Steps       []WorkflowStep
+}
+
+// TemplateParam is a variable the generator asks for
+type TemplateParam struct {
+	Name    string
+	Prompt  string
+	Default string
+}
+
+// Templates returns the built-in workflow templates
+func Templates() []Template {
+	return []Template{
+		{
+			Name:        "daily_report",
+			Description: "Turn today's daily notes into a short report and save it to a file",
+			Params: []TemplateParam{
+				{Name: "audience", Prompt: "Who is the report for", Default: "the team"},
+				{Name: "report_path", Prompt: "Where to save the report (relative to the workspace)", Default: "reports/daily_report.md"},
+			},
+			Steps: []WorkflowStep{
+				{Name: "notes", Tool: "journal_read", Args: map[string]interface{}{"days": 1}},
+				{Name: "report", Goal: "Write a short daily report for {{audience}} from today's notes below. Group it under Done, In progress and Blockers, and keep each point to one line.\n\n{{notes_output}}"},
+				{Name: "save", Tool: "write_file", Args: map[string]interface{}{"path": "{{report_path}}", "content": "{{report_output}}"}},
+			},
+		},
+		{
+			Name:        "device_health_check",
+			Description: "Collect battery, storage, memory and OS details from an Android device and summarize its health",
+			Params: []TemplateParam{
+				{Name: "device", Prompt: "Device serial (empty for the only connected device)", Default: ""},
+				{Name: "battery_threshold", Prompt: "Warn when the battery level is below (%)", Default: "20"},
+			},
+			Steps: []WorkflowStep{
+				{Name: "battery", Tool: "adb_shell", Args: map[string]interface{}{"command": "dumpsys battery", "device": "{{device}}"}},
+				{Name: "storage", Tool: "adb_shell", Args: map[string]interface{}{"command": "df -h /data", "device": "{{device}}"}},
+				{Name: "memory", Tool: "adb_shell", Args: map[string]interface{}{"command": "head -n 3 /proc/meminfo", "device": "{{device}}"}},
+				{Name: "os_version", Tool: "adb_shell", Args: map[string]interface{}{"command": "getprop ro.build.version.release", "device": "{{device}}"}},
+				{Name: "summary", Goal: "Summarize the health of this Android device in a few lines. Flag a battery level below {{battery_threshold}}%, storage above 90% used and low available memory.\n\nBattery:\n{{battery_output}}\n\nStorage:\n{{storage_output}}\n\nMemory:\n{{memory_output}}\n\nAndroid version: {{os_version_output}}"},
+			},
+		},
+		{
+			Name:        "app_ui_test",
+			Description: "Launch an Android app, capture its first screen and check that the expected text is visible",
+			Params: []TemplateParam{
+				{Name: "package", Prompt: "App package name", Default: "com.android.settings"},
+				{Name: "expected_text", Prompt: "Text that should be on the first screen", Default: "Settings"},
+				{Name: "wait_seconds", Prompt: "Seconds to wait for the app to open", Default: "10"},
+				{Name: "device", Prompt: "Device serial (empty for the only connected device)", Default: ""},
+			},
+			Steps: []WorkflowStep{
+				{Name: "launch", Tool: "adb_open_app", Args: map[string]interface{}{"package": "{{package}}", "device": "{{device}}"}},
+				{Name: "wait", WaitFor: map[string]interface{}{"command": "dumpsys window | grep mCurrentFocus", "match": "{{package}}", "timeout": "{{wait_seconds}}", "device": "{{device}}"}},
+				{Name: "screenshot", Tool: "adb_screenshot", Args: map[string]interface{}{"filename": "{{package}}_launch.png", "device": "{{device}}"}},
+				{Name: "ui", Tool: "adb_ui_dump", Args: map[string]interface{}{"device": "{{device}}"}},
+				{Name: "verdict", Goal: "This is the UI dump of {{package}} right after launch. Answer PASS if the text \"{{expected_text}}\" is visible, otherwise FAIL, followed by one line explaining why.\n\n{{ui_output}}"},
+				{Name: "home", Tool: "adb_keyevent", Args: map[string]interface{}{"keycode": 3, "device": "{{device}}"}},
+			},
+		},
+	}
+}
+
+// FindTemplate looks up a built-in template by name
+func FindTemplate(name string) (Template, bool) {
+	for _, t := range Templates() {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Instantiate builds a workflow from the template. values override the
+// parameter defaults and become the workflow's default variables.
+func (t Template) Instantiate(name string, values map[string]string) *WorkflowDefinition {
+	vars := make(map[string]string, len(t.Params))
+	for _, p := range t.Params {
+		vars[p.Name] = p.Default
+		if v, ok := values[p.Name]; ok {
+			vars[p.Name] = v
+		}
+	}
+	steps := make([]WorkflowStep, len(t.Steps))
+	for i, s := range t.Steps {
+		steps[i] = s
+		if s.Args != nil {
+			steps[i].Args = make(map[string]interface{}, len(s.Args))
+			for k, v := range s.Args {
+				steps[i].Args[k] = v
+			}
+		}
+	}
+	return &WorkflowDefinition{
+		Name:        name,
+		Description: t.Description,
+		Variables:   vars,
+		Steps:       steps,
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/pepebot-space/pepebot/pkg/cron"
+	"github.com/pepebot-space/pepebot/pkg/secrets"
 )
 
 // ToolExecutor abstracts the tool registry for workflow step execution.
@@ -25,6 +30,12 @@ type WorkflowAgentProcessor interface {
 	ProcessDirect(ctx context.Context, content string, media []string, sessionKey string, agentName string) (string, error)
 }
 
+// WorkflowTeamRunner lets team steps hand a goal to a team of agents.
+// Implemented by AgentManager next to WorkflowAgentProcessor.
+type WorkflowTeamRunner interface {
+	RunTeam(ctx context.Context, teamName, task, sessionKey string) (string, error)
+}
+
 // WorkflowSkillProvider allows workflows to load skill content.
 // Implemented by skills.SkillsLoader.
 type WorkflowSkillProvider interface {
@@ -43,6 +54,39 @@ type WorkflowDefinition struct {
 	Description string            `json:"description"`
 	Variables   map[string]string `json:"variables,omitempty"`
 	Steps       []WorkflowStep    `json:"steps"`
+	Schedule    *WorkflowSchedule `json:"schedule,omitempty"`
+}
+
+// WorkflowSchedule runs a workflow on a cron schedule. The gateway registers
+// it as a cron job at startup, so no separate `pepebot cron add` is needed.
+type WorkflowSchedule struct {
+	Cron    string            `json:"cron"`              // five-field cron expression or @daily, @hourly, ...
+	TZ      string            `json:"tz,omitempty"`      // IANA time zone, default local time
+	Enabled *bool             `json:"enabled,omitempty"` // default true
+	Vars    map[string]string `json:"vars,omitempty"`    // variable overrides for scheduled runs
+	Channel string            `json:"channel,omitempty"` // optional: deliver the result here
+	ChatID  string            `json:"chat_id,omitempty"`
+	JitterS int               `json:"jitter_s,omitempty"` // random delay of up to this many seconds
+	Overlap string            `json:"overlap,omitempty"`  // skip (default) or queue
+	CatchUp string            `json:"catch_up,omitempty"` // skip, once or all
+}
+
+// cronSchedule converts the block to the cron job schedule
+func (s *WorkflowSchedule) cronSchedule() cron.CronSchedule {
+	return cron.CronSchedule{
+		Kind:     "cron",
+		Expr:     s.Cron,
+		TZ:       s.TZ,
+		JitterMS: int64(s.JitterS) * 1000,
+		Overlap:  s.Overlap,
+		CatchUp:  s.CatchUp,
+	}
+}
+
+// IsEnabled reports whether the schedule is active; it is unless
+// "enabled" is set to false
+func (s *WorkflowSchedule) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
 }
 
 // WorkflowStep represents a single step in a workflow.
@@ -53,6 +97,39 @@ type WorkflowStep struct {
 	Goal  string                 `json:"goal,omitempty"`  // Natural language goal for LLM
 	Skill string                 `json:"skill,omitempty"` // Skill name to load and combine with goal
 	Agent string                 `json:"agent,omitempty"` // Agent name to delegate goal to
+	Team  string                 `json:"team,omitempty"`  // Team name to delegate goal to
+
+	Extract string `json:"extract,omitempty"` // Path or "regex:" pattern applied to a step's output
+	From    string `json:"from,omitempty"`    // Step whose output extract reads (default: previous step)
+
+	Workflow string            `json:"workflow,omitempty"` // Sub-workflow to run
+	Vars     map[string]string `json:"vars,omitempty"`     // Variables passed to the sub-workflow
+
+	// WaitFor is shorthand for an adb_wait_for tool step with these args
+	WaitFor map[string]interface{} `json:"wait_for,omitempty"`
+}
+
+// waitForTool runs wait_for steps
+const waitForTool = "adb_wait_for"
+
+// expandStep turns shorthand steps into the tool steps they stand for
+func expandStep(step WorkflowStep) WorkflowStep {
+	if step.WaitFor != nil && step.Tool == "" {
+		step.Tool = waitForTool
+		step.Args = step.WaitFor
+	}
+	return step
+}
+
+// maxWorkflowDepth limits how deeply workflows can call each other
+const maxWorkflowDepth = 5
+
+// workflowStackKey holds the chain of workflows being executed in the context
+type workflowStackKey struct{}
+
+func workflowStack(ctx context.Context) []string {
+	stack, _ := ctx.Value(workflowStackKey{}).([]string)
+	return stack
 }
 
 // WorkflowHelper manages workflow execution and storage.
@@ -62,6 +139,7 @@ type WorkflowHelper struct {
 	skillProvider  WorkflowSkillProvider
 	agentProcessor WorkflowAgentProcessor
 	goalProcessor  GoalProcessor
+	secrets        *secrets.Store
 }
 
 // NewWorkflowHelper creates a new WorkflowHelper.
@@ -178,8 +256,20 @@ func (h *WorkflowHelper) RunWorkflowFile(ctx context.Context, filePath string, v
 	return h.ExecuteWorkflow(ctx, wf, vars)
 }
 
-// ExecuteWorkflow executes an already-loaded workflow definition.
+// ExecuteWorkflow executes an already-loaded workflow definition and records
+// the run in workflows/runs.jsonl.
 func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefinition, overrideVars map[string]string) (string, error) {
+	started := time.Now()
+	usedSecrets := make(map[string]string)
+	ctx = context.WithValue(ctx, workflowStackKey{}, []string{wf.Name})
+	output, _, err := h.executeSteps(ctx, wf, overrideVars, usedSecrets)
+	// Secret values never leave the run: not in the output, the error or runs.jsonl
+	output, err = redactSecrets(output, usedSecrets), redactError(err, usedSecrets)
+	h.recordRun(wf.Name, started, err)
+	return output, err
+}
+
+func (h *WorkflowHelper) executeSteps(ctx context.Context, wf *WorkflowDefinition, overrideVars map[string]string, usedSecrets map[string]string) (string, string, error) {
 	// Merge variables: workflow defaults + overrides
 	variables := make(map[string]string)
 	for k, v := range wf.Variables {
@@ -194,9 +284,56 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 	results = append(results, fmt.Sprintf("Description: %s", wf.Description))
 	results = append(results, "")
 
+	lastOutput := ""
 	for i, step := range wf.Steps {
+		step = expandStep(step)
+
+		// Stop between steps once the turn is cancelled (/stop)
+		if err := ctx.Err(); err != nil {
+			results = append(results, fmt.Sprintf("Stopped before step %d/%d: %s", i+1, len(wf.Steps), step.Name))
+			return strings.Join(results, "\n"), lastOutput, fmt.Errorf("workflow stopped before step %d (%s): %w", i+1, step.Name, err)
+		}
+
 		results = append(results, fmt.Sprintf("Step %d/%d: %s", i+1, len(wf.Steps), step.Name))
 
+		// Extract step
+		if step.Extract != "" {
+			source := lastOutput
+			if step.From != "" {
+				source = variables[step.From+"_output"]
+			}
+			value, err := Extract(source, step.Extract)
+			if err != nil {
+				results = append(results, fmt.Sprintf("  ERROR: extract failed: %v", err))
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+			}
+			variables[step.Name+"_output"] = value
+			variables[step.Name] = value
+			results = append(results, fmt.Sprintf("  Extract: %s", step.Extract))
+			results = append(results, fmt.Sprintf("  Value: %s", value))
+		}
+
+		// Workflow step
+		if step.Workflow != "" {
+			subVars := make(map[string]string, len(step.Vars))
+			for k, v := range step.Vars {
+				subVars[k] = interpolateVariables(v, variables)
+			}
+			results = append(results, fmt.Sprintf("  Workflow: %s", step.Workflow))
+			subLog, subOutput, err := h.executeSubWorkflow(ctx, step.Workflow, subVars, usedSecrets)
+			for _, line := range strings.Split(subLog, "\n") {
+				if line != "" {
+					results = append(results, "    "+line)
+				}
+			}
+			if err != nil {
+				results = append(results, fmt.Sprintf("  ERROR: %v", err))
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+			}
+			variables[step.Name+"_output"] = subOutput
+			variables[step.Name] = subOutput
+		}
+
 		// Tool step
 		if step.Tool != "" {
 			interpolatedArgs := interpolateArgs(step.Args, variables)
@@ -205,12 +342,21 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 				interpolatedArgs = coerceArgs(schema, interpolatedArgs)
 			}
 
-			output, err := h.executor.Execute(ctx, step.Tool, interpolatedArgs)
+			interpolatedArgs, err := h.resolveSecrets(interpolatedArgs, usedSecrets)
 			if err != nil {
 				results = append(results, fmt.Sprintf("  ERROR: %v", err))
-				return strings.Join(results, "\n"), fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
 			}
 
+			output, err := h.executor.Execute(withUsedSecrets(ctx, usedSecrets), step.Tool, interpolatedArgs)
+			if err != nil {
+				results = append(results, fmt.Sprintf("  ERROR: %v", err))
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+			}
+			// Tools may echo resolved secrets back (curl -v, env, error text);
+			// redact before the output reaches later steps or is truncated
+			output = redactSecrets(output, usedSecrets)
+
 			variables[step.Name+"_output"] = output
 			variables[step.Name] = output
 
@@ -226,12 +372,12 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 		if step.Skill != "" {
 			if h.skillProvider == nil {
 				results = append(results, "  ERROR: skill provider not available")
-				return strings.Join(results, "\n"), fmt.Errorf("step %d (%s) failed: skill provider not available", i+1, step.Name)
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: skill provider not available", i+1, step.Name)
 			}
 			skillContent, ok := h.skillProvider.LoadSkill(step.Skill)
 			if !ok {
 				results = append(results, fmt.Sprintf("  ERROR: skill '%s' not found", step.Skill))
-				return strings.Join(results, "\n"), fmt.Errorf("step %d (%s) failed: skill '%s' not found", i+1, step.Name, step.Skill)
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: skill '%s' not found", i+1, step.Name, step.Skill)
 			}
 			interpolatedGoal := interpolateVariables(step.Goal, variables)
 			combined := fmt.Sprintf("Using skill '%s':\n\n%s\n\nGoal: %s", step.Skill, skillContent, interpolatedGoal)
@@ -245,15 +391,16 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 		if step.Agent != "" {
 			if h.agentProcessor == nil {
 				results = append(results, "  ERROR: agent processor not available (standalone mode)")
-				return strings.Join(results, "\n"), fmt.Errorf("step %d (%s) failed: agent processor not available (standalone mode does not support agent steps)", i+1, step.Name)
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: agent processor not available (standalone mode does not support agent steps)", i+1, step.Name)
 			}
 			interpolatedGoal := interpolateVariables(step.Goal, variables)
 			sessionKey := fmt.Sprintf("workflow:%s:%s", wf.Name, step.Name)
 			agentResponse, err := h.agentProcessor.ProcessDirect(ctx, interpolatedGoal, nil, sessionKey, step.Agent)
 			if err != nil {
 				results = append(results, fmt.Sprintf("  ERROR: agent '%s' failed: %v", step.Agent, err))
-				return strings.Join(results, "\n"), fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
 			}
+			agentResponse = redactSecrets(agentResponse, usedSecrets)
 			variables[step.Name+"_output"] = agentResponse
 			variables[step.Name] = agentResponse
 			displayOutput := agentResponse
@@ -265,8 +412,34 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 			results = append(results, fmt.Sprintf("  Response: %s", displayOutput))
 		}
 
-		// Goal step (pure LLM, no skill/agent)
-		if step.Goal != "" && step.Skill == "" && step.Agent == "" {
+		// Team step
+		if step.Team != "" {
+			runner, ok := h.agentProcessor.(WorkflowTeamRunner)
+			if !ok {
+				results = append(results, "  ERROR: team runner not available (standalone mode)")
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: team runner not available (standalone mode does not support team steps)", i+1, step.Name)
+			}
+			interpolatedGoal := interpolateVariables(step.Goal, variables)
+			sessionKey := fmt.Sprintf("workflow:%s:%s", wf.Name, step.Name)
+			teamResponse, err := runner.RunTeam(ctx, step.Team, interpolatedGoal, sessionKey)
+			if err != nil {
+				results = append(results, fmt.Sprintf("  ERROR: team '%s' failed: %v", step.Team, err))
+				return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+			}
+			teamResponse = redactSecrets(teamResponse, usedSecrets)
+			variables[step.Name+"_output"] = teamResponse
+			variables[step.Name] = teamResponse
+			displayOutput := teamResponse
+			if len(displayOutput) > 500 {
+				displayOutput = displayOutput[:500] + "... (truncated)"
+			}
+			results = append(results, fmt.Sprintf("  Team: %s", step.Team))
+			results = append(results, fmt.Sprintf("  Goal: %s", interpolatedGoal))
+			results = append(results, fmt.Sprintf("  Response: %s", displayOutput))
+		}
+
+		// Goal step (pure LLM, no skill/agent/team)
+		if step.Goal != "" && step.Skill == "" && step.Agent == "" && step.Team == "" {
 			interpolatedGoal := interpolateVariables(step.Goal, variables)
 			results = append(results, fmt.Sprintf("  Goal: %s", interpolatedGoal))
 
@@ -274,8 +447,9 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 				goalOutput, err := h.goalProcessor.ProcessGoal(ctx, interpolatedGoal)
 				if err != nil {
 					results = append(results, fmt.Sprintf("  ERROR: goal processing failed: %v", err))
-					return strings.Join(results, "\n"), fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+					return strings.Join(results, "\n"), lastOutput, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
 				}
+				goalOutput = redactSecrets(goalOutput, usedSecrets)
 				variables[step.Name+"_output"] = goalOutput
 				variables[step.Name] = goalOutput
 				variables[step.Name+"_goal"] = interpolatedGoal
@@ -290,16 +464,75 @@ func (h *WorkflowHelper) ExecuteWorkflow(ctx context.Context, wf *WorkflowDefini
 			}
 		}
 
+		if out, ok := variables[step.Name+"_output"]; ok {
+			lastOutput = out
+		}
 		results = append(results, "")
 	}
 
 	results = append(results, "Workflow execution completed successfully!")
-	return strings.Join(results, "\n"), nil
+	return strings.Join(results, "\n"), lastOutput, nil
+}
+
+// executeSubWorkflow runs a workflow called from a workflow step. Its output
+// is the output of its last step.
+func (h *WorkflowHelper) executeSubWorkflow(ctx context.Context, name string, vars map[string]string, usedSecrets map[string]string) (string, string, error) {
+	stack := workflowStack(ctx)
+	for _, caller := range stack {
+		if caller == name {
+			return "", "", fmt.Errorf("workflow cycle: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+	if len(stack) >= maxWorkflowDepth {
+		return "", "", fmt.Errorf("workflows nested deeper than %d: %s -> %s", maxWorkflowDepth, strings.Join(stack, " -> "), name)
+	}
+
+	sub, err := h.LoadWorkflow(name)
+	if err != nil {
+		return "", "", err
+	}
+	if err := validateWorkflow(sub, nil); err != nil {
+		return "", "", fmt.Errorf("workflow '%s' is invalid: %w", name, err)
+	}
+
+	next := append(append([]string{}, stack...), name)
+	return h.executeSteps(context.WithValue(ctx, workflowStackKey{}, next), sub, vars, usedSecrets)
 }
 
 // Validate validates a workflow's structure using the executor for tool/param checking.
+// Workflows called by workflow steps must exist and must not call back into
+// the workflow that called them.
 func (h *WorkflowHelper) Validate(wf *WorkflowDefinition) error {
-	return validateWorkflow(wf, h.executor)
+	if err := validateWorkflow(wf, h.executor); err != nil {
+		return err
+	}
+	return h.checkCalls(wf, []string{wf.Name})
+}
+
+// checkCalls follows workflow steps through the saved workflows to find
+// missing workflows, cycles and chains deeper than maxWorkflowDepth.
+func (h *WorkflowHelper) checkCalls(wf *WorkflowDefinition, stack []string) error {
+	for i, step := range wf.Steps {
+		if step.Workflow == "" {
+			continue
+		}
+		for _, caller := range stack {
+			if caller == step.Workflow {
+				return fmt.Errorf("step %d (%s): workflow cycle: %s -> %s", i+1, step.Name, strings.Join(stack, " -> "), step.Workflow)
+			}
+		}
+		if len(stack) >= maxWorkflowDepth {
+			return fmt.Errorf("step %d (%s): workflows nested deeper than %d: %s -> %s", i+1, step.Name, maxWorkflowDepth, strings.Join(stack, " -> "), step.Workflow)
+		}
+		sub, err := h.LoadWorkflow(step.Workflow)
+		if err != nil {
+			return fmt.Errorf("step %d (%s): workflow '%s' not found", i+1, step.Name, step.Workflow)
+		}
+		if err := h.checkCalls(sub, append(append([]string{}, stack...), step.Workflow)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ValidateDefinition validates a workflow definition without a tool executor (structure only).
@@ -320,15 +553,29 @@ func interpolateVariables(input string, variables map[string]string) string {
 func interpolateArgs(args map[string]interface{}, variables map[string]string) map[string]interface{} {
 	result := make(map[string]interface{})
 	for key, value := range args {
-		if strVal, ok := value.(string); ok {
-			result[key] = interpolateVariables(strVal, variables)
-		} else {
-			result[key] = value
-		}
+		result[key] = interpolateValue(value, variables)
 	}
 	return result
 }
 
+// interpolateValue replaces variables in strings, including those nested in
+// objects and arrays (e.g. intent extras)
+func interpolateValue(value interface{}, variables map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return interpolateVariables(v, variables)
+	case map[string]interface{}:
+		return interpolateArgs(v, variables)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = interpolateValue(item, variables)
+		}
+		return out
+	}
+	return value
+}
+
 // coerceArgs converts string values to the types expected by the tool's parameter schema.
 // This fixes the issue where variable interpolation produces strings like "540"
 // but tools like adb_tap expect float64.
@@ -381,6 +628,14 @@ func validateWorkflow(wf *WorkflowDefinition, executor ToolExecutor) error {
 	if len(wf.Steps) == 0 {
 		return fmt.Errorf("workflow must have at least one step")
 	}
+	if wf.Schedule != nil {
+		if err := cron.ValidateSchedule(wf.Schedule.cronSchedule()); err != nil {
+			return fmt.Errorf("schedule: %w", err)
+		}
+		if (wf.Schedule.Channel == "") != (wf.Schedule.ChatID == "") {
+			return fmt.Errorf("schedule: 'channel' and 'chat_id' must be set together")
+		}
+	}
 
 	definedVars := make(map[string]bool)
 	for k := range wf.Variables {
@@ -391,11 +646,52 @@ func validateWorkflow(wf *WorkflowDefinition, executor ToolExecutor) error {
 		if step.Name == "" {
 			return fmt.Errorf("step %d: missing 'name' field", i+1)
 		}
-		if step.Tool == "" && step.Goal == "" && step.Skill == "" && step.Agent == "" {
-			return fmt.Errorf("step %d (%s): must have at least one of 'tool', 'goal', 'skill', or 'agent' field", i+1, step.Name)
+		if step.WaitFor != nil {
+			if step.Tool != "" || step.Goal != "" || step.Skill != "" || step.Agent != "" || step.Team != "" || step.Extract != "" || step.Workflow != "" {
+				return fmt.Errorf("step %d (%s): 'wait_for' cannot be combined with other step types", i+1, step.Name)
+			}
+			step = expandStep(step)
+		}
+		if step.Tool == "" && step.Goal == "" && step.Skill == "" && step.Agent == "" && step.Team == "" && step.Extract == "" && step.Workflow == "" {
+			return fmt.Errorf("step %d (%s): must have at least one of 'tool', 'goal', 'skill', 'agent', 'team', 'extract' or 'workflow' field", i+1, step.Name)
+		}
+		if step.Workflow != "" {
+			if step.Tool != "" || step.Goal != "" || step.Skill != "" || step.Agent != "" || step.Team != "" || step.Extract != "" {
+				return fmt.Errorf("step %d (%s): 'workflow' cannot be combined with other step types", i+1, step.Name)
+			}
+			if step.Workflow == wf.Name {
+				return fmt.Errorf("step %d (%s): a workflow cannot call itself", i+1, step.Name)
+			}
 		}
-		if step.Tool != "" && (step.Skill != "" || step.Agent != "") {
-			return fmt.Errorf("step %d (%s): 'tool' cannot be combined with 'skill' or 'agent'", i+1, step.Name)
+		if len(step.Vars) > 0 && step.Workflow == "" {
+			return fmt.Errorf("step %d (%s): 'vars' is only used with 'workflow'", i+1, step.Name)
+		}
+		if step.Extract != "" {
+			if step.Tool != "" || step.Goal != "" || step.Skill != "" || step.Agent != "" || step.Team != "" {
+				return fmt.Errorf("step %d (%s): 'extract' cannot be combined with other step types", i+1, step.Name)
+			}
+			if step.From == "" && i == 0 {
+				return fmt.Errorf("step %d (%s): 'extract' needs a previous step or a 'from' field", i+1, step.Name)
+			}
+			if step.From != "" && !definedVars[step.From+"_output"] {
+				return fmt.Errorf("step %d (%s): 'from' refers to unknown step '%s'", i+1, step.Name, step.From)
+			}
+			if pattern, ok := strings.CutPrefix(strings.TrimSpace(step.Extract), "regex:"); ok {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("step %d (%s): invalid extract regex: %v", i+1, step.Name, err)
+				}
+			} else if _, err := parsePath(step.Extract); err != nil {
+				return fmt.Errorf("step %d (%s): %v", i+1, step.Name, err)
+			}
+		}
+		if strings.Contains(step.Goal, "{{secret:") {
+			return fmt.Errorf("step %d (%s): secrets can only be used in tool 'args', not in 'goal' (goals are sent to the model)", i+1, step.Name)
+		}
+		if step.From != "" && step.Extract == "" {
+			return fmt.Errorf("step %d (%s): 'from' is only used with 'extract'", i+1, step.Name)
+		}
+		if step.Tool != "" && (step.Skill != "" || step.Agent != "" || step.Team != "") {
+			return fmt.Errorf("step %d (%s): 'tool' cannot be combined with 'skill', 'agent' or 'team'", i+1, step.Name)
 		}
 		if step.Tool != "" && step.Goal != "" {
 			return fmt.Errorf("step %d (%s): has both 'tool' and 'goal'. Use only one per step", i+1, step.Name)
@@ -409,6 +705,12 @@ func validateWorkflow(wf *WorkflowDefinition, executor ToolExecutor) error {
 		if step.Agent != "" && step.Goal == "" {
 			return fmt.Errorf("step %d (%s): 'agent' step requires a 'goal' field", i+1, step.Name)
 		}
+		if step.Team != "" && (step.Skill != "" || step.Agent != "") {
+			return fmt.Errorf("step %d (%s): 'team' cannot be combined with 'skill' or 'agent'", i+1, step.Name)
+		}
+		if step.Team != "" && step.Goal == "" {
+			return fmt.Errorf("step %d (%s): 'team' step requires a 'goal' field", i+1, step.Name)
+		}
 
 		if step.Tool != "" {
 			if step.Args == nil {
@@ -3,6 +3,7 @@ package workflow
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 )
@@ -10,9 +11,11 @@ import (
 // mockGoalProcessor simulates an LLM processing a goal.
 type mockGoalProcessor struct {
 	response string
+	lastGoal string
 }
 
 func (m *mockGoalProcessor) ProcessGoal(ctx context.Context, goal string) (string, error) {
+	m.lastGoal = goal
 	return m.response, nil
 }
 
@@ -183,3 +186,366 @@ func TestGoalStepVariableNaming(t *testing.T) {
 		t.Errorf("{{gen_goal}} = %q, want %q", v, "original goal text")
 	}
 }
+
+// mockTeamProcessor answers agent and team steps.
+type mockTeamProcessor struct {
+	team, task string
+}
+
+func (m *mockTeamProcessor) ProcessDirect(ctx context.Context, content string, media []string, sessionKey string, agentName string) (string, error) {
+	return "agent reply", nil
+}
+
+func (m *mockTeamProcessor) RunTeam(ctx context.Context, teamName, task, sessionKey string) (string, error) {
+	m.team, m.task = teamName, task
+	return "team report", nil
+}
+
+// TestTeamStep tests that team steps go to the team runner and store its answer.
+func TestTeamStep(t *testing.T) {
+	executor := &mockToolExecutor{}
+	processor := &mockTeamProcessor{}
+	helper := &WorkflowHelper{workspace: t.TempDir(), executor: executor, agentProcessor: processor}
+
+	wf := &WorkflowDefinition{
+		Name:      "team_workflow",
+		Variables: map[string]string{"topic": "solar panels"},
+		Steps: []WorkflowStep{
+			{Name: "research", Team: "research", Goal: "Compare {{topic}}"},
+			{Name: "send", Tool: "discord_send", Args: map[string]interface{}{"content": "{{research_output}}"}},
+		},
+	}
+	if err := ValidateDefinition(wf); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+
+	if _, err := helper.ExecuteWorkflow(context.Background(), wf, nil); err != nil {
+		t.Fatalf("workflow execution failed: %v", err)
+	}
+	if processor.team != "research" || processor.task != "Compare solar panels" {
+		t.Errorf("team runner got team=%q task=%q", processor.team, processor.task)
+	}
+	if executor.lastArgs["content"] != "team report" {
+		t.Errorf("team output not passed on: %v", executor.lastArgs["content"])
+	}
+
+	wf.Steps[0].Goal = ""
+	if err := ValidateDefinition(wf); err == nil {
+		t.Error("team step without goal should not validate")
+	}
+}
+
+// TestScheduledJobs tests that schedule blocks become cron jobs.
+func TestScheduledJobs(t *testing.T) {
+	helper := NewWorkflowHelper(t.TempDir(), nil)
+	os.MkdirAll(helper.WorkflowsDir(), 0755)
+	off := false
+	step := []WorkflowStep{{Name: "check", Goal: "Check the battery"}}
+
+	helper.SaveWorkflow("morning", &WorkflowDefinition{Name: "morning", Steps: step,
+		Schedule: &WorkflowSchedule{Cron: "0 7 * * *", TZ: "Asia/Jakarta", Vars: map[string]string{"device": "pixel"}, Channel: "telegram", ChatID: "42"}})
+	helper.SaveWorkflow("paused", &WorkflowDefinition{Name: "paused", Steps: step,
+		Schedule: &WorkflowSchedule{Cron: "@daily", Enabled: &off}})
+	helper.SaveWorkflow("manual", &WorkflowDefinition{Name: "manual", Steps: step})
+	helper.SaveWorkflow("broken", &WorkflowDefinition{Name: "broken", Steps: step,
+		Schedule: &WorkflowSchedule{Cron: "every morning"}})
+
+	jobs, errs := helper.ScheduledJobs()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Errorf("expected an error for the broken schedule, got %v", errs)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %+v", jobs)
+	}
+	for _, job := range jobs {
+		switch job.Source {
+		case "workflow:morning":
+			if !job.Enabled || job.Schedule.TZ != "Asia/Jakarta" || job.Payload.Vars["device"] != "pixel" || !job.Payload.Deliver || job.Payload.To != "42" {
+				t.Errorf("unexpected job: %+v", job)
+			}
+		case "workflow:paused":
+			if job.Enabled {
+				t.Error("schedule with enabled=false should be disabled")
+			}
+		default:
+			t.Errorf("unexpected source %q", job.Source)
+		}
+	}
+}
+
+// TestExtract tests path and regex extraction from step output.
+func TestExtract(t *testing.T) {
+	devices := `[{"serial": "emulator-5554", "status": "device"}, {"serial": "R58M", "status": "offline"}]`
+	tests := []struct {
+		output, expr, want string
+	}{
+		{devices, ".[0].serial", "emulator-5554"},
+		{devices, "$[-1].status", "offline"},
+		{devices, ".[].serial", "emulator-5554\nR58M"},
+		{devices, "[1]", `{"serial":"R58M","status":"offline"}`},
+		{`{"web": {"results": [{"url": "https://a"}]}, "n": 2}`, "web.results[0].url", "https://a"},
+		{`{"n": 2, "ok": true}`, ".n", "2"},
+		{`{"odd key": "x"}`, `.["odd key"]`, "x"},
+		{"Found devices:\n" + devices, ".[1].serial", "R58M"},
+		{"Results for: go\n1. Go\n   https://go.dev\n", `regex:https?://\S+`, "https://go.dev"},
+		{"version 1.2.3 installed", `regex:version (\S+)`, "1.2.3"},
+	}
+	for _, tt := range tests {
+		got, err := Extract(tt.output, tt.expr)
+		if err != nil {
+			t.Errorf("Extract(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Extract(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{".[5]", ".missing", ".[0].serial.x", "regex:nomatch", ".[0"} {
+		if _, err := Extract(devices, expr); err == nil {
+			t.Errorf("Extract(%q) should fail", expr)
+		}
+	}
+	if _, err := Extract("plain text", ".a"); err == nil {
+		t.Error("path on non-JSON output should fail")
+	}
+}
+
+// TestExtractStep tests that extract steps feed later steps.
+func TestExtractStep(t *testing.T) {
+	executor := &mockToolExecutor{}
+	helper := &WorkflowHelper{workspace: t.TempDir(), executor: executor}
+
+	wf := &WorkflowDefinition{
+		Name:      "extract_workflow",
+		Variables: map[string]string{"data": `{"devices": [{"serial": "abc123"}]}`},
+		Steps: []WorkflowStep{
+			{Name: "list", Tool: "discord_send", Args: map[string]interface{}{"content": "{{data}}"}},
+			{Name: "serial", Extract: ".devices[0].serial"},
+			{Name: "send", Tool: "discord_send", Args: map[string]interface{}{"content": "device {{serial}}"}},
+		},
+	}
+	if err := ValidateDefinition(wf); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	if _, err := helper.ExecuteWorkflow(context.Background(), wf, nil); err != nil {
+		t.Fatalf("workflow execution failed: %v", err)
+	}
+	if executor.lastArgs["content"] != "device abc123" {
+		t.Errorf("extracted value not passed on: %v", executor.lastArgs["content"])
+	}
+
+	wf.Steps[1] = WorkflowStep{Name: "serial", Extract: ".x", From: "nope"}
+	if err := ValidateDefinition(wf); err == nil {
+		t.Error("extract from an unknown step should not validate")
+	}
+	wf.Steps[1] = WorkflowStep{Name: "serial", Extract: ".x", Tool: "discord_send", Args: map[string]interface{}{}}
+	if err := ValidateDefinition(wf); err == nil {
+		t.Error("extract combined with tool should not validate")
+	}
+}
+
+// TestWaitForStep tests that wait_for steps run adb_wait_for
+func TestWaitForStep(t *testing.T) {
+	executor := &mockToolExecutor{}
+	helper := &WorkflowHelper{workspace: t.TempDir(), executor: executor}
+
+	wf := &WorkflowDefinition{
+		Name:      "wait_workflow",
+		Variables: map[string]string{"label": "Sign in"},
+		Steps: []WorkflowStep{
+			{Name: "wait", WaitFor: map[string]interface{}{"text": "{{label}}", "timeout": 10}},
+		},
+	}
+	if err := ValidateDefinition(wf); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	if _, err := helper.ExecuteWorkflow(context.Background(), wf, nil); err != nil {
+		t.Fatalf("workflow execution failed: %v", err)
+	}
+	if executor.lastTool != "adb_wait_for" || executor.lastArgs["text"] != "Sign in" || executor.lastArgs["timeout"] != 10 {
+		t.Errorf("unexpected call %s %v", executor.lastTool, executor.lastArgs)
+	}
+
+	wf.Steps[0].Tool = "adb_tap"
+	if err := ValidateDefinition(wf); err == nil {
+		t.Error("wait_for combined with tool should not validate")
+	}
+}
+
+// TestNestedArgsAreInterpolated tests variables inside objects and arrays.
+func TestNestedArgsAreInterpolated(t *testing.T) {
+	args := interpolateArgs(map[string]interface{}{
+		"data":       "app://orders/{{id}}",
+		"extras":     map[string]interface{}{"note": "order {{id}}", "notify": true},
+		"categories": []interface{}{"{{category}}"},
+	}, map[string]string{"id": "42", "category": "android.intent.category.DEFAULT"})
+
+	extras := args["extras"].(map[string]interface{})
+	if args["data"] != "app://orders/42" || extras["note"] != "order 42" || extras["notify"] != true {
+		t.Errorf("unexpected args %v", args)
+	}
+	if c := args["categories"].([]interface{}); c[0] != "android.intent.category.DEFAULT" {
+		t.Errorf("unexpected categories %v", c)
+	}
+}
+
+// TestSecretsAreResolvedAndRedacted tests {{secret:NAME}} in tool args.
+func TestSecretsAreResolvedAndRedacted(t *testing.T) {
+	executor := &mockToolExecutor{}
+	helper := &WorkflowHelper{workspace: t.TempDir(), executor: executor}
+	if err := helper.secretStore().Set("API_TOKEN", "tok-5f2a"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	wf := &WorkflowDefinition{
+		Name: "secret_workflow",
+		Steps: []WorkflowStep{
+			{Name: "call", Tool: "discord_send", Args: map[string]interface{}{"content": "Bearer {{secret:API_TOKEN}}"}},
+		},
+	}
+	output, err := helper.ExecuteWorkflow(context.Background(), wf, nil)
+	if err != nil {
+		t.Fatalf("workflow execution failed: %v", err)
+	}
+	if executor.lastArgs["content"] != "Bearer tok-5f2a" {
+		t.Errorf("secret not resolved: %v", executor.lastArgs["content"])
+	}
+	if strings.Contains(output, "tok-5f2a") || !strings.Contains(output, "{{secret:API_TOKEN}}") {
+		t.Errorf("secret not redacted from output:\n%s", output)
+	}
+
+	// Tool output echoing the secret is redacted before later steps see it,
+	// also when the display cut would split the secret
+	wf.Steps = []WorkflowStep{
+		{Name: "call", Tool: "discord_send", Args: map[string]interface{}{"content": strings.Repeat("x", 490) + "{{secret:API_TOKEN}}"}},
+		{Name: "summarize", Goal: "Summarize {{call_output}}"},
+	}
+	goals := &mockGoalProcessor{response: "ok"}
+	helper.goalProcessor = goals
+	output, err = helper.ExecuteWorkflow(context.Background(), wf, nil)
+	if err != nil {
+		t.Fatalf("workflow execution failed: %v", err)
+	}
+	if strings.Contains(goals.lastGoal, "tok-5f2a") {
+		t.Errorf("secret passed on to a later goal: %s", goals.lastGoal)
+	}
+	if strings.Contains(output, "tok-") {
+		t.Errorf("truncated secret leaked into output:\n%s", output)
+	}
+
+	wf.Steps = []WorkflowStep{{Name: "call", Tool: "discord_send", Args: map[string]interface{}{"content": "{{secret:MISSING_PEPEBOT_SECRET}}"}}}
+	if _, err := helper.ExecuteWorkflow(context.Background(), wf, nil); err == nil {
+		t.Error("unknown secret should fail the step")
+	}
+
+	wf.Steps = []WorkflowStep{{Name: "ask", Goal: "Use {{secret:API_TOKEN}}"}}
+	if err := ValidateDefinition(wf); err == nil {
+		t.Error("secret in a goal should not validate")
+	}
+}
+
+// TestTemplatesValidate tests that every built-in template produces a valid
+// workflow, also after it is written to and read back from the workspace.
+func TestTemplatesValidate(t *testing.T) {
+	helper := NewWorkflowHelper(t.TempDir(), nil)
+	for _, tmpl := range Templates() {
+		wf := tmpl.Instantiate("my_"+tmpl.Name, map[string]string{"device": "emulator-5554"})
+		if err := ValidateDefinition(wf); err != nil {
+			t.Errorf("%s: %v", tmpl.Name, err)
+			continue
+		}
+		for _, p := range tmpl.Params {
+			if _, ok := wf.Variables[p.Name]; !ok {
+				t.Errorf("%s: variable %s missing", tmpl.Name, p.Name)
+			}
+		}
+		if err := helper.SaveWorkflow(wf.Name, wf); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := helper.LoadWorkflow(wf.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateDefinition(loaded); err != nil {
+			t.Errorf("%s after reload: %v", tmpl.Name, err)
+		}
+	}
+
+	if _, ok := FindTemplate("device_health_check"); !ok {
+		t.Error("FindTemplate should find device_health_check")
+	}
+	tmpl, _ := FindTemplate("device_health_check")
+	if wf := tmpl.Instantiate("x", map[string]string{"device": "a"}); tmpl.Params[0].Default != "" || wf.Variables["device"] != "a" {
+		t.Error("Instantiate should not modify the template")
+	}
+}
+
+// TestNestedWorkflow tests workflow steps: vars in, last step output out.
+func TestNestedWorkflow(t *testing.T) {
+	executor := &mockToolExecutor{}
+	helper := NewWorkflowHelper(t.TempDir(), executor)
+	helper.SaveWorkflow("notify", &WorkflowDefinition{
+		Name:      "notify",
+		Variables: map[string]string{"text": "default"},
+		Steps: []WorkflowStep{
+			{Name: "send", Tool: "discord_send", Args: map[string]interface{}{"content": "[{{text}}]"}},
+		},
+	})
+
+	wf := &WorkflowDefinition{
+		Name:      "parent",
+		Variables: map[string]string{"who": "ops"},
+		Steps: []WorkflowStep{
+			{Name: "first", Workflow: "notify", Vars: map[string]string{"text": "hi {{who}}"}},
+			{Name: "second", Tool: "discord_send", Args: map[string]interface{}{"content": "got {{first_output}}"}},
+		},
+	}
+	if err := helper.checkCalls(wf, []string{wf.Name}); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	output, err := helper.ExecuteWorkflow(context.Background(), wf, nil)
+	if err != nil {
+		t.Fatalf("workflow execution failed: %v", err)
+	}
+	if executor.lastArgs["content"] != "got sent: [hi ops]" {
+		t.Errorf("sub-workflow output not passed on: %v", executor.lastArgs["content"])
+	}
+	if !strings.Contains(output, "    Executing workflow: notify") {
+		t.Errorf("sub-workflow log missing:\n%s", output)
+	}
+
+	wf.Steps[0].Workflow = "missing"
+	if err := helper.checkCalls(wf, []string{wf.Name}); err == nil {
+		t.Error("call to a missing workflow should not validate")
+	}
+}
+
+// TestNestedWorkflowCycles tests cycle detection and the depth limit.
+func TestNestedWorkflowCycles(t *testing.T) {
+	helper := NewWorkflowHelper(t.TempDir(), &mockToolExecutor{})
+	call := func(name, next string) *WorkflowDefinition {
+		return &WorkflowDefinition{Name: name, Steps: []WorkflowStep{{Name: "call", Workflow: next}}}
+	}
+	helper.SaveWorkflow("a", call("a", "b"))
+	helper.SaveWorkflow("b", call("b", "a"))
+
+	a, _ := helper.LoadWorkflow("a")
+	if err := helper.Validate(a); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Validate should report the cycle, got %v", err)
+	}
+	_, err := helper.ExecuteWorkflow(context.Background(), a, nil)
+	if err == nil || !strings.Contains(err.Error(), "workflow cycle: a -> b -> a") {
+		t.Errorf("execution should stop at the cycle, got %v", err)
+	}
+
+	// w0 -> w1 -> ... -> w6 is deeper than the limit
+	for i := 0; i < 6; i++ {
+		helper.SaveWorkflow(fmt.Sprintf("w%d", i), call(fmt.Sprintf("w%d", i), fmt.Sprintf("w%d", i+1)))
+	}
+	helper.SaveWorkflow("w6", &WorkflowDefinition{Name: "w6", Steps: []WorkflowStep{{Name: "end", Goal: "done"}}})
+	w0, _ := helper.LoadWorkflow("w0")
+	if _, err := helper.ExecuteWorkflow(context.Background(), w0, nil); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+		t.Errorf("execution should stop at the depth limit, got %v", err)
+	}
+}